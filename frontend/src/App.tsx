@@ -28,6 +28,7 @@ import { Button } from "./components/ui/Button";
 import { useQueryClient } from "@tanstack/react-query";
 import { installTrayVoiceBridge, installVoiceBridge } from "./voice";
 import { clearAllDrafts } from "./utils/drafts";
+import { armIdleTimer, loadAutoLockMinutes, onAutoLockChange } from "./utils/autoLock";
 
 type AppState =
   | "initializing"
@@ -428,9 +429,28 @@ function MainApp() {
     } catch (err) {
       console.error("[App] lock failed:", err);
     }
+    // Drop decrypted message bodies held in the query cache; everything is
+    // refetched from the local DB after the next unlock.
+    queryClient.clear();
     setPendingPinUser(currentUser);
     setAppState("pin-entry");
-  }, [currentUser]);
+  }, [currentUser, queryClient]);
+
+  // Inactivity auto-lock (device-local, see utils/autoLock). Re-armed when
+  // the window is changed in Preferences.
+  const [autoLockMinutes, setAutoLockMinutes] = useState(() => loadAutoLockMinutes(currentUser?.id));
+  useEffect(() => {
+    setAutoLockMinutes(loadAutoLockMinutes(currentUser?.id));
+    return onAutoLockChange(() => setAutoLockMinutes(loadAutoLockMinutes(currentUser?.id)));
+  }, [currentUser?.id]);
+  useEffect(() => {
+    if (appState !== "ready" || !currentUser) {
+      return;
+    }
+    return armIdleTimer(autoLockMinutes, () => {
+      void handleLock();
+    });
+  }, [appState, currentUser, autoLockMinutes, handleLock]);
 
   // After delete_account succeeds in Settings, transition to auth screen.
   // Zustand logout() is called in Settings.tsx before this fires.
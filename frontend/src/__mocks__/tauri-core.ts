@@ -145,6 +145,11 @@ function handleCommand(command: string, args: Record<string, unknown>): unknown
     case 'list_user_groups':
       return store.groups;
 
+    case 'reserve_group_slug': {
+      const { slug } = args as { slug: string };
+      return slug;
+    }
+
     case 'create_group': {
       const { name, description, ownerId } = args as {
         name: string;
@@ -204,6 +209,10 @@ function handleCommand(command: string, args: Record<string, unknown>): unknown
       return { messages, next_cursor: null };
     }
 
+    case 'ingest_channel_envelopes':
+    case 'ingest_dm_envelopes':
+      return [];
+
     case 'list_dm_channels':
       return store.dmChannels;
 
@@ -250,6 +259,9 @@ function handleCommand(command: string, args: Record<string, unknown>): unknown
       return user;
     }
 
+    case 'get_pinned_messages':
+      return [];
+
     // These are no-ops or stubs for commands not needed in frontend tests
     case 'search_user_by_username':
     case 'invite_to_group':
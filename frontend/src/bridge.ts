@@ -71,7 +71,15 @@ export { writeFile, readFile, stat, type FileInfo } from "./bridge/fs";
 export { shellOpen } from "./bridge/shell";
 
 // App / path / process.
-export { getVersion, tempDir, relaunch, exit, convertFileSrc } from "./bridge/app";
+export {
+  getVersion,
+  tempDir,
+  relaunch,
+  exit,
+  convertFileSrc,
+  getLaunchAtLogin,
+  setLaunchAtLogin,
+} from "./bridge/app";
 
 // Notifications.
 export {
@@ -1,5 +1,6 @@
 /**
- * App / path / process bridge — version, temp dir, relaunch, exit.
+ * App / path / process bridge — version, temp dir, relaunch, exit,
+ * launch-at-login.
  *
  * Under Tauri: delegates to `@tauri-apps/api/app`, `@tauri-apps/api/path`,
  * and `@tauri-apps/plugin-process`. Under Electron: routes to the preload
@@ -11,6 +12,7 @@
  */
 
 import { electron, hasElectron } from "./runtime";
+import { invoke } from "./invoke";
 
 export async function getVersion(): Promise<string> {
   if (hasElectron()) {
@@ -46,6 +48,24 @@ export async function exit(code = 0): Promise<void> {
   await mod.exit(code);
 }
 
+// Launch-at-login is device-local: the OS login entry is the source of
+// truth, so there is nothing to mirror into synced preferences. Under Tauri
+// the entry lives in `src-tauri/src/autostart.rs`.
+export async function getLaunchAtLogin(): Promise<boolean> {
+  if (hasElectron()) {
+    return electron().appGetLaunchAtLogin();
+  }
+  return invoke<boolean>("autostart_is_enabled");
+}
+
+export async function setLaunchAtLogin(enabled: boolean): Promise<void> {
+  if (hasElectron()) {
+    await electron().appSetLaunchAtLogin(enabled);
+    return;
+  }
+  await invoke("autostart_set_enabled", { enabled });
+}
+
 // Sync under both runtimes. Tauri's convertFileSrc is sync; Electron's
 // preload exposes a sync wrapper too. We eagerly import @tauri-apps/api/core
 // here because the same module already underpins invoke/Channel — there's
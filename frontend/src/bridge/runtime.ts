@@ -86,6 +86,9 @@ export interface ElectronAPI {
   tempDir: () => Promise<string>;
   appRelaunch: () => Promise<void>;
   appExit: (code?: number) => Promise<void>;
+  // Launch-at-login (Electron's app.setLoginItemSettings).
+  appGetLaunchAtLogin: () => Promise<boolean>;
+  appSetLaunchAtLogin: (enabled: boolean) => Promise<void>;
 
   // ── Notifications ──────────────────────────────────────────────────────
   notificationsPermissionGranted: () => Promise<boolean>;
@@ -0,0 +1,85 @@
+import React, { useState } from "react";
+import { AlertTriangle, X } from "lucide-react";
+import { dialogSave, writeFile } from "../bridge";
+import {
+  useAcknowledgeCrashReports,
+  useCrashReports,
+  useExportCrashReports,
+} from "../hooks/queries/useCrashReports";
+import { errorMessage } from "../utils/errorMessage";
+import { Button } from "./ui/Button";
+
+/// "Pollis quit unexpectedly" notice, shown on the launch after a crash.
+///
+/// Release builds abort on panic, so there's no way to prompt in the crashing
+/// process itself — the panic hook (pollis-core/src/commands/crash.rs) writes
+/// a scrubbed report to disk and this banner offers it here instead. Export
+/// saves every report as one text file for a bug report; dismissing marks them
+/// seen. Reports stay on disk (newest 20) either way.
+export const CrashReportBanner: React.FC = () => {
+  const { data: reports } = useCrashReports();
+  const acknowledge = useAcknowledgeCrashReports();
+  const exportReports = useExportCrashReports();
+  const [error, setError] = useState<string | null>(null);
+
+  const unseen = (reports ?? []).filter((r) => !r.acknowledged);
+  if (unseen.length === 0) {
+    return null;
+  }
+
+  const handleExport = async () => {
+    setError(null);
+    try {
+      const text = await exportReports.mutateAsync();
+      const target = await dialogSave({
+        defaultPath: "pollis-crash-reports.txt",
+        filters: [{ name: "Text", extensions: ["txt"] }],
+      });
+      if (!target) {
+        return;
+      }
+      await writeFile(target, new TextEncoder().encode(text));
+      acknowledge.mutate();
+    } catch (err) {
+      setError(errorMessage(err, "Failed to export crash reports"));
+    }
+  };
+
+  return (
+    <div
+      data-testid="crash-report-banner"
+      role="alert"
+      className="flex items-center gap-3 px-4 py-2 bg-surface-raised border-b border-line"
+    >
+      <AlertTriangle size={16} aria-hidden="true" className="text-accent shrink-0" />
+      <div className="flex-1 min-w-0 text-xs font-mono">
+        <span className="text-accent font-semibold">
+          Pollis quit unexpectedly.
+        </span>
+        <span className="text-dim">
+          {" "}A crash report was saved on this device (no message content).
+          Export it to attach to a bug report.
+        </span>
+        {error && <span style={{ color: "var(--c-danger)" }}>{" "}{error}</span>}
+      </div>
+      <Button
+        size="sm"
+        variant="primary"
+        disabled={exportReports.isPending}
+        onClick={() => {
+          void handleExport();
+        }}
+      >
+        Export report
+      </Button>
+      <button
+        type="button"
+        onClick={() => acknowledge.mutate()}
+        aria-label="Dismiss crash notice"
+        className="icon-btn-sm shrink-0 text-dim"
+      >
+        <X size={14} aria-hidden="true" />
+      </button>
+    </div>
+  );
+};
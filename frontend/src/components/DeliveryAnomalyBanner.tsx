@@ -0,0 +1,74 @@
+import React, { useState } from "react";
+import { ShieldAlert, X } from "lucide-react";
+import {
+  useAcknowledgeDeliveryCanaryAlert,
+  useDeliveryCanaryAlerts,
+  useDeliveryCanaryReport,
+} from "../hooks/queries/useDeliveryCanary";
+import { formatTimeOfDay } from "../utils/format";
+
+/// "The server isn't delivering messages as sent" notice, shown when this
+/// device's own envelopes come back dropped, replayed, altered or late more
+/// often than the threshold (pollis-core/src/commands/delivery_canary.rs).
+///
+/// The evidence list is the anomalies themselves, for a bug report. Message
+/// contents are end-to-end encrypted either way; this is about the server
+/// misbehaving, not reading. Dismissing hides it until the next anomaly.
+export const DeliveryAnomalyBanner: React.FC = () => {
+  useDeliveryCanaryAlerts();
+  const { data: report } = useDeliveryCanaryReport();
+  const acknowledge = useAcknowledgeDeliveryCanaryAlert();
+  const [showEvidence, setShowEvidence] = useState(false);
+
+  if (!report?.alert) {
+    return null;
+  }
+
+  return (
+    <div
+      data-testid="delivery-anomaly-banner"
+      role="alert"
+      className="flex flex-col gap-1 px-4 py-2 bg-surface-raised border-b border-line"
+    >
+      <div className="flex items-center gap-3">
+        <ShieldAlert size={16} aria-hidden="true" className="text-accent shrink-0" />
+        <div className="flex-1 min-w-0 text-xs font-mono">
+          <span className="text-accent font-semibold">
+            Message delivery looks tampered with.
+          </span>
+          <span className="text-dim">
+            {" "}{Math.round(report.anomaly_rate * 100)}% of the {report.checked} messages you sent
+            came back wrong ({report.dropped} dropped, {report.duplicated} replayed,{" "}
+            {report.modified} altered, {report.delayed} held up).
+          </span>
+        </div>
+        <button
+          type="button"
+          data-testid="delivery-anomaly-evidence-toggle"
+          onClick={() => setShowEvidence((v) => !v)}
+          className="text-xs font-mono cursor-pointer text-dim hover:text-muted"
+        >
+          {showEvidence ? "hide evidence" : "evidence"}
+        </button>
+        <button
+          type="button"
+          onClick={() => acknowledge.mutate()}
+          aria-label="Dismiss delivery notice"
+          className="icon-btn-sm shrink-0 text-dim"
+        >
+          <X size={14} aria-hidden="true" />
+        </button>
+      </div>
+      {showEvidence && (
+        <ul data-testid="delivery-anomaly-evidence" className="flex flex-col gap-0.5 pl-7 text-xs font-mono text-muted">
+          {report.evidence.map((a) => (
+            <li key={`${a.kind}:${a.envelope_id}:${a.at}`} className="select-text">
+              <span className="text-dim">{formatTimeOfDay(a.at * 1000)}</span> {a.kind} {a.envelope_id} in{" "}
+              {a.conversation_id} — {a.detail}
+            </li>
+          ))}
+        </ul>
+      )}
+    </div>
+  );
+};
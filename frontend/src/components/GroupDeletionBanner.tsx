@@ -0,0 +1,122 @@
+import React, { useState } from "react";
+import { useNavigate } from "@tanstack/react-router";
+import { Trash2 } from "lucide-react";
+import { observer } from "mobx-react-lite";
+import { appStore } from "../stores/appStore";
+import {
+  useCancelGroupDeletion,
+  useForgetGroupHistory,
+  useUserGroupsWithChannels,
+} from "../hooks/queries/useGroups";
+import { errorMessage } from "../utils/errorMessage";
+import { formatShortDateTime } from "../utils/format";
+
+interface GroupDeletionBannerProps {
+  groupId: string;
+  channelId: string;
+}
+
+// "This group will be deleted" notice above a channel while the owner's
+// scheduled deletion is in its grace period (migration 000023).
+//
+// Each member decides what happens to their own copy: it stays on this device
+// unless they delete it here, and admins may export the channel first when the
+// group allows exports. The owner can cancel. Not dismissable — it goes away
+// with the deletion (or the group).
+export const GroupDeletionBanner: React.FC<GroupDeletionBannerProps> = observer(({ groupId, channelId }) => {
+  const navigate = useNavigate();
+  const { currentUser } = appStore;
+  const { data: groupsWithChannels } = useUserGroupsWithChannels();
+  const cancelDeletion = useCancelGroupDeletion();
+  const forgetHistory = useForgetGroupHistory();
+  const [forgotten, setForgotten] = useState<number | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  const group = groupsWithChannels?.find((g) => g.id === groupId);
+  if (!group?.deletion_purge_after) {
+    return null;
+  }
+  const isOwner = !!currentUser && group.created_by === currentUser.id;
+  const canExport = group.current_user_role === "admin" && group.allow_export;
+  // The DS stamps SQLite `datetime()` values: UTC, without the zone.
+  const purgeAt = formatShortDateTime(`${group.deletion_purge_after.replace(" ", "T")}Z`);
+
+  const handleForget = async () => {
+    setError(null);
+    try {
+      setForgotten(await forgetHistory.mutateAsync({ groupId }));
+    } catch (err) {
+      setError(errorMessage(err, "Failed to delete messages"));
+    }
+  };
+
+  const handleCancel = async () => {
+    setError(null);
+    try {
+      await cancelDeletion.mutateAsync({ groupId });
+    } catch (err) {
+      setError(errorMessage(err, "Failed to cancel deletion"));
+    }
+  };
+
+  const action = "font-mono cursor-pointer text-dim hover:text-muted disabled:opacity-50";
+
+  return (
+    <div
+      data-testid="group-deletion-banner"
+      role="status"
+      className="flex items-center gap-3 px-4 py-2 bg-surface-raised border-b border-line"
+    >
+      <Trash2 size={16} aria-hidden="true" className="text-accent shrink-0" />
+      <div className="flex-1 min-w-0 text-xs font-mono">
+        <span className="text-accent font-semibold">
+          {group.name} will be deleted around {purgeAt}.
+        </span>
+        <span className="text-dim">
+          {" "}
+          {forgotten === null
+            ? "Your copy of its messages stays on this device unless you delete it."
+            : `Deleted ${forgotten} message${forgotten === 1 ? "" : "s"} from this device.`}
+        </span>
+        {error && <span className="text-muted">{" "}{error}</span>}
+      </div>
+      {canExport && (
+        <button
+          type="button"
+          data-testid="group-deletion-export"
+          onClick={() =>
+            navigate({
+              to: "/groups/$groupId/channels/$channelId/rename",
+              params: { groupId, channelId },
+            })
+          }
+          className={action}
+        >
+          export
+        </button>
+      )}
+      {forgotten === null && (
+        <button
+          type="button"
+          data-testid="group-deletion-forget"
+          onClick={handleForget}
+          disabled={forgetHistory.isPending}
+          className={action}
+        >
+          delete my copy
+        </button>
+      )}
+      {isOwner && (
+        <button
+          type="button"
+          data-testid="group-deletion-cancel"
+          onClick={handleCancel}
+          disabled={cancelDeletion.isPending}
+          className={action}
+        >
+          cancel deletion
+        </button>
+      )}
+    </div>
+  );
+});
@@ -0,0 +1,158 @@
+import React, { useState } from "react";
+import {
+  useCreateGroupWebhook,
+  useDeleteGroupWebhook,
+  useGroupWebhooks,
+  type WebhookEvent,
+} from "../hooks/queries/useGroups";
+import { errorMessage } from "../utils/errorMessage";
+import { TextInput } from "./ui/TextInput";
+import { Checkbox } from "./ui/Checkbox";
+import { Button } from "./ui/Button";
+
+const EVENT_LABELS: Record<WebhookEvent, string> = {
+  message_count: "Message count (batched, no content)",
+  member_joined: "Member joined",
+  channel_created: "Channel created",
+};
+
+const ALL_EVENTS = Object.keys(EVENT_LABELS) as WebhookEvent[];
+
+interface GroupWebhooksProps {
+  groupId: string;
+}
+
+// Admin-only list + inline form for outbound group webhooks. Payloads are
+// metadata only (ids, counts, timestamps) — never message content. The
+// signing secret is shown once, right after creation.
+export const GroupWebhooks: React.FC<GroupWebhooksProps> = ({ groupId }) => {
+  const { data: webhooks } = useGroupWebhooks(groupId);
+  const createWebhook = useCreateGroupWebhook();
+  const deleteWebhook = useDeleteGroupWebhook();
+
+  const [url, setUrl] = useState("");
+  const [events, setEvents] = useState<WebhookEvent[]>(ALL_EVENTS);
+  const [secret, setSecret] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  const toggleEvent = (event: WebhookEvent, checked: boolean) => {
+    setEvents((prev) => (checked ? [...prev, event] : prev.filter((e) => e !== event)));
+  };
+
+  const handleCreate = async () => {
+    setError(null);
+    setSecret(null);
+    if (!url.trim()) {
+      setError("URL is required");
+      return;
+    }
+    if (events.length === 0) {
+      setError("Pick at least one event");
+      return;
+    }
+    try {
+      const created = await createWebhook.mutateAsync({ groupId, url: url.trim(), events });
+      setSecret(created.secret);
+      setUrl("");
+    } catch (err) {
+      setError(errorMessage(err, "Failed to create webhook"));
+    }
+  };
+
+  return (
+    <div data-testid="group-webhooks" className="w-full max-w-md flex flex-col gap-4 mt-8">
+      <div>
+        <p className="text-xs font-mono" style={{ color: "var(--c-text)" }}>Webhooks</p>
+        <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+          Signed HTTPS notifications for group activity. Payloads never include message content.
+        </p>
+      </div>
+
+      {webhooks && webhooks.length > 0 && (
+        <ul data-testid="group-webhooks-list" className="flex flex-col gap-2">
+          {webhooks.map((hook) => (
+            <li key={hook.id} className="flex items-start justify-between gap-3">
+              <div className="min-w-0">
+                <p className="text-xs font-mono truncate" style={{ color: "var(--c-text)" }}>{hook.url}</p>
+                <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                  {hook.events.join(", ")}
+                  {hook.last_status != null && ` · last ${hook.last_status}`}
+                </p>
+                {hook.failure_count > 0 && (
+                  <p className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+                    {hook.failure_count} failed deliver{hook.failure_count === 1 ? "y" : "ies"}
+                  </p>
+                )}
+              </div>
+              <Button
+                data-testid={`group-webhook-delete-${hook.id}`}
+                variant="danger"
+                size="xs"
+                isLoading={deleteWebhook.isPending && deleteWebhook.variables?.webhookId === hook.id}
+                loadingText="Removing…"
+                onClick={() => {
+                  setError(null);
+                  deleteWebhook.mutate(
+                    { webhookId: hook.id, groupId },
+                    { onError: (err) => setError(errorMessage(err, "Failed to delete webhook")) },
+                  );
+                }}
+              >
+                Remove
+              </Button>
+            </li>
+          ))}
+        </ul>
+      )}
+
+      <TextInput
+        label="Endpoint URL"
+        value={url}
+        onChange={setUrl}
+        placeholder="https://example.com/pollis-hook"
+        disabled={createWebhook.isPending}
+        id="group-webhook-url"
+        data-testid="group-webhook-url"
+      />
+      <div className="flex flex-col gap-1">
+        {ALL_EVENTS.map((event) => (
+          <Checkbox
+            key={event}
+            data-testid={`group-webhook-event-${event}`}
+            label={EVENT_LABELS[event]}
+            checked={events.includes(event)}
+            disabled={createWebhook.isPending}
+            onChange={(checked) => toggleEvent(event, checked)}
+          />
+        ))}
+      </div>
+
+      {secret && (
+        <div data-testid="group-webhook-secret" className="flex flex-col gap-1">
+          <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+            Signing secret — copy it now, it won't be shown again:
+          </p>
+          <code className="text-xs font-mono break-all select-all" style={{ color: "var(--c-text)" }}>
+            {secret}
+          </code>
+        </div>
+      )}
+
+      {error && (
+        <p data-testid="group-webhook-error" className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+          {error}
+        </p>
+      )}
+
+      <Button
+        data-testid="group-webhook-create"
+        variant="secondary"
+        isLoading={createWebhook.isPending}
+        loadingText="Adding…"
+        onClick={handleCreate}
+      >
+        Add webhook
+      </Button>
+    </div>
+  );
+};
@@ -6,6 +6,9 @@ import { TitleBar } from "./TitleBar";
 import { WindowResizeEdges } from "./WindowResizeEdges";
 import { BreadcrumbNav } from "./BreadcrumbNav";
 import { MigrationBanner } from "../MigrationBanner";
+import { CrashReportBanner } from "../CrashReportBanner";
+import { MaintenanceBanner } from "../MaintenanceBanner";
+import { DeliveryAnomalyBanner } from "../DeliveryAnomalyBanner";
 import { Sidebar } from "./Sidebar";
 import { StatusBarSummary } from "./StatusBarSummary";
 import { VoiceBar } from "../Voice/VoiceBar";
@@ -25,14 +28,16 @@ import { isDropTargetActive } from "../../stores/dropTargetStore";
 import { useUserGroupsWithChannels } from "../../hooks/queries/useGroups";
 import { useLiveKitRealtime } from "../../hooks/useLiveKitRealtime";
 import { useBadge } from "../../hooks/useBadge";
-import { AlertTriangle, Download, Mail, Phone, X } from "lucide-react";
+import { AlarmClock, AlertTriangle, Download, Mail, Phone, X } from "lucide-react";
 import { startUpdatePolling, stopUpdatePolling } from "../../services/updatePoller";
 import { loadDeviceCallRingtone } from "../../utils/notify";
 import { logIgnored } from "../../utils/log";
 import { usePreferences } from "../../hooks/queries/usePreferences";
+import { useReminderScheduler } from "../../hooks/queries/useReminders";
 import { voiceSession } from "../../voice";
 import { userIdFromVoiceIdentity } from "../../voice/identity";
 import { useGlobalShortcut } from "../../keyboard";
+import { retryCryptoObligations, unmetObligations, useCryptoObligations } from "../../utils/cryptoObligations";
 import type { RouterContext } from "../../types/router";
 
 /**
@@ -44,6 +49,8 @@ const SIDEBAR_DEFAULT_LS_KEY = "pollis.sidebar_open_by_default";
 
 export const AppShell: React.FC = observer(() => {
   const [isSyncing, setIsSyncing] = useState(false);
+  const cryptoObligations = useCryptoObligations();
+  const unmetCryptoObligations = unmetObligations(cryptoObligations);
   const [isSearchOpen, setIsSearchOpen] = useState(false);
   const [isDragOver, setIsDragOver] = useState(false);
   // Sidebar visibility — initial value read synchronously from
@@ -293,6 +300,9 @@ export const AppShell: React.FC = observer(() => {
   // Sync unread count to OS dock/taskbar badge
   useBadge();
 
+  // Fire message reminders as they come due (one timer, not a poll).
+  useReminderScheduler();
+
   // Sync groups+channels into the store once loaded
   useEffect(() => {
     if (!groupsWithChannels) {
@@ -363,6 +373,21 @@ export const AppShell: React.FC = observer(() => {
     },
     { enabled: !!activeVoiceChannelId },
   );
+  // Push-to-talk: held, not toggled. In-app only — the key works while a
+  // Pollis window has focus, and blur counts as letting go.
+  const pushToTalk = prefsQuery.data?.voice_input_mode === "push_to_talk";
+  useGlobalShortcut(
+    "voice.pushToTalk",
+    () => {
+      void voiceSession.setPushToTalk(true);
+    },
+    {
+      enabled: !!activeVoiceChannelId && pushToTalk,
+      onRelease: () => {
+        void voiceSession.setPushToTalk(false);
+      },
+    },
+  );
   useGlobalShortcut(
     "voice.leave",
     () => {
@@ -548,6 +573,15 @@ export const AppShell: React.FC = observer(() => {
       {/* End-of-life nudge — only renders in the legacy Electron build */}
       <MigrationBanner />
 
+      {/* "Pollis quit unexpectedly" — only after a crash wrote a report */}
+      <CrashReportBanner />
+
+      {/* Server maintenance — only while the DS is refusing writes */}
+      <MaintenanceBanner />
+
+      {/* Delivery canaries — only once our own envelopes come back wrong too often */}
+      <DeliveryAnomalyBanner />
+
       {/* Main content — sidebar + matched child route. The screen-share
           viewer mounts INSIDE this region so the TitleBar (drag handle),
           BreadcrumbNav, VoiceBar, and bottom status bar all stay visible
@@ -762,21 +796,66 @@ export const AppShell: React.FC = observer(() => {
             className="text-xs font-mono status-bar-blink flex items-center gap-1 cursor-pointer"
             style={{ color: barInk, background: "none", border: "none", padding: 0 }}
             onClick={() => {
-              router.navigate({ to: "/dms/$conversationId", params: { conversationId: statusBarAlert.roomId } });
+              const reminder = statusBarAlert.reminder;
+              if (reminder) {
+                appStore.setFocusMessage({ conversationId: statusBarAlert.roomId, messageId: reminder.messageId });
+              }
+              if (reminder?.groupId) {
+                router.navigate({
+                  to: "/groups/$groupId/channels/$channelId",
+                  params: { groupId: reminder.groupId, channelId: statusBarAlert.roomId },
+                });
+              } else {
+                router.navigate({ to: "/dms/$conversationId", params: { conversationId: statusBarAlert.roomId } });
+              }
               setStatusBarAlert(null);
             }}
           >
-            <Mail className="w-4 h-4" />: @{statusBarAlert.senderUsername}
+            {statusBarAlert.reminder ? (
+              <>
+                <AlarmClock className="w-4 h-4" />: reminder
+              </>
+            ) : (
+              <>
+                <Mail className="w-4 h-4" />: @{statusBarAlert.senderUsername}
+              </>
+            )}
           </button>
-        ) : isSyncing ? (
+        ) : isSyncing || cryptoObligations.running ? (
           <div
             data-testid="status-bar-syncing"
             className="flex items-center gap-1.5 text-xs font-mono pointer-events-none"
             style={{ color: barInk }}
           >
-            <span>syncing…</span>
+            <span>
+              syncing…
+              {cryptoObligations.running && cryptoObligations.obligations.length > 0 && (
+                <>
+                  {" "}
+                  {cryptoObligations.obligations.filter((o) => o.status !== "pending" && o.status !== "running").length}
+                  /{cryptoObligations.obligations.length}
+                </>
+              )}
+            </span>
             <LoadingSpinner size="sm" />
           </div>
+        ) : currentUser && unmetCryptoObligations.length > 0 ? (
+          // Crypto obligations the sweep couldn't finish (e.g. a group whose
+          // catch-up failed). They stay unmet until retried here.
+          <button
+            data-testid="status-bar-crypto-retry"
+            className="text-xs font-mono flex items-center gap-1 cursor-pointer"
+            style={{ color: barInk, background: "none", border: "none", padding: 0 }}
+            title={unmetCryptoObligations.map((o) => `${o.id}: ${o.error ?? o.status}`).join("\n")}
+            onClick={() => {
+              retryCryptoObligations(currentUser.id).catch((err) => {
+                console.warn('[mls] retry_crypto_obligations failed:', err);
+              });
+            }}
+          >
+            <AlertTriangle className="w-4 h-4" />
+            {unmetCryptoObligations.length} sync step{unmetCryptoObligations.length === 1 ? "" : "s"} failed · retry
+          </button>
         ) : null}
         </div>
         </div>
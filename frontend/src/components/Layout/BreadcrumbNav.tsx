@@ -74,6 +74,8 @@ function useBreadcrumbTrail(): Segment[] {
           out.push({ label: "Invite Member", to: `/groups/${groupId}/invite` });
         } else if (pathname.endsWith("/leave")) {
           out.push({ label: "Leave Group", to: `/groups/${groupId}/leave` });
+        } else if (pathname.endsWith("/delete")) {
+          out.push({ label: "Delete Group", to: `/groups/${groupId}/delete` });
         } else if (pathname.endsWith("/members")) {
           out.push({ label: "Members", to: `/groups/${groupId}/members` });
         } else if (pathname.includes("/members/") && pathname.endsWith("/kick")) {
@@ -8,14 +8,22 @@ import { appStore } from "../../stores/appStore";
 import { MessageList } from "../Message/MessageList";
 import { ReplyPreview } from "../Message/ReplyPreview";
 import { MessageQueue } from "../Message/MessageQueue";
+import { PinnedMessages } from "../Message/PinnedMessages";
+import { TimelineFilters } from "../Message/TimelineFilters";
+import { ActivitySparkline } from "../Message/ActivitySparkline";
+import { GroupDeletionBanner } from "../GroupDeletionBanner";
 import { ChatInput, type Attachment, type ChatInputHandle } from "../ui/ChatInput";
 import { LoadingSpinner } from "../ui/LoaderSpinner";
 import { Button } from "../ui/Button";
 import { useMessages, useSendMessage, messageQueryKeys, useDeleteMessage, useEditMessage, useAcceptDMRequest, useBlockUser } from "../../hooks/queries";
+import { useFilteredMessages, type MessageFilter } from "../../hooks/queries/useMessages";
 import { transformChannelMessage, type RawChannelMessage } from "../../hooks/queries/useMessages";
-import { useGroupMembers, useDeleteChannel } from "../../hooks/queries/useGroups";
+import { useGroupEvents, useGroupMembers, useDeleteChannel } from "../../hooks/queries/useGroups";
+import { usePinnedMessageIds, useSetPinned } from "../../hooks/queries/usePins";
 import type { Message, MessageAttachment } from "../../types";
 import { blurhashFromUrl } from "../../utils/imageProcessing";
+import { executeSlashCommand } from "../../utils/slashCommands";
+import { isMaintenanceError, waitForWritable } from "../../utils/maintenance";
 import { useTypingPublisher } from "../../hooks/useTypingPublisher";
 import { TypingIndicator } from "../TypingIndicator";
 
@@ -66,6 +74,8 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
     currentUser,
     pendingDeleteChannelId,
     setPendingDeleteChannelId,
+    focusMessage,
+    setFocusMessage,
   } = appStore;
   const acceptDmRequestMutation = useAcceptDMRequest();
   const blockUserMutation = useBlockUser();
@@ -79,11 +89,29 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
     () => new Set(groupMembers.filter((m) => m.role === "admin").map((m) => m.user_id)),
     [groupMembers],
   );
+  // Timeline notices for the open channel: the group's joins and departures,
+  // plus this channel's own creation (not its siblings').
+  const { data: allGroupEvents } = useGroupEvents(
+    selectedChannelId ? selectedGroupId ?? null : null,
+  );
+  const channelEvents = useMemo(
+    () =>
+      allGroupEvents?.filter(
+        (e) => e.kind !== "channel_created" || e.subject_id === selectedChannelId,
+      ),
+    [allGroupEvents, selectedChannelId],
+  );
   // Viewer is an admin in this channel's group — gates the moderator
   // delete affordance on other members' messages.
   const viewerIsAdmin =
     !!selectedGroupId && !!currentUser && adminUserIds.has(currentUser.id);
 
+  // Pins are keyed by the conversation the message lives in: the channel
+  // id for channels, the DM id for DMs.
+  const pinConversationId = selectedChannelId ?? selectedConversationId ?? null;
+  const pinnedMessageIds = usePinnedMessageIds(pinConversationId);
+  const setPinned = useSetPinned();
+
   const chatInputRef = useRef<ChatInputHandle>(null);
 
   // For channels the LiveKit room is the parent group's MLS group id; for
@@ -121,6 +149,24 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
   const [loadingMore, setLoadingMore] = useState(false);
   const [pageCursor, setPageCursor] = useState<PageCursor | null>(null);
 
+  // Timeline filters (media / links / unread / one member). While any is set
+  // the list shows the filtered local read instead of the live timeline.
+  const timelineId = selectedChannelId ?? selectedConversationId ?? null;
+  const [timelineFilter, setTimelineFilter] = useState<MessageFilter>({});
+  const filterActive = !!(
+    timelineFilter.media || timelineFilter.links || timelineFilter.unread || timelineFilter.sender_id
+  );
+  const filtered = useFilteredMessages(timelineId, filterActive ? timelineFilter : null);
+  const openedUnread = timelineId ? appStore.openedUnread[timelineId] ?? 0 : 0;
+  useEffect(() => {
+    setTimelineFilter({});
+    return () => {
+      if (timelineId) {
+        appStore.forgetOpenedUnread(timelineId);
+      }
+    };
+  }, [timelineId]);
+
   // Reset pagination and edit state when the selected channel/conversation changes.
   useEffect(() => {
     setOlderMessages([]);
@@ -198,6 +244,17 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
     return deduped.sort((a, b) => a.created_at - b.created_at);
   }, [olderMessages, messages]);
 
+  // Who has spoken in the loaded timeline, for the "from" filter.
+  const timelineSenders = useMemo(() => {
+    const names = new Map<string, string>();
+    for (const m of allMessages) {
+      if (!names.has(m.sender_id)) {
+        names.set(m.sender_id, m.sender_username ?? m.sender_id);
+      }
+    }
+    return [...names].map(([id, name]) => ({ id, name }));
+  }, [allMessages]);
+
   const loadMore = async () => {
     if (!pageCursor || loadingMore || !currentUser) {
       return;
@@ -284,6 +341,22 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
     setPendingDeleteId(messageId);
   };
 
+  const handlePin = async (messageId: string) => {
+    if (!pinConversationId || !currentUser) {
+      return;
+    }
+    try {
+      await setPinned.mutateAsync({
+        conversationId: pinConversationId,
+        messageId,
+        userId: currentUser.id,
+        pinned: !pinnedMessageIds.has(messageId),
+      });
+    } catch (err) {
+      console.error("Failed to update pin:", err);
+    }
+  };
+
   const handleSaveEdit = async () => {
     const trimmed = editDraftValue.trim();
     if (!trimmed || !editingMessage) {
@@ -330,6 +403,20 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
     }
   };
 
+  const handleCommand = async (line: string): Promise<string> => {
+    if (!currentUser) {
+      throw new Error("No current user");
+    }
+    return executeSlashCommand({
+      userId: currentUser.id,
+      groupId: selectedChannelId ? selectedGroupId ?? null : null,
+      channelId: selectedChannelId ?? null,
+      conversationId: selectedChannelId ? null : selectedConversationId ?? null,
+      replyToMessageId: replyToMessageId ?? null,
+      queryClient,
+    }, line);
+  };
+
   const handleSend = async (text: string, attachments: Attachment[]) => {
     if (!text.trim() && attachments.length === 0) {
       return;
@@ -408,6 +495,9 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
               path: att.path,
               filename: att.name,
               contentType: att.mimeType,
+              // Channel uploads follow the group's storage target; DMs use the default.
+              groupId: selectedChannelId ? selectedGroupId ?? null : null,
+              dmChannelId: selectedChannelId ? null : selectedConversationId ?? null,
             })
           )
         );
@@ -434,13 +524,27 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
         content = JSON.stringify(envelope);
       }
 
-      await sendMessageMutation.mutateAsync({
-        channelId: selectedChannelId || "",
-        conversationId: selectedConversationId || "",
-        content,
-        replyToMessageId: replyToMessageId ?? undefined,
-        optimisticId,
-      });
+      // During server maintenance the stub stays 'sending' and the send is
+      // held until the window closes, then tried again.
+      let refused = false;
+      while (true) {
+        await waitForWritable(refused);
+        try {
+          await sendMessageMutation.mutateAsync({
+            channelId: selectedChannelId || "",
+            conversationId: selectedConversationId || "",
+            content,
+            replyToMessageId: replyToMessageId ?? undefined,
+            optimisticId,
+          });
+          break;
+        } catch (error) {
+          if (!isMaintenanceError(error)) {
+            throw error;
+          }
+          refused = true;
+        }
+      }
     } catch (error) {
       // Mark the optimistic stub as failed so the user can see it.
       queryClient.setQueryData<MessagesQueryData>(queryKey, (old) => {
@@ -482,14 +586,36 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
       className="flex-1 flex flex-col overflow-hidden min-w-0"
       style={{ background: 'var(--c-bg)' }}
     >
+      {selectedChannelId && selectedGroupId && (
+        <GroupDeletionBanner groupId={selectedGroupId} channelId={selectedChannelId} />
+      )}
+      {pinConversationId && currentUser && (
+        <PinnedMessages
+          conversationId={pinConversationId}
+          currentUserId={currentUser.id}
+          viewerIsAdmin={viewerIsAdmin}
+        />
+      )}
+      <TimelineFilters
+        filter={timelineFilter}
+        onChange={setTimelineFilter}
+        openedUnread={openedUnread}
+        senders={timelineSenders}
+        trailing={
+          <ActivitySparkline
+            channelId={selectedChannelId ?? null}
+            conversationId={selectedChannelId ? null : selectedConversationId ?? null}
+          />
+        }
+      />
       <div className="flex-1 flex flex-col overflow-hidden min-h-0">
-        {messagesLoading ? (
+        {messagesLoading || (filterActive && filtered.isLoading) ? (
           <div className="flex-1 flex items-center justify-center">
             <LoadingSpinner size="base" />
           </div>
         ) : (
           <MessageList
-            messages={allMessages}
+            messages={filterActive ? filtered.messages : allMessages}
             // MLS group id for the open conversation. Groups: selectedGroupId.
             // DMs: selectedConversationId (the dm_channel_id IS the MLS group
             // id for that DM). Either way, this is what RosterChanged events
@@ -499,6 +625,7 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
             // has a member list; DMs are 1:1 so banner names there fall
             // back to user_id (no membership churn anyway).
             groupIdForNames={selectedGroupId ?? null}
+            groupEvents={selectedChannelId && !filterActive ? channelEvents : undefined}
             adminUserIds={selectedGroupId ? adminUserIds : undefined}
             viewerIsAdmin={viewerIsAdmin}
             onReply={(id) => {
@@ -509,13 +636,22 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
             }}
             onEdit={handleEdit}
             onDelete={handleDelete}
+            onPin={handlePin}
+            pinnedMessageIds={pinnedMessageIds}
+            // A reminder's jump link, once this is the conversation it names.
+            focusMessageId={
+              focusMessage && focusMessage.conversationId === (selectedChannelId ?? selectedConversationId)
+                ? focusMessage.messageId
+                : null
+            }
+            onFocusDone={() => setFocusMessage(null)}
             // TODO: scroll-to-message not yet implemented; prop left unwired
             getAuthorUsername={(authorId, message) =>
               message?.sender_username || (authorId === currentUser?.id ? (currentUser?.username ?? authorId) : authorId)
             }
-            hasMore={!!pageCursor}
-            isFetchingMore={loadingMore}
-            onLoadMore={loadMore}
+            hasMore={filterActive ? filtered.hasNextPage : !!pageCursor}
+            isFetchingMore={filterActive ? filtered.isFetchingNextPage : loadingMore}
+            onLoadMore={filterActive ? () => void filtered.fetchNextPage() : loadMore}
           />
         )}
       </div>
@@ -714,6 +850,7 @@ export const MainContent: React.FC<MainContentProps> = observer(({ pendingDmRequ
           <ChatInput
             ref={chatInputRef}
             onSend={handleSend}
+            onCommand={handleCommand}
             onValueChange={typing.notify}
             autoFocus
             // @all fans out a notification only in group channels (DMs don't),
@@ -14,9 +14,12 @@ import {
   ShieldAlert,
   Keyboard,
   Download,
+  Pin,
+  Activity,
 } from "lucide-react";
 import { useUserGroupsWithChannels } from "../../hooks/queries/useGroups";
-import { useDMConversations } from "../../hooks/queries/useMessages";
+import { useDMConversations, useMostActiveChannels } from "../../hooks/queries/useMessages";
+import { usePinConversation, useSetSidebarOrder } from "../../hooks/queries/useSidebarOrder";
 import { useVoiceRoomCounts } from "../../hooks/queries/useVoiceParticipants";
 import { usePeerVerifications } from "../../hooks/queries/useUserProfile";
 import { observer } from "mobx-react-lite";
@@ -72,6 +75,40 @@ export const Sidebar: React.FC<SidebarProps> = observer(({ isOpen, onToggle }) =
     return map;
   }, [peerVerifications]);
   const unreadCounts = appStore.unreadCounts;
+  const pinConversation = usePinConversation();
+  const setSidebarOrder = useSetSidebarOrder();
+
+  // Drag-reorder within a section (groups or dms). Pinned rows keep their pin
+  // order and aren't draggable; dropping row A on row B puts A just above B.
+  // The saved order covers both sections, so the untouched one is sent as-is.
+  const [dragging, setDragging] = useState<{ section: "groups" | "dms"; id: string } | null>(null);
+  const dropOn = (section: "groups" | "dms", targetId: string) => {
+    if (!dragging || dragging.section !== section || dragging.id === targetId) {
+      setDragging(null);
+      return;
+    }
+    const groupIds = groupsWithChannels.map((g) => g.id);
+    const dmIds = dmConversations.map((c) => c.id);
+    const ids = section === "groups" ? groupIds : dmIds;
+    const moved = ids.filter((id) => id !== dragging.id);
+    moved.splice(moved.indexOf(targetId), 0, dragging.id);
+    setSidebarOrder.mutate(section === "groups" ? [...moved, ...dmIds] : [...groupIds, ...moved]);
+    setDragging(null);
+  };
+  const dragFor = (section: "groups" | "dms", id: string, pinned: boolean): RowDrag | undefined =>
+    pinned
+      ? undefined
+      : {
+        onDragStart: () => setDragging({ section, id }),
+        onDragEnd: () => setDragging(null),
+        onDrop: () => dropOn(section, id),
+      };
+  const pinFor = (id: string, name: string, pinned: boolean): RowPin => ({
+    pinned,
+    onToggle: () => pinConversation.mutate({ targetId: id, pinned: !pinned }),
+    ariaLabel: pinned ? `Unpin ${name}` : `Pin ${name}`,
+    testId: `sidebar-pin-${id}`,
+  });
 
   // Stable list of voice channel ids across all groups; powers the live
   // "users connected" badge on voice channel rows. Realtime voice events
@@ -85,6 +122,14 @@ export const Sidebar: React.FC<SidebarProps> = observer(({ isOpen, onToggle }) =
   );
   const { data: voiceCounts = {} } = useVoiceRoomCounts(voiceChannelIds);
 
+  // The few busiest conversations of the last 24h (local activity rollup);
+  // their channel rows get a small activity mark.
+  const { data: mostActive } = useMostActiveChannels("24h", 3);
+  const busiestIds = useMemo(
+    () => new Set((mostActive ?? []).filter((c) => c.count > 0).map((c) => c.channel_id)),
+    [mostActive]
+  );
+
   const [collapsedGroups, setCollapsedGroups] = useState<Set<string>>(() => {
     try {
       const raw = localStorage.getItem(COLLAPSED_GROUPS_KEY);
@@ -184,6 +229,8 @@ export const Sidebar: React.FC<SidebarProps> = observer(({ isOpen, onToggle }) =
                   }}
                   label={group.name}
                   badge={isCollapsed && groupUnread > 0 ? groupUnread : null}
+                  pin={pinFor(group.id, group.name, group.pinned)}
+                  drag={dragFor("groups", group.id, group.pinned)}
                 />
                 {!isCollapsed &&
                   group.channels.map((ch) => {
@@ -217,6 +264,17 @@ export const Sidebar: React.FC<SidebarProps> = observer(({ isOpen, onToggle }) =
                         leading={isVoice ? <Volume2 {...iconProps} /> : <Hash {...iconProps} />}
                         label={ch.name}
                         badge={badge}
+                        trailing={
+                          !isVoice && busiestIds.has(ch.id) ? (
+                            <span
+                              data-testid={`sidebar-busy-${ch.id}`}
+                              title="One of your busiest conversations in the last 24h"
+                              className="inline-flex shrink-0 text-dim"
+                            >
+                              <Activity {...iconProps} />
+                            </span>
+                          ) : null
+                        }
                       />
                     );
                   })}
@@ -281,9 +339,11 @@ export const Sidebar: React.FC<SidebarProps> = observer(({ isOpen, onToggle }) =
                     <PresenceDot userId={c.user2_id ?? null} />
                   )
                 }
-                label={c.user2_identifier}
+                label={c.user2_nickname ?? c.user2_identifier}
                 badge={unread > 0 ? unread : null}
                 trailing={trailing}
+                pin={pinFor(c.id, c.user2_nickname ?? c.user2_identifier, !!c.pinned)}
+                drag={dragFor("dms", c.id, !!c.pinned)}
               />
             );
           })}
@@ -378,6 +438,19 @@ interface RowChevron {
   ariaLabel: string;
 }
 
+interface RowPin {
+  pinned: boolean;
+  onToggle: () => void;
+  ariaLabel: string;
+  testId: string;
+}
+
+interface RowDrag {
+  onDragStart: () => void;
+  onDragEnd: () => void;
+  onDrop: () => void;
+}
+
 interface RowProps {
   indent: number;
   isActive: boolean;
@@ -389,13 +462,28 @@ interface RowProps {
   badge?: number | null;
   /** Optional trailing decoration (e.g. shield-check / shield-alert badges) rendered before the unread badge. */
   trailing?: React.ReactNode;
+  /** When provided, renders a pin toggle as a sibling button: always shown while pinned, on hover otherwise. */
+  pin?: RowPin;
+  /** When provided, the row is a drag source and drop target for sidebar reordering. */
+  drag?: RowDrag;
 }
 
-const Row: React.FC<RowProps> = ({ indent, isActive, onClick, leading, chevron, label, badge, trailing }) => {
+const Row: React.FC<RowProps> = ({ indent, isActive, onClick, leading, chevron, label, badge, trailing, pin, drag }) => {
   return (
     <div
       data-active={isActive ? "true" : "false"}
-      className={`sidebar-row flex w-full items-stretch border-l-2 transition-colors ${
+      draggable={drag ? true : undefined}
+      onDragStart={drag ? (e) => {
+        e.dataTransfer.effectAllowed = "move";
+        drag.onDragStart();
+      } : undefined}
+      onDragEnd={drag?.onDragEnd}
+      onDragOver={drag ? (e) => e.preventDefault() : undefined}
+      onDrop={drag ? (e) => {
+        e.preventDefault();
+        drag.onDrop();
+      } : undefined}
+      className={`group sidebar-row flex w-full items-stretch border-l-2 transition-colors ${
         isActive
           ? "bg-hover border-accent text-accent"
           : "bg-transparent border-transparent text-fg hover:bg-hover"
@@ -427,6 +515,24 @@ const Row: React.FC<RowProps> = ({ indent, isActive, onClick, leading, chevron,
         {trailing}
         {badge != null && <UnreadBadge count={badge} />}
       </button>
+      {pin && (
+        <button
+          type="button"
+          tabIndex={-1}
+          onClick={(e) => {
+            e.stopPropagation();
+            pin.onToggle();
+          }}
+          aria-label={pin.ariaLabel}
+          title={pin.ariaLabel}
+          data-testid={pin.testId}
+          className={`inline-flex items-center pr-2.5 cursor-pointer hover:text-accent ${
+            pin.pinned ? "text-accent" : "text-muted opacity-0 group-hover:opacity-100"
+          }`}
+        >
+          <Pin {...iconProps} />
+        </button>
+      )}
     </div>
   );
 };
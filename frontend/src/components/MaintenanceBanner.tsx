@@ -0,0 +1,37 @@
+import React from "react";
+import { Wrench } from "lucide-react";
+import { useMaintenance } from "../utils/maintenance";
+import { formatTimeOfDay } from "../utils/format";
+
+/// "Server maintenance" notice, shown while the Delivery Service refuses
+/// writes (pollis-delivery/src/maintenance.rs).
+///
+/// Reading still works; messages sent meanwhile stay "sending" and go out on
+/// their own once the window closes (see `waitForWritable`). Not dismissable —
+/// it disappears when the window does.
+export const MaintenanceBanner: React.FC = () => {
+  const { enabled, eta, message } = useMaintenance();
+  if (!enabled) {
+    return null;
+  }
+
+  return (
+    <div
+      data-testid="maintenance-banner"
+      role="alert"
+      className="flex items-center gap-3 px-4 py-2 bg-surface-raised border-b border-line"
+    >
+      <Wrench size={16} aria-hidden="true" className="text-accent shrink-0" />
+      <div className="flex-1 min-w-0 text-xs font-mono">
+        <span className="text-accent font-semibold">
+          Server maintenance.
+        </span>
+        <span className="text-dim">
+          {" "}Sending is paused{eta ? ` until about ${formatTimeOfDay(eta * 1000)}` : ""};
+          messages you send now go out when it ends.
+        </span>
+        {message && <span className="text-muted">{" "}{message}</span>}
+      </div>
+    </div>
+  );
+};
@@ -0,0 +1,44 @@
+import React, { useState } from "react";
+import { useChannelActivity, type ActivityRange } from "../../hooks/queries/useMessages";
+
+interface ActivitySparklineProps {
+  channelId: string | null;
+  conversationId: string | null;
+}
+
+const NEXT_RANGE: Record<ActivityRange, ActivityRange> = { "24h": "7d", "7d": "30d", "30d": "24h" };
+const WIDTH = 72;
+const HEIGHT = 12;
+
+// Messages per hour (24h) or per day (7d / 30d) in the open conversation,
+// from the local activity rollup (get_channel_activity). Clicking cycles the
+// range. Renders nothing until there's data.
+export const ActivitySparkline: React.FC<ActivitySparklineProps> = ({ channelId, conversationId }) => {
+  const [range, setRange] = useState<ActivityRange>("24h");
+  const { data } = useChannelActivity(channelId, conversationId, range);
+  if (!data || data.buckets.length < 2) {
+    return null;
+  }
+
+  const max = Math.max(1, ...data.buckets.map((b) => b.count));
+  const step = WIDTH / (data.buckets.length - 1);
+  const points = data.buckets
+    .map((b, i) => `${(i * step).toFixed(1)},${(HEIGHT - 1 - (b.count / max) * (HEIGHT - 2)).toFixed(1)}`)
+    .join(" ");
+
+  return (
+    <button
+      type="button"
+      data-testid="activity-sparkline"
+      onClick={() => setRange(NEXT_RANGE[range])}
+      title={`${data.total} message${data.total === 1 ? "" : "s"} in the last ${range}`}
+      aria-label={`Activity: ${data.total} messages in the last ${range}. Show a different range.`}
+      className="flex items-center gap-1.5 font-mono cursor-pointer text-dim hover:text-muted"
+    >
+      <svg width={WIDTH} height={HEIGHT} viewBox={`0 0 ${WIDTH} ${HEIGHT}`} aria-hidden="true">
+        <polyline points={points} fill="none" stroke="currentColor" strokeWidth={1} strokeLinejoin="round" />
+      </svg>
+      <span>{range}</span>
+    </button>
+  );
+};
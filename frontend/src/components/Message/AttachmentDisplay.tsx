@@ -11,6 +11,8 @@ import { LoadingSpinner } from "../ui/LoaderSpinner";
 import { InlineAudioPlayer } from "../ui/InlineAudioPlayer";
 import { AudioPlayer } from "../ui/AudioPlayer";
 import type { MessageAttachment } from "../../types";
+import { useLowBandwidthMode } from "../../utils/lowBandwidth";
+import { useDownloadPercent } from "../../utils/downloadProgress";
 
 // Co-located: only used by AttachmentDisplay.
 const BlurhashCanvas: React.FC<{ hash: string; width: number; height: number }> = ({
@@ -78,9 +80,15 @@ export const AttachmentDisplay: React.FC<{ attachment: MessageAttachment }> = ({
     attachment.localPreviewUrl ?? null
   );
   const [isLoading, setIsLoading] = useState(false);
+  const downloadPercent = useDownloadPercent(isLoading ? attachment.content_hash : undefined);
   const [error, setError] = useState<string | null>(null);
   const [viewerOpen, setViewerOpen] = useState(false);
   const [downloadStatus, setDownloadStatus] = useState<"idle" | "downloading" | "done">("idle");
+  // Low-bandwidth mode: images and audio wait for an explicit click before
+  // fetching (video already loads on open).
+  const lowBandwidth = useLowBandwidthMode();
+  const [loadRequested, setLoadRequested] = useState(false);
+  const isDeferred = lowBandwidth && !loadRequested && !downloadUrl && !isPending;
   // Video-specific state.
   const [duration, setDuration] = useState<number | null>(null);
   const [poster, setPoster] = useState<string | null>(null);
@@ -171,6 +179,9 @@ export const AttachmentDisplay: React.FC<{ attachment: MessageAttachment }> = ({
     if ((!isImage && !isAudio) || isPending || downloadUrl) {
       return;
     }
+    if (lowBandwidth && !loadRequested) {
+      return;
+    }
     // A previously fetched URL rendered and then errored past the retry cap —
     // stop re-fetching, surface the failure instead of spinning.
     if (renderFailuresRef.current > MAX_RENDER_RETRIES) {
@@ -196,7 +207,7 @@ export const AttachmentDisplay: React.FC<{ attachment: MessageAttachment }> = ({
     // Key on every input the guard reads, not just object_key. Previously a
     // confirmed attachment (isPending flips false) or a downloadUrl reset back
     // to null (failed load) never re-fired this effect, so the media never retried.
-  }, [isImage, isAudio, isPending, downloadUrl, attachment.object_key, attachment.content_hash, attachment.content_type]);
+  }, [isImage, isAudio, isPending, downloadUrl, lowBandwidth, loadRequested, attachment.object_key, attachment.content_hash, attachment.content_type]);
 
   // Revoke decrypted blob URLs we created when they're replaced or on unmount.
   // Skip non-blob URLs (e.g. tauri convertFileSrc paths) and skip the
@@ -364,9 +375,15 @@ export const AttachmentDisplay: React.FC<{ attachment: MessageAttachment }> = ({
       <>
         <button
           data-testid={`attachment-${attachment.id}`}
-          onClick={() => { if (downloadUrl) { setViewerOpen(true); } }}
-          disabled={!downloadUrl}
-          aria-label={`View ${attachment.filename}`}
+          onClick={() => {
+            if (downloadUrl) {
+              setViewerOpen(true);
+            } else if (isDeferred) {
+              setLoadRequested(true);
+            }
+          }}
+          disabled={!downloadUrl && !isDeferred}
+          aria-label={isDeferred ? `Load ${attachment.filename}` : `View ${attachment.filename}`}
           title={attachment.filename}
           style={{
             width: 96,
@@ -376,7 +393,7 @@ export const AttachmentDisplay: React.FC<{ attachment: MessageAttachment }> = ({
             border: "none",
             borderRadius: "0.5rem",
             overflow: "hidden",
-            cursor: downloadUrl ? "zoom-in" : "default",
+            cursor: downloadUrl ? "zoom-in" : isDeferred ? "pointer" : "default",
             display: "flex",
             alignItems: "center",
             justifyContent: "center",
@@ -399,13 +416,25 @@ export const AttachmentDisplay: React.FC<{ attachment: MessageAttachment }> = ({
               }}
             />
           ) : attachment.blurhash && attachment.width && attachment.height ? (
-            <div style={{ width: "100%", height: "100%", overflow: "hidden" }}>
+            <div style={{ width: "100%", height: "100%", overflow: "hidden", position: "relative" }}>
               <BlurhashCanvas
                 hash={attachment.blurhash}
                 width={attachment.width}
                 height={attachment.height}
               />
+              {isDeferred && (
+                <span
+                  className="text-xs font-mono"
+                  style={{ position: "absolute", inset: 0, display: "flex", alignItems: "center", justifyContent: "center", color: "var(--c-text)" }}
+                >
+                  [load]
+                </span>
+              )}
             </div>
+          ) : isDeferred ? (
+            <span className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+              [load]
+            </span>
           ) : (
             <span className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
               {error ? "err" : "…"}
@@ -477,9 +506,23 @@ export const AttachmentDisplay: React.FC<{ attachment: MessageAttachment }> = ({
                 borderRadius: 8,
               }}
             >
-              <span className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
-                {isLoading ? "loading…" : error ? error : "…"}
-              </span>
+              {isDeferred ? (
+                <button
+                  type="button"
+                  onClick={() => setLoadRequested(true)}
+                  aria-label={`Load ${attachment.filename}`}
+                  className="text-xs font-mono bg-transparent border-0 p-0 cursor-pointer"
+                  style={{ color: "var(--c-accent)" }}
+                >
+                  [load]
+                </button>
+              ) : (
+                <span className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                  {isLoading
+                    ? (downloadPercent != null ? `loading… ${downloadPercent}%` : "loading…")
+                    : error ? error : "…"}
+                </span>
+              )}
             </div>
           )}
           {renderCaptionBar()}
@@ -1,5 +1,7 @@
 import React, { useCallback, useMemo, useState } from "react";
 import { shellOpen } from "../../bridge";
+import { useLowBandwidthMode } from "../../utils/lowBandwidth";
+import { useLinkChecks } from "../../hooks/queries/useLinks";
 
 // Known limitation (low priority): inline previews only fire when the URL ends in
 // a recognised image/video extension. Sites like Giphy/Tenor/Imgur that serve media
@@ -70,15 +72,33 @@ interface MediaLinkUnfurlProps {
 }
 
 export const MediaLinkUnfurl: React.FC<MediaLinkUnfurlProps> = ({ text }) => {
-  const links = useMemo(() => extractMediaLinks(text), [text]);
+  const candidates = useMemo(() => extractMediaLinks(text), [text]);
   const [hidden, setHidden] = useState<Set<string>>(() => new Set());
+  // Unfurling fetches the media from a third-party host; skip it entirely in
+  // low-bandwidth mode (the link itself still renders in the message text).
+  const lowBandwidth = useLowBandwidthMode();
+  // The link policy decides what may be fetched (https only, previews on) and
+  // hands back the URL with tracking parameters removed — that's what loads.
+  const rawUrls = useMemo(() => candidates.map((l) => l.url), [candidates]);
+  const { data: checks } = useLinkChecks(rawUrls, !lowBandwidth);
+  const links = useMemo(
+    () =>
+      candidates.flatMap((l, i): MediaLink[] => {
+        const check = checks?.[i];
+        if (!check || !check.preview || check.action !== "open") {
+          return [];
+        }
+        return [{ url: check.url, kind: l.kind }];
+      }),
+    [candidates, checks],
+  );
 
   const handleClick = useCallback((url: string) => {
-    shellOpen(ensureProtocol(url));
+    void shellOpen(url);
   }, []);
 
   const visible = links.filter((l) => !hidden.has(l.url));
-  if (visible.length === 0) {
+  if (lowBandwidth || visible.length === 0) {
     return null;
   }
 
@@ -95,7 +115,7 @@ export const MediaLinkUnfurl: React.FC<MediaLinkUnfurlProps> = ({ text }) => {
   return (
     <div data-testid="media-link-unfurl" className="mt-2 flex flex-wrap gap-1">
       {visible.map((link) => {
-        const href = ensureProtocol(link.url);
+        const href = link.url;
         const onError = () =>
           setHidden((prev) => {
             const next = new Set(prev);
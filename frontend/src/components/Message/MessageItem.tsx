@@ -1,5 +1,5 @@
-import React from "react";
-import { Reply, CornerUpLeft, Edit2, Trash2 } from "lucide-react";
+import React, { useCallback, useState } from "react";
+import { Reply, CornerUpLeft, Edit2, Trash2, Languages, Pin, PinOff, AlarmClock } from "lucide-react";
 import { formatTimeOfDay, formatFullTimestamp } from "../../utils/format";
 import { observer } from "mobx-react-lite";
 import { appStore } from "../../stores/appStore";
@@ -7,8 +7,11 @@ import { LinkifiedText } from "../ui/LinkifiedText";
 import { MediaLinkUnfurl } from "./MediaLinkUnfurl";
 import { getUsernameColor, useBackgroundIsLight } from "../../utils/usernameColor";
 import { useSkin } from "../../hooks/queries/usePreferences";
+import { useTranslationBackend, useTranslateMessage } from "../../hooks/queries/useTranslation";
+import { errorMessage } from "../../utils/errorMessage";
 import { AttachmentDisplay } from "./AttachmentDisplay";
 import { MessageAvatar } from "./MessageAvatar";
+import { ReminderMenu } from "./ReminderMenu";
 import type { Message } from "../../types";
 
 interface MessageItemProps {
@@ -28,7 +31,9 @@ interface MessageItemProps {
   onReply?: (messageId: string) => void;
   onEdit?: (messageId: string) => void;
   onDelete?: (messageId: string) => void;
+  /** Toggles the pin; shown when set. `isPinned` picks pin vs unpin. */
   onPin?: (messageId: string) => void;
+  isPinned?: boolean;
   onScrollToReply?: (messageId: string) => void;
 }
 
@@ -47,6 +52,8 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
   onReply,
   onEdit,
   onDelete,
+  onPin,
+  isPinned = false,
   onScrollToReply,
 }) => {
   const { currentUser } = appStore;
@@ -73,6 +80,49 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
 
   const isDeleted = !!message.deleted_at;
 
+  // On-device translation (see useTranslation). The action only shows once a
+  // local backend is configured; the result renders under the body.
+  const translationBackend = useTranslationBackend();
+  const translate = useTranslateMessage();
+  const [translation, setTranslation] = useState<string | null>(null);
+  const canTranslate = !!translationBackend.data && !isDeleted;
+  const handleTranslate = () => {
+    if (translation !== null) {
+      setTranslation(null);
+      return;
+    }
+    translate.mutate(
+      { messageId: message.id, targetLang: null },
+      { onSuccess: (text) => setTranslation(text) },
+    );
+  };
+  const translationBlock = (translation !== null || translate.isError) && (
+    <div
+      data-testid="message-translation"
+      className="mt-0.5 text-xs break-words"
+      style={{
+        color: translate.isError ? "var(--c-danger)" : "var(--c-text-dim)",
+        whiteSpace: "pre-wrap",
+      }}
+    >
+      {translate.isError ? errorMessage(translate.error) : translation}
+    </div>
+  );
+
+  // "Remind me" menu: right-click the row or the toolbar alarm. Only for
+  // messages already in the local store (not still sending).
+  const [reminderOpen, setReminderOpen] = useState(false);
+  const canRemind = !isDeleted && (!message.status || message.status === "sent");
+  const closeReminder = useCallback(() => setReminderOpen(false), []);
+  const handleContextMenu = (e: React.MouseEvent) => {
+    if (!canRemind) {
+      return;
+    }
+    e.preventDefault();
+    setReminderOpen(true);
+  };
+  const reminderMenu = reminderOpen && <ReminderMenu messageId={message.id} onClose={closeReminder} />;
+
   // content_decrypted is undefined when decryption failed (the server returned
   // null). Show [encrypted] in that case rather than an empty row.
   const content = isDeleted ? "[deleted]" : (message.content_decrypted ?? "[encrypted]");
@@ -126,6 +176,7 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
       <div
         data-testid={`message-${message.id}`}
         aria-label={`Message from ${authorUsername}`}
+        onContextMenu={handleContextMenu}
         className="group relative grid grid-cols-[3.5rem_minmax(0,1fr)] gap-x-2 items-start px-4 hover:bg-hover transition-colors duration-75"
         style={{
           paddingTop: isGroupStart ? "var(--msg-header-gap)" : "var(--msg-group-gap)",
@@ -226,6 +277,8 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
             )}
           </div>
 
+          {translationBlock}
+
           {/* Inline previews for media URLs typed in the message body */}
           {!isDeleted && <MediaLinkUnfurl text={content} />}
 
@@ -246,6 +299,39 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
             >
               <Reply size={16} />
             </button>
+            {canTranslate && (
+              <button
+                data-testid="translate-button"
+                onClick={handleTranslate}
+                aria-label={translation !== null ? "Hide translation" : "Translate message"}
+                disabled={translate.isPending}
+                className="p-1 text-[var(--c-text-muted)] hover:text-[var(--c-text-accent)]"
+              >
+                <Languages size={16} />
+              </button>
+            )}
+            {onPin && (
+              <button
+                data-testid="pin-button"
+                onClick={() => onPin(message.id)}
+                aria-label={isPinned ? "Unpin message" : "Pin message"}
+                className="p-1 text-[var(--c-text-muted)] hover:text-[var(--c-text-accent)]"
+              >
+                {isPinned ? <PinOff size={16} /> : <Pin size={16} />}
+              </button>
+            )}
+            {canRemind && (
+              <button
+                data-testid="remind-button"
+                // Keep the menu's click-outside from closing it first, so this toggles.
+                onMouseDown={(e) => e.stopPropagation()}
+                onClick={() => setReminderOpen((open) => !open)}
+                aria-label="Remind me about this message"
+                className="p-1 text-[var(--c-text-muted)] hover:text-[var(--c-text-accent)]"
+              >
+                <AlarmClock size={16} />
+              </button>
+            )}
             {isOwn && onEdit && (
               <button
                 data-testid="edit-button"
@@ -278,6 +364,7 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
             )}
           </div>
         )}
+        {reminderMenu}
       </div>
     );
   }
@@ -286,6 +373,7 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
     <div
       data-testid={`message-${message.id}`}
       aria-label={`Message from ${authorUsername}`}
+      onContextMenu={handleContextMenu}
       className="group relative px-4 py-1 hover:bg-[var(--c-hover)] transition-colors duration-75"
     >
       {/* Reply thread indicator */}
@@ -389,6 +477,39 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
             >
               <Reply size={18} />
             </button>
+            {canTranslate && (
+              <button
+                data-testid="translate-button"
+                onClick={handleTranslate}
+                aria-label={translation !== null ? "Hide translation" : "Translate message"}
+                disabled={translate.isPending}
+                className="opacity-0 group-hover:opacity-100 text-[var(--c-text-muted)] hover:text-[var(--c-text-accent)]"
+              >
+                <Languages size={18} />
+              </button>
+            )}
+            {onPin && (
+              <button
+                data-testid="pin-button"
+                onClick={() => onPin(message.id)}
+                aria-label={isPinned ? "Unpin message" : "Pin message"}
+                className="opacity-0 group-hover:opacity-100 text-[var(--c-text-muted)] hover:text-[var(--c-text-accent)]"
+              >
+                {isPinned ? <PinOff size={18} /> : <Pin size={18} />}
+              </button>
+            )}
+            {canRemind && (
+              <button
+                data-testid="remind-button"
+                // Keep the menu's click-outside from closing it first, so this toggles.
+                onMouseDown={(e) => e.stopPropagation()}
+                onClick={() => setReminderOpen((open) => !open)}
+                aria-label="Remind me about this message"
+                className="opacity-0 group-hover:opacity-100 text-[var(--c-text-muted)] hover:text-[var(--c-text-accent)]"
+              >
+                <AlarmClock size={18} />
+              </button>
+            )}
             {isOwn && onEdit && (
               <button
                 data-testid="edit-button"
@@ -423,6 +544,8 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
         )}
       </div>
 
+      {translationBlock && <div className="pl-20">{translationBlock}</div>}
+
       {/* Inline previews for media URLs typed in the message body */}
       {!isDeleted && <MediaLinkUnfurl text={content} />}
 
@@ -446,6 +569,7 @@ export const MessageItem: React.FC<MessageItemProps> = observer(({
 
       {/* Reactions row — disabled, needs more thought */}
       {/* <MessageReactions messageId={message.id} /> */}
+      {reminderMenu}
     </div>
   );
 });
@@ -1,12 +1,12 @@
-import React, { useEffect, useLayoutEffect, useRef, useMemo } from "react";
+import React, { useEffect, useLayoutEffect, useRef, useMemo, useState } from "react";
 import { MessageItem } from "./MessageItem";
 import { useBlockedUsers } from "../../hooks/queries";
 import { useGroupMembers } from "../../hooks/queries/useGroups";
 import { observer } from "mobx-react-lite";
 import { rosterChangeStore, type RosterBanner } from "../../stores/rosterChangeStore";
 import { formatDayDivider } from "../../utils/format";
-import { useSkin } from "../../hooks/queries/usePreferences";
-import type { Message } from "../../types";
+import { useMutedMembers, useSkin } from "../../hooks/queries/usePreferences";
+import type { GroupEvent, Message } from "../../types";
 
 const toMs = (timestamp: number): number =>
   timestamp < 1e12 ? timestamp * 1000 : timestamp;
@@ -92,6 +92,46 @@ const RosterChangeBanner: React.FC<{
   );
 };
 
+// Persistent timeline notice from the group event log. Same hairline layout as
+// a roster banner, but italic with a leading marker so it reads as the
+// group's history rather than something that just happened.
+const GroupEventNotice: React.FC<{ event: GroupEvent; name: string }> = ({ event, name }) => {
+  let label: string;
+  switch (event.kind) {
+    case "member_joined":
+      label = `${name} joined the group`;
+      break;
+    case "member_left":
+      label = `${name} left the group`;
+      break;
+    case "channel_created":
+      label = `#${name} was created`;
+      break;
+    case "deletion_scheduled":
+      label = `${name} scheduled the group for deletion`;
+      break;
+    case "deletion_cancelled":
+      label = "Group deletion was cancelled";
+      break;
+  }
+  return (
+    <div
+      data-testid={`group-event-${event.id}`}
+      className="flex items-center gap-3 px-4 py-2 select-none"
+    >
+      <div className="flex-1 h-px" style={{ background: "var(--c-border)" }} />
+      <span
+        className="text-xs font-mono italic"
+        style={{ color: "var(--c-text-dim)" }}
+      >
+        {"· "}
+        {label}
+      </span>
+      <div className="flex-1 h-px" style={{ background: "var(--c-border)" }} />
+    </div>
+  );
+};
+
 interface MessageListProps {
   messages: Message[];
   /** MLS group / DM conversation id. When set, inline roster-change
@@ -102,6 +142,10 @@ interface MessageListProps {
   /** Group id for resolving display names in roster banners. Equal to
    *  `conversationId` for top-level group MLS; null for DMs. */
   groupIdForNames?: string | null;
+  /** Group timeline notices for this channel (joins, departures, its own
+   *  creation), interleaved with messages. When set, they replace the
+   *  session-only joined/left roster banners. */
+  groupEvents?: GroupEvent[];
   adminUserIds?: Set<string>;
   /** True when the viewer is an admin in this list's group — enables
    * deleting other members' messages for moderation. */
@@ -110,7 +154,12 @@ interface MessageListProps {
   onEdit?: (messageId: string) => void;
   onDelete?: (messageId: string) => void;
   onPin?: (messageId: string) => void;
+  pinnedMessageIds?: Set<string>;
   onScrollToMessage?: (messageId: string) => void;
+  /** Scroll to this message once loaded, paging back as needed; then
+   *  `onFocusDone` (also called when history runs out without it). */
+  focusMessageId?: string | null;
+  onFocusDone?: () => void;
   getAuthorUsername?: (authorId: string, message?: Message) => string;
   hasMore?: boolean;
   isFetchingMore?: boolean;
@@ -121,12 +170,17 @@ export const MessageList: React.FC<MessageListProps> = observer(({
   messages,
   conversationId,
   groupIdForNames,
+  groupEvents,
   adminUserIds,
   viewerIsAdmin = false,
   onReply,
   onEdit,
   onDelete,
+  onPin,
+  pinnedMessageIds,
   onScrollToMessage,
+  focusMessageId,
+  onFocusDone,
   getAuthorUsername,
   hasMore,
   isFetchingMore,
@@ -141,6 +195,13 @@ export const MessageList: React.FC<MessageListProps> = observer(({
     () => new Set(blockedUsers.map((b) => b.user_id)),
     [blockedUsers],
   );
+  // Members muted in this group collapse to one line; "show" expands a single
+  // message for this view only. The live prefs win once loaded so a toggle
+  // applies at once; before that the read's `sender_muted` flag decides.
+  const { mutedIds, loaded: mutedLoaded } = useMutedMembers(groupIdForNames ?? null);
+  const isMutedSender = (message: Message) =>
+    mutedLoaded ? mutedIds.has(message.sender_id) : !!message.sender_muted;
+  const [revealedIds, setRevealedIds] = useState<Set<string>>(() => new Set());
   // Saved scroll metrics taken just before a load-more fetch begins, used to
   // restore relative scroll position after older messages are prepended.
   const savedScrollRef = useRef<{ scrollTop: number; scrollHeight: number } | null>(null);
@@ -168,13 +229,18 @@ export const MessageList: React.FC<MessageListProps> = observer(({
   // for display-name resolution: groupIdForNames === conversationId for
   // group MLS, so this read is normally cached by the same query the
   // member list page uses.
-  const rosterBanners =
+  const allRosterBanners =
     (conversationId ? rosterChangeStore.byConversation[conversationId] : undefined) ?? [];
+  const rosterBanners = groupEvents
+    ? allRosterBanners.filter(
+        (b) => b.payload.kind !== "joined" && b.payload.kind !== "left",
+      )
+    : allRosterBanners;
   const { data: groupMembers = [] } = useGroupMembers(groupIdForNames ?? null);
   const usernameByUserId = useMemo(() => {
     const map = new Map<string, string>();
     for (const m of groupMembers) {
-      map.set(m.user_id, m.username ?? m.user_id);
+      map.set(m.user_id, m.display_name ?? m.username ?? m.user_id);
     }
     return map;
   }, [groupMembers]);
@@ -185,7 +251,8 @@ export const MessageList: React.FC<MessageListProps> = observer(({
   // carry an `observed_at_ms` set when the realtime event landed.
   type TimelineItem =
     | { kind: "message"; key: string; ts: number; message: Message }
-    | { kind: "banner"; key: string; ts: number; banner: RosterBanner };
+    | { kind: "banner"; key: string; ts: number; banner: RosterBanner }
+    | { kind: "event"; key: string; ts: number; event: GroupEvent };
   const timeline: TimelineItem[] = useMemo(() => {
     const items: TimelineItem[] = sortedMessages.map((message) => ({
       kind: "message",
@@ -201,9 +268,20 @@ export const MessageList: React.FC<MessageListProps> = observer(({
         banner,
       });
     }
+    // While older pages are still unloaded, only show notices inside the
+    // loaded window — otherwise every historical join piles up at the top.
+    const oldestLoaded =
+      hasMore && sortedMessages.length > 0 ? toMs(sortedMessages[0].created_at) : -Infinity;
+    for (const event of groupEvents ?? []) {
+      const ts = new Date(event.occurred_at).getTime();
+      if (ts < oldestLoaded) {
+        continue;
+      }
+      items.push({ kind: "event", key: `event:${event.id}`, ts, event });
+    }
     items.sort((a, b) => a.ts - b.ts);
     return items;
-  }, [sortedMessages, rosterBanners]);
+  }, [sortedMessages, rosterBanners, groupEvents, hasMore]);
 
   // Scroll to bottom when new messages arrive (but not when older pages load).
   useEffect(() => {
@@ -258,6 +336,25 @@ export const MessageList: React.FC<MessageListProps> = observer(({
     return () => container.removeEventListener("scroll", handleScroll);
   }, [hasMore, isFetchingMore, onLoadMore]);
 
+  // Jump to a message (a reminder's link): scroll to it once it's loaded,
+  // pulling older pages until it shows up or history runs out.
+  const onFocusDoneRef = useRef(onFocusDone);
+  onFocusDoneRef.current = onFocusDone;
+  useEffect(() => {
+    if (!focusMessageId) {
+      return;
+    }
+    if (sortedMessages.some((m) => m.id === focusMessageId)) {
+      const el = containerRef.current?.querySelector(`[data-testid="message-${focusMessageId}"]`);
+      el?.scrollIntoView({ behavior: "smooth", block: "center" });
+      onFocusDoneRef.current?.();
+    } else if (hasMore && !isFetchingMore) {
+      onLoadMore?.();
+    } else if (!hasMore && sortedMessages.length > 0) {
+      onFocusDoneRef.current?.();
+    }
+  }, [focusMessageId, sortedMessages, hasMore, isFetchingMore, onLoadMore]);
+
   const scrollToMessage = (messageId: string) => {
     const el = containerRef.current?.querySelector(`[data-testid="message-${messageId}"]`);
     if (el) {
@@ -311,11 +408,20 @@ export const MessageList: React.FC<MessageListProps> = observer(({
         const isGroupStart =
           item.kind === "message" &&
           (prev === null ||
-            prev.kind === "banner" ||
+            prev.kind !== "message" ||
             showDivider ||
             prev.message.sender_id !== item.message.sender_id ||
             item.ts - prev.ts > GROUP_GAP_MS);
 
+        if (item.kind === "event") {
+          const { event } = item;
+          const name =
+            event.kind === "channel_created"
+              ? event.subject_name ?? event.subject_id
+              : usernameByUserId.get(event.subject_id) ?? event.subject_name ?? event.subject_id;
+          return <GroupEventNotice key={item.key} event={event} name={name} />;
+        }
+
         if (item.kind === "banner") {
           return (
             <RosterChangeBanner
@@ -348,6 +454,32 @@ export const MessageList: React.FC<MessageListProps> = observer(({
               </span>
             </div>
           </div>
+        ) : isMutedSender(message) && !revealedIds.has(message.id) ? (
+          <div
+            key={message.id}
+            data-testid={`message-muted-${message.id}`}
+            className="px-4 py-1"
+          >
+            <div className="flex items-start gap-2 min-w-0">
+              <span
+                className="font-mono text-sm"
+                style={{ color: "var(--c-text-muted)" }}
+              >
+                [muted]
+              </span>
+              <button
+                type="button"
+                data-testid={`message-muted-show-${message.id}`}
+                className="font-mono text-sm underline"
+                style={{ color: "var(--c-text-dim)" }}
+                onClick={() =>
+                  setRevealedIds((prev) => new Set(prev).add(message.id))
+                }
+              >
+                show
+              </button>
+            </div>
+          </div>
         ) : (
           <MessageItem
             key={message.id}
@@ -364,6 +496,8 @@ export const MessageList: React.FC<MessageListProps> = observer(({
             onReply={onReply}
             onEdit={onEdit}
             onDelete={onDelete}
+            onPin={onPin}
+            isPinned={pinnedMessageIds?.has(message.id) ?? false}
             onScrollToReply={scrollToMessage}
           />
         );
@@ -0,0 +1,102 @@
+import React, { useState } from "react";
+import { usePinnedMessages, useSetPinned } from "../../hooks/queries/usePins";
+import { transformChannelMessage } from "../../hooks/queries/useMessages";
+import { errorMessage } from "../../utils/errorMessage";
+
+interface PinnedMessagesProps {
+  conversationId: string;
+  currentUserId: string;
+  /** Group admins may take down anyone's pin; everyone else only their own. */
+  viewerIsAdmin: boolean;
+}
+
+// Collapsible strip above the message list. Hidden when nothing is pinned.
+export const PinnedMessages: React.FC<PinnedMessagesProps> = ({
+  conversationId,
+  currentUserId,
+  viewerIsAdmin,
+}) => {
+  const { data: pins = [] } = usePinnedMessages(conversationId);
+  const setPinned = useSetPinned();
+  const [expanded, setExpanded] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  if (pins.length === 0) {
+    return null;
+  }
+
+  const handleUnpin = async (messageId: string) => {
+    setError(null);
+    try {
+      await setPinned.mutateAsync({
+        conversationId,
+        messageId,
+        userId: currentUserId,
+        pinned: false,
+      });
+    } catch (err) {
+      setError(errorMessage(err, "Failed to unpin"));
+    }
+  };
+
+  return (
+    <div
+      data-testid="pinned-messages"
+      className="flex-shrink-0"
+      style={{ borderBottom: "1px solid var(--c-border)", background: "var(--c-surface)" }}
+    >
+      <button
+        type="button"
+        onClick={() => setExpanded((v) => !v)}
+        aria-expanded={expanded}
+        className="w-full text-left px-4 py-1.5 text-2xs font-mono uppercase tracking-widest bg-transparent"
+        style={{ color: "var(--c-text-muted)" }}
+      >
+        {expanded ? "▾" : "▸"} pinned ({pins.length})
+      </button>
+      {expanded && (
+        <ul className="px-4 pb-2 flex flex-col gap-1 max-h-48 overflow-y-auto">
+          {pins.map((pin) => {
+            const message = pin.message ? transformChannelMessage(pin.message) : null;
+            const author = message?.sender_username ?? message?.sender_id ?? "unknown";
+            let snippet = "not available on this device";
+            if (message?.deleted_at) {
+              snippet = "message deleted";
+            } else if (message?.content_decrypted) {
+              snippet = message.content_decrypted;
+            } else if (message && message.attachments && message.attachments.length > 0) {
+              snippet = `[${message.attachments.length} attachment${message.attachments.length === 1 ? "" : "s"}]`;
+            }
+            const canUnpin = viewerIsAdmin || pin.pinned_by === currentUserId;
+            return (
+              <li key={pin.message_id} className="flex items-center gap-2 text-xs font-mono">
+                <span className="flex-shrink-0" style={{ color: "var(--c-accent-dim)" }}>
+                  {message ? author : "—"}
+                </span>
+                <span className="flex-1 min-w-0 truncate" style={{ color: "var(--c-text)" }}>
+                  {snippet}
+                </span>
+                {canUnpin && (
+                  <button
+                    type="button"
+                    onClick={() => handleUnpin(pin.message_id)}
+                    disabled={setPinned.isPending}
+                    aria-label="Unpin message"
+                    className="flex-shrink-0 bg-transparent text-[var(--c-text-muted)] hover:text-[var(--c-text-accent)]"
+                  >
+                    [unpin]
+                  </button>
+                )}
+              </li>
+            );
+          })}
+          {error && (
+            <li className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+              {error}
+            </li>
+          )}
+        </ul>
+      )}
+    </div>
+  );
+};
@@ -0,0 +1,97 @@
+import React, { useEffect, useMemo, useRef } from "react";
+import { AlarmClock, X } from "lucide-react";
+import { useCreateReminder, useDeleteReminder, useReminders } from "../../hooks/queries/useReminders";
+import { formatReminderTime, reminderPresets } from "../../utils/reminders";
+import { errorMessage } from "../../utils/errorMessage";
+
+interface ReminderMenuProps {
+  messageId: string;
+  onClose: () => void;
+}
+
+// Inline "remind me" menu anchored to a message row (right-click or the
+// toolbar's alarm button). Picks a preset, or cancels an existing reminder for
+// this message. Closes on pick, Escape or a click outside.
+export const ReminderMenu: React.FC<ReminderMenuProps> = ({ messageId, onClose }) => {
+  const ref = useRef<HTMLDivElement>(null);
+  const { data: reminders } = useReminders();
+  const createReminder = useCreateReminder();
+  const deleteReminder = useDeleteReminder();
+  const existing = reminders?.find((r) => r.message_id === messageId) ?? null;
+  // Worked out once per open so the labels and times can't drift apart.
+  const presets = useMemo(() => reminderPresets(), []);
+
+  // Escape in the capture phase so AppShell's nav.back doesn't also fire.
+  useEffect(() => {
+    const onKey = (e: KeyboardEvent) => {
+      if (e.key === "Escape") {
+        e.stopImmediatePropagation();
+        onClose();
+      }
+    };
+    const onPointer = (e: MouseEvent) => {
+      if (ref.current && !ref.current.contains(e.target as Node)) {
+        onClose();
+      }
+    };
+    window.addEventListener("keydown", onKey, { capture: true });
+    window.addEventListener("mousedown", onPointer);
+    return () => {
+      window.removeEventListener("keydown", onKey, { capture: true });
+      window.removeEventListener("mousedown", onPointer);
+    };
+  }, [onClose]);
+
+  const itemClass =
+    "w-full flex items-center justify-between gap-4 px-2 py-1 text-left text-xs font-mono hover:bg-hover text-[var(--c-text)]";
+
+  return (
+    <div
+      ref={ref}
+      data-testid="reminder-menu"
+      role="menu"
+      aria-label="Remind me about this message"
+      className="absolute right-4 top-4 z-20 min-w-[14rem] flex flex-col rounded-[var(--radius-control)] border border-line bg-surface-raised py-1"
+    >
+      <div
+        className="flex items-center gap-1 px-2 pb-1 text-2xs font-mono uppercase tracking-widest"
+        style={{ color: "var(--c-text-muted)" }}
+      >
+        <AlarmClock size={12} />
+        Remind me
+      </div>
+      {presets.map((preset) => (
+        <button
+          key={preset.label}
+          role="menuitem"
+          className={itemClass}
+          disabled={createReminder.isPending}
+          onClick={() => createReminder.mutate({ messageId, at: preset.at }, { onSuccess: onClose })}
+        >
+          <span>{preset.label}</span>
+          <span style={{ color: "var(--c-text-muted)" }}>{formatReminderTime(preset.at)}</span>
+        </button>
+      ))}
+      {existing && (
+        <button
+          data-testid="reminder-cancel"
+          role="menuitem"
+          className={itemClass}
+          disabled={deleteReminder.isPending}
+          onClick={() => deleteReminder.mutate(existing.id, { onSuccess: onClose })}
+        >
+          <span className="flex items-center gap-1">
+            <X size={12} />
+            Cancel reminder
+          </span>
+          <span style={{ color: "var(--c-text-muted)" }}>{formatReminderTime(existing.at)}</span>
+        </button>
+      )}
+      {(createReminder.isError || deleteReminder.isError) && (
+        <div className="px-2 pt-1 text-xs" style={{ color: "var(--c-danger)" }}>
+          {errorMessage(createReminder.error ?? deleteReminder.error)}
+        </div>
+      )}
+    </div>
+  );
+};
@@ -0,0 +1,83 @@
+import React from "react";
+import type { MessageFilter } from "../../hooks/queries/useMessages";
+
+interface TimelineFiltersProps {
+  filter: MessageFilter;
+  onChange: (filter: MessageFilter) => void;
+  /** Messages that were unread when the conversation opened; 0 hides "unread". */
+  openedUnread: number;
+  /** Members to offer under "from", as seen in the loaded timeline. */
+  senders: { id: string; name: string }[];
+  /** Rendered at the right end of the strip (the activity sparkline). */
+  trailing?: React.ReactNode;
+}
+
+// Thin strip above the message list: narrow the timeline to media, links,
+// what was unread, or one member — any combination. Filtering runs over the
+// local message cache (read_filtered_messages), so it works offline.
+export const TimelineFilters: React.FC<TimelineFiltersProps> = ({
+  filter,
+  onChange,
+  openedUnread,
+  senders,
+  trailing,
+}) => {
+  const active = !!(filter.media || filter.links || filter.unread || filter.sender_id);
+
+  const toggle = (label: string, on: boolean, next: MessageFilter, testId: string) => (
+    <button
+      type="button"
+      data-testid={testId}
+      aria-pressed={on}
+      onClick={() => onChange(next)}
+      className={`font-mono cursor-pointer ${on ? "text-accent" : "text-dim hover:text-muted"}`}
+    >
+      {label}
+    </button>
+  );
+
+  return (
+    <div
+      data-testid="timeline-filters"
+      className="flex items-center gap-3 px-4 py-1 flex-shrink-0 border-b border-line text-2xs font-mono text-muted"
+    >
+      <span className="uppercase tracking-widest">show</span>
+      {toggle("media", !!filter.media, { ...filter, media: !filter.media }, "timeline-filter-media")}
+      {toggle("links", !!filter.links, { ...filter, links: !filter.links }, "timeline-filter-links")}
+      {openedUnread > 0 &&
+        toggle(
+          `unread (${openedUnread})`,
+          !!filter.unread,
+          { ...filter, unread: filter.unread ? undefined : openedUnread },
+          "timeline-filter-unread",
+        )}
+      <select
+        data-testid="timeline-filter-sender"
+        aria-label="Only messages from"
+        value={filter.sender_id ?? ""}
+        onChange={(e) => onChange({ ...filter, sender_id: e.target.value || undefined })}
+        className="bg-transparent font-mono text-2xs text-dim cursor-pointer"
+      >
+        <option value="">from anyone</option>
+        {senders.map((s) => (
+          <option key={s.id} value={s.id}>
+            from {s.name}
+          </option>
+        ))}
+      </select>
+      <div className="ml-auto flex items-center gap-3">
+        {active && (
+          <button
+            type="button"
+            data-testid="timeline-filter-clear"
+            onClick={() => onChange({})}
+            className="font-mono cursor-pointer text-dim hover:text-muted"
+          >
+            clear
+          </button>
+        )}
+        {trailing}
+      </div>
+    </div>
+  );
+};
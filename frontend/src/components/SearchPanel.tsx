@@ -123,18 +123,19 @@ function buildVoiceResults(
  */
 function buildPersonResults(
   groupMembers: GroupMemberWithGroup[],
-  dmConversations: Array<{ id: string; user2_id?: string; user2_identifier: string; user2_avatar_url?: string }> | undefined,
+  dmConversations: Array<{ id: string; user2_id?: string; user2_identifier: string; user2_nickname?: string; user2_avatar_url?: string }> | undefined,
   currentUserId: string | null,
 ): SearchResultItem[] {
   // user_id → existing DM conversation_id, so we can attach it to any
   // matching group-member row and avoid emitting a duplicate person.
-  const dmByUserId = new Map<string, { conversationId: string; identifier: string; avatarKey?: string | null }>();
+  const dmByUserId = new Map<string, { conversationId: string; identifier: string; nickname?: string; avatarKey?: string | null }>();
   if (dmConversations) {
     for (const c of dmConversations) {
       if (c.user2_id) {
         dmByUserId.set(c.user2_id, {
           conversationId: c.id,
           identifier: c.user2_identifier,
+          nickname: c.user2_nickname,
           avatarKey: c.user2_avatar_url ?? null,
         });
       }
@@ -150,11 +151,13 @@ function buildPersonResults(
     }
     seen.add(m.user_id);
     const dm = dmByUserId.get(m.user_id);
-    const username = m.username || m.display_name || dm?.identifier || m.user_id;
+    const username = m.username || dm?.identifier || m.user_id;
+    // A local nickname leads the name, so searching for it finds the person.
+    const nickname = m.display_name || dm?.nickname;
     out.push({
       type: "person",
       id: `person-${m.user_id}`,
-      name: `@${username}`,
+      name: nickname ? `${nickname} (@${username})` : `@${username}`,
       breadcrumb: dm ? `/dm/${username}` : `/user/${username}`,
       userId: m.user_id,
       avatarKey: m.avatar_url ?? dm?.avatarKey ?? null,
@@ -171,7 +174,7 @@ function buildPersonResults(
     out.push({
       type: "person",
       id: `person-${userId}`,
-      name: `@${dm.identifier}`,
+      name: dm.nickname ? `${dm.nickname} (@${dm.identifier})` : `@${dm.identifier}`,
       breadcrumb: `/dm/${dm.identifier}`,
       userId,
       avatarKey: dm.avatarKey,
@@ -185,8 +188,8 @@ function buildPersonResults(
 const PAGE_RESULTS: SearchResultItem[] = [
   { type: "page", id: "page-settings", name: "User", breadcrumb: "/user", path: "/user", keywords: "account profile username email avatar settings" },
   { type: "page", id: "page-settings-hub", name: "Settings", breadcrumb: "/settings", path: "/settings", keywords: "preferences user security" },
-  { type: "page", id: "page-preferences", name: "Preferences", breadcrumb: "/preferences", path: "/preferences", keywords: "theme color font notifications appearance" },
-  { type: "page", id: "page-voice-settings", name: "Voice & Video", breadcrumb: "/settings/voice", path: "/voice-settings", keywords: "microphone speaker audio mic noise suppression echo cancellation agc auto join camera webcam video preview permissions" },
+  { type: "page", id: "page-preferences", name: "Preferences", breadcrumb: "/preferences", path: "/preferences", keywords: "theme color font notifications appearance privacy typing links tracking previews" },
+  { type: "page", id: "page-voice-settings", name: "Voice & Video", breadcrumb: "/settings/voice", path: "/voice-settings", keywords: "microphone speaker audio mic noise suppression echo cancellation agc auto join camera webcam video preview permissions push to talk ptt voice activity sensitivity auto mute" },
   { type: "page", id: "page-security", name: "Security", breadcrumb: "/security", path: "/security", keywords: "audit log devices identity key rotation camera microphone screen permission permissions revoke privacy access" },
   { type: "page", id: "page-shortcuts", name: "Key Bindings", breadcrumb: "/shortcuts", path: "/shortcuts", keywords: "key bindings keyboard shortcuts hotkeys keybindings cmd ctrl" },
   { type: "page", id: "page-update", name: "Software Update", breadcrumb: "/update", path: "/update", keywords: "update version upgrade install release" },
@@ -11,6 +11,9 @@ import { userIdFromVoiceIdentity } from "../../voice/identity";
 import { toggleScreenShare } from "../../screenshare/screenShareActions";
 import { toggleCamera } from "../../camera/cameraActions";
 import { shareOf, cameraOf } from "../../types/voice-state";
+import { useMediaPermissions, openPrivacySettings } from "../../hooks/queries/useMediaPermissions";
+import { usePreferences } from "../../hooks/queries/usePreferences";
+import { useShortcutLabel } from "../../keyboard";
 
 interface VoiceBarProps {
   channelId: string;
@@ -27,6 +30,20 @@ export const VoiceBar: React.FC<VoiceBarProps> = observer(({ channelId, channelN
   // Listen-only: joined without a working capture device. The mute toggle
   // becomes a non-interactive "listening only" indicator.
   const micAvailable = voiceState.kind === 'joined' ? voiceState.micAvailable : true;
+  // Push-to-talk not held, or auto-mute after silence: unmuted, but sending
+  // silence. Dim the mic rather than show it as muted.
+  const transmitting = voiceState.kind === 'joined' ? voiceState.transmitting : true;
+  const pushToTalkLabel = useShortcutLabel("voice.pushToTalk");
+  const { query: prefsQuery } = usePreferences();
+  const pushToTalk = prefsQuery.data?.voice_input_mode === "push_to_talk";
+  const heldBackTitle = pushToTalk
+    ? `Push to talk — hold ${pushToTalkLabel}`
+    : "Auto-muted after silence — speak to resume";
+  // A mic the OS denies still opens and records silence on macOS/Windows, so
+  // the backend reports it available. Check the OS grant and point the user
+  // at the setting instead of letting them talk into nothing.
+  const { data: mediaPermissions } = useMediaPermissions();
+  const micDenied = mediaPermissions?.microphone === "denied";
   const share = shareOf(voiceState);
   const shareActive = share.kind === 'active';
   // Anything non-idle/non-picking means the button should become a "stop"
@@ -96,13 +113,25 @@ export const VoiceBar: React.FC<VoiceBarProps> = observer(({ channelId, channelN
         {channelName}
       </PillButton>
 
-      {/* Mute toggle — or, listen-only, a static "listening only" indicator */}
-      {micAvailable ? (
+      {/* Mute toggle — or, listen-only, a static "listening only" indicator,
+          or, when the OS denies the mic, a link to the privacy setting */}
+      {micDenied ? (
+        <PillButton
+          data-testid="voice-bar-mic-denied"
+          accent="var(--c-danger)"
+          onClick={() => { void openPrivacySettings("microphone"); }}
+          title="Microphone access is blocked — open system settings to allow it"
+          aria-label="Microphone access is blocked — open system settings to allow it"
+          square
+        >
+          <MicOff size={12} />
+        </PillButton>
+      ) : micAvailable ? (
         <PillButton
           data-testid="voice-bar-mute-button"
-          accent={voiceIsMuted ? "var(--c-danger)" : "var(--c-accent)"}
+          accent={voiceIsMuted ? "var(--c-danger)" : transmitting ? "var(--c-accent)" : "var(--c-text-dim)"}
           onClick={toggleMute}
-          title={voiceIsMuted ? "Unmute microphone" : "Mute microphone"}
+          title={voiceIsMuted ? "Unmute microphone" : transmitting ? "Mute microphone" : heldBackTitle}
           aria-label={voiceIsMuted ? "Unmute microphone" : "Mute microphone"}
           square
         >
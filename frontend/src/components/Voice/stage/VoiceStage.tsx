@@ -32,6 +32,7 @@ import { toggleCamera } from "../../../camera/cameraActions";
 import { disambiguateVoiceNames } from "../../../voice/disambiguateNames";
 import { userIdFromVoiceIdentity, voiceUserKey } from "../../../voice/identity";
 import { voiceSession } from "../../../voice";
+import { useMediaPermissions, openPrivacySettings } from "../../../hooks/queries/useMediaPermissions";
 import { Button } from "../../ui/Button";
 import { NavigableGrid } from "../../ui/NavigableGrid";
 import { ScreenSharePicker } from "../ScreenSharePicker";
@@ -99,6 +100,9 @@ export const VoiceStage: React.FC<VoiceStageProps> = observer(
     // Listen-only: joined without a working capture device. The mute button
     // becomes a non-interactive "listening only" indicator.
     const micAvailable = voiceState.kind === "joined" ? voiceState.micAvailable : true;
+    // See VoiceBar: an OS-denied mic records silence rather than failing.
+    const { data: mediaPermissions } = useMediaPermissions();
+    const micDenied = mediaPermissions?.microphone === "denied";
 
     const [focusId, setFocusId] = useState<string | null>(null);
 
@@ -378,7 +382,17 @@ export const VoiceStage: React.FC<VoiceStageProps> = observer(
             <div className="vs-foot-side">
               {isInCall || callMode ? (
                 <div className="vs-tray">
-                  {micAvailable ? (
+                  {micDenied ? (
+                    <button
+                      className="vs-tray-btn danger on"
+                      data-testid="voice-tray-mic-denied"
+                      title="Microphone access is blocked — open system settings to allow it"
+                      aria-label="Microphone access is blocked — open system settings to allow it"
+                      onClick={() => { void openPrivacySettings("microphone"); }}
+                    >
+                      <MicOff size={15} />
+                    </button>
+                  ) : micAvailable ? (
                     <button
                       className={"vs-tray-btn danger" + (micMuted ? " on" : "")}
                       data-testid="voice-tray-mute"
@@ -16,6 +16,8 @@ import { observer } from "mobx-react-lite";
 import { dropTargetStore } from "../../stores/dropTargetStore";
 import { getDraft, setDraft } from "../../utils/drafts";
 import { mentionsAll } from "../../utils/mentions";
+import { errorMessage } from "../../utils/errorMessage";
+import { isSlashCommand, listSlashCommands } from "../../utils/slashCommands";
 
 // Attachment carries a filesystem path so Rust can read the file directly —
 // no bytes-over-IPC bottleneck, no size limit.
@@ -59,6 +61,10 @@ interface ChatInputProps {
   // out an `all_mention`). Gates the live "@all notifies everyone" composer
   // hint so it only appears where the mention does something.
   canNotifyAll?: boolean;
+  // Runs a registered slash command (`/mute 1h`, `/topic …`) typed into the
+  // composer instead of sending it, resolving to a short confirmation. When
+  // omitted, lines starting with `/` are sent as ordinary messages.
+  onCommand?: (line: string) => Promise<string>;
 }
 
 function typeFromMime(mime: string): Attachment["type"] {
@@ -159,6 +165,7 @@ const ChatInputInner: React.ForwardRefRenderFunction<ChatInputHandle, ChatInputP
   onValueChange,
   draftKey = null,
   canNotifyAll = false,
+  onCommand,
 }, ref) => {
   const [message, setMessage] = useState(() => getDraft(draftKey));
   // Re-sync message when draftKey changes within the same mount (e.g. the
@@ -177,6 +184,9 @@ const ChatInputInner: React.ForwardRefRenderFunction<ChatInputHandle, ChatInputP
   // Lightbox for previewing attachments before send.
   const [expandedPreview, setExpandedPreview] = useState<{ url: string; type: "image" | "video" } | null>(null);
   const textareaRef = useRef<HTMLTextAreaElement>(null);
+  // Result of the last slash command — an inline row under the attachments,
+  // cleared as soon as the user types again.
+  const [commandFeedback, setCommandFeedback] = useState<{ text: string; isError: boolean } | null>(null);
 
   // Close preview lightbox on Escape.
   useEffect(() => {
@@ -415,16 +425,42 @@ const ChatInputInner: React.ForwardRefRenderFunction<ChatInputHandle, ChatInputP
   // when the send will fan out an `all_mention`.
   const willNotifyEveryone = canNotifyAll && mentionsAll(message);
 
+  // While the first word is a bare `/prefix`, list the matching commands so
+  // they're discoverable without leaving the keyboard.
+  const commandSuggestions = onCommand && /^\/[^\s/]*$/.test(message)
+    ? listSlashCommands(message.slice(1))
+    : [];
+
+  const clearComposer = () => {
+    setMessage("");
+    setDraft(draftKey, "");
+    onValueChange?.("");
+  };
+
+  const runCommand = (line: string) => {
+    if (!onCommand) { return; }
+    clearComposer();
+    onCommand(line)
+      .then((text) => setCommandFeedback({ text, isError: false }))
+      .catch((err) => setCommandFeedback({ text: errorMessage(err), isError: true }));
+  };
+
   const handleSend = () => {
     if (!message.trim() && attachments.length === 0) { return; }
     if (hasLoadingAttachments) { return; }
-    onSend(message.trim(), attachments);
-    setMessage("");
-    setDraft(draftKey, "");
+    if (onCommand && attachments.length === 0 && isSlashCommand(message)) {
+      runCommand(message.trim());
+      return;
+    }
+    // `//text` is the escape for a literal message that starts with `/`.
+    const text = onCommand && message.trimStart().startsWith("//")
+      ? message.trim().slice(1)
+      : message.trim();
+    onSend(text, attachments);
     // Reset signals to "no longer typing" — covers the typing indicator
     // publisher in the parent so the receiver doesn't keep us in the
     // "still typing" state until TTL.
-    onValueChange?.("");
+    clearComposer();
     // Do NOT revoke preview blob URLs here — they may still be referenced by
     // optimistic message stubs in React Query cache. Let them be GC'd naturally.
     setAttachments([]);
@@ -521,6 +557,38 @@ const ChatInputInner: React.ForwardRefRenderFunction<ChatInputHandle, ChatInputP
         </div>
       )}
 
+      {/* Slash command suggestions — inline, never a popup. */}
+      {commandSuggestions.length > 0 && (
+        <div
+          className="px-3 py-1 flex flex-col gap-0.5 text-xs font-mono"
+          style={{ borderBottom: "1px solid var(--c-border)" }}
+          data-testid="slash-command-suggestions"
+        >
+          {commandSuggestions.map((c) => (
+            <div key={c.name} className="flex items-center gap-2" style={{ opacity: c.enabled ? 1 : 0.5 }}>
+              <span style={{ color: "var(--c-accent)", fontWeight: 600 }}>{c.usage}</span>
+              <span style={{ color: "var(--c-text-muted)" }}>
+                {c.enabled ? c.description : `${c.description} (disabled)`}
+              </span>
+            </div>
+          ))}
+        </div>
+      )}
+
+      {/* Last slash command result. */}
+      {commandFeedback && (
+        <div
+          className="px-3 py-1 text-xs font-mono"
+          style={{
+            color: commandFeedback.isError ? "var(--c-danger)" : "var(--c-text-muted)",
+            borderBottom: "1px solid var(--c-border)",
+          }}
+          role="status"
+        >
+          {commandFeedback.text}
+        </div>
+      )}
+
       {/* Input row — floor its height on the shared chrome-bar token so the
           composer, in-channel voice bar, and sidebar Close all match. */}
       <div className="flex items-start gap-1 px-2 py-1.5 min-h-bar">
@@ -543,6 +611,7 @@ const ChatInputInner: React.ForwardRefRenderFunction<ChatInputHandle, ChatInputP
             setMessage(next);
             setDraft(draftKey, next);
             onValueChange?.(next);
+            setCommandFeedback(null);
           }}
           onFocus={() => setIsFocused(true)}
           onBlur={() => setIsFocused(false)}
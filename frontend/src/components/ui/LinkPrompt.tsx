@@ -0,0 +1,37 @@
+import React from "react";
+import { AlertTriangle } from "lucide-react";
+import type { LinkCheck } from "../../hooks/queries/useLinks";
+
+interface LinkPromptProps {
+  check: LinkCheck;
+  onConfirm: () => void;
+  onDismiss: () => void;
+}
+
+// Inline confirmation shown next to a link the policy won't open outright:
+// `confirm` offers "Open anyway", `block` only explains. Never a modal.
+export const LinkPrompt: React.FC<LinkPromptProps> = ({ check, onConfirm, onDismiss }) => {
+  const buttonClass = "underline hover:no-underline bg-transparent border-0 p-0 cursor-pointer";
+
+  return (
+    <span
+      data-testid="link-prompt"
+      role="alert"
+      className="inline-flex flex-wrap items-center gap-2 ml-1 px-1 text-xs font-mono text-muted rounded-[var(--radius-control)] border border-line"
+    >
+      <AlertTriangle size={12} aria-hidden="true" />
+      <span>
+        {check.action === "block" ? "Can't open this link" : "Open this link?"}
+        {check.reason ? ` — ${check.reason}` : ""}
+      </span>
+      {check.action === "confirm" && (
+        <button type="button" data-testid="link-prompt-open" className={buttonClass} onClick={onConfirm}>
+          Open anyway
+        </button>
+      )}
+      <button type="button" data-testid="link-prompt-dismiss" className={buttonClass} onClick={onDismiss}>
+        {check.action === "confirm" ? "Cancel" : "Dismiss"}
+      </button>
+    </span>
+  );
+};
@@ -1,5 +1,6 @@
 import React, { useCallback } from "react";
-import { shellOpen } from "../../bridge";
+import { useOpenLink } from "../../hooks/queries/useLinks";
+import { LinkPrompt } from "./LinkPrompt";
 
 // Matches http://, https://, and www. prefixed URLs
 const URL_REGEX =
@@ -22,15 +23,18 @@ interface LinkifiedTextProps {
 
 /**
  * Renders text with URLs detected and displayed as clickable links.
- * Links open in the system browser via Tauri's shell plugin.
+ * Links go through the link policy (`check_link`) and open in the system
+ * browser; ones needing confirmation get an inline prompt after the link.
  */
 export const LinkifiedText: React.FC<LinkifiedTextProps> = ({ text }) => {
+  const { pending, open, confirm, dismiss } = useOpenLink();
+
   const handleClick = useCallback(
-    (e: React.MouseEvent<HTMLAnchorElement>, url: string) => {
+    (e: React.MouseEvent<HTMLAnchorElement>, url: string, key: string) => {
       e.preventDefault();
-      shellOpen(ensureProtocol(url));
+      void open(url, key);
     },
-    [],
+    [open],
   );
 
   const parts: React.ReactNode[] = [];
@@ -47,17 +51,23 @@ export const LinkifiedText: React.FC<LinkifiedTextProps> = ({ text }) => {
     }
 
     const url = match[0];
+    const key = String(match.index);
     parts.push(
       <a
-        key={match.index}
+        key={key}
         href={ensureProtocol(url)}
-        onClick={(e) => handleClick(e, url)}
+        onClick={(e) => handleClick(e, url, key)}
         className="message-link"
         title={ensureProtocol(url)}
       >
         {url}
       </a>,
     );
+    if (pending?.key === key) {
+      parts.push(
+        <LinkPrompt key={`${key}-prompt`} check={pending.check} onConfirm={confirm} onDismiss={dismiss} />,
+      );
+    }
 
     lastIndex = URL_REGEX.lastIndex;
   }
@@ -9,7 +9,7 @@ interface TextInputProps {
   description?: string;
   error?: string;
   disabled?: boolean;
-  type?: "text" | "password" | "email" | "number";
+  type?: "text" | "password" | "email" | "number" | "time";
   className?: string;
   id?: string;
   required?: boolean;
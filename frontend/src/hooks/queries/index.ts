@@ -7,6 +7,16 @@ export * from "./useGroups";
 export * from "./useMessages";
 export * from "./useSearchMessages";
 export * from "./useReactions";
+export * from "./usePins";
 export * from "./useBlocks";
 export * from "./useTransparency";
 export * from "./useMessageRetention";
+export * from "./useInactivityPolicy";
+export * from "./useTranslation";
+export * from "./useSidebarOrder";
+export * from "./useRelayLatency";
+export * from "./useDeliveryLatency";
+export * from "./useDeliveryCanary";
+export * from "./useCrashReports";
+export * from "./useReminders";
+export * from "./useLinks";
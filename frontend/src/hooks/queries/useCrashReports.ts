@@ -0,0 +1,42 @@
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { invoke } from "../../bridge";
+
+// Mirrors `CrashReportSummary` in pollis-core/src/commands/crash.rs.
+export interface CrashReportSummary {
+  id: string;
+  created_at: number; // unix seconds
+  location: string | null;
+  acknowledged: boolean;
+}
+
+const crashReportsKey = ["crash-reports"] as const;
+
+// Query: crash reports written by the panic hook on this device, newest first.
+// Read once per launch — a crash ends the process, so nothing new can appear
+// while this one is running.
+export function useCrashReports() {
+  return useQuery({
+    queryKey: crashReportsKey,
+    queryFn: () => invoke<CrashReportSummary[]>("list_crash_reports"),
+    staleTime: Infinity,
+  });
+}
+
+// Mutation: mark every current report as seen so the next-launch notice hides.
+export function useAcknowledgeCrashReports() {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: () => invoke<void>("acknowledge_crash_reports"),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: crashReportsKey });
+    },
+  });
+}
+
+// Mutation: every report concatenated as one text blob, for the user to save
+// and attach to a bug report.
+export function useExportCrashReports() {
+  return useMutation({
+    mutationFn: () => invoke<string>("export_crash_reports"),
+  });
+}
@@ -0,0 +1,64 @@
+import { useEffect } from "react";
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { Channel, invoke } from "../../bridge";
+
+// Mirrors `CanaryAnomaly` in pollis-core/src/commands/delivery_canary.rs.
+export interface CanaryAnomaly {
+  kind: "dropped" | "duplicated" | "modified" | "delayed";
+  envelope_id: string;
+  conversation_id: string;
+  at: number; // unix seconds
+  detail: string;
+}
+
+// Mirrors `DeliveryCanaryReport`.
+export interface DeliveryCanaryReport {
+  checked: number;
+  pending: number;
+  dropped: number;
+  duplicated: number;
+  modified: number;
+  delayed: number;
+  anomaly_rate: number;
+  alert: boolean;
+  evidence: CanaryAnomaly[]; // newest last
+}
+
+const deliveryCanaryKey = ["delivery-canary"] as const;
+
+// Query: this session's checks of our own envelopes coming back from the
+// server. Refetched on mount/focus; alerts arrive through
+// `useDeliveryCanaryAlerts` rather than by polling.
+export function useDeliveryCanaryReport() {
+  return useQuery({
+    queryKey: deliveryCanaryKey,
+    queryFn: () => invoke<DeliveryCanaryReport>("get_delivery_canary_report"),
+    staleTime: 1000 * 10,
+    refetchOnWindowFocus: true,
+  });
+}
+
+// Subscribe once to the alert channel and feed raised alerts into the query.
+export function useDeliveryCanaryAlerts() {
+  const queryClient = useQueryClient();
+  useEffect(() => {
+    const channel = new Channel<DeliveryCanaryReport>();
+    channel.onmessage = (report) => {
+      queryClient.setQueryData(deliveryCanaryKey, report);
+    };
+    invoke("subscribe_delivery_canary_alerts", { onEvent: channel }).catch(() => {
+      // Older backend without canaries — nothing to alert on.
+    });
+  }, [queryClient]);
+}
+
+// Mutation: hide the alert until another anomaly arrives.
+export function useAcknowledgeDeliveryCanaryAlert() {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: () => invoke<DeliveryCanaryReport>("acknowledge_delivery_canary_alert"),
+    onSuccess: (report) => {
+      queryClient.setQueryData(deliveryCanaryKey, report);
+    },
+  });
+}
@@ -0,0 +1,29 @@
+import { useQuery } from "@tanstack/react-query";
+import { invoke } from "../../bridge";
+
+// Mirrors `LatencyPercentiles` in pollis-core/src/commands/delivery_latency.rs.
+export interface LatencyPercentiles {
+  samples: number;
+  p50_ms: number | null;
+  p90_ms: number | null;
+  p99_ms: number | null;
+  max_ms: number | null;
+}
+
+// Mirrors `DeliveryLatencyStats`.
+export interface DeliveryLatencyStats {
+  session: LatencyPercentiles;
+  last_hour: LatencyPercentiles;
+  excluded: number; // offline catch-up / far-off sender clock
+}
+
+// Query: send → decrypt latency of messages received this session. Refetched
+// on mount/focus only — samples come from real deliveries, nothing to poll.
+export function useDeliveryLatency() {
+  return useQuery({
+    queryKey: ["delivery-latency"],
+    queryFn: () => invoke<DeliveryLatencyStats>("get_delivery_latency_stats"),
+    staleTime: 1000 * 10,
+    refetchOnWindowFocus: true,
+  });
+}
@@ -2,10 +2,11 @@ import { useQuery, useQueries, useMutation, useQueryClient } from "@tanstack/rea
 import { useMemo } from "react";
 import { invoke } from "../../bridge";
 import * as api from "../../services/api";
-import type { GroupWithChannels } from "../../services/api";
+import type { GroupWithChannels, StorageTarget } from "../../services/api";
 import { appStore } from "../../stores/appStore";
 import { useObserver } from "mobx-react-lite";
-import type { Group, Channel, GroupMember } from "../../types";
+import { messageQueryKeys } from "./useMessages";
+import type { Group, Channel, GroupEvent, GroupJoinCode, GroupMember, GroupStructure, OwnershipTransfer } from "../../types";
 
 export const groupQueryKeys = {
   all: ["groups"] as const,
@@ -14,10 +15,15 @@ export const groupQueryKeys = {
   group: (groupId: string) => ["groups", groupId] as const,
   channels: (groupId: string) => ["groups", groupId, "channels"] as const,
   members: (groupId: string) => ["groups", groupId, "members"] as const,
+  events: (groupId: string) => ["groups", groupId, "events"] as const,
   pendingInvites: (userId: string | null) => ["group-invites", "pending", userId] as const,
+  ownershipTransfers: (userId: string | null) => ["group-ownership-transfers", userId] as const,
   joinRequests: (groupId: string) => ["group-join-requests", groupId] as const,
   myJoinRequest: (groupId: string | undefined, userId: string | null) =>
     ["group-join-requests", "my", groupId, userId] as const,
+  webhooks: (groupId: string) => ["groups", groupId, "webhooks"] as const,
+  joinCode: (groupId: string) => ["groups", groupId, "join-code"] as const,
+  storageTargets: ["storage-targets"] as const,
 };
 
 export function useUserGroupsWithChannels() {
@@ -166,13 +172,259 @@ export function useUpdateGroup() {
         iconUrl: null,
       });
     },
-    onSuccess: () => {
+    onSuccess: (_data, { groupId }) => {
       queryClient.invalidateQueries({
         queryKey: groupQueryKeys.userGroups(currentUser?.id ?? null),
       });
       queryClient.invalidateQueries({
         queryKey: groupQueryKeys.userGroupsWithChannels(currentUser?.id ?? null),
       });
+      // A rename changes the join code's tag.
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.joinCode(groupId) });
+    },
+  });
+}
+
+// Admin toggle for the group's message-export policy (`groups.allow_export`).
+export function useSetGroupExportPolicy() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({ groupId, allowExport }: { groupId: string; allowExport: boolean }) => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      await invoke("set_group_export_policy", {
+        groupId,
+        requesterId: currentUser.id,
+        allowExport,
+      });
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({
+        queryKey: groupQueryKeys.userGroupsWithChannels(currentUser?.id ?? null),
+      });
+    },
+  });
+}
+
+// Storage targets this deployment declares (DS env). Deployment config, so
+// fetched once per session.
+export function useStorageTargets(enabled: boolean) {
+  return useQuery({
+    queryKey: groupQueryKeys.storageTargets,
+    queryFn: () => invoke<StorageTarget[]>("list_storage_targets"),
+    enabled,
+    staleTime: Infinity,
+  });
+}
+
+// Owner setting: pin the group's new attachments to a storage target (null =
+// the default bucket) — `groups.storage_target`.
+export function useSetGroupStorageTarget() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({ groupId, storageTarget }: { groupId: string; storageTarget: string | null }) => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      await invoke("set_group_storage_target", {
+        groupId,
+        requesterId: currentUser.id,
+        storageTarget,
+      });
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({
+        queryKey: groupQueryKeys.userGroupsWithChannels(currentUser?.id ?? null),
+      });
+    },
+  });
+}
+
+// Choices offered for a channel's retention window; 0 keeps history.
+export const CHANNEL_RETENTION_OPTIONS: { days: number; label: string }[] = [
+  { days: 0, label: "Keep" },
+  { days: 1, label: "1 day" },
+  { days: 7, label: "7 days" },
+  { days: 30, label: "30 days" },
+  { days: 90, label: "90 days" },
+];
+
+// Admin setting for how long a channel keeps history (`channels.retention_days`).
+export function useSetChannelRetention() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({ channelId, days }: { groupId: string; channelId: string; days: number }) => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      await invoke("set_channel_retention", {
+        channelId,
+        requesterId: currentUser.id,
+        days,
+      });
+    },
+    onSuccess: (_data, { groupId }) => {
+      queryClient.invalidateQueries({
+        queryKey: groupQueryKeys.userGroupsWithChannels(currentUser?.id ?? null),
+      });
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.channels(groupId) });
+    },
+  });
+}
+
+export type WebhookEvent = "message_count" | "member_joined" | "channel_created";
+
+export type GroupWebhook = {
+  id: string;
+  group_id: string;
+  url: string;
+  events: WebhookEvent[];
+  created_by: string;
+  created_at: string;
+  last_delivery_at?: string | null;
+  last_status?: number | null;
+  failure_count: number;
+};
+
+// `secret` is only returned at creation time; it signs every delivery.
+export type CreatedWebhook = {
+  webhook: GroupWebhook;
+  secret: string;
+};
+
+// Outbound webhooks registered on a group. Admin-only.
+export function useGroupWebhooks(groupId: string | null) {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useQuery({
+    queryKey: groupQueryKeys.webhooks(groupId ?? ""),
+    queryFn: async (): Promise<GroupWebhook[]> => {
+      if (!currentUser || !groupId) {
+        return [];
+      }
+      return await invoke<GroupWebhook[]>("list_group_webhooks", { groupId, requesterId: currentUser.id });
+    },
+    enabled: !!currentUser && !!groupId,
+    staleTime: 1000 * 30,
+  });
+}
+
+export function useCreateGroupWebhook() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({
+      groupId,
+      url,
+      events,
+    }: {
+      groupId: string;
+      url: string;
+      events: WebhookEvent[];
+    }): Promise<CreatedWebhook> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<CreatedWebhook>("create_group_webhook", {
+        groupId,
+        requesterId: currentUser.id,
+        url,
+        events,
+      });
+    },
+    onSuccess: (_created, { groupId }) => {
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.webhooks(groupId) });
+    },
+  });
+}
+
+export function useDeleteGroupWebhook() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({ webhookId }: { webhookId: string; groupId: string }) => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      await invoke("delete_group_webhook", { webhookId, requesterId: currentUser.id });
+    },
+    onSuccess: (_data, { groupId }) => {
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.webhooks(groupId) });
+    },
+  });
+}
+
+// Decrypted channel history as a CSV, JSON or Matrix event-stream string, built from this
+// device's local message cache. Admin-only and subject to the group's
+// export policy; `from` / `to` are optional RFC 3339 bounds.
+export function useExportChannelMessages() {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({
+      channelId,
+      format,
+      from,
+      to,
+    }: {
+      channelId: string;
+      format: "csv" | "json" | "matrix";
+      from?: string;
+      to?: string;
+    }): Promise<string> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<string>("export_channel_messages", {
+        userId: currentUser.id,
+        channelId,
+        format,
+        from: from ?? null,
+        to: to ?? null,
+      });
+    },
+  });
+}
+
+export interface AttachmentExportSummary {
+  exported: number;
+  skipped: number;
+  failed: number;
+  paused: boolean;
+  manifest_path: string;
+}
+
+// Every attachment in the group this device can decrypt, written to a folder
+// with a manifest.json. Same gate as the history export. Re-running into the
+// same folder resumes (files already there with the right hash are skipped).
+export function useExportGroupAttachments() {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({
+      groupId,
+      destPath,
+    }: {
+      groupId: string;
+      destPath: string;
+    }): Promise<AttachmentExportSummary> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<AttachmentExportSummary>("export_group_attachments", {
+        userId: currentUser.id,
+        groupId,
+        destPath,
+      });
     },
   });
 }
@@ -242,6 +494,7 @@ export function useCreateChannel() {
         name: rawChannel.name,
         description: rawChannel.description || '',
         channel_type: 'text',
+        retention_days: null,
         created_by: currentUser?.id || '',
         created_at: 0,
         updated_at: 0,
@@ -325,16 +578,73 @@ export function useDeleteChannel() {
   });
 }
 
+// Owner only. Schedules the group's deletion with a 7-day grace period; the
+// DS purges it afterwards unless useCancelGroupDeletion withdraws it first.
+export function useDeleteGroup() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({ groupId }: { groupId: string }) => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      await invoke("delete_group", { groupId, requesterId: currentUser.id });
+    },
+    onSuccess: (_data, { groupId }) => {
+      queryClient.invalidateQueries({
+        queryKey: groupQueryKeys.userGroupsWithChannels(currentUser?.id ?? null),
+      });
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.events(groupId) });
+    },
+  });
+}
+
+export function useCancelGroupDeletion() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({ groupId }: { groupId: string }) => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      await invoke("cancel_group_deletion", { groupId, requesterId: currentUser.id });
+    },
+    onSuccess: (_data, { groupId }) => {
+      queryClient.invalidateQueries({
+        queryKey: groupQueryKeys.userGroupsWithChannels(currentUser?.id ?? null),
+      });
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.events(groupId) });
+    },
+  });
+}
+
+// Deletes this device's copy of the group's messages (the group itself is
+// untouched). Resolves to how many were deleted.
+export function useForgetGroupHistory() {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: async ({ groupId }: { groupId: string }): Promise<number> => {
+      return await invoke<number>("forget_group_history", { groupId });
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: messageQueryKeys.all });
+    },
+  });
+}
+
 export function useLeaveGroup() {
   const queryClient = useQueryClient();
   const currentUser = useObserver(() => appStore.currentUser);
 
   return useMutation({
-    mutationFn: async (groupId: string) => {
+    mutationFn: async ({ groupId, deleteHistory }: { groupId: string; deleteHistory: boolean }) => {
       if (!currentUser) {
         throw new Error("No current user");
       }
-      await invoke("leave_group", { groupId, userId: currentUser.id });
+      await invoke("leave_group", { groupId, userId: currentUser.id, deleteHistory });
     },
     onSuccess: () => {
       queryClient.invalidateQueries({
@@ -481,6 +791,24 @@ export function useGroupMembers(groupId: string | null) {
   });
 }
 
+// Timeline notices (joins, departures, channel creation). Under the
+// ["groups", groupId] prefix, so the membership_changed invalidation refreshes
+// them along with the member list.
+export function useGroupEvents(groupId: string | null) {
+  return useQuery({
+    queryKey: groupQueryKeys.events(groupId ?? ''),
+    queryFn: async (): Promise<GroupEvent[]> => {
+      if (!groupId) {
+        return [];
+      }
+      return await invoke<GroupEvent[]>('get_group_events', { groupId });
+    },
+    enabled: !!groupId,
+    staleTime: 1000 * 30,
+    refetchOnWindowFocus: true,
+  });
+}
+
 export function useSetMemberRole() {
   const queryClient = useQueryClient();
   const currentUser = useObserver(() => appStore.currentUser);
@@ -518,6 +846,90 @@ export function useKickMember() {
   });
 }
 
+export function usePendingOwnershipTransfers() {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useQuery({
+    queryKey: groupQueryKeys.ownershipTransfers(currentUser?.id ?? null),
+    queryFn: async (): Promise<OwnershipTransfer[]> => {
+      if (!currentUser) {
+        return [];
+      }
+      return await invoke<OwnershipTransfer[]>('get_pending_ownership_transfers', { userId: currentUser.id });
+    },
+    enabled: !!currentUser,
+    staleTime: 1000 * 30,
+    refetchOnWindowFocus: true,
+  });
+}
+
+export function useTransferGroupOwnership() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({ groupId, newOwnerId }: { groupId: string; newOwnerId: string }) => {
+      if (!currentUser) {
+        throw new Error('No current user');
+      }
+      await invoke('transfer_group_ownership', { groupId, requesterId: currentUser.id, newOwnerId });
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.ownershipTransfers(currentUser?.id ?? null) });
+    },
+  });
+}
+
+export function useAcceptGroupOwnership() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async (groupId: string) => {
+      if (!currentUser) {
+        throw new Error('No current user');
+      }
+      await invoke('accept_group_ownership', { groupId, userId: currentUser.id });
+      return groupId;
+    },
+    onSuccess: (groupId) => {
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.ownershipTransfers(currentUser?.id ?? null) });
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.members(groupId) });
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.userGroupsWithChannels(currentUser?.id ?? null) });
+    },
+  });
+}
+
+export function useDeclineGroupOwnership() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async (groupId: string) => {
+      if (!currentUser) {
+        throw new Error('No current user');
+      }
+      await invoke('decline_group_ownership', { groupId, userId: currentUser.id });
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: groupQueryKeys.ownershipTransfers(currentUser?.id ?? null) });
+    },
+  });
+}
+
+export function useExportGroupStructure() {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async (groupId: string): Promise<GroupStructure> => {
+      if (!currentUser) {
+        throw new Error('No current user');
+      }
+      return await invoke<GroupStructure>('export_group_structure', { groupId, requesterId: currentUser.id });
+    },
+  });
+}
+
 export function useRequestGroupAccess() {
   const currentUser = useObserver(() => appStore.currentUser);
 
@@ -531,6 +943,24 @@ export function useRequestGroupAccess() {
   });
 }
 
+// The group's join code for the invite page QR. Changes when the group is
+// renamed; `useUpdateGroup` invalidates it.
+export function useGroupJoinCode(groupId: string) {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useQuery({
+    queryKey: groupQueryKeys.joinCode(groupId),
+    queryFn: async (): Promise<GroupJoinCode> => {
+      if (!currentUser) {
+        throw new Error('No current user');
+      }
+      return await invoke<GroupJoinCode>('get_group_join_code', { groupId, userId: currentUser.id });
+    },
+    enabled: !!currentUser && !!groupId,
+    staleTime: 1000 * 60,
+  });
+}
+
 export function useMyJoinRequest(groupId: string | undefined) {
   const currentUser = useObserver(() => appStore.currentUser);
 
@@ -0,0 +1,75 @@
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { useObserver } from "mobx-react-lite";
+import { invoke } from "../../bridge";
+import { appStore } from "../../stores/appStore";
+
+// Mirrors `InactivityPolicy` in pollis-core/src/commands/user.rs. Timestamps
+// are SQLite `datetime('now')` strings (UTC).
+export interface InactivityPolicy {
+  inactive_days: number;
+  delete_recovery: boolean;
+  last_active_at: string;
+  notified_at: string | null;
+  recovery_deleted_at: string | null;
+}
+
+// Windows offered in Preferences. The Delivery Service accepts 30–730.
+export const INACTIVITY_OPTIONS = [
+  { label: "Off", days: null },
+  { label: "90 days", days: 90 },
+  { label: "180 days", days: 180 },
+  { label: "1 year", days: 365 },
+] as const;
+
+export const inactivityQueryKeys = {
+  policy: (userId: string | null) => ["inactivityPolicy", userId] as const,
+};
+
+// Query: the current user's inactive-account policy (null = none set).
+export function useInactivityPolicy() {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useQuery({
+    queryKey: inactivityQueryKeys.policy(currentUser?.id ?? null),
+    queryFn: async (): Promise<InactivityPolicy | null> => {
+      if (!currentUser) {
+        return null;
+      }
+      return await invoke<InactivityPolicy | null>("get_inactivity_policy", {
+        userId: currentUser.id,
+      });
+    },
+    enabled: !!currentUser,
+    staleTime: 1000 * 60 * 5,
+  });
+}
+
+// Mutation: set (days) or clear (null) the policy.
+export function useSetInactivityPolicy() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({
+      inactiveDays,
+      deleteRecovery,
+    }: {
+      inactiveDays: number | null;
+      deleteRecovery: boolean;
+    }): Promise<void> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      await invoke("set_inactivity_policy", {
+        userId: currentUser.id,
+        inactiveDays,
+        deleteRecovery,
+      });
+    },
+    onSuccess: () => {
+      void queryClient.invalidateQueries({
+        queryKey: inactivityQueryKeys.policy(currentUser?.id ?? null),
+      });
+    },
+  });
+}
@@ -0,0 +1,74 @@
+import { useCallback, useState } from "react";
+import { useQuery } from "@tanstack/react-query";
+import { invoke, shellOpen } from "../../bridge";
+import { usePreferences } from "./usePreferences";
+import { errorMessage } from "../../utils/errorMessage";
+
+// Mirrors `LinkCheck` in pollis-core/src/commands/links.rs — the one place the
+// link policy lives. `url` is what to open or fetch (tracking parameters
+// already removed); `reason` explains a `confirm` or `block`.
+export interface LinkCheck {
+  url: string;
+  host: string;
+  stripped: string[];
+  action: "open" | "confirm" | "block";
+  reason: string | null;
+  preview: boolean;
+}
+
+// Query: the policy applied to every URL in a message, for inline previews.
+// Keyed on the link settings too, so flipping one re-checks on next render.
+export function useLinkChecks(urls: string[], enabled = true) {
+  const { query: prefs } = usePreferences();
+  const policyKey = [
+    prefs.data?.link_confirm_insecure ?? true,
+    prefs.data?.link_strip_tracking ?? true,
+    prefs.data?.link_previews ?? true,
+  ];
+
+  return useQuery({
+    queryKey: ["links", "check", urls, policyKey],
+    queryFn: async (): Promise<LinkCheck[]> => await invoke<LinkCheck[]>("check_links", { urls }),
+    enabled: enabled && urls.length > 0,
+    staleTime: Infinity,
+  });
+}
+
+// Opening a link from a message: check it, open the cleaned URL straight away
+// when the policy allows, otherwise hold it as `pending` so the caller can
+// render an inline confirmation (`LinkPrompt`). `key` identifies which link on
+// the surface is pending.
+export function useOpenLink() {
+  const [pending, setPending] = useState<{ key: string; check: LinkCheck } | null>(null);
+
+  const open = useCallback(async (url: string, key: string) => {
+    let check: LinkCheck;
+    try {
+      check = await invoke<LinkCheck>("check_link", { url });
+    } catch (e) {
+      // Never fall back to opening an unchecked link.
+      setPending({
+        key,
+        check: { url, host: "", stripped: [], action: "block", reason: errorMessage(e), preview: false },
+      });
+      return;
+    }
+    if (check.action === "open") {
+      setPending(null);
+      await shellOpen(check.url);
+      return;
+    }
+    setPending({ key, check });
+  }, []);
+
+  const confirm = useCallback(() => {
+    if (pending?.check.action === "confirm") {
+      void shellOpen(pending.check.url);
+    }
+    setPending(null);
+  }, [pending]);
+
+  const dismiss = useCallback(() => setPending(null), []);
+
+  return { pending, open, confirm, dismiss };
+}
@@ -0,0 +1,98 @@
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { useObserver } from "mobx-react-lite";
+import { invoke } from "../../bridge";
+import { appStore } from "../../stores/appStore";
+
+// Device-local snapshots of the encrypted local DB. Taken automatically on
+// unlock (at most once a day) and on demand; they never leave this device.
+// Mirrors the Rust `LocalBackup` struct (pollis-core/src/db/backup.rs).
+export interface LocalBackup {
+  timestamp: string; // e.g. "20260101T120000Z" — pass back to restore
+  size_bytes: number;
+}
+
+// Validated identically in the Rust core (`set_local_backup_count`).
+export const MAX_LOCAL_BACKUP_COUNT = 10;
+
+const localBackupsKey = (userId: string | null) => ["local_backups", userId] as const;
+const localBackupCountKey = ["local_backup_count"] as const;
+
+export function useLocalBackups() {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useQuery({
+    queryKey: localBackupsKey(currentUser?.id ?? null),
+    queryFn: async (): Promise<LocalBackup[]> => {
+      if (!currentUser) {
+        return [];
+      }
+      return await invoke<LocalBackup[]>("list_local_backups", { userId: currentUser.id });
+    },
+    enabled: !!currentUser,
+  });
+}
+
+export function useCreateLocalBackup() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async (): Promise<LocalBackup | null> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<LocalBackup | null>("create_local_backup", { userId: currentUser.id });
+    },
+    onSuccess: () => {
+      void queryClient.invalidateQueries({ queryKey: localBackupsKey(currentUser?.id ?? null) });
+    },
+  });
+}
+
+// Restores history and settings only — MLS and identity state are never rolled
+// back. Resolves to the number of rows written.
+export function useRestoreLocalBackup() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async (timestamp: string): Promise<number> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<number>("restore_local_backup", { userId: currentUser.id, timestamp });
+    },
+    onSuccess: () => {
+      void queryClient.invalidateQueries();
+    },
+  });
+}
+
+// Query: how many snapshots this device keeps (0 = snapshots off).
+export function useLocalBackupCount() {
+  return useQuery({
+    queryKey: localBackupCountKey,
+    queryFn: async (): Promise<number> => {
+      return await invoke<number>("get_local_backup_count");
+    },
+    staleTime: 1000 * 60 * 5,
+  });
+}
+
+export function useSetLocalBackupCount() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async (count: number): Promise<void> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      await invoke("set_local_backup_count", { userId: currentUser.id, count });
+    },
+    onSuccess: () => {
+      void queryClient.invalidateQueries({ queryKey: localBackupCountKey });
+      void queryClient.invalidateQueries({ queryKey: localBackupsKey(currentUser?.id ?? null) });
+    },
+  });
+}
@@ -1,9 +1,9 @@
 import { useEffect, useRef } from "react";
-import { useQuery, useMutation, useQueryClient } from "@tanstack/react-query";
+import { useQuery, useInfiniteQuery, useMutation, useQueryClient } from "@tanstack/react-query";
 import { invoke } from "../../bridge";
 import { appStore } from "../../stores/appStore";
 import { useObserver } from "mobx-react-lite";
-import type { Message, DMConversation } from "../../types";
+import type { Message, DMConversation, ConversationPreview, BroadcastReport } from "../../types";
 
 // Per-conversation timestamp of the last background ingest. Used to debounce
 // rapid channel-switching so we don't fire one ingest per click. Realtime
@@ -24,7 +24,16 @@ export function markIngested(conversationId: string): void {
 export const messageQueryKeys = {
   all: ["messages"] as const,
   channel: (channelId: string | null) => ["messages", "channel", channelId] as const,
+  filtered: (targetId: string | null, filter: MessageFilter | null) =>
+    ["messages", "filtered", targetId, filter] as const,
   conversation: (conversationId: string | null) => ["messages", "conversation", conversationId] as const,
+  // Under the conversation's own key, so whatever refreshes its timeline
+  // refreshes its activity too.
+  activity: (channelId: string | null, conversationId: string | null, range: ActivityRange) =>
+    channelId
+      ? (["messages", "channel", channelId, "activity", range] as const)
+      : (["messages", "conversation", conversationId, "activity", range] as const),
+  mostActive: (range: ActivityRange, limit: number) => ["messages", "most-active", range, limit] as const,
   dmConversations: (userId: string | null) => ["dm-conversations", userId] as const,
 };
 
@@ -32,6 +41,7 @@ export const lastMessageQueryKeys = {
   all: ["last-message"] as const,
   channel: (channelId: string | null) => ["last-message", "channel", channelId] as const,
   conversation: (conversationId: string | null) => ["last-message", "conversation", conversationId] as const,
+  previews: ["last-message", "previews"] as const,
 };
 
 // Wire shape of a single attachment inside the `_att` array embedded in
@@ -68,6 +78,8 @@ export type RawChannelMessage = {
   sent_at: string;
   edited_at?: string;
   deleted_at?: string;
+  // Sender is muted in this channel's group (set by the read commands).
+  sender_muted?: boolean;
 };
 
 type MessagePage = {
@@ -79,7 +91,8 @@ type RawDmChannel = {
   id: string;
   created_by: string;
   created_at: string;
-  members: Array<{ user_id: string; username?: string; avatar_url?: string; added_by: string; added_at: string }>;
+  members: Array<{ user_id: string; username?: string; avatar_url?: string; added_by: string; added_at: string; nickname?: string | null }>;
+  pinned?: boolean;
 };
 
 // Parses structured attachment JSON embedded in message content.
@@ -158,6 +171,7 @@ export function transformChannelMessage(m: RawChannelMessage): Message {
     attachments: parsed?.attachments ?? [],
     edited_at: m.edited_at,
     deleted_at: m.deleted_at,
+    sender_muted: m.sender_muted,
   };
 }
 
@@ -250,6 +264,81 @@ export function useMessages(channelId: string | null, conversationId: string | n
 /**
  * @deprecated Use useMessages(channelId, null) instead
  */
+// Mirrors MessageFilter in pollis-core/src/commands/messages/types.rs. Every
+// set field must match.
+export type MessageFilter = {
+  media?: boolean;
+  links?: boolean;
+  sender_id?: string;
+  // Only the newest n messages — what was unread when the conversation opened.
+  unread?: number;
+};
+
+// A filtered timeline for a channel or DM, read from the local message cache
+// (read_filtered_messages). Pages newest-first; `fetchNextPage` goes older.
+// Disabled while `filter` is null.
+export function useFilteredMessages(targetId: string | null, filter: MessageFilter | null) {
+  const query = useInfiniteQuery({
+    queryKey: messageQueryKeys.filtered(targetId, filter),
+    queryFn: async ({ pageParam }) =>
+      invoke<MessagePage>('read_filtered_messages', {
+        conversationId: targetId,
+        filter,
+        limit: 50,
+        cursor: pageParam,
+      }),
+    initialPageParam: null as MessagePage['next_cursor'],
+    getNextPageParam: (last) => last.next_cursor,
+    enabled: !!targetId && !!filter,
+    staleTime: 1000 * 30,
+  });
+  const messages = (query.data?.pages ?? [])
+    .flatMap((p) => p.messages.map(transformChannelMessage))
+    .sort((a, b) => a.created_at - b.created_at);
+  return { ...query, messages };
+}
+
+// Mirrors ActivityRange / ChannelActivity / ActiveChannel in
+// pollis-core/src/commands/messages/types.rs. "24h" is hourly, "7d" and "30d"
+// are daily; bucket starts are UTC.
+export type ActivityRange = "24h" | "7d" | "30d";
+
+export type ChannelActivity = {
+  channel_id: string;
+  range: ActivityRange;
+  // Oldest first, zero-filled across the whole range.
+  buckets: { start: string; count: number }[];
+  total: number;
+};
+
+export type ActiveChannel = { channel_id: string; count: number };
+
+// Message counts for a channel or DM, read from the local hourly rollup
+// (get_channel_activity) — cheap enough to render on every open.
+export function useChannelActivity(
+  channelId: string | null,
+  conversationId: string | null,
+  range: ActivityRange,
+) {
+  const targetId = channelId ?? conversationId;
+  return useQuery({
+    queryKey: messageQueryKeys.activity(channelId, conversationId, range),
+    queryFn: () => invoke<ChannelActivity>("get_channel_activity", { channelId: targetId, range }),
+    enabled: !!targetId,
+    staleTime: 1000 * 60,
+  });
+}
+
+// Busiest channels and DMs over `range`, from the same rollup
+// (get_most_active_channels).
+export function useMostActiveChannels(range: ActivityRange, limit = 10) {
+  return useQuery({
+    queryKey: messageQueryKeys.mostActive(range, limit),
+    queryFn: () => invoke<ActiveChannel[]>("get_most_active_channels", { range, limit }),
+    staleTime: 1000 * 60,
+  });
+}
+
 export function useChannelMessages(channelId: string | null) {
   return useMessages(channelId, null);
 }
@@ -338,8 +427,10 @@ export function useDMConversations() {
           id: c.id,
           user1_id: currentUser.id,
           user2_identifier: other?.username || other?.user_id || 'Unknown',
+          user2_nickname: other?.nickname ?? undefined,
           user2_id: other?.user_id,
           user2_avatar_url: other?.avatar_url,
+          pinned: c.pinned ?? false,
           created_at: new Date(c.created_at).getTime(),
           updated_at: new Date(c.created_at).getTime(),
         };
@@ -385,6 +476,52 @@ export function useLastMessage(channelId: string | null, conversationId: string
   });
 }
 
+// One local read for every conversation's newest message. Unlike
+// useLastMessage this never touches Turso, so the sidebar can render
+// snippets before any network round trip.
+export function useConversationPreviews() {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useQuery({
+    queryKey: lastMessageQueryKeys.previews,
+    queryFn: () => invoke<ConversationPreview[]>('list_conversation_previews'),
+    enabled: !!currentUser,
+    staleTime: 1000 * 30,
+    refetchOnWindowFocus: true,
+  });
+}
+
+// Admin announcement to channels across several groups. Each target is sent
+// (and MLS-encrypted) separately on the Rust side; the report says which
+// channels got it.
+export function useBroadcastAnnouncement() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useMutation({
+    mutationFn: async ({ channelIds, content }: { channelIds: string[]; content: string }) => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<BroadcastReport>('broadcast_announcement', {
+        senderId: currentUser.id,
+        channelIds,
+        content,
+        senderUsername: currentUser.username ?? null,
+      });
+    },
+    onSuccess: (report) => {
+      for (const r of report.results) {
+        if (r.message_id) {
+          queryClient.invalidateQueries({ queryKey: messageQueryKeys.channel(r.channel_id) });
+          queryClient.invalidateQueries({ queryKey: lastMessageQueryKeys.channel(r.channel_id) });
+        }
+      }
+      queryClient.invalidateQueries({ queryKey: lastMessageQueryKeys.previews });
+    },
+  });
+}
+
 export function useLeaveDM() {
   const queryClient = useQueryClient();
   const currentUser = useObserver(() => appStore.currentUser);
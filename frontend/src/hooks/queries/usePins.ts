@@ -0,0 +1,59 @@
+import { useMemo } from "react";
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { invoke } from "../../bridge";
+import type { RawChannelMessage } from "./useMessages";
+
+// Mirrors `PinnedMessage` in pollis-core/src/commands/messages/pins.rs.
+// `message` is this device's local copy; null when the device never had it.
+export interface PinnedMessage {
+  conversation_id: string;
+  message_id: string;
+  pinned_by: string;
+  pinned_at: string;
+  message: RawChannelMessage | null;
+}
+
+export const pinQueryKeys = {
+  conversation: (conversationId: string | null) => ["pinnedMessages", conversationId] as const,
+};
+
+// Query: a channel's or DM's pins, newest first. Other members' changes
+// arrive as a `pins_changed` realtime event, which invalidates this key.
+export function usePinnedMessages(conversationId: string | null) {
+  return useQuery({
+    queryKey: pinQueryKeys.conversation(conversationId),
+    queryFn: async (): Promise<PinnedMessage[]> => {
+      if (!conversationId) {
+        return [];
+      }
+      return await invoke<PinnedMessage[]>("get_pinned_messages", { conversationId });
+    },
+    enabled: !!conversationId,
+    staleTime: 1000 * 60,
+  });
+}
+
+// The pinned message ids of a conversation, for marking rows in the list.
+export function usePinnedMessageIds(conversationId: string | null): Set<string> {
+  const { data } = usePinnedMessages(conversationId);
+  return useMemo(() => new Set((data ?? []).map((p) => p.message_id)), [data]);
+}
+
+type PinVariables = { conversationId: string; messageId: string; userId: string };
+
+// Mutation: pin or unpin. Unpinning someone else's pin needs group admin;
+// the Delivery Service enforces it and the cap on pins per conversation.
+export function useSetPinned() {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: async ({ pinned, ...vars }: PinVariables & { pinned: boolean }) => {
+      await invoke(pinned ? "pin_message" : "unpin_message", vars);
+    },
+    onSuccess: (_data, variables) => {
+      queryClient.invalidateQueries({
+        queryKey: pinQueryKeys.conversation(variables.conversationId),
+      });
+    },
+  });
+}
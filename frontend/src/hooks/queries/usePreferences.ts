@@ -1,4 +1,4 @@
-import { useCallback, useEffect } from "react";
+import { useCallback, useEffect, useMemo } from "react";
 import { useQuery, useQueryClient, type QueryClient } from "@tanstack/react-query";
 import { invoke, setTrayCloseToTray, setTrayEnabled } from "../../bridge";
 import { appStore } from "../../stores/appStore";
@@ -23,6 +23,12 @@ import {
  */
 export type NoiseSuppressionLevel = "off" | "low" | "moderate" | "high";
 
+/**
+ * Mirrors `voice::InputMode` in pollis-core: transmit whenever speaking, or
+ * only while the push-to-talk key is held.
+ */
+export type VoiceInputMode = "voice_activity" | "push_to_talk";
+
 /**
  * Network-privacy relay overlay mode. Mirrors the Rust `pollis_relay::OverlayMode`
  * that `get_overlay_mode` / `set_overlay_mode` (see `commands/overlay.rs`) parse
@@ -66,6 +72,12 @@ export interface PreferencesData {
   echo_cancellation?: boolean;
   /** RNNoise click/keystroke suppression (separate from APM's spectral NS). */
   click_suppression?: boolean;
+  /** Voice activity (default) or push-to-talk. */
+  voice_input_mode?: VoiceInputMode;
+  /** How quiet speech may be and still count. 0..=100; higher = quieter. */
+  vad_sensitivity?: number;
+  /** Stop transmitting after this many seconds without speech. 0 = off. */
+  vad_auto_mute_secs?: number;
   /**
    * Screen-share capture/encode framerate ceiling, in fps. One of
    * `SCREEN_SHARE_FPS_OPTIONS` (15 / 30 / 60). Read at share-start and passed
@@ -106,6 +118,14 @@ export interface PreferencesData {
    * in `src-tauri/src/commands/voice.rs`.
    */
   user_volumes?: { [userId: string]: number };
+  /**
+   * Pinned conversations and drag-reordered sidebar order; group ids and DM
+   * channel ids share both lists. Written only through `pin_conversation` /
+   * `set_sidebar_order` (see `useSidebarOrder.ts`) — the list commands apply
+   * it on the Rust side, so the frontend just renders in the order
+   * it receives.
+   */
+  sidebar_order?: { pinned?: string[]; order?: string[] };
   /**
    * Per-command keyboard shortcut overrides, keyed by `ShortcutCommandId`.
    * Values are canonical combo strings (e.g. `"mod+shift+k"`) understood by
@@ -120,6 +140,33 @@ export interface PreferencesData {
    * load. Absent → `off` (the direct path). See `OverlayMode`.
    */
   overlay_mode?: OverlayMode;
+  /**
+   * Whether this account broadcasts typing indicators at all. Synced so the
+   * choice holds on every device. Absent → true.
+   */
+  send_typing_indicators?: boolean;
+  /**
+   * Conversations (channel ids and DM ids) this account never sends typing
+   * indicators to, on top of the global switch. Written through
+   * `useTypingPrivacy().setMuted`.
+   */
+  typing_muted_conversations?: string[];
+  /**
+   * Link privacy, applied by `check_link` / `check_links` in
+   * pollis-core/src/commands/links.rs (which reads these keys itself):
+   * confirm before opening plain-http links, strip tracking parameters
+   * (`utm_*`, `fbclid`, …) from links before opening or previewing them, and
+   * show inline media previews at all. Each absent → true.
+   */
+  link_confirm_insecure?: boolean;
+  link_strip_tracking?: boolean;
+  link_previews?: boolean;
+  /**
+   * Members muted per group, keyed by group id. A muted member's messages are
+   * collapsed in that group's channels and their `@all` never pings. Only this
+   * account is affected. Written through `useMutedMembers().setMuted`.
+   */
+  muted_members?: { [groupId: string]: string[] };
 }
 
 // Voice-identity parsing (`userIdFromVoiceIdentity`) lives in
@@ -164,6 +211,9 @@ export const APM_DEFAULTS = {
   noise_suppression_level: "high" as NoiseSuppressionLevel,
   echo_cancellation: true,
   click_suppression: false,
+  voice_input_mode: "voice_activity" as VoiceInputMode,
+  vad_sensitivity: 50,
+  vad_auto_mute_secs: 0,
 } as const;
 
 /**
@@ -179,6 +229,9 @@ export interface ApmConfig {
   ns_level: NoiseSuppressionLevel;
   aec_enabled: boolean;
   click_suppression: boolean;
+  input_mode: VoiceInputMode;
+  vad_sensitivity: number;
+  vad_auto_mute_secs: number;
 }
 
 /**
@@ -194,9 +247,28 @@ export function preferencesToApmConfig(prefs: PreferencesData | undefined): ApmC
     ns_level: prefs?.noise_suppression_level ?? APM_DEFAULTS.noise_suppression_level,
     aec_enabled: prefs?.echo_cancellation ?? APM_DEFAULTS.echo_cancellation,
     click_suppression: prefs?.click_suppression ?? APM_DEFAULTS.click_suppression,
+    input_mode: prefs?.voice_input_mode === "push_to_talk" ? "push_to_talk" : "voice_activity",
+    vad_sensitivity: clampVadSensitivity(prefs?.vad_sensitivity ?? APM_DEFAULTS.vad_sensitivity),
+    vad_auto_mute_secs: clampAutoMute(prefs?.vad_auto_mute_secs ?? APM_DEFAULTS.vad_auto_mute_secs),
   };
 }
 
+/** Sensitivity is 0..=100; the backend clamps the same. */
+function clampVadSensitivity(v: number): number {
+  if (!Number.isFinite(v)) {
+    return APM_DEFAULTS.vad_sensitivity;
+  }
+  return Math.max(0, Math.min(100, Math.round(v)));
+}
+
+/** Seconds, sent as a u16. Negative or junk turns auto-mute off. */
+function clampAutoMute(v: number): number {
+  if (!Number.isFinite(v) || v < 0) {
+    return 0;
+  }
+  return Math.min(3600, Math.round(v));
+}
+
 /** AGC target is exposed in 3..=15 dB and the backend clamps the same. */
 function clampAgcTarget(v: number): number {
   if (!Number.isFinite(v)) {
@@ -319,6 +391,9 @@ export function usePreferences() {
         ),
         echo_cancellation: getPreference<boolean>(json, "echo_cancellation", APM_DEFAULTS.echo_cancellation),
         click_suppression: getPreference<boolean>(json, "click_suppression", APM_DEFAULTS.click_suppression),
+        voice_input_mode: getPreference<VoiceInputMode>(json, "voice_input_mode", APM_DEFAULTS.voice_input_mode),
+        vad_sensitivity: getPreference<number>(json, "vad_sensitivity", APM_DEFAULTS.vad_sensitivity),
+        vad_auto_mute_secs: getPreference<number>(json, "vad_auto_mute_secs", APM_DEFAULTS.vad_auto_mute_secs),
         screen_share_max_fps: clampScreenShareFps(
           getPreference<number>(json, "screen_share_max_fps", SCREEN_SHARE_FPS_DEFAULT),
         ),
@@ -340,6 +415,20 @@ export function usePreferences() {
         overlay_mode: normalizeOverlayMode(
           getPreference<string | undefined>(json, "overlay_mode", undefined),
         ),
+        send_typing_indicators: getPreference<boolean>(json, "send_typing_indicators", true),
+        typing_muted_conversations: getPreference<string[] | undefined>(
+          json,
+          "typing_muted_conversations",
+          undefined,
+        ),
+        link_confirm_insecure: getPreference<boolean>(json, "link_confirm_insecure", true),
+        link_strip_tracking: getPreference<boolean>(json, "link_strip_tracking", true),
+        link_previews: getPreference<boolean>(json, "link_previews", true),
+        muted_members: getPreference<{ [groupId: string]: string[] } | undefined>(
+          json,
+          "muted_members",
+          undefined,
+        ),
       };
     },
     enabled: !!currentUser,
@@ -372,6 +461,70 @@ export function useSkin(): Skin {
   return normalizeSkin(query.data?.skin);
 }
 
+/**
+ * Typing-indicator privacy for one conversation (`conversationId` is the
+ * channel id or DM id; null → nothing selected). `allowed` is false when the
+ * global switch is off or the conversation is muted; `setMuted` adds or drops
+ * it from the synced mute list. Until prefs load, typing is allowed — the
+ * default for an account that never touched either setting.
+ */
+export function useTypingPrivacy(conversationId: string | null) {
+  const { query, save } = usePreferences();
+  const muted = conversationId !== null
+    && (query.data?.typing_muted_conversations ?? []).includes(conversationId);
+  const allowed = (query.data?.send_typing_indicators ?? true) && !muted;
+
+  const setMuted = useCallback(
+    (value: boolean) => {
+      if (!conversationId) {
+        return;
+      }
+      const current = query.data?.typing_muted_conversations ?? [];
+      const next = value
+        ? Array.from(new Set([...current, conversationId]))
+        : current.filter((id) => id !== conversationId);
+      save({ ...(query.data ?? {}), typing_muted_conversations: next });
+    },
+    [conversationId, query.data, save],
+  );
+
+  return { allowed, muted, setMuted };
+}
+
+/**
+ * Members this account has muted in `groupId` (null → a DM, where nothing is
+ * muted; block the user instead). `setMuted` adds or drops one user from the
+ * synced list. `loaded` is false until preferences arrive; until then callers
+ * fall back to the `sender_muted` flag the read commands put on each message.
+ */
+export function useMutedMembers(groupId: string | null) {
+  const { query, save } = usePreferences();
+  const list = groupId ? query.data?.muted_members?.[groupId] : undefined;
+  const mutedIds = useMemo(() => new Set(list ?? []), [list]);
+
+  const setMuted = useCallback(
+    (userId: string, value: boolean) => {
+      if (!groupId) {
+        return;
+      }
+      const all = query.data?.muted_members ?? {};
+      const current = all[groupId] ?? [];
+      const next = value
+        ? Array.from(new Set([...current, userId]))
+        : current.filter((id) => id !== userId);
+      const { [groupId]: _previous, ...others } = all;
+      void _previous;
+      save({
+        ...(query.data ?? {}),
+        muted_members: next.length > 0 ? { ...others, [groupId]: next } : others,
+      });
+    },
+    [groupId, query.data, save],
+  );
+
+  return { mutedIds, loaded: query.data !== undefined, setMuted };
+}
+
 /**
  * Apply loaded preferences (accent_color, background_color) to CSS vars.
  * Call this once after the preferences query resolves.
@@ -0,0 +1,27 @@
+import { useQuery } from "@tanstack/react-query";
+import { invoke } from "../../bridge";
+
+// Mirrors `RelayLatencyReport` in pollis-core/src/net/relay_latency.rs.
+export interface RelayLatencySample {
+  at: number; // unix seconds
+  rtt_ms: number | null; // null = failed dial
+}
+
+export interface RelayLatencyReport {
+  addr: string;
+  ewma_ms: number | null;
+  preferred: boolean;
+  history: RelayLatencySample[]; // oldest first
+}
+
+// Query: per-relay dial latency for the overlay diagnostics. Refetched on
+// mount/focus only — the numbers come from real dials, there's nothing to poll.
+export function useRelayLatency(enabled: boolean) {
+  return useQuery({
+    queryKey: ["relay-latency"],
+    queryFn: () => invoke<RelayLatencyReport[]>("get_relay_latency"),
+    enabled,
+    staleTime: 1000 * 10,
+    refetchOnWindowFocus: true,
+  });
+}
@@ -0,0 +1,156 @@
+import { useEffect, useRef, useState } from "react";
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { invoke } from "../../bridge";
+import { appStore } from "../../stores/appStore";
+import { useObserver } from "mobx-react-lite";
+import { useUserGroupsWithChannels } from "./useGroups";
+import { notify } from "../../utils/notify";
+
+// Mirrors `Reminder` in pollis-core/src/commands/reminders.rs. Stored under
+// `reminders` in the synced preferences blob; `preview` is this device's local
+// copy of the message text, absent when the device never had the message.
+export interface Reminder {
+  id: string;
+  message_id: string;
+  conversation_id: string;
+  /** Unix ms. */
+  at: number;
+  created_at: number;
+  preview?: string | null;
+}
+
+export const reminderQueryKeys = {
+  list: (userId: string | null) => ["reminders", userId] as const,
+};
+
+// setTimeout takes a signed 32-bit delay; anything longer fires at once.
+const MAX_TIMER_MS = 2 ** 31 - 1;
+
+// Query: pending reminders, earliest first. Remote-first like preferences, so
+// a reminder made on another device shows up on the next refetch.
+export function useReminders() {
+  const currentUser = useObserver(() => appStore.currentUser);
+
+  return useQuery({
+    queryKey: reminderQueryKeys.list(currentUser?.id ?? null),
+    queryFn: async (): Promise<Reminder[]> => {
+      if (!currentUser) {
+        return [];
+      }
+      return await invoke<Reminder[]>("list_reminders", { userId: currentUser.id });
+    },
+    enabled: !!currentUser,
+    staleTime: 1000 * 60,
+  });
+}
+
+// Both commands rewrite the preferences blob, so the cached copy is refetched
+// too — otherwise the next preferences save would drop the change.
+function useInvalidateReminders() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+  return (reminders: Reminder[]) => {
+    const userId = currentUser?.id ?? null;
+    queryClient.setQueryData(reminderQueryKeys.list(userId), reminders);
+    queryClient.invalidateQueries({ queryKey: ["user", "preferences", userId] });
+  };
+}
+
+// Mutation: remind me about a message at `at` (unix ms). Replaces any
+// existing reminder for the same message.
+export function useCreateReminder() {
+  const currentUser = useObserver(() => appStore.currentUser);
+  const queryClient = useQueryClient();
+  const invalidate = useInvalidateReminders();
+
+  return useMutation({
+    mutationFn: async ({ messageId, at }: { messageId: string; at: number }): Promise<Reminder> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<Reminder>("create_reminder", { userId: currentUser.id, messageId, at });
+    },
+    onSuccess: (reminder) => {
+      const current =
+        queryClient.getQueryData<Reminder[]>(reminderQueryKeys.list(currentUser?.id ?? null)) ?? [];
+      invalidate(
+        [...current.filter((r) => r.message_id !== reminder.message_id), reminder].sort(
+          (a, b) => a.at - b.at,
+        ),
+      );
+    },
+  });
+}
+
+// Mutation: cancel a reminder, or clear one that has fired.
+export function useDeleteReminder() {
+  const currentUser = useObserver(() => appStore.currentUser);
+  const invalidate = useInvalidateReminders();
+
+  return useMutation({
+    mutationFn: async (reminderId: string): Promise<Reminder[]> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<Reminder[]>("delete_reminder", { userId: currentUser.id, reminderId });
+    },
+    onSuccess: invalidate,
+  });
+}
+
+// Fires reminders on this device. One timer for the earliest pending reminder,
+// re-armed whenever the list changes; reminders that came due while the app
+// was closed fire on launch. Firing raises a `reminder` notification whose
+// status-bar alert jumps to the message, then deletes the reminder so linked
+// devices don't fire it again once they refetch.
+export function useReminderScheduler() {
+  const { data: reminders } = useReminders();
+  const { data: groupsWithChannels } = useUserGroupsWithChannels();
+  const deleteReminder = useDeleteReminder();
+  const queryClient = useQueryClient();
+  const userId = useObserver(() => appStore.currentUser?.id ?? null);
+  // Read when the timer fires, so neither re-arms it.
+  const groupsRef = useRef(groupsWithChannels);
+  groupsRef.current = groupsWithChannels;
+  const deleteRef = useRef(deleteReminder.mutate);
+  deleteRef.current = deleteReminder.mutate;
+  // Bumped when a capped timer wakes before the reminder is due.
+  const [rearm, setRearm] = useState(0);
+  const next = reminders?.[0] ?? null;
+
+  useEffect(() => {
+    if (!next) {
+      return;
+    }
+    const timer = setTimeout(() => {
+      if (next.at > Date.now()) {
+        setRearm((n) => n + 1);
+        return;
+      }
+      const group = groupsRef.current?.find((g) =>
+        g.channels.some((c) => c.id === next.conversation_id),
+      );
+      const channel = group?.channels.find((c) => c.id === next.conversation_id);
+      notify("reminder", {
+        roomId: next.conversation_id,
+        title: group && channel ? `Reminder: #${channel.name} in ${group.name}` : "Reminder",
+        body: next.preview ?? "A message you asked to be reminded about",
+        senderUsername: "reminder",
+        reminder: { messageId: next.message_id, groupId: group?.id ?? null },
+      });
+      deleteRef.current(next.id, {
+        // Offline: drop it from this device's list so the next one arms. It
+        // comes back (and fires again) only if a later refetch still has it.
+        onError: (e) => {
+          console.warn("[reminders] delete_reminder failed:", e);
+          queryClient.setQueryData<Reminder[]>(reminderQueryKeys.list(userId), (list) =>
+            list?.filter((r) => r.id !== next.id),
+          );
+        },
+      });
+    }, Math.min(MAX_TIMER_MS, Math.max(0, next.at - Date.now())));
+    return () => clearTimeout(timer);
+    // `next` is keyed by id/at; a refetch returning the same head doesn't re-arm.
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [next?.id, next?.at, rearm]);
+}
@@ -0,0 +1,70 @@
+import { useMutation, useQueryClient } from "@tanstack/react-query";
+import { invoke } from "../../bridge";
+import { appStore } from "../../stores/appStore";
+import { useObserver } from "mobx-react-lite";
+import { groupQueryKeys } from "./useGroups";
+import { messageQueryKeys } from "./useMessages";
+
+// Mirrors `SidebarOrder` in pollis-core/src/commands/sidebar.rs. Stored under
+// `sidebar_order` in the synced preferences blob; the list commands
+// (`list_user_groups_with_channels`, `list_dm_channels`) already return rows
+// in this order, so callers never sort themselves.
+export interface SidebarOrder {
+  pinned: string[];
+  order: string[];
+}
+
+// Both commands rewrite the preferences blob, so the cached copy has to be
+// refetched too — otherwise the next preferences save would write back a blob
+// without the new `sidebar_order`.
+function useInvalidateSidebar() {
+  const queryClient = useQueryClient();
+  const currentUser = useObserver(() => appStore.currentUser);
+  return () => {
+    const userId = currentUser?.id ?? null;
+    queryClient.invalidateQueries({ queryKey: groupQueryKeys.userGroupsWithChannels(userId) });
+    queryClient.invalidateQueries({ queryKey: groupQueryKeys.userGroups(userId) });
+    queryClient.invalidateQueries({ queryKey: messageQueryKeys.dmConversations(userId) });
+    queryClient.invalidateQueries({ queryKey: ["user", "preferences", userId] });
+  };
+}
+
+// Mutation: pin or unpin a group or DM conversation at the top of its section.
+export function usePinConversation() {
+  const currentUser = useObserver(() => appStore.currentUser);
+  const invalidate = useInvalidateSidebar();
+
+  return useMutation({
+    mutationFn: async ({ targetId, pinned }: { targetId: string; pinned: boolean }): Promise<SidebarOrder> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<SidebarOrder>("pin_conversation", {
+        userId: currentUser.id,
+        targetId,
+        pinned,
+      });
+    },
+    onSuccess: invalidate,
+  });
+}
+
+// Mutation: save the full sidebar order after a drag-reorder. `ids` is every
+// group id followed by every DM id, in their new order.
+export function useSetSidebarOrder() {
+  const currentUser = useObserver(() => appStore.currentUser);
+  const invalidate = useInvalidateSidebar();
+
+  return useMutation({
+    mutationFn: async (ids: string[]): Promise<SidebarOrder> => {
+      if (!currentUser) {
+        throw new Error("No current user");
+      }
+      return await invoke<SidebarOrder>("set_sidebar_order", {
+        userId: currentUser.id,
+        ids,
+      });
+    },
+    onSuccess: invalidate,
+  });
+}
@@ -0,0 +1,51 @@
+import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
+import { invoke } from "../../bridge";
+
+// On-device message translation. The backend is a local executable the user
+// points Pollis at; the Rust core feeds it decrypted text on stdin and caches
+// the result in the local DB, so plaintext never leaves the device. The
+// backend path and each conversation's target language are device-local
+// (`ui_state`), never synced.
+
+export const translationQueryKeys = {
+  backend: ["translation", "backend"] as const,
+};
+
+// Query: the configured backend path, or null when translation is off.
+export function useTranslationBackend() {
+  return useQuery({
+    queryKey: translationQueryKeys.backend,
+    queryFn: async (): Promise<string | null> => {
+      return await invoke<string | null>("get_translation_backend");
+    },
+    staleTime: 1000 * 60 * 5,
+  });
+}
+
+// Mutation: set the backend path (absolute), or clear it with null / "".
+export function useSetTranslationBackend() {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: async (path: string | null): Promise<void> => {
+      await invoke("set_translation_backend", { path: path || null });
+    },
+    onSuccess: () => {
+      void queryClient.invalidateQueries({ queryKey: translationQueryKeys.backend });
+    },
+  });
+}
+
+// Translate one message. `targetLang` null uses the conversation's language
+// (set with `/translate <lang>`).
+// Translations are cached in the local DB, so this is cheap to repeat.
+export function useTranslateMessage() {
+  return useMutation({
+    mutationFn: async (vars: { messageId: string; targetLang: string | null }): Promise<string> => {
+      return await invoke<string>("translate_message", {
+        messageId: vars.messageId,
+        targetLang: vars.targetLang,
+      });
+    },
+  });
+}
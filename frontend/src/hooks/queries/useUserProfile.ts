@@ -5,6 +5,7 @@ import { appStore } from "../../stores/appStore";
 import { useObserver } from "mobx-react-lite";
 import { messageQueryKeys } from "./useMessages";
 import { groupQueryKeys } from "./useGroups";
+import { useLowBandwidthMode } from "../../utils/lowBandwidth";
 
 export interface ServiceUserData {
   username: string;
@@ -54,6 +55,11 @@ export interface SafetyNumberInfo {
   qr_payload: string;
 }
 
+export interface IdentityFingerprint {
+  /// 30 digits in 5-digit blocks — the user's half of every safety number.
+  fingerprint: string;
+}
+
 export interface PeerVerificationEntry {
   peer_user_id: string;
   verified: boolean;
@@ -99,6 +105,22 @@ export function useSafetyNumber(peerUserId: string | null | undefined) {
   });
 }
 
+// Own fingerprint, from the account key on this device. Only changes on an
+// identity reset, which re-signs-in, so it never goes stale in a session.
+export function useIdentityFingerprint() {
+  const currentUser = useObserver(() => appStore.currentUser);
+  return useQuery({
+    queryKey: ["safety", "fingerprint", currentUser?.id ?? null] as const,
+    queryFn: async (): Promise<IdentityFingerprint> => {
+      return await invoke<IdentityFingerprint>("get_identity_fingerprint", {
+        userId: currentUser!.id,
+      });
+    },
+    enabled: !!currentUser,
+    staleTime: Infinity,
+  });
+}
+
 export function useSetContactVerified(peerUserId: string | null | undefined) {
   const queryClient = useQueryClient();
   return useMutation({
@@ -116,6 +138,47 @@ export function useSetContactVerified(peerUserId: string | null | undefined) {
   });
 }
 
+// Local-only nickname + note for a contact (`contact_note` in the encrypted
+// local DB; never synced). The nickname shows up in member and DM listings.
+export interface ContactNote {
+  user_id: string;
+  nickname: string | null;
+  notes: string | null;
+  updated_at: string;
+}
+
+export const contactNoteQueryKeys = {
+  note: (userId: string | null) => ["contact-note", userId] as const,
+};
+
+export function useContactNote(userId: string | null | undefined) {
+  return useQuery({
+    queryKey: contactNoteQueryKeys.note(userId ?? null),
+    queryFn: async (): Promise<ContactNote | null> => {
+      return await invoke<ContactNote | null>("get_contact_note", { userId });
+    },
+    enabled: !!userId,
+  });
+}
+
+export function useSaveContactNote(userId: string | null | undefined) {
+  const queryClient = useQueryClient();
+  return useMutation({
+    mutationFn: async (vars: { nickname: string; notes: string }) => {
+      await invoke("set_contact_nickname", { userId, nickname: vars.nickname || null });
+      await invoke("set_contact_notes", { userId, notes: vars.notes || null });
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: contactNoteQueryKeys.note(userId ?? null) });
+      // Member and DM listings resolve nicknames in the Rust core; refetch them.
+      queryClient.invalidateQueries({
+        predicate: (q) => q.queryKey[0] === "groups" && q.queryKey[2] === "members",
+      });
+      queryClient.invalidateQueries({ queryKey: ["dm-conversations"] });
+    },
+  });
+}
+
 export function useUserProfile() {
   const currentUser = useObserver(() => appStore.currentUser);
 
@@ -210,6 +273,9 @@ export function useUserAvatar() {
 }
 
 export function useAvatarBlobUrl(avatarKey: string | null | undefined) {
+  // Low-bandwidth mode stops new fetches; avatars already in the query cache
+  // still render.
+  const lowBandwidth = useLowBandwidthMode();
   return useQuery({
     queryKey: ["avatar-blob", avatarKey ?? null],
     queryFn: async (): Promise<string | null> => {
@@ -219,10 +285,14 @@ export function useAvatarBlobUrl(avatarKey: string | null | undefined) {
       const { getFileDownloadUrl } = await import("../../services/r2-upload");
       return await getFileDownloadUrl(avatarKey);
     },
-    enabled: !!avatarKey,
+    enabled: !!avatarKey && !lowBandwidth,
     staleTime: 1000 * 60 * 30,
     gcTime: 1000 * 60 * 60,
     retry: 1,
+    // download_file serves from the local avatar cache (stale when offline)
+    // and revalidates against R2, so re-asking on reconnect is cheap and picks
+    // up avatars that changed while offline.
+    refetchOnReconnect: "always",
   });
 }
 
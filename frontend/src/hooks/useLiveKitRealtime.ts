@@ -13,7 +13,10 @@ import { lastMessageQueryKeys, messageQueryKeys, useDMConversations, markIngeste
 import { invalidateVoiceRoom, voiceQueryKeys } from './queries/useVoiceParticipants';
 import { usePreferences } from './queries/usePreferences';
 import { groupQueryKeys, useUserGroupsWithChannels } from './queries/useGroups';
-import { notify, setNotifyPrefs, loadDeviceCallRingtone } from '../utils/notify';
+import { pinQueryKeys } from './queries/usePins';
+import { blocksQueryKeys } from './queries/useBlocks';
+import type { DmChannel, IngestedMessages } from '../types';
+import { notify, setNotifyPrefs, loadDeviceCallRingtone, resetQuietHoursDigest } from '../utils/notify';
 import { logIgnored } from '../utils/log';
 import { typingStore, typingRoomKey } from '../stores/typingStore';
 import { presenceStore } from '../stores/presenceStore';
@@ -103,6 +106,10 @@ type RealtimeEvent =
     message_id: string;
     deleted_by: string;
   }
+  | {
+    type: 'pins_changed';
+    conversation_id: string;
+  }
   | {
     type: 'enrollment_requested';
     request_id: string;
@@ -197,21 +204,31 @@ export function useLiveKitRealtime() {
   // ── Room name lookup (for notification titles) ────────────────────────────
 
   const roomNameMapRef = useRef<Map<string, string>>(new Map());
+  // Room id → the text channels it carries, for the reconnect backfill. DM
+  // rooms aren't in here; their room id is the conversation id.
+  const roomChannelsRef = useRef<Map<string, string[]>>(new Map());
   useEffect(() => {
     const map = new Map<string, string>();
+    const channels = new Map<string, string[]>();
     if (groupsWithChannels) {
       for (const group of groupsWithChannels) {
+        const textChannelIds: string[] = [];
         for (const channel of group.channels) {
           map.set(channel.id, `${group.name} / #${channel.name}`);
+          if (channel.channel_type === 'text') {
+            textChannelIds.push(channel.id);
+          }
         }
+        channels.set(group.id, textChannelIds);
       }
     }
     if (dmConversations) {
       for (const conv of dmConversations) {
-        map.set(conv.id, conv.user2_identifier);
+        map.set(conv.id, conv.user2_nickname ?? conv.user2_identifier);
       }
     }
     roomNameMapRef.current = map;
+    roomChannelsRef.current = channels;
   }, [groupsWithChannels, dmConversations]);
 
   // ── Refs to avoid stale closures in the channel handler ───────────────────
@@ -238,6 +255,9 @@ export function useLiveKitRealtime() {
   const activeVoiceChannelIdRef = useRef<string | null>(activeVoiceChannelId);
   useEffect(() => { activeVoiceChannelIdRef.current = activeVoiceChannelId; }, [activeVoiceChannelId]);
 
+  const prefsQueryDataRef = useRef(prefsQuery.data);
+  useEffect(() => { prefsQueryDataRef.current = prefsQuery.data; }, [prefsQuery.data]);
+
   // ── Notification permission + prefs → notify() ────────────────────────────
   // Re-checks the OS permission whenever the user's notification preference
   // changes so toggling "on" in Preferences → granting the OS prompt →
@@ -317,22 +337,25 @@ export function useLiveKitRealtime() {
     // Pull new envelopes for a conversation, then invalidate so the local
     // read picks them up. The local-first read path no longer runs ingest
     // inside the queryFn, so realtime hints must drive it explicitly.
+    // Resolves to who sent the newly ingested messages per conversation, or
+    // null when the ingest failed.
     const ingestAndInvalidate = (
       channelId: string | null,
       conversationId: string | null,
-    ) => {
+    ): Promise<IngestedMessages[] | null> => {
       const targetId = channelId ?? conversationId;
       if (!targetId) {
-        return;
+        return Promise.resolve(null);
       }
       const command = channelId ? 'ingest_channel_envelopes' : 'ingest_dm_envelopes';
       const args = channelId
         ? { userId: currentUser.id, channelId }
         : { userId: currentUser.id, dmChannelId: conversationId };
       markIngested(targetId);
-      invoke(command, args)
+      return invoke<IngestedMessages[]>(command, args)
         .catch((err) => {
           console.warn(`[realtime] ${command} failed:`, err);
+          return null;
         })
         .finally(() => {
           if (channelId) {
@@ -469,11 +492,17 @@ export function useLiveKitRealtime() {
         return;
       }
 
+      if (event.type === 'pins_changed') {
+        queryClientRef.current.invalidateQueries({ queryKey: pinQueryKeys.conversation(event.conversation_id) });
+        return;
+      }
+
       if (event.type === 'realtime_reconnected') {
         // The event stream doesn't replay missed events, so resync state
         // that may have drifted during the outage.
         queryClientRef.current.invalidateQueries({ queryKey: voiceQueryKeys.allRoomCounts });
         queryClientRef.current.invalidateQueries({ queryKey: voiceQueryKeys.allParticipants });
+        queryClientRef.current.invalidateQueries({ queryKey: ['pinnedMessages'] });
         // Wipe stale presence for the reconnected room — Rust will re-emit
         // a fresh participant snapshot right after.
         presenceStore.resetRoom(event.room_id);
@@ -501,6 +530,27 @@ export function useLiveKitRealtime() {
           } catch (err) {
             console.warn('[realtime] reconnect: process_pending_commits failed:', err);
           }
+          // process_pending_commits is the interleaved catch-up: it has
+          // already ingested every conversation bound to this room, including
+          // messages whose new_message pings were lost with the stream. Only
+          // the rendered lists are stale now, so refetch them. Done even when
+          // the catch-up errored: it may have ingested part of the backlog.
+          const channelIds = roomChannelsRef.current.get(event.room_id);
+          if (channelIds) {
+            for (const channelId of channelIds) {
+              queryClientRef.current.invalidateQueries({ queryKey: messageQueryKeys.channel(channelId) });
+            }
+          } else {
+            queryClientRef.current.invalidateQueries({ queryKey: messageQueryKeys.conversation(event.room_id) });
+          }
+          queryClientRef.current.invalidateQueries({ queryKey: lastMessageQueryKeys.all });
+        } else {
+          // The inbox room is this user's own connection coming back, so run
+          // the whole crypto-obligations queue (cert re-signing, key packages,
+          // every conversation) — progress shows in the status bar.
+          invoke('catch_up_all_mls_groups', { userId: currentUser.id }).catch((err) => {
+            console.warn('[realtime] reconnect: catch_up_all_mls_groups failed:', err);
+          });
         }
         return;
       }
@@ -620,6 +670,9 @@ export function useLiveKitRealtime() {
         queryClientRef.current.invalidateQueries({
           queryKey: groupQueryKeys.members(event.conversation_id),
         });
+        queryClientRef.current.invalidateQueries({
+          queryKey: groupQueryKeys.events(event.conversation_id),
+        });
         return;
       }
 
@@ -695,6 +748,14 @@ export function useLiveKitRealtime() {
         if (event.sender_id === currentUserIdRef.current) {
           return;
         }
+        // A member muted in this channel's group never pings (see
+        // `useMutedMembers`).
+        const mutedByGroup = prefsQueryDataRef.current?.muted_members ?? {};
+        for (const [groupId, channelIds] of roomChannelsRef.current) {
+          if (channelIds.includes(event.channel_id) && mutedByGroup[groupId]?.includes(event.sender_id)) {
+            return;
+          }
+        }
         const senderUsername = event.sender_username ?? 'Someone';
         const title = roomNameMapRef.current.get(event.channel_id) ?? 'New mention';
         notify('all_mention', {
@@ -721,7 +782,7 @@ export function useLiveKitRealtime() {
 
       // Ingest the new envelope, then invalidate the affected room's
       // query and last-message preview so they pick the new message up.
-      ingestAndInvalidate(channelId, conversationId);
+      const ingested = ingestAndInvalidate(channelId, conversationId);
 
       const isSelected =
         (channelId && channelId === selectedChannelIdRef.current) ||
@@ -730,6 +791,37 @@ export function useLiveKitRealtime() {
         return;
       }
 
+      // A DM still sitting in Requests already pinged once as dm_request;
+      // further messages stay quiet until it's accepted.
+      if (conversationId) {
+        const requests = queryClientRef.current.getQueryData<DmChannel[]>(
+          blocksQueryKeys.dmRequests(currentUserIdRef.current),
+        );
+        if (requests?.some((r) => r.id === conversationId)) {
+          return;
+        }
+      }
+
+      // Stay quiet (no ping, no badge) when everything this ping brought in
+      // came from members muted in the channel's group (see
+      // `useMutedMembers`). Only waits on the ingest when someone is muted;
+      // a failed or empty ingest notifies as before.
+      if (channelId) {
+        const mutedByGroup = prefsQueryDataRef.current?.muted_members ?? {};
+        let muted: string[] = [];
+        for (const [groupId, channelIds] of roomChannelsRef.current) {
+          if (channelIds.includes(channelId)) {
+            muted = mutedByGroup[groupId] ?? [];
+          }
+        }
+        if (muted.length > 0) {
+          const senders = (await ingested)?.find((i) => i.conversation_id === channelId)?.sender_ids ?? [];
+          if (senders.length > 0 && senders.every((id) => muted.includes(id))) {
+            return;
+          }
+        }
+      }
+
       const title = roomNameMapRef.current.get(incomingId) ?? 'New message';
       const body = `${senderUsername}: New message`;
       notify(conversationId ? 'direct_message' : 'channel_message', {
@@ -745,6 +837,7 @@ export function useLiveKitRealtime() {
     });
 
     return () => {
+      resetQuietHoursDigest();
       // Disconnect all rooms when the user logs out.
       invoke('connect_rooms', {
         roomIds: [],
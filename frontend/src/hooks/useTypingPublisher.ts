@@ -3,6 +3,7 @@ import { invoke } from "../bridge";
 import { appStore } from "../stores/appStore";
 import { useObserver } from "mobx-react-lite";
 import { TYPING_REFRESH_MS } from "../stores/typingStore";
+import { useTypingPrivacy } from "./queries/usePreferences";
 
 /**
  * Returns a `notify(value)` callback that the chat input should fire on every
@@ -14,6 +15,10 @@ import { TYPING_REFRESH_MS } from "../stores/typingStore";
  * The publish target is a LiveKit room — `roomId` is the group's MLS group
  * id for channels and the DM conversation id for DMs. Pass `null` for
  * either id when not applicable; the receiver routes by whichever is set.
+ *
+ * Nothing is published while typing indicators are turned off, globally or
+ * for this conversation (`useTypingPrivacy`). Peers then simply never see
+ * this user typing there.
  */
 export function useTypingPublisher(args: {
   roomId: string | null;
@@ -22,6 +27,7 @@ export function useTypingPublisher(args: {
 }) {
   const { roomId, channelId, conversationId } = args;
   const currentUser = useObserver(() => appStore.currentUser);
+  const { allowed } = useTypingPrivacy(channelId ?? conversationId ?? null);
 
   // We avoid hammering publish_typing on every keystroke by tracking the
   // last-sent timestamp and only re-emitting once the throttle window has
@@ -37,6 +43,11 @@ export function useTypingPublisher(args: {
       if (!roomId || !currentUser) {
         return;
       }
+      // A trailing stop still goes out after the switch flips mid-keystroke,
+      // so peers don't keep rendering a stale "typing…".
+      if (!allowed && isTyping) {
+        return;
+      }
       invoke("publish_typing", {
         roomId,
         channelId: channelId ?? null,
@@ -49,7 +60,7 @@ export function useTypingPublisher(args: {
         console.warn("[typing] publish_typing failed:", err);
       });
     },
-    [roomId, channelId, conversationId, currentUser],
+    [roomId, channelId, conversationId, currentUser, allowed],
   );
 
   const stop = useCallback(() => {
@@ -71,6 +82,9 @@ export function useTypingPublisher(args: {
         stop();
         return;
       }
+      if (!allowed) {
+        return;
+      }
       const now = Date.now();
       if (now - lastSentRef.current >= TYPING_REFRESH_MS) {
         lastSentRef.current = now;
@@ -87,7 +101,7 @@ export function useTypingPublisher(args: {
         stop();
       }, TYPING_REFRESH_MS);
     },
-    [publish, stop],
+    [publish, stop, allowed],
   );
 
   // Stop on unmount (component teardown / navigation away) and also when
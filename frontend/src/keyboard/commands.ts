@@ -17,6 +17,7 @@ export type ShortcutCommandId =
   | "app.sync"
   | "nav.back"
   | "voice.toggleMute"
+  | "voice.pushToTalk"
   | "voice.leave";
 
 export type ShortcutCategory = "Application" | "Navigation" | "Voice";
@@ -90,6 +91,12 @@ export const SHORTCUT_COMMANDS: Record<
     category: "Voice",
     defaultCombo: "mod+shift+m",
   },
+  "voice.pushToTalk": {
+    id: "voice.pushToTalk",
+    title: "Push to talk (hold)",
+    category: "Voice",
+    defaultCombo: "f8",
+  },
   "voice.leave": {
     id: "voice.leave",
     title: "Leave call",
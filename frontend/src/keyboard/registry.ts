@@ -10,9 +10,14 @@
 // listener. Capture-phase modal-cancel handlers (ChatInput, MainContent,
 // MessageItem, VoiceChannel) still run first and may stopImmediatePropagation
 // to claim Escape before nav.back ever sees it — that behavior is preserved.
+//
+// Hold commands (voice.pushToTalk) also register `release`: the matching
+// keyup — on the key alone, so letting go of a modifier first still counts —
+// or the window losing focus ends the hold. Auto-repeat keydowns while held
+// are swallowed rather than re-invoking.
 
 import { resolveCombo } from "./bindings";
-import { comboMatchesEvent, parseCombo } from "./keyCombo";
+import { comboMatchesEvent, normalizeKey, parseCombo } from "./keyCombo";
 import type { ShortcutCommandId } from "./commands";
 
 export interface ShortcutRegistration {
@@ -23,17 +28,23 @@ export interface ShortcutRegistration {
   priority: number;
   /** preventDefault on a match. Default true; nav.back opts out. */
   preventDefault: boolean;
+  /** Makes this a hold command: called when the pressed key is released. */
+  release?: (e: KeyboardEvent | null) => void;
 }
 
 // Token identity guards against StrictMode / fast-refresh double-invokes:
 // unregister only clears the slot if it still holds *this* registration.
 const registry = new Map<ShortcutCommandId, ShortcutRegistration>();
 const tokens = new Map<ShortcutCommandId, object>();
+// Hold commands currently pressed, with the key that will release them.
+const held = new Map<ShortcutCommandId, string>();
 
 let listenerAttached = false;
 
 function onKeyDown(e: KeyboardEvent): void {
   let best: ShortcutRegistration | null = null;
+  let bestId: ShortcutCommandId | null = null;
+  let bestKey = "";
 
   for (const [id, reg] of registry) {
     if (!reg.enabled) {
@@ -45,29 +56,63 @@ function onKeyDown(e: KeyboardEvent): void {
     }
     if (!best || reg.priority > best.priority) {
       best = reg;
+      bestId = id;
+      bestKey = parsed.key;
     }
   }
 
-  if (!best) {
+  if (!best || !bestId) {
     return;
   }
   if (best.preventDefault) {
     e.preventDefault();
   }
+  if (best.release) {
+    if (held.has(bestId)) {
+      return;
+    }
+    held.set(bestId, bestKey);
+  }
   best.invoke(e);
 }
 
+function onKeyUp(e: KeyboardEvent): void {
+  const key = normalizeKey(e.key);
+  for (const [id, heldKey] of held) {
+    if (heldKey === key) {
+      releaseHeld(id, e);
+    }
+  }
+}
+
+function onBlur(): void {
+  for (const id of [...held.keys()]) {
+    releaseHeld(id, null);
+  }
+}
+
+function releaseHeld(id: ShortcutCommandId, e: KeyboardEvent | null): void {
+  if (!held.delete(id)) {
+    return;
+  }
+  registry.get(id)?.release?.(e);
+}
+
 function ensureListener(): void {
   if (listenerAttached) {
     return;
   }
   window.addEventListener("keydown", onKeyDown);
+  window.addEventListener("keyup", onKeyUp);
+  window.addEventListener("blur", onBlur);
   listenerAttached = true;
 }
 
 function maybeDetachListener(): void {
   if (listenerAttached && registry.size === 0) {
     window.removeEventListener("keydown", onKeyDown);
+    window.removeEventListener("keyup", onKeyUp);
+    window.removeEventListener("blur", onBlur);
     listenerAttached = false;
   }
 }
@@ -89,6 +134,9 @@ export function registerShortcut(
 
   return () => {
     if (tokens.get(id) === token) {
+      // Don't leave a hold stuck on when its owner goes away (e.g. leaving
+      // the call mid-press).
+      releaseHeld(id, null);
       registry.delete(id);
       tokens.delete(id);
       maybeDetachListener();
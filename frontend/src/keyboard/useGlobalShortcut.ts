@@ -14,6 +14,11 @@ export interface UseGlobalShortcutOptions {
   priority?: number;
   /** preventDefault on match. Default true; pass false for nav.back. */
   preventDefault?: boolean;
+  /**
+   * Makes this a hold command (push-to-talk): called when the key is let go
+   * or the window loses focus. Key auto-repeat doesn't re-fire `handler`.
+   */
+  onRelease?: () => void;
 }
 
 /**
@@ -31,6 +36,9 @@ export function useGlobalShortcut(
 ): void {
   const handlerRef = useRef(handler);
   handlerRef.current = handler;
+  const releaseRef = useRef(options?.onRelease);
+  releaseRef.current = options?.onRelease;
+  const holdable = options?.onRelease !== undefined;
 
   const enabled = options?.enabled ?? true;
   const priority = options?.priority ?? 0;
@@ -42,6 +50,7 @@ export function useGlobalShortcut(
       enabled,
       priority,
       preventDefault,
+      release: holdable ? () => releaseRef.current?.() : undefined,
     });
-  }, [id, enabled, priority, preventDefault]);
+  }, [id, enabled, priority, preventDefault, holdable]);
 }
@@ -1,6 +1,6 @@
 import React, { useEffect } from "react";
 import { useNavigate, useParams } from "@tanstack/react-router";
-import { Pencil, Trash2 } from "lucide-react";
+import { Pencil, Timer, Trash2 } from "lucide-react";
 import { MainContent } from "../components/Layout/MainContent";
 import { useUserGroupsWithChannels } from "../hooks/queries/useGroups";
 import { appStore } from "../stores/appStore";
@@ -53,6 +53,17 @@ export const ChannelPage: React.FC = observer(() => {
         }}
       >
         <span className="flex-1">{title}</span>
+        {/* Admin-set retention: history here is ephemeral for everyone. */}
+        {channel?.retention_days ? (
+          <span
+            data-testid="channel-retention-indicator"
+            className="flex items-center gap-1 mr-3"
+            title={`Messages in this channel are deleted after ${channel.retention_days} day${channel.retention_days === 1 ? "" : "s"}, for everyone`}
+          >
+            <Timer size={12} aria-hidden="true" />
+            {channel.retention_days}d
+          </span>
+        ) : null}
         {isAdmin && channel && pendingDeleteChannelId !== channelId && (
           <div className="flex items-center gap-2">
             <button
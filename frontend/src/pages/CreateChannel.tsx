@@ -72,6 +72,7 @@ export const CreateChannel: React.FC<CreateChannelProps> = observer(({ onSuccess
         name: channel.name,
         description: channel.description || '',
         channel_type: channel.channel_type as Channel['channel_type'],
+        retention_days: null,
         created_by: currentUser.id,
         created_at: Date.now(),
         updated_at: Date.now(),
@@ -46,6 +46,12 @@ export const CreateGroup: React.FC<CreateGroupProps> = observer(({ onSuccess })
     setIsLoading(true);
     setError(null);
     try {
+      // Reserve first so two people creating "Book Club" at once can't both
+      // get /g/book-club. The DS returns the canonical form it reserved.
+      const reservedSlug = await invoke<string>('reserve_group_slug', {
+        slug: finalSlug,
+        userId: currentUser.id,
+      });
       const group = await invoke<{ id: string; name: string; description?: string; owner_id: string; created_at: string }>(
         'create_group',
         {
@@ -54,11 +60,12 @@ export const CreateGroup: React.FC<CreateGroupProps> = observer(({ onSuccess })
           ownerId: currentUser.id,
           createDefaultTextChannel: createTextChannel,
           createDefaultVoiceChannel: createVoiceChannel,
+          slug: reservedSlug,
         },
       );
       const groupData: Group = {
         id: group.id,
-        slug: finalSlug,
+        slug: reservedSlug,
         name: group.name,
         description: group.description || '',
         created_by: group.owner_id,
@@ -4,7 +4,9 @@ import { useNavigate, useParams } from "@tanstack/react-router";
 import { appStore } from "../stores/appStore";
 import { observer } from "mobx-react-lite";
 import { useLeaveDM } from "../hooks/queries/useMessages";
+import { useTypingPrivacy } from "../hooks/queries/usePreferences";
 import { Button } from "../components/ui/Button";
+import { Switch } from "../components/ui/Switch";
 import { PageShell } from "../components/Layout/PageShell";
 
 export const DMSettingsPage: React.FC = observer(() => {
@@ -12,6 +14,7 @@ export const DMSettingsPage: React.FC = observer(() => {
   const { conversationId } = useParams({ from: "/dms/$conversationId/settings" });
   const { setSelectedConversationId } = appStore;
   const leaveDMMutation = useLeaveDM();
+  const typingPrivacy = useTypingPrivacy(conversationId);
 
   return (
     <PageShell title="Conversation Settings">
@@ -21,6 +24,15 @@ export const DMSettingsPage: React.FC = observer(() => {
             {errorMessage(leaveDMMutation.error, "Failed to leave conversation")}
           </p>
         )}
+        <div className="w-full max-w-[280px]">
+          <Switch
+            id="dm-settings-typing"
+            data-testid="dm-settings-typing"
+            label="Send typing indicators here"
+            checked={!typingPrivacy.muted}
+            onChange={(val) => typingPrivacy.setMuted(!val)}
+          />
+        </div>
         <Button
           data-testid="dm-settings-leave-button"
           onClick={async () => {
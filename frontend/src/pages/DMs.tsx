@@ -82,7 +82,7 @@ export const DMsPage: React.FC = observer(() => {
         ) : undefined;
         return {
           id: c.id,
-          label: c.user2_identifier,
+          label: c.user2_nickname ?? c.user2_identifier,
           icon: (
             <PresenceAvatar
               userId={c.user2_id ?? null}
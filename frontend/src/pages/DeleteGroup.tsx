@@ -0,0 +1,106 @@
+import { errorMessage } from "../utils/errorMessage";
+import React from "react";
+import { useNavigate, useParams } from "@tanstack/react-router";
+import { appStore } from "../stores/appStore";
+import { observer } from "mobx-react-lite";
+import {
+  useCancelGroupDeletion,
+  useDeleteGroup,
+  useUserGroupsWithChannels,
+} from "../hooks/queries/useGroups";
+import { Button } from "../components/ui/Button";
+import { PageShell } from "../components/Layout/PageShell";
+import { formatShortDateTime } from "../utils/format";
+
+// Owner-only. Deleting is two-phase: this schedules it, members see it in the
+// group timeline and a banner for 7 days, and the server purges the group
+// afterwards. Until then the owner can cancel from here or from the banner.
+export const DeleteGroupPage: React.FC = observer(() => {
+  const navigate = useNavigate();
+  const { groupId } = useParams({ from: "/groups/$groupId/delete" });
+  const { currentUser } = appStore;
+  const deleteGroupMutation = useDeleteGroup();
+  const cancelDeletionMutation = useCancelGroupDeletion();
+
+  const { data: groupsWithChannels, isLoading } = useUserGroupsWithChannels();
+  const group = groupsWithChannels?.find((g) => g.id === groupId);
+
+  if (isLoading || !group) {
+    return null;
+  }
+
+  const isOwner = !!currentUser && group.created_by === currentUser.id;
+  const backToGroup = () => navigate({ to: "/groups/$groupId", params: { groupId } });
+  const mutation = group.deletion_purge_after ? cancelDeletionMutation : deleteGroupMutation;
+
+  return (
+    <PageShell title="Delete Group">
+      <div className="h-full flex flex-col items-center justify-center gap-4 px-6">
+        {!isOwner ? (
+          <p className="text-xs font-mono text-center text-dim">
+            Only the owner can delete <strong>{group.name}</strong>.
+          </p>
+        ) : group.deletion_purge_after ? (
+          <p data-testid="delete-group-scheduled" className="text-xs font-mono text-center text-dim">
+            <strong>{group.name}</strong> will be deleted around{" "}
+            {formatShortDateTime(`${group.deletion_purge_after.replace(" ", "T")}Z`)}.
+            <br />
+            Members have been told; you can still cancel.
+          </p>
+        ) : (
+          <p className="text-xs font-mono text-center text-dim">
+            Delete <strong>{group.name}</strong> for everyone?
+            <br />
+            Members are told now and the group is deleted in 7 days, with all
+            its channels and messages on the server. You can cancel until then.
+          </p>
+        )}
+        {mutation.isError && (
+          <p className="text-xs font-mono text-danger">
+            {errorMessage(mutation.error, "Failed to update group deletion")}
+          </p>
+        )}
+        <div className="flex gap-3">
+          {isOwner && !group.deletion_purge_after && (
+            <Button
+              data-testid="delete-group-confirm"
+              variant="danger"
+              onClick={async () => {
+                try {
+                  await deleteGroupMutation.mutateAsync({ groupId: group.id });
+                } catch {
+                  // error shown via isError above
+                }
+              }}
+              disabled={deleteGroupMutation.isPending}
+              isLoading={deleteGroupMutation.isPending}
+              loadingText="Scheduling…"
+            >
+              Delete in 7 days
+            </Button>
+          )}
+          {isOwner && group.deletion_purge_after && (
+            <Button
+              data-testid="delete-group-cancel-deletion"
+              onClick={async () => {
+                try {
+                  await cancelDeletionMutation.mutateAsync({ groupId: group.id });
+                } catch {
+                  // error shown via isError above
+                }
+              }}
+              disabled={cancelDeletionMutation.isPending}
+              isLoading={cancelDeletionMutation.isPending}
+              loadingText="Cancelling…"
+            >
+              Cancel deletion
+            </Button>
+          )}
+          <Button data-testid="delete-group-back" variant="secondary" onClick={backToGroup}>
+            Back
+          </Button>
+        </div>
+      </div>
+    </PageShell>
+  );
+});
@@ -1,6 +1,6 @@
 import React, { useMemo } from "react";
 import { useNavigate, useParams } from "@tanstack/react-router";
-import { ArrowLeft, Hash, Plus, Volume2, Users, UserPlus, Inbox, LogOut, Pencil } from "lucide-react";
+import { ArrowLeft, Hash, Plus, Volume2, Users, UserPlus, Inbox, LogOut, Pencil, Trash2 } from "lucide-react";
 import { TerminalMenu, type TerminalMenuItem } from "../components/ui/TerminalMenu";
 import { appStore } from "../stores/appStore";
 import { observer } from "mobx-react-lite";
@@ -17,6 +17,7 @@ export const GroupPage: React.FC = observer(() => {
   const { data: groupsWithChannels, isLoading } = useUserGroupsWithChannels();
   const group = groupsWithChannels?.find((g) => g.id === groupId);
   const isAdmin = group?.current_user_role === 'admin';
+  const isOwner = !!group && group.created_by === appStore.currentUser?.id;
 
   const voiceChannelIds = useMemo(
     () => (group?.channels ?? []).filter((ch) => ch.channel_type === "voice").map((ch) => ch.id),
@@ -152,6 +153,16 @@ export const GroupPage: React.FC = observer(() => {
       type: "system" as const,
       testId: "menu-item-leave-group",
     },
+    ...(isOwner ? [
+      {
+        id: "delete-group",
+        label: group.deletion_purge_after ? "Deletion Scheduled" : "Delete Group",
+        icon: <Trash2 size={14} />,
+        action: () => navigate({ to: "/groups/$groupId/delete", params: { groupId } }),
+        type: "system" as const,
+        testId: "menu-item-delete-group",
+      },
+    ] : []),
     {
       id: "__back__",
       label: "Go back",
@@ -1,6 +1,7 @@
 import { errorMessage } from "../utils/errorMessage";
 import React, { useState } from "react";
-import { useSendGroupInvite } from "../hooks/queries";
+import { QRCodeSVG } from "qrcode.react";
+import { useGroupJoinCode, useSendGroupInvite } from "../hooks/queries";
 import { TextInput } from "../components/ui/TextInput";
 import { Button } from "../components/ui/Button";
 
@@ -13,6 +14,21 @@ export const InviteMember: React.FC<InviteMemberProps> = ({ groupId, groupName }
   const [username, setUsername] = useState("");
   const [success, setSuccess] = useState(false);
   const inviteMutation = useSendGroupInvite();
+  const { data: joinCode } = useGroupJoinCode(groupId);
+  const [codeCopied, setCodeCopied] = useState(false);
+
+  const handleCopyCode = async () => {
+    if (!joinCode) {
+      return;
+    }
+    try {
+      await navigator.clipboard.writeText(joinCode.payload);
+      setCodeCopied(true);
+      setTimeout(() => setCodeCopied(false), 2000);
+    } catch (err) {
+      console.error("Failed to copy join code:", err);
+    }
+  };
 
   const handleInvite = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -76,6 +92,29 @@ export const InviteMember: React.FC<InviteMemberProps> = ({ groupId, groupName }
               {errorMessage(inviteMutation.error, "Failed to send invite")}
             </p>
           )}
+
+          {joinCode && (
+            <div data-testid="group-join-code" className="flex flex-col gap-2">
+              <p className="text-xs font-mono" style={{ color: 'var(--c-text-dim)' }}>
+                In person? Have them scan this, or paste the code into Find Group. They'll still need an admin to approve the request.
+              </p>
+              <div className="flex items-center gap-3">
+                <div style={{ background: "var(--c-bg)", padding: 4, borderRadius: 4 }}>
+                  <QRCodeSVG
+                    value={joinCode.payload}
+                    size={128}
+                    bgColor="var(--c-bg)"
+                    fgColor="var(--c-accent)"
+                    includeMargin={false}
+                    marginSize={0}
+                  />
+                </div>
+                <Button type="button" variant="secondary" size="sm" onClick={handleCopyCode}>
+                  {codeCopied ? "Copied" : "Copy code"}
+                </Button>
+              </div>
+            </div>
+          )}
         </form>
       </div>
     </div>
@@ -1,10 +1,11 @@
 import { errorMessage } from "../utils/errorMessage";
-import React from "react";
+import React, { useState } from "react";
 import { useNavigate, useParams } from "@tanstack/react-router";
 import { appStore } from "../stores/appStore";
 import { observer } from "mobx-react-lite";
 import { useLeaveGroup, useUserGroupsWithChannels } from "../hooks/queries/useGroups";
 import { Button } from "../components/ui/Button";
+import { Checkbox } from "../components/ui/Checkbox";
 import { PageShell } from "../components/Layout/PageShell";
 
 export const LeaveGroupPage: React.FC = observer(() => {
@@ -12,6 +13,7 @@ export const LeaveGroupPage: React.FC = observer(() => {
   const { groupId } = useParams({ from: "/groups/$groupId/leave" });
   const { setSelectedGroupId, setSelectedChannelId } = appStore;
   const leaveGroupMutation = useLeaveGroup();
+  const [deleteHistory, setDeleteHistory] = useState(false);
 
   const { data: groupsWithChannels, isLoading } = useUserGroupsWithChannels();
   const group = groupsWithChannels?.find((g) => g.id === groupId);
@@ -28,6 +30,13 @@ export const LeaveGroupPage: React.FC = observer(() => {
           <br />
           You will need a new invite to rejoin.
         </p>
+        <Checkbox
+          data-testid="leave-group-delete-history"
+          label="Also delete this group's messages from this device"
+          checked={deleteHistory}
+          onChange={setDeleteHistory}
+          disabled={leaveGroupMutation.isPending}
+        />
         {leaveGroupMutation.isError && (
           <p className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
             {errorMessage(leaveGroupMutation.error, "Failed to leave group")}
@@ -39,7 +48,7 @@ export const LeaveGroupPage: React.FC = observer(() => {
             variant="danger"
             onClick={async () => {
               try {
-                await leaveGroupMutation.mutateAsync(group.id);
+                await leaveGroupMutation.mutateAsync({ groupId: group.id, deleteHistory });
                 setSelectedGroupId(null);
                 setSelectedChannelId(null);
                 navigate({ to: "/" });
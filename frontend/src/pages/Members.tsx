@@ -5,6 +5,7 @@ import { appStore } from "../stores/appStore";
 import { observer } from "mobx-react-lite";
 import { useGroupMembers, useSetMemberRole } from "../hooks/queries/useGroups";
 import { usePeerVerifications } from "../hooks/queries/useUserProfile";
+import { useMutedMembers } from "../hooks/queries/usePreferences";
 import { Switch } from "../components/ui/Switch";
 import { Button } from "../components/ui/Button";
 import { NavigableList } from "../components/ui/NavigableList";
@@ -20,6 +21,7 @@ export const Members: React.FC<MembersProps> = observer(({ groupId, isAdmin }) =
   const { data: members = [], isLoading } = useGroupMembers(groupId);
   const setRoleMutation = useSetMemberRole();
   const { data: peerVerifications = [] } = usePeerVerifications();
+  const { mutedIds, setMuted } = useMutedMembers(groupId);
   // peerUserId → { verified, key_changed }. Reuses the same query the DM
   // sidebar already loads, so the badge state is consistent across every
   // surface where the same person appears (DM, group, channel author).
@@ -83,7 +85,12 @@ export const Members: React.FC<MembersProps> = observer(({ groupId, isAdmin }) =
             className="flex-1 truncate flex items-center gap-2"
             style={{ color: "var(--c-text)" }}
           >
-            <span className="truncate">{m.username ?? m.user_id}</span>
+            <span className="truncate">{m.display_name ?? m.username ?? m.user_id}</span>
+            {m.display_name && m.username && (
+              <span className="truncate" style={{ color: "var(--c-text-muted)" }}>
+                @{m.username}
+              </span>
+            )}
             {badge}
             {isSelf && (
               <span className="ml-1" style={{ color: "var(--c-text-muted)" }}>
@@ -95,10 +102,24 @@ export const Members: React.FC<MembersProps> = observer(({ groupId, isAdmin }) =
       }}
       controls={(m) => {
         const isSelf = m.user_id === currentUser?.id;
-        if (!isAdmin || isSelf) {
+        if (isSelf) {
           return [];
         }
+        // Muting is personal (this account only), so every member gets it.
+        const mute = (
+          <Switch
+            id={`member-mute-toggle-${m.user_id}`}
+            data-testid={`member-mute-${m.user_id}`}
+            label="mute"
+            checked={mutedIds.has(m.user_id)}
+            onChange={(val) => setMuted(m.user_id, val)}
+          />
+        );
+        if (!isAdmin) {
+          return [mute];
+        }
         return [
+          mute,
           <Switch
             id={`member-admin-toggle-${m.user_id}`}
             label="admin"
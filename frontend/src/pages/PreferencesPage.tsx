@@ -2,6 +2,8 @@ import React, { useState, useEffect, useCallback } from "react";
 import { useNavigate } from "@tanstack/react-router";
 import {
   invoke,
+  getLaunchAtLogin,
+  setLaunchAtLogin,
   isPermissionGranted,
   requestPermission,
   setTrayCloseToTray,
@@ -20,6 +22,15 @@ import {
   useSetMessageRetention,
   MESSAGE_RETENTION_OPTIONS,
 } from "../hooks/queries/useMessageRetention";
+import { useTranslationBackend, useSetTranslationBackend } from "../hooks/queries/useTranslation";
+import {
+  useInactivityPolicy,
+  useSetInactivityPolicy,
+  INACTIVITY_OPTIONS,
+} from "../hooks/queries/useInactivityPolicy";
+import { useRelayLatency } from "../hooks/queries/useRelayLatency";
+import { useDeliveryLatency, type LatencyPercentiles } from "../hooks/queries/useDeliveryLatency";
+import { useDeliveryCanaryReport } from "../hooks/queries/useDeliveryCanary";
 import {
   hslToHex,
   hexToHsl,
@@ -35,10 +46,24 @@ import {
 import { RangeSlider } from "../components/ui/RangeSlider";
 import { Switch } from "../components/ui/Switch";
 import { Button } from "../components/ui/Button";
+import { TextInput } from "../components/ui/TextInput";
 import { appStore } from "../stores/appStore";
 import { observer } from "mobx-react-lite";
 import { loadDeviceCallRingtone, saveDeviceCallRingtone } from "../utils/notify";
+import { AUTO_LOCK_OPTIONS, loadAutoLockMinutes, saveAutoLockMinutes } from "../utils/autoLock";
+import { loadLowBandwidthMode, saveLowBandwidthMode } from "../utils/lowBandwidth";
+import {
+  clearDigest,
+  loadQuietHours,
+  minutesToTime,
+  saveQuietHours,
+  timeToMinutes,
+  topMentions,
+  useDigest,
+  type QuietHours,
+} from "../utils/quietHours";
 import { isMac } from "../utils/platform";
+import { errorMessage } from "../utils/errorMessage";
 import { useShortcutLabel } from "../keyboard";
 
 function getRootVar(name: string): string {
@@ -49,6 +74,13 @@ function isValidHex(val: string): boolean {
   return /^#[0-9a-fA-F]{6}$/.test(val);
 }
 
+function formatLatency(p: LatencyPercentiles | undefined): string {
+  if (!p || p.samples === 0) {
+    return "no messages yet";
+  }
+  return `p50 ${p.p50_ms} ms · p90 ${p.p90_ms} ms · p99 ${p.p99_ms} ms (${p.samples} messages)`;
+}
+
 export const PreferencesPage: React.FC = observer(() => {
   const navigate = useNavigate();
   const currentUser = appStore.currentUser;
@@ -62,14 +94,27 @@ export const PreferencesPage: React.FC = observer(() => {
   const [fontSize, setFontSize] = useState<number>(15);
   const [allowDesktopNotifications, setAllowDesktopNotifications] = useState<boolean>(true);
   const [allowSoundEffects, setAllowSoundEffects] = useState<boolean>(true);
+  const [sendTypingIndicators, setSendTypingIndicators] = useState<boolean>(true);
   const [allowCallRingtone, setAllowCallRingtone] = useState<boolean>(true);
+  const [autoLockMinutes, setAutoLockMinutes] = useState<number>(0);
+  const [lowBandwidth, setLowBandwidth] = useState<boolean>(false);
+  const [quietHours, setQuietHours] = useState<QuietHours | null>(null);
+  const digest = useDigest();
   const [sidebarOpenByDefault, setSidebarOpenByDefault] = useState<boolean>(true);
   const [closeToTray, setCloseToTray] = useState<boolean>(true);
   const [menubarIcon, setMenubarIcon] = useState<boolean>(false);
+  // Read from the OS login entry, not the synced prefs — per device.
+  const [launchAtLogin, setLaunchAtLoginState] = useState<boolean>(false);
+  const [launchAtLoginError, setLaunchAtLoginError] = useState<string | null>(null);
   const [overlayMode, setOverlayMode] = useState<OverlayMode>("off");
   // Inline status line under the relay control: an apply error (e.g. Strict
   // with no relay reachable) surfaces here rather than throwing.
   const [overlayStatus, setOverlayStatus] = useState<string | null>(null);
+  // Per-relay dial latency; the accent dot marks the relay new circuits prefer.
+  const { data: relayLatency = [] } = useRelayLatency(overlayMode !== "off");
+  // Send → decrypt latency of messages received this session.
+  const { data: deliveryLatency } = useDeliveryLatency();
+  const { data: deliveryCanary } = useDeliveryCanaryReport();
   const [accentHexInput, setAccentHexInput] = useState<string>(() => hslToHex(38, 90, 62));
   const [bgHexInput, setBgHexInput] = useState<string>(() => hslToHex(38, 20, 4));
 
@@ -81,6 +126,21 @@ export const PreferencesPage: React.FC = observer(() => {
   const setRetention = useSetMessageRetention();
   const retentionDays = retentionQuery.data ?? MESSAGE_RETENTION_OPTIONS[0].days;
 
+  // Account-wide inactive-account policy, enforced by the Delivery Service.
+  // Each option / the recovery switch saves immediately.
+  const inactivityQuery = useInactivityPolicy();
+  const setInactivity = useSetInactivityPolicy();
+  const inactivityPolicy = inactivityQuery.data ?? null;
+
+  // Device-local translation backend (see useTranslation). Edited as a draft
+  // and saved explicitly — the core rejects relative paths.
+  const translationBackendQuery = useTranslationBackend();
+  const setTranslationBackend = useSetTranslationBackend();
+  const [translationBackendDraft, setTranslationBackendDraft] = useState("");
+  useEffect(() => {
+    setTranslationBackendDraft(translationBackendQuery.data ?? "");
+  }, [translationBackendQuery.data]);
+
   // Apply saved preferences on first load
   useEffect(() => {
     if (query.data) {
@@ -94,6 +154,9 @@ export const PreferencesPage: React.FC = observer(() => {
       if (query.data.allow_sound_effects !== undefined) {
         setAllowSoundEffects(query.data.allow_sound_effects);
       }
+      if (query.data.send_typing_indicators !== undefined) {
+        setSendTypingIndicators(query.data.send_typing_indicators);
+      }
       if (query.data.sidebar_open_by_default !== undefined) {
         setSidebarOpenByDefault(query.data.sidebar_open_by_default);
       }
@@ -132,6 +195,9 @@ export const PreferencesPage: React.FC = observer(() => {
       if (!isNaN(fs)) { setFontSize(fs); }
     }
     setAllowCallRingtone(loadDeviceCallRingtone(currentUser?.id));
+    setAutoLockMinutes(loadAutoLockMinutes(currentUser?.id));
+    setLowBandwidth(loadLowBandwidthMode(currentUser?.id));
+    setQuietHours(loadQuietHours(currentUser?.id));
   }, [currentUser?.id]);
 
   const save = useCallback((opts: {
@@ -139,6 +205,7 @@ export const PreferencesPage: React.FC = observer(() => {
     bgH?: number; bgS?: number; bgL?: number;
     skin?: Skin;
     notifications?: boolean; soundEffects?: boolean;
+    typingIndicators?: boolean;
     sidebarOpenByDefault?: boolean;
     closeToTray?: boolean;
     menubarIcon?: boolean;
@@ -151,6 +218,7 @@ export const PreferencesPage: React.FC = observer(() => {
     const bl = opts.bgL ?? bgLightness;
     const notif = opts.notifications ?? allowDesktopNotifications;
     const sfx = opts.soundEffects ?? allowSoundEffects;
+    const typingOn = opts.typingIndicators ?? sendTypingIndicators;
     const sidebar = opts.sidebarOpenByDefault ?? sidebarOpenByDefault;
     const tray = opts.closeToTray ?? closeToTray;
     const menubar = opts.menubarIcon ?? menubarIcon;
@@ -171,12 +239,13 @@ export const PreferencesPage: React.FC = observer(() => {
       skin: skinVal,
       allow_desktop_notifications: notif,
       allow_sound_effects: sfx,
+      send_typing_indicators: typingOn,
       sidebar_open_by_default: sidebar,
       close_to_tray: tray,
       menubar_icon: menubar,
       overlay_mode: overlay,
     });
-  }, [savePrefs, query.data, hue, saturation, bgHue, bgSaturation, bgLightness, skin, allowDesktopNotifications, allowSoundEffects, sidebarOpenByDefault, closeToTray, menubarIcon, overlayMode]);
+  }, [savePrefs, query.data, hue, saturation, bgHue, bgSaturation, bgLightness, skin, allowDesktopNotifications, allowSoundEffects, sendTypingIndicators, sidebarOpenByDefault, closeToTray, menubarIcon, overlayMode]);
 
   // Drive the merged overlay engine (`set_overlay_mode`) to `val`, live. Never
   // throws: a rejected apply (e.g. Strict with no relay reachable — the engine
@@ -282,6 +351,22 @@ export const PreferencesPage: React.FC = observer(() => {
     save({ soundEffects: val });
   };
 
+  const handleSendTypingIndicators = (val: boolean) => {
+    setSendTypingIndicators(val);
+    save({ typingIndicators: val });
+  };
+
+  // Link privacy switches render straight from the query — nothing else on
+  // this page depends on them, and `check_link` reads the saved keys itself.
+  const handleLinkPref = (
+    key: "link_confirm_insecure" | "link_strip_tracking" | "link_previews",
+    val: boolean,
+  ) => {
+    const { font_size: _legacyFontSize, ...rest } = query.data ?? {};
+    void _legacyFontSize;
+    savePrefs({ ...rest, [key]: val });
+  };
+
   const handleSidebarOpenByDefault = (val: boolean) => {
     setSidebarOpenByDefault(val);
     save({ sidebarOpenByDefault: val });
@@ -309,11 +394,57 @@ export const PreferencesPage: React.FC = observer(() => {
     });
   };
 
+  useEffect(() => {
+    getLaunchAtLogin()
+      .then(setLaunchAtLoginState)
+      .catch((err) => console.warn("[autostart] getLaunchAtLogin failed:", err));
+  }, []);
+
+  const handleLaunchAtLogin = (val: boolean) => {
+    setLaunchAtLoginState(val);
+    setLaunchAtLoginError(null);
+    setLaunchAtLogin(val).catch((err) => {
+      setLaunchAtLoginState(!val);
+      setLaunchAtLoginError(errorMessage(err));
+    });
+  };
+
   const handleAllowCallRingtone = (val: boolean) => {
     setAllowCallRingtone(val);
     saveDeviceCallRingtone(currentUser?.id, val);
   };
 
+  const handleAutoLock = (minutes: number) => {
+    setAutoLockMinutes(minutes);
+    saveAutoLockMinutes(currentUser?.id, minutes);
+  };
+
+  const handleLowBandwidth = (val: boolean) => {
+    setLowBandwidth(val);
+    saveLowBandwidthMode(currentUser?.id, val);
+  };
+
+  // Defaults to 22:00–07:00 when first switched on.
+  const handleQuietHoursEnabled = (val: boolean) => {
+    const next = val ? { start: 22 * 60, end: 7 * 60 } : null;
+    setQuietHours(next);
+    saveQuietHours(currentUser?.id, next);
+  };
+
+  const handleQuietHoursTime = (edge: "start" | "end", value: string) => {
+    const minutes = timeToMinutes(value);
+    if (minutes === null || !quietHours) {
+      return;
+    }
+    const next = { ...quietHours, [edge]: minutes };
+    setQuietHours(next);
+    // A zero-length window would read back as off; keep it in the inputs
+    // only until the user picks a different time.
+    if (next.start !== next.end) {
+      saveQuietHours(currentUser?.id, next);
+    }
+  };
+
   const handleAllowDesktopNotifications = async (val: boolean) => {
     setAllowDesktopNotifications(val);
     save({ notifications: val });
@@ -596,6 +727,99 @@ export const PreferencesPage: React.FC = observer(() => {
                   Plays a looping ring on this device when someone calls. Off here doesn't mute the alert badge or your other devices.
                 </p>
               </div>
+              <div className="flex flex-col gap-1.5 mt-4">
+                <span className="text-xs font-mono" style={{ color: "var(--c-text-dim)" }}>
+                  Auto-lock after inactivity
+                </span>
+                <div
+                  role="radiogroup"
+                  aria-label="Auto-lock after inactivity"
+                  className="flex gap-2 flex-wrap"
+                >
+                  {AUTO_LOCK_OPTIONS.map((option) => {
+                    const selected = autoLockMinutes === option.minutes;
+                    return (
+                      <Button
+                        key={option.minutes}
+                        variant={selected ? "primary" : "secondary"}
+                        size="sm"
+                        aria-label={option.label}
+                        data-testid={`pref-auto-lock-${option.minutes}`}
+                        onClick={() => handleAutoLock(option.minutes)}
+                      >
+                        {option.label}
+                      </Button>
+                    );
+                  })}
+                </div>
+                <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                  Locks Pollis behind your PIN when this device has been idle, same as the lock shortcut. Decrypted messages are cleared from memory until you unlock.
+                </p>
+              </div>
+              <div className="flex flex-col gap-1.5 mt-4">
+                <Switch
+                  id="pref-low-bandwidth"
+                  label="Low-bandwidth mode"
+                  checked={lowBandwidth}
+                  onChange={handleLowBandwidth}
+                />
+                <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                  For metered connections. Images and audio load only when you click them, avatars aren't downloaded, and media links aren't previewed. Messages still arrive normally.
+                </p>
+              </div>
+              <div className="flex flex-col gap-1.5 mt-4">
+                <Switch
+                  id="pref-quiet-hours"
+                  label="Quiet hours"
+                  checked={quietHours !== null}
+                  onChange={handleQuietHoursEnabled}
+                />
+                {quietHours && (
+                  <div className="flex gap-3">
+                    <TextInput
+                      id="pref-quiet-hours-start"
+                      label="From"
+                      type="time"
+                      value={minutesToTime(quietHours.start)}
+                      onChange={(v) => handleQuietHoursTime("start", v)}
+                    />
+                    <TextInput
+                      id="pref-quiet-hours-end"
+                      label="Until"
+                      type="time"
+                      value={minutesToTime(quietHours.end)}
+                      onChange={(v) => handleQuietHoursTime("end", v)}
+                    />
+                  </div>
+                )}
+                <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                  Messages, @all mentions, DM requests and invites stay silent on this device during these hours; unread badges still update. When quiet hours end you get one notification summarising what came in. Calls still ring.
+                </p>
+                {digest && digest.rooms.length > 0 && (
+                  <div data-testid="quiet-hours-digest" className="flex flex-col gap-1 mt-1">
+                    <span className="text-xs font-mono" style={{ color: "var(--c-text-dim)" }}>
+                      Held back during quiet hours
+                    </span>
+                    <ul className="flex flex-col gap-0.5">
+                      {digest.rooms.map((room) => {
+                        const mentions = topMentions(room);
+                        return (
+                          <li key={room.roomId} className="text-xs font-mono" style={{ color: "var(--c-text)" }}>
+                            {room.title} — {room.count} new since{" "}
+                            {new Date(room.firstUnreadAt).toLocaleTimeString([], { hour: "2-digit", minute: "2-digit" })}
+                            {mentions.length > 0 && ` · @all from ${mentions.join(", ")}`}
+                          </li>
+                        );
+                      })}
+                    </ul>
+                    <div>
+                      <Button variant="secondary" size="sm" onClick={clearDigest}>
+                        Clear
+                      </Button>
+                    </div>
+                  </div>
+                )}
+              </div>
             </section>
 
             {/* Layout */}
@@ -630,6 +854,22 @@ export const PreferencesPage: React.FC = observer(() => {
                   </p>
                 </div>
               )}
+              <div className="flex flex-col gap-1.5">
+                <Switch
+                  id="pref-launch-at-login"
+                  label="Launch at login"
+                  checked={launchAtLogin}
+                  onChange={handleLaunchAtLogin}
+                />
+                <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                  Starts Pollis when you sign in to this computer{isMac ? "" : ", hidden in the tray"} so messages and calls keep arriving. Applies to this device only.
+                </p>
+                {launchAtLoginError && (
+                  <p className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+                    {launchAtLoginError}
+                  </p>
+                )}
+              </div>
               {isMac && (
                 <div className="flex flex-col gap-1.5">
                   <Switch
@@ -667,6 +907,54 @@ export const PreferencesPage: React.FC = observer(() => {
               />
             </section>
 
+            {/* Typing privacy — synced. Individual conversations can also be
+                muted from their settings page (`useTypingPrivacy`). */}
+            <section className="flex flex-col gap-4 mb-12">
+              <h2
+                className="text-xs font-mono font-medium uppercase tracking-widest pb-1 border-b"
+                style={{ color: "var(--c-text)", borderColor: "var(--c-border)" }}
+              >
+                Privacy
+              </h2>
+              <Switch
+                id="pref-typing-indicators"
+                data-testid="pref-typing-indicators"
+                label="Send typing indicators"
+                checked={sendTypingIndicators}
+                onChange={handleSendTypingIndicators}
+              />
+              <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                When off, nobody sees you typing. You still see others.
+              </p>
+              <Switch
+                id="pref-link-strip-tracking"
+                data-testid="pref-link-strip-tracking"
+                label="Remove tracking from links"
+                checked={query.data?.link_strip_tracking ?? true}
+                onChange={(val) => handleLinkPref("link_strip_tracking", val)}
+              />
+              <Switch
+                id="pref-link-confirm-insecure"
+                data-testid="pref-link-confirm-insecure"
+                label="Ask before opening unencrypted (http) links"
+                checked={query.data?.link_confirm_insecure ?? true}
+                onChange={(val) => handleLinkPref("link_confirm_insecure", val)}
+              />
+              <Switch
+                id="pref-link-previews"
+                data-testid="pref-link-previews"
+                label="Show image and video previews"
+                checked={query.data?.link_previews ?? true}
+                onChange={(val) => handleLinkPref("link_previews", val)}
+              />
+              <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                Links open in your default browser. Tracking parameters such as
+                utm_source and fbclid are removed first. Previews load straight
+                from the linked site, so it sees your IP address; they're only
+                shown for https links.
+              </p>
+            </section>
+
             {/* Local message history (this device) — device-local retention
                 window stored in the local DB, not synced across the account. */}
             <section className="flex flex-col gap-4 mb-12">
@@ -710,6 +998,45 @@ export const PreferencesPage: React.FC = observer(() => {
               </p>
             </section>
 
+            {/* Translation (this device) — local backend path stored in the
+                local DB, not synced. */}
+            <section className="flex flex-col gap-4 mb-12">
+              <h2
+                className="text-xs font-mono font-medium uppercase tracking-widest pb-1 border-b"
+                style={{ color: "var(--c-text)", borderColor: "var(--c-border)" }}
+              >
+                Translation (this device)
+              </h2>
+              <TextInput
+                id="pref-translation-backend"
+                label="Translation program"
+                value={translationBackendDraft}
+                onChange={setTranslationBackendDraft}
+                placeholder="/usr/local/bin/translate"
+                error={setTranslationBackend.isError ? errorMessage(setTranslationBackend.error) : undefined}
+                data-testid="pref-translation-backend"
+              />
+              <div className="flex gap-2">
+                <Button
+                  size="sm"
+                  variant="primary"
+                  data-testid="pref-translation-backend-save"
+                  disabled={translationBackendDraft.trim() === (translationBackendQuery.data ?? "")}
+                  isLoading={setTranslationBackend.isPending}
+                  onClick={() => setTranslationBackend.mutate(translationBackendDraft.trim())}
+                >
+                  Save
+                </Button>
+              </div>
+              <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                An executable on this device that reads text on stdin and prints
+                the translation, given the target language (like de or pt-BR) as
+                its only argument. Messages never leave the device. Pick a
+                room's language with /translate, then use the translate action
+                on a message. Leave empty to turn translation off.
+              </p>
+            </section>
+
             {/* Network privacy — relay overlay (#455). Synced across devices and
                 applied live via set_overlay_mode. */}
             <section className="flex flex-col gap-4 mb-12">
@@ -767,6 +1094,133 @@ export const PreferencesPage: React.FC = observer(() => {
                   Couldn't apply relay mode: {overlayStatus} — currently {overlayMode}.
                 </p>
               )}
+              {overlayMode !== "off" && relayLatency.length > 0 && (
+                <ul
+                  data-testid="pref-relay-latency"
+                  className="flex flex-col gap-1 text-xs font-mono font-machine"
+                  style={{ color: "var(--c-text-muted)" }}
+                >
+                  {relayLatency.map((r) => (
+                    <li key={r.addr} title={r.history.map((h) => (h.rtt_ms === null ? "fail" : `${h.rtt_ms}ms`)).join(" ")}>
+                      <span style={{ color: r.preferred ? "var(--c-text-accent)" : "var(--c-text-dim)" }}>
+                        {r.preferred ? "● " : "○ "}
+                        {r.addr}
+                      </span>{" "}
+                      — {r.ewma_ms === null ? "unreachable" : `${Math.round(r.ewma_ms)} ms`}
+                    </li>
+                  ))}
+                </ul>
+              )}
+            </section>
+
+            {/* Delivery latency — device-local diagnostics, never uploaded. */}
+            <section className="flex flex-col gap-4 mb-12">
+              <h2
+                className="text-xs font-mono font-medium uppercase tracking-widest pb-1 border-b"
+                style={{ color: "var(--c-text)", borderColor: "var(--c-border)" }}
+              >
+                Message delivery
+              </h2>
+              <ul data-testid="pref-delivery-latency" className="flex flex-col gap-1 text-xs font-mono text-muted">
+                <li>
+                  <span className="text-dim">Last hour</span> — {formatLatency(deliveryLatency?.last_hour)}
+                </li>
+                <li>
+                  <span className="text-dim">This session</span> — {formatLatency(deliveryLatency?.session)}
+                </li>
+                <li data-testid="pref-delivery-canary">
+                  <span className="text-dim">Sent and checked</span> —{" "}
+                  {deliveryCanary && deliveryCanary.checked > 0
+                    ? `${deliveryCanary.checked} back, ${deliveryCanary.dropped} dropped, ${deliveryCanary.duplicated} replayed, ${deliveryCanary.modified} altered, ${deliveryCanary.delayed} held up`
+                    : "nothing sent yet"}
+                </li>
+              </ul>
+              <p className="text-xs font-mono text-muted">
+                Time from a message being sent to it arriving here, measured on
+                this device only. A last hour well above the session means
+                delivery is getting slower. Messages that waited for you to come
+                online aren't counted.
+              </p>
+              <p className="text-xs font-mono text-muted">
+                Every message you send is checked when it comes back from the
+                server: it should return once and unaltered. A warning appears
+                if too many don't.
+              </p>
+            </section>
+
+            {/* Inactive account — synced (server-side policy). */}
+            <section className="flex flex-col gap-4 mb-12">
+              <h2
+                className="text-xs font-mono font-medium uppercase tracking-widest pb-1 border-b"
+                style={{ color: "var(--c-text)", borderColor: "var(--c-border)" }}
+              >
+                Inactive account
+              </h2>
+              <div
+                role="radiogroup"
+                aria-label="Inactive account window"
+                className="flex gap-2 flex-wrap"
+              >
+                {INACTIVITY_OPTIONS.map((option) => {
+                  const selected = (inactivityPolicy?.inactive_days ?? null) === option.days;
+                  return (
+                    <Button
+                      key={option.label}
+                      variant={selected ? "primary" : "secondary"}
+                      size="sm"
+                      aria-label={option.label}
+                      data-testid={`pref-inactivity-${option.days ?? "off"}`}
+                      disabled={inactivityQuery.isLoading}
+                      onClick={() => {
+                        if (selected) {
+                          return;
+                        }
+                        setInactivity.mutate({
+                          inactiveDays: option.days,
+                          deleteRecovery: option.days !== null && (inactivityPolicy?.delete_recovery ?? false),
+                        });
+                      }}
+                    >
+                      {option.label}
+                    </Button>
+                  );
+                })}
+              </div>
+              <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                If you don't unlock Pollis on any device for this long, we email
+                you a warning. Unlocking any device cancels it.
+              </p>
+              {inactivityPolicy && (
+                <Switch
+                  id="pref-inactivity-delete-recovery"
+                  data-testid="pref-inactivity-delete-recovery"
+                  label="Then delete my Secret Key backup"
+                  checked={inactivityPolicy.delete_recovery}
+                  disabled={setInactivity.isPending}
+                  onChange={(checked) =>
+                    setInactivity.mutate({
+                      inactiveDays: inactivityPolicy.inactive_days,
+                      deleteRecovery: checked,
+                    })
+                  }
+                  description="Two weeks after an unanswered warning, the server deletes your encrypted Secret Key backup. Your devices keep working, but if you lose all of them the account can't be recovered."
+                />
+              )}
+              {inactivityPolicy?.notified_at && (
+                <p data-testid="pref-inactivity-notified" className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+                  An inactivity warning was sent on {inactivityPolicy.notified_at} UTC.
+                </p>
+              )}
+              {inactivityPolicy?.recovery_deleted_at && (
+                <p data-testid="pref-inactivity-recovery-deleted" className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+                  Your Secret Key backup was deleted for inactivity on {inactivityPolicy.recovery_deleted_at} UTC.
+                </p>
+              )}
+              {setInactivity.isError && (
+                <p className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+                  {errorMessage(setInactivity.error, "Failed to save inactive-account policy")}
+                </p>
+              )}
             </section>
 
             {/* Voice */}
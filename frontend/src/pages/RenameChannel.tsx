@@ -2,7 +2,13 @@ import { errorMessage } from "../utils/errorMessage";
 import React, { useEffect, useState } from "react";
 import { appStore } from "../stores/appStore";
 import { observer } from "mobx-react-lite";
-import { useUpdateChannel, useUserGroupsWithChannels } from "../hooks/queries/useGroups";
+import {
+  CHANNEL_RETENTION_OPTIONS,
+  useExportChannelMessages,
+  useSetChannelRetention,
+  useUpdateChannel,
+  useUserGroupsWithChannels,
+} from "../hooks/queries/useGroups";
 import { TextInput } from "../components/ui/TextInput";
 import { TextArea } from "../components/ui/TextArea";
 import { Button } from "../components/ui/Button";
@@ -17,6 +23,8 @@ export const RenameChannel: React.FC<RenameChannelProps> = observer(({ groupId,
   const { currentUser } = appStore;
   const { data: groupsWithChannels } = useUserGroupsWithChannels();
   const updateChannel = useUpdateChannel();
+  const exportMessages = useExportChannelMessages();
+  const setRetention = useSetChannelRetention();
 
   const group = groupsWithChannels?.find((g) => g.id === groupId);
   const channel = group?.channels.find((c) => c.id === channelId);
@@ -24,6 +32,8 @@ export const RenameChannel: React.FC<RenameChannelProps> = observer(({ groupId,
   const [name, setName] = useState(channel?.name ?? "");
   const [description, setDescription] = useState(channel?.description ?? "");
   const [error, setError] = useState<string | null>(null);
+  const [exportError, setExportError] = useState<string | null>(null);
+  const [retentionError, setRetentionError] = useState<string | null>(null);
 
   useEffect(() => {
     if (channel) {
@@ -68,6 +78,36 @@ export const RenameChannel: React.FC<RenameChannelProps> = observer(({ groupId,
     }
   };
 
+  const handleRetention = async (days: number) => {
+    setRetentionError(null);
+    try {
+      await setRetention.mutateAsync({ groupId, channelId, days });
+    } catch (err) {
+      setRetentionError(errorMessage(err, "Failed to change channel history"));
+    }
+  };
+
+  const handleExport = async (format: "csv" | "json" | "matrix") => {
+    setExportError(null);
+    try {
+      const text = await exportMessages.mutateAsync({ channelId, format });
+      const blob = new Blob([text], { type: format === "csv" ? "text/csv" : "application/json" });
+      const url = URL.createObjectURL(blob);
+      const a = document.createElement("a");
+      a.href = url;
+      a.download =
+        format === "matrix"
+          ? `${channel?.name ?? "channel"}-matrix.json`
+          : `${channel?.name ?? "channel"}-messages.${format}`;
+      document.body.appendChild(a);
+      a.click();
+      document.body.removeChild(a);
+      URL.revokeObjectURL(url);
+    } catch (err) {
+      setExportError(errorMessage(err, "Failed to export messages"));
+    }
+  };
+
   if (!currentUser) {
     return (
       <div data-testid="rename-channel-no-user" className="flex items-center justify-center flex-1" style={{ background: "var(--c-bg)" }}>
@@ -133,6 +173,108 @@ export const RenameChannel: React.FC<RenameChannelProps> = observer(({ groupId,
           >
             Save
           </Button>
+
+          {group?.current_user_role === "admin" && (
+            <div
+              data-testid="channel-retention-section"
+              className="flex flex-col gap-2 pt-4 border-t"
+              style={{ borderColor: "var(--c-border)" }}
+            >
+              <p className="text-xs font-mono" style={{ color: "var(--c-text-dim)" }}>
+                Channel history
+              </p>
+              <div role="radiogroup" aria-label="Channel history retention" className="flex gap-2 flex-wrap">
+                {CHANNEL_RETENTION_OPTIONS.map((option) => {
+                  const selected = (channel.retention_days ?? 0) === option.days;
+                  return (
+                    <Button
+                      key={option.days}
+                      data-testid={`channel-retention-${option.days}`}
+                      type="button"
+                      variant={selected ? "primary" : "secondary"}
+                      size="sm"
+                      aria-label={option.label}
+                      disabled={setRetention.isPending}
+                      onClick={() => {
+                        if (selected) {
+                          return;
+                        }
+                        handleRetention(option.days);
+                      }}
+                    >
+                      {option.label}
+                    </Button>
+                  );
+                })}
+              </div>
+              <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                Messages older than this are deleted for everyone — from the relay and from every member's devices.
+              </p>
+              {retentionError && (
+                <p data-testid="channel-retention-error" className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+                  {retentionError}
+                </p>
+              )}
+            </div>
+          )}
+
+          {group?.current_user_role === "admin" && (
+            <div
+              data-testid="export-channel-section"
+              className="flex flex-col gap-2 pt-4 border-t"
+              style={{ borderColor: "var(--c-border)" }}
+            >
+              <p className="text-xs font-mono" style={{ color: "var(--c-text-dim)" }}>
+                Export messages
+              </p>
+              {group.allow_export ? (
+                <div className="flex gap-2">
+                  <Button
+                    data-testid="export-channel-csv"
+                    type="button"
+                    variant="secondary"
+                    size="sm"
+                    disabled={exportMessages.isPending}
+                    onClick={() => handleExport("csv")}
+                  >
+                    CSV
+                  </Button>
+                  <Button
+                    data-testid="export-channel-json"
+                    type="button"
+                    variant="secondary"
+                    size="sm"
+                    disabled={exportMessages.isPending}
+                    onClick={() => handleExport("json")}
+                  >
+                    JSON
+                  </Button>
+                  <Button
+                    data-testid="export-channel-matrix"
+                    type="button"
+                    variant="secondary"
+                    size="sm"
+                    disabled={exportMessages.isPending}
+                    onClick={() => handleExport("matrix")}
+                  >
+                    Matrix
+                  </Button>
+                </div>
+              ) : (
+                <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                  Export is turned off for this group.
+                </p>
+              )}
+              <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                Decrypted history as this device has it — messages from before it joined aren't included.
+              </p>
+              {exportError && (
+                <p data-testid="export-channel-error" className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+                  {exportError}
+                </p>
+              )}
+            </div>
+          )}
         </form>
       </div>
     </div>
@@ -2,10 +2,21 @@ import { errorMessage } from "../utils/errorMessage";
 import React, { useEffect, useState } from "react";
 import { appStore } from "../stores/appStore";
 import { observer } from "mobx-react-lite";
-import { useUpdateGroup, useUserGroupsWithChannels } from "../hooks/queries/useGroups";
+import {
+  useExportGroupAttachments,
+  useSetGroupExportPolicy,
+  useSetGroupStorageTarget,
+  useStorageTargets,
+  useUpdateGroup,
+  useUserGroupsWithChannels,
+  type AttachmentExportSummary,
+} from "../hooks/queries/useGroups";
+import { dialogOpen, invoke } from "../bridge";
 import { TextInput } from "../components/ui/TextInput";
 import { TextArea } from "../components/ui/TextArea";
 import { Button } from "../components/ui/Button";
+import { Switch } from "../components/ui/Switch";
+import { GroupWebhooks } from "../components/GroupWebhooks";
 
 interface RenameGroupProps {
   groupId: string;
@@ -16,12 +27,19 @@ export const RenameGroup: React.FC<RenameGroupProps> = observer(({ groupId, onSu
   const { currentUser } = appStore;
   const { data: groupsWithChannels } = useUserGroupsWithChannels();
   const updateGroup = useUpdateGroup();
+  const setExportPolicy = useSetGroupExportPolicy();
+  const exportAttachments = useExportGroupAttachments();
 
   const group = groupsWithChannels?.find((g) => g.id === groupId);
+  const isOwner = !!currentUser && group?.created_by === currentUser.id;
+  const setStorageTarget = useSetGroupStorageTarget();
+  const { data: storageTargets = [] } = useStorageTargets(isOwner);
 
   const [name, setName] = useState(group?.name ?? "");
   const [description, setDescription] = useState(group?.description ?? "");
   const [error, setError] = useState<string | null>(null);
+  const [exportSummary, setExportSummary] = useState<AttachmentExportSummary | null>(null);
+  const [exportError, setExportError] = useState<string | null>(null);
 
   useEffect(() => {
     if (group) {
@@ -30,6 +48,21 @@ export const RenameGroup: React.FC<RenameGroupProps> = observer(({ groupId, onSu
     }
   }, [group?.id]);
 
+  const handleExportAttachments = async () => {
+    setExportError(null);
+    setExportSummary(null);
+    const picked = await dialogOpen({ directory: true, title: "Export attachments to…" });
+    const destPath = Array.isArray(picked) ? picked[0] : picked;
+    if (!destPath) {
+      return;
+    }
+    try {
+      setExportSummary(await exportAttachments.mutateAsync({ groupId, destPath }));
+    } catch (err) {
+      setExportError(errorMessage(err, "Failed to export attachments"));
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setError(null);
@@ -115,6 +148,106 @@ export const RenameGroup: React.FC<RenameGroupProps> = observer(({ groupId, onSu
           />
           <input data-testid="rename-group-description-input" type="hidden" value={description} readOnly />
 
+          {group.current_user_role === "admin" && (
+            <Switch
+              id="group-allow-export"
+              data-testid="group-allow-export"
+              label="Allow message export"
+              description="Lets admins download channel history as CSV or JSON. Turn off for sensitive groups."
+              checked={group.allow_export}
+              disabled={setExportPolicy.isPending}
+              onChange={(allowExport) => {
+                setError(null);
+                setExportPolicy.mutate(
+                  { groupId, allowExport },
+                  { onError: (err) => setError(errorMessage(err, "Failed to update export policy")) },
+                );
+              }}
+            />
+          )}
+
+          {group.current_user_role === "admin" && group.allow_export && (
+            <div data-testid="export-attachments-section" className="flex flex-col gap-2">
+              <div className="flex gap-2">
+                <Button
+                  data-testid="export-attachments-button"
+                  type="button"
+                  variant="secondary"
+                  size="sm"
+                  isLoading={exportAttachments.isPending}
+                  loadingText="Exporting…"
+                  onClick={handleExportAttachments}
+                >
+                  Export attachments
+                </Button>
+                {exportAttachments.isPending && (
+                  <Button
+                    data-testid="pause-attachments-export"
+                    type="button"
+                    variant="secondary"
+                    size="sm"
+                    onClick={() => void invoke("pause_attachment_export")}
+                  >
+                    Pause
+                  </Button>
+                )}
+              </div>
+              <p className="text-xs font-mono" style={{ color: "var(--c-text-muted)" }}>
+                Decrypted files into a folder, by channel and date, with a manifest of hashes.
+                Export into the same folder again to resume.
+              </p>
+              {exportSummary && (
+                <p data-testid="export-attachments-summary" className="text-xs font-mono" style={{ color: "var(--c-text-dim)" }}>
+                  {exportSummary.paused ? "Paused" : "Done"}: {exportSummary.exported} exported
+                  {exportSummary.skipped > 0 && `, ${exportSummary.skipped} already there`}
+                  {exportSummary.failed > 0 && `, ${exportSummary.failed} failed (export again to retry)`}
+                </p>
+              )}
+              {exportError && (
+                <p data-testid="export-attachments-error" className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
+                  {exportError}
+                </p>
+              )}
+            </div>
+          )}
+
+          {isOwner && (storageTargets.length > 0 || group.storage_target) && (
+            <div data-testid="group-storage-target-section" className="flex flex-col gap-2">
+              <p className="text-xs font-mono text-dim">Attachment storage</p>
+              <div role="radiogroup" aria-label="Attachment storage" className="flex gap-2 flex-wrap">
+                {[{ id: null, label: "Default" }, ...storageTargets].map((target) => {
+                  const selected = (group.storage_target ?? null) === target.id;
+                  return (
+                    <Button
+                      key={target.id ?? "default"}
+                      data-testid={`group-storage-target-${target.id ?? "default"}`}
+                      type="button"
+                      variant={selected ? "primary" : "secondary"}
+                      size="sm"
+                      aria-label={target.label}
+                      disabled={setStorageTarget.isPending}
+                      onClick={() => {
+                        if (selected) {
+                          return;
+                        }
+                        setError(null);
+                        setStorageTarget.mutate(
+                          { groupId, storageTarget: target.id },
+                          { onError: (err) => setError(errorMessage(err, "Failed to update attachment storage")) },
+                        );
+                      }}
+                    >
+                      {target.label}
+                    </Button>
+                  );
+                })}
+              </div>
+              <p className="text-xs font-mono text-muted">
+                Where new attachments in this group are stored. Files already sent stay where they are.
+              </p>
+            </div>
+          )}
+
           {error && (
             <p data-testid="rename-group-error" className="text-xs font-mono" style={{ color: "var(--c-danger)" }}>
               {error}
@@ -132,6 +265,11 @@ export const RenameGroup: React.FC<RenameGroupProps> = observer(({ groupId, onSu
           </Button>
         </form>
       </div>
+      {group.current_user_role === "admin" && (
+        <div className="flex justify-center px-6 pb-8">
+          <GroupWebhooks groupId={groupId} />
+        </div>
+      )}
     </div>
   );
 });
@@ -20,6 +20,7 @@ export const SearchGroupPage: React.FC = observer(() => {
   const [slug, setSlug] = useState("");
   const [isSearching, setIsSearching] = useState(false);
   const [searchError, setSearchError] = useState<string | null>(null);
+  const [inviteRedeemed, setInviteRedeemed] = useState(false);
   const [foundGroup, setFoundGroup] = useState<{ id: string; name: string; description?: string } | null>(null);
 
   const requestAccessMutation = useRequestGroupAccess();
@@ -36,8 +37,20 @@ export const SearchGroupPage: React.FC = observer(() => {
     setIsSearching(true);
     setSearchError(null);
     setFoundGroup(null);
+    setInviteRedeemed(false);
     try {
-      const group = await invoke<{ id: string; name: string; description?: string }>('search_group_by_slug', { slug: slug.trim() });
+      const query = slug.trim();
+      // A link from an emailed invite becomes a pending invite for this account.
+      if (query.includes("token=") && currentUser) {
+        await invoke<string>('redeem_email_invite', { token: query, userId: currentUser.id });
+        queryClient.invalidateQueries({ queryKey: groupQueryKeys.pendingInvites(currentUser.id) });
+        setInviteRedeemed(true);
+        return;
+      }
+      // A scanned / pasted QR join code resolves to the same result card.
+      const group = query.startsWith("pollis-group:")
+        ? await invoke<{ id: string; name: string; description?: string }>('resolve_group_join_code', { payload: query })
+        : await invoke<{ id: string; name: string; description?: string }>('search_group_by_slug', { slug: query });
       setFoundGroup(group);
     } catch (err) {
       setSearchError(errorMessage(err, "Group not found"));
@@ -68,7 +81,7 @@ export const SearchGroupPage: React.FC = observer(() => {
 
             <form onSubmit={handleSearch} className="flex flex-col gap-3">
               <TextInput
-                label="Group Slug"
+                label="Group Slug, Join Code or Invite Link"
                 value={slug}
                 onChange={setSlug}
                 placeholder="my-group"
@@ -155,6 +168,12 @@ export const SearchGroupPage: React.FC = observer(() => {
               </Card>
             )}
 
+            {inviteRedeemed && (
+              <p data-testid="invite-redeemed-indicator" className="text-xs font-mono" style={{ color: 'var(--c-text-muted)' }}>
+                Invite added. Accept it from your pending invites.
+              </p>
+            )}
+
             {searchError && (
               <p data-testid="search-group-error" className="text-xs font-mono" style={{ color: 'var(--c-danger)' }}>
                 {searchError}
@@ -1,6 +1,7 @@
 import { errorMessage } from "../utils/errorMessage";
 import React, { useEffect, useState, useCallback } from "react";
 import { useNavigate, useRouter } from "@tanstack/react-router";
+import { useQueryClient } from "@tanstack/react-query";
 import { PageShell } from "../components/Layout/PageShell";
 import { Button } from "../components/ui/Button";
 import { TextInput } from "../components/ui/TextInput";
@@ -12,7 +13,11 @@ import type { RouterContext } from "../types/router";
 import * as api from "../services/api";
 import { AccountKeyAuditLine } from "../components/Security/AccountKeyAuditLine";
 import { BuildVerifyLine } from "../components/Security/BuildVerifyLine";
-import { useSelfAuditAccountKey, useVerifyOwnBuild } from "../hooks/queries";
+import {
+  useIdentityFingerprint,
+  useSelfAuditAccountKey,
+  useVerifyOwnBuild,
+} from "../hooks/queries";
 import { getVersion, shellOpen } from "../bridge";
 import { usePreferences } from "../hooks/queries/usePreferences";
 import {
@@ -76,6 +81,12 @@ function describe(event: api.SecurityEvent): { heading: string; detail: string }
         detail:
           "You reset your account. All previous devices and groups were orphaned.",
       };
+    case "identity_rotated":
+      return {
+        heading: "Account key rotated",
+        detail:
+          "Your account key was replaced by one signed with the old key. Your Secret Key changed; other devices need it to sign in again.",
+      };
     case "secret_key_rotated":
       return {
         heading: "Secret Key rotated",
@@ -113,6 +124,8 @@ export const SecurityPage: React.FC = observer(() => {
   const { onDeleteAccount } = router.options.context as RouterContext;
   const { currentUser } = appStore;
   const { data: selfAudit } = useSelfAuditAccountKey();
+  const { data: ownFingerprint } = useIdentityFingerprint();
+  const queryClient = useQueryClient();
   // "This build" verification is on-demand (a mutation), never run on mount.
   const buildVerify = useVerifyOwnBuild();
   const [appVersion, setAppVersion] = useState<string | null>(null);
@@ -120,6 +133,14 @@ export const SecurityPage: React.FC = observer(() => {
   const [visibleEvents, setVisibleEvents] = useState(SECURITY_EVENTS_PAGE_SIZE);
   const [error, setError] = useState<string | null>(null);
 
+  // Identity-key rotation: an inline PIN prompt, then the new Secret Key
+  // shown once in place of the form.
+  const [rotateOpen, setRotateOpen] = useState(false);
+  const [rotatePin, setRotatePin] = useState("");
+  const [rotateBusy, setRotateBusy] = useState(false);
+  const [rotateError, setRotateError] = useState<string | null>(null);
+  const [rotatedSecretKey, setRotatedSecretKey] = useState<string | null>(null);
+
   const [deleteConfirmText, setDeleteConfirmText] = useState("");
   const [isDeleting, setIsDeleting] = useState(false);
   const [deleteError, setDeleteError] = useState<string | null>(null);
@@ -239,6 +260,26 @@ export const SecurityPage: React.FC = observer(() => {
     }
   };
 
+  const rotateIdentity = async () => {
+    if (!currentUser) {
+      return;
+    }
+    setRotateBusy(true);
+    setRotateError(null);
+    try {
+      const secretKey = await api.rotateAccountIdentity(currentUser.id, rotatePin);
+      setRotatedSecretKey(secretKey);
+      setRotateOpen(false);
+      setRotatePin("");
+      void queryClient.invalidateQueries({ queryKey: ["safety", "fingerprint"] });
+      void queryClient.invalidateQueries({ queryKey: ["selfAuditAccountKey"] });
+    } catch (err) {
+      setRotateError(errorMessage(err, "Failed to rotate account key"));
+    } finally {
+      setRotateBusy(false);
+    }
+  };
+
   const deviceDisplayName = (device: api.DeviceInfo): string =>
     device.device_name ?? shortId(device.device_id);
 
@@ -292,6 +333,94 @@ export const SecurityPage: React.FC = observer(() => {
                 testId="self-account-key-audit"
               />
             )}
+            {ownFingerprint && (
+              <div className="flex flex-col gap-1" data-testid="own-fingerprint">
+                <span className="text-2xs uppercase tracking-widest" style={{ color: "var(--c-text-muted)" }}>
+                  Your fingerprint
+                </span>
+                <span className="text-xs tabular-nums" style={{ color: "var(--c-text)" }}>
+                  {ownFingerprint.fingerprint}
+                </span>
+                <span className="text-2xs" style={{ color: "var(--c-text-muted)", lineHeight: 1.5 }}>
+                  Half of every safety number a contact sees for you.
+                </span>
+              </div>
+            )}
+
+            {rotatedSecretKey ? (
+              <div className="flex flex-col gap-2" data-testid="rotated-secret-key">
+                <span className="text-xs" style={{ color: "var(--c-text)", lineHeight: 1.5 }}>
+                  Your account key was rotated. This is your new Secret Key —
+                  the old one no longer works. Save it now; it won't be shown
+                  again.
+                </span>
+                <span className="text-xs tabular-nums select-all" style={{ color: "var(--c-text)" }}>
+                  {rotatedSecretKey}
+                </span>
+                <div className="self-start">
+                  <Button
+                    data-testid="rotated-secret-key-done"
+                    size="sm"
+                    onClick={() => setRotatedSecretKey(null)}
+                  >
+                    I saved it
+                  </Button>
+                </div>
+              </div>
+            ) : rotateOpen ? (
+              <div className="flex flex-col gap-3" data-testid="rotate-identity-confirm">
+                <p className="text-xs" style={{ color: "var(--c-text-muted)", lineHeight: 1.5 }}>
+                  Your current key signs the new one, so contacts keep their
+                  verification. You get a new Secret Key, and your other
+                  devices will need it to sign in again.
+                </p>
+                <TextInput
+                  label="PIN"
+                  type="password"
+                  value={rotatePin}
+                  onChange={setRotatePin}
+                  autoFocus
+                  data-testid="rotate-identity-pin"
+                />
+                {rotateError && (
+                  <p className="text-xs" style={{ color: "var(--c-danger)" }}>
+                    {rotateError}
+                  </p>
+                )}
+                <div className="flex gap-2">
+                  <Button
+                    data-testid="rotate-identity-submit"
+                    size="sm"
+                    disabled={rotatePin.length === 0 || rotateBusy}
+                    onClick={rotateIdentity}
+                  >
+                    {rotateBusy ? "Rotating…" : "Rotate key"}
+                  </Button>
+                  <Button
+                    variant="secondary"
+                    size="sm"
+                    disabled={rotateBusy}
+                    onClick={() => {
+                      setRotateOpen(false);
+                      setRotatePin("");
+                      setRotateError(null);
+                    }}
+                  >
+                    Cancel
+                  </Button>
+                </div>
+              </div>
+            ) : (
+              <div className="self-start">
+                <Button
+                  data-testid="rotate-identity-button"
+                  variant="secondary"
+                  onClick={() => setRotateOpen(true)}
+                >
+                  Rotate account key
+                </Button>
+              </div>
+            )}
           </section>
 
           {/* This build — optional, on-demand check that this running build's
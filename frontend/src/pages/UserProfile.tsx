@@ -1,4 +1,4 @@
-import React from "react";
+import React, { useEffect, useState } from "react";
 import { useNavigate, useParams } from "@tanstack/react-router";
 import { ArrowLeft, MessageCircle, Ban } from "lucide-react";
 import { QRCodeSVG } from "qrcode.react";
@@ -9,8 +9,13 @@ import {
   useOtherUserProfile,
   useSafetyNumber,
   useSetContactVerified,
+  useContactNote,
+  useSaveContactNote,
 } from "../hooks/queries/useUserProfile";
 import { Button } from "../components/ui/Button";
+import { TextInput } from "../components/ui/TextInput";
+import { TextArea } from "../components/ui/TextArea";
+import { errorMessage } from "../utils/errorMessage";
 import { AccountKeyAuditLine } from "../components/Security/AccountKeyAuditLine";
 import { useBlockUser, usePeerAuditAccountKey } from "../hooks/queries";
 import { useCreateOrGetDMConversation } from "../hooks/queries/useMessages";
@@ -28,9 +33,22 @@ export const UserProfilePage: React.FC = observer(() => {
   const setVerified = useSetContactVerified(userId);
   const blockMutation = useBlockUser();
   const dmMutation = useCreateOrGetDMConversation();
+  const { data: contactNote } = useContactNote(userId);
+  const saveContactNote = useSaveContactNote(userId);
+  const [nicknameDraft, setNicknameDraft] = useState("");
+  const [notesDraft, setNotesDraft] = useState("");
 
   const isSelf = currentUser?.id === userId;
 
+  useEffect(() => {
+    setNicknameDraft(contactNote?.nickname ?? "");
+    setNotesDraft(contactNote?.notes ?? "");
+  }, [contactNote]);
+
+  const contactNoteDirty =
+    nicknameDraft.trim() !== (contactNote?.nickname ?? "") ||
+    notesDraft.trim() !== (contactNote?.notes ?? "");
+
   const handleBlock = async () => {
     try {
       await blockMutation.mutateAsync(userId);
@@ -255,6 +273,57 @@ export const UserProfilePage: React.FC = observer(() => {
                 />
               )}
 
+              {/* Local nickname + note. Stored only in this device's encrypted
+                  local DB; the contact never sees either. */}
+              {!isSelf && (
+                <div
+                  data-testid="contact-note"
+                  className="flex flex-col gap-3 pt-4"
+                  style={{ borderTop: "1px solid var(--c-border)" }}
+                >
+                  <TextInput
+                    id="contact-nickname"
+                    label="Nickname"
+                    value={nicknameDraft}
+                    onChange={setNicknameDraft}
+                    placeholder={profile.username ? `@${profile.username}` : ""}
+                    data-testid="contact-nickname-input"
+                  />
+                  <TextArea
+                    id="contact-notes"
+                    label="Notes"
+                    value={notesDraft}
+                    onChange={setNotesDraft}
+                    rows={3}
+                  />
+                  {saveContactNote.isError && (
+                    <span className="font-mono text-xs" style={{ color: "var(--c-danger)" }}>
+                      {errorMessage(saveContactNote.error)}
+                    </span>
+                  )}
+                  <p className="font-mono text-xs" style={{ color: "var(--c-text-muted)" }}>
+                    Only on this device. The nickname replaces their username in
+                    your member lists, DMs and search.
+                  </p>
+                  <div>
+                    <Button
+                      variant="secondary"
+                      disabled={!contactNoteDirty}
+                      isLoading={saveContactNote.isPending}
+                      onClick={() =>
+                        saveContactNote.mutate({
+                          nickname: nicknameDraft.trim(),
+                          notes: notesDraft.trim(),
+                        })
+                      }
+                      data-testid="contact-note-save"
+                    >
+                      Save
+                    </Button>
+                  </div>
+                </div>
+              )}
+
               <div style={{ borderTop: "1px solid var(--c-border)" }}>
                 <TerminalMenu items={items} onEsc={() => navigate({ to: "/dms" })} />
               </div>
@@ -22,6 +22,7 @@ import { cameraSession, LOCAL_CAMERA_PREVIEW_KEY, friendlyCameraError } from "..
 import { cameraPreviewStore } from "../camera/cameraPreviewStore";
 import { RemoteVideoTile } from "../components/Voice/RemoteVideoTile";
 import { useMediaPermissions, openPrivacySettings, type PermissionState } from "../hooks/queries/useMediaPermissions";
+import { useShortcutLabel } from "../keyboard";
 
 const CAMERA_DEVICE_KEY = "pollis:camera-device";
 
@@ -327,6 +328,11 @@ export const VoiceSettingsPage: React.FC = observer(() => {
   const aecEnabled = preferences.query.data?.echo_cancellation ?? true;
   const clickSuppression = preferences.query.data?.click_suppression ?? false;
 
+  const pushToTalk = preferences.query.data?.voice_input_mode === "push_to_talk";
+  const pushToTalkLabel = useShortcutLabel("voice.pushToTalk");
+  const vadSensitivity = preferences.query.data?.vad_sensitivity ?? 50;
+  const autoMuteSecs = preferences.query.data?.vad_auto_mute_secs ?? 0;
+
   const autoJoinVoice = preferences.query.data?.auto_join_voice ?? false;
   const handleAutoJoinVoice = (enabled: boolean) => {
     preferences.save({ ...preferences.query.data, auto_join_voice: enabled });
@@ -645,6 +651,48 @@ export const VoiceSettingsPage: React.FC = observer(() => {
           />
         </section>
 
+        <section className="flex flex-col gap-7 mb-12" data-testid="voice-input-section">
+          <h2
+            className="text-xs font-mono font-medium uppercase tracking-widest pb-1 border-b"
+            style={{ color: "var(--c-text)", borderColor: "var(--c-border)" }}
+          >
+            Voice Input
+          </h2>
+
+          <Switch
+            label="Push to Talk"
+            checked={pushToTalk}
+            onChange={(enabled) =>
+              savePrefsAndPushApm({ voice_input_mode: enabled ? "push_to_talk" : "voice_activity" })
+            }
+            description={`Only send your mic while you hold ${pushToTalkLabel}. Works while a Pollis window is focused; change the key under Key Bindings.`}
+          />
+
+          <RangeSlider
+            label="Voice Sensitivity"
+            value={vadSensitivity}
+            onChange={(v) => savePrefsAndPushApm({ vad_sensitivity: v })}
+            min={0}
+            max={100}
+            step={5}
+            disabled={pushToTalk}
+            sublabel="How quiet you can be and still count as talking. Turn it down if background noise lights up your speaking indicator; up if soft speech gets missed."
+            description={`${vadSensitivity}`}
+          />
+
+          <RangeSlider
+            label="Auto-Mute After Silence"
+            value={autoMuteSecs}
+            onChange={(v) => savePrefsAndPushApm({ vad_auto_mute_secs: v })}
+            min={0}
+            max={30}
+            step={1}
+            disabled={pushToTalk}
+            sublabel="Stop sending your mic after this long without speech, so room noise between sentences doesn't go out. Speaking again resumes it."
+            description={autoMuteSecs === 0 ? "off" : `${autoMuteSecs}s`}
+          />
+        </section>
+
         <section className="flex flex-col gap-4 mb-12">
           <h2
             className="text-xs font-mono font-medium uppercase tracking-widest pb-1 border-b"
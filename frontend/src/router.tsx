@@ -14,6 +14,7 @@ import { DMsPage } from "./pages/DMs";
 import { DMPage } from "./pages/DM";
 import { DMSettingsPage } from "./pages/DMSettings";
 import { LeaveGroupPage } from "./pages/LeaveGroup";
+import { DeleteGroupPage } from "./pages/DeleteGroup";
 import { VoiceChannelPage } from "./pages/VoiceChannel";
 import { CreateGroupPage } from "./pages/CreateGroupPage";
 import { SearchGroupPage } from "./pages/SearchGroupPage";
@@ -140,6 +141,12 @@ const leaveGroupRoute = createRoute({
   component: LeaveGroupPage,
 });
 
+const deleteGroupRoute = createRoute({
+  getParentRoute: () => rootRoute,
+  path: "/groups/$groupId/delete",
+  component: DeleteGroupPage,
+});
+
 const voiceChannelRoute = createRoute({
   getParentRoute: () => rootRoute,
   path: "/groups/$groupId/voice/$channelId",
@@ -293,6 +300,7 @@ const routeTree = rootRoute.addChildren([
   joinRequestsRoute,
   inviteMemberRoute,
   leaveGroupRoute,
+  deleteGroupRoute,
   voiceChannelRoute,
   dmsRoute,
   startDMRoute,
@@ -153,6 +153,16 @@ export async function resetIdentityAndRecover(
   return invoke<string>('reset_identity_and_recover', { userId, confirmEmail });
 }
 
+/// Planned identity-key rotation. The PIN re-confirms the user; the old key
+/// signs the new one so contacts re-pin without a key-change warning. Returns
+/// the new Secret Key, which replaces the old one — show it once.
+export async function rotateAccountIdentity(
+  userId: string,
+  pin: string,
+): Promise<string> {
+  return invoke<string>('rotate_account_identity', { userId, pin });
+}
+
 // ── Security events ────────────────────────────────────────────────────────
 
 export interface SecurityEvent {
@@ -252,7 +262,7 @@ export async function searchUserByUsername(username: string): Promise<UserProfil
 import { deriveSlug } from '../utils/urlRouting';
 
 type RawGroup = { id: string; name: string; description?: string; owner_id: string; created_at: string };
-type RawChannel = { id: string; group_id: string; name: string; description?: string; channel_type?: string };
+type RawChannel = { id: string; group_id: string; name: string; description?: string; channel_type?: string; retention_days?: number | null };
 
 function toGroup(g: RawGroup): Group {
   const ts = new Date(g.created_at).getTime();
@@ -275,25 +285,49 @@ function toChannel(c: RawChannel): Channel {
     name: c.name,
     description: c.description || '',
     channel_type: (c.channel_type === 'voice' ? 'voice' : 'text'),
+    retention_days: c.retention_days ?? null,
     created_by: '',
     created_at: 0,
     updated_at: 0,
   };
 }
 
-type RawGroupWithChannels = RawGroup & { channels: RawChannel[]; current_user_role: string };
+type RawGroupWithChannels = RawGroup & { channels: RawChannel[]; current_user_role: string; allow_export?: boolean; pinned?: boolean; slug?: string | null; deletion_purge_after?: string | null; storage_target?: string | null };
 
 export interface GroupWithChannels extends Group {
   channels: Channel[];
   current_user_role: 'admin' | 'member';
+  // Group policy: may admins export channel history (export_channel_messages)?
+  allow_export: boolean;
+  // Pinned to the top of this user's sidebar (synced `sidebar_order` pref).
+  pinned: boolean;
+  // When the owner's scheduled deletion purges the group (`YYYY-MM-DD HH:MM:SS`
+  // UTC, from the DS); null unless one is pending.
+  deletion_purge_after: string | null;
+  // Storage target the owner pinned attachments to (`groups.storage_target`);
+  // null for the deployment's default bucket.
+  storage_target: string | null;
+}
+
+// A store group attachments can be pinned to, declared by the deployment.
+export interface StorageTarget {
+  id: string;
+  label: string;
+  region: string;
 }
 
 export async function listUserGroupsWithChannels(userId: string): Promise<GroupWithChannels[]> {
   const groups = await invoke<RawGroupWithChannels[]>('list_user_groups_with_channels', { userId });
   return (groups || []).map((g) => ({
     ...toGroup(g),
+    // Reserved slug when the group has one; older groups keep the derived one.
+    slug: g.slug || deriveSlug(g.name),
     channels: (g.channels || []).map(toChannel),
     current_user_role: (g.current_user_role === 'admin' ? 'admin' : 'member') as 'admin' | 'member',
+    allow_export: g.allow_export ?? true,
+    pinned: g.pinned ?? false,
+    deletion_purge_after: g.deletion_purge_after ?? null,
+    storage_target: g.storage_target ?? null,
   }));
 }
 
@@ -1,6 +1,7 @@
 import { invoke } from '../bridge';
 import type { PresignedUploadResponse } from '../types';
 import { IMAGE_EXT_MIME } from '../utils/fileIcon';
+import { clearDownloadProgress } from '../utils/downloadProgress';
 
 function sanitizeFilename(name: string): string {
   return name.replace(/[^A-Za-z0-9._-]/g, '_');
@@ -136,6 +137,8 @@ export async function getMediaUrl(
   promise.catch(() => {
     // On error, drop the cached rejection so the next caller retries.
     inFlight.delete(contentHash);
+  }).finally(() => {
+    clearDownloadProgress(contentHash);
   });
   return promise;
 }
@@ -9,7 +9,12 @@ type CameraRemote = { trackKey: string; width: number; height: number };
 type EnrollmentApproval = { requestId: string; newDeviceId: string; verificationCode: string };
 type IncomingCall = { callId: string; roomName: string; callerId: string; callerUsername: string };
 type OutgoingCall = { callId: string; calleeId: string };
-type StatusBarAlert = { senderUsername: string; roomId: string };
+type StatusBarAlert = {
+  senderUsername: string;
+  roomId: string;
+  // Set for a reminder coming due: clicking jumps to this message.
+  reminder?: { messageId: string; groupId: string | null };
+};
 
 class AppStore implements AppState {
   // ── Core / user ────────────────────────────────────────────────────────
@@ -37,6 +42,10 @@ class AppStore implements AppState {
 
   // Unread message counts keyed by conversation_id or channel_id
   unreadCounts: Record<string, number> = {};
+  // What markRead last cleared, per conversation — how many messages were
+  // unread when it was opened, for the timeline's "unread" filter. Dropped
+  // again when the conversation is left (forgetOpenedUnread).
+  openedUnread: Record<string, number> = {};
 
   // Voice room + local screenshare state. Single source of truth — see
   // `frontend/src/types/voice-state.ts` for the union shape. Replaces the
@@ -50,6 +59,10 @@ class AppStore implements AppState {
   // channel/DM the user is not currently viewing. Cleared on navigation.
   statusBarAlert: StatusBarAlert | null = null;
 
+  // A message its conversation should scroll to once open and loaded (a
+  // reminder's jump link). That conversation's MessageList clears it.
+  focusMessage: { conversationId: string; messageId: string } | null = null;
+
   // Voice join failure — surfaced in the bottom bar when join_voice_channel
   // fails (e.g. the LiveKit server is unreachable). Cleared on dismiss or on
   // the next join attempt.
@@ -208,11 +221,21 @@ class AppStore implements AppState {
     if (!(id in this.unreadCounts)) {
       return;
     }
+    this.openedUnread = { ...this.openedUnread, [id]: this.unreadCounts[id] };
     const next = { ...this.unreadCounts };
     delete next[id];
     this.unreadCounts = next;
   }
 
+  forgetOpenedUnread(id: string) {
+    if (!(id in this.openedUnread)) {
+      return;
+    }
+    const next = { ...this.openedUnread };
+    delete next[id];
+    this.openedUnread = next;
+  }
+
   // Increments the unread count for a conversation or channel by 1
   incrementUnread(id: string) {
     this.unreadCounts = {
@@ -261,6 +284,7 @@ class AppStore implements AppState {
       micMuted: false,
       // Assume a mic until the backend says otherwise via `mic_availability`.
       micAvailable: true,
+      transmitting: true,
       share: { kind: 'idle' },
       camera: { kind: 'idle' },
     };
@@ -305,6 +329,13 @@ class AppStore implements AppState {
     this.voiceState = { ...this.voiceState, micAvailable: available };
   }
 
+  voiceSetTransmitting(transmitting: boolean) {
+    if (this.voiceState.kind !== 'joined') {
+      return;
+    }
+    this.voiceState = { ...this.voiceState, transmitting };
+  }
+
   shareStartPicking(sources: SourceList) {
     if (this.voiceState.kind !== 'joined' || this.voiceState.share.kind !== 'idle') {
       console.warn('[voiceState] shareStartPicking ignored:', this.voiceState.kind, 'share=', this.voiceState.kind === 'joined' ? this.voiceState.share.kind : 'n/a');
@@ -445,6 +476,10 @@ class AppStore implements AppState {
     this.statusBarAlert = alert;
   }
 
+  setFocusMessage(focus: { conversationId: string; messageId: string } | null) {
+    this.focusMessage = focus;
+  }
+
   setVoiceError(message: string | null) {
     this.voiceError = message;
   }
@@ -530,6 +565,7 @@ class AppStore implements AppState {
     this.isLoading = false;
     this.error = null;
     this.unreadCounts = {};
+    this.openedUnread = {};
     this.voiceState = { kind: 'idle' };
     this.statusBarAlert = null;
     this.voiceError = null;
@@ -18,5 +18,9 @@ export interface DmChannel {
     added_by: string;
     added_at: string;
     accepted_at?: string | null;
+    // This device's local nickname for the member (list_contact_notes).
+    nickname?: string | null;
   }>;
+  // Pinned in this user's sidebar (list_dm_channels only).
+  pinned?: boolean;
 }
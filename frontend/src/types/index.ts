@@ -48,12 +48,75 @@ export interface Group {
 export interface GroupMember {
   user_id: string;
   username?: string;
+  // This device's local nickname for the member, if one is set.
   display_name?: string;
   avatar_url?: string;
   role: 'admin' | 'member';
   joined_at: string;
 }
 
+// A group timeline notice from `get_group_events`. Derived on this device from
+// the roster and channel list, never sent; rendered inline between messages.
+export interface GroupEvent {
+  id: string;
+  group_id: string;
+  kind:
+    | 'member_joined'
+    | 'member_left'
+    | 'channel_created'
+    | 'deletion_scheduled'
+    | 'deletion_cancelled';
+  // User id (for `deletion_scheduled`, the owner who scheduled it), the
+  // created channel's id, or the group id for `deletion_cancelled`.
+  subject_id: string;
+  subject_name?: string | null;
+  // RFC 3339.
+  occurred_at: string;
+}
+
+// A pending two-step ownership transfer (`get_pending_ownership_transfers`).
+// Nothing changes hands until `to_user_id` accepts.
+export interface OwnershipTransfer {
+  group_id: string;
+  group_name: string;
+  from_user_id: string;
+  from_username?: string;
+  to_user_id: string;
+  created_at: string;
+}
+
+// Portable, message-free description of a group (`export_group_structure`).
+export interface GroupStructure {
+  format_version: number;
+  name: string;
+  description?: string;
+  icon_url?: string;
+  channels: GroupStructureChannel[];
+  members: GroupStructureMember[];
+  exported_at: string; // RFC 3339
+}
+
+export interface GroupStructureChannel {
+  name: string;
+  description?: string;
+  channel_type: 'text' | 'voice';
+}
+
+export interface GroupStructureMember {
+  user_id: string;
+  username?: string;
+  role: 'admin' | 'member';
+  is_owner: boolean;
+}
+
+// Text behind the "scan to join" QR (`get_group_join_code`). Locates the
+// group only — joining still needs an admin to approve the request.
+export interface GroupJoinCode {
+  payload: string; // pollis-group:v1:<group_id>:<tag>
+  group_name: string;
+  slug: string;
+}
+
 export interface Channel {
   id: string; // ULID
   group_id: string;
@@ -61,6 +124,8 @@ export interface Channel {
   name: string;
   description?: string;
   channel_type: 'text' | 'voice';
+  // Admin-set history window in days; null keeps history.
+  retention_days: number | null;
   created_by: string; // user_id
   created_at: number;
   updated_at: number;
@@ -87,6 +152,7 @@ export interface Message {
   // Edit/delete metadata
   edited_at?: string; // ISO timestamp if message was edited
   deleted_at?: string; // ISO timestamp if message was soft-deleted
+  sender_muted?: boolean; // sender muted in this channel's group (from the read commands)
   // UI state
   status?: 'pending' | 'sending' | 'sent' | 'failed' | 'cancelled';
 }
@@ -98,12 +164,52 @@ export interface ReplyPreview {
   timestamp: number;
 }
 
+// Who sent the ordinary messages an ingest just stored, per conversation
+// (ingest_channel_envelopes / ingest_dm_envelopes).
+export interface IngestedMessages {
+  conversation_id: string;
+  sender_ids: string[];
+}
+
+// Newest visible message per conversation, read from the local cache.
+export interface ConversationPreview {
+  conversation_id: string;
+  message_id: string;
+  sender_id: string;
+  sender_username?: string;
+  kind: "text" | "attachment";
+  snippet: string;
+  sent_at: string;
+  edited_at?: string;
+}
+
+// Per-channel outcome of `broadcast_announcement`. Exactly one of
+// message_id / error is set.
+export interface BroadcastTargetResult {
+  channel_id: string;
+  group_id?: string;
+  message_id?: string;
+  error?: string;
+}
+
+export interface BroadcastReport {
+  broadcast_id: string;
+  sent: number;
+  failed: number;
+  results: BroadcastTargetResult[];
+}
+
 export interface DMConversation {
   id: string; // ULID (conversation_id)
   user1_id: string; // user_id
   user2_identifier: string; // username/email/phone of other user
+  // This device's local nickname for the other user; shown in place of
+  // user2_identifier in listings when set.
+  user2_nickname?: string;
   user2_id?: string;
   user2_avatar_url?: string;
+  // Pinned to the top of this user's sidebar (synced `sidebar_order` pref).
+  pinned?: boolean;
   created_at: number;
   updated_at: number;
 }
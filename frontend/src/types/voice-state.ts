@@ -41,6 +41,10 @@ export type VoiceState =
        *  The UI shows a "listening only" indicator instead of a mute toggle.
        *  Set from the backend `mic_availability` event at join. */
       micAvailable: boolean;
+      /** False while push-to-talk (key not held) or auto-mute is sending
+       *  silence in place of the mic. Set from the backend `transmit_changed`
+       *  event; the voice bar dims the mic button. */
+      transmitting: boolean;
       share: ShareState;
       camera: CameraState;
     }
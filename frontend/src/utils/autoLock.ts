@@ -0,0 +1,95 @@
+// Device-local inactivity auto-lock. After the configured idle window with no
+// keyboard, pointer, or wheel input in the window, App runs the same path as
+// the Cmd/Ctrl+L screen lock: `lock()` drops the unwrapped keys and closes the
+// local DB, and the decrypted query cache is cleared, so nothing new can be
+// decrypted until the PIN is entered again.
+//
+// Same storage pattern as the call-ringtone toggle in `notify.ts`: keyed by
+// user id in localStorage. The value is the idle window in minutes; 0 (or
+// missing) means auto-lock is off.
+const AUTO_LOCK_KEY_PREFIX = 'pollis-auto-lock:';
+
+export const AUTO_LOCK_OPTIONS: { minutes: number; label: string }[] = [
+  { minutes: 0, label: 'Never' },
+  { minutes: 1, label: '1 minute' },
+  { minutes: 5, label: '5 minutes' },
+  { minutes: 15, label: '15 minutes' },
+  { minutes: 60, label: '1 hour' },
+];
+
+const ACTIVITY_EVENTS = ['keydown', 'pointerdown', 'pointermove', 'wheel'] as const;
+
+const listeners = new Set<() => void>();
+
+function autoLockKey(userId: string | null | undefined): string {
+  return `${AUTO_LOCK_KEY_PREFIX}${userId ?? 'anon'}`;
+}
+
+export function loadAutoLockMinutes(userId: string | null | undefined): number {
+  try {
+    const minutes = Number(localStorage.getItem(autoLockKey(userId)));
+    return Number.isFinite(minutes) && minutes > 0 ? minutes : 0;
+  } catch {
+    return 0;
+  }
+}
+
+export function saveAutoLockMinutes(userId: string | null | undefined, minutes: number): void {
+  try {
+    localStorage.setItem(autoLockKey(userId), String(minutes));
+  } catch {
+    // localStorage unavailable / quota exceeded — fall through silently
+  }
+  for (const listener of listeners) {
+    listener();
+  }
+}
+
+// Called whenever any user's auto-lock window changes on this device, so a
+// mounted idle timer can re-arm with the new value.
+export function onAutoLockChange(listener: () => void): () => void {
+  listeners.add(listener);
+  return () => {
+    listeners.delete(listener);
+  };
+}
+
+// Arm an idle timer that fires `onIdle` once after `minutes` without input.
+// Event-driven: every input event re-arms a single setTimeout. Returns the
+// teardown. `minutes <= 0` installs nothing.
+export function armIdleTimer(minutes: number, onIdle: () => void): () => void {
+  if (minutes <= 0) {
+    return () => {};
+  }
+  const windowMs = minutes * 60_000;
+  let timer: ReturnType<typeof setTimeout> | null = null;
+  let lastReset = 0;
+  const reset = () => {
+    const now = Date.now();
+    // pointermove fires constantly; re-arming more than once a second buys
+    // nothing at minute granularity.
+    if (timer !== null && now - lastReset < 1000) {
+      return;
+    }
+    lastReset = now;
+    if (timer !== null) {
+      clearTimeout(timer);
+    }
+    timer = setTimeout(() => {
+      timer = null;
+      onIdle();
+    }, windowMs);
+  };
+  reset();
+  for (const event of ACTIVITY_EVENTS) {
+    window.addEventListener(event, reset, { passive: true });
+  }
+  return () => {
+    if (timer !== null) {
+      clearTimeout(timer);
+    }
+    for (const event of ACTIVITY_EVENTS) {
+      window.removeEventListener(event, reset);
+    }
+  };
+}
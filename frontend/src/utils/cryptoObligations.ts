@@ -0,0 +1,71 @@
+import { useSyncExternalStore } from 'react';
+import { Channel, invoke } from '../bridge';
+
+// Mirrors CryptoObligations in pollis-core/src/commands/mls/obligations.rs
+export type ObligationKind = 'device_certs' | 'welcomes' | 'key_packages' | 'conversation';
+export type ObligationStatus = 'pending' | 'running' | 'done' | 'failed' | 'blocked';
+
+export type CryptoObligation = {
+  id: string;
+  kind: ObligationKind;
+  conversation_id: string | null;
+  status: ObligationStatus;
+  error: string | null;
+};
+
+export type CryptoObligations = {
+  user_id: string | null;
+  obligations: CryptoObligation[];
+  running: boolean;
+};
+
+// The MLS work this device owed when it came back (cert re-signing, Welcomes,
+// key packages, per-conversation catch-up). Rust runs the queue from
+// `catch_up_all_mls_groups` and pushes every step; nothing here polls.
+let queue: CryptoObligations = { user_id: null, obligations: [], running: false };
+const listeners = new Set<() => void>();
+let subscribed = false;
+
+function apply(next: CryptoObligations): void {
+  queue = next;
+  for (const listener of listeners) {
+    listener();
+  }
+}
+
+function ensureSubscribed(): void {
+  if (subscribed) {
+    return;
+  }
+  subscribed = true;
+  const channel = new Channel<CryptoObligations>();
+  channel.onmessage = (ev) => {
+    apply(ev);
+  };
+  invoke('subscribe_crypto_obligation_events', { onEvent: channel }).catch(() => {
+    // Older backend without the queue — the sweep still runs, just unreported.
+    subscribed = false;
+  });
+}
+
+function subscribe(listener: () => void): () => void {
+  ensureSubscribed();
+  listeners.add(listener);
+  return () => {
+    listeners.delete(listener);
+  };
+}
+
+export function useCryptoObligations(): CryptoObligations {
+  return useSyncExternalStore(subscribe, () => queue);
+}
+
+// Obligations that need the user: failed, or blocked behind a failure.
+export function unmetObligations(q: CryptoObligations): CryptoObligation[] {
+  return q.obligations.filter((o) => o.status === 'failed' || o.status === 'blocked');
+}
+
+// Re-run the failed and blocked obligations. Progress arrives on the channel.
+export async function retryCryptoObligations(userId: string): Promise<void> {
+  apply(await invoke<CryptoObligations>('retry_crypto_obligations', { userId }));
+}
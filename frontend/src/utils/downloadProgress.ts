@@ -0,0 +1,66 @@
+import { useSyncExternalStore } from 'react';
+import { Channel, invoke } from '../bridge';
+
+// Mirrors MediaDownloadProgress in pollis-core/src/commands/r2.rs
+type MediaDownloadProgress = {
+  content_hash: string;
+  received: number;
+  total: number | null;
+};
+
+// Byte progress of in-flight attachment downloads, keyed by content hash.
+// Rust reports roughly every MiB (and once at the end); a resumed download
+// starts from the bytes already on disk rather than zero.
+const progress = new Map<string, MediaDownloadProgress>();
+const listeners = new Set<() => void>();
+let subscribed = false;
+
+function notify(): void {
+  for (const listener of listeners) {
+    listener();
+  }
+}
+
+// One channel for the whole app, opened on first use.
+function ensureSubscribed(): void {
+  if (subscribed) {
+    return;
+  }
+  subscribed = true;
+  const channel = new Channel<MediaDownloadProgress>();
+  channel.onmessage = (ev) => {
+    progress.set(ev.content_hash, ev);
+    notify();
+  };
+  invoke('subscribe_media_download_events', { onEvent: channel }).catch(() => {
+    // Older backend without progress events — downloads still work, just
+    // without a percentage.
+    subscribed = false;
+  });
+}
+
+export function clearDownloadProgress(contentHash: string): void {
+  if (progress.delete(contentHash)) {
+    notify();
+  }
+}
+
+function subscribe(listener: () => void): () => void {
+  ensureSubscribed();
+  listeners.add(listener);
+  return () => {
+    listeners.delete(listener);
+  };
+}
+
+// Whole-number percentage for a download in flight, or null when nothing has
+// been reported yet or the size is unknown.
+export function useDownloadPercent(contentHash: string | undefined): number | null {
+  return useSyncExternalStore(subscribe, () => {
+    const p = contentHash ? progress.get(contentHash) : undefined;
+    if (!p || !p.total) {
+      return null;
+    }
+    return Math.min(100, Math.floor((p.received / p.total) * 100));
+  });
+}
@@ -0,0 +1,51 @@
+import { useSyncExternalStore } from 'react';
+import { useObserver } from 'mobx-react-lite';
+import { appStore } from '../stores/appStore';
+
+// Device-local low-bandwidth mode, for metered or slow connections. While on:
+// image / audio attachments wait for a click instead of auto-loading, avatars
+// aren't fetched (cached ones still render), and pasted media links aren't
+// unfurled. Messages themselves are unaffected.
+//
+// Same storage pattern as the auto-lock window in `autoLock.ts`: keyed by user
+// id in localStorage, with listeners so mounted components re-render when the
+// toggle flips.
+const LOW_BANDWIDTH_KEY_PREFIX = 'pollis-low-bandwidth:';
+
+const listeners = new Set<() => void>();
+
+function lowBandwidthKey(userId: string | null | undefined): string {
+  return `${LOW_BANDWIDTH_KEY_PREFIX}${userId ?? 'anon'}`;
+}
+
+export function loadLowBandwidthMode(userId: string | null | undefined): boolean {
+  try {
+    return localStorage.getItem(lowBandwidthKey(userId)) === '1';
+  } catch {
+    return false;
+  }
+}
+
+export function saveLowBandwidthMode(userId: string | null | undefined, enabled: boolean): void {
+  try {
+    localStorage.setItem(lowBandwidthKey(userId), enabled ? '1' : '0');
+  } catch {
+    // localStorage unavailable / quota exceeded — fall through silently
+  }
+  for (const listener of listeners) {
+    listener();
+  }
+}
+
+function subscribe(listener: () => void): () => void {
+  listeners.add(listener);
+  return () => {
+    listeners.delete(listener);
+  };
+}
+
+// Reactive read of the current user's low-bandwidth mode on this device.
+export function useLowBandwidthMode(): boolean {
+  const userId = useObserver(() => appStore.currentUser?.id);
+  return useSyncExternalStore(subscribe, () => loadLowBandwidthMode(userId));
+}
@@ -0,0 +1,113 @@
+import { useSyncExternalStore } from 'react';
+import { Channel, invoke } from '../bridge';
+import { errorMessage } from './errorMessage';
+
+// Mirrors MaintenanceStatus in pollis-core/src/commands/maintenance.rs
+export type MaintenanceStatus = {
+  enabled: boolean;
+  // Unix seconds
+  eta: number | null;
+  message: string | null;
+};
+
+// Re-check this long after the ETA (or now, without one), so the first check
+// doesn't race the operator closing the window.
+const RECHECK_GRACE_MS = 15_000;
+const RECHECK_DEFAULT_MS = 60_000;
+
+// The DS maintenance window. Rust pushes every change (a refused write opens
+// it, the first accepted write closes it); while it's open a single timer asks
+// the DS again at the announced ETA — one check, re-armed only while the
+// window stays open.
+let status: MaintenanceStatus = { enabled: false, eta: null, message: null };
+const listeners = new Set<() => void>();
+const waiters = new Set<() => void>();
+let subscribed = false;
+let recheckTimer: ReturnType<typeof setTimeout> | null = null;
+
+function apply(next: MaintenanceStatus): void {
+  status = next;
+  if (recheckTimer) {
+    clearTimeout(recheckTimer);
+    recheckTimer = null;
+  }
+  if (next.enabled) {
+    const untilEta = next.eta ? next.eta * 1000 - Date.now() : 0;
+    const delay = untilEta > 0 ? untilEta + RECHECK_GRACE_MS : RECHECK_DEFAULT_MS;
+    recheckTimer = setTimeout(() => {
+      recheckTimer = null;
+      void recheck();
+    }, delay);
+  } else {
+    for (const resolve of waiters) {
+      resolve();
+    }
+    waiters.clear();
+  }
+  for (const listener of listeners) {
+    listener();
+  }
+}
+
+async function recheck(): Promise<void> {
+  try {
+    apply(await invoke<MaintenanceStatus>('get_maintenance_status'));
+  } catch {
+    // DS unreachable — keep the window as it was and try again later.
+    apply(status);
+  }
+}
+
+// One channel for the whole app, opened on first use. Also asks the DS once,
+// so a window that opened before this launch shows without a refused write.
+function ensureSubscribed(): void {
+  if (subscribed) {
+    return;
+  }
+  subscribed = true;
+  const channel = new Channel<MaintenanceStatus>();
+  channel.onmessage = (ev) => {
+    apply(ev);
+  };
+  invoke('subscribe_maintenance_events', { onEvent: channel })
+    .then(() => recheck())
+    .catch(() => {
+      // Older backend without maintenance events — refused writes still
+      // fail as before, just without the banner.
+      subscribed = false;
+    });
+}
+
+function subscribe(listener: () => void): () => void {
+  ensureSubscribed();
+  listeners.add(listener);
+  return () => {
+    listeners.delete(listener);
+  };
+}
+
+export function useMaintenance(): MaintenanceStatus {
+  return useSyncExternalStore(subscribe, () => status);
+}
+
+// Whether `err` is the DS refusing a write during maintenance
+// (Error::Maintenance in pollis-core/src/error.rs).
+export function isMaintenanceError(err: unknown): boolean {
+  return errorMessage(err).startsWith('Server maintenance');
+}
+
+// Resolves as soon as the window is closed (immediately when it already is).
+// After a refused write, pass `refused` so a refusal that beats its channel
+// event still waits instead of retrying straight away.
+export function waitForWritable(refused = false): Promise<void> {
+  ensureSubscribed();
+  if (refused && !status.enabled) {
+    apply({ enabled: true, eta: null, message: null });
+  }
+  if (!status.enabled) {
+    return Promise.resolve();
+  }
+  return new Promise((resolve) => {
+    waiters.add(resolve);
+  });
+}
@@ -2,6 +2,8 @@ import { sendNotification } from '../bridge';
 import { playSfx } from './sfx';
 import { logIgnored } from './log';
 import { appStore } from '../stores/appStore';
+import { isRoomMuted } from './roomMute';
+import { clearDigest, getDigest, loadQuietHours, quietHoursEnd, recordDigest, topMentions } from './quietHours';
 
 export type Category =
   | 'direct_message'
@@ -14,7 +16,8 @@ export type Category =
   | 'group_invite'
   | 'enrollment'
   | 'incoming_call'
-  | 'all_mention';
+  | 'all_mention'
+  | 'reminder';
 
 type CategoryConfig = {
   sound?: 'ping' | 'join' | 'leave';
@@ -28,6 +31,11 @@ type CategoryConfig = {
   // is on globally. The looping ringtone itself is driven separately from
   // `AppShell`'s incomingCall effect via `start_ring` / `stop_ring`.
   honorsRingtonePref?: boolean;
+  // Held back during quiet hours: no sound, OS banner or status-bar alert,
+  // counted into the digest instead. The badge still updates.
+  digest?: boolean;
+  // Fires even in a room muted with `/mute` — the user asked for it.
+  ignoresRoomMute?: boolean;
   cooldownMs?: number;
 };
 
@@ -42,20 +50,23 @@ type CategoryConfig = {
 // personal events (DMs, invites, enrollment) — channel chatter only updates
 // the unread badge so noisy rooms don't become a constant ping.
 const CATEGORIES: Record<Category, CategoryConfig> = {
-  direct_message:    { sound: 'ping',  osNotif: true,  badge: true, alert: true, cooldownMs: 2500 },
-  channel_message:   {                                  badge: true,              cooldownMs: 2500 },
+  direct_message:    { sound: 'ping',  osNotif: true,  badge: true, alert: true, digest: true, cooldownMs: 2500 },
+  channel_message:   {                                  badge: true,              digest: true, cooldownMs: 2500 },
   voice_other_join:  { sound: 'join'                                                              },
   voice_other_leave: { sound: 'leave'                                                             },
   voice_self_join:   { sound: 'join'                                                              },
   voice_self_leave:  { sound: 'leave'                                                             },
-  dm_request:        { sound: 'ping',  osNotif: true,               alert: true                   },
-  group_invite:      { sound: 'ping',  osNotif: true,               alert: true                   },
+  dm_request:        { sound: 'ping',  osNotif: true,               alert: true, digest: true     },
+  group_invite:      { sound: 'ping',  osNotif: true,               alert: true, digest: true     },
   enrollment:        { sound: 'ping',  osNotif: true,                            overlay: true    },
   incoming_call:     {                  osNotif: true,                            honorsRingtonePref: true },
   // @all in a group: the one channel event that DOES raise an OS ping (unlike
   // channel_message). Badge is left to the accompanying new_message event so a
   // connected client doesn't double-count unread.
-  all_mention:       { sound: 'ping',  osNotif: true,                            cooldownMs: 2500 },
+  all_mention:       { sound: 'ping',  osNotif: true,                            digest: true, cooldownMs: 2500 },
+  // A message reminder coming due (`useReminderScheduler`). Not held for quiet
+  // hours: the user picked the time. The alert jumps to the message.
+  reminder:          { sound: 'ping',  osNotif: true,               alert: true, ignoresRoomMute: true },
 };
 
 export type NotifyPayload = {
@@ -64,6 +75,8 @@ export type NotifyPayload = {
   body?: string;
   senderUsername?: string;
   enrollment?: { requestId: string; newDeviceId: string; verificationCode: string };
+  // Reminder alerts only: the message to jump to, and its group (null = DM).
+  reminder?: { messageId: string; groupId: string | null };
 };
 
 type NotifyPrefs = {
@@ -136,12 +149,34 @@ export function notify(category: Category, payload: NotifyPayload = {}): void {
   // continues to read the account-level allow_sound_effects only.
   const ringtoneAllowed = !config.honorsRingtonePref || prefs.allowCallRingtone;
 
-  if (config.sound && prefs.allowSound && ringtoneAllowed && !cooled) {
+  // A room muted with `/mute` keeps counting unread but stays silent: no
+  // sound, OS banner, or status-bar alert until the mute expires.
+  const roomMuted = !config.ignoresRoomMute && !!payload.roomId && isRoomMuted(appStore.currentUser?.id, payload.roomId);
+
+  // Quiet hours: hold the event back for the end-of-window digest. A muted
+  // room stays out of the digest too. Calls and enrollment always get through.
+  const quietEnd = config.digest ? quietHoursEnd(loadQuietHours(appStore.currentUser?.id)) : null;
+  if (quietEnd !== null) {
+    if (!roomMuted) {
+      recordDigest(quietEnd, {
+        roomId: payload.roomId ?? category,
+        title: payload.title,
+        mentionFrom: category === 'all_mention' ? payload.senderUsername : undefined,
+      });
+      scheduleDigestFlush(quietEnd);
+    }
+    if (config.badge && payload.roomId) {
+      appStore.incrementUnread(payload.roomId);
+    }
+    return;
+  }
+
+  if (config.sound && prefs.allowSound && ringtoneAllowed && !roomMuted && !cooled) {
     playSfx(config.sound);
     fired = true;
   }
 
-  if (config.osNotif && prefs.allowOsNotif && prefs.osPermissionGranted && ringtoneAllowed && !cooled) {
+  if (config.osNotif && prefs.allowOsNotif && prefs.osPermissionGranted && ringtoneAllowed && !roomMuted && !cooled) {
     const title = payload.title ?? 'New message';
     const body = payload.body ?? (payload.senderUsername ? `${payload.senderUsername}: New message` : '');
     sendNotification({ title, body }).catch(logIgnored);
@@ -152,10 +187,11 @@ export function notify(category: Category, payload: NotifyPayload = {}): void {
     appStore.incrementUnread(payload.roomId);
   }
 
-  if (config.alert && payload.roomId && payload.senderUsername) {
+  if (config.alert && payload.roomId && payload.senderUsername && !roomMuted) {
     appStore.setStatusBarAlert({
       senderUsername: payload.senderUsername,
       roomId: payload.roomId,
+      reminder: payload.reminder,
     });
   }
 
@@ -167,3 +203,58 @@ export function notify(category: Category, payload: NotifyPayload = {}): void {
     cooldowns.set(cooldownKey, now);
   }
 }
+
+let digestTimer: ReturnType<typeof setTimeout> | null = null;
+
+// One timer for the end of the current quiet window. If the window was moved
+// later in the meantime, the flush re-arms for the new end instead.
+function scheduleDigestFlush(windowEnd: number): void {
+  if (digestTimer !== null) {
+    return;
+  }
+  digestTimer = setTimeout(() => {
+    digestTimer = null;
+    const stillQuiet = quietHoursEnd(loadQuietHours(appStore.currentUser?.id));
+    if (stillQuiet !== null) {
+      scheduleDigestFlush(stillQuiet);
+      return;
+    }
+    deliverDigest();
+  }, Math.max(0, windowEnd - Date.now()));
+}
+
+// The single end-of-quiet-hours notification. The digest itself stays
+// readable from Preferences until the next quiet window replaces it.
+function deliverDigest(): void {
+  const digest = getDigest();
+  if (!digest || digest.rooms.length === 0) {
+    return;
+  }
+  const total = digest.rooms.reduce((n, r) => n + r.count, 0);
+  const title = `While you were away: ${total} notification${total === 1 ? '' : 's'}`;
+  const lines = [...digest.rooms]
+    .sort((a, b) => b.count - a.count)
+    .slice(0, 4)
+    .map((room) => {
+      const mentions = topMentions(room);
+      return mentions.length > 0
+        ? `${room.title} (${room.count}) — @all from ${mentions.join(', ')}`
+        : `${room.title} (${room.count})`;
+    });
+  if (prefs.allowSound) {
+    playSfx('ping');
+  }
+  if (prefs.allowOsNotif && prefs.osPermissionGranted) {
+    sendNotification({ title, body: lines.join('\n') }).catch(logIgnored);
+  }
+}
+
+// Drop the digest and its pending delivery. Called when the signed-in user
+// goes away so one account's digest never surfaces for the next.
+export function resetQuietHoursDigest(): void {
+  if (digestTimer !== null) {
+    clearTimeout(digestTimer);
+    digestTimer = null;
+  }
+  clearDigest();
+}
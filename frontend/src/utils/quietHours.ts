@@ -0,0 +1,176 @@
+import { useSyncExternalStore } from 'react';
+
+// Device-local quiet hours. Inside the window, notify() stops pinging for
+// message-like categories and records them here instead; when the window ends
+// the digest is summarised in a single notification (see `notify.ts`).
+//
+// Same storage pattern as the call-ringtone toggle in `notify.ts`: keyed by
+// user id in localStorage. The value is `{ start, end }` in minutes after
+// local midnight; a window whose end is earlier than its start runs over
+// midnight. Missing means quiet hours are off.
+//
+// The digest itself lives in memory only — room names, counts and sender
+// usernames never touch disk, and a restart or sign-out drops it.
+const QUIET_HOURS_KEY_PREFIX = 'pollis-quiet-hours:';
+
+export type QuietHours = { start: number; end: number };
+
+export type DigestRoom = {
+  roomId: string;
+  title: string;
+  count: number;
+  // Sender username → @all mentions from them, for the "top mentions" line.
+  mentions: Record<string, number>;
+  // Epoch-ms of the first message held back — where unread starts.
+  firstUnreadAt: number;
+};
+
+export type Digest = {
+  // Epoch-ms the quiet window that collected this digest ends.
+  windowEnd: number;
+  rooms: DigestRoom[];
+};
+
+function quietHoursKey(userId: string | null | undefined): string {
+  return `${QUIET_HOURS_KEY_PREFIX}${userId ?? 'anon'}`;
+}
+
+export function loadQuietHours(userId: string | null | undefined): QuietHours | null {
+  try {
+    const raw = localStorage.getItem(quietHoursKey(userId));
+    if (raw === null) {
+      return null;
+    }
+    const parsed = JSON.parse(raw);
+    if (!parsed || typeof parsed.start !== 'number' || typeof parsed.end !== 'number' || parsed.start === parsed.end) {
+      return null;
+    }
+    return { start: parsed.start, end: parsed.end };
+  } catch {
+    return null;
+  }
+}
+
+// `null` turns quiet hours off.
+export function saveQuietHours(userId: string | null | undefined, hours: QuietHours | null): void {
+  try {
+    if (hours === null) {
+      localStorage.removeItem(quietHoursKey(userId));
+    } else {
+      localStorage.setItem(quietHoursKey(userId), JSON.stringify(hours));
+    }
+  } catch {
+    // localStorage unavailable / quota exceeded — fall through silently
+  }
+}
+
+// Epoch-ms the window containing `now` ends, or null when `now` is outside it.
+export function quietHoursEnd(hours: QuietHours | null, now: number = Date.now()): number | null {
+  if (!hours) {
+    return null;
+  }
+  const d = new Date(now);
+  const minute = d.getHours() * 60 + d.getMinutes();
+  const wraps = hours.end < hours.start;
+  const inside = wraps
+    ? minute >= hours.start || minute < hours.end
+    : minute >= hours.start && minute < hours.end;
+  if (!inside) {
+    return null;
+  }
+  const end = new Date(now);
+  end.setHours(Math.floor(hours.end / 60), hours.end % 60, 0, 0);
+  if (end.getTime() <= now) {
+    end.setDate(end.getDate() + 1);
+  }
+  return end.getTime();
+}
+
+// "HH:MM" ↔ minutes after midnight, for `<input type="time">`.
+export function minutesToTime(minutes: number): string {
+  const h = Math.floor(minutes / 60);
+  const m = minutes % 60;
+  return `${String(h).padStart(2, '0')}:${String(m).padStart(2, '0')}`;
+}
+
+export function timeToMinutes(value: string): number | null {
+  const match = /^(\d{1,2}):(\d{2})$/.exec(value);
+  if (!match) {
+    return null;
+  }
+  const h = Number(match[1]);
+  const m = Number(match[2]);
+  if (h > 23 || m > 59) {
+    return null;
+  }
+  return h * 60 + m;
+}
+
+let digest: Digest | null = null;
+const listeners = new Set<() => void>();
+
+function emit(): void {
+  for (const listener of listeners) {
+    listener();
+  }
+}
+
+// Count one held-back notification against `roomId`. An @all mention
+// (`mentionFrom`) arrives alongside its own new-message event, so it's tallied
+// as a mention without counting the message twice. A digest left over from an
+// earlier window is replaced rather than extended.
+export function recordDigest(
+  windowEnd: number,
+  entry: { roomId: string; title?: string; mentionFrom?: string },
+): void {
+  if (!digest || digest.windowEnd !== windowEnd) {
+    digest = { windowEnd, rooms: [] };
+  }
+  const rooms = [...digest.rooms];
+  const index = rooms.findIndex((r) => r.roomId === entry.roomId);
+  const prev: DigestRoom = index >= 0
+    ? rooms[index]
+    : { roomId: entry.roomId, title: entry.title ?? 'New message', count: 0, mentions: {}, firstUnreadAt: Date.now() };
+  const next: DigestRoom = { ...prev, count: entry.mentionFrom ? prev.count : prev.count + 1 };
+  if (entry.mentionFrom) {
+    next.mentions = { ...prev.mentions, [entry.mentionFrom]: (prev.mentions[entry.mentionFrom] ?? 0) + 1 };
+  }
+  if (index >= 0) {
+    rooms[index] = next;
+  } else {
+    rooms.push(next);
+  }
+  digest = { windowEnd, rooms };
+  emit();
+}
+
+// The current digest, or null when nothing has been held back.
+export function getDigest(): Digest | null {
+  return digest;
+}
+
+export function clearDigest(): void {
+  if (digest !== null) {
+    digest = null;
+    emit();
+  }
+}
+
+// Senders ordered by how often they mentioned @all, most first.
+export function topMentions(room: DigestRoom, limit = 3): string[] {
+  return Object.entries(room.mentions)
+    .sort((a, b) => b[1] - a[1])
+    .slice(0, limit)
+    .map(([sender]) => sender);
+}
+
+function subscribe(listener: () => void): () => void {
+  listeners.add(listener);
+  return () => {
+    listeners.delete(listener);
+  };
+}
+
+export function useDigest(): Digest | null {
+  return useSyncExternalStore(subscribe, getDigest);
+}
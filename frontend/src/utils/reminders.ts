@@ -0,0 +1,44 @@
+// "Remind me" presets for a message. Times are worked out in the device's
+// local time zone and sent as an absolute instant (unix ms), so a reminder
+// made for "tomorrow 9am" fires at that moment on every linked device, even
+// one set to another zone. Using the local Date constructor keeps 9am at 9am
+// across a DST change overnight.
+
+export type ReminderPreset = { label: string; at: number };
+
+const MINUTE = 60_000;
+const HOUR = 60 * MINUTE;
+
+// The next `hour`:00 local time on a day `days` ahead of `now`.
+function atLocalHour(now: Date, days: number, hour: number): number {
+  return new Date(now.getFullYear(), now.getMonth(), now.getDate() + days, hour, 0, 0, 0).getTime();
+}
+
+export function reminderPresets(now: Date = new Date()): ReminderPreset[] {
+  const t = now.getTime();
+  const presets: ReminderPreset[] = [
+    { label: "In 20 minutes", at: t + 20 * MINUTE },
+    { label: "In 1 hour", at: t + HOUR },
+    { label: "In 2 hours", at: t + 2 * HOUR },
+  ];
+  // Later today only while there's a useful gap before 6pm.
+  const evening = atLocalHour(now, 0, 18);
+  if (evening - t > 2 * HOUR) {
+    presets.push({ label: "This evening, 6pm", at: evening });
+  }
+  presets.push({ label: "Tomorrow, 9am", at: atLocalHour(now, 1, 9) });
+  // Monday is day 1; from a Monday this is the following week's.
+  const daysToMonday = ((8 - now.getDay()) % 7) || 7;
+  presets.push({ label: "Next Monday, 9am", at: atLocalHour(now, daysToMonday, 9) });
+  return presets;
+}
+
+// Short "when" for a pending reminder: time today, else weekday + time.
+export function formatReminderTime(at: number, now: Date = new Date()): string {
+  const when = new Date(at);
+  const time = when.toLocaleTimeString([], { hour: "numeric", minute: "2-digit" });
+  if (when.toDateString() === now.toDateString()) {
+    return time;
+  }
+  return `${when.toLocaleDateString([], { weekday: "short", month: "short", day: "numeric" })}, ${time}`;
+}
@@ -0,0 +1,65 @@
+// Device-local per-room notification mute, set by `/mute` in the composer.
+// Same storage pattern as the call-ringtone toggle in `notify.ts`: keyed by
+// user id in localStorage so a shared OS account with multiple Pollis users
+// keeps each user's mutes separate. The value is a map of room id (channel
+// id or DM conversation id — the same `roomId` notify() receives) to the
+// epoch-ms instant the mute expires. Expired entries are treated as unmuted
+// and dropped on the next write.
+const ROOM_MUTE_KEY_PREFIX = 'pollis-room-mute:';
+
+type MuteMap = Record<string, number>;
+
+function roomMuteKey(userId: string | null | undefined): string {
+  return `${ROOM_MUTE_KEY_PREFIX}${userId ?? 'anon'}`;
+}
+
+function loadMutes(userId: string | null | undefined): MuteMap {
+  try {
+    const raw = localStorage.getItem(roomMuteKey(userId));
+    if (raw === null) {
+      return {};
+    }
+    const parsed = JSON.parse(raw);
+    return parsed && typeof parsed === 'object' ? (parsed as MuteMap) : {};
+  } catch {
+    return {};
+  }
+}
+
+function saveMutes(userId: string | null | undefined, mutes: MuteMap): void {
+  try {
+    localStorage.setItem(roomMuteKey(userId), JSON.stringify(mutes));
+  } catch {
+    // localStorage unavailable / quota exceeded — fall through silently
+  }
+}
+
+// Epoch-ms the room's mute expires, or null when it isn't muted.
+export function roomMutedUntil(userId: string | null | undefined, roomId: string): number | null {
+  const until = loadMutes(userId)[roomId];
+  if (typeof until !== 'number' || until <= Date.now()) {
+    return null;
+  }
+  return until;
+}
+
+export function isRoomMuted(userId: string | null | undefined, roomId: string): boolean {
+  return roomMutedUntil(userId, roomId) !== null;
+}
+
+// Mute `roomId` for `durationMs`. A duration of 0 clears the mute.
+export function muteRoom(userId: string | null | undefined, roomId: string, durationMs: number): void {
+  const now = Date.now();
+  const mutes = loadMutes(userId);
+  for (const [id, until] of Object.entries(mutes)) {
+    if (until <= now) {
+      delete mutes[id];
+    }
+  }
+  if (durationMs > 0) {
+    mutes[roomId] = now + durationMs;
+  } else {
+    delete mutes[roomId];
+  }
+  saveMutes(userId, mutes);
+}
@@ -0,0 +1,259 @@
+import type { QueryClient } from "@tanstack/react-query";
+import { invoke } from "../bridge";
+import { groupQueryKeys } from "../hooks/queries/useGroups";
+import { muteRoom } from "./roomMute";
+import { pinQueryKeys } from "../hooks/queries/usePins";
+
+// Composer slash commands. A line starting with `/name` is parsed here and
+// run against the room it was typed in instead of being sent as a message.
+// Every built-in goes through the same Rust commands the mouse-driven UI
+// uses, so permission checks (admin-only invite, channel edits) stay on the
+// backend. `//text` escapes to a literal message starting with `/`.
+//
+// The registry is open: feature code can `registerSlashCommand()` its own
+// entries and they show up in `listSlashCommands()` (the composer's
+// suggestion row) exactly like the built-ins.
+
+export interface SlashContext {
+  userId: string;
+  // Set for group channels; null in DMs.
+  groupId: string | null;
+  channelId: string | null;
+  conversationId: string | null;
+  // The message the composer is replying to, if any.
+  replyToMessageId: string | null;
+  queryClient: QueryClient;
+}
+
+export interface SlashCommand {
+  name: string;
+  usage: string;
+  description: string;
+  // Disabled commands stay listed (so they're discoverable) but refuse to
+  // run until something calls `setSlashCommandEnabled(name, true)`.
+  enabled: boolean;
+  // Resolves to a short confirmation shown inline under the composer.
+  // Throw to report a failure the same way.
+  run: (ctx: SlashContext, args: string) => Promise<string>;
+}
+
+export interface ParsedSlashLine {
+  name: string;
+  args: string;
+}
+
+const registry = new Map<string, SlashCommand>();
+
+export function registerSlashCommand(command: SlashCommand): void {
+  const name = command.name.toLowerCase();
+  if (registry.has(name)) {
+    throw new Error(`slash command /${name} is already registered`);
+  }
+  registry.set(name, { ...command, name });
+}
+
+export function setSlashCommandEnabled(name: string, enabled: boolean): void {
+  const command = registry.get(name.toLowerCase());
+  if (command) {
+    command.enabled = enabled;
+  }
+}
+
+// Registered commands whose name starts with `prefix`, sorted by name.
+export function listSlashCommands(prefix = ""): SlashCommand[] {
+  const needle = prefix.toLowerCase();
+  return [...registry.values()]
+    .filter((c) => c.name.startsWith(needle))
+    .sort((a, b) => a.name.localeCompare(b.name));
+}
+
+// Split `/name rest of line` into its parts. Returns null when the line is
+// not a command: no leading `/`, the `//` escape, or an empty name.
+export function parseSlashLine(line: string): ParsedSlashLine | null {
+  const trimmed = line.trimStart();
+  if (!trimmed.startsWith("/") || trimmed.startsWith("//")) {
+    return null;
+  }
+  const match = /^\/(\S+)\s*([\s\S]*)$/.exec(trimmed);
+  if (!match) {
+    return null;
+  }
+  return { name: match[1].toLowerCase(), args: match[2].trim() };
+}
+
+// True when `line` names a registered command — the composer's cue to route
+// it to `executeSlashCommand` instead of sending it.
+export function isSlashCommand(line: string): boolean {
+  const parsed = parseSlashLine(line);
+  return !!parsed && registry.has(parsed.name);
+}
+
+export async function executeSlashCommand(ctx: SlashContext, line: string): Promise<string> {
+  const parsed = parseSlashLine(line);
+  if (!parsed) {
+    throw new Error("not a command");
+  }
+  const command = registry.get(parsed.name);
+  if (!command) {
+    throw new Error(`unknown command /${parsed.name}`);
+  }
+  if (!command.enabled) {
+    throw new Error(`/${command.name} is disabled`);
+  }
+  return command.run(ctx, parsed.args);
+}
+
+const DURATION_UNITS_MS: Record<string, number> = {
+  m: 60_000,
+  h: 3_600_000,
+  d: 86_400_000,
+  w: 604_800_000,
+};
+
+// Longest accepted `/mute` — anything beyond this is really "leave the room".
+export const MAX_MUTE_MS = 30 * DURATION_UNITS_MS.d;
+
+// Parse `30m`, `1h`, `2d`, `1w` into milliseconds. Returns null for anything
+// else, including zero and values past MAX_MUTE_MS.
+export function parseDuration(input: string): number | null {
+  const match = /^(\d+)\s*([mhdw])$/i.exec(input.trim());
+  if (!match) {
+    return null;
+  }
+  const ms = Number(match[1]) * DURATION_UNITS_MS[match[2].toLowerCase()];
+  if (ms <= 0 || ms > MAX_MUTE_MS) {
+    return null;
+  }
+  return ms;
+}
+
+function roomIdOf(ctx: SlashContext): string | null {
+  return ctx.channelId ?? ctx.conversationId;
+}
+
+registerSlashCommand({
+  name: "invite",
+  usage: "/invite <username or email>",
+  description: "Invite someone to this group",
+  enabled: true,
+  run: async (ctx, args) => {
+    if (!ctx.groupId) {
+      throw new Error("/invite only works in a group channel");
+    }
+    const invitee = args.replace(/^@/, "");
+    if (!invitee || /\s/.test(invitee)) {
+      throw new Error("usage: /invite <username or email>");
+    }
+    await invoke("send_group_invite", {
+      groupId: ctx.groupId,
+      inviterId: ctx.userId,
+      inviteeIdentifier: invitee,
+    });
+    return `invited ${invitee}`;
+  },
+});
+
+registerSlashCommand({
+  name: "topic",
+  usage: "/topic <text>",
+  description: "Set this channel's topic (empty clears it)",
+  enabled: true,
+  run: async (ctx, args) => {
+    if (!ctx.groupId || !ctx.channelId) {
+      throw new Error("/topic only works in a group channel");
+    }
+    await invoke("update_channel", {
+      channelId: ctx.channelId,
+      requesterId: ctx.userId,
+      name: null,
+      description: args,
+    });
+    ctx.queryClient.invalidateQueries({ queryKey: groupQueryKeys.channels(ctx.groupId) });
+    ctx.queryClient.invalidateQueries({ queryKey: groupQueryKeys.userGroupsWithChannels(ctx.userId) });
+    return args ? "topic updated" : "topic cleared";
+  },
+});
+
+registerSlashCommand({
+  name: "mute",
+  usage: "/mute <30m|1h|1d|1w|off>",
+  description: "Silence notifications from this room on this device",
+  enabled: true,
+  run: async (ctx, args) => {
+    const roomId = roomIdOf(ctx);
+    if (!roomId) {
+      throw new Error("nothing to mute here");
+    }
+    if (args.toLowerCase() === "off") {
+      muteRoom(ctx.userId, roomId, 0);
+      return "unmuted";
+    }
+    const ms = parseDuration(args);
+    if (ms === null) {
+      throw new Error("usage: /mute <30m|1h|1d|1w|off> (max 30d)");
+    }
+    muteRoom(ctx.userId, roomId, ms);
+    return `muted for ${args.trim()}`;
+  },
+});
+
+registerSlashCommand({
+  name: "translate",
+  usage: "/translate <language|off>",
+  description: "Set this room's translation language on this device",
+  enabled: true,
+  run: async (ctx, args) => {
+    const roomId = roomIdOf(ctx);
+    if (!roomId) {
+      throw new Error("nothing to translate here");
+    }
+    if (!args) {
+      const current = await invoke<string | null>("get_conversation_translation_language", {
+        conversationId: roomId,
+      });
+      return current ? `translating to ${current}` : "translation is off here";
+    }
+    const off = args.toLowerCase() === "off";
+    // The core validates the tag (BCP 47 shape: en, pt-BR, zh-Hant).
+    await invoke("set_conversation_translation_language", {
+      conversationId: roomId,
+      targetLang: off ? null : args,
+    });
+    return off ? "translation off" : `translating to ${args}`;
+  },
+});
+
+registerSlashCommand({
+  name: "pin",
+  usage: "/pin",
+  description: "Pin the message you're replying to",
+  enabled: true,
+  run: async (ctx) => {
+    const roomId = roomIdOf(ctx);
+    if (!roomId) {
+      throw new Error("nothing to pin here");
+    }
+    if (!ctx.replyToMessageId) {
+      throw new Error("reply to a message, then /pin");
+    }
+    await invoke("pin_message", {
+      conversationId: roomId,
+      messageId: ctx.replyToMessageId,
+      userId: ctx.userId,
+    });
+    ctx.queryClient.invalidateQueries({ queryKey: pinQueryKeys.conversation(roomId) });
+    return "pinned";
+  },
+});
+
+registerSlashCommand({
+  name: "giphy",
+  usage: "/giphy <search>",
+  description: "Search GIPHY for a GIF",
+  // Off by default: the search term would leave the device in plaintext to a
+  // third party, which the rest of the app never does.
+  enabled: false,
+  run: async () => {
+    throw new Error("/giphy has no provider configured");
+  },
+});
@@ -30,6 +30,7 @@ export type VoiceEvent =
   | { type: 'muted'; identity: string }
   | { type: 'unmuted'; identity: string }
   | { type: 'mic_availability'; identity: string; available: boolean }
+  | { type: 'transmit_changed'; identity: string; transmitting: boolean }
   | { type: 'speaking_started'; identity: string }
   | { type: 'speaking_stopped'; identity: string }
   | { type: 'audio_bands'; identity: string; bands: number[] }
@@ -86,6 +87,9 @@ export interface VoiceSessionState {
    *  Mirrored onto the store's `joined.micAvailable`; drives the "listening
    *  only" indicator in place of the mute toggle. */
   micAvailable: boolean;
+  /** False while push-to-talk or auto-mute is holding the mic back. Mirrored
+   *  onto the store's `joined.transmitting`; dims the voice-bar mic. */
+  transmitting: boolean;
   /** Last error from a failed join. Cleared on the next intent change. */
   error: string | null;
 }
@@ -126,6 +130,7 @@ const INITIAL_STATE: VoiceSessionState = {
   participants: [],
   isMuted: false,
   micAvailable: true,
+  transmitting: true,
   error: null,
 };
 
@@ -298,6 +303,21 @@ class VoiceSessionManager {
     }
   }
 
+  /**
+   * Press or release the push-to-talk key. No-op if not joined; the backend
+   * ignores it outside push-to-talk mode.
+   */
+  async setPushToTalk(pressed: boolean): Promise<void> {
+    if (this.state.phase !== 'joined') {
+      return;
+    }
+    try {
+      await invoke('set_voice_push_to_talk', { pressed });
+    } catch (e) {
+      console.warn('[voice] set_voice_push_to_talk failed:', e);
+    }
+  }
+
   /** Persist a device pref and live-switch the input device. Safe to call outside a session. */
   async setInputDevice(deviceName: string): Promise<void> {
     persistDevicePref('input', deviceName);
@@ -461,6 +481,7 @@ class VoiceSessionManager {
       // Reset any stale listen-only state from a previous session; the
       // backend re-asserts it via `mic_availability` on this join.
       micAvailable: true,
+      transmitting: true,
       participants: [
         {
           identity: localIdentity,
@@ -689,6 +710,13 @@ class VoiceSessionManager {
         }
         break;
       }
+      case 'transmit_changed': {
+        // Only ever carries the local identity.
+        if (event.identity === localIdentity) {
+          this.setState({ transmitting: event.transmitting });
+        }
+        break;
+      }
       case 'speaking_started':
       case 'speaking_stopped': {
         const speaking = event.type === 'speaking_started';
@@ -905,7 +933,7 @@ voiceSession.subscribe(() => {
         store.voiceStartJoining(s.channelId, s.counterpartyUserId);
         store.voiceJoined();
       }
-      // Mic-mute + availability mirror.
+      // Mic-mute + availability + transmit mirror.
       const after = appStore.voiceState;
       if (after.kind === 'joined' && after.micMuted !== s.isMuted) {
         store.voiceSetMicMuted(s.isMuted);
@@ -913,6 +941,9 @@ voiceSession.subscribe(() => {
       if (after.kind === 'joined' && after.micAvailable !== s.micAvailable) {
         store.voiceSetMicAvailable(s.micAvailable);
       }
+      if (after.kind === 'joined' && after.transmitting !== s.transmitting) {
+        store.voiceSetTransmitting(s.transmitting);
+      }
       break;
     }
     case 'leaving': {
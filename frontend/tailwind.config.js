@@ -28,6 +28,8 @@ export default {
         fg:    'var(--c-text)',
         dim:   'var(--c-text-dim)',
         muted: 'var(--c-text-muted)',
+        // Destructive actions and errors → `text-danger`.
+        danger: 'var(--c-danger)',
         // Hairline borders / dividers → `border-line` `border-line-strong`,
         // also `bg-line` for the rare hairline fill (e.g. tray separator).
         line: {
@@ -242,7 +242,8 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
     };
 
     use crate::commands::{
-        auth, blocks, device_enrollment, dm, groups, messages, pin, safety, user,
+        auth, blocks, contacts, device_enrollment, dm, groups, links, maintenance, messages, pin,
+        reminders, safety, sidebar, user,
     };
 
     match cmd.as_str() {
@@ -270,6 +271,11 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             crate::commands::mls::poll_mls_welcomes(&state()?, user_id).await?;
             ok(())
         }
+        "get_crypto_obligations" => ok(crate::commands::mls::get_crypto_obligations()),
+        "retry_crypto_obligations" => {
+            let user_id: String = arg(&args, "userId")?;
+            ok(crate::commands::mls::retry_crypto_obligations(&state()?, user_id).await?)
+        }
         "logout" => {
             let delete: bool = arg_opt(&args, "deleteData")?.unwrap_or(false);
             auth::logout(&state()?, delete).await?;
@@ -309,6 +315,11 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             device_enrollment::recover_with_secret_key(&state()?, user_id, secret_key).await?;
             ok(())
         }
+        "rotate_account_identity" => {
+            let user_id: String = arg(&args, "userId")?;
+            let p: String = arg(&args, "pin")?;
+            ok(device_enrollment::rotate_account_identity(&state()?, user_id, p).await?)
+        }
         "list_pending_enrollment_requests" => {
             let user_id: String = arg(&args, "userId")?;
             ok(device_enrollment::list_pending_enrollment_requests(&state()?, user_id).await?)
@@ -405,6 +416,22 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             user::save_preferences(user_id, preferences_json, &state()?).await?;
             ok(())
         }
+        "get_inactivity_policy" => {
+            let user_id: String = arg(&args, "userId")?;
+            ok(user::get_inactivity_policy(user_id, &state()?).await?)
+        }
+        "set_inactivity_policy" => {
+            let user_id: String = arg(&args, "userId")?;
+            let inactive_days: Option<u32> = arg_opt(&args, "inactiveDays")?;
+            let delete_recovery: bool = arg(&args, "deleteRecovery")?;
+            user::set_inactivity_policy(user_id, inactive_days, delete_recovery, &state()?).await?;
+            ok(())
+        }
+        "get_my_usage" => {
+            let user_id: String = arg(&args, "userId")?;
+            let days: Option<u32> = arg_opt(&args, "days")?;
+            ok(user::get_my_usage(user_id, days, &state()?).await?)
+        }
 
         // ----- groups -----
         "list_user_groups" => {
@@ -427,12 +454,14 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
                 arg_opt(&args, "createDefaultTextChannel")?;
             let create_default_voice_channel: Option<bool> =
                 arg_opt(&args, "createDefaultVoiceChannel")?;
+            let slug: Option<String> = arg_opt(&args, "slug")?;
             ok(groups::create_group(
                 name,
                 description,
                 owner_id,
                 create_default_text_channel,
                 create_default_voice_channel,
+                slug,
                 &state()?,
             )
             .await?)
@@ -459,6 +488,50 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             groups::delete_group(group_id, requester_id, &state()?).await?;
             ok(())
         }
+        "cancel_group_deletion" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let requester_id: String = arg(&args, "requesterId")?;
+            groups::cancel_group_deletion(group_id, requester_id, &state()?).await?;
+            ok(())
+        }
+        "forget_group_history" => {
+            let group_id: String = arg(&args, "groupId")?;
+            ok(groups::forget_group_history(group_id, &state()?).await?)
+        }
+        "set_group_export_policy" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let requester_id: String = arg(&args, "requesterId")?;
+            let allow_export: bool = arg(&args, "allowExport")?;
+            groups::set_group_export_policy(group_id, requester_id, allow_export, &state()?).await?;
+            ok(())
+        }
+        "list_storage_targets" => ok(groups::list_storage_targets(&state()?).await?),
+        "set_group_storage_target" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let requester_id: String = arg(&args, "requesterId")?;
+            let storage_target: Option<String> = arg_opt(&args, "storageTarget")?;
+            groups::set_group_storage_target(group_id, requester_id, storage_target, &state()?)
+                .await?;
+            ok(())
+        }
+        "list_group_webhooks" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let requester_id: String = arg(&args, "requesterId")?;
+            ok(groups::list_group_webhooks(group_id, requester_id, &state()?).await?)
+        }
+        "create_group_webhook" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let requester_id: String = arg(&args, "requesterId")?;
+            let url: String = arg(&args, "url")?;
+            let events: Vec<String> = arg(&args, "events")?;
+            ok(groups::create_group_webhook(group_id, requester_id, url, events, &state()?).await?)
+        }
+        "delete_group_webhook" => {
+            let webhook_id: String = arg(&args, "webhookId")?;
+            let requester_id: String = arg(&args, "requesterId")?;
+            groups::delete_group_webhook(webhook_id, requester_id, &state()?).await?;
+            ok(())
+        }
         "update_channel" => {
             let channel_id: String = arg(&args, "channelId")?;
             let requester_id: String = arg(&args, "requesterId")?;
@@ -473,6 +546,13 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             )
             .await?)
         }
+        "set_channel_retention" => {
+            let channel_id: String = arg(&args, "channelId")?;
+            let requester_id: String = arg(&args, "requesterId")?;
+            let days: i64 = arg(&args, "days")?;
+            groups::set_channel_retention(channel_id, requester_id, days, &state()?).await?;
+            ok(())
+        }
         "delete_channel" => {
             let channel_id: String = arg(&args, "channelId")?;
             let requester_id: String = arg(&args, "requesterId")?;
@@ -490,6 +570,24 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             let slug: String = arg(&args, "slug")?;
             ok(groups::search_group_by_slug(slug, &state()?).await?)
         }
+        "normalize_slug" => {
+            let input: String = arg(&args, "input")?;
+            ok(groups::normalize_slug(input, &state()?).await?)
+        }
+        "reserve_group_slug" => {
+            let slug: String = arg(&args, "slug")?;
+            let user_id: String = arg(&args, "userId")?;
+            ok(groups::reserve_group_slug(slug, user_id, &state()?).await?)
+        }
+        "get_group_join_code" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let user_id: String = arg(&args, "userId")?;
+            ok(groups::get_group_join_code(group_id, user_id, &state()?).await?)
+        }
+        "resolve_group_join_code" => {
+            let payload: String = arg(&args, "payload")?;
+            ok(groups::resolve_group_join_code(payload, &state()?).await?)
+        }
         "request_group_access" => {
             let group_id: String = arg(&args, "groupId")?;
             let requester_id: String = arg(&args, "requesterId")?;
@@ -526,14 +624,47 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             groups::set_member_role(group_id, user_id, role, requester_id, &state()?).await?;
             ok(())
         }
+        "transfer_group_ownership" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let requester_id: String = arg(&args, "requesterId")?;
+            let new_owner_id: String = arg(&args, "newOwnerId")?;
+            groups::transfer_group_ownership(group_id, requester_id, new_owner_id, &state()?).await?;
+            ok(())
+        }
+        "get_pending_ownership_transfers" => {
+            let user_id: String = arg(&args, "userId")?;
+            ok(groups::get_pending_ownership_transfers(user_id, &state()?).await?)
+        }
+        "accept_group_ownership" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let user_id: String = arg(&args, "userId")?;
+            groups::accept_group_ownership(group_id, user_id, &state()?).await?;
+            ok(())
+        }
+        "decline_group_ownership" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let user_id: String = arg(&args, "userId")?;
+            groups::decline_group_ownership(group_id, user_id, &state()?).await?;
+            ok(())
+        }
+        "export_group_structure" => {
+            let group_id: String = arg(&args, "groupId")?;
+            let requester_id: String = arg(&args, "requesterId")?;
+            ok(groups::export_group_structure(group_id, requester_id, &state()?).await?)
+        }
         "get_group_members" => {
             let group_id: String = arg(&args, "groupId")?;
             ok(groups::get_group_members(group_id, &state()?).await?)
         }
+        "get_group_events" => {
+            let group_id: String = arg(&args, "groupId")?;
+            ok(groups::get_group_events(group_id, &state()?).await?)
+        }
         "leave_group" => {
             let group_id: String = arg(&args, "groupId")?;
             let user_id: String = arg(&args, "userId")?;
-            groups::leave_group(group_id, user_id, &state()?).await?;
+            let delete_history: Option<bool> = arg_opt(&args, "deleteHistory")?;
+            groups::leave_group(group_id, user_id, delete_history, &state()?).await?;
             ok(())
         }
         "send_group_invite" => {
@@ -549,6 +680,11 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             .await?;
             ok(())
         }
+        "redeem_email_invite" => {
+            let token: String = arg(&args, "token")?;
+            let user_id: String = arg(&args, "userId")?;
+            ok(groups::redeem_email_invite(token, user_id, &state()?).await?)
+        }
         "get_pending_invites" => {
             let user_id: String = arg(&args, "userId")?;
             ok(groups::get_pending_invites(user_id, &state()?).await?)
@@ -615,6 +751,69 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             )
             .await?)
         }
+        "broadcast_announcement" => {
+            let sender_id: String = arg(&args, "senderId")?;
+            let channel_ids: Vec<String> = arg(&args, "channelIds")?;
+            let content: String = arg(&args, "content")?;
+            let sender_username: Option<String> = arg_opt(&args, "senderUsername")?;
+            ok(messages::broadcast_announcement(
+                sender_id,
+                channel_ids,
+                content,
+                sender_username,
+                &state()?,
+            )
+            .await?)
+        }
+        "export_channel_messages" => {
+            let user_id: String = arg(&args, "userId")?;
+            let channel_id: String = arg(&args, "channelId")?;
+            let format: String = arg(&args, "format")?;
+            let from: Option<String> = arg_opt(&args, "from")?;
+            let to: Option<String> = arg_opt(&args, "to")?;
+            ok(messages::export_channel_messages(user_id, channel_id, format, from, to, &state()?)
+                .await?)
+        }
+        "export_group_attachments" => {
+            let user_id: String = arg(&args, "userId")?;
+            let group_id: String = arg(&args, "groupId")?;
+            let dest_path: String = arg(&args, "destPath")?;
+            ok(messages::export_group_attachments(user_id, group_id, dest_path, &state()?).await?)
+        }
+        "pause_attachment_export" => {
+            messages::pause_attachment_export();
+            ok(())
+        }
+        "get_channel_activity" => {
+            let channel_id: String = arg(&args, "channelId")?;
+            let range: messages::ActivityRange = arg(&args, "range")?;
+            ok(messages::get_channel_activity(channel_id, range, &state()?).await?)
+        }
+        "get_most_active_channels" => {
+            let range: messages::ActivityRange = arg(&args, "range")?;
+            let limit: Option<i64> = arg_opt(&args, "limit")?;
+            ok(messages::get_most_active_channels(range, limit, &state()?).await?)
+        }
+        "translate_message" => {
+            let message_id: String = arg(&args, "messageId")?;
+            let target_lang: Option<String> = arg_opt(&args, "targetLang")?;
+            ok(messages::translate_message(message_id, target_lang, &state()?).await?)
+        }
+        "get_translation_backend" => ok(messages::get_translation_backend(&state()?).await?),
+        "set_translation_backend" => {
+            let path: Option<String> = arg_opt(&args, "path")?;
+            ok(messages::set_translation_backend(path, &state()?).await?)
+        }
+        "get_conversation_translation_language" => {
+            let conversation_id: String = arg(&args, "conversationId")?;
+            ok(messages::get_conversation_translation_language(conversation_id, &state()?).await?)
+        }
+        "set_conversation_translation_language" => {
+            let conversation_id: String = arg(&args, "conversationId")?;
+            let target_lang: Option<String> = arg_opt(&args, "targetLang")?;
+            ok(messages::set_conversation_translation_language(conversation_id, target_lang, &state()?)
+                .await?)
+        }
         "get_channel_messages" => {
             let user_id: String = arg(&args, "userId")?;
             let channel_id: String = arg(&args, "channelId")?;
@@ -638,8 +837,7 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
         "ingest_channel_envelopes" => {
             let user_id: String = arg(&args, "userId")?;
             let channel_id: String = arg(&args, "channelId")?;
-            messages::ingest_channel_envelopes(user_id, channel_id, &state()?).await?;
-            ok(())
+            ok(messages::ingest_channel_envelopes(user_id, channel_id, &state()?).await?)
         }
         "add_reaction" => {
             let message_id: String = arg(&args, "messageId")?;
@@ -659,6 +857,24 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             let message_id: String = arg(&args, "messageId")?;
             ok(messages::get_reactions(message_id, &state()?).await?)
         }
+        "pin_message" => {
+            let conversation_id: String = arg(&args, "conversationId")?;
+            let message_id: String = arg(&args, "messageId")?;
+            let user_id: String = arg(&args, "userId")?;
+            messages::pin_message(conversation_id, message_id, user_id, &state()?).await?;
+            ok(())
+        }
+        "unpin_message" => {
+            let conversation_id: String = arg(&args, "conversationId")?;
+            let message_id: String = arg(&args, "messageId")?;
+            let user_id: String = arg(&args, "userId")?;
+            messages::unpin_message(conversation_id, message_id, user_id, &state()?).await?;
+            ok(())
+        }
+        "get_pinned_messages" => {
+            let conversation_id: String = arg(&args, "conversationId")?;
+            ok(messages::get_pinned_messages(conversation_id, &state()?).await?)
+        }
         "edit_message" => {
             let conversation_id: String = arg(&args, "conversationId")?;
             let message_id: String = arg(&args, "messageId")?;
@@ -683,8 +899,7 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
         "ingest_dm_envelopes" => {
             let user_id: String = arg(&args, "userId")?;
             let dm_channel_id: String = arg(&args, "dmChannelId")?;
-            messages::ingest_dm_envelopes(user_id, dm_channel_id, &state()?).await?;
-            ok(())
+            ok(messages::ingest_dm_envelopes(user_id, dm_channel_id, &state()?).await?)
         }
 
         // ----- dm -----
@@ -717,6 +932,69 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             ok(blocks::list_blocked_users(user_id, &state()?).await?)
         }
 
+        // ----- contacts (local nicknames / notes) -----
+        "set_contact_nickname" => {
+            let user_id: String = arg(&args, "userId")?;
+            let nickname: Option<String> = arg_opt(&args, "nickname")?;
+            contacts::set_contact_nickname(user_id, nickname, &state()?).await?;
+            ok(())
+        }
+        "set_contact_notes" => {
+            let user_id: String = arg(&args, "userId")?;
+            let notes: Option<String> = arg_opt(&args, "notes")?;
+            contacts::set_contact_notes(user_id, notes, &state()?).await?;
+            ok(())
+        }
+        "get_contact_note" => {
+            let user_id: String = arg(&args, "userId")?;
+            ok(contacts::get_contact_note(user_id, &state()?).await?)
+        }
+        "list_contact_notes" => ok(contacts::list_contact_notes(&state()?).await?),
+
+        // ----- sidebar (pins / custom order, synced via preferences) -----
+        "get_sidebar_order" => ok(sidebar::get_sidebar_order(&state()?).await?),
+        "pin_conversation" => {
+            let user_id: String = arg(&args, "userId")?;
+            let target_id: String = arg(&args, "targetId")?;
+            let pinned: bool = arg(&args, "pinned")?;
+            ok(sidebar::pin_conversation(user_id, target_id, pinned, &state()?).await?)
+        }
+        "set_sidebar_order" => {
+            let user_id: String = arg(&args, "userId")?;
+            let ids: Vec<String> = arg(&args, "ids")?;
+            ok(sidebar::set_sidebar_order(user_id, ids, &state()?).await?)
+        }
+
+        // ----- reminders (synced via preferences) -----
+        "list_reminders" => {
+            let user_id: String = arg(&args, "userId")?;
+            ok(reminders::list_reminders(user_id, &state()?).await?)
+        }
+        "create_reminder" => {
+            let user_id: String = arg(&args, "userId")?;
+            let message_id: String = arg(&args, "messageId")?;
+            let at: i64 = arg(&args, "at")?;
+            ok(reminders::create_reminder(user_id, message_id, at, &state()?).await?)
+        }
+        "delete_reminder" => {
+            let user_id: String = arg(&args, "userId")?;
+            let reminder_id: String = arg(&args, "reminderId")?;
+            ok(reminders::delete_reminder(user_id, reminder_id, &state()?).await?)
+        }
+
+        // ----- links -----
+        "check_link" => {
+            let url: String = arg(&args, "url")?;
+            ok(links::check_link(url, &state()?).await?)
+        }
+        "check_links" => {
+            let urls: Vec<String> = arg(&args, "urls")?;
+            ok(links::check_links(urls, &state()?).await?)
+        }
+
+        // ----- maintenance -----
+        "get_maintenance_status" => ok(maintenance::get_maintenance_status(&state()?).await?),
+
         // ----- safety -----
         "get_safety_number" => {
             let my_user_id: String = arg(&args, "myUserId")?;
@@ -729,6 +1007,10 @@ async fn invoke_inner(cmd: String, args_json: String) -> Result<String, BridgeEr
             safety::set_contact_verified(peer_user_id, verified, &state()?).await?;
             ok(())
         }
+        "get_identity_fingerprint" => {
+            let user_id: String = arg(&args, "userId")?;
+            ok(safety::get_identity_fingerprint(user_id, &state()?).await?)
+        }
         "list_peer_verifications" => ok(safety::list_peer_verifications(&state()?).await?),
 
         "list_dm_requests" => {
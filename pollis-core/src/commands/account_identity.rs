@@ -463,6 +463,40 @@ pub async fn wipe_local_account_identity(state: &AppState, user_id: &str) -> Res
 ///
 /// Returns the new formatted Secret Key to show the user once.
 pub async fn reset_identity(state: &Arc<AppState>, user_id: &str) -> Result<String> {
+    replace_account_identity(state, user_id, None).await
+}
+
+/// Planned rotation: like [`reset_identity`], but made on a signed-in device
+/// that still holds the current account key, so the old key signs the new one
+/// ([`rotation_signed_payload`]). The DS stores that signature in
+/// `account_key_log.rotation_sig`, and peers that pinned an earlier version
+/// follow the chain to the new key and re-pin without clearing `verified`
+/// (`safety::check_and_pin_account_key`). A reset can't sign — its old key is
+/// gone — so it still shows up to peers as a key change.
+///
+/// Takes the PIN: it is checked (counting toward the lockout) before anything
+/// is written, and the new key is wrapped under it in place of the old one.
+/// The local DB key is kept, so history stays readable. The old private key is
+/// not kept: device certs are re-signed under the new key below, and the old
+/// public key stays in `account_key_log`.
+///
+/// Other devices of the account are in the same position as after a reset:
+/// their locally held key no longer matches, so they re-enroll or recover with
+/// the new Secret Key.
+///
+/// Records `kind = 'identity_rotated'`. Returns the new Secret Key.
+pub async fn rotate_identity(state: &Arc<AppState>, user_id: &str, pin: &str) -> Result<String> {
+    let current = crate::commands::pin::verify_pin(state, user_id, pin).await?;
+    replace_account_identity(state, user_id, Some((&current, pin))).await
+}
+
+/// Shared body of [`reset_identity`] and [`rotate_identity`]. `previous` is
+/// the unlocked state being replaced and its PIN, when the old key is held.
+async fn replace_account_identity(
+    state: &Arc<AppState>,
+    user_id: &str,
+    previous: Option<(&UnlockState, &str)>,
+) -> Result<String> {
     let mut rng = OsRng;
 
     // 1. Generate a new Ed25519 keypair.
@@ -492,15 +526,15 @@ pub async fn reset_identity(state: &Arc<AppState>, user_id: &str) -> Result<Stri
     //    the sole writer — which performs the conditional append in ONE
     //    transaction (`pollis_delivery::account::apply_rotate_identity`).
     let conn = state.remote_db.conn().await?;
-    let based_on_version: i64 = {
+    let (based_on_version, current_pub): (i64, Option<Vec<u8>>) = {
         let mut rows = conn
             .query(
-                "SELECT identity_version FROM users WHERE id = ?1",
+                "SELECT identity_version, account_id_pub FROM users WHERE id = ?1",
                 libsql::params![user_id.to_string()],
             )
             .await?;
         match rows.next().await? {
-            Some(row) => row.get(0)?,
+            Some(row) => (row.get(0)?, row.get::<Option<Vec<u8>>>(1).ok().flatten()),
             None => {
                 return Err(Error::Other(anyhow::anyhow!(
                     "user {user_id} not found during reset_identity"
@@ -509,16 +543,61 @@ pub async fn reset_identity(state: &Arc<AppState>, user_id: &str) -> Result<Stri
         }
     };
 
+    // The synced preferences are sealed under a key derived from the account
+    // key (`user::preferences_key`). Open them while the old key is still
+    // held so they can be re-sealed under the new one once it's installed;
+    // otherwise every device would read an unopenable row and the next save
+    // would overwrite it. A reset has no old key, so its row stays unreadable.
+    let carried_preferences = match previous {
+        Some((current, _)) => {
+            match crate::commands::user::open_remote_preferences_with(
+                state,
+                user_id,
+                &current.account_id_key,
+            )
+            .await
+            {
+                Ok(prefs) => prefs,
+                Err(e) => {
+                    eprintln!("[rotate] open synced preferences with the old key: {e}");
+                    None
+                }
+            }
+        }
+        None => None,
+    };
+
+    // A planned rotation signs the new key with the old one. The held key must
+    // be the published one, or the DS (and every peer) would reject the chain.
+    let rotation_sig = match previous {
+        Some((current, _)) => {
+            let old = signing_key_from_bytes(&current.account_id_key)?;
+            let old_pub = old.verifying_key().to_bytes();
+            if current_pub.as_deref() != Some(&old_pub[..]) {
+                return Err(Error::Crypto(
+                    "held account key is not the published one; cannot sign the rotation".into(),
+                ));
+            }
+            let payload =
+                rotation_signed_payload(user_id, based_on_version + 1, &old_pub, &public_bytes)?;
+            Some(old.sign(&payload).to_bytes())
+        }
+        None => None,
+    };
+
     let new_version: i64 = {
         use base64::Engine as _;
         let b64 = base64::engine::general_purpose::STANDARD;
-        let body = serde_json::json!({
+        let mut body = serde_json::json!({
             "based_on_version": based_on_version,
             "account_id_pub": b64.encode(public_bytes),
             "salt": b64.encode(salt),
             "nonce": b64.encode(nonce),
             "wrapped_key": b64.encode(&wrapped),
         });
+        if let Some(sig) = rotation_sig {
+            body["rotation_sig"] = serde_json::Value::String(b64.encode(sig));
+        }
         let resp =
             crate::commands::mls::ds_post_signed_or_session(state, "/v1/account/rotate-identity", &body)
                 .await?;
@@ -545,8 +624,38 @@ pub async fn reset_identity(state: &Arc<AppState>, user_id: &str) -> Result<Stri
 
     // 4. Install the new private key in AppState.unlock so the calling
     //    device is enrolled under the new identity. The bytes never
-    //    touch the keystore unwrapped — set_pin will wrap them.
-    *state.unlock.lock().await = Some(unlock_state_with_fresh_db_key(user_id, &private_bytes));
+    //    touch the keystore unwrapped — after a reset, set_pin wraps
+    //    them; a rotation keeps the open DB's key and rewraps under the
+    //    PIN it was given. A failed rewrap doesn't undo the published
+    //    rotation: the Secret Key returned below recovers the new key.
+    match previous {
+        Some((current, pin)) => {
+            *state.unlock.lock().await = Some(UnlockState {
+                user_id: user_id.to_string(),
+                db_key: current.db_key.clone(),
+                account_id_key: Zeroizing::new(private_bytes.to_vec()),
+            });
+            if let Err(e) =
+                crate::commands::pin::rewrap_account_id_key(state, user_id, pin, &private_bytes)
+                    .await
+            {
+                eprintln!("[rotate] rewrap of the new account key failed: {e}");
+            }
+        }
+        None => {
+            *state.unlock.lock().await =
+                Some(unlock_state_with_fresh_db_key(user_id, &private_bytes));
+        }
+    }
+
+    // 4b. Re-seal the synced preferences under the new key. Best-effort: on
+    //     failure the local cache still holds them and the next save seals
+    //     them again.
+    if let Some(prefs) = carried_preferences {
+        if let Err(e) = crate::commands::user::reseal_preferences(state, user_id, &prefs).await {
+            eprintln!("[rotate] re-seal synced preferences (non-fatal): {e}");
+        }
+    }
 
     // 5. Re-sign every existing `user_device` row for this user against
     //    the freshly rotated account identity. Without this, every
@@ -565,8 +674,13 @@ pub async fn reset_identity(state: &Arc<AppState>, user_id: &str) -> Result<Stri
     // 6. Record the reset in the security log. Best-effort only. Routed through
     //    the DS (sole writer; #419 domains E+G).
     let metadata = format!("new_identity_version={new_version}");
+    let kind = if previous.is_some() {
+        "identity_rotated"
+    } else {
+        "identity_reset"
+    };
     let body = serde_json::json!({
-        "kind": "identity_reset",
+        "kind": kind,
         "device_id": serde_json::Value::Null,
         "metadata": metadata,
     });
@@ -631,6 +745,85 @@ fn signing_key_from_bytes(bytes: &[u8]) -> Result<SigningKey> {
     Ok(SigningKey::from_bytes(&arr))
 }
 
+// ── Rotation chain ───────────────────────────────────────────────────────────
+
+/// Domain separator for a rotation signature. `pollis-delivery`'s
+/// `account::rotation_signed_payload` is a verbatim port — bump both together.
+pub const ROTATION_DOMAIN: &[u8] = b"pollis-account-key-rotation-v1\x00";
+
+/// The byte string the outgoing account key signs when it hands over to the
+/// next one:
+///
+/// ```text
+/// ROTATION_DOMAIN          (31 bytes, trailing NUL included)
+/// u8  user_id_len          || user_id bytes
+/// u32 new_identity_version (big-endian)
+/// old account_id_pub       (32 bytes)
+/// new account_id_pub       (32 bytes)
+/// ```
+pub fn rotation_signed_payload(
+    user_id: &str,
+    new_version: i64,
+    old_pub: &[u8],
+    new_pub: &[u8],
+) -> Result<Vec<u8>> {
+    if user_id.len() > u8::MAX as usize || old_pub.len() != 32 || new_pub.len() != 32 {
+        return Err(Error::Crypto("malformed rotation statement".into()));
+    }
+    let version = u32::try_from(new_version)
+        .map_err(|_| Error::Crypto(format!("identity_version out of range: {new_version}")))?;
+    let mut out = Vec::with_capacity(ROTATION_DOMAIN.len() + 1 + user_id.len() + 4 + 64);
+    out.extend_from_slice(ROTATION_DOMAIN);
+    out.push(user_id.len() as u8);
+    out.extend_from_slice(user_id.as_bytes());
+    out.extend_from_slice(&version.to_be_bytes());
+    out.extend_from_slice(old_pub);
+    out.extend_from_slice(new_pub);
+    Ok(out)
+}
+
+/// Walk `steps` — `(identity_version, account_id_pub, rotation_sig)` rows of
+/// `account_key_log` above the pinned version, in version order — from the
+/// pinned key to `current_pub`. True only if every step is the next version,
+/// is signed by the key before it, and the walk ends on `current_pub`. Any
+/// unsigned step (a soft reset) or gap breaks the chain.
+pub fn verify_rotation_chain(
+    user_id: &str,
+    pinned_pub: &[u8],
+    pinned_version: i64,
+    steps: &[(i64, Vec<u8>, Option<Vec<u8>>)],
+    current_pub: &[u8],
+) -> bool {
+    let mut prev_pub = pinned_pub.to_vec();
+    let mut prev_version = pinned_version;
+    for (version, new_pub, sig) in steps {
+        if *version != prev_version + 1 {
+            return false;
+        }
+        let Some(sig) = sig else {
+            return false;
+        };
+        let Ok(payload) = rotation_signed_payload(user_id, *version, &prev_pub, new_pub) else {
+            return false;
+        };
+        let Ok(pk) = <[u8; 32]>::try_from(prev_pub.as_slice()) else {
+            return false;
+        };
+        let Ok(vk) = VerifyingKey::from_bytes(&pk) else {
+            return false;
+        };
+        let Ok(sig) = Signature::from_slice(sig) else {
+            return false;
+        };
+        if vk.verify_strict(&payload, &sig).is_err() {
+            return false;
+        }
+        prev_pub = new_pub.clone();
+        prev_version = *version;
+    }
+    !steps.is_empty() && prev_pub == current_pub
+}
+
 // ── Device certificate ───────────────────────────────────────────────────────
 
 // The device-cert PAYLOAD FORMAT and its VERIFICATION live in the standalone
@@ -916,6 +1109,40 @@ mod tests {
         assert!(aes_gcm_decrypt(&wrap2, &nonce, &ct).is_err());
     }
 
+    /// Two signed rotations chain from the pinned key to the current one; a
+    /// gap, a wrong signer or an unsigned (reset) step does not.
+    #[test]
+    fn rotation_chain_follows_signed_steps_only() {
+        let k1 = SigningKey::from_bytes(&[1u8; 32]);
+        let k2 = SigningKey::from_bytes(&[2u8; 32]);
+        let k3 = SigningKey::from_bytes(&[3u8; 32]);
+        let (p1, p2, p3) = (
+            k1.verifying_key().to_bytes().to_vec(),
+            k2.verifying_key().to_bytes().to_vec(),
+            k3.verifying_key().to_bytes().to_vec(),
+        );
+        let sign = |by: &SigningKey, v: i64, old: &[u8], new: &[u8]| {
+            let payload = rotation_signed_payload("u1", v, old, new).unwrap();
+            Some(by.sign(&payload).to_bytes().to_vec())
+        };
+        let s2 = sign(&k1, 2, &p1, &p2);
+        let s3 = sign(&k2, 3, &p2, &p3);
+
+        let chain = vec![(2, p2.clone(), s2.clone()), (3, p3.clone(), s3.clone())];
+        assert!(verify_rotation_chain("u1", &p1, 1, &chain, &p3));
+        // Bound to the user and to the pinned version.
+        assert!(!verify_rotation_chain("u2", &p1, 1, &chain, &p3));
+        assert!(!verify_rotation_chain("u1", &p1, 0, &chain, &p3));
+        // Must end on the server's current key.
+        assert!(!verify_rotation_chain("u1", &p1, 1, &chain[..1], &p3));
+        // A step the previous key didn't sign, or an unsigned reset, breaks it.
+        let forged = vec![(2, p2.clone(), sign(&k3, 2, &p1, &p2)), (3, p3.clone(), s3.clone())];
+        assert!(!verify_rotation_chain("u1", &p1, 1, &forged, &p3));
+        let reset = vec![(2, p2.clone(), None), (3, p3.clone(), s3)];
+        assert!(!verify_rotation_chain("u1", &p1, 1, &reset, &p3));
+        assert!(!verify_rotation_chain("u1", &p1, 1, &[], &p1));
+    }
+
     /// Schema-level tests for the account_key_log history table (migration
     /// 000005) and the dual-write contract. Like the rest of pollis-core's
     /// unit tests, these drive an in-memory rusqlite DB and exercise the exact
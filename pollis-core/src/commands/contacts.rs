@@ -0,0 +1,185 @@
+//! Local-only contact nicknames and notes.
+//!
+//! A nickname and a freeform note per user, kept in the `contact_note` table
+//! of this user's local DB — encrypted at rest with the rest of it (SQLCipher)
+//! and never sent anywhere, so the person being nicknamed can't see it and the
+//! user's other devices don't either.
+//!
+//! Listings resolve nicknames on the way out: `get_group_members` fills
+//! `GroupMember::display_name`, and the DM listings fill
+//! `DmChannelMember::nickname`. Both are `None` before the local DB is open.
+
+use std::collections::HashMap;
+use std::sync::Arc;
+
+use rusqlite::OptionalExtension;
+use serde::{Deserialize, Serialize};
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+/// Longest nickname accepted, in characters.
+pub const MAX_NICKNAME_CHARS: usize = 64;
+/// Longest note accepted, in characters.
+pub const MAX_NOTES_CHARS: usize = 4000;
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ContactNote {
+    pub user_id: String,
+    pub nickname: Option<String>,
+    pub notes: Option<String>,
+    pub updated_at: String,
+}
+
+/// Trim, turn blank into `None`, and enforce `max` characters.
+fn clean(value: Option<String>, max: usize, what: &str) -> Result<Option<String>> {
+    let Some(value) = value.map(|v| v.trim().to_string()).filter(|v| !v.is_empty()) else {
+        return Ok(None);
+    };
+    if value.chars().count() > max {
+        return Err(Error::Other(anyhow::anyhow!("{what} is longer than {max} characters")));
+    }
+    Ok(Some(value))
+}
+
+/// Upsert one column, then drop the row once both fields are empty.
+fn write_field(
+    conn: &rusqlite::Connection,
+    user_id: &str,
+    column: &str,
+    value: Option<&str>,
+) -> Result<()> {
+    conn.execute(
+        &format!(
+            "INSERT INTO contact_note (user_id, {column}, updated_at) VALUES (?1, ?2, datetime('now')) \
+             ON CONFLICT(user_id) DO UPDATE SET {column} = ?2, updated_at = datetime('now')"
+        ),
+        rusqlite::params![user_id, value],
+    )?;
+    conn.execute(
+        "DELETE FROM contact_note WHERE user_id = ?1 AND nickname IS NULL AND notes IS NULL",
+        rusqlite::params![user_id],
+    )?;
+    Ok(())
+}
+
+/// Every nickname on this device, keyed by user id.
+pub(crate) fn nicknames(conn: &rusqlite::Connection) -> Result<HashMap<String, String>> {
+    let mut stmt =
+        conn.prepare("SELECT user_id, nickname FROM contact_note WHERE nickname IS NOT NULL")?;
+    let rows = stmt.query_map([], |r| Ok((r.get::<_, String>(0)?, r.get::<_, String>(1)?)))?;
+    Ok(rows.collect::<rusqlite::Result<_>>()?)
+}
+
+/// [`nicknames`] for a listing command: empty instead of an error when the
+/// local DB isn't open, so listings keep working before unlock.
+pub(crate) async fn load_nicknames(state: &Arc<AppState>) -> HashMap<String, String> {
+    let guard = state.local_db.lock().await;
+    match guard.as_ref() {
+        Some(db) => nicknames(db.conn()).unwrap_or_default(),
+        None => HashMap::new(),
+    }
+}
+
+/// Set (or with `None` / blank, clear) the nickname for `user_id`.
+pub async fn set_contact_nickname(
+    user_id: String,
+    nickname: Option<String>,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let nickname = clean(nickname, MAX_NICKNAME_CHARS, "nickname")?;
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    write_field(db.conn(), &user_id, "nickname", nickname.as_deref())
+}
+
+/// Set (or with `None` / blank, clear) the note for `user_id`.
+pub async fn set_contact_notes(
+    user_id: String,
+    notes: Option<String>,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let notes = clean(notes, MAX_NOTES_CHARS, "note")?;
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    write_field(db.conn(), &user_id, "notes", notes.as_deref())
+}
+
+/// The nickname and note for `user_id`, if either is set.
+pub async fn get_contact_note(
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<Option<ContactNote>> {
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    Ok(db
+        .conn()
+        .query_row(
+            "SELECT user_id, nickname, notes, updated_at FROM contact_note WHERE user_id = ?1",
+            rusqlite::params![user_id],
+            |r| {
+                Ok(ContactNote {
+                    user_id: r.get(0)?,
+                    nickname: r.get(1)?,
+                    notes: r.get(2)?,
+                    updated_at: r.get(3)?,
+                })
+            },
+        )
+        .optional()?)
+}
+
+/// Every contact with a nickname or note, by nickname then user id.
+pub async fn list_contact_notes(state: &Arc<AppState>) -> Result<Vec<ContactNote>> {
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    let mut stmt = db.conn().prepare(
+        "SELECT user_id, nickname, notes, updated_at FROM contact_note
+         ORDER BY nickname IS NULL, nickname COLLATE NOCASE, user_id",
+    )?;
+    let rows = stmt.query_map([], |r| {
+        Ok(ContactNote {
+            user_id: r.get(0)?,
+            nickname: r.get(1)?,
+            notes: r.get(2)?,
+            updated_at: r.get(3)?,
+        })
+    })?;
+    Ok(rows.collect::<rusqlite::Result<_>>()?)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn clean_trims_blanks_and_caps_length() {
+        assert_eq!(clean(Some("  Bo  ".into()), 10, "x").unwrap().as_deref(), Some("Bo"));
+        assert_eq!(clean(Some("   ".into()), 10, "x").unwrap(), None);
+        assert_eq!(clean(None, 10, "x").unwrap(), None);
+        assert!(clean(Some("é".repeat(11)), 10, "x").is_err());
+        assert!(clean(Some("é".repeat(10)), 10, "x").is_ok());
+    }
+
+    #[test]
+    fn row_is_dropped_once_both_fields_are_cleared() {
+        let db = crate::db::local::LocalDb::open_in_memory().unwrap();
+        let conn = db.conn();
+        write_field(conn, "u1", "nickname", Some("Bo")).unwrap();
+        write_field(conn, "u1", "notes", Some("met at the meetup")).unwrap();
+        assert_eq!(nicknames(conn).unwrap().get("u1").map(String::as_str), Some("Bo"));
+
+        write_field(conn, "u1", "nickname", None).unwrap();
+        assert!(nicknames(conn).unwrap().is_empty());
+        let count: i64 = conn
+            .query_row("SELECT COUNT(*) FROM contact_note", [], |r| r.get(0))
+            .unwrap();
+        assert_eq!(count, 1, "the note keeps the row alive");
+
+        write_field(conn, "u1", "notes", None).unwrap();
+        let count: i64 = conn
+            .query_row("SELECT COUNT(*) FROM contact_note", [], |r| r.get(0))
+            .unwrap();
+        assert_eq!(count, 0);
+    }
+}
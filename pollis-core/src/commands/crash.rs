@@ -0,0 +1,304 @@
+//! Local crash reports.
+//!
+//! [`install_panic_hook`] chains a hook onto the default one that writes each
+//! panic — thread, source location, a scrubbed panic message and a backtrace —
+//! to `crash-<unix secs>-<pid>.log` under the crash directory
+//! (`app_data_dir()/crash-reports`, plumbed in by the Tauri shim). Release
+//! builds use `panic = "abort"`, so the process can't carry on after a panic;
+//! the hook runs before the abort, and the UI offers the report on the next
+//! launch instead (`list_crash_reports` / `acknowledge_crash_reports`).
+//!
+//! Reports never leave the device on their own. `export_crash_reports` returns
+//! them as one text blob the user can save and attach to a bug report.
+//!
+//! **No plaintext content.** Panic messages are built from arbitrary values
+//! and could in principle carry message text, so the payload is scrubbed
+//! before it touches disk: quoted strings are replaced, long hex / base64-ish
+//! tokens (keys, ciphertext, ids) are replaced, and the result is truncated.
+//! Backtraces only contain symbol names and file paths.
+
+use std::path::{Path, PathBuf};
+use std::sync::OnceLock;
+use std::time::{SystemTime, UNIX_EPOCH};
+
+use serde::Serialize;
+
+use crate::error::{Error, Result};
+
+static CRASH_DIR: OnceLock<PathBuf> = OnceLock::new();
+
+/// Reports kept on disk; older ones are pruned when a new one is written.
+const MAX_REPORTS: usize = 20;
+
+/// Upper bound on the scrubbed panic message.
+const MAX_MESSAGE_CHARS: usize = 500;
+
+/// Tokens at least this long made of hex / base64 characters are redacted.
+const MAX_TOKEN_CHARS: usize = 24;
+
+/// Marker file holding the newest report id the user has already seen.
+const ACK_FILE: &str = "acknowledged";
+
+#[derive(Debug, Clone, Serialize)]
+pub struct CrashReportSummary {
+    /// File stem, e.g. `crash-1760000000-4242`.
+    pub id: String,
+    /// Unix seconds the crash was recorded.
+    pub created_at: i64,
+    /// `file:line` of the panic, when known.
+    pub location: Option<String>,
+    /// Whether the user has already dismissed the "Pollis quit unexpectedly"
+    /// notice for this report.
+    pub acknowledged: bool,
+}
+
+/// Initialise the crash report directory. Must be called once during app
+/// setup, before [`install_panic_hook`] has anything to write. Idempotent:
+/// subsequent calls are ignored.
+pub fn set_crash_dir(path: PathBuf) {
+    let _ = std::fs::create_dir_all(&path);
+    let _ = CRASH_DIR.set(path);
+}
+
+fn crash_dir() -> Result<&'static PathBuf> {
+    CRASH_DIR
+        .get()
+        .ok_or_else(|| Error::Other(anyhow::anyhow!("crash report dir not initialised")))
+}
+
+/// Chain the crash-log writer onto the current panic hook. The previous hook
+/// still runs afterwards, so panics keep printing to stderr as before.
+pub fn install_panic_hook() {
+    let previous = std::panic::take_hook();
+    std::panic::set_hook(Box::new(move |info| {
+        if let Some(dir) = CRASH_DIR.get() {
+            let payload = info
+                .payload()
+                .downcast_ref::<&str>()
+                .map(|s| s.to_string())
+                .or_else(|| info.payload().downcast_ref::<String>().cloned())
+                .unwrap_or_else(|| "<non-string panic payload>".to_string());
+            let location = info
+                .location()
+                .map(|l| format!("{}:{}", l.file(), l.line()));
+            let thread = std::thread::current()
+                .name()
+                .unwrap_or("<unnamed>")
+                .to_string();
+            let backtrace = std::backtrace::Backtrace::force_capture();
+            let report = render_report(
+                now_secs(),
+                &thread,
+                location.as_deref(),
+                &payload,
+                &backtrace.to_string(),
+            );
+            if let Err(e) = write_report(dir, &report) {
+                eprintln!("[crash] failed to write crash report: {e}");
+            }
+        }
+        previous(info);
+    }));
+}
+
+fn now_secs() -> i64 {
+    SystemTime::now()
+        .duration_since(UNIX_EPOCH)
+        .map(|d| d.as_secs() as i64)
+        .unwrap_or(0)
+}
+
+fn render_report(
+    created_at: i64,
+    thread: &str,
+    location: Option<&str>,
+    payload: &str,
+    backtrace: &str,
+) -> String {
+    format!(
+        "pollis crash report\n\
+         created_at: {created_at}\n\
+         version: {}\n\
+         os: {} {}\n\
+         thread: {thread}\n\
+         location: {}\n\
+         message: {}\n\
+         \n\
+         {backtrace}\n",
+        env!("CARGO_PKG_VERSION"),
+        std::env::consts::OS,
+        std::env::consts::ARCH,
+        location.unwrap_or("<unknown>"),
+        scrub(payload),
+    )
+}
+
+fn write_report(dir: &Path, report: &str) -> std::io::Result<()> {
+    let path = dir.join(format!("crash-{}-{}.log", now_secs(), std::process::id()));
+    std::fs::write(path, report)?;
+    prune(dir, MAX_REPORTS)
+}
+
+/// Replace anything that could be user content with placeholders: quoted
+/// strings, then long hex / base64-ish tokens. Truncated to
+/// [`MAX_MESSAGE_CHARS`].
+fn scrub(message: &str) -> String {
+    let mut out = String::with_capacity(message.len());
+    let mut chars = message.chars().peekable();
+    while let Some(c) = chars.next() {
+        if c == '"' || c == '\'' || c == '`' {
+            // Skip to the matching close quote (or the end).
+            for next in chars.by_ref() {
+                if next == c {
+                    break;
+                }
+            }
+            out.push_str("<redacted>");
+        } else {
+            out.push(c);
+        }
+    }
+
+    let out = out
+        .split(' ')
+        .map(|word| {
+            let is_token_char =
+                |ch: char| ch.is_ascii_alphanumeric() || matches!(ch, '+' | '/' | '=' | '-' | '_');
+            if word.chars().count() >= MAX_TOKEN_CHARS && word.chars().all(is_token_char) {
+                "<redacted>"
+            } else {
+                word
+            }
+        })
+        .collect::<Vec<_>>()
+        .join(" ");
+
+    if out.chars().count() > MAX_MESSAGE_CHARS {
+        let mut truncated: String = out.chars().take(MAX_MESSAGE_CHARS).collect();
+        truncated.push_str("…");
+        truncated
+    } else {
+        out
+    }
+}
+
+/// Report files in `dir`, newest first.
+fn report_files(dir: &Path) -> std::io::Result<Vec<(String, i64)>> {
+    let mut out = Vec::new();
+    for entry in std::fs::read_dir(dir)? {
+        let name = entry?.file_name().to_string_lossy().into_owned();
+        let Some(stem) = name.strip_suffix(".log") else {
+            continue;
+        };
+        let Some(created_at) = stem
+            .strip_prefix("crash-")
+            .and_then(|rest| rest.split('-').next())
+            .and_then(|secs| secs.parse::<i64>().ok())
+        else {
+            continue;
+        };
+        out.push((stem.to_string(), created_at));
+    }
+    out.sort_by(|a, b| b.1.cmp(&a.1).then_with(|| b.0.cmp(&a.0)));
+    Ok(out)
+}
+
+fn prune(dir: &Path, keep: usize) -> std::io::Result<()> {
+    for (id, _) in report_files(dir)?.into_iter().skip(keep) {
+        let _ = std::fs::remove_file(dir.join(format!("{id}.log")));
+    }
+    Ok(())
+}
+
+fn read_location(dir: &Path, id: &str) -> Option<String> {
+    let text = std::fs::read_to_string(dir.join(format!("{id}.log"))).ok()?;
+    text.lines()
+        .find_map(|line| line.strip_prefix("location: "))
+        .filter(|loc| *loc != "<unknown>")
+        .map(str::to_string)
+}
+
+/// Crash reports on this device, newest first.
+pub fn list_crash_reports() -> Result<Vec<CrashReportSummary>> {
+    let dir = crash_dir()?;
+    let acknowledged = std::fs::read_to_string(dir.join(ACK_FILE))
+        .ok()
+        .and_then(|s| s.trim().parse::<i64>().ok())
+        .unwrap_or(0);
+    let files = report_files(dir).map_err(|e| Error::Other(e.into()))?;
+    Ok(files
+        .into_iter()
+        .map(|(id, created_at)| CrashReportSummary {
+            location: read_location(dir, &id),
+            acknowledged: created_at <= acknowledged,
+            id,
+            created_at,
+        })
+        .collect())
+}
+
+/// Mark every current report as seen so the next-launch notice stops
+/// showing. Reports stay on disk for [`export_crash_reports`].
+pub fn acknowledge_crash_reports() -> Result<()> {
+    let dir = crash_dir()?;
+    let newest = report_files(dir)
+        .map_err(|e| Error::Other(e.into()))?
+        .first()
+        .map(|(_, created_at)| *created_at)
+        .unwrap_or(0);
+    std::fs::write(dir.join(ACK_FILE), newest.to_string()).map_err(|e| Error::Other(e.into()))?;
+    Ok(())
+}
+
+/// Every report on this device concatenated, newest first, for the user to
+/// save and attach to a bug report.
+pub fn export_crash_reports() -> Result<String> {
+    let dir = crash_dir()?;
+    let files = report_files(dir).map_err(|e| Error::Other(e.into()))?;
+    let mut out = String::new();
+    for (id, _) in files {
+        let Ok(text) = std::fs::read_to_string(dir.join(format!("{id}.log"))) else {
+            continue;
+        };
+        out.push_str(&format!("===== {id} =====\n"));
+        out.push_str(&text);
+        out.push('\n');
+    }
+    Ok(out)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn scrub_removes_quoted_strings_and_tokens() {
+        let msg = r#"called `Result::unwrap()` on an `Err` value: Parse("hello world") key=deadbeefdeadbeefdeadbeefdeadbeef"#;
+        let scrubbed = scrub(msg);
+        assert!(!scrubbed.contains("hello world"));
+        assert!(!scrubbed.contains("deadbeef"));
+        assert!(scrubbed.starts_with("called <redacted> on an <redacted> value: Parse(<redacted>)"));
+    }
+
+    #[test]
+    fn scrub_truncates_long_messages() {
+        let msg = "word ".repeat(500);
+        assert!(scrub(&msg).chars().count() <= MAX_MESSAGE_CHARS + 1);
+    }
+
+    #[test]
+    fn prune_keeps_the_newest_reports() {
+        let dir = std::env::temp_dir().join(format!("pollis-crash-test-{}", ulid::Ulid::new()));
+        std::fs::create_dir_all(&dir).unwrap();
+        for secs in 1..=5 {
+            std::fs::write(dir.join(format!("crash-{secs}-1.log")), "x").unwrap();
+        }
+        prune(&dir, 2).unwrap();
+        let ids: Vec<String> = report_files(&dir)
+            .unwrap()
+            .into_iter()
+            .map(|(id, _)| id)
+            .collect();
+        assert_eq!(ids, ["crash-5-1", "crash-4-1"]);
+    }
+}
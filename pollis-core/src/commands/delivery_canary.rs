@@ -0,0 +1,432 @@
+//! Delivery canaries: checking that the Delivery Service hands back what this
+//! device sent, once, unmodified.
+//!
+//! Every message envelope this device posts is a canary. Its id and a digest
+//! of its ciphertext are kept in memory ([`record_sent`]). The device later
+//! fetches its own envelope back with everyone else's (the catch-up fetch),
+//! and [`observe_fetch`] checks it:
+//!
+//!   - **modified**: it came back with a different ciphertext;
+//!   - **duplicated**: its ciphertext came back under another envelope id (a
+//!     replay). The same id coming back again is not counted: the watermark
+//!     legitimately stops short of an envelope it can't decrypt yet, so
+//!     later envelopes are fetched more than once;
+//!   - **dropped**: a fetch returned envelopes sent both before and after it,
+//!     but not it, so the server skipped it;
+//!   - **delayed**: the DS took longer than [`SLOW_ACK_MS`] to accept it.
+//!
+//! There are no extra envelopes and no timer. CLAUDE.md forbids periodic
+//! client traffic, and a canary the server could tell apart from a real
+//! message would prove nothing. The trade-off is that a device that doesn't
+//! send isn't checking. Nothing is uploaded. [`get_delivery_canary_report`]
+//! reports the counts and the latest anomalies as evidence. The alert goes to
+//! the sink registered with [`subscribe_delivery_canary_alerts`] (the banner)
+//! once the anomaly rate passes [`ALERT_RATE`] over at least
+//! [`ALERT_MIN_CHECKED`] canaries.
+
+use std::collections::{HashMap, VecDeque};
+use std::sync::{Arc, Mutex};
+use std::time::{SystemTime, UNIX_EPOCH};
+
+use serde::Serialize;
+use sha2::{Digest, Sha256};
+
+use crate::error::Result;
+use crate::sink::EventSink;
+
+/// Canaries awaiting their return; the oldest is forgotten past this.
+const PENDING_LEN: usize = 500;
+/// Returned canaries remembered for duplicate detection.
+const RETURNED_LEN: usize = 1000;
+/// Anomalies kept as evidence, newest last.
+const EVIDENCE_LEN: usize = 50;
+/// A DS write acknowledged slower than this counts as delayed.
+pub const SLOW_ACK_MS: u64 = 10_000;
+/// Anomalies per checked canary above which the user is alerted…
+pub const ALERT_RATE: f64 = 0.05;
+/// …once at least this many canaries have been checked.
+pub const ALERT_MIN_CHECKED: u64 = 20;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum CanaryAnomalyKind {
+    Dropped,
+    Duplicated,
+    Modified,
+    Delayed,
+}
+
+/// One anomaly, kept as evidence.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct CanaryAnomaly {
+    pub kind: CanaryAnomalyKind,
+    pub envelope_id: String,
+    pub conversation_id: String,
+    /// When it was detected, unix seconds.
+    pub at: i64,
+    pub detail: String,
+}
+
+/// Returned by `get_delivery_canary_report` and pushed on alert.
+#[derive(Debug, Clone, Default, PartialEq, Serialize)]
+pub struct DeliveryCanaryReport {
+    /// Canaries resolved: seen back, or found dropped.
+    pub checked: u64,
+    /// Sent and not seen back yet.
+    pub pending: usize,
+    pub dropped: u64,
+    pub duplicated: u64,
+    pub modified: u64,
+    pub delayed: u64,
+    /// Anomalies per checked canary.
+    pub anomaly_rate: f64,
+    /// Over the threshold, with an anomaly the user hasn't acknowledged.
+    pub alert: bool,
+    pub evidence: Vec<CanaryAnomaly>,
+}
+
+struct Sent {
+    conversation_id: String,
+    sent_at: String,
+    digest: [u8; 32],
+}
+
+#[derive(Default)]
+struct Store {
+    pending: HashMap<String, Sent>,
+    /// Pending ids in send order, for the [`PENDING_LEN`] cap.
+    pending_order: VecDeque<String>,
+    /// `(envelope id, digest)` of canaries seen back, oldest first.
+    returned: VecDeque<(String, [u8; 32])>,
+    checked: u64,
+    counts: HashMap<CanaryAnomalyKind, u64>,
+    evidence: VecDeque<CanaryAnomaly>,
+    /// Anomaly total when the user last acknowledged the alert.
+    acknowledged: u64,
+}
+
+static STORE: Mutex<Option<Store>> = Mutex::new(None);
+static SINK: Mutex<Option<Arc<dyn EventSink<DeliveryCanaryReport>>>> = Mutex::new(None);
+
+fn now_secs() -> i64 {
+    SystemTime::now()
+        .duration_since(UNIX_EPOCH)
+        .map(|d| d.as_secs() as i64)
+        .unwrap_or(0)
+}
+
+fn digest(ciphertext: &str) -> [u8; 32] {
+    Sha256::digest(ciphertext.as_bytes()).into()
+}
+
+/// Run `f` on the store, then push the report if it raised the alert.
+fn with_store(f: impl FnOnce(&mut Store)) {
+    let raised = {
+        let mut guard = STORE.lock().unwrap();
+        let store = guard.get_or_insert_with(Store::default);
+        let before = report_of(store).alert;
+        f(store);
+        let after = report_of(store);
+        (!before && after.alert).then_some(after)
+    };
+    if let Some(report) = raised {
+        tracing::warn!(
+            rate = report.anomaly_rate,
+            "[canary] delivery anomaly rate over threshold"
+        );
+        let sink = SINK.lock().ok().and_then(|g| g.clone());
+        if let Some(sink) = sink {
+            let _ = sink.send(report);
+        }
+    }
+}
+
+impl Store {
+    fn anomaly(
+        &mut self,
+        kind: CanaryAnomalyKind,
+        envelope_id: &str,
+        conversation_id: &str,
+        detail: String,
+    ) {
+        eprintln!("[canary] {kind:?} envelope {envelope_id} in {conversation_id}: {detail}");
+        *self.counts.entry(kind).or_default() += 1;
+        if self.evidence.len() == EVIDENCE_LEN {
+            self.evidence.pop_front();
+        }
+        self.evidence.push_back(CanaryAnomaly {
+            kind,
+            envelope_id: envelope_id.to_string(),
+            conversation_id: conversation_id.to_string(),
+            at: now_secs(),
+            detail,
+        });
+    }
+
+    fn total_anomalies(&self) -> u64 {
+        self.counts.values().sum()
+    }
+
+    fn send(
+        &mut self,
+        id: &str,
+        conversation_id: &str,
+        ciphertext: &str,
+        sent_at: &str,
+        ack_ms: u64,
+    ) {
+        if ack_ms > SLOW_ACK_MS {
+            self.anomaly(
+                CanaryAnomalyKind::Delayed,
+                id,
+                conversation_id,
+                format!("the server took {ack_ms} ms to accept it"),
+            );
+        }
+        if self.pending_order.len() == PENDING_LEN {
+            if let Some(oldest) = self.pending_order.pop_front() {
+                self.pending.remove(&oldest);
+            }
+        }
+        self.pending_order.push_back(id.to_string());
+        self.pending.insert(
+            id.to_string(),
+            Sent {
+                conversation_id: conversation_id.to_string(),
+                sent_at: sent_at.to_string(),
+                digest: digest(ciphertext),
+            },
+        );
+    }
+
+    fn resolve(&mut self, id: &str) -> Option<Sent> {
+        let sent = self.pending.remove(id)?;
+        self.pending_order.retain(|p| p != id);
+        self.checked += 1;
+        Some(sent)
+    }
+
+    fn fetch(&mut self, conversation_id: &str, envelopes: &[(&str, &str, &str)]) {
+        for &(id, ciphertext, _) in envelopes {
+            let got = digest(ciphertext);
+            if let Some(sent) = self.resolve(id) {
+                if sent.digest != got {
+                    self.anomaly(
+                        CanaryAnomalyKind::Modified,
+                        id,
+                        conversation_id,
+                        format!(
+                            "ciphertext digest {} came back as {}",
+                            hex::encode(&sent.digest[..8]),
+                            hex::encode(&got[..8])
+                        ),
+                    );
+                }
+                if self.returned.len() == RETURNED_LEN {
+                    self.returned.pop_front();
+                }
+                self.returned.push_back((id.to_string(), got));
+                continue;
+            }
+            let original = self
+                .returned
+                .iter()
+                .find(|(rid, d)| *d == got && rid.as_str() != id)
+                .map(|(rid, _)| rid.clone())
+                .or_else(|| {
+                    self.pending
+                        .iter()
+                        .find(|(_, s)| s.digest == got)
+                        .map(|(pid, _)| pid.clone())
+                });
+            if let Some(original) = original {
+                self.anomaly(
+                    CanaryAnomalyKind::Duplicated,
+                    id,
+                    conversation_id,
+                    format!("replays the ciphertext of envelope {original}"),
+                );
+            }
+        }
+
+        // A pending canary strictly inside the span this fetch returned was
+        // in its window and skipped. RFC 3339 stamps from `to_rfc3339`
+        // compare in order as strings.
+        let (Some(first), Some(last)) = (
+            envelopes.iter().map(|e| e.2).min(),
+            envelopes.iter().map(|e| e.2).max(),
+        ) else {
+            return;
+        };
+        let skipped: Vec<String> = self
+            .pending
+            .iter()
+            .filter(|(_, s)| {
+                s.conversation_id == conversation_id
+                    && s.sent_at.as_str() > first
+                    && s.sent_at.as_str() < last
+            })
+            .map(|(id, _)| id.clone())
+            .collect();
+        for id in skipped {
+            if let Some(sent) = self.resolve(&id) {
+                self.anomaly(
+                    CanaryAnomalyKind::Dropped,
+                    &id,
+                    conversation_id,
+                    format!(
+                        "sent at {} but missing between {first} and {last}",
+                        sent.sent_at
+                    ),
+                );
+            }
+        }
+    }
+}
+
+fn report_of(store: &Store) -> DeliveryCanaryReport {
+    let count = |k| store.counts.get(&k).copied().unwrap_or(0);
+    let total = store.total_anomalies();
+    let anomaly_rate = if store.checked == 0 {
+        0.0
+    } else {
+        total as f64 / store.checked as f64
+    };
+    DeliveryCanaryReport {
+        checked: store.checked,
+        pending: store.pending.len(),
+        dropped: count(CanaryAnomalyKind::Dropped),
+        duplicated: count(CanaryAnomalyKind::Duplicated),
+        modified: count(CanaryAnomalyKind::Modified),
+        delayed: count(CanaryAnomalyKind::Delayed),
+        anomaly_rate,
+        alert: store.checked >= ALERT_MIN_CHECKED
+            && anomaly_rate > ALERT_RATE
+            && total > store.acknowledged,
+        evidence: store.evidence.iter().cloned().collect(),
+    }
+}
+
+/// Remember an envelope this device just posted. `ciphertext` is the
+/// envelope's `ciphertext` column as sent; `ack_ms` is how long the DS took.
+pub(crate) fn record_sent(
+    id: &str,
+    conversation_id: &str,
+    ciphertext: &str,
+    sent_at: &str,
+    ack_ms: u64,
+) {
+    with_store(|s| s.send(id, conversation_id, ciphertext, sent_at, ack_ms));
+}
+
+/// Check one conversation's fetched envelopes, `(id, ciphertext, sent_at)`,
+/// against the canaries still out.
+pub(crate) fn observe_fetch(conversation_id: &str, envelopes: &[(&str, &str, &str)]) {
+    if envelopes.is_empty() {
+        return;
+    }
+    with_store(|s| s.fetch(conversation_id, envelopes));
+}
+
+/// Route alerts to `sink`, replacing any previous subscriber. A raised alert
+/// is sent straight away.
+pub fn subscribe_delivery_canary_alerts(sink: Arc<dyn EventSink<DeliveryCanaryReport>>) {
+    let current = STORE
+        .lock()
+        .unwrap()
+        .as_ref()
+        .map(report_of)
+        .unwrap_or_default();
+    if current.alert {
+        let _ = sink.send(current);
+    }
+    if let Ok(mut guard) = SINK.lock() {
+        *guard = Some(sink);
+    }
+}
+
+/// Counts and evidence for this session.
+pub async fn get_delivery_canary_report() -> Result<DeliveryCanaryReport> {
+    Ok(STORE
+        .lock()
+        .unwrap()
+        .as_ref()
+        .map(report_of)
+        .unwrap_or_default())
+}
+
+/// Dismiss the alert until another anomaly arrives while over the threshold.
+pub async fn acknowledge_delivery_canary_alert() -> Result<DeliveryCanaryReport> {
+    let mut guard = STORE.lock().unwrap();
+    let store = guard.get_or_insert_with(Store::default);
+    store.acknowledged = store.total_anomalies();
+    Ok(report_of(store))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const T1: &str = "2026-01-01T10:00:01+00:00";
+    const T2: &str = "2026-01-01T10:00:02+00:00";
+    const T3: &str = "2026-01-01T10:00:03+00:00";
+
+    #[test]
+    fn a_canary_that_returns_intact_is_clean() {
+        let mut s = Store::default();
+        s.send("m1", "c1", "mls:aa", T2, 80);
+        s.fetch("c1", &[("x0", "mls:00", T1), ("m1", "mls:aa", T2)]);
+        let r = report_of(&s);
+        assert_eq!((r.checked, r.pending), (1, 0));
+        assert!(r.evidence.is_empty());
+    }
+
+    #[test]
+    fn modification_and_replay_are_caught_but_a_refetch_is_not() {
+        let mut s = Store::default();
+        s.send("m1", "c1", "mls:aa", T1, 80);
+        s.send("m2", "c1", "mls:bb", T2, 80);
+        s.fetch("c1", &[("m1", "mls:zz", T1), ("m2", "mls:bb", T2)]);
+        // The same envelope again (a watermark held back), and its
+        // ciphertext under a new id.
+        s.fetch("c1", &[("m2", "mls:bb", T2), ("r9", "mls:bb", T3)]);
+        let r = report_of(&s);
+        assert_eq!(r.modified, 1);
+        assert_eq!(r.duplicated, 1);
+        assert_eq!(r.evidence[1].envelope_id, "r9");
+        assert_eq!(r.evidence[0].envelope_id, "m1");
+    }
+
+    #[test]
+    fn only_a_canary_inside_the_fetched_span_counts_as_dropped() {
+        let mut s = Store::default();
+        s.send("m1", "c1", "mls:aa", T2, 80);
+        s.send("m2", "c1", "mls:bb", T3, 80);
+        s.send("m3", "c2", "mls:cc", T2, 80);
+        s.fetch("c1", &[("x1", "mls:01", T1), ("x3", "mls:03", T3)]);
+        let r = report_of(&s);
+        // m2 sits on the span's edge and m3 is another conversation.
+        assert_eq!(r.dropped, 1);
+        assert_eq!(r.pending, 2);
+    }
+
+    #[test]
+    fn alert_needs_enough_samples_and_clears_on_acknowledge() {
+        let mut s = Store::default();
+        s.send("slow", "c1", "mls:00", T1, SLOW_ACK_MS + 1);
+        s.fetch("c1", &[("slow", "mls:00", T1)]);
+        assert!(!report_of(&s).alert);
+
+        for i in 0..ALERT_MIN_CHECKED {
+            let id = format!("m{i}");
+            s.send(&id, "c1", &format!("mls:{i}"), T2, 80);
+            s.fetch("c1", &[(id.as_str(), &format!("mls:{i}") as &str, T2)]);
+        }
+        s.send("bad", "c1", "mls:bad", T2, 80);
+        s.fetch("c1", &[("bad", "mls:evil", T2)]);
+        assert!(report_of(&s).alert);
+
+        s.acknowledged = s.total_anomalies();
+        assert!(!report_of(&s).alert);
+    }
+}
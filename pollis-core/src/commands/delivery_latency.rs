@@ -0,0 +1,181 @@
+//! End-to-end message delivery latency, measured on the receiving device.
+//!
+//! Every message this device decrypts at ingest is timed from the sender's
+//! `sent_at` (stamped by the sending client and stored verbatim by the DS) to
+//! the moment it was decrypted here. Samples stay in memory for the process —
+//! nothing is uploaded, and no conversation or sender is kept with them —
+//! and [`get_delivery_latency_stats`] reports percentiles for diagnostics.
+//!
+//! Two filters keep the numbers about delivery rather than about being away:
+//! a message older than [`MAX_SAMPLE_MS`] when it arrives is catch-up after
+//! being offline (counted in `excluded`, not sampled), and a sender clock
+//! running ahead by more than [`MAX_SKEW_MS`] makes a sample meaningless (also
+//! excluded); smaller skews are clamped to 0.
+
+use std::collections::VecDeque;
+use std::sync::Mutex;
+use std::time::{SystemTime, UNIX_EPOCH};
+
+use serde::Serialize;
+
+use crate::error::Result;
+
+/// Samples kept, newest last.
+const HISTORY_LEN: usize = 1000;
+/// Longer than this and the message was waiting for us to come online.
+const MAX_SAMPLE_MS: i64 = 10 * 60 * 1000;
+/// Sender clock ahead of ours by up to this much still counts (as 0 ms).
+const MAX_SKEW_MS: i64 = 5 * 1000;
+/// Window of [`DeliveryLatencyStats::last_hour`].
+const RECENT_SECS: i64 = 60 * 60;
+
+/// Percentiles over one window; all `None` when it has no samples.
+#[derive(Debug, Clone, Default, Serialize, PartialEq)]
+pub struct LatencyPercentiles {
+    pub samples: usize,
+    pub p50_ms: Option<u64>,
+    pub p90_ms: Option<u64>,
+    pub p99_ms: Option<u64>,
+    pub max_ms: Option<u64>,
+}
+
+/// Returned by `get_delivery_latency_stats`. A `last_hour` well above
+/// `session` is a delivery path getting slower.
+#[derive(Debug, Clone, Default, Serialize, PartialEq)]
+pub struct DeliveryLatencyStats {
+    /// Every kept sample since the app started.
+    pub session: LatencyPercentiles,
+    pub last_hour: LatencyPercentiles,
+    /// Messages not sampled: offline catch-up or a sender clock far ahead.
+    pub excluded: u64,
+}
+
+#[derive(Default)]
+struct Store {
+    /// `(decrypted at, unix secs; latency ms)`, oldest first.
+    samples: VecDeque<(i64, u64)>,
+    excluded: u64,
+}
+
+static STORE: Mutex<Store> = Mutex::new(Store {
+    samples: VecDeque::new(),
+    excluded: 0,
+});
+
+fn now_ms() -> i64 {
+    SystemTime::now()
+        .duration_since(UNIX_EPOCH)
+        .map(|d| d.as_millis() as i64)
+        .unwrap_or(0)
+}
+
+/// Record the delivery of a message stamped `sent_at` (RFC 3339) that was
+/// decrypted just now. Unparseable stamps are ignored.
+pub(crate) fn record_delivery(sent_at: &str) {
+    let Ok(sent) = chrono::DateTime::parse_from_rfc3339(sent_at) else {
+        return;
+    };
+    record_at(
+        &mut STORE.lock().unwrap(),
+        sent.timestamp_millis(),
+        now_ms(),
+    );
+}
+
+fn record_at(store: &mut Store, sent_ms: i64, now_ms: i64) {
+    let latency = now_ms - sent_ms;
+    if !(-MAX_SKEW_MS..=MAX_SAMPLE_MS).contains(&latency) {
+        store.excluded += 1;
+        return;
+    }
+    if store.samples.len() == HISTORY_LEN {
+        store.samples.pop_front();
+    }
+    store
+        .samples
+        .push_back((now_ms / 1000, latency.max(0) as u64));
+}
+
+/// Nearest-rank percentiles of `values`.
+fn percentiles(mut values: Vec<u64>) -> LatencyPercentiles {
+    if values.is_empty() {
+        return LatencyPercentiles::default();
+    }
+    values.sort_unstable();
+    let rank = |p: usize| values[((values.len() * p).div_ceil(100)).max(1) - 1];
+    LatencyPercentiles {
+        samples: values.len(),
+        p50_ms: Some(rank(50)),
+        p90_ms: Some(rank(90)),
+        p99_ms: Some(rank(99)),
+        max_ms: values.last().copied(),
+    }
+}
+
+fn stats_at(store: &Store, now_secs: i64) -> DeliveryLatencyStats {
+    DeliveryLatencyStats {
+        session: percentiles(store.samples.iter().map(|(_, ms)| *ms).collect()),
+        last_hour: percentiles(
+            store
+                .samples
+                .iter()
+                .filter(|(at, _)| now_secs - at <= RECENT_SECS)
+                .map(|(_, ms)| *ms)
+                .collect(),
+        ),
+        excluded: store.excluded,
+    }
+}
+
+/// Send → decrypt latency percentiles for messages received this session.
+pub async fn get_delivery_latency_stats() -> Result<DeliveryLatencyStats> {
+    let store = STORE.lock().unwrap();
+    Ok(stats_at(&store, now_ms() / 1000))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn percentiles_use_nearest_rank() {
+        let p = percentiles((1..=100).collect());
+        assert_eq!(p.samples, 100);
+        assert_eq!(p.p50_ms, Some(50));
+        assert_eq!(p.p90_ms, Some(90));
+        assert_eq!(p.p99_ms, Some(99));
+        assert_eq!(p.max_ms, Some(100));
+        assert_eq!(percentiles(vec![7]).p99_ms, Some(7));
+        assert_eq!(percentiles(Vec::new()), LatencyPercentiles::default());
+    }
+
+    #[test]
+    fn catch_up_and_skew_are_excluded() {
+        let mut store = Store::default();
+        let now = 1_000_000_000;
+        record_at(&mut store, now - 250, now);
+        // Sender clock slightly ahead: clamped to 0.
+        record_at(&mut store, now + 1_000, now);
+        // Offline catch-up and a far-ahead clock: not sampled.
+        record_at(&mut store, now - MAX_SAMPLE_MS - 1, now);
+        record_at(&mut store, now + MAX_SKEW_MS + 1, now);
+        let stats = stats_at(&store, now / 1000);
+        assert_eq!(stats.session.samples, 2);
+        assert_eq!(stats.session.max_ms, Some(250));
+        assert_eq!(stats.session.p50_ms, Some(0));
+        assert_eq!(stats.excluded, 2);
+    }
+
+    #[test]
+    fn last_hour_drops_old_samples() {
+        let mut store = Store::default();
+        let start = 1_000_000_000;
+        record_at(&mut store, start - 900, start);
+        let later = start + (RECENT_SECS + 1) * 1000;
+        record_at(&mut store, later - 100, later);
+        let stats = stats_at(&store, later / 1000);
+        assert_eq!(stats.session.samples, 2);
+        assert_eq!(stats.last_hour.samples, 1);
+        assert_eq!(stats.last_hour.max_ms, Some(100));
+    }
+}
@@ -865,6 +865,20 @@ pub async fn reject_device_enrollment(
     Ok(())
 }
 
+/// Planned identity-key rotation from Settings → Security. Unlike
+/// [`reset_identity_and_recover`] nothing is torn down: the old key signs the
+/// new one, so group memberships, local history and this device's cert all
+/// carry over, and peers re-pin without a "safety number changed" warning.
+/// `pin` re-confirms the user and re-wraps the new key. Returns the new
+/// Secret Key, which replaces the old one; the frontend must show it once.
+pub async fn rotate_account_identity(
+    state: &Arc<AppState>,
+    user_id: String,
+    pin: String,
+) -> Result<String> {
+    crate::commands::account_identity::rotate_identity(state, &user_id, &pin).await
+}
+
 // ── Finalization ─────────────────────────────────────────────────────────────
 
 /// Run after a successful account_id_key install on the new device.
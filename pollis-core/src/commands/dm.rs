@@ -20,6 +20,10 @@ pub struct DmChannel {
     pub created_by: String,
     pub created_at: String,
     pub members: Vec<DmChannelMember>,
+    /// Pinned to the top of the sidebar (`sidebar_order` preference). Only
+    /// filled by `list_dm_channels`.
+    #[serde(default)]
+    pub pinned: bool,
 }
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -30,6 +34,9 @@ pub struct DmChannelMember {
     pub added_by: String,
     pub added_at: String,
     pub accepted_at: Option<String>,
+    /// This device's local nickname for the member (`commands::contacts`).
+    #[serde(default)]
+    pub nickname: Option<String>,
 }
 
 /// Fetch members of a DM channel from the remote DB.
@@ -54,11 +61,23 @@ async fn fetch_dm_members(
             added_by: row.get(3)?,
             added_at: row.get(4)?,
             accepted_at: row.get(5)?,
+            nickname: None,
         });
     }
     Ok(members)
 }
 
+/// Fill each member's `nickname` from this device's contact notes.
+async fn attach_nicknames(state: &Arc<AppState>, channels: &mut [DmChannel]) {
+    let nicknames = crate::commands::contacts::load_nicknames(state).await;
+    if nicknames.is_empty() {
+        return;
+    }
+    for member in channels.iter_mut().flat_map(|c| c.members.iter_mut()) {
+        member.nickname = nicknames.get(&member.user_id).cloned();
+    }
+}
+
 
 pub async fn create_dm_channel(
     creator_id: String,
@@ -171,6 +190,7 @@ pub async fn create_dm_channel(
         created_by: creator_id,
         created_at: now,
         members,
+        pinned: false,
     })
 }
 
@@ -216,9 +236,16 @@ pub async fn list_dm_channels(
             created_by,
             created_at,
             members,
+            pinned: false,
         });
     }
 
+    attach_nicknames(state, &mut channels).await;
+    let order = crate::commands::sidebar::load_sidebar_order(state).await;
+    order.sort(&mut channels, |c| c.id.as_str());
+    for c in &mut channels {
+        c.pinned = order.is_pinned(&c.id);
+    }
     Ok(channels)
 }
 
@@ -264,9 +291,11 @@ pub async fn list_dm_requests(
             created_by,
             created_at,
             members,
+            pinned: false,
         });
     }
 
+    attach_nicknames(state, &mut channels).await;
     Ok(channels)
 }
 
@@ -309,7 +338,9 @@ pub async fn get_dm_channel(
 
     let members = fetch_dm_members(&conn, &id).await?;
 
-    Ok(DmChannel { id, created_by, created_at, members })
+    let mut channel = DmChannel { id, created_by, created_at, members, pinned: false };
+    attach_nicknames(state, std::slice::from_mut(&mut channel)).await;
+    Ok(channel)
 }
 
 pub async fn add_user_to_dm_channel(
@@ -13,7 +13,7 @@ pub async fn list_group_channels(
     let conn = state.remote_db.conn().await?;
 
     let mut rows = conn.query(
-        "SELECT id, group_id, name, description, channel_type FROM channels WHERE group_id = ?1",
+        "SELECT id, group_id, name, description, channel_type, retention_days FROM channels WHERE group_id = ?1",
         libsql::params![group_id],
     ).await?;
 
@@ -25,6 +25,7 @@ pub async fn list_group_channels(
             name: row.get(2)?,
             description: row.get(3)?,
             channel_type: row.get::<Option<String>>(4)?.unwrap_or_else(|| "text".to_string()),
+            retention_days: row.get(5)?,
         });
     }
 
@@ -56,7 +57,7 @@ pub async fn create_channel(
     });
     crate::commands::mls::ds_post_ok(state, "/v1/channels/create", &body).await?;
 
-    Ok(Channel { id, group_id, name, description, channel_type })
+    Ok(Channel { id, group_id, name, description, channel_type, retention_days: None })
 }
 
 pub async fn update_channel(
@@ -77,7 +78,7 @@ pub async fn update_channel(
     let group_id: String = if let Some(row) = rows.next().await? {
         row.get(0)?
     } else {
-        return Err(Error::Other(anyhow::anyhow!("channel not found")));
+        return Err(Error::NotFound("channel".into()));
     };
 
     let mut role_rows = conn.query(
@@ -106,7 +107,7 @@ pub async fn update_channel(
     crate::commands::mls::ds_post_ok(state, "/v1/channels/update", &body).await?;
 
     let mut rows = conn.query(
-        "SELECT id, group_id, name, description, channel_type FROM channels WHERE id = ?1",
+        "SELECT id, group_id, name, description, channel_type, retention_days FROM channels WHERE id = ?1",
         libsql::params![channel_id],
     ).await?;
 
@@ -117,12 +118,79 @@ pub async fn update_channel(
             name: row.get(2)?,
             description: row.get(3)?,
             channel_type: row.get::<Option<String>>(4)?.unwrap_or_else(|| "text".to_string()),
+            retention_days: row.get(5)?,
         })
     } else {
         Err(Error::Other(anyhow::anyhow!("channel not found after update")))
     }
 }
 
+/// Longest channel retention window, in days. Matches the DS bound.
+const MAX_CHANNEL_RETENTION_DAYS: i64 = 3650;
+
+/// Set how many days a channel keeps its history; `0` keeps it forever.
+/// Admin-only; the DS re-derives the role before writing
+/// `channels.retention_days`. Enforced by envelope GC on the relay and by
+/// every member's `run_message_eviction`.
+pub async fn set_channel_retention(
+    channel_id: String,
+    requester_id: String,
+    days: i64,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    if !(0..=MAX_CHANNEL_RETENTION_DAYS).contains(&days) {
+        return Err(Error::Other(anyhow::anyhow!(
+            "retention must be between 0 and {MAX_CHANNEL_RETENTION_DAYS} days"
+        )));
+    }
+
+    let conn = state.remote_db.conn().await?;
+
+    let mut rows = conn.query(
+        "SELECT gm.role FROM channels c
+         JOIN group_member gm ON gm.group_id = c.group_id AND gm.user_id = ?2
+         WHERE c.id = ?1",
+        libsql::params![channel_id.clone(), requester_id.clone()],
+    ).await?;
+    let role: String = if let Some(row) = rows.next().await? {
+        row.get(0)?
+    } else {
+        return Err(Error::Other(anyhow::anyhow!("requester is not a group member")));
+    };
+    if role != "admin" {
+        return Err(Error::Other(anyhow::anyhow!("only group admins can change channel retention")));
+    }
+
+    let body = serde_json::json!({
+        "channel_id": channel_id,
+        "requester_id": requester_id,
+        "retention_days": days,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/channels/update", &body).await?;
+
+    Ok(())
+}
+
+/// `(channel_id, retention_days)` for every channel `user_id` can see that has
+/// a retention window set.
+pub(crate) async fn channel_retention_windows(
+    user_id: &str,
+    state: &Arc<AppState>,
+) -> Result<Vec<(String, i64)>> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn.query(
+        "SELECT c.id, c.retention_days FROM channels c
+         JOIN group_member gm ON gm.group_id = c.group_id AND gm.user_id = ?1
+         WHERE c.retention_days IS NOT NULL AND c.retention_days > 0",
+        libsql::params![user_id.to_string()],
+    ).await?;
+    let mut out = Vec::new();
+    while let Some(row) = rows.next().await? {
+        out.push((row.get(0)?, row.get(1)?));
+    }
+    Ok(out)
+}
+
 pub async fn delete_channel(
     channel_id: String,
     requester_id: String,
@@ -138,7 +206,7 @@ pub async fn delete_channel(
     let group_id: String = if let Some(row) = rows.next().await? {
         row.get(0)?
     } else {
-        return Err(Error::Other(anyhow::anyhow!("channel not found")));
+        return Err(Error::NotFound("channel".into()));
     };
 
     let mut role_rows = conn.query(
@@ -0,0 +1,390 @@
+//! Group event log: the "alice joined the group" / "#design was created" lines
+//! the channel timeline shows between messages.
+//!
+//! Derived on this device from remote rows it already reads — a member's
+//! `group_member.joined_at` and a channel's `channels.created_at` — and kept in
+//! the local `group_event` table (SQLCipher, like every other local row). They
+//! are not messages: nobody sends them, nothing goes through MLS, and the
+//! server learns nothing new. A departure has no remote row to read, so it is
+//! noticed by diffing the current roster against the members this log last
+//! saw, and stamped with the time it was noticed. A scheduled deletion is read
+//! from `group_deletion`; its cancellation, like a departure, is the row going
+//! away and is stamped when first noticed.
+
+use std::collections::{HashMap, HashSet};
+use std::sync::Arc;
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+use super::types::GroupEvent;
+
+pub(crate) const MEMBER_JOINED: &str = "member_joined";
+pub(crate) const MEMBER_LEFT: &str = "member_left";
+pub(crate) const CHANNEL_CREATED: &str = "channel_created";
+pub(crate) const DELETION_SCHEDULED: &str = "deletion_scheduled";
+pub(crate) const DELETION_CANCELLED: &str = "deletion_cancelled";
+
+/// A member, channel or pending deletion as the remote DB has it right now.
+#[derive(Debug, Clone)]
+pub(crate) struct Observed {
+    pub id: String,
+    pub name: Option<String>,
+    /// `joined_at` / `created_at` / `requested_at`, as stored remotely.
+    pub at: String,
+}
+
+/// Sync the group's event log from the remote roster and channel list, then
+/// return all of it, oldest first.
+pub async fn get_group_events(group_id: String, state: &Arc<AppState>) -> Result<Vec<GroupEvent>> {
+    sync_group_events(state, &group_id).await?;
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    Ok(read_group_events(db.conn(), &group_id, None, None)?)
+}
+
+pub(crate) async fn sync_group_events(state: &Arc<AppState>, group_id: &str) -> Result<()> {
+    let conn = state.remote_db.conn().await?;
+
+    // The group row and its pending deletion, if any. A group that is gone
+    // (purged, or never visible to us) has nothing to sync — its empty roster
+    // must not read as everyone leaving.
+    let mut rows = conn.query(
+        "SELECT gd.requested_by, u.username, gd.requested_at
+         FROM groups g
+         LEFT JOIN group_deletion gd ON gd.group_id = g.id
+         LEFT JOIN users u ON u.id = gd.requested_by
+         WHERE g.id = ?1",
+        libsql::params![group_id.to_string()],
+    ).await?;
+    let Some(row) = rows.next().await? else {
+        return Ok(());
+    };
+    let deletion = match row.get::<Option<String>>(0)? {
+        Some(id) => Some(Observed {
+            id,
+            name: row.get(1)?,
+            at: row.get(2)?,
+        }),
+        None => None,
+    };
+    drop(rows);
+
+    let mut members = Vec::new();
+    let mut rows = conn.query(
+        "SELECT gm.user_id, u.username, gm.joined_at
+         FROM group_member gm
+         LEFT JOIN users u ON u.id = gm.user_id
+         WHERE gm.group_id = ?1",
+        libsql::params![group_id.to_string()],
+    ).await?;
+    while let Some(row) = rows.next().await? {
+        members.push(Observed {
+            id: row.get(0)?,
+            name: row.get(1)?,
+            at: row.get(2)?,
+        });
+    }
+
+    let mut channels = Vec::new();
+    let mut rows = conn.query(
+        "SELECT id, name, created_at FROM channels WHERE group_id = ?1",
+        libsql::params![group_id.to_string()],
+    ).await?;
+    while let Some(row) = rows.next().await? {
+        channels.push(Observed {
+            id: row.get(0)?,
+            name: row.get(1)?,
+            at: row.get(2)?,
+        });
+    }
+
+    let now = chrono::Utc::now().to_rfc3339();
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    record_group_events(db.conn(), group_id, &members, &channels, deletion.as_ref(), &now)?;
+    Ok(())
+}
+
+/// Fold the current remote view into the log. Idempotent: a join is keyed by
+/// its `joined_at`, a channel by its id and a scheduled deletion by its
+/// `requested_at`, so re-running adds nothing; a member who leaves and comes
+/// back gets a new `joined_at` and a new row. `deletion` is the pending
+/// `group_deletion` row (`id` = who scheduled it).
+pub(crate) fn record_group_events(
+    conn: &rusqlite::Connection,
+    group_id: &str,
+    members: &[Observed],
+    channels: &[Observed],
+    deletion: Option<&Observed>,
+    now: &str,
+) -> rusqlite::Result<()> {
+    let present = present_members(conn, group_id)?;
+    let deletion_pending =
+        latest_deletion_kind(conn, group_id)?.as_deref() == Some(DELETION_SCHEDULED);
+    let tx = conn.unchecked_transaction()?;
+    let mut insert = tx.prepare(
+        "INSERT OR IGNORE INTO group_event (id, group_id, kind, subject_id, subject_name, occurred_at)
+         VALUES (?1, ?2, ?3, ?4, ?5, ?6)",
+    )?;
+
+    for m in members {
+        let at = to_rfc3339(&m.at);
+        insert.execute(rusqlite::params![
+            format!("{MEMBER_JOINED}:{group_id}:{}:{at}", m.id),
+            group_id,
+            MEMBER_JOINED,
+            m.id,
+            m.name,
+            at,
+        ])?;
+    }
+
+    let current: HashSet<&str> = members.iter().map(|m| m.id.as_str()).collect();
+    for (user_id, name) in &present {
+        if current.contains(user_id.as_str()) {
+            continue;
+        }
+        insert.execute(rusqlite::params![
+            format!("{MEMBER_LEFT}:{group_id}:{user_id}:{now}"),
+            group_id,
+            MEMBER_LEFT,
+            user_id,
+            name,
+            now,
+        ])?;
+    }
+
+    for c in channels {
+        insert.execute(rusqlite::params![
+            format!("{CHANNEL_CREATED}:{}", c.id),
+            group_id,
+            CHANNEL_CREATED,
+            c.id,
+            c.name,
+            to_rfc3339(&c.at),
+        ])?;
+    }
+
+    match deletion {
+        Some(d) => {
+            let at = to_rfc3339(&d.at);
+            insert.execute(rusqlite::params![
+                format!("{DELETION_SCHEDULED}:{group_id}:{at}"),
+                group_id,
+                DELETION_SCHEDULED,
+                d.id,
+                d.name,
+                at,
+            ])?;
+        }
+        None if deletion_pending => {
+            insert.execute(rusqlite::params![
+                format!("{DELETION_CANCELLED}:{group_id}:{now}"),
+                group_id,
+                DELETION_CANCELLED,
+                group_id,
+                Option::<String>::None,
+                now,
+            ])?;
+        }
+        None => {}
+    }
+
+    drop(insert);
+    tx.commit()
+}
+
+/// Kind of the group's latest logged deletion event, if any.
+fn latest_deletion_kind(
+    conn: &rusqlite::Connection,
+    group_id: &str,
+) -> rusqlite::Result<Option<String>> {
+    let mut stmt = conn.prepare(
+        "SELECT kind FROM group_event
+         WHERE group_id = ?1 AND kind IN (?2, ?3)
+         ORDER BY occurred_at DESC, id DESC
+         LIMIT 1",
+    )?;
+    let mut rows = stmt.query(rusqlite::params![group_id, DELETION_SCHEDULED, DELETION_CANCELLED])?;
+    match rows.next()? {
+        Some(row) => Ok(Some(row.get(0)?)),
+        None => Ok(None),
+    }
+}
+
+/// Members whose latest logged event is a join, with the name it carried.
+fn present_members(
+    conn: &rusqlite::Connection,
+    group_id: &str,
+) -> rusqlite::Result<HashMap<String, Option<String>>> {
+    let mut stmt = conn.prepare(
+        "SELECT subject_id, subject_name, kind FROM group_event
+         WHERE group_id = ?1 AND kind IN (?2, ?3)
+         ORDER BY occurred_at ASC, id ASC",
+    )?;
+    let rows = stmt.query_map(rusqlite::params![group_id, MEMBER_JOINED, MEMBER_LEFT], |row| {
+        Ok((
+            row.get::<_, String>(0)?,
+            row.get::<_, Option<String>>(1)?,
+            row.get::<_, String>(2)?,
+        ))
+    })?;
+    let mut present = HashMap::new();
+    for row in rows {
+        let (user_id, name, kind) = row?;
+        if kind == MEMBER_JOINED {
+            present.insert(user_id, name);
+        } else {
+            present.remove(&user_id);
+        }
+    }
+    Ok(present)
+}
+
+/// The group's logged events, oldest first. `from` (inclusive) / `to`
+/// (exclusive) bound `occurred_at` the same way the export bounds `sent_at`.
+pub(crate) fn read_group_events(
+    conn: &rusqlite::Connection,
+    group_id: &str,
+    from: Option<&str>,
+    to: Option<&str>,
+) -> rusqlite::Result<Vec<GroupEvent>> {
+    let mut stmt = conn.prepare(
+        "SELECT id, group_id, kind, subject_id, subject_name, occurred_at
+         FROM group_event
+         WHERE group_id = ?1
+           AND (?2 IS NULL OR occurred_at >= ?2)
+           AND (?3 IS NULL OR occurred_at < ?3)
+         ORDER BY occurred_at ASC, id ASC",
+    )?;
+    let rows = stmt.query_map(rusqlite::params![group_id, from, to], |row| {
+        Ok(GroupEvent {
+            id: row.get(0)?,
+            group_id: row.get(1)?,
+            kind: row.get(2)?,
+            subject_id: row.get(3)?,
+            subject_name: row.get(4)?,
+            occurred_at: row.get(5)?,
+        })
+    })?;
+    rows.collect()
+}
+
+/// Remote timestamps are SQLite `datetime('now')` (`YYYY-MM-DD HH:MM:SS`, UTC);
+/// message `sent_at` is RFC 3339. Normalize so the two sort together.
+fn to_rfc3339(at: &str) -> String {
+    match chrono::NaiveDateTime::parse_from_str(at, "%Y-%m-%d %H:%M:%S") {
+        Ok(naive) => naive.and_utc().to_rfc3339(),
+        Err(_) => at.to_string(),
+    }
+}
+
+impl GroupEvent {
+    /// One-line text for exports; the UI renders its own.
+    pub(crate) fn describe(&self) -> String {
+        let name = self.subject_name.as_deref().unwrap_or(&self.subject_id);
+        match self.kind.as_str() {
+            MEMBER_JOINED => format!("{name} joined the group"),
+            MEMBER_LEFT => format!("{name} left the group"),
+            CHANNEL_CREATED => format!("#{name} was created"),
+            DELETION_SCHEDULED => format!("{name} scheduled the group for deletion"),
+            DELETION_CANCELLED => "group deletion was cancelled".to_string(),
+            other => format!("{other}: {name}"),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::db::local::LocalDb;
+
+    fn member(id: &str, at: &str) -> Observed {
+        Observed {
+            id: id.into(),
+            name: Some(format!("{id}-name")),
+            at: at.into(),
+        }
+    }
+
+    fn kinds(conn: &rusqlite::Connection) -> Vec<(String, String)> {
+        read_group_events(conn, "g1", None, None)
+            .unwrap()
+            .into_iter()
+            .map(|e| (e.kind, e.subject_id))
+            .collect()
+    }
+
+    #[test]
+    fn joins_channels_and_leaves_are_logged_once() {
+        let db = LocalDb::open_in_memory().unwrap();
+        let conn = db.conn();
+        let channels = [member("c1", "2024-01-01 00:00:00")];
+        let both = [member("u1", "2024-01-01 00:00:01"), member("u2", "2024-01-02 00:00:00")];
+
+        record_group_events(conn, "g1", &both, &channels, None, "2024-01-03T00:00:00+00:00").unwrap();
+        record_group_events(conn, "g1", &both, &channels, None, "2024-01-04T00:00:00+00:00").unwrap();
+        assert_eq!(
+            kinds(conn),
+            vec![
+                (CHANNEL_CREATED.into(), "c1".into()),
+                (MEMBER_JOINED.into(), "u1".into()),
+                (MEMBER_JOINED.into(), "u2".into()),
+            ]
+        );
+
+        // u2 is gone from the roster: one leave, not one per sync.
+        let one = [member("u1", "2024-01-01 00:00:01")];
+        record_group_events(conn, "g1", &one, &channels, None, "2024-01-05T00:00:00+00:00").unwrap();
+        record_group_events(conn, "g1", &one, &channels, None, "2024-01-06T00:00:00+00:00").unwrap();
+        let events = read_group_events(conn, "g1", None, None).unwrap();
+        let left: Vec<_> = events.iter().filter(|e| e.kind == MEMBER_LEFT).collect();
+        assert_eq!(left.len(), 1);
+        assert_eq!(left[0].subject_id, "u2");
+        assert_eq!(left[0].occurred_at, "2024-01-05T00:00:00+00:00");
+        assert_eq!(left[0].describe(), "u2-name left the group");
+
+        // Rejoining is a new join.
+        let back = [member("u1", "2024-01-01 00:00:01"), member("u2", "2024-01-07 00:00:00")];
+        record_group_events(conn, "g1", &back, &channels, None, "2024-01-08T00:00:00+00:00").unwrap();
+        assert_eq!(kinds(conn).last().unwrap(), &(MEMBER_JOINED.to_string(), "u2".to_string()));
+    }
+
+    #[test]
+    fn deletion_is_logged_and_its_cancellation_noticed() {
+        let db = LocalDb::open_in_memory().unwrap();
+        let conn = db.conn();
+        let owner = [member("u1", "2024-01-01 00:00:00")];
+        let scheduled = member("u1", "2024-02-01 00:00:00");
+
+        for now in ["2024-02-02T00:00:00+00:00", "2024-02-03T00:00:00+00:00"] {
+            record_group_events(conn, "g1", &owner, &[], Some(&scheduled), now).unwrap();
+        }
+        // Withdrawn: one cancellation, stamped when first noticed.
+        for now in ["2024-02-04T00:00:00+00:00", "2024-02-05T00:00:00+00:00"] {
+            record_group_events(conn, "g1", &owner, &[], None, now).unwrap();
+        }
+
+        let events = read_group_events(conn, "g1", None, None).unwrap();
+        let deletion: Vec<_> = events
+            .iter()
+            .filter(|e| e.kind == DELETION_SCHEDULED || e.kind == DELETION_CANCELLED)
+            .map(|e| (e.kind.as_str(), e.occurred_at.as_str()))
+            .collect();
+        assert_eq!(
+            deletion,
+            vec![
+                (DELETION_SCHEDULED, "2024-02-01T00:00:00+00:00"),
+                (DELETION_CANCELLED, "2024-02-04T00:00:00+00:00"),
+            ]
+        );
+        assert_eq!(events[1].describe(), "u1-name scheduled the group for deletion");
+    }
+
+    #[test]
+    fn remote_timestamps_sort_with_messages() {
+        assert_eq!(to_rfc3339("2024-01-02 03:04:05"), "2024-01-02T03:04:05+00:00");
+        assert_eq!(to_rfc3339("2024-01-02T03:04:05+00:00"), "2024-01-02T03:04:05+00:00");
+    }
+}
@@ -5,7 +5,7 @@ use crate::error::{Error, Result};
 use crate::state::AppState;
 
 use super::derive_slug;
-use super::types::{Channel, Group, GroupWithChannels};
+use super::types::{Channel, Group, GroupWithChannels, NormalizedSlug, StorageTarget};
 
 pub async fn list_user_groups_with_channels(
     user_id: String,
@@ -16,10 +16,13 @@ pub async fn list_user_groups_with_channels(
     let mut rows = conn.query(
         "SELECT g.id, g.name, g.description, g.owner_id, g.created_at,
                 c.id, c.group_id, c.name, c.description, c.channel_type,
-                gm.role
+                gm.role, g.allow_export, c.retention_days, gs.slug, gd.purge_after,
+                g.storage_target
          FROM groups g
          JOIN group_member gm ON gm.group_id = g.id
          LEFT JOIN channels c ON c.group_id = g.id
+         LEFT JOIN group_slug gs ON gs.group_id = g.id
+         LEFT JOIN group_deletion gd ON gd.group_id = g.id
          WHERE gm.user_id = ?1
          ORDER BY g.created_at, c.name",
         libsql::params![user_id],
@@ -38,6 +41,7 @@ pub async fn list_user_groups_with_channels(
                     name: row.get(7)?,
                     description: row.get(8)?,
                     channel_type: row.get::<Option<String>>(9)?.unwrap_or_else(|| "text".to_string()),
+                    retention_days: row.get(12)?,
                 });
             }
         } else {
@@ -49,6 +53,7 @@ pub async fn list_user_groups_with_channels(
                     name: row.get(7)?,
                     description: row.get(8)?,
                     channel_type: row.get::<Option<String>>(9)?.unwrap_or_else(|| "text".to_string()),
+                    retention_days: row.get(12)?,
                 });
             }
             groups.push(GroupWithChannels {
@@ -58,11 +63,21 @@ pub async fn list_user_groups_with_channels(
                 owner_id: row.get(3)?,
                 created_at: row.get(4)?,
                 current_user_role: row.get::<Option<String>>(10)?.unwrap_or_else(|| "member".to_string()),
+                allow_export: row.get::<Option<i64>>(11)?.unwrap_or(1) != 0,
+                slug: row.get(13)?,
+                deletion_purge_after: row.get(14)?,
+                storage_target: row.get(15)?,
+                pinned: false,
                 channels,
             });
         }
     }
 
+    let order = crate::commands::sidebar::load_sidebar_order(state).await;
+    order.sort(&mut groups, |g| g.id.as_str());
+    for g in &mut groups {
+        g.pinned = order.is_pinned(&g.id);
+    }
     Ok(groups)
 }
 
@@ -91,6 +106,9 @@ pub async fn list_user_groups(
         });
     }
 
+    crate::commands::sidebar::load_sidebar_order(state)
+        .await
+        .sort(&mut groups, |g| g.id.as_str());
     Ok(groups)
 }
 
@@ -104,6 +122,9 @@ pub async fn create_group(
     create_default_text_channel: Option<bool>,
     // Opt-in to auto-creating a Voice Chat voice channel.
     create_default_voice_channel: Option<bool>,
+    // A slug the owner reserved with `reserve_group_slug`; the DS claims it in
+    // the same transaction as the group insert.
+    slug: Option<String>,
     state: &Arc<AppState>,
 ) -> Result<Group> {
     let id = Ulid::new().to_string();
@@ -124,6 +145,7 @@ pub async fn create_group(
         "owner_id": owner_id,
         "default_text_channel_id": text_channel_id,
         "default_voice_channel_id": voice_channel_id,
+        "slug": slug,
         "created_at": now,
     });
     crate::commands::mls::ds_post_ok(state, "/v1/groups/create", &body).await?;
@@ -199,31 +221,91 @@ pub async fn update_group(
     }
 }
 
-pub async fn delete_group(
+/// Turn admin message export on or off for a group. Admin-only; the DS
+/// re-derives the role before writing `groups.allow_export`.
+pub async fn set_group_export_policy(
     group_id: String,
     requester_id: String,
+    allow_export: bool,
     state: &Arc<AppState>,
 ) -> Result<()> {
     let conn = state.remote_db.conn().await?;
 
-    // Only admins can delete the group
     let mut rows = conn.query(
         "SELECT role FROM group_member WHERE group_id = ?1 AND user_id = ?2",
         libsql::params![group_id.clone(), requester_id.clone()],
     ).await?;
-
     let role: String = if let Some(row) = rows.next().await? {
         row.get(0)?
     } else {
         return Err(Error::Other(anyhow::anyhow!("you are not a member of this group")));
     };
-
     if role != "admin" {
-        return Err(Error::Other(anyhow::anyhow!("only group admins can delete the group")));
+        return Err(Error::Other(anyhow::anyhow!("only group admins can change the export policy")));
     }
 
-    // CASCADE deletes group_member and channels entries. Route the delete through
-    // the Delivery Service (admin re-checked server-side).
+    let body = serde_json::json!({
+        "group_id": group_id,
+        "requester_id": requester_id,
+        "allow_export": allow_export,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/groups/update", &body).await?;
+
+    Ok(())
+}
+
+/// The storage targets this deployment declares, for the owner's residency
+/// setting.
+pub async fn list_storage_targets(state: &Arc<AppState>) -> Result<Vec<StorageTarget>> {
+    crate::commands::mls::ds_client::ds_storage_targets(state).await
+}
+
+/// Pin the group's attachments to a declared storage target, or back to the
+/// default bucket with `None`. Owner-only; the DS re-checks the owner and the
+/// target before writing `groups.storage_target`. Only new uploads move —
+/// existing attachments stay where they were stored.
+pub async fn set_group_storage_target(
+    group_id: String,
+    requester_id: String,
+    storage_target: Option<String>,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let conn = state.remote_db.conn().await?;
+
+    let mut rows = conn.query(
+        "SELECT owner_id FROM groups WHERE id = ?1",
+        libsql::params![group_id.clone()],
+    ).await?;
+    let owner_id: String = if let Some(row) = rows.next().await? {
+        row.get(0)?
+    } else {
+        return Err(Error::NotFound("group".into()));
+    };
+    if owner_id != requester_id {
+        return Err(Error::Other(anyhow::anyhow!("only the group owner can change where attachments are stored")));
+    }
+
+    let body = serde_json::json!({
+        "group_id": group_id,
+        "requester_id": requester_id,
+        "storage_target": storage_target.unwrap_or_default(),
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/groups/update", &body).await?;
+
+    Ok(())
+}
+
+/// Schedule the group's deletion. Step one of two: the DS records it with a
+/// 7-day grace period, during which members see it in the group timeline and
+/// the owner may `cancel_group_deletion`; the DS purges the group afterwards.
+pub async fn delete_group(
+    group_id: String,
+    requester_id: String,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    require_group_owner(&group_id, &requester_id, "delete the group", state).await?;
+
+    // Owner re-checked server-side; nothing is deleted until the purge sweep.
     let body = serde_json::json!({
         "group_id": group_id,
         "requester_id": requester_id,
@@ -233,7 +315,116 @@ pub async fn delete_group(
     Ok(())
 }
 
-/// Find a group whose name derives to the given slug.
+/// Withdraw a scheduled deletion during its grace period. Owner only.
+pub async fn cancel_group_deletion(
+    group_id: String,
+    requester_id: String,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    require_group_owner(&group_id, &requester_id, "cancel its deletion", state).await?;
+
+    let body = serde_json::json!({
+        "group_id": group_id,
+        "requester_id": requester_id,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/groups/delete/cancel", &body).await?;
+
+    Ok(())
+}
+
+/// Delete this device's local messages in the group's channels — the "don't
+/// keep a copy" choice for a group that is about to be deleted. Returns how
+/// many were deleted. The group and everyone else's copies are untouched.
+pub async fn forget_group_history(group_id: String, state: &Arc<AppState>) -> Result<usize> {
+    let conn = state.remote_db.conn().await?;
+    let mut channel_ids = Vec::new();
+    let mut rows = conn.query(
+        "SELECT id FROM channels WHERE group_id = ?1",
+        libsql::params![group_id],
+    ).await?;
+    while let Some(row) = rows.next().await? {
+        channel_ids.push(row.get::<String>(0)?);
+    }
+
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    Ok(crate::db::local::delete_conversation_messages(db.conn(), &channel_ids)?)
+}
+
+async fn require_group_owner(
+    group_id: &str,
+    requester_id: &str,
+    action: &str,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn.query(
+        "SELECT owner_id FROM groups WHERE id = ?1",
+        libsql::params![group_id.to_string()],
+    ).await?;
+    let owner: String = match rows.next().await? {
+        Some(row) => row.get(0)?,
+        None => return Err(Error::NotFound("group".into())),
+    };
+    if owner != requester_id {
+        return Err(Error::Other(anyhow::anyhow!("only the group owner can {action}")));
+    }
+    Ok(())
+}
+
+/// Canonical form of a slug or group name, as the DS will reserve it.
+pub async fn normalize_slug(input: String, state: &Arc<AppState>) -> Result<NormalizedSlug> {
+    let resp = crate::commands::mls::ds_post(
+        state,
+        "/v1/slugs/normalize",
+        &serde_json::json!({ "input": input }),
+    )
+    .await?;
+    if !resp.status().is_success() {
+        let s = resp.status();
+        let txt = resp.text().await.unwrap_or_default();
+        return Err(anyhow::anyhow!("normalize slug failed ({s}): {txt}").into());
+    }
+    Ok(resp.json().await.map_err(anyhow::Error::from)?)
+}
+
+/// Reserve `slug` (normalized server-side) for `user_id` ahead of
+/// `create_group`. Returns the slug as reserved. A slug held by another group
+/// or another user's live reservation is `Error::Conflict`.
+pub async fn reserve_group_slug(
+    slug: String,
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<String> {
+    let resp = crate::commands::mls::ds_post(
+        state,
+        "/v1/slugs/reserve",
+        &serde_json::json!({ "slug": slug, "user_id": user_id }),
+    )
+    .await?;
+    match resp.status().as_u16() {
+        200 => {}
+        409 => return Err(Error::Conflict(format!("The slug '{slug}' is already taken"))),
+        400 => {
+            let v: serde_json::Value = resp.json().await.unwrap_or_default();
+            let msg = v["error"].as_str().unwrap_or("invalid slug").to_string();
+            return Err(anyhow::anyhow!("{msg}").into());
+        }
+        _ => {
+            let s = resp.status();
+            let txt = resp.text().await.unwrap_or_default();
+            return Err(anyhow::anyhow!("reserve slug failed ({s}): {txt}").into());
+        }
+    }
+    let v: serde_json::Value = resp.json().await.map_err(anyhow::Error::from)?;
+    v["slug"]
+        .as_str()
+        .map(str::to_string)
+        .ok_or_else(|| anyhow::anyhow!("reserve slug: missing slug in response").into())
+}
+
+/// Find a group by slug: its reserved `group_slug` row first, then (for groups
+/// created before reservations) a group whose name derives to it.
 /// Returns an error if no match is found.
 pub async fn search_group_by_slug(
     slug: String,
@@ -242,6 +433,22 @@ pub async fn search_group_by_slug(
     let conn = state.remote_db.conn().await?;
     let target = slug.trim().to_lowercase();
 
+    let mut rows = conn.query(
+        "SELECT g.id, g.name, g.description, g.owner_id, g.created_at
+         FROM group_slug s JOIN groups g ON g.id = s.group_id
+         WHERE s.slug = ?1",
+        libsql::params![target.clone()],
+    ).await?;
+    if let Some(row) = rows.next().await? {
+        return Ok(Group {
+            id: row.get(0)?,
+            name: row.get(1)?,
+            description: row.get(2)?,
+            owner_id: row.get(3)?,
+            created_at: row.get(4)?,
+        });
+    }
+
     let mut rows = conn.query(
         "SELECT id, name, description, owner_id, created_at FROM groups",
         libsql::params![],
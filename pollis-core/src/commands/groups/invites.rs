@@ -6,7 +6,8 @@ use crate::state::AppState;
 
 use super::types::PendingInvite;
 
-/// Invite a user (by username) to a group. Inviter must be a current member.
+/// Invite a user (by username or email) to a group. Inviter must be a current
+/// member. An email with no account gets an emailed invite instead.
 pub async fn send_group_invite(
     group_id: String,
     inviter_id: String,
@@ -36,6 +37,10 @@ pub async fn send_group_invite(
     ).await?;
     let invitee_id: String = if let Some(row) = user_rows.next().await? {
         row.get(0)?
+    } else if invitee_identifier.contains('@') {
+        // No account yet: the DS emails them an invite that turns into a
+        // pending one when they sign up.
+        return send_email_invite(&group_id, &inviter_id, invitee_identifier.trim(), state).await;
     } else {
         return Err(Error::Other(anyhow::anyhow!("user '{}' not found", invitee_identifier)));
     };
@@ -59,7 +64,7 @@ pub async fn send_group_invite(
         libsql::params![group_id.clone(), invitee_id.clone()],
     ).await?;
     if member_rows.next().await?.is_some() {
-        return Err(Error::Other(anyhow::anyhow!("that user is already a member of this group")));
+        return Err(Error::Conflict("that user is already a member of this group".into()));
     }
 
     // Check for existing pending invite
@@ -68,7 +73,7 @@ pub async fn send_group_invite(
         libsql::params![group_id.clone(), invitee_id.clone()],
     ).await?;
     if existing.next().await?.is_some() {
-        return Err(Error::Other(anyhow::anyhow!("a pending invite already exists for this user")));
+        return Err(Error::Conflict("a pending invite already exists for this user".into()));
     }
 
     let id = Ulid::new().to_string();
@@ -143,6 +148,79 @@ pub async fn send_group_invite(
 /// Look up the inviter's username and the group's name for a group-invite alert.
 /// Public directory metadata only. Returns `(None, None)` for either field that
 /// can't be resolved (missing user/group row).
+/// Invite an address with no account via `POST /v1/email-invites/create`.
+async fn send_email_invite(
+    group_id: &str,
+    inviter_id: &str,
+    email: &str,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let body = serde_json::json!({
+        "id": Ulid::new().to_string(),
+        "group_id": group_id,
+        "inviter_id": inviter_id,
+        "email": email,
+    });
+    let resp = crate::commands::mls::ds_post(state, "/v1/email-invites/create", &body).await?;
+    match resp.status().as_u16() {
+        200 => Ok(()),
+        409 => {
+            let v: serde_json::Value = resp.json().await.unwrap_or_default();
+            let msg = v["error"].as_str().unwrap_or("already invited").to_string();
+            Err(Error::Conflict(msg))
+        }
+        429 => Err(anyhow::anyhow!(
+            "You've sent too many email invites today. Try again tomorrow."
+        )
+        .into()),
+        503 => Err(anyhow::anyhow!(
+            "Email invites aren't enabled on this server. Ask them to sign up, then invite their username."
+        )
+        .into()),
+        _ => {
+            let s = resp.status();
+            let txt = resp.text().await.unwrap_or_default();
+            Err(anyhow::anyhow!("email invite failed ({s}): {txt}").into())
+        }
+    }
+}
+
+/// Redeem the token from an emailed invite link (the whole link or just the
+/// token). The invite becomes a pending invite for `user_id`, accepted like
+/// any other. Returns its group id.
+pub async fn redeem_email_invite(
+    token: String,
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<String> {
+    let token = token
+        .trim()
+        .rsplit_once("token=")
+        .map(|(_, t)| t)
+        .unwrap_or(token.trim())
+        .to_string();
+    let resp = crate::commands::mls::ds_post(
+        state,
+        "/v1/email-invites/redeem",
+        &serde_json::json!({ "token": token, "user_id": user_id }),
+    )
+    .await?;
+    match resp.status().as_u16() {
+        200 => {}
+        404 => return Err(Error::NotFound("invite".into())),
+        _ => {
+            let s = resp.status();
+            let txt = resp.text().await.unwrap_or_default();
+            return Err(anyhow::anyhow!("redeem invite failed ({s}): {txt}").into());
+        }
+    }
+    let v: serde_json::Value = resp.json().await.map_err(anyhow::Error::from)?;
+    v["group_id"]
+        .as_str()
+        .map(str::to_string)
+        .ok_or_else(|| anyhow::anyhow!("redeem invite: missing group_id in response").into())
+}
+
 async fn fetch_invite_alert_names(
     conn: &libsql::Connection,
     group_id: &str,
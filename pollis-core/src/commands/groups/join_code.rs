@@ -0,0 +1,116 @@
+//! Group join codes — the text behind the "scan to join" QR.
+//!
+//! A code is `pollis-group:v1:<group_id>:<tag>`, where `tag` is a short hash
+//! of the group id and its current name. It carries no secret and grants
+//! nothing by itself: resolving it finds the group, and joining still goes
+//! through `request_group_access` and an admin's approval, exactly like a slug
+//! search. The tag only makes sure the group the scanner is asked to join is
+//! the one that was shown to them — a code printed before a rename resolves
+//! to an "out of date" error rather than to a group under a different name.
+//! There is no relay URL in the payload: every client already talks to the
+//! one DS it was built for.
+
+use sha2::{Digest, Sha256};
+use std::sync::Arc;
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+use super::types::{Group, GroupJoinCode};
+
+const JOIN_CODE_PREFIX: &str = "pollis-group";
+const JOIN_CODE_VERSION: u32 = 1;
+
+/// First 16 hex chars of SHA-256 over the group id and name.
+pub(super) fn join_code_tag(group_id: &str, name: &str) -> String {
+    let mut hasher = Sha256::new();
+    hasher.update(b"pollis-group-join-v1\0");
+    hasher.update(group_id.as_bytes());
+    hasher.update(b"\0");
+    hasher.update(name.as_bytes());
+    hex::encode(hasher.finalize())[..16].to_string()
+}
+
+pub(super) fn format_join_code(group_id: &str, name: &str) -> String {
+    format!(
+        "{JOIN_CODE_PREFIX}:v{JOIN_CODE_VERSION}:{group_id}:{}",
+        join_code_tag(group_id, name)
+    )
+}
+
+/// `(group_id, tag)` from a code, or `None` when it isn't one.
+pub(super) fn parse_join_code(payload: &str) -> Option<(String, String)> {
+    let mut parts = payload.trim().split(':');
+    if parts.next()? != JOIN_CODE_PREFIX || parts.next()? != format!("v{JOIN_CODE_VERSION}") {
+        return None;
+    }
+    let group_id = parts.next()?;
+    let tag = parts.next()?;
+    if parts.next().is_some() || group_id.is_empty() || tag.len() != 16 {
+        return None;
+    }
+    Some((group_id.to_string(), tag.to_ascii_lowercase()))
+}
+
+/// The join code for `group_id`. Any member may show it.
+pub async fn get_group_join_code(
+    group_id: String,
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<GroupJoinCode> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn
+        .query(
+            "SELECT g.name, gs.slug FROM groups g
+             JOIN group_member gm ON gm.group_id = g.id AND gm.user_id = ?2
+             LEFT JOIN group_slug gs ON gs.group_id = g.id
+             WHERE g.id = ?1",
+            libsql::params![group_id.clone(), user_id],
+        )
+        .await?;
+    let (name, reserved_slug): (String, Option<String>) = match rows.next().await? {
+        Some(row) => (row.get(0)?, row.get(1)?),
+        None => {
+            return Err(Error::Other(anyhow::anyhow!(
+                "you are not a member of this group"
+            )))
+        }
+    };
+    Ok(GroupJoinCode {
+        payload: format_join_code(&group_id, &name),
+        slug: reserved_slug.unwrap_or_else(|| super::derive_slug(&name)),
+        group_name: name,
+    })
+}
+
+/// Look up the group a scanned or pasted code points at. The caller then
+/// offers `request_group_access` for it.
+pub async fn resolve_group_join_code(payload: String, state: &Arc<AppState>) -> Result<Group> {
+    let (group_id, tag) = parse_join_code(&payload)
+        .ok_or_else(|| Error::Other(anyhow::anyhow!("That isn't a Pollis group code")))?;
+
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn
+        .query(
+            "SELECT id, name, description, owner_id, created_at FROM groups WHERE id = ?1",
+            libsql::params![group_id],
+        )
+        .await?;
+    let row = rows
+        .next()
+        .await?
+        .ok_or_else(|| Error::NotFound("group".into()))?;
+    let group = Group {
+        id: row.get(0)?,
+        name: row.get(1)?,
+        description: row.get(2)?,
+        owner_id: row.get(3)?,
+        created_at: row.get(4)?,
+    };
+    if join_code_tag(&group.id, &group.name) != tag {
+        return Err(Error::Other(anyhow::anyhow!(
+            "This group code is out of date — ask for a new one"
+        )));
+    }
+    Ok(group)
+}
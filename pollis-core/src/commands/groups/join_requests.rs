@@ -20,7 +20,7 @@ pub async fn request_group_access(
         libsql::params![group_id.clone()],
     ).await?;
     if rows.next().await?.is_none() {
-        return Err(Error::Other(anyhow::anyhow!("group not found")));
+        return Err(Error::NotFound("group".into()));
     }
 
     // Check not already a member
@@ -29,7 +29,7 @@ pub async fn request_group_access(
         libsql::params![group_id.clone(), requester_id.clone()],
     ).await?;
     if member_rows.next().await?.is_some() {
-        return Err(Error::Other(anyhow::anyhow!("you are already a member of this group")));
+        return Err(Error::Conflict("you are already a member of this group".into()));
     }
 
     // Block duplicate pending requests, but allow re-application after rejection.
@@ -43,7 +43,7 @@ pub async fn request_group_access(
     if let Some(row) = existing.next().await? {
         let status: String = row.get(0)?;
         if status == "pending" {
-            return Err(Error::Other(anyhow::anyhow!("you already have a pending request for this group")));
+            return Err(Error::Conflict("you already have a pending request for this group".into()));
         }
     }
 
@@ -19,12 +19,15 @@ pub async fn get_group_members(
         libsql::params![group_id],
     ).await?;
 
+    // `display_name` is this device's local nickname, if any.
+    let nicknames = crate::commands::contacts::load_nicknames(state).await;
     let mut members = Vec::new();
     while let Some(row) = rows.next().await? {
+        let user_id: String = row.get(0)?;
         members.push(GroupMember {
-            user_id: row.get(0)?,
+            display_name: nicknames.get(&user_id).cloned(),
+            user_id,
             username: row.get(1)?,
-            display_name: None,
             avatar_url: row.get(2)?,
             role: row.get(3)?,
             joined_at: row.get(4)?,
@@ -108,6 +111,7 @@ pub async fn remove_member_from_group(
 pub async fn leave_group(
     group_id: String,
     user_id: String,
+    delete_history: Option<bool>,
     state: &Arc<AppState>,
 ) -> Result<()> {
     let conn = state.remote_db.conn().await?;
@@ -133,6 +137,19 @@ pub async fn leave_group(
     //     )));
     // }
 
+    // The group's channels, read while we're still a member: with "delete
+    // history" their local messages go once the leave has landed.
+    let mut channel_ids = Vec::new();
+    if delete_history.unwrap_or(false) {
+        let mut rows = conn.query(
+            "SELECT id FROM channels WHERE group_id = ?1",
+            libsql::params![group_id.clone()],
+        ).await?;
+        while let Some(row) = rows.next().await? {
+            channel_ids.push(row.get::<String>(0)?);
+        }
+    }
+
     // Route the leaver's member-row delete (and, when the group empties, the group
     // delete) through the Delivery Service — one server-authorized write scoped to
     // the signer's own row.
@@ -153,6 +170,15 @@ pub async fn leave_group(
         Err(e) => eprintln!("[mls] leave_group: forget local group {group_id}: {e}"),
     }
 
+    if !channel_ids.is_empty() {
+        let guard = state.local_db.lock().await;
+        if let Some(db) = guard.as_ref() {
+            if let Err(e) = crate::db::local::delete_conversation_messages(db.conn(), &channel_ids) {
+                eprintln!("[groups] leave_group: delete local history of {group_id}: {e}");
+            }
+        }
+    }
+
     // Signal remaining members to reconcile (removes the leaver's stale leaf).
     // Use publish_to_room_server since the leaver may not be connected to the room.
     if let Err(e) = crate::commands::livekit::publish_to_room_server(
@@ -4,13 +4,18 @@
 //! tests) keeps resolving names at `pollis_core::commands::groups::*`.
 
 mod channels;
+mod events;
 mod groups;
 mod invites;
+mod join_code;
 mod join_requests;
 mod membership;
+mod ownership;
 mod types;
+mod webhooks;
 
-/// Mirrors the frontend `deriveSlug` in urlRouting.ts.
+/// Mirrors the frontend `deriveSlug` in urlRouting.ts. Only used for groups
+/// with no reserved slug; the DS's `normalize_slug` is canonical for new ones.
 pub(super) fn derive_slug(name: &str) -> String {
     let lower = name.to_lowercase();
     let cleaned: String = lower
@@ -35,25 +40,44 @@ pub(super) fn derive_slug(name: &str) -> String {
 }
 
 // ── Types ────────────────────────────────────────────────────────────────────
-pub use types::{Channel, Group, GroupMember, GroupWithChannels, JoinRequest, PendingInvite};
+pub use types::{
+    Channel, CreatedWebhook, Group, GroupEvent, GroupJoinCode, GroupMember, GroupStructure,
+    GroupStructureChannel, GroupStructureMember, GroupWebhook, GroupWithChannels, JoinRequest,
+    NormalizedSlug, OwnershipTransfer, PendingInvite, StorageTarget,
+};
 
 // ── Group CRUD / search ──────────────────────────────────────────────────────
 pub use groups::{
-    create_group, delete_group, list_user_groups, list_user_groups_with_channels,
-    search_group_by_slug, update_group,
+    cancel_group_deletion, create_group, delete_group, forget_group_history, list_storage_targets,
+    list_user_groups, list_user_groups_with_channels, normalize_slug, reserve_group_slug,
+    search_group_by_slug, set_group_export_policy, set_group_storage_target, update_group,
 };
 
 // ── Channel CRUD ─────────────────────────────────────────────────────────────
-pub use channels::{create_channel, delete_channel, list_group_channels, update_channel};
+pub use channels::{
+    create_channel, delete_channel, list_group_channels, set_channel_retention, update_channel,
+};
+pub(crate) use channels::channel_retention_windows;
 
 // ── Membership / roles ───────────────────────────────────────────────────────
 pub use membership::{
     get_group_members, leave_group, remove_member_from_group, set_member_role,
 };
 
+// ── Timeline notices ─────────────────────────────────────────────────────────
+pub use events::get_group_events;
+pub(crate) use events::{read_group_events, sync_group_events, CHANNEL_CREATED};
+
+// ── Ownership transfer / export ──────────────────────────────────────────────
+pub use ownership::{
+    accept_group_ownership, decline_group_ownership, export_group_structure,
+    get_pending_ownership_transfers, transfer_group_ownership, GROUP_STRUCTURE_FORMAT_VERSION,
+};
+
 // ── Invites ──────────────────────────────────────────────────────────────────
 pub use invites::{
-    accept_group_invite, decline_group_invite, get_pending_invites, send_group_invite,
+    accept_group_invite, decline_group_invite, get_pending_invites, redeem_email_invite,
+    send_group_invite,
 };
 
 // ── Join requests ────────────────────────────────────────────────────────────
@@ -62,5 +86,11 @@ pub use join_requests::{
     request_group_access,
 };
 
+// ── Join codes (QR) ──────────────────────────────────────────────────────────
+pub use join_code::{get_group_join_code, resolve_group_join_code};
+
+// ── Outbound webhooks ────────────────────────────────────────────────────────
+pub use webhooks::{create_group_webhook, delete_group_webhook, list_group_webhooks};
+
 #[cfg(test)]
 mod tests;
@@ -0,0 +1,211 @@
+use std::sync::Arc;
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+use super::types::{GroupStructure, GroupStructureChannel, GroupStructureMember, OwnershipTransfer};
+
+/// Version stamped into every [`GroupStructure`]. Bump when the shape changes in
+/// a way an importer must distinguish.
+pub const GROUP_STRUCTURE_FORMAT_VERSION: u32 = 1;
+
+/// Propose `new_owner_id` as the group's owner. Step one of two — nothing
+/// changes hands until the target calls [`accept_group_ownership`]. Requester
+/// must be the current owner; the target must be a current member.
+pub async fn transfer_group_ownership(
+    group_id: String,
+    requester_id: String,
+    new_owner_id: String,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    if new_owner_id == requester_id {
+        return Err(Error::Conflict("you already own this group".into()));
+    }
+
+    let conn = state.remote_db.conn().await?;
+
+    let mut rows = conn.query(
+        "SELECT owner_id FROM groups WHERE id = ?1",
+        libsql::params![group_id.clone()],
+    ).await?;
+    let owner_id: String = if let Some(row) = rows.next().await? {
+        row.get(0)?
+    } else {
+        return Err(Error::NotFound("group".into()));
+    };
+    if owner_id != requester_id {
+        return Err(Error::Other(anyhow::anyhow!("only the group owner can transfer ownership")));
+    }
+
+    let mut target_rows = conn.query(
+        "SELECT 1 FROM group_member WHERE group_id = ?1 AND user_id = ?2",
+        libsql::params![group_id.clone(), new_owner_id.clone()],
+    ).await?;
+    if target_rows.next().await?.is_none() {
+        return Err(Error::Other(anyhow::anyhow!("user is not a member of this group")));
+    }
+
+    // Route the pending-row upsert through the Delivery Service (ownership
+    // re-derived server-side, target membership re-checked).
+    let body = serde_json::json!({
+        "group_id": group_id,
+        "new_owner_id": new_owner_id,
+        "requester_id": requester_id,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/groups/transfer-ownership", &body).await?;
+
+    Ok(())
+}
+
+/// Pending ownership transfers the user is party to — proposals waiting on
+/// them (`to_user_id`) and proposals they made that haven't been answered.
+pub async fn get_pending_ownership_transfers(
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<Vec<OwnershipTransfer>> {
+    let conn = state.remote_db.conn().await?;
+
+    let mut rows = conn.query(
+        "SELECT t.group_id, g.name, t.from_user_id, u.username, t.to_user_id, t.created_at
+         FROM group_ownership_transfer t
+         JOIN groups g ON g.id = t.group_id
+         LEFT JOIN users u ON u.id = t.from_user_id
+         WHERE t.to_user_id = ?1 OR t.from_user_id = ?1
+         ORDER BY t.created_at DESC",
+        libsql::params![user_id],
+    ).await?;
+
+    let mut transfers = Vec::new();
+    while let Some(row) = rows.next().await? {
+        transfers.push(OwnershipTransfer {
+            group_id: row.get(0)?,
+            group_name: row.get(1)?,
+            from_user_id: row.get(2)?,
+            from_username: row.get(3)?,
+            to_user_id: row.get(4)?,
+            created_at: row.get(5)?,
+        });
+    }
+
+    Ok(transfers)
+}
+
+/// Accept a pending transfer addressed to `user_id`. The DS moves
+/// `groups.owner_id` and promotes the new owner to admin atomically.
+pub async fn accept_group_ownership(
+    group_id: String,
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let body = serde_json::json!({
+        "group_id": group_id,
+        "user_id": user_id,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/groups/accept-ownership", &body).await?;
+
+    // The new owner is now an admin — refresh everyone's members list.
+    if let Err(e) = crate::commands::livekit::publish_member_role_changed_to_room(
+        &state.livekit,
+        &group_id,
+    )
+    .await
+    {
+        eprintln!("[role] accept_group_ownership: publish MemberRoleChanged: {e}");
+    }
+
+    Ok(())
+}
+
+/// Decline a transfer addressed to `user_id`, or withdraw one they proposed.
+pub async fn decline_group_ownership(
+    group_id: String,
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let body = serde_json::json!({
+        "group_id": group_id,
+        "user_id": user_id,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/groups/decline-ownership", &body).await?;
+
+    Ok(())
+}
+
+/// Portable description of a group — settings, channels and member roles, but
+/// never messages — suitable for re-creating the group elsewhere. Requester
+/// must be an admin.
+pub async fn export_group_structure(
+    group_id: String,
+    requester_id: String,
+    state: &Arc<AppState>,
+) -> Result<GroupStructure> {
+    let conn = state.remote_db.conn().await?;
+
+    let mut rows = conn.query(
+        "SELECT role FROM group_member WHERE group_id = ?1 AND user_id = ?2",
+        libsql::params![group_id.clone(), requester_id.clone()],
+    ).await?;
+    let role: String = if let Some(row) = rows.next().await? {
+        row.get(0)?
+    } else {
+        return Err(Error::Other(anyhow::anyhow!("you are not a member of this group")));
+    };
+    if role != "admin" {
+        return Err(Error::Other(anyhow::anyhow!("only group admins can export the group")));
+    }
+
+    let mut group_rows = conn.query(
+        "SELECT name, description, icon_url, owner_id FROM groups WHERE id = ?1",
+        libsql::params![group_id.clone()],
+    ).await?;
+    let (name, description, icon_url, owner_id): (String, Option<String>, Option<String>, String) =
+        if let Some(row) = group_rows.next().await? {
+            (row.get(0)?, row.get(1)?, row.get(2)?, row.get(3)?)
+        } else {
+            return Err(Error::NotFound("group".into()));
+        };
+
+    let mut channel_rows = conn.query(
+        "SELECT name, description, channel_type FROM channels
+         WHERE group_id = ?1
+         ORDER BY created_at, name",
+        libsql::params![group_id.clone()],
+    ).await?;
+    let mut channels = Vec::new();
+    while let Some(row) = channel_rows.next().await? {
+        channels.push(GroupStructureChannel {
+            name: row.get(0)?,
+            description: row.get(1)?,
+            channel_type: row.get::<Option<String>>(2)?.unwrap_or_else(|| "text".to_string()),
+        });
+    }
+
+    let mut member_rows = conn.query(
+        "SELECT gm.user_id, u.username, gm.role
+         FROM group_member gm
+         LEFT JOIN users u ON u.id = gm.user_id
+         WHERE gm.group_id = ?1
+         ORDER BY gm.joined_at",
+        libsql::params![group_id],
+    ).await?;
+    let mut members = Vec::new();
+    while let Some(row) = member_rows.next().await? {
+        let user_id: String = row.get(0)?;
+        members.push(GroupStructureMember {
+            is_owner: user_id == owner_id,
+            user_id,
+            username: row.get(1)?,
+            role: row.get(2)?,
+        });
+    }
+
+    Ok(GroupStructure {
+        format_version: GROUP_STRUCTURE_FORMAT_VERSION,
+        name,
+        description,
+        icon_url,
+        channels,
+        members,
+        exported_at: chrono::Utc::now().to_rfc3339(),
+    })
+}
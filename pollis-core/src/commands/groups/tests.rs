@@ -83,6 +83,36 @@ fn slug_unicode_stripped() {
     assert_eq!(super::derive_slug("café"), "caf");
 }
 
+// ── join codes ─────────────────────────────────────────────────────────
+
+#[test]
+fn join_code_round_trips() {
+    let code = super::join_code::format_join_code("01HXGROUP", "Test Group");
+    assert!(code.starts_with("pollis-group:v1:01HXGROUP:"));
+    let (group_id, tag) = super::join_code::parse_join_code(&code).unwrap();
+    assert_eq!(group_id, "01HXGROUP");
+    assert_eq!(tag, super::join_code::join_code_tag("01HXGROUP", "Test Group"));
+}
+
+#[test]
+fn join_code_tag_changes_with_rename() {
+    assert_ne!(
+        super::join_code::join_code_tag("g1", "Test Group"),
+        super::join_code::join_code_tag("g1", "Renamed Group"),
+    );
+}
+
+#[test]
+fn join_code_rejects_other_payloads() {
+    use super::join_code::parse_join_code;
+    assert!(parse_join_code("pollis-key:v1:aa:bb").is_none());
+    assert!(parse_join_code("pollis-group:v2:g1:0123456789abcdef").is_none());
+    assert!(parse_join_code("pollis-group:v1::0123456789abcdef").is_none());
+    assert!(parse_join_code("pollis-group:v1:g1:short").is_none());
+    assert!(parse_join_code("pollis-group:v1:g1:0123456789abcdef:extra").is_none());
+    assert!(parse_join_code("test-group").is_none());
+}
+
 // ── group queries ──────────────────────────────────────────────────────
 
 #[test]
@@ -9,6 +9,33 @@ pub struct Group {
     pub created_at: String,
 }
 
+/// The DS's canonical slug for some input, from `normalize_slug`. `slug` is
+/// `None` when nothing usable is left; `reserved` means no group may take it.
+#[derive(Debug, Serialize, Deserialize)]
+pub struct NormalizedSlug {
+    pub slug: Option<String>,
+    pub reserved: bool,
+}
+
+/// A store a group's attachments can be pinned to, from
+/// `list_storage_targets`. Declared by the deployment; credentials stay on the
+/// DS.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct StorageTarget {
+    pub id: String,
+    pub label: String,
+    pub region: String,
+}
+
+/// What the "scan to join" QR encodes, from `get_group_join_code`.
+#[derive(Debug, Serialize, Deserialize)]
+pub struct GroupJoinCode {
+    /// `pollis-group:v1:<group_id>:<tag>` — the QR's text.
+    pub payload: String,
+    pub group_name: String,
+    pub slug: String,
+}
+
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct Channel {
     pub id: String,
@@ -18,6 +45,10 @@ pub struct Channel {
     // 'text' or 'voice' — persisted in Turso.
     // Migration: ALTER TABLE channels ADD COLUMN channel_type TEXT NOT NULL DEFAULT 'text';
     pub channel_type: String,
+    /// Admin-set retention window in days (`channels.retention_days`,
+    /// migration 000016). `None` keeps history.
+    #[serde(default)]
+    pub retention_days: Option<i64>,
 }
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -28,6 +59,25 @@ pub struct GroupWithChannels {
     pub owner_id: String,
     pub created_at: String,
     pub current_user_role: String,
+    /// Whether admins may export this group's channel history
+    /// (`groups.allow_export`, migration 000011).
+    pub allow_export: bool,
+    /// Reserved `/g/<slug>` (`group_slug`, migration 000018). `None` for groups
+    /// created before reservations, which keep their name-derived slug.
+    #[serde(default)]
+    pub slug: Option<String>,
+    /// When a scheduled deletion purges the group (`group_deletion`, migration
+    /// 000023); `None` unless the owner has scheduled one.
+    #[serde(default)]
+    pub deletion_purge_after: Option<String>,
+    /// Storage target the group's attachments are pinned to
+    /// (`groups.storage_target`, migration 000025); `None` for the default
+    /// bucket. Set by the owner; `upload_media` follows it.
+    #[serde(default)]
+    pub storage_target: Option<String>,
+    /// Pinned to the top of the sidebar (`sidebar_order` preference).
+    #[serde(default)]
+    pub pinned: bool,
     pub channels: Vec<Channel>,
 }
 
@@ -60,3 +110,85 @@ pub struct JoinRequest {
     pub status: String,
     pub created_at: String,
 }
+
+#[derive(Debug, Serialize, Deserialize)]
+pub struct OwnershipTransfer {
+    pub group_id: String,
+    pub group_name: String,
+    pub from_user_id: String,
+    pub from_username: Option<String>,
+    pub to_user_id: String,
+    pub created_at: String,
+}
+
+/// Portable, message-free description of a group produced by
+/// `export_group_structure`.
+#[derive(Debug, Serialize, Deserialize)]
+pub struct GroupStructure {
+    pub format_version: u32,
+    pub name: String,
+    pub description: Option<String>,
+    pub icon_url: Option<String>,
+    pub channels: Vec<GroupStructureChannel>,
+    pub members: Vec<GroupStructureMember>,
+    pub exported_at: String,
+}
+
+#[derive(Debug, Serialize, Deserialize)]
+pub struct GroupStructureChannel {
+    pub name: String,
+    pub description: Option<String>,
+    pub channel_type: String,
+}
+
+#[derive(Debug, Serialize, Deserialize)]
+pub struct GroupStructureMember {
+    pub user_id: String,
+    pub username: Option<String>,
+    pub role: String,
+    pub is_owner: bool,
+}
+
+/// A group timeline notice from the local `group_event` log (see
+/// `groups/events.rs`). Not a message: derived on this device, never sent.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct GroupEvent {
+    pub id: String,
+    pub group_id: String,
+    /// `member_joined`, `member_left` or `channel_created`.
+    pub kind: String,
+    /// The member's user id, or the created channel's id.
+    pub subject_id: String,
+    /// Username or channel name when the event was logged.
+    pub subject_name: Option<String>,
+    /// RFC 3339. A join or channel carries its remote timestamp; a departure,
+    /// the time this device noticed it.
+    pub occurred_at: String,
+}
+
+/// An outbound webhook registered on a group (remote `group_webhook`). The
+/// signing secret is never stored — it is only in [`CreatedWebhook`].
+#[derive(Debug, Serialize, Deserialize)]
+pub struct GroupWebhook {
+    pub id: String,
+    pub group_id: String,
+    pub url: String,
+    /// Subscribed events: `message_count`, `member_joined`, `channel_created`.
+    pub events: Vec<String>,
+    pub created_by: String,
+    pub created_at: String,
+    pub last_delivery_at: Option<String>,
+    /// HTTP status of the last delivery; `None` if never delivered or the last
+    /// attempt failed before a response.
+    pub last_status: Option<i64>,
+    /// Consecutive failed deliveries.
+    pub failure_count: i64,
+}
+
+/// Returned once by `create_group_webhook`: the row plus the signing secret
+/// the receiver verifies `X-Pollis-Signature` with.
+#[derive(Debug, Serialize, Deserialize)]
+pub struct CreatedWebhook {
+    pub webhook: GroupWebhook,
+    pub secret: String,
+}
@@ -0,0 +1,120 @@
+//! Outbound group webhooks. Admins register a URL the DS notifies, with signed,
+//! content-free events (`message_count`, `member_joined`, `channel_created`) —
+//! see `pollis-delivery/src/webhooks.rs` for the payloads and signature. Writes
+//! go through the DS (`/v1/webhooks/*`); the list reads `group_webhook`
+//! directly like the rest of the group reads.
+
+use std::sync::Arc;
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+use super::types::{CreatedWebhook, GroupWebhook};
+
+async fn require_admin(conn: &libsql::Connection, group_id: &str, requester_id: &str) -> Result<()> {
+    let mut rows = conn.query(
+        "SELECT role FROM group_member WHERE group_id = ?1 AND user_id = ?2",
+        libsql::params![group_id.to_string(), requester_id.to_string()],
+    ).await?;
+    let role: String = if let Some(row) = rows.next().await? {
+        row.get(0)?
+    } else {
+        return Err(Error::Other(anyhow::anyhow!("you are not a member of this group")));
+    };
+    if role != "admin" {
+        return Err(Error::Other(anyhow::anyhow!("only group admins can manage webhooks")));
+    }
+    Ok(())
+}
+
+async fn read_webhooks(conn: &libsql::Connection, where_sql: &str, id: &str) -> Result<Vec<GroupWebhook>> {
+    let mut rows = conn.query(
+        &format!(
+            "SELECT id, group_id, url, events, created_by, created_at, last_delivery_at, last_status, failure_count \
+             FROM group_webhook WHERE {where_sql} ORDER BY created_at ASC"
+        ),
+        libsql::params![id.to_string()],
+    ).await?;
+    let mut out = Vec::new();
+    while let Some(row) = rows.next().await? {
+        let events: String = row.get(3)?;
+        out.push(GroupWebhook {
+            id: row.get(0)?,
+            group_id: row.get(1)?,
+            url: row.get(2)?,
+            events: events.split(',').filter(|e| !e.is_empty()).map(str::to_string).collect(),
+            created_by: row.get(4)?,
+            created_at: row.get(5)?,
+            last_delivery_at: row.get(6)?,
+            last_status: row.get(7)?,
+            failure_count: row.get(8)?,
+        });
+    }
+    Ok(out)
+}
+
+/// The group's webhooks, oldest first. Admin only.
+pub async fn list_group_webhooks(
+    group_id: String,
+    requester_id: String,
+    state: &Arc<AppState>,
+) -> Result<Vec<GroupWebhook>> {
+    let conn = state.remote_db.conn().await?;
+    require_admin(&conn, &group_id, &requester_id).await?;
+    read_webhooks(&conn, "group_id = ?1", &group_id).await
+}
+
+/// Register a webhook. Admin only. The returned secret is shown once — the DS
+/// derives it and never stores it, so it can't be fetched again.
+pub async fn create_group_webhook(
+    group_id: String,
+    requester_id: String,
+    url: String,
+    events: Vec<String>,
+    state: &Arc<AppState>,
+) -> Result<CreatedWebhook> {
+    let conn = state.remote_db.conn().await?;
+    require_admin(&conn, &group_id, &requester_id).await?;
+
+    let body = serde_json::json!({
+        "group_id": group_id,
+        "requester_id": requester_id,
+        "url": url,
+        "events": events,
+    });
+    let resp = crate::commands::mls::ds_post(state, "/v1/webhooks/create", &body).await?;
+    let status = resp.status();
+    if !status.is_success() {
+        let txt = resp.text().await.unwrap_or_default();
+        return Err(Error::Other(anyhow::anyhow!("create webhook {status}: {txt}")));
+    }
+    #[derive(serde::Deserialize)]
+    struct Resp {
+        id: String,
+        secret: String,
+    }
+    let parsed: Resp = resp
+        .json()
+        .await
+        .map_err(|e| Error::Other(anyhow::anyhow!("create webhook decode: {e}")))?;
+
+    let webhook = read_webhooks(&conn, "id = ?1", &parsed.id)
+        .await?
+        .pop()
+        .ok_or_else(|| Error::Other(anyhow::anyhow!("webhook not found after create")))?;
+    Ok(CreatedWebhook { webhook, secret: parsed.secret })
+}
+
+/// Remove a webhook. Admin only; the DS re-derives the role from the webhook's
+/// group.
+pub async fn delete_group_webhook(
+    webhook_id: String,
+    requester_id: String,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let body = serde_json::json!({
+        "webhook_id": webhook_id,
+        "requester_id": requester_id,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/webhooks/delete", &body).await
+}
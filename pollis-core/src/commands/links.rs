@@ -0,0 +1,290 @@
+//! Link policy: what happens when a link in a message is opened or previewed.
+//!
+//! Every message surface (linkified text, inline media previews) runs URLs
+//! through [`check`] before acting on them, so the rules live in one place:
+//!
+//!   - only `http`/`https` open; anything else (`javascript:`, `file:`, …) is
+//!     blocked;
+//!   - tracking parameters (`utm_*`, `fbclid`, `gclid`, …) are removed when
+//!     `link_strip_tracking` is on;
+//!   - plain `http`, credentials in the URL (`https://bank.com@evil.example`)
+//!     and punycode hosts ask for confirmation first — `http` only while
+//!     `link_confirm_insecure` is on;
+//!   - inline previews are fetched only for `https` links without credentials,
+//!     and only while `link_previews` is on.
+//!
+//! The three settings are keys in the synced preferences blob (see `user.rs`),
+//! so they apply per account on every device. They're read from the local
+//! mirror: checking a link costs no round trip and works offline.
+
+use std::sync::Arc;
+
+use reqwest::Url;
+use serde::Serialize;
+
+use crate::error::Result;
+use crate::state::AppState;
+
+/// Query parameters removed by `link_strip_tracking`, matched exactly.
+const TRACKING_PARAMS: &[&str] = &[
+    "fbclid",
+    "gclid",
+    "gclsrc",
+    "dclid",
+    "gbraid",
+    "wbraid",
+    "msclkid",
+    "yclid",
+    "twclid",
+    "ttclid",
+    "igshid",
+    "mc_cid",
+    "mc_eid",
+    "_hsenc",
+    "_hsmi",
+    "mkt_tok",
+    "oly_anon_id",
+    "oly_enc_id",
+    "vero_id",
+    "wickedid",
+    "ref_src",
+];
+/// ... and by prefix.
+const TRACKING_PREFIXES: &[&str] = &["utm_", "pk_", "hsa_"];
+
+#[derive(Debug, Clone, Copy)]
+pub struct LinkPolicy {
+    pub confirm_insecure: bool,
+    pub strip_tracking: bool,
+    pub previews: bool,
+}
+
+impl Default for LinkPolicy {
+    fn default() -> Self {
+        Self {
+            confirm_insecure: true,
+            strip_tracking: true,
+            previews: true,
+        }
+    }
+}
+
+impl LinkPolicy {
+    fn from_preferences(preferences_json: &str) -> Self {
+        let prefs = serde_json::from_str::<serde_json::Value>(preferences_json).unwrap_or_default();
+        let flag =
+            |key: &str, default: bool| prefs.get(key).and_then(|v| v.as_bool()).unwrap_or(default);
+        let defaults = Self::default();
+        Self {
+            confirm_insecure: flag("link_confirm_insecure", defaults.confirm_insecure),
+            strip_tracking: flag("link_strip_tracking", defaults.strip_tracking),
+            previews: flag("link_previews", defaults.previews),
+        }
+    }
+}
+
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "snake_case")]
+pub enum LinkAction {
+    Open,
+    /// Open only after the user confirms `reason`.
+    Confirm,
+    /// Never open.
+    Block,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct LinkCheck {
+    /// What to open or fetch: normalized, tracking parameters removed. The
+    /// input unchanged when blocked.
+    pub url: String,
+    /// Host to show in a confirmation, e.g. `example.com`.
+    pub host: String,
+    /// Names of the query parameters removed.
+    pub stripped: Vec<String>,
+    pub action: LinkAction,
+    /// Why the link needs confirming or is blocked.
+    pub reason: Option<String>,
+    /// Whether a message surface may fetch an inline preview of it.
+    pub preview: bool,
+}
+
+fn is_tracking_param(name: &str) -> bool {
+    let name = name.to_ascii_lowercase();
+    TRACKING_PARAMS.contains(&name.as_str())
+        || TRACKING_PREFIXES.iter().any(|p| name.starts_with(p))
+}
+
+fn blocked(raw: &str, reason: &str) -> LinkCheck {
+    LinkCheck {
+        url: raw.to_string(),
+        host: String::new(),
+        stripped: Vec::new(),
+        action: LinkAction::Block,
+        reason: Some(reason.to_string()),
+        preview: false,
+    }
+}
+
+/// Apply `policy` to one URL as it appeared in a message. Bare `www.` links
+/// get `https://`, matching how they're rendered.
+pub fn check(raw: &str, policy: &LinkPolicy) -> LinkCheck {
+    let raw = raw.trim();
+    let with_scheme = if raw.len() >= 4 && raw[..4].eq_ignore_ascii_case("www.") {
+        format!("https://{raw}")
+    } else {
+        raw.to_string()
+    };
+    let Ok(mut url) = Url::parse(&with_scheme) else {
+        return blocked(raw, "not a valid web address");
+    };
+    let secure = match url.scheme() {
+        "https" => true,
+        "http" => false,
+        _ => return blocked(raw, "only web links (http/https) open from messages"),
+    };
+    let host = url.host_str().unwrap_or_default().to_string();
+    if host.is_empty() {
+        return blocked(raw, "not a valid web address");
+    }
+
+    let mut stripped = Vec::new();
+    if policy.strip_tracking && url.query().is_some() {
+        let (kept, dropped): (Vec<_>, Vec<_>) = url
+            .query_pairs()
+            .map(|(k, v)| (k.into_owned(), v.into_owned()))
+            .partition(|(k, _)| !is_tracking_param(k));
+        if !dropped.is_empty() {
+            stripped = dropped.into_iter().map(|(k, _)| k).collect();
+            if kept.is_empty() {
+                url.set_query(None);
+            } else {
+                url.query_pairs_mut().clear().extend_pairs(kept);
+            }
+        }
+    }
+
+    let has_credentials = !url.username().is_empty() || url.password().is_some();
+    // First match wins: the most misleading problem is the one to explain.
+    let reason = if has_credentials {
+        Some(format!("the link hides its real destination, {host}"))
+    } else if host.split('.').any(|label| label.starts_with("xn--")) {
+        Some(format!(
+            "{host} uses characters that can look like a different address"
+        ))
+    } else if !secure && policy.confirm_insecure {
+        Some(format!("{host} isn't using an encrypted connection (http)"))
+    } else {
+        None
+    };
+
+    LinkCheck {
+        url: url.to_string(),
+        host,
+        stripped,
+        action: if reason.is_some() {
+            LinkAction::Confirm
+        } else {
+            LinkAction::Open
+        },
+        reason,
+        preview: policy.previews && secure && !has_credentials,
+    }
+}
+
+/// The policy from the local preferences mirror; defaults when the local DB
+/// isn't open or nothing is saved.
+async fn load_policy(state: &Arc<AppState>) -> LinkPolicy {
+    let guard = state.local_db.lock().await;
+    let Some(db) = guard.as_ref() else {
+        return LinkPolicy::default();
+    };
+    db.conn()
+        .query_row("SELECT preferences FROM preferences LIMIT 1", [], |row| {
+            row.get::<_, String>(0)
+        })
+        .map(|p| LinkPolicy::from_preferences(&p))
+        .unwrap_or_default()
+}
+
+/// Check one link before opening it.
+pub async fn check_link(url: String, state: &Arc<AppState>) -> Result<LinkCheck> {
+    Ok(check(&url, &load_policy(state).await))
+}
+
+/// Check every link in a message at once (for inline previews).
+pub async fn check_links(urls: Vec<String>, state: &Arc<AppState>) -> Result<Vec<LinkCheck>> {
+    let policy = load_policy(state).await;
+    Ok(urls.iter().map(|u| check(u, &policy)).collect())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn strips_tracking_and_keeps_the_rest() {
+        let c = check(
+            "https://example.com/a?id=7&utm_source=x&fbclid=abc#top",
+            &LinkPolicy::default(),
+        );
+        assert_eq!(c.url, "https://example.com/a?id=7#top");
+        assert_eq!(c.stripped, ["utm_source", "fbclid"]);
+        assert_eq!(c.action, LinkAction::Open);
+        assert!(c.preview);
+
+        let c = check("https://example.com/?utm_medium=y", &LinkPolicy::default());
+        assert_eq!(c.url, "https://example.com/");
+
+        let keep = LinkPolicy {
+            strip_tracking: false,
+            ..LinkPolicy::default()
+        };
+        let c = check("https://example.com/?utm_medium=y", &keep);
+        assert_eq!(c.url, "https://example.com/?utm_medium=y");
+        assert!(c.stripped.is_empty());
+    }
+
+    #[test]
+    fn insecure_and_deceptive_links_need_confirming() {
+        let c = check("http://example.com", &LinkPolicy::default());
+        assert_eq!(c.action, LinkAction::Confirm);
+        assert!(!c.preview);
+        let relaxed = LinkPolicy {
+            confirm_insecure: false,
+            ..LinkPolicy::default()
+        };
+        assert_eq!(
+            check("http://example.com", &relaxed).action,
+            LinkAction::Open
+        );
+
+        let c = check("https://bank.example@evil.example/login", &relaxed);
+        assert_eq!(c.action, LinkAction::Confirm);
+        assert_eq!(c.host, "evil.example");
+        assert!(!c.preview);
+
+        let c = check("https://xn--pple-43d.com", &relaxed);
+        assert_eq!(c.action, LinkAction::Confirm);
+    }
+
+    #[test]
+    fn other_schemes_are_blocked() {
+        for raw in ["javascript:alert(1)", "file:///etc/passwd", "not a url"] {
+            assert_eq!(check(raw, &LinkPolicy::default()).action, LinkAction::Block);
+        }
+        assert_eq!(
+            check("www.example.com/x", &LinkPolicy::default()).url,
+            "https://www.example.com/x"
+        );
+    }
+
+    #[test]
+    fn policy_reads_preferences() {
+        let p = LinkPolicy::from_preferences(r#"{"link_previews":false}"#);
+        assert!(!p.previews);
+        assert!(p.strip_tracking && p.confirm_insecure);
+        assert!(check("https://example.com/a.png", &p).action == LinkAction::Open);
+        assert!(!check("https://example.com/a.png", &p).preview);
+    }
+}
@@ -266,6 +266,13 @@ pub(super) fn dispatch_data(payload: &[u8], channel: &dyn crate::sink::EventSink
                 });
             }
         }
+        Some("pins_changed") => {
+            if let Some(conversation_id) = data.get("conversation_id").and_then(|v| v.as_str()) {
+                let _ = channel.send(RealtimeEvent::PinsChanged {
+                    conversation_id: conversation_id.to_owned(),
+                });
+            }
+        }
         _ => {}
     }
     None
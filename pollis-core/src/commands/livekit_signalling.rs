@@ -58,6 +58,15 @@ pub fn deleted_message_payload(
     })
 }
 
+/// `pins_changed` wake-up: the conversation's pinned list changed, refetch.
+/// Who pinned or unpinned is read from `pinned_message`, not the ping.
+pub fn pins_changed_payload(conversation_id: &str) -> Value {
+    json!({
+        "type": "pins_changed",
+        "conversation_id": conversation_id,
+    })
+}
+
 /// `membership_changed` wake-up: the named group's membership changed, refetch.
 pub fn membership_changed_payload(group_id: &str) -> Value {
     json!({
@@ -167,6 +176,14 @@ mod tests {
         assert_no_identity(&p);
     }
 
+    #[test]
+    fn pins_changed_ping_is_routing_only() {
+        let p = pins_changed_payload("chan-1");
+        assert_eq!(p["type"], "pins_changed");
+        assert_eq!(p["conversation_id"], "chan-1");
+        assert_no_identity(&p);
+    }
+
     #[test]
     fn membership_changed_ping_is_routing_only() {
         let p = membership_changed_payload("group-1");
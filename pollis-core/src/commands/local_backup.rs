@@ -0,0 +1,75 @@
+//! Device-local snapshot backups of the encrypted per-user database.
+//!
+//! Snapshots are taken automatically on unlock (at most once a day) and on
+//! demand here; they stay on this device, encrypted under the same key as the
+//! live DB, and never leave it — this is not a key backup. Restore writes the
+//! snapshot's history and settings back but never its MLS or identity state.
+//!
+//! The storage primitives live in `db::backup`; these are the thin async
+//! command wrappers that take the shared `AppState` local DB.
+
+use std::sync::Arc;
+
+use crate::db::backup;
+pub use crate::db::backup::LocalBackup;
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+/// List this user's snapshots, newest first.
+pub async fn list_local_backups(user_id: String) -> Result<Vec<LocalBackup>> {
+    backup::list_backups(&backup::backups_dir(), &user_id)
+}
+
+/// Take a snapshot now. `None` when snapshots are disabled (count `0`).
+pub async fn create_local_backup(
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<Option<LocalBackup>> {
+    let guard = state.local_db.lock().await;
+    let db = guard
+        .as_ref()
+        .ok_or_else(|| Error::Other(anyhow::anyhow!("local database not open")))?;
+    backup::create_backup(db.conn(), &backup::backups_dir(), &user_id)
+}
+
+/// Write the snapshot taken at `timestamp` back into the live DB, then re-run
+/// the retention sweep so messages the device or channel windows had evicted
+/// don't come back with it. Returns the number of rows written.
+pub async fn restore_local_backup(
+    user_id: String,
+    timestamp: String,
+    state: &Arc<AppState>,
+) -> Result<usize> {
+    let written = {
+        let guard = state.local_db.lock().await;
+        let db = guard
+            .as_ref()
+            .ok_or_else(|| Error::Other(anyhow::anyhow!("local database not open")))?;
+        backup::restore_backup(db.conn(), &backup::backups_dir(), &user_id, &timestamp)?
+    };
+    crate::commands::messages::run_message_eviction(state).await?;
+    Ok(written)
+}
+
+/// How many snapshots this device keeps. `0` means snapshots are off.
+pub async fn get_local_backup_count(state: &Arc<AppState>) -> Result<i64> {
+    let guard = state.local_db.lock().await;
+    let db = guard
+        .as_ref()
+        .ok_or_else(|| Error::Other(anyhow::anyhow!("local database not open")))?;
+    backup::get_backup_count(db.conn())
+}
+
+/// Set how many snapshots to keep and prune any beyond the new count.
+pub async fn set_local_backup_count(
+    user_id: String,
+    count: i64,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let guard = state.local_db.lock().await;
+    let db = guard
+        .as_ref()
+        .ok_or_else(|| Error::Other(anyhow::anyhow!("local database not open")))?;
+    backup::set_backup_count(db.conn(), count)?;
+    backup::prune_backups(&backup::backups_dir(), &user_id, count)
+}
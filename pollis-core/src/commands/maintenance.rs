@@ -0,0 +1,121 @@
+//! Client side of the Delivery Service maintenance window
+//! (`pollis_delivery::maintenance`).
+//!
+//! While the DS is in maintenance it refuses writes with `503` +
+//! `X-Pollis-Maintenance: 1` and keeps serving reads. `ds_post` hands every DS
+//! reply to [`observe`]: a refusal becomes [`Error::Maintenance`] and opens
+//! the window here, and the first write that goes through again closes it.
+//! Each change is pushed to the sink registered with
+//! [`subscribe_maintenance_events`] (the banner), and
+//! [`get_maintenance_status`] asks the DS directly — the frontend calls it at
+//! the announced ETA to find out whether to release held writes.
+
+use std::sync::{Arc, Mutex as StdMutex};
+
+use serde::{Deserialize, Serialize};
+
+use crate::error::{Error, Result};
+use crate::sink::EventSink;
+use crate::state::AppState;
+
+/// Header the DS sets on a maintenance refusal.
+const MAINTENANCE_HEADER: &str = "x-pollis-maintenance";
+
+/// Mirrors `pollis_delivery::maintenance::MaintenanceStatus`.
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct MaintenanceStatus {
+    pub enabled: bool,
+    /// When the DS expects writes back, unix seconds.
+    #[serde(default)]
+    pub eta: Option<i64>,
+    /// Operator note for the banner.
+    #[serde(default)]
+    pub message: Option<String>,
+}
+
+static CURRENT: StdMutex<Option<MaintenanceStatus>> = StdMutex::new(None);
+static SINK: StdMutex<Option<Arc<dyn EventSink<MaintenanceStatus>>>> = StdMutex::new(None);
+
+/// Route window changes to `sink`, replacing any previous subscriber. The
+/// current state is sent straight away so a late subscriber isn't stale.
+pub fn subscribe_maintenance_events(sink: Arc<dyn EventSink<MaintenanceStatus>>) {
+    let _ = sink.send(current());
+    if let Ok(mut guard) = SINK.lock() {
+        *guard = Some(sink);
+    }
+}
+
+/// The window as last seen; closed until a DS reply says otherwise.
+pub fn current() -> MaintenanceStatus {
+    CURRENT
+        .lock()
+        .ok()
+        .and_then(|g| g.clone())
+        .unwrap_or_default()
+}
+
+/// Record `status`, telling the subscriber when it changed.
+fn record(status: MaintenanceStatus) {
+    let changed = match CURRENT.lock() {
+        Ok(mut guard) => {
+            let next = status.enabled.then(|| status.clone());
+            let changed = *guard != next;
+            *guard = next;
+            changed
+        }
+        Err(_) => false,
+    };
+    if !changed {
+        return;
+    }
+    tracing::info!(enabled = status.enabled, eta = ?status.eta, "[maintenance] DS window changed");
+    let sink = SINK.lock().ok().and_then(|g| g.clone());
+    if let Some(sink) = sink {
+        let _ = sink.send(status);
+    }
+}
+
+/// Inspect a DS write reply: a maintenance refusal opens the window and
+/// becomes [`Error::Maintenance`]; anything else closes it and is passed
+/// through for the caller to map.
+pub(crate) async fn observe(resp: reqwest::Response) -> Result<reqwest::Response> {
+    let refused = resp.status() == reqwest::StatusCode::SERVICE_UNAVAILABLE
+        && resp.headers().contains_key(MAINTENANCE_HEADER);
+    if !refused {
+        if current().enabled {
+            record(MaintenanceStatus::default());
+        }
+        return Ok(resp);
+    }
+    let mut status = resp.json::<MaintenanceStatus>().await.unwrap_or_default();
+    status.enabled = true;
+    record(status);
+    Err(Error::Maintenance)
+}
+
+/// Ask the DS whether its maintenance window is still open (`GET
+/// /v1/maintenance`, unauthenticated) and record the answer.
+pub async fn get_maintenance_status(state: &Arc<AppState>) -> Result<MaintenanceStatus> {
+    let status = crate::commands::mls::ds_client::ds_maintenance_status(state).await?;
+    record(status.clone());
+    Ok(status)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn status_matches_the_ds_wire_shape() {
+        let status: MaintenanceStatus =
+            serde_json::from_str(r#"{"enabled":true,"eta":1767225600,"message":"moving databases"}"#)
+                .unwrap();
+        assert!(status.enabled);
+        assert_eq!(status.eta, Some(1767225600));
+        // The refusal body carries `error` instead of `enabled`.
+        let refusal: MaintenanceStatus =
+            serde_json::from_str(r#"{"error":"MAINTENANCE","eta":null,"message":null}"#).unwrap();
+        assert!(!refusal.enabled);
+        assert!(refusal.eta.is_none());
+    }
+}
@@ -0,0 +1,130 @@
+//! Per-channel activity: message counts per hour or day, for sparklines and
+//! "most active channels". Read from `message_activity`, the hourly rollup the
+//! local schema's triggers keep as messages are ingested, sent and evicted —
+//! never from `message` itself, so the cost doesn't grow with history.
+//!
+//! Counts cover what's in the local cache: messages evicted by retention drop
+//! out, and a device that joined late only counts what it has ingested.
+//! Buckets are UTC hours and UTC days.
+
+use std::sync::Arc;
+
+use chrono::{DateTime, Duration, DurationRound, Utc};
+
+use crate::error::Result;
+use crate::state::AppState;
+
+use super::types::{ActiveChannel, ActivityBucket, ActivityRange, ChannelActivity};
+
+/// Format of `message_activity.hour` and of every bucket `start`.
+const HOUR_FORMAT: &str = "%Y-%m-%dT%H:00:00Z";
+
+/// First bucket start, bucket width and bucket count for `range` at `now`.
+fn window(range: ActivityRange, now: DateTime<Utc>) -> (DateTime<Utc>, Duration, i64) {
+    let (step, n) = match range {
+        ActivityRange::Day => (Duration::hours(1), 24),
+        ActivityRange::Week => (Duration::days(1), 7),
+        ActivityRange::Month => (Duration::days(1), 30),
+    };
+    let current = now.duration_trunc(step).unwrap_or(now);
+    (current - step * (n as i32 - 1), step, n)
+}
+
+/// `conversation_id`'s activity over `range`, ending with the bucket `now`
+/// falls in.
+pub(super) fn activity(
+    conn: &rusqlite::Connection,
+    conversation_id: &str,
+    range: ActivityRange,
+    now: DateTime<Utc>,
+) -> rusqlite::Result<ChannelActivity> {
+    let (start, step, n) = window(range, now);
+    let mut buckets: Vec<ActivityBucket> = (0..n)
+        .map(|i| ActivityBucket {
+            start: (start + step * i as i32).format(HOUR_FORMAT).to_string(),
+            count: 0,
+        })
+        .collect();
+
+    let mut stmt = conn.prepare(
+        "SELECT hour, count FROM message_activity
+         WHERE conversation_id = ?1 AND hour >= ?2",
+    )?;
+    let rows = stmt.query_map(
+        rusqlite::params![conversation_id, start.format(HOUR_FORMAT).to_string()],
+        |row| Ok((row.get::<_, String>(0)?, row.get::<_, i64>(1)?)),
+    )?;
+    for (hour, count) in rows.filter_map(|r| r.ok()) {
+        let Ok(at) = DateTime::parse_from_rfc3339(&hour) else {
+            continue;
+        };
+        let i = (at.with_timezone(&Utc) - start).num_seconds() / step.num_seconds();
+        // Hours past `now` (a sender's clock running ahead) aren't shown.
+        if let Some(bucket) = usize::try_from(i).ok().and_then(|i| buckets.get_mut(i)) {
+            bucket.count += count;
+        }
+    }
+
+    let total = buckets.iter().map(|b| b.count).sum();
+    Ok(ChannelActivity {
+        channel_id: conversation_id.to_string(),
+        range,
+        buckets,
+        total,
+    })
+}
+
+/// Conversations with the most messages over `range`, busiest first.
+pub(super) fn most_active(
+    conn: &rusqlite::Connection,
+    range: ActivityRange,
+    limit: i64,
+    now: DateTime<Utc>,
+) -> rusqlite::Result<Vec<ActiveChannel>> {
+    let (start, _, _) = window(range, now);
+    let mut stmt = conn.prepare(
+        "SELECT conversation_id, SUM(count) AS total FROM message_activity
+         WHERE hour >= ?1
+         GROUP BY conversation_id
+         ORDER BY total DESC, conversation_id
+         LIMIT ?2",
+    )?;
+    let rows = stmt.query_map(
+        rusqlite::params![start.format(HOUR_FORMAT).to_string(), limit],
+        |row| {
+            Ok(ActiveChannel {
+                channel_id: row.get(0)?,
+                count: row.get(1)?,
+            })
+        },
+    )?;
+    Ok(rows.filter_map(|r| r.ok()).collect())
+}
+
+/// Local-only message counts for one channel or DM over `range`.
+pub async fn get_channel_activity(
+    channel_id: String,
+    range: ActivityRange,
+    state: &Arc<AppState>,
+) -> Result<ChannelActivity> {
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
+    Ok(activity(db.conn(), &channel_id, range, Utc::now())?)
+}
+
+/// Local-only "most active" list over `range` (default 10 entries). Covers
+/// channels and DMs alike; callers keep the ids they're showing.
+pub async fn get_most_active_channels(
+    range: ActivityRange,
+    limit: Option<i64>,
+    state: &Arc<AppState>,
+) -> Result<Vec<ActiveChannel>> {
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
+    Ok(most_active(
+        db.conn(),
+        range,
+        limit.unwrap_or(10).clamp(1, 100),
+        Utc::now(),
+    )?)
+}
@@ -0,0 +1,279 @@
+//! Admin export of every attachment in a group to a folder on disk.
+//!
+//! Same gate as the history export in `export.rs` (group admin, `allow_export`
+//! on) and the same source: after a normal ingest pass, each channel's `_att`
+//! manifests are read from this device's local `message` table, so the export
+//! holds what this device can decrypt. Each attachment comes through
+//! [`download_media`] (media cache first, resumable ranged download otherwise)
+//! and is written decrypted to `<dest>/<channel>/<YYYY-MM-DD>/<filename>`.
+//!
+//! Resumable by construction: a file already at its path whose SHA-256 matches
+//! the attachment's content hash is skipped, so running the export again after
+//! [`pause_attachment_export`], a crash or a dropped connection picks up where
+//! it stopped. Files are written via a temp name and renamed, so a partial file
+//! is never mistaken for a finished one. `manifest.json` at the root lists every
+//! exported file with its hash for integrity checks, and is rewritten on each
+//! run (including a paused one).
+
+use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::sync::Arc;
+
+use serde::Serialize;
+use sha2::{Digest, Sha256};
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+use super::edit_delete::parse_attachment_refs;
+use super::export::check_group_export_allowed;
+use super::ingest::ingest_channel_envelopes_inner;
+
+/// Set by [`pause_attachment_export`]; the running export stops after the
+/// current file. Cleared when an export starts.
+static PAUSE_REQUESTED: AtomicBool = AtomicBool::new(false);
+
+#[derive(Debug, Serialize)]
+pub struct AttachmentExportSummary {
+    /// Written by this run.
+    pub exported: usize,
+    /// Already on disk with the right hash from an earlier run.
+    pub skipped: usize,
+    /// Couldn't be downloaded or written; running the export again retries them.
+    pub failed: usize,
+    /// Stopped early by [`pause_attachment_export`].
+    pub paused: bool,
+    pub manifest_path: String,
+}
+
+#[derive(Debug, Serialize)]
+struct ManifestEntry {
+    /// Relative to the export root, `/`-separated.
+    path: String,
+    sha256: String,
+    size_bytes: u64,
+    channel_id: String,
+    channel_name: String,
+    message_id: String,
+    sent_at: String,
+}
+
+#[derive(Debug, Serialize)]
+struct Manifest {
+    group_id: String,
+    exported_at: String,
+    complete: bool,
+    files: Vec<ManifestEntry>,
+}
+
+/// Export every attachment in `group_id` into `dest_path` (created if missing).
+pub async fn export_group_attachments(
+    user_id: String,
+    group_id: String,
+    dest_path: String,
+    state: &Arc<AppState>,
+) -> Result<AttachmentExportSummary> {
+    check_group_export_allowed(&group_id, &user_id, state).await?;
+    PAUSE_REQUESTED.store(false, Ordering::SeqCst);
+
+    let root = PathBuf::from(&dest_path);
+    tokio::fs::create_dir_all(&root)
+        .await
+        .map_err(|e| Error::Other(anyhow::anyhow!("create {dest_path}: {e}")))?;
+
+    let channels: Vec<(String, String)> = {
+        let conn = state.remote_db.conn().await?;
+        let mut rows = conn
+            .query(
+                "SELECT id, name FROM channels \
+                 WHERE group_id = ?1 AND channel_type != 'voice' ORDER BY name, id",
+                libsql::params![group_id.clone()],
+            )
+            .await?;
+        let mut out = Vec::new();
+        while let Some(row) = rows.next().await? {
+            out.push((row.get(0)?, row.get(1)?));
+        }
+        out
+    };
+
+    let mut summary = AttachmentExportSummary {
+        exported: 0,
+        skipped: 0,
+        failed: 0,
+        paused: false,
+        manifest_path: root.join("manifest.json").to_string_lossy().into_owned(),
+    };
+    let mut entries: Vec<ManifestEntry> = Vec::new();
+    // Relative path → content hash claimed by this run, so two different
+    // files with the same name on the same day don't overwrite each other.
+    let mut claimed: HashMap<String, String> = HashMap::new();
+
+    'channels: for (channel_id, channel_name) in &channels {
+        if let Err(e) = ingest_channel_envelopes_inner(state, &user_id, channel_id).await {
+            // Export what's already local rather than failing the whole group.
+            eprintln!("[attachment_export] ingest {channel_id}: {e}");
+        }
+
+        let rows: Vec<(String, String, String)> = {
+            let guard = state.local_db.lock().await;
+            let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+            let mut stmt = db.conn().prepare(
+                "SELECT id, content, sent_at FROM message
+                 WHERE conversation_id = ?1 AND deleted_at IS NULL AND content LIKE '%\"_att\"%'
+                 ORDER BY sent_at ASC, id ASC",
+            )?;
+            let mapped = stmt.query_map(rusqlite::params![channel_id], |row| {
+                Ok((row.get(0)?, row.get(1)?, row.get(2)?))
+            })?;
+            mapped.collect::<rusqlite::Result<_>>()?
+        };
+
+        let channel_dir = safe_segment(channel_name, channel_id);
+        for (message_id, content, sent_at) in rows {
+            for att in parse_attachment_refs(&content) {
+                if PAUSE_REQUESTED.load(Ordering::SeqCst) {
+                    summary.paused = true;
+                    break 'channels;
+                }
+
+                let day = sent_at.get(..10).unwrap_or("undated");
+                let name = safe_segment(att.name.as_deref().unwrap_or(""), &att.content_hash);
+                let mut rel = format!("{channel_dir}/{day}/{name}");
+                if claimed.get(&rel).is_some_and(|h| *h != att.content_hash) {
+                    let prefix = att.content_hash.get(..8).unwrap_or(&att.content_hash);
+                    rel = format!("{channel_dir}/{day}/{prefix}-{name}");
+                }
+                if claimed.get(&rel) == Some(&att.content_hash) {
+                    // Same file attached twice that day — one copy is enough.
+                    continue;
+                }
+                claimed.insert(rel.clone(), att.content_hash.clone());
+
+                let path = root.join(&rel);
+                let size = match existing_size_if_matches(&path, &att.content_hash).await {
+                    Some(size) => {
+                        summary.skipped += 1;
+                        size
+                    }
+                    None => {
+                        match fetch_and_write(state, &att.r2_key, &att.content_hash, &path).await {
+                            Ok(size) => {
+                                summary.exported += 1;
+                                size
+                            }
+                            Err(e) => {
+                                eprintln!("[attachment_export] {rel}: {e}");
+                                summary.failed += 1;
+                                continue;
+                            }
+                        }
+                    }
+                };
+                entries.push(ManifestEntry {
+                    path: rel,
+                    sha256: att.content_hash.clone(),
+                    size_bytes: size,
+                    channel_id: channel_id.clone(),
+                    channel_name: channel_name.clone(),
+                    message_id: message_id.clone(),
+                    sent_at: sent_at.clone(),
+                });
+            }
+        }
+    }
+
+    let manifest = Manifest {
+        group_id,
+        exported_at: chrono::Utc::now().to_rfc3339(),
+        complete: !summary.paused && summary.failed == 0,
+        files: entries,
+    };
+    let json = serde_json::to_vec_pretty(&manifest)?;
+    tokio::fs::write(root.join("manifest.json"), json)
+        .await
+        .map_err(|e| Error::Other(anyhow::anyhow!("write manifest: {e}")))?;
+
+    Ok(summary)
+}
+
+/// Ask a running [`export_group_attachments`] to stop after the current file.
+/// Running the export again resumes it.
+pub fn pause_attachment_export() {
+    PAUSE_REQUESTED.store(true, Ordering::SeqCst);
+}
+
+/// The file's size when `path` already holds content hashing to `content_hash`.
+async fn existing_size_if_matches(path: &Path, content_hash: &str) -> Option<u64> {
+    let data = tokio::fs::read(path).await.ok()?;
+    (hex::encode(Sha256::digest(&data)) == content_hash).then_some(data.len() as u64)
+}
+
+/// Download + decrypt (hash-checked by [`download_media`]) and write
+/// atomically. Returns the plaintext size.
+async fn fetch_and_write(
+    state: &Arc<AppState>,
+    r2_key: &str,
+    content_hash: &str,
+    path: &Path,
+) -> Result<u64> {
+    let data =
+        crate::commands::r2::download_media(r2_key.to_string(), content_hash.to_string(), state)
+            .await?;
+    let write = async {
+        if let Some(parent) = path.parent() {
+            tokio::fs::create_dir_all(parent).await?;
+        }
+        let mut tmp = path.as_os_str().to_owned();
+        tmp.push(".part");
+        let tmp = PathBuf::from(tmp);
+        tokio::fs::write(&tmp, &data).await?;
+        if let Err(e) = tokio::fs::rename(&tmp, path).await {
+            let _ = tokio::fs::remove_file(&tmp).await;
+            return Err(e);
+        }
+        Ok::<_, std::io::Error>(())
+    };
+    write
+        .await
+        .map_err(|e| Error::Other(anyhow::anyhow!("write {}: {e}", path.display())))?;
+    Ok(data.len() as u64)
+}
+
+/// A single path segment from user-chosen text (channel or file name): no
+/// separators, no leading dots, nothing a filesystem chokes on. Empty results
+/// fall back to `fallback`.
+fn safe_segment(name: &str, fallback: &str) -> String {
+    let cleaned: String = name
+        .chars()
+        .map(|c| {
+            if c.is_control() || matches!(c, '/' | '\\' | ':' | '*' | '?' | '"' | '<' | '>' | '|') {
+                '_'
+            } else {
+                c
+            }
+        })
+        .collect();
+    let trimmed = cleaned.trim().trim_start_matches('.').trim();
+    if trimmed.is_empty() {
+        fallback.to_string()
+    } else {
+        trimmed.chars().take(120).collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn safe_segment_strips_separators_and_dots() {
+        assert_eq!(safe_segment("general", "c1"), "general");
+        assert_eq!(safe_segment("../../etc/passwd", "x"), "_.._etc_passwd");
+        assert_eq!(safe_segment("a\\b:c", "x"), "a_b_c");
+        assert_eq!(safe_segment("..", "fallback"), "fallback");
+        assert_eq!(safe_segment("   ", "fallback"), "fallback");
+        assert_eq!(safe_segment(".hidden", "x"), "hidden");
+    }
+}
@@ -0,0 +1,155 @@
+//! Cross-group announcement broadcast for admins.
+//!
+//! One announcement, many channels: each target goes through the ordinary
+//! [`send_message`] path, so it is MLS-encrypted under the target group's
+//! current epoch, posted as its own envelope, and pinged on that group's
+//! LiveKit room exactly like a hand-typed message. Channels in the same group
+//! share one MLS group but still get one envelope each, because the envelope
+//! is addressed by channel id.
+//!
+//! The caller must be an admin of every target's group. Targets are checked
+//! and sent one at a time; a failure on one channel is recorded in the
+//! returned [`BroadcastReport`] and the rest still go out. The report is the
+//! only record — nothing about the broadcast as a unit is stored remotely.
+
+use std::sync::Arc;
+
+use serde::{Deserialize, Serialize};
+use ulid::Ulid;
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+use super::send::send_message;
+
+/// Most channels one broadcast may target. Keeps a single call from fanning
+/// out into an unbounded run of MLS encrypts and Turso writes.
+pub const MAX_BROADCAST_TARGETS: usize = 25;
+
+/// Per-channel outcome of a broadcast. Exactly one of `message_id` / `error`
+/// is set.
+#[derive(Debug, Serialize, Deserialize)]
+pub struct BroadcastTargetResult {
+    pub channel_id: String,
+    pub group_id: Option<String>,
+    pub message_id: Option<String>,
+    pub error: Option<String>,
+}
+
+#[derive(Debug, Serialize, Deserialize)]
+pub struct BroadcastReport {
+    pub broadcast_id: String,
+    pub sent: usize,
+    pub failed: usize,
+    pub results: Vec<BroadcastTargetResult>,
+}
+
+/// Drop blanks and repeats from `channel_ids`, keeping first-seen order, and
+/// enforce [`MAX_BROADCAST_TARGETS`].
+pub(super) fn normalize_targets(channel_ids: Vec<String>) -> Result<Vec<String>> {
+    let mut out: Vec<String> = Vec::with_capacity(channel_ids.len());
+    for id in channel_ids {
+        let id = id.trim().to_string();
+        if id.is_empty() || out.contains(&id) {
+            continue;
+        }
+        out.push(id);
+    }
+    if out.is_empty() {
+        return Err(Error::Other(anyhow::anyhow!("select at least one channel")));
+    }
+    if out.len() > MAX_BROADCAST_TARGETS {
+        return Err(Error::Other(anyhow::anyhow!(
+            "a broadcast can target at most {MAX_BROADCAST_TARGETS} channels"
+        )));
+    }
+    Ok(out)
+}
+
+/// Resolve `channel_id` to its group and confirm `sender_id` administers it.
+async fn admin_group_for_channel(
+    channel_id: &str,
+    sender_id: &str,
+    state: &Arc<AppState>,
+) -> Result<String> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn.query(
+        "SELECT c.group_id, gm.role
+         FROM channels c
+         LEFT JOIN group_member gm ON gm.group_id = c.group_id AND gm.user_id = ?2
+         WHERE c.id = ?1",
+        libsql::params![channel_id.to_string(), sender_id.to_string()],
+    ).await?;
+    let Some(row) = rows.next().await? else {
+        return Err(Error::NotFound("channel".into()));
+    };
+    let group_id: String = row.get(0)?;
+    let role: Option<String> = row.get(1)?;
+    match role.as_deref() {
+        Some("admin") => Ok(group_id),
+        Some(_) => Err(Error::Other(anyhow::anyhow!("only admins can broadcast"))),
+        None => Err(Error::Other(anyhow::anyhow!("you are not a member of this group"))),
+    }
+}
+
+/// Send `content` to every channel in `channel_ids` and report per-channel
+/// delivery. Fails up front only on an empty announcement or an invalid
+/// target list; per-channel failures land in the report.
+pub async fn broadcast_announcement(
+    sender_id: String,
+    channel_ids: Vec<String>,
+    content: String,
+    sender_username: Option<String>,
+    state: &Arc<AppState>,
+) -> Result<BroadcastReport> {
+    state.check_not_outdated()?;
+    if content.trim().is_empty() {
+        return Err(Error::Other(anyhow::anyhow!("announcement is empty")));
+    }
+    let targets = normalize_targets(channel_ids)?;
+
+    let mut results = Vec::with_capacity(targets.len());
+    for channel_id in targets {
+        let group_id = match admin_group_for_channel(&channel_id, &sender_id, state).await {
+            Ok(g) => g,
+            Err(e) => {
+                results.push(BroadcastTargetResult {
+                    channel_id,
+                    group_id: None,
+                    message_id: None,
+                    error: Some(e.to_string()),
+                });
+                continue;
+            }
+        };
+        let sent = send_message(
+            channel_id.clone(),
+            sender_id.clone(),
+            content.clone(),
+            None,
+            sender_username.clone(),
+            state,
+        ).await;
+        let (message_id, error) = match sent {
+            Ok(msg) => (Some(msg.id), None),
+            Err(e) => {
+                eprintln!("[broadcast] send to channel {channel_id} failed: {e}");
+                (None, Some(e.to_string()))
+            }
+        };
+        results.push(BroadcastTargetResult {
+            channel_id,
+            group_id: Some(group_id),
+            message_id,
+            error,
+        });
+    }
+
+    let sent = results.iter().filter(|r| r.message_id.is_some()).count();
+    Ok(BroadcastReport {
+        broadcast_id: Ulid::new().to_string(),
+        sent,
+        failed: results.len() - sent,
+        results,
+    })
+}
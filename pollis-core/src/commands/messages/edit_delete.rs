@@ -60,7 +60,7 @@ pub async fn delete_message(
     // from the sealed envelope would misroute EVERY self-delete to the admin path.
     let local_row: Option<(String, String)> = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         db.conn()
             .query_row(
                 "SELECT sender_id, conversation_id FROM message WHERE id = ?1",
@@ -151,7 +151,7 @@ pub async fn delete_message(
         // what propagates the delete.
         let orphaned: Vec<AttachmentRef> = {
             let guard = state.local_db.lock().await;
-            let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+            let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
 
             let content: Option<String> = db.conn()
                 .query_row(
@@ -232,7 +232,7 @@ pub async fn delete_message(
     // single lock scope to avoid races with concurrent sends.
     let orphaned: Vec<AttachmentRef> = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
 
         let content: Option<String> = db.conn()
             .query_row(
@@ -368,7 +368,7 @@ pub async fn edit_message_as(
     // Encrypt the padded new content, repairing the local group if it is missing.
     let needs_repair = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         crate::commands::mls::try_mls_encrypt(
             db.conn(),
             &mls_group_id,
@@ -382,7 +382,7 @@ pub async fn edit_message_as(
 
     let ciphertext_remote = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         let plaintext = super::framing::pad(new_content.as_bytes());
         let mls_bytes = crate::commands::mls::try_mls_encrypt(db.conn(), &mls_group_id, &plaintext)
             .ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!(
@@ -450,7 +450,7 @@ async fn send_redaction_message(
     // it is missing (a wiped local DB), mirroring `edit_message`.
     let needs_repair = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         crate::commands::mls::try_mls_encrypt(
             db.conn(),
             &mls_group_id,
@@ -464,7 +464,7 @@ async fn send_redaction_message(
 
     let ciphertext_remote = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         let plaintext = super::framing::pad_redaction(target_message_id);
         let mls_bytes = crate::commands::mls::try_mls_encrypt(db.conn(), &mls_group_id, &plaintext)
             .ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!(
@@ -492,15 +492,17 @@ async fn send_redaction_message(
 
 /// Attachment identifier extracted from a message's plaintext JSON payload.
 #[derive(Debug, Clone)]
-struct AttachmentRef {
-    content_hash: String,
-    r2_key: String,
+pub(super) struct AttachmentRef {
+    pub(super) content_hash: String,
+    pub(super) r2_key: String,
+    /// The sender's original filename, when the manifest carries one.
+    pub(super) name: Option<String>,
 }
 
 /// Parse the `_att` array out of a message's local plaintext content and
 /// return the (content_hash, r2_key) pairs. Returns an empty Vec for plain
 /// text messages, malformed JSON, or any missing fields.
-fn parse_attachment_refs(raw: &str) -> Vec<AttachmentRef> {
+pub(super) fn parse_attachment_refs(raw: &str) -> Vec<AttachmentRef> {
     if !raw.starts_with('{') {
         return Vec::new();
     }
@@ -517,7 +519,8 @@ fn parse_attachment_refs(raw: &str) -> Vec<AttachmentRef> {
             if hash.is_empty() || key.is_empty() {
                 return None;
             }
-            Some(AttachmentRef { content_hash: hash, r2_key: key })
+            let name = a.get("name").and_then(|n| n.as_str()).map(str::to_string);
+            Some(AttachmentRef { content_hash: hash, r2_key: key, name })
         })
         .collect()
 }
@@ -683,7 +686,7 @@ pub async fn edit_message(
     // transparently repair and retry.
     let needs_repair = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         crate::commands::mls::try_mls_encrypt(db.conn(), &mls_group_id, new_content.as_bytes()).is_none()
     };
 
@@ -699,7 +702,7 @@ pub async fn edit_message(
 
     let ciphertext_remote = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
 
         // Size padding (issue #331 v2, §4.1) — same scheme as the send path:
         // pad TEXT edits to a size bucket; leave attachment edits unpadded.
@@ -0,0 +1,355 @@
+//! Admin export of a channel's message history as CSV, JSON or a
+//! Matrix-compatible event stream (see `matrix.rs`).
+//!
+//! The export is built entirely from this device's decrypt-once cache (the
+//! local `message` table) after a normal ingest pass, so it contains exactly
+//! what this device can read: messages from before the device joined, or
+//! already evicted by the retention window, are not in it. Nothing is
+//! re-decrypted and nothing plaintext leaves the device — the caller gets
+//! the file contents back and decides where to save them.
+//!
+//! Gated on two remote facts: the caller is an admin of the channel's group,
+//! and the group's `allow_export` policy (migration 000011) is on. The DS
+//! can't enforce the export itself (it never sees plaintext); it only
+//! controls who may flip the policy.
+
+use std::sync::Arc;
+
+use serde::Serialize;
+
+use crate::commands::groups::{read_group_events, sync_group_events, GroupEvent, CHANNEL_CREATED};
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+use super::ingest::ingest_channel_envelopes_inner;
+use super::matrix::{render_matrix, MatrixMember, MatrixReaction};
+use super::read::attach_sender_usernames_local;
+use super::types::ChannelMessage;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub(super) enum ExportFormat {
+    Csv,
+    Json,
+    Matrix,
+}
+
+impl ExportFormat {
+    pub(super) fn parse(format: &str) -> Result<Self> {
+        match format.trim().to_ascii_lowercase().as_str() {
+            "csv" => Ok(Self::Csv),
+            "json" => Ok(Self::Json),
+            "matrix" => Ok(Self::Matrix),
+            other => Err(Error::Other(anyhow::anyhow!("unsupported export format '{other}'"))),
+        }
+    }
+}
+
+/// One exported row. Attachment payloads are split into their caption
+/// (`content`) and file names; plain messages have no attachments. A `system`
+/// row is a group timeline notice ("alice joined the group"), not a message:
+/// it has no sender.
+#[derive(Debug, Serialize)]
+pub(super) struct ExportedMessage {
+    pub id: String,
+    pub sent_at: String,
+    pub sender_id: String,
+    pub sender_username: Option<String>,
+    pub content: String,
+    pub attachments: Vec<String>,
+    pub reply_to_id: Option<String>,
+    pub edited_at: Option<String>,
+    pub system: bool,
+}
+
+impl ExportedMessage {
+    pub(super) fn from_message(m: ChannelMessage) -> Self {
+        let raw = m.content.unwrap_or_default();
+        let (content, attachments) = split_attachments(&raw).unwrap_or((raw, Vec::new()));
+        Self {
+            id: m.id,
+            sent_at: m.sent_at,
+            sender_id: m.sender_id,
+            sender_username: m.sender_username,
+            content,
+            attachments,
+            reply_to_id: m.reply_to_id,
+            edited_at: m.edited_at,
+            system: false,
+        }
+    }
+
+    pub(super) fn from_event(e: GroupEvent) -> Self {
+        Self {
+            content: e.describe(),
+            id: e.id,
+            sent_at: e.occurred_at,
+            sender_id: String::new(),
+            sender_username: None,
+            attachments: Vec::new(),
+            reply_to_id: None,
+            edited_at: None,
+            system: true,
+        }
+    }
+}
+
+/// `{"_att":[{name,…}],"_txt":"caption"}` → (caption, file names).
+pub(super) fn split_attachments(content: &str) -> Option<(String, Vec<String>)> {
+    if !content.starts_with('{') {
+        return None;
+    }
+    let parsed: serde_json::Value = serde_json::from_str(content).ok()?;
+    let atts = parsed.get("_att")?.as_array()?;
+    let caption = parsed.get("_txt").and_then(|v| v.as_str()).unwrap_or("").to_string();
+    let names = atts
+        .iter()
+        .filter_map(|a| a.get("name").and_then(|v| v.as_str()))
+        .map(str::to_string)
+        .collect();
+    Some((caption, names))
+}
+
+/// Quote a CSV field per RFC 4180. Fields a spreadsheet would evaluate as a
+/// formula (leading `=`, `+`, `-`, `@`, tab, CR) get a `'` prefix so opening
+/// an export can't run something a channel member typed.
+pub(super) fn csv_field(value: &str) -> String {
+    let guarded = if value.starts_with(['=', '+', '-', '@', '\t', '\r']) {
+        format!("'{value}")
+    } else {
+        value.to_string()
+    };
+    if guarded.contains([',', '"', '\n', '\r']) {
+        format!("\"{}\"", guarded.replace('"', "\"\""))
+    } else {
+        guarded
+    }
+}
+
+/// CSV / JSON rendering. Matrix needs more than the flattened rows and goes
+/// through [`render_matrix`] instead.
+pub(super) fn render(format: ExportFormat, rows: &[ExportedMessage]) -> Result<String> {
+    match format {
+        ExportFormat::Json => Ok(serde_json::to_string_pretty(rows)?),
+        ExportFormat::Matrix => Err(Error::Other(anyhow::anyhow!(
+            "matrix export needs channel context; use render_matrix"
+        ))),
+        ExportFormat::Csv => {
+            let mut out = String::from(
+                "id,sent_at,sender_id,sender_username,content,attachments,reply_to_id,edited_at,system\r\n",
+            );
+            for r in rows {
+                let attachments = r.attachments.join("; ");
+                let fields = [
+                    r.id.as_str(),
+                    r.sent_at.as_str(),
+                    r.sender_id.as_str(),
+                    r.sender_username.as_deref().unwrap_or(""),
+                    r.content.as_str(),
+                    attachments.as_str(),
+                    r.reply_to_id.as_deref().unwrap_or(""),
+                    r.edited_at.as_deref().unwrap_or(""),
+                    if r.system { "true" } else { "false" },
+                ];
+                let line: Vec<String> = fields.iter().map(|f| csv_field(f)).collect();
+                out.push_str(&line.join(","));
+                out.push_str("\r\n");
+            }
+            Ok(out)
+        }
+    }
+}
+
+/// Confirm `user_id` may export `channel_id`: admin of its group, and the
+/// group's export policy is on. Returns the group id.
+async fn check_export_allowed(
+    channel_id: &str,
+    user_id: &str,
+    state: &Arc<AppState>,
+) -> Result<String> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn.query(
+        "SELECT gm.role, g.allow_export, c.group_id
+         FROM channels c
+         JOIN groups g ON g.id = c.group_id
+         LEFT JOIN group_member gm ON gm.group_id = c.group_id AND gm.user_id = ?2
+         WHERE c.id = ?1",
+        libsql::params![channel_id.to_string(), user_id.to_string()],
+    ).await?;
+    let Some(row) = rows.next().await? else {
+        return Err(Error::NotFound("channel".into()));
+    };
+    export_permission(row.get(0)?, row.get(1)?)?;
+    Ok(row.get(2)?)
+}
+
+/// [`check_export_allowed`] for a whole group (the attachment export).
+pub(super) async fn check_group_export_allowed(
+    group_id: &str,
+    user_id: &str,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn.query(
+        "SELECT gm.role, g.allow_export
+         FROM groups g
+         LEFT JOIN group_member gm ON gm.group_id = g.id AND gm.user_id = ?2
+         WHERE g.id = ?1",
+        libsql::params![group_id.to_string(), user_id.to_string()],
+    ).await?;
+    let Some(row) = rows.next().await? else {
+        return Err(Error::NotFound("group".into()));
+    };
+    export_permission(row.get(0)?, row.get(1)?)
+}
+
+/// The caller's `role` in the group (`None` = not a member) and the group's
+/// `allow_export` flag → may they export?
+fn export_permission(role: Option<String>, allow_export: i64) -> Result<()> {
+    match role.as_deref() {
+        Some("admin") => {}
+        Some(_) => return Err(Error::Other(anyhow::anyhow!("only group admins can export messages"))),
+        None => return Err(Error::Other(anyhow::anyhow!("you are not a member of this group"))),
+    }
+    if allow_export == 0 {
+        return Err(Error::Other(anyhow::anyhow!("message export is disabled for this group")));
+    }
+    Ok(())
+}
+
+/// Channel name, current members (with usernames) and the reactions on
+/// `messages`, for the Matrix export.
+async fn matrix_context(
+    channel_id: &str,
+    messages: &[ChannelMessage],
+    state: &Arc<AppState>,
+) -> Result<(String, Vec<MatrixMember>, Vec<MatrixReaction>)> {
+    let conn = state.remote_db.conn().await?;
+
+    let mut rows = conn.query(
+        "SELECT name FROM channels WHERE id = ?1",
+        libsql::params![channel_id.to_string()],
+    ).await?;
+    let channel_name: String = match rows.next().await? {
+        Some(row) => row.get(0)?,
+        None => return Err(Error::NotFound("channel".into())),
+    };
+
+    let mut members = Vec::new();
+    let mut rows = conn.query(
+        "SELECT gm.user_id, u.username, gm.joined_at
+         FROM channels c
+         JOIN group_member gm ON gm.group_id = c.group_id
+         LEFT JOIN users u ON u.id = gm.user_id
+         WHERE c.id = ?1
+         ORDER BY gm.joined_at ASC",
+        libsql::params![channel_id.to_string()],
+    ).await?;
+    while let Some(row) = rows.next().await? {
+        members.push(MatrixMember {
+            user_id: row.get(0)?,
+            username: row.get(1)?,
+            joined_at: row.get(2)?,
+        });
+    }
+
+    // Chunked so the IN list stays well under SQLite's parameter limit.
+    let mut reactions = Vec::new();
+    for chunk in messages.chunks(200) {
+        let placeholders = (1..=chunk.len())
+            .map(|i| format!("?{i}"))
+            .collect::<Vec<_>>()
+            .join(",");
+        let sql = format!(
+            "SELECT id, message_id, user_id, emoji, created_at FROM message_reaction
+             WHERE message_id IN ({placeholders}) ORDER BY created_at ASC"
+        );
+        let params: Vec<String> = chunk.iter().map(|m| m.id.clone()).collect();
+        let mut rows = conn.query(&sql, libsql::params_from_iter(params)).await?;
+        while let Some(row) = rows.next().await? {
+            reactions.push(MatrixReaction {
+                id: row.get(0)?,
+                message_id: row.get(1)?,
+                user_id: row.get(2)?,
+                emoji: row.get(3)?,
+                created_at: row.get(4)?,
+            });
+        }
+    }
+
+    Ok((channel_name, members, reactions))
+}
+
+/// Export a channel's history as `format` (`"csv"`, `"json"` or `"matrix"`),
+/// oldest first. `from` / `to` are optional RFC 3339 bounds on `sent_at` — `from`
+/// inclusive, `to` exclusive. Deleted messages are left out.
+pub async fn export_channel_messages(
+    user_id: String,
+    channel_id: String,
+    format: String,
+    from: Option<String>,
+    to: Option<String>,
+    state: &Arc<AppState>,
+) -> Result<String> {
+    let format = ExportFormat::parse(&format)?;
+    let group_id = check_export_allowed(&channel_id, &user_id, state).await?;
+
+    ingest_channel_envelopes_inner(state, &user_id, &channel_id).await?;
+
+    let mut messages: Vec<ChannelMessage> = {
+        let guard = state.local_db.lock().await;
+        let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+        let mut stmt = db.conn().prepare(
+            "SELECT id, conversation_id, sender_id, content, reply_to_id, sent_at, edited_at
+             FROM message
+             WHERE conversation_id = ?1
+               AND deleted_at IS NULL
+               AND (?2 IS NULL OR sent_at >= ?2)
+               AND (?3 IS NULL OR sent_at < ?3)
+             ORDER BY sent_at ASC, id ASC",
+        )?;
+        let mapped = stmt.query_map(rusqlite::params![channel_id, from, to], |row| {
+            Ok(ChannelMessage {
+                id: row.get(0)?,
+                conversation_id: row.get(1)?,
+                sender_id: row.get(2)?,
+                sender_username: None,
+                ciphertext: String::new(),
+                content: row.get(3)?,
+                reply_to_id: row.get(4)?,
+                sent_at: row.get(5)?,
+                edited_at: row.get(6)?,
+                deleted_at: None,
+                sender_muted: false,
+            })
+        })?;
+        mapped.collect::<rusqlite::Result<_>>()?
+    };
+    attach_sender_usernames_local(state, &mut messages).await?;
+
+    if format == ExportFormat::Matrix {
+        let (channel_name, members, reactions) = matrix_context(&channel_id, &messages, state).await?;
+        return render_matrix(&channel_id, &channel_name, &members, &messages, &reactions);
+    }
+
+    // Timeline notices in the same window: the group's joins and departures,
+    // and this channel's own creation. Best effort — an export without them
+    // is still a complete message history.
+    if let Err(e) = sync_group_events(state, &group_id).await {
+        eprintln!("[export] group event sync failed for {group_id}: {e}");
+    }
+    let events = {
+        let guard = state.local_db.lock().await;
+        let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+        read_group_events(db.conn(), &group_id, from.as_deref(), to.as_deref())?
+    };
+
+    let mut rows: Vec<ExportedMessage> = messages.into_iter().map(ExportedMessage::from_message).collect();
+    rows.extend(
+        events
+            .into_iter()
+            .filter(|e| e.kind != CHANNEL_CREATED || e.subject_id == channel_id)
+            .map(ExportedMessage::from_event),
+    );
+    rows.sort_by(|a, b| a.sent_at.cmp(&b.sent_at));
+    render(format, &rows)
+}
@@ -0,0 +1,108 @@
+//! Filtered timelines: only media, only links, only one member, only unread —
+//! any combination, read from the local decrypted `message` cache. No network
+//! and no ingest; like `read_channel_messages`, callers ingest separately.
+//!
+//! The filters are SQL predicates over `message.content`, so they page with
+//! the same `(sent_at, id)` cursor as the plain timeline. Attachments are the
+//! `{"_att":[…]}` payloads the composer sends; a link is `http://`,
+//! `https://` or `www.` in a text message (attachment payloads carry blob URLs
+//! of their own, so they never count as links).
+
+use std::sync::Arc;
+
+use rusqlite::types::Value;
+
+use crate::error::Result;
+use crate::state::AppState;
+
+use super::read::{attach_sender_usernames_local, flag_muted_senders, row_to_message};
+use super::types::{ChannelMessage, MessageCursor, MessageFilter, MessagePage};
+
+/// `LIKE` pattern for an attachment payload (`_` escaped with `\`).
+const ATTACHMENT_PREFIX: &str = "{\"\\_att\":%";
+
+/// One newest-first page of `conversation_id` matching `filter`.
+pub(super) fn filtered_page(
+    conn: &rusqlite::Connection,
+    conversation_id: &str,
+    filter: &MessageFilter,
+    cursor: Option<&MessageCursor>,
+    limit: i64,
+) -> rusqlite::Result<Vec<ChannelMessage>> {
+    let mut sql = String::from(
+        "SELECT id, conversation_id, sender_id, ciphertext, content, reply_to_id, sent_at, edited_at, deleted_at
+         FROM message
+         WHERE conversation_id = ?1 AND deleted_at IS NULL AND content IS NOT NULL",
+    );
+    let mut params: Vec<Value> = vec![Value::Text(conversation_id.to_string())];
+    // Bind `value` as the next parameter and return its `?N` placeholder.
+    fn bind(params: &mut Vec<Value>, value: Value) -> String {
+        params.push(value);
+        format!("?{}", params.len())
+    }
+
+    if filter.media {
+        let p = bind(&mut params, Value::Text(ATTACHMENT_PREFIX.into()));
+        sql.push_str(&format!(" AND content LIKE {p} ESCAPE '\\'"));
+    }
+    if filter.links {
+        let p = bind(&mut params, Value::Text(ATTACHMENT_PREFIX.into()));
+        sql.push_str(&format!(
+            " AND content NOT LIKE {p} ESCAPE '\\'
+              AND (content LIKE '%http://%' OR content LIKE '%https://%' OR content LIKE '%www.%')"
+        ));
+    }
+    if let Some(sender) = filter.sender_id.as_deref() {
+        let p = bind(&mut params, Value::Text(sender.to_string()));
+        sql.push_str(&format!(" AND sender_id = {p}"));
+    }
+    if let Some(n) = filter.unread {
+        let p = bind(&mut params, Value::Integer(n.max(0)));
+        sql.push_str(&format!(
+            " AND id IN (SELECT id FROM message WHERE conversation_id = ?1
+                         ORDER BY sent_at DESC, id DESC LIMIT {p})"
+        ));
+    }
+    if let Some(c) = cursor {
+        let at = bind(&mut params, Value::Text(c.sent_at.clone()));
+        let id = bind(&mut params, Value::Text(c.id.clone()));
+        sql.push_str(&format!(" AND (sent_at < {at} OR (sent_at = {at} AND id < {id}))"));
+    }
+    let p = bind(&mut params, Value::Integer(limit));
+    sql.push_str(&format!(" ORDER BY sent_at DESC, id DESC LIMIT {p}"));
+
+    let mut stmt = conn.prepare(&sql)?;
+    let rows = stmt.query_map(rusqlite::params_from_iter(params), row_to_message)?;
+    Ok(rows.filter_map(|r| r.ok()).collect())
+}
+
+/// Local-only read of a filtered page of a channel or DM. Same paging as
+/// `read_channel_messages`: pass `next_cursor` back for the next older page.
+pub async fn read_filtered_messages(
+    conversation_id: String,
+    filter: MessageFilter,
+    limit: Option<i64>,
+    cursor: Option<MessageCursor>,
+    state: &Arc<AppState>,
+) -> Result<MessagePage> {
+    let limit = limit.unwrap_or(50);
+    let mut messages = {
+        let guard = state.local_db.lock().await;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
+        let mut page = filtered_page(db.conn(), &conversation_id, &filter, cursor.as_ref(), limit)?;
+        flag_muted_senders(db.conn(), &mut page);
+        page
+    };
+    attach_sender_usernames_local(state, &mut messages).await?;
+
+    let next_cursor = if messages.len() == limit as usize {
+        messages.last().map(|m| MessageCursor {
+            sent_at: m.sent_at.clone(),
+            id: m.id.clone(),
+        })
+    } else {
+        None
+    };
+
+    Ok(MessagePage { messages, next_cursor })
+}
@@ -5,6 +5,8 @@ use std::sync::Arc;
 use crate::error::Result;
 use crate::state::AppState;
 
+use super::types::IngestedMessages;
+
 /// One row from `message_envelope`:
 /// `(id, sender_id, ciphertext, reply_to_id, target_message_id, sent_at, type)`.
 type EnvelopeRow = (
@@ -34,7 +36,7 @@ pub async fn ingest_channel_envelopes_inner(
     state: &Arc<AppState>,
     user_id: &str,
     channel_id: &str,
-) -> Result<()> {
+) -> Result<Vec<IngestedMessages>> {
     let conn = state.remote_db.conn().await?;
 
     // Single round-trip: resolve mls_group_id AND confirm membership. Returns
@@ -50,7 +52,7 @@ pub async fn ingest_channel_envelopes_inner(
         ).await?;
         match rows.next().await? {
             Some(row) => row.get::<String>(0)?,
-            None => return Ok(()),
+            None => return Ok(Vec::new()),
         }
     };
 
@@ -66,7 +68,7 @@ pub async fn ingest_channel_envelopes_inner(
     }
 
     // Catch up the whole group (all sibling channels) in one epoch-stepped pass.
-    catch_up_mls_group_interleaved(state, &mls_group_id, user_id).await
+    catch_up_mls_group_reporting(state, &mls_group_id, user_id).await
 }
 
 /// Group-level interleaved catch-up: advance the SHARED MLS group for
@@ -107,6 +109,18 @@ pub async fn catch_up_mls_group_interleaved(
     mls_group_id: &str,
     user_id: &str,
 ) -> Result<()> {
+    catch_up_mls_group_reporting(state, mls_group_id, user_id).await?;
+    Ok(())
+}
+
+/// [`catch_up_mls_group_interleaved`], reporting who sent the ordinary messages
+/// it stored for the first time — what the ingest commands hand the realtime
+/// handler so a ping from a muted member stays quiet.
+async fn catch_up_mls_group_reporting(
+    state: &Arc<AppState>,
+    mls_group_id: &str,
+    user_id: &str,
+) -> Result<Vec<IngestedMessages>> {
     let conn = state.remote_db.conn().await?;
 
     // Enumerate every conversation bound to this MLS group. Two shapes (mirrors
@@ -135,6 +149,20 @@ pub async fn catch_up_mls_group_interleaved(
         out
     };
 
+    // Remember each channel's group for local reads (`read::flag_muted_senders`).
+    if !is_dm {
+        let guard = state.local_db.lock().await;
+        if let Some(db) = guard.as_ref() {
+            for cid in &conversation_ids {
+                let _ = db.conn().execute(
+                    "INSERT OR REPLACE INTO conversation_group (conversation_id, group_id)
+                     VALUES (?1, ?2)",
+                    rusqlite::params![cid, mls_group_id],
+                );
+            }
+        }
+    }
+
     let device_id = state.device_id.lock().await.clone();
     let did_param = device_id.clone().unwrap_or_default();
 
@@ -168,13 +196,19 @@ pub async fn catch_up_mls_group_interleaved(
                 row.get::<String>(6)?,
             ));
         }
+        // Our own envelopes in this batch are delivery canaries.
+        let fetched: Vec<(&str, &str, &str)> = envs
+            .iter()
+            .map(|e| (e.0.as_str(), e.2.as_str(), e.5.as_str()))
+            .collect();
+        crate::commands::delivery_canary::observe_fetch(cid, &fetched);
         per_conv.push((cid.clone(), envs));
     }
 
     // Drive the shared group's replay once, decrypting each conversation's
     // envelopes as the group reaches their epoch. Returns the per-conversation
     // watermark each device may advance to.
-    let watermarks: Vec<(String, Option<String>)> =
+    let (watermarks, ingested) =
         ingest_group_envelopes_interleaved(state, user_id, mls_group_id, &per_conv).await?;
 
     // Advance each conversation's watermark + run envelope GC through the
@@ -200,7 +234,7 @@ pub async fn catch_up_mls_group_interleaved(
         }
     }
 
-    Ok(())
+    Ok(ingested)
 }
 
 /// Core of [`catch_up_mls_group_interleaved`]: drive the shared MLS group's
@@ -224,12 +258,15 @@ pub async fn catch_up_mls_group_interleaved(
 /// an undecryptable-for-now message is re-fetched on a later pass instead of
 /// being skipped. Pre-join messages do NOT stop the watermark; the Delivery
 /// Service's envelope GC is the backstop that removes them from the fetch set.
+///
+/// Also returns, per conversation with any, the senders of the ordinary
+/// messages stored for the first time this pass.
 async fn ingest_group_envelopes_interleaved(
     state: &Arc<AppState>,
     user_id: &str,
     mls_group_id: &str,
     per_conv: &[(String, Vec<EnvelopeRow>)],
-) -> Result<Vec<(String, Option<String>)>> {
+) -> Result<(Vec<(String, Option<String>)>, Vec<IngestedMessages>)> {
     // Pre-parse each message/edit envelope's MLS epoch across ALL bound
     // conversations (delete/unknown carry none) and index `(conv_idx, env_idx)`
     // by epoch so the per-epoch hook decrypts exactly the ones sealed at the
@@ -263,17 +300,21 @@ async fn ingest_group_envelopes_interleaved(
     // watermark loop. Runs even with zero envelopes so the group still advances
     // to head (the cold-launch sweep guarantee).
     let mut max_fired_epoch: Option<u64> = None;
+    // new_senders[ci]: senders of conversation ci's newly stored messages.
+    let mut new_senders: Vec<Vec<String>> = vec![Vec::new(); per_conv.len()];
     {
         let mut on_epoch = |conn: &rusqlite::Connection, epoch: u64| {
             max_fired_epoch = Some(max_fired_epoch.map_or(epoch, |m| m.max(epoch)));
             if let Some(indices) = by_epoch.get(&epoch) {
                 for &(ci, ei) in indices {
-                    decrypt_and_persist_one(
+                    if let Some(sender) = decrypt_and_persist_one(
                         conn,
                         &per_conv[ci].0,
                         mls_group_id,
                         &per_conv[ci].1[ei],
-                    );
+                    ) {
+                        new_senders[ci].push(sender);
+                    }
                 }
             }
         };
@@ -297,7 +338,7 @@ async fn ingest_group_envelopes_interleaved(
         let guard = state.local_db.lock().await;
         let db = guard
             .as_ref()
-            .ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+            .ok_or(crate::error::Error::NotSignedIn)?;
         for (_cid, envs) in per_conv {
             for (_, _, _, _, target_id, _, env_type) in envs {
                 if env_type == "delete" {
@@ -338,7 +379,16 @@ async fn ingest_group_envelopes_interleaved(
             super::watermark::next_watermark(&items, max_fired_epoch).map(str::to_string);
         out.push((cid.clone(), watermark));
     }
-    Ok(out)
+    let ingested = per_conv
+        .iter()
+        .zip(new_senders)
+        .filter(|(_, senders)| !senders.is_empty())
+        .map(|((cid, _), sender_ids)| IngestedMessages {
+            conversation_id: cid.clone(),
+            sender_ids,
+        })
+        .collect();
+    Ok((out, ingested))
 }
 
 /// Decrypt one `message` or `edit` envelope at the group's CURRENT epoch and
@@ -348,12 +398,14 @@ async fn ingest_group_envelopes_interleaved(
 /// Infallible: a failed decrypt or a transient DB error simply leaves nothing
 /// persisted (the envelope stays in `message_envelope` for a later retry), the
 /// same outcome the watermark logic accounts for.
+///
+/// Returns the sender when this stored an ordinary message for the first time.
 fn decrypt_and_persist_one(
     conn: &rusqlite::Connection,
     conversation_id: &str,
     mls_group_id: &str,
     env: &EnvelopeRow,
-) {
+) -> Option<String> {
     // `sender_id` (the server-writable envelope column) is intentionally NOT
     // read for attribution — the sender is taken from the MLS credential inside
     // the ciphertext (sealed sender, `docs/metadata-minimization-design.md` §2).
@@ -371,13 +423,13 @@ fn decrypt_and_persist_one(
                 .flatten()
                 .unwrap_or(false);
             if exists {
-                return;
+                return None;
             }
             let Some(bytes) = ciphertext
                 .strip_prefix("mls:")
                 .and_then(|h| hex::decode(h).ok())
             else {
-                return;
+                return None;
             };
             // Attribute from the MLS-authenticated credential inside the
             // ciphertext, NOT the server-writable `message_envelope.sender_id`
@@ -388,7 +440,7 @@ fn decrypt_and_persist_one(
             else {
                 // Decrypt failed — leave the envelope in message_envelope for a
                 // future retry; the watermark is computed to not skip past it.
-                return;
+                return None;
             };
             match super::framing::classify(&plain) {
                 // "Delete for everyone" (E2EE redaction). Honor it ONLY when the
@@ -425,12 +477,17 @@ fn decrypt_and_persist_one(
                 // attachment envelopes, so old and new clients interoperate.
                 super::framing::Frame::Text(plaintext) => {
                     if let Ok(text) = String::from_utf8(plaintext) {
-                        let _ = conn.execute(
+                        let inserted = conn.execute(
                             "INSERT OR IGNORE INTO message
                              (id, conversation_id, sender_id, ciphertext, content, reply_to_id, sent_at)
                              VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)",
                             rusqlite::params![id, conversation_id, cred_sender, bytes, text, reply_to_id, sent_at],
                         );
+                        // First time this device sees it: time send → decrypt.
+                        if matches!(inserted, Ok(1)) {
+                            crate::commands::delivery_latency::record_delivery(sent_at);
+                            return Some(cred_sender);
+                        }
                     }
                 }
             }
@@ -450,7 +507,7 @@ fn decrypt_and_persist_one(
                     .and_then(|h| hex::decode(h).ok())
                     .and_then(|b| crate::commands::mls::try_mls_decrypt(conn, mls_group_id, &b))
                 else {
-                    return;
+                    return None;
                 };
                 let author: Option<String> = conn
                     .query_row(
@@ -462,7 +519,7 @@ fn decrypt_and_persist_one(
                     .ok()
                     .flatten();
                 if author.as_deref() != Some(cred_sender.as_str()) {
-                    return;
+                    return None;
                 }
                 // Strip size padding (§4.1); no-op for legacy/unpadded edits.
                 if let Ok(text) = String::from_utf8(super::framing::strip(&plain)) {
@@ -477,6 +534,7 @@ fn decrypt_and_persist_one(
         }
         _ => {}
     }
+    None
 }
 
 /// Frontend-triggerable ingest for a channel. Used by LiveKit real-time hints
@@ -486,7 +544,7 @@ pub async fn ingest_channel_envelopes(
     user_id: String,
     channel_id: String,
     state: &Arc<AppState>,
-) -> Result<()> {
+) -> Result<Vec<IngestedMessages>> {
     ingest_channel_envelopes_inner(state, &user_id, &channel_id).await
 }
 
@@ -496,7 +554,7 @@ pub async fn ingest_dm_envelopes_inner(
     state: &Arc<AppState>,
     user_id: &str,
     dm_channel_id: &str,
-) -> Result<()> {
+) -> Result<Vec<IngestedMessages>> {
     let conn = state.remote_db.conn().await?;
 
     let is_member: bool = {
@@ -509,7 +567,7 @@ pub async fn ingest_dm_envelopes_inner(
         rows.next().await?.is_some()
     };
     if !is_member {
-        return Ok(());
+        return Ok(Vec::new());
     }
 
     let device_id = state.device_id.lock().await.clone();
@@ -546,7 +604,7 @@ pub async fn ingest_dm_envelopes_inner(
     // A DM's MLS group backs exactly one conversation (mls_group_id ==
     // dm_channel_id), so the group-level catch-up degenerates to the single-
     // conversation case — same interleaved decrypt + watermark + GC path.
-    catch_up_mls_group_interleaved(state, dm_channel_id, user_id).await
+    catch_up_mls_group_reporting(state, dm_channel_id, user_id).await
 }
 
 /// Frontend-triggerable ingest for a DM channel.
@@ -554,6 +612,6 @@ pub async fn ingest_dm_envelopes(
     user_id: String,
     dm_channel_id: String,
     state: &Arc<AppState>,
-) -> Result<()> {
+) -> Result<Vec<IngestedMessages>> {
     ingest_dm_envelopes_inner(state, &user_id, &dm_channel_id).await
 }
@@ -0,0 +1,266 @@
+//! Matrix-compatible rendering for channel exports (`format = "matrix"`).
+//!
+//! Produces a room event stream in Matrix client-server event shape —
+//! `m.room.member` joins, `m.room.message` messages (with `m.in_reply_to`
+//! replies) and `m.reaction` annotations — so a bridge or migration tool can
+//! replay a channel into a Matrix room. The export is pure rendering over data
+//! `export.rs` has already gathered; nothing here touches the network.
+//!
+//! Ids use the placeholder server [`MATRIX_SERVER`]: `@<username>:pollis.invalid`
+//! for users, `!<channel id>:pollis.invalid` for the room and `$<message id>`
+//! for events. Importers remap them to their own homeserver.
+//!
+//! Attachments can't be carried inline — Matrix media lives behind `mxc://`
+//! URLs on the target homeserver. Each attachment becomes its own media event
+//! without a `url`, plus an entry in the top-level `attachments` manifest with
+//! the Pollis object key and content hash, so the importer can fetch the blob
+//! through Pollis, upload it, and fill the `url` in.
+//!
+//! Only current members get a membership event (Pollis keeps no leave
+//! history), and edits are exported as their final text with the edit time
+//! under `io.pollis.edited_at`.
+
+use std::collections::HashMap;
+
+use serde::Serialize;
+use serde_json::{json, Value};
+
+use crate::error::Result;
+
+use super::types::ChannelMessage;
+
+/// Placeholder homeserver for exported ids. `.invalid` never resolves, so an
+/// un-remapped import can't accidentally point at a real server.
+const MATRIX_SERVER: &str = "pollis.invalid";
+
+/// Identifies the document shape for importers.
+const FORMAT_TAG: &str = "io.pollis.matrix_export.v1";
+
+pub(super) struct MatrixMember {
+    pub user_id: String,
+    pub username: Option<String>,
+    pub joined_at: String,
+}
+
+pub(super) struct MatrixReaction {
+    pub id: String,
+    pub message_id: String,
+    pub user_id: String,
+    pub emoji: String,
+    pub created_at: String,
+}
+
+/// One attachment the importer must re-upload before replaying its event.
+#[derive(Debug, Serialize)]
+struct AttachmentManifestEntry {
+    event_id: String,
+    key: String,
+    hash: Option<String>,
+    name: String,
+    mimetype: Option<String>,
+    size: Option<u64>,
+}
+
+/// `@localpart:server`, with the localpart folded to Matrix's allowed
+/// character set (lowercase `a-z 0-9 . _ = - /`).
+fn matrix_user(user_id: &str, username: Option<&str>) -> String {
+    let local: String = username
+        .unwrap_or(user_id)
+        .to_lowercase()
+        .chars()
+        .map(|c| {
+            if c.is_ascii_alphanumeric() || matches!(c, '.' | '_' | '=' | '-' | '/') {
+                c
+            } else {
+                '_'
+            }
+        })
+        .collect();
+    format!("@{local}:{MATRIX_SERVER}")
+}
+
+/// Unix millis for an RFC 3339 (`sent_at`) or SQLite `datetime('now')`
+/// (`joined_at`, `created_at`) timestamp; `0` when neither parses.
+fn ts_millis(ts: &str) -> i64 {
+    if let Ok(dt) = chrono::DateTime::parse_from_rfc3339(ts) {
+        return dt.timestamp_millis();
+    }
+    chrono::NaiveDateTime::parse_from_str(ts, "%Y-%m-%d %H:%M:%S")
+        .map(|dt| dt.and_utc().timestamp_millis())
+        .unwrap_or(0)
+}
+
+fn msgtype_for(mimetype: Option<&str>) -> &'static str {
+    match mimetype.unwrap_or("").split('/').next().unwrap_or("") {
+        "image" => "m.image",
+        "video" => "m.video",
+        "audio" => "m.audio",
+        _ => "m.file",
+    }
+}
+
+/// Render the channel as a Matrix event stream, oldest first.
+pub(super) fn render_matrix(
+    channel_id: &str,
+    channel_name: &str,
+    members: &[MatrixMember],
+    messages: &[ChannelMessage],
+    reactions: &[MatrixReaction],
+) -> Result<String> {
+    let room_id = format!("!{channel_id}:{MATRIX_SERVER}");
+    let usernames: HashMap<&str, Option<&str>> = members
+        .iter()
+        .map(|m| (m.user_id.as_str(), m.username.as_deref()))
+        .collect();
+    let sender_of = |user_id: &str| matrix_user(user_id, usernames.get(user_id).copied().flatten());
+
+    let mut events: Vec<(i64, Value)> = Vec::new();
+    let mut attachments: Vec<AttachmentManifestEntry> = Vec::new();
+
+    for m in members {
+        let user = sender_of(&m.user_id);
+        events.push((
+            ts_millis(&m.joined_at),
+            json!({
+                "type": "m.room.member",
+                "room_id": room_id,
+                "sender": user,
+                "state_key": user,
+                "origin_server_ts": ts_millis(&m.joined_at),
+                "content": {
+                    "membership": "join",
+                    "displayname": m.username.as_deref().unwrap_or(&m.user_id),
+                },
+            }),
+        ));
+    }
+
+    for m in messages {
+        let ts = ts_millis(&m.sent_at);
+        let sender = matrix_user(
+            &m.sender_id,
+            m.sender_username
+                .as_deref()
+                .or_else(|| usernames.get(m.sender_id.as_str()).copied().flatten()),
+        );
+        let raw = m.content.as_deref().unwrap_or("");
+        let parsed: Option<Value> = raw
+            .starts_with('{')
+            .then(|| serde_json::from_str::<Value>(raw).ok())
+            .flatten()
+            .filter(|v| v.get("_att").map(Value::is_array).unwrap_or(false));
+
+        // One Matrix event per caption / attachment, each paired with its
+        // `_att` descriptor when it's media.
+        let mut parts: Vec<(Value, Option<&Value>)> = Vec::new();
+        match &parsed {
+            Some(v) => {
+                let caption = v.get("_txt").and_then(Value::as_str).unwrap_or("");
+                if !caption.is_empty() {
+                    parts.push((json!({ "msgtype": "m.text", "body": caption }), None));
+                }
+                for att in v["_att"].as_array().into_iter().flatten() {
+                    let name = att
+                        .get("name")
+                        .and_then(Value::as_str)
+                        .unwrap_or("attachment");
+                    let mimetype = att.get("ct").and_then(Value::as_str);
+                    let mut info = serde_json::Map::new();
+                    if let Some(ct) = mimetype {
+                        info.insert("mimetype".into(), json!(ct));
+                    }
+                    for field in ["size", "w", "h"] {
+                        if let Some(n) = att.get(field).and_then(Value::as_u64) {
+                            info.insert(field.into(), json!(n));
+                        }
+                    }
+                    let content = json!({
+                        "msgtype": msgtype_for(mimetype),
+                        "body": name,
+                        "info": info,
+                    });
+                    parts.push((content, Some(att)));
+                }
+            }
+            None => parts.push((json!({ "msgtype": "m.text", "body": raw }), None)),
+        }
+        if parts.is_empty() {
+            parts.push((json!({ "msgtype": "m.text", "body": "" }), None));
+        }
+
+        for (i, (mut content, att)) in parts.into_iter().enumerate() {
+            // The first event keeps the message id so replies and reactions
+            // land on it; the rest get a stable suffix.
+            let event_id = if i == 0 {
+                format!("${}", m.id)
+            } else {
+                format!("${}-{i}", m.id)
+            };
+            if let Some(reply_to) = &m.reply_to_id {
+                content["m.relates_to"] =
+                    json!({ "m.in_reply_to": { "event_id": format!("${reply_to}") } });
+            }
+            if let Some(edited_at) = &m.edited_at {
+                content["io.pollis.edited_at"] = json!(edited_at);
+            }
+            if let Some(att) = att {
+                attachments.push(AttachmentManifestEntry {
+                    event_id: event_id.clone(),
+                    key: att
+                        .get("key")
+                        .and_then(Value::as_str)
+                        .unwrap_or("")
+                        .to_string(),
+                    hash: att.get("hash").and_then(Value::as_str).map(str::to_string),
+                    name: content["body"].as_str().unwrap_or("").to_string(),
+                    mimetype: att.get("ct").and_then(Value::as_str).map(str::to_string),
+                    size: att.get("size").and_then(Value::as_u64),
+                });
+            }
+            events.push((
+                ts,
+                json!({
+                    "type": "m.room.message",
+                    "event_id": event_id,
+                    "room_id": room_id,
+                    "sender": sender,
+                    "origin_server_ts": ts,
+                    "content": content,
+                }),
+            ));
+        }
+    }
+
+    for r in reactions {
+        let ts = ts_millis(&r.created_at);
+        events.push((
+            ts,
+            json!({
+                "type": "m.reaction",
+                "event_id": format!("${}", r.id),
+                "room_id": room_id,
+                "sender": sender_of(&r.user_id),
+                "origin_server_ts": ts,
+                "content": {
+                    "m.relates_to": {
+                        "rel_type": "m.annotation",
+                        "event_id": format!("${}", r.message_id),
+                        "key": r.emoji,
+                    },
+                },
+            }),
+        ));
+    }
+
+    // Stable: same-millisecond events keep members → messages → reactions order.
+    events.sort_by_key(|(ts, _)| *ts);
+
+    let doc = json!({
+        "format": FORMAT_TAG,
+        "room_id": room_id,
+        "room_name": channel_name,
+        "events": events.into_iter().map(|(_, e)| e).collect::<Vec<_>>(),
+        "attachments": attachments,
+    });
+    Ok(serde_json::to_string_pretty(&doc)?)
+}
@@ -1,16 +1,24 @@
-//! Message send / receive / edit / delete / reactions commands — split into
+//! Message send / receive / edit / delete / reactions / pins commands — split into
 //! cohesive submodules. Public surface is preserved via the `pub use`
 //! re-exports below so every external caller (Tauri shims, sibling
 //! `commands::*` modules, integration tests) keeps resolving names at
 //! `pollis_core::commands::messages::*`.
 
+mod activity;
+mod attachment_export;
+mod broadcast;
 mod edit_delete;
+mod export;
+mod filter;
 pub(crate) mod framing;
 mod ingest;
+mod matrix;
+mod pins;
 mod reactions;
 mod read;
 mod retention;
 mod send;
+mod translate;
 mod types;
 // `watermark` (the `next_watermark` pure fn + `EnvKind`) is `pub` — not because
 // any runtime caller needs the module path (they go through `pub use` below), but
@@ -21,18 +29,33 @@ pub mod watermark;
 
 // ── Types ────────────────────────────────────────────────────────────────────
 pub use types::{
-    ChannelMessage, ChannelPreview, Message, MessageCursor, MessagePage, MessageWithContext,
-    SearchResult,
+    ActiveChannel, ActivityBucket, ActivityRange, ChannelActivity, ChannelMessage, ChannelPreview,
+    ConversationPreview, IngestedMessages, Message, MessageCursor, MessageFilter, MessagePage,
+    MessageWithContext, SearchResult,
 };
 
 // ── Send ─────────────────────────────────────────────────────────────────────
 pub use send::send_message;
 
+// ── Broadcast ────────────────────────────────────────────────────────────────
+pub use broadcast::{
+    broadcast_announcement, BroadcastReport, BroadcastTargetResult, MAX_BROADCAST_TARGETS,
+};
+
+// ── Export ───────────────────────────────────────────────────────────────────
+pub use export::export_channel_messages;
+pub use attachment_export::{
+    export_group_attachments, pause_attachment_export, AttachmentExportSummary,
+};
+
 // ── Read / list / search ─────────────────────────────────────────────────────
 pub use read::{
-    get_channel_messages, get_dm_messages, list_channel_previews, list_messages,
-    list_messages_by_sender, read_channel_messages, read_dm_messages, search_messages,
+    get_channel_messages, get_dm_messages, list_channel_previews, list_conversation_previews,
+    list_messages, list_messages_by_sender, read_channel_messages, read_dm_messages,
+    search_messages, PREVIEW_SNIPPET_CHARS,
 };
+pub use filter::read_filtered_messages;
+pub use activity::{get_channel_activity, get_most_active_channels};
 
 // ── Ingest (envelope pull + watermark + cleanup) ─────────────────────────────
 pub use ingest::{
@@ -48,6 +71,15 @@ pub use edit_delete::{edit_message_as, send_redaction_as};
 // ── Reactions ────────────────────────────────────────────────────────────────
 pub use reactions::{add_reaction, get_reactions, remove_reaction, Reaction};
 
+// ── Pins ─────────────────────────────────────────────────────────────────────
+pub use pins::{get_pinned_messages, pin_message, unpin_message, PinnedMessage};
+
+// ── Translation (local backend) ───────────────────────────────────────────────
+pub use translate::{
+    get_conversation_translation_language, get_translation_backend,
+    set_conversation_translation_language, set_translation_backend, translate_message,
+};
+
 // ── Retention / local eviction ───────────────────────────────────────────────
 pub use retention::{get_message_retention, run_message_eviction, set_message_retention};
 
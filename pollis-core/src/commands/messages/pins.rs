@@ -0,0 +1,194 @@
+//! Pinned messages.
+//!
+//! A pin is a remote `pinned_message` row holding ids only — conversation,
+//! message, pinner, time — written through the DS (`/v1/pins/add`,
+//! `/v1/pins/remove`), which enforces membership, the per-conversation cap and
+//! the pinner-or-admin rule for unpinning. The pinned message's content never
+//! leaves the devices that hold it: [`get_pinned_messages`] resolves each id
+//! against this device's local `message` table. After a change the other
+//! members get a routing-only `pins_changed` ping on the conversation's room
+//! and refetch.
+
+use rusqlite::OptionalExtension;
+use serde::{Deserialize, Serialize};
+use std::sync::Arc;
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+use super::read::attach_sender_usernames_local;
+use super::types::ChannelMessage;
+
+/// One pin, newest first from [`get_pinned_messages`].
+#[derive(Debug, Serialize, Deserialize)]
+pub struct PinnedMessage {
+    pub conversation_id: String,
+    pub message_id: String,
+    pub pinned_by: String,
+    pub pinned_at: String,
+    /// The message from this device's history. `None` when this device never
+    /// received it (joined after it was sent, or evicted by retention).
+    pub message: Option<ChannelMessage>,
+}
+
+/// Pin `message_id` in `conversation_id`. Pinning an already pinned message
+/// succeeds and keeps the original pinner.
+pub async fn pin_message(
+    conversation_id: String,
+    message_id: String,
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let body = serde_json::json!({
+        "conversation_id": conversation_id,
+        "message_id": message_id,
+        "user_id": user_id,
+    });
+    let resp = crate::commands::mls::ds_post(state, "/v1/pins/add", &body).await?;
+    let status = resp.status();
+    if status == reqwest::StatusCode::CONFLICT {
+        // `limit_exceeded` body from the DS cap check.
+        let max = resp
+            .json::<serde_json::Value>()
+            .await
+            .ok()
+            .and_then(|v| v.get("max").and_then(|m| m.as_u64()));
+        return Err(Error::Other(anyhow::anyhow!(match max {
+            Some(n) => format!("this conversation already has {n} pinned messages; unpin one first"),
+            None => "this conversation has reached its pinned message limit".to_string(),
+        })));
+    }
+    if !status.is_success() {
+        let txt = resp.text().await.unwrap_or_default();
+        return Err(Error::Other(anyhow::anyhow!("pin_message {status}: {txt}")));
+    }
+
+    notify_pins_changed(state, &conversation_id).await;
+    Ok(())
+}
+
+/// Unpin `message_id`. The DS allows this for whoever pinned it and, in a
+/// group channel, any group admin.
+pub async fn unpin_message(
+    conversation_id: String,
+    message_id: String,
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let body = serde_json::json!({
+        "conversation_id": conversation_id,
+        "message_id": message_id,
+        "user_id": user_id,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/pins/remove", &body).await?;
+
+    notify_pins_changed(state, &conversation_id).await;
+    Ok(())
+}
+
+/// The conversation's pins, newest first, each joined to the local copy of
+/// the message when this device has one.
+pub async fn get_pinned_messages(
+    conversation_id: String,
+    state: &Arc<AppState>,
+) -> Result<Vec<PinnedMessage>> {
+    let mut pins: Vec<PinnedMessage> = Vec::new();
+    {
+        let conn = state.remote_db.conn().await?;
+        let mut rows = conn
+            .query(
+                "SELECT message_id, pinned_by, pinned_at FROM pinned_message \
+                 WHERE conversation_id = ?1 ORDER BY pinned_at DESC",
+                libsql::params![conversation_id.clone()],
+            )
+            .await?;
+        while let Some(row) = rows.next().await? {
+            pins.push(PinnedMessage {
+                conversation_id: conversation_id.clone(),
+                message_id: row.get(0)?,
+                pinned_by: row.get(1)?,
+                pinned_at: row.get(2)?,
+                message: None,
+            });
+        }
+    }
+    if pins.is_empty() {
+        return Ok(pins);
+    }
+
+    let mut messages: Vec<ChannelMessage> = Vec::new();
+    {
+        let guard = state.local_db.lock().await;
+        let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+        let mut stmt = db.conn().prepare(
+            "SELECT id, conversation_id, sender_id, ciphertext, content, reply_to_id, sent_at, edited_at, deleted_at
+             FROM message WHERE id = ?1 AND conversation_id = ?2",
+        )?;
+        for pin in &pins {
+            let found = stmt
+                .query_row(rusqlite::params![pin.message_id, conversation_id], |row| {
+                    let ct: Vec<u8> = row.get(3)?;
+                    let content: Option<String> = row.get(4)?;
+                    let deleted_at: Option<String> = row.get(8)?;
+                    Ok(ChannelMessage {
+                        id: row.get(0)?,
+                        conversation_id: row.get(1)?,
+                        sender_id: row.get(2)?,
+                        sender_username: None,
+                        ciphertext: format!("mls:{}", hex::encode(&ct)),
+                        // Same masking as the page reads: a deleted message
+                        // stays pinned but shows no content.
+                        content: if deleted_at.is_some() { None } else { content },
+                        reply_to_id: row.get(5)?,
+                        sent_at: row.get(6)?,
+                        edited_at: row.get(7)?,
+                        deleted_at,
+                        sender_muted: false,
+                    })
+                })
+                .optional()?;
+            if let Some(m) = found {
+                messages.push(m);
+            }
+        }
+    }
+    attach_sender_usernames_local(state, &mut messages).await?;
+
+    for m in messages {
+        if let Some(pin) = pins.iter_mut().find(|p| p.message_id == m.id) {
+            pin.message = Some(m);
+        }
+    }
+    Ok(pins)
+}
+
+/// Best-effort `pins_changed` ping. A channel's room is its group id; a DM's
+/// room is the DM id itself.
+async fn notify_pins_changed(state: &Arc<AppState>, conversation_id: &str) {
+    let room = match channel_group_id(state, conversation_id).await {
+        Ok(Some(group_id)) => group_id,
+        Ok(None) => conversation_id.to_string(),
+        Err(e) => {
+            eprintln!("[pins] resolve room for {conversation_id}: {e}");
+            return;
+        }
+    };
+    let payload = crate::commands::livekit_signalling::pins_changed_payload(conversation_id);
+    if let Err(e) = crate::commands::livekit::publish_to_room_server(state, &room, payload).await {
+        eprintln!("[pins] publish pins_changed to {room}: {e}");
+    }
+}
+
+async fn channel_group_id(state: &Arc<AppState>, conversation_id: &str) -> Result<Option<String>> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn
+        .query(
+            "SELECT group_id FROM channels WHERE id = ?1",
+            libsql::params![conversation_id.to_string()],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) => Some(row.get(0)?),
+        None => None,
+    })
+}
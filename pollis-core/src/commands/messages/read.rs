@@ -5,13 +5,17 @@ use crate::state::AppState;
 
 use crate::db::queries::MESSAGES_BY_SENDER as QUERY_MESSAGES_BY_SENDER;
 use crate::db::queries::CHANNEL_PREVIEWS as QUERY_CHANNEL_PREVIEWS;
+use crate::db::queries::LOCAL_CONVERSATION_PREVIEWS as QUERY_LOCAL_CONVERSATION_PREVIEWS;
 
 use super::ingest::{ingest_channel_envelopes_inner, ingest_dm_envelopes_inner};
 use super::types::{
-    ChannelMessage, ChannelPreview, Message, MessageCursor, MessagePage, MessageWithContext,
-    SearchResult,
+    ChannelMessage, ChannelPreview, ConversationPreview, Message, MessageCursor, MessagePage,
+    MessageWithContext, SearchResult,
 };
 
+/// Longest preview snippet, in characters.
+pub const PREVIEW_SNIPPET_CHARS: usize = 100;
+
 pub async fn list_messages(
     conversation_id: String,
     limit: Option<i64>,
@@ -19,7 +23,7 @@ pub async fn list_messages(
     state: &Arc<AppState>,
 ) -> Result<Vec<Message>> {
     let guard = state.local_db.lock().await;
-    let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+    let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
     let limit = limit.unwrap_or(50);
 
     let messages = if let Some(before) = before_id {
@@ -106,6 +110,69 @@ pub async fn get_channel_messages(
     Ok(MessagePage { messages, next_cursor })
 }
 
+/// Map a `SELECT id, conversation_id, sender_id, ciphertext, content,
+/// reply_to_id, sent_at, edited_at, deleted_at FROM message` row.
+pub(super) fn row_to_message(row: &rusqlite::Row<'_>) -> rusqlite::Result<ChannelMessage> {
+    let ct: Vec<u8> = row.get(3)?;
+    let content: Option<String> = row.get(4)?;
+    let deleted_at: Option<String> = row.get(8)?;
+    Ok(ChannelMessage {
+        id: row.get(0)?,
+        conversation_id: row.get(1)?,
+        sender_id: row.get(2)?,
+        sender_username: None,
+        ciphertext: format!("mls:{}", hex::encode(&ct)),
+        // Soft-deleted messages mask content to None regardless of cache.
+        content: if deleted_at.is_some() { None } else { content },
+        reply_to_id: row.get(5)?,
+        sent_at: row.get(6)?,
+        edited_at: row.get(7)?,
+        deleted_at,
+        sender_muted: false,
+    })
+}
+
+/// Set `sender_muted` on messages whose sender this account has muted in the
+/// conversation's group. The group comes from `conversation_group` (recorded
+/// by ingest) and the muted lists from the local preferences mirror, so DMs
+/// and not-yet-ingested channels are never flagged.
+pub(super) fn flag_muted_senders(conn: &rusqlite::Connection, messages: &mut [ChannelMessage]) {
+    if messages.is_empty() {
+        return;
+    }
+    let muted: std::collections::HashMap<String, Vec<String>> = conn
+        .query_row("SELECT preferences FROM preferences LIMIT 1", [], |row| {
+            row.get::<_, String>(0)
+        })
+        .ok()
+        .and_then(|p| serde_json::from_str::<serde_json::Value>(&p).ok())
+        .and_then(|v| v.get("muted_members").cloned())
+        .and_then(|m| serde_json::from_value(m).ok())
+        .unwrap_or_default();
+    if muted.values().all(|ids| ids.is_empty()) {
+        return;
+    }
+
+    let mut groups: std::collections::HashMap<String, Option<String>> =
+        std::collections::HashMap::new();
+    for m in messages.iter_mut() {
+        let group_id = groups
+            .entry(m.conversation_id.clone())
+            .or_insert_with(|| {
+                conn.query_row(
+                    "SELECT group_id FROM conversation_group WHERE conversation_id = ?1",
+                    rusqlite::params![m.conversation_id],
+                    |row| row.get::<_, String>(0),
+                )
+                .ok()
+            })
+            .clone();
+        m.sender_muted = group_id
+            .and_then(|g| muted.get(&g))
+            .is_some_and(|ids| ids.iter().any(|id| id == &m.sender_id));
+    }
+}
+
 /// Read a page of messages for a conversation from the local `message` table,
 /// newest-first. Used by both channel and DM read paths after ingest has
 /// persisted any new envelopes.
@@ -116,26 +183,7 @@ async fn read_local_channel_page(
     limit: i64,
 ) -> Result<Vec<ChannelMessage>> {
     let guard = state.local_db.lock().await;
-    let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
-
-    fn row_to_message(row: &rusqlite::Row<'_>) -> rusqlite::Result<ChannelMessage> {
-        let ct: Vec<u8> = row.get(3)?;
-        let content: Option<String> = row.get(4)?;
-        let deleted_at: Option<String> = row.get(8)?;
-        Ok(ChannelMessage {
-            id: row.get(0)?,
-            conversation_id: row.get(1)?,
-            sender_id: row.get(2)?,
-            sender_username: None,
-            ciphertext: format!("mls:{}", hex::encode(&ct)),
-            // Soft-deleted messages mask content to None regardless of cache.
-            content: if deleted_at.is_some() { None } else { content },
-            reply_to_id: row.get(5)?,
-            sent_at: row.get(6)?,
-            edited_at: row.get(7)?,
-            deleted_at,
-        })
-    }
+    let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
 
     let mut rows: Vec<ChannelMessage> = Vec::new();
     match cursor {
@@ -171,6 +219,7 @@ async fn read_local_channel_page(
             }
         }
     }
+    flag_muted_senders(db.conn(), &mut rows);
 
     Ok(rows)
 }
@@ -179,7 +228,7 @@ async fn read_local_channel_page(
 /// sender_ids missing from the cache, do one batched remote fetch and
 /// write the results back. After the first read of a channel/DM, the
 /// cache is warm and subsequent reads are zero-remote.
-async fn attach_sender_usernames_local(
+pub(super) async fn attach_sender_usernames_local(
     state: &Arc<AppState>,
     messages: &mut [ChannelMessage],
 ) -> Result<()> {
@@ -196,7 +245,7 @@ async fn attach_sender_usernames_local(
     let mut found: std::collections::HashMap<String, String> = std::collections::HashMap::new();
     let missing: Vec<String> = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         let placeholders = (1..=ids_vec.len())
             .map(|i| format!("?{i}"))
             .collect::<Vec<_>>()
@@ -365,6 +414,70 @@ pub async fn list_channel_previews(
     Ok(previews)
 }
 
+/// Newest visible message per conversation, from the local decrypted cache only
+/// — no MLS, no network — so the conversation list renders instantly. Edits and
+/// deletes land on the local `message` row, so the next call reflects them.
+pub async fn list_conversation_previews(state: &Arc<AppState>) -> Result<Vec<ConversationPreview>> {
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
+
+    let mut stmt = db.conn().prepare(QUERY_LOCAL_CONVERSATION_PREVIEWS)?;
+    let rows = stmt.query_map([], |row| {
+        let content: String = row.get(4)?;
+        let (kind, snippet) = preview_snippet(&content);
+        Ok(ConversationPreview {
+            conversation_id: row.get(0)?,
+            message_id: row.get(1)?,
+            sender_id: row.get(2)?,
+            sender_username: row.get(3)?,
+            kind: kind.to_string(),
+            snippet,
+            sent_at: row.get(5)?,
+            edited_at: row.get(6)?,
+        })
+    })?;
+
+    Ok(rows.filter_map(|r| r.ok()).collect())
+}
+
+/// Reduce a message's plaintext `content` to a `(kind, snippet)` preview.
+/// Attachment payloads (`{"_att":[…],"_txt":"caption"}`) preview as their
+/// caption, falling back to the first file name; plain text is whitespace-
+/// collapsed. Either way the snippet is cut to [`PREVIEW_SNIPPET_CHARS`].
+pub(super) fn preview_snippet(content: &str) -> (&'static str, String) {
+    let (kind, text) = match attachment_preview_text(content) {
+        Some(text) => ("attachment", text),
+        None => ("text", content.to_string()),
+    };
+    let collapsed = text.split_whitespace().collect::<Vec<_>>().join(" ");
+    let snippet = match collapsed.char_indices().nth(PREVIEW_SNIPPET_CHARS) {
+        Some((cut, _)) => format!("{}…", &collapsed[..cut]),
+        None => collapsed,
+    };
+    (kind, snippet)
+}
+
+fn attachment_preview_text(content: &str) -> Option<String> {
+    if !content.starts_with('{') {
+        return None;
+    }
+    let parsed: serde_json::Value = serde_json::from_str(content).ok()?;
+    let atts = parsed.get("_att")?.as_array()?;
+    let caption = parsed
+        .get("_txt")
+        .and_then(|v| v.as_str())
+        .filter(|t| !t.trim().is_empty());
+    Some(match caption {
+        Some(caption) => caption.to_string(),
+        None => atts
+            .first()
+            .and_then(|a| a.get("name"))
+            .and_then(|v| v.as_str())
+            .unwrap_or("")
+            .to_string(),
+    })
+}
+
 /// Fetch a page of messages for a DM channel the user is a member of.
 /// Results are ordered newest-first.
 pub async fn get_dm_messages(
@@ -402,7 +515,7 @@ pub async fn search_messages(
     state: &Arc<AppState>,
 ) -> Result<Vec<SearchResult>> {
     let guard = state.local_db.lock().await;
-    let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+    let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
     let limit = limit.unwrap_or(50);
     let pattern = format!("%{}%", query);
 
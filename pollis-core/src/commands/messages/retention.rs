@@ -7,6 +7,11 @@
 //! orthogonal to MLS epoch visibility — see the "bounded history" product
 //! principle in CLAUDE.md.
 //!
+//! The sweep also applies admin-set channel retention (`channels.retention_days`):
+//! a channel with a window loses local messages *sent* before it, on every
+//! member's device, whatever this device's own setting is. Those windows are
+//! read from Turso; if that fails the sweep still runs the device window.
+//!
 //! The actual storage + eviction primitives live in `db::local`; these are the
 //! thin async command wrappers that take the shared `AppState` local DB.
 
@@ -37,11 +42,32 @@ pub async fn set_message_retention(days: i64, state: &Arc<AppState>) -> Result<(
 
 /// Run an eviction sweep now — the lifecycle entry point used by the startup
 /// and app-focus hooks. Returns the number of rows deleted. A no-op (returns
-/// `Ok(0)`) when retention is Forever or no local DB is open yet.
+/// `Ok(0)`) when retention is Forever, no channel has a window, or no local DB
+/// is open yet.
 pub async fn run_message_eviction(state: &Arc<AppState>) -> Result<usize> {
+    // Fetched before taking the local DB lock so the network round trip
+    // doesn't hold it.
+    let user_id = state.unlock.lock().await.as_ref().map(|u| u.user_id.clone());
+    let channel_windows = match user_id {
+        Some(user_id) => crate::commands::groups::channel_retention_windows(&user_id, state)
+            .await
+            .unwrap_or_else(|e| {
+                eprintln!("[retention] channel windows: {e}");
+                Vec::new()
+            }),
+        None => Vec::new(),
+    };
+
     let guard = state.local_db.lock().await;
     let Some(db) = guard.as_ref() else {
         return Ok(0);
     };
-    crate::db::local::evict_old_messages(db.conn())
+    let mut deleted = crate::db::local::evict_old_messages(db.conn())?;
+    let now = chrono::Utc::now();
+    for (channel_id, days) in channel_windows {
+        let cutoff = (now - chrono::Duration::days(days)).to_rfc3339();
+        deleted +=
+            crate::db::local::evict_conversation_messages_before(db.conn(), &channel_id, &cutoff)?;
+    }
+    Ok(deleted)
 }
@@ -79,9 +79,7 @@ pub async fn send_message(
         // it's only read back via the `content` column.
         {
             let guard = state.local_db.lock().await;
-            let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(
-                anyhow::anyhow!("Not signed in")
-            ))?;
+            let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
             let empty: Vec<u8> = Vec::new();
             db.conn().execute(
                 "INSERT INTO message (id, conversation_id, sender_id, ciphertext, content, reply_to_id, sent_at)
@@ -126,9 +124,17 @@ pub async fn send_message(
         eprintln!("[messages] send_message: catch_up_mls_group for {mls_group_id}: {e}");
     }
 
+    // Now at head: if a roster device that could be added is still missing
+    // from the tree, add it before sealing, so the message isn't unreadable to
+    // a recent joiner. Non-fatal — on failure the send goes out as before and
+    // the next send retries.
+    if let Err(e) = crate::commands::mls::send_preflight(state, &mls_group_id, &sender_id).await {
+        eprintln!("[messages] send_message: preflight for {mls_group_id}: {e}");
+    }
+
     let ciphertext_remote = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| crate::error::Error::Other(anyhow::anyhow!("Not signed in")))?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
 
         // Size padding (issue #331 v2, `docs/metadata-minimization-design.md`
         // §4.1). Pad TEXT plaintext to a size bucket before encryption so the
@@ -194,32 +200,60 @@ pub async fn send_message(
         "reply_to_id": reply_to_id,
         "sent_at": now,
     });
-    crate::commands::mls::ds_post_ok(state, "/v1/messages/send", &body).await?;
+    let posted = std::time::Instant::now();
+    let resp = crate::commands::mls::ds_post(state, "/v1/messages/send", &body).await?;
+    if !resp.status().is_success() {
+        let s = resp.status();
+        let txt = resp.text().await.unwrap_or_default();
+        return Err(crate::error::Error::Other(anyhow::anyhow!("ds_post /v1/messages/send {s}: {txt}")));
+    }
+    // The envelope is its own delivery canary: checked when our next fetch
+    // brings it back.
+    crate::commands::delivery_canary::record_sent(
+        &id,
+        &conversation_id,
+        &ciphertext_remote,
+        &now,
+        posted.elapsed().as_millis() as u64,
+    );
+    // The DS pings the room itself once the envelope is written, so the
+    // wake-up no longer depends on this client surviving past the send. It
+    // says so with `notified: true` once the ping has actually gone out; an
+    // older DS, one without LiveKit configured, or a ping that failed or
+    // timed out omits it and we publish as before.
+    let ds_notified = resp
+        .json::<serde_json::Value>()
+        .await
+        .ok()
+        .and_then(|v| v.get("notified").and_then(|n| n.as_bool()))
+        .unwrap_or(false);
 
     // Notify recipients via LiveKit. Non-fatal — errors are logged, not returned.
     // §5 signalling minimization: the wake-up carries conversation routing only,
     // no sender — recipients attribute the message from the decrypted envelope.
-    if is_channel {
-        // One LiveKit room per group covers all its channels.
-        // Receivers filter by channel_id in the event payload.
-        if let Err(e) = crate::commands::livekit::publish_new_message_to_room(
-            state,
-            &mls_group_id,
-            Some(&conversation_id),
-            None,
-        ).await {
-            eprintln!("[realtime] send_message: publish to group {mls_group_id}: {e}");
-        }
-    } else {
-        // DM: publish directly to the shared DM room (conversation_id is the room name).
-        // Both participants are connected to this room via connect_rooms.
-        if let Err(e) = crate::commands::livekit::publish_new_message_to_room(
-            state,
-            &conversation_id,
-            None,
-            Some(&conversation_id),
-        ).await {
-            eprintln!("[realtime] send_message: publish to DM room {conversation_id}: {e}");
+    if !ds_notified {
+        if is_channel {
+            // One LiveKit room per group covers all its channels.
+            // Receivers filter by channel_id in the event payload.
+            if let Err(e) = crate::commands::livekit::publish_new_message_to_room(
+                state,
+                &mls_group_id,
+                Some(&conversation_id),
+                None,
+            ).await {
+                eprintln!("[realtime] send_message: publish to group {mls_group_id}: {e}");
+            }
+        } else {
+            // DM: publish directly to the shared DM room (conversation_id is the room name).
+            // Both participants are connected to this room via connect_rooms.
+            if let Err(e) = crate::commands::livekit::publish_new_message_to_room(
+                state,
+                &conversation_id,
+                None,
+                Some(&conversation_id),
+            ).await {
+                eprintln!("[realtime] send_message: publish to DM room {conversation_id}: {e}");
+            }
         }
     }
 
@@ -4,6 +4,7 @@ use crate::db::BASELINE_SQL as BASELINE;
 
 use crate::db::queries::MESSAGES_BY_SENDER as QUERY_MESSAGES_BY_SENDER;
 use crate::db::queries::CHANNEL_PREVIEWS as QUERY_CHANNEL_PREVIEWS;
+use crate::db::queries::LOCAL_CONVERSATION_PREVIEWS as QUERY_LOCAL_CONVERSATION_PREVIEWS;
 
 // Both queries operate on the remote schema. Tests use rusqlite in-memory
 // (same SQLite dialect, no libsql threading conflict in test binaries).
@@ -161,3 +162,441 @@ fn channel_previews_excludes_channels_user_is_not_in() {
 
     assert!(!channel_ids.contains(&"ch-secret".to_string()));
 }
+
+// ── Local conversation previews (local schema) ───────────────────────────────
+
+fn local_db_with_messages() -> crate::db::local::LocalDb {
+    let db = crate::db::local::LocalDb::open_in_memory().unwrap();
+    let conn = db.conn();
+    conn.execute("INSERT INTO user_cache (id, username) VALUES ('alice', 'alice')", []).unwrap();
+    for (id, conv, sender, content, sent_at) in [
+        ("m1", "conv-a", "alice", "first", "2024-01-01T10:00:00Z"),
+        ("m2", "conv-a", "bob", "second", "2024-01-01T11:00:00Z"),
+        ("m3", "conv-b", "alice", "only", "2024-01-02T09:00:00Z"),
+    ] {
+        conn.execute(
+            "INSERT INTO message (id, conversation_id, sender_id, ciphertext, content, sent_at)
+             VALUES (?1, ?2, ?3, X'00', ?4, ?5)",
+            rusqlite::params![id, conv, sender, content, sent_at],
+        ).unwrap();
+    }
+    db
+}
+
+fn local_previews(conn: &Connection) -> Vec<(String, String, Option<String>)> {
+    let mut stmt = conn.prepare(QUERY_LOCAL_CONVERSATION_PREVIEWS).unwrap();
+    stmt.query_map([], |row| Ok((row.get(0)?, row.get(1)?, row.get(3)?)))
+        .unwrap()
+        .map(|r| r.unwrap())
+        .collect()
+}
+
+#[test]
+fn local_previews_pick_newest_per_conversation_most_recent_first() {
+    let db = local_db_with_messages();
+    let previews = local_previews(db.conn());
+    assert_eq!(
+        previews,
+        vec![
+            ("conv-b".into(), "m3".into(), Some("alice".into())),
+            ("conv-a".into(), "m2".into(), None),
+        ]
+    );
+}
+
+#[test]
+fn local_previews_fall_back_past_deleted_messages() {
+    let db = local_db_with_messages();
+    db.conn()
+        .execute("UPDATE message SET deleted_at = datetime('now') WHERE id = 'm2'", [])
+        .unwrap();
+    let previews = local_previews(db.conn());
+    assert!(previews.contains(&("conv-a".into(), "m1".into(), Some("alice".into()))));
+}
+
+#[test]
+fn preview_snippet_collapses_and_truncates_text() {
+    let (kind, snippet) = super::read::preview_snippet("hello\n\n  world");
+    assert_eq!((kind, snippet.as_str()), ("text", "hello world"));
+
+    let long = "é".repeat(super::read::PREVIEW_SNIPPET_CHARS + 5);
+    let (_, snippet) = super::read::preview_snippet(&long);
+    assert_eq!(snippet.chars().count(), super::read::PREVIEW_SNIPPET_CHARS + 1);
+    assert!(snippet.ends_with('…'));
+}
+
+#[test]
+fn preview_snippet_uses_attachment_caption_then_file_name() {
+    let (kind, snippet) = super::read::preview_snippet(
+        r#"{"_att":[{"key":"media/x","hash":"h","name":"cat.png"}],"_txt":"look"}"#,
+    );
+    assert_eq!((kind, snippet.as_str()), ("attachment", "look"));
+
+    let (kind, snippet) =
+        super::read::preview_snippet(r#"{"_att":[{"key":"media/x","hash":"h","name":"cat.png"}]}"#);
+    assert_eq!((kind, snippet.as_str()), ("attachment", "cat.png"));
+
+    // JSON-looking text without `_att` is just text.
+    let (kind, _) = super::read::preview_snippet(r#"{"not":"an attachment"}"#);
+    assert_eq!(kind, "text");
+}
+
+// ── Filtered timelines (local schema) ────────────────────────────────────────
+
+fn local_db_for_filters() -> crate::db::local::LocalDb {
+    let db = crate::db::local::LocalDb::open_in_memory().unwrap();
+    for (id, sender, content, sent_at) in [
+        ("f1", "alice", "plain words", "2024-01-01T10:00:00Z"),
+        ("f2", "bob", "see https://example.com", "2024-01-01T10:01:00Z"),
+        ("f3", "alice", r#"{"_att":[{"url":"https://r2/x","name":"cat.png"}]}"#, "2024-01-01T10:02:00Z"),
+        ("f4", "bob", r#"{"_att":[{"name":"a.pdf"}],"_txt":"www.docs.example"}"#, "2024-01-01T10:03:00Z"),
+        ("f5", "alice", "www.pollis.com and more", "2024-01-01T10:04:00Z"),
+        ("f6", "bob", r#"{"xatt":1}"#, "2024-01-01T10:05:00Z"),
+    ] {
+        db.conn().execute(
+            "INSERT INTO message (id, conversation_id, sender_id, ciphertext, content, sent_at)
+             VALUES (?1, 'conv', ?2, X'00', ?3, ?4)",
+            rusqlite::params![id, sender, content, sent_at],
+        ).unwrap();
+    }
+    db
+}
+
+fn filtered_ids(
+    conn: &Connection,
+    filter: super::MessageFilter,
+    cursor: Option<&super::MessageCursor>,
+    limit: i64,
+) -> Vec<String> {
+    super::filter::filtered_page(conn, "conv", &filter, cursor, limit)
+        .unwrap()
+        .into_iter()
+        .map(|m| m.id)
+        .collect()
+}
+
+#[test]
+fn filters_pick_media_links_and_sender() {
+    let db = local_db_for_filters();
+    let media = super::MessageFilter { media: true, ..Default::default() };
+    assert_eq!(filtered_ids(db.conn(), media, None, 50), ["f4", "f3"]);
+
+    // Attachment payloads carry blob URLs, so they never count as links.
+    let links = super::MessageFilter { links: true, ..Default::default() };
+    assert_eq!(filtered_ids(db.conn(), links, None, 50), ["f5", "f2"]);
+
+    let alice_media = super::MessageFilter {
+        media: true,
+        sender_id: Some("alice".into()),
+        ..Default::default()
+    };
+    assert_eq!(filtered_ids(db.conn(), alice_media, None, 50), ["f3"]);
+}
+
+#[test]
+fn unread_filter_keeps_the_newest_and_pages_by_cursor() {
+    let db = local_db_for_filters();
+    db.conn()
+        .execute("UPDATE message SET deleted_at = datetime('now') WHERE id = 'f5'", [])
+        .unwrap();
+    let unread = super::MessageFilter { unread: Some(3), ..Default::default() };
+    // Newest three are f6, f5, f4; deleted f5 is never shown.
+    assert_eq!(filtered_ids(db.conn(), unread.clone(), None, 50), ["f6", "f4"]);
+
+    let everything = super::MessageFilter::default();
+    assert_eq!(filtered_ids(db.conn(), everything.clone(), None, 2), ["f6", "f4"]);
+    let cursor = super::MessageCursor {
+        sent_at: "2024-01-01T10:03:00Z".into(),
+        id: "f4".into(),
+    };
+    assert_eq!(filtered_ids(db.conn(), everything, Some(&cursor), 2), ["f3", "f2"]);
+}
+
+#[test]
+fn muted_senders_are_flagged_only_in_their_group() {
+    let db = local_db_for_filters();
+    let conn = db.conn();
+    conn.execute(
+        "INSERT INTO preferences (preferences) VALUES (?1)",
+        [r#"{"muted_members":{"g1":["bob"],"g2":["alice"]}}"#],
+    ).unwrap();
+    conn.execute(
+        "INSERT INTO conversation_group (conversation_id, group_id) VALUES ('conv', 'g1')",
+        [],
+    ).unwrap();
+    let mut page =
+        super::filter::filtered_page(conn, "conv", &super::MessageFilter::default(), None, 50)
+            .unwrap();
+    super::read::flag_muted_senders(conn, &mut page);
+    for m in &page {
+        assert_eq!(m.sender_muted, m.sender_id == "bob", "{}", m.id);
+    }
+
+    // A conversation with no recorded group (a DM) is never flagged.
+    conn.execute("DELETE FROM conversation_group", []).unwrap();
+    for m in page.iter_mut() {
+        m.sender_muted = false;
+    }
+    super::read::flag_muted_senders(conn, &mut page);
+    assert!(page.iter().all(|m| !m.sender_muted));
+}
+
+// ── Broadcast target normalization ───────────────────────────────────────────
+
+#[test]
+fn broadcast_targets_drop_blanks_and_repeats_in_order() {
+    use super::broadcast::normalize_targets;
+    let ids = vec!["c2".to_string(), " ".to_string(), "c1".to_string(), "c2".to_string()];
+    assert_eq!(normalize_targets(ids).unwrap(), vec!["c2".to_string(), "c1".to_string()]);
+}
+
+#[test]
+fn broadcast_targets_reject_empty_and_oversized_lists() {
+    use super::broadcast::{normalize_targets, MAX_BROADCAST_TARGETS};
+    assert!(normalize_targets(vec![String::new()]).is_err());
+    let many: Vec<String> = (0..=MAX_BROADCAST_TARGETS).map(|i| format!("c{i}")).collect();
+    assert!(normalize_targets(many).is_err());
+    let max: Vec<String> = (0..MAX_BROADCAST_TARGETS).map(|i| format!("c{i}")).collect();
+    assert_eq!(normalize_targets(max).unwrap().len(), MAX_BROADCAST_TARGETS);
+}
+
+// ── Channel export rendering ─────────────────────────────────────────────────
+
+fn exported(id: &str, content: Option<&str>) -> super::export::ExportedMessage {
+    super::export::ExportedMessage::from_message(super::types::ChannelMessage {
+        id: id.to_string(),
+        conversation_id: "ch".to_string(),
+        sender_id: "alice".to_string(),
+        sender_username: Some("alice".to_string()),
+        ciphertext: String::new(),
+        content: content.map(str::to_string),
+        reply_to_id: None,
+        sent_at: "2024-01-01T00:00:00Z".to_string(),
+        edited_at: None,
+        deleted_at: None,
+        sender_muted: false,
+    })
+}
+
+#[test]
+fn export_format_parses_case_insensitively() {
+    use super::export::ExportFormat;
+    assert_eq!(ExportFormat::parse("CSV").unwrap(), ExportFormat::Csv);
+    assert_eq!(ExportFormat::parse(" json ").unwrap(), ExportFormat::Json);
+    assert!(ExportFormat::parse("xml").is_err());
+}
+
+#[test]
+fn export_csv_quotes_and_defuses_formulas() {
+    use super::export::csv_field;
+    assert_eq!(csv_field("plain"), "plain");
+    assert_eq!(csv_field("a,b"), "\"a,b\"");
+    assert_eq!(csv_field("say \"hi\""), "\"say \"\"hi\"\"\"");
+    assert_eq!(csv_field("line\nbreak"), "\"line\nbreak\"");
+    assert_eq!(csv_field("=SUM(A1)"), "'=SUM(A1)");
+    assert_eq!(csv_field("-1,2"), "\"'-1,2\"");
+}
+
+#[test]
+fn export_splits_attachment_payloads() {
+    let row = exported("m1", Some(r#"{"_att":[{"name":"a.png"},{"name":"b.pdf"}],"_txt":"look"}"#));
+    assert_eq!(row.content, "look");
+    assert_eq!(row.attachments, vec!["a.png".to_string(), "b.pdf".to_string()]);
+    let plain = exported("m2", Some("hello"));
+    assert_eq!(plain.content, "hello");
+    assert!(plain.attachments.is_empty());
+}
+
+#[test]
+fn export_renders_csv_header_and_rows() {
+    use super::export::{render, ExportFormat};
+    let rows = vec![exported("m1", Some("hi, there")), exported("m2", None)];
+    let csv = render(ExportFormat::Csv, &rows).unwrap();
+    let lines: Vec<&str> = csv.split("\r\n").collect();
+    assert_eq!(lines[0], "id,sent_at,sender_id,sender_username,content,attachments,reply_to_id,edited_at,system");
+    assert_eq!(lines[1], "m1,2024-01-01T00:00:00Z,alice,alice,\"hi, there\",,,,false");
+    assert_eq!(lines[2], "m2,2024-01-01T00:00:00Z,alice,alice,,,,,false");
+
+    let json: serde_json::Value = serde_json::from_str(&render(ExportFormat::Json, &rows).unwrap()).unwrap();
+    assert_eq!(json.as_array().unwrap().len(), 2);
+    assert_eq!(json[0]["content"], "hi, there");
+}
+
+#[test]
+fn export_marks_group_events_as_system_rows() {
+    use super::export::{render, ExportFormat, ExportedMessage};
+    let event = crate::commands::groups::GroupEvent {
+        id: "member_joined:g1:bob:2024-01-01T00:00:00+00:00".to_string(),
+        group_id: "g1".to_string(),
+        kind: "member_joined".to_string(),
+        subject_id: "bob".to_string(),
+        subject_name: Some("bob".to_string()),
+        occurred_at: "2024-01-01T00:00:00+00:00".to_string(),
+    };
+    let rows = vec![exported("m1", Some("hi")), ExportedMessage::from_event(event)];
+    let csv = render(ExportFormat::Csv, &rows).unwrap();
+    let lines: Vec<&str> = csv.split("\r\n").collect();
+    assert!(lines[2].ends_with(",bob joined the group,,,,true"), "{}", lines[2]);
+
+    let json: serde_json::Value = serde_json::from_str(&render(ExportFormat::Json, &rows).unwrap()).unwrap();
+    assert_eq!(json[0]["system"], false);
+    assert_eq!(json[1]["system"], true);
+    assert_eq!(json[1]["sender_id"], "");
+}
+
+#[test]
+fn export_format_parses_matrix() {
+    use super::export::ExportFormat;
+    assert_eq!(ExportFormat::parse("Matrix").unwrap(), ExportFormat::Matrix);
+}
+
+#[test]
+fn export_matrix_renders_events_and_attachment_manifest() {
+    use super::matrix::{render_matrix, MatrixMember, MatrixReaction};
+    let members = vec![MatrixMember {
+        user_id: "alice".to_string(),
+        username: Some("Alice Smith".to_string()),
+        joined_at: "2023-12-31 00:00:00".to_string(),
+    }];
+    let message = |id: &str, content: &str, sent_at: &str| super::types::ChannelMessage {
+        id: id.to_string(),
+        conversation_id: "ch".to_string(),
+        sender_id: "alice".to_string(),
+        sender_username: Some("Alice Smith".to_string()),
+        ciphertext: String::new(),
+        content: Some(content.to_string()),
+        reply_to_id: None,
+        sent_at: sent_at.to_string(),
+        edited_at: None,
+        deleted_at: None,
+        sender_muted: false,
+    };
+    let first = message("m1", "hello", "2024-01-01T00:00:00Z");
+    let mut reply = message(
+        "m2",
+        r#"{"_att":[{"key":"media/k1","hash":"h1","name":"a.png","ct":"image/png","size":10,"w":2,"h":3}],"_txt":"look"}"#,
+        "2024-01-01T00:00:01Z",
+    );
+    reply.reply_to_id = Some("m1".to_string());
+    reply.edited_at = Some("2024-01-02T00:00:00Z".to_string());
+    let reactions = vec![MatrixReaction {
+        id: "r1".to_string(),
+        message_id: "m1".to_string(),
+        user_id: "alice".to_string(),
+        emoji: "👍".to_string(),
+        created_at: "2024-01-01 00:00:05".to_string(),
+    }];
+
+    let out = render_matrix("ch", "general", &members, &[first, reply], &reactions).unwrap();
+    let doc: serde_json::Value = serde_json::from_str(&out).unwrap();
+    assert_eq!(doc["room_id"], "!ch:pollis.invalid");
+    let events = doc["events"].as_array().unwrap();
+    let types: Vec<&str> = events.iter().map(|e| e["type"].as_str().unwrap()).collect();
+    assert_eq!(types, ["m.room.member", "m.room.message", "m.room.message", "m.room.message", "m.reaction"]);
+
+    assert_eq!(events[0]["state_key"], "@alice_smith:pollis.invalid");
+    assert_eq!(events[1]["event_id"], "$m1");
+    assert_eq!(events[1]["content"]["body"], "hello");
+    // Caption keeps the message id; the image gets a suffixed one.
+    assert_eq!(events[2]["event_id"], "$m2");
+    assert_eq!(events[2]["content"]["m.relates_to"]["m.in_reply_to"]["event_id"], "$m1");
+    assert_eq!(events[2]["content"]["io.pollis.edited_at"], "2024-01-02T00:00:00Z");
+    assert_eq!(events[3]["event_id"], "$m2-1");
+    assert_eq!(events[3]["content"]["msgtype"], "m.image");
+    assert_eq!(events[3]["content"]["info"]["w"], 2);
+    assert_eq!(events[4]["content"]["m.relates_to"]["event_id"], "$m1");
+    assert_eq!(events[4]["content"]["m.relates_to"]["key"], "👍");
+
+    let manifest = doc["attachments"].as_array().unwrap();
+    assert_eq!(manifest.len(), 1);
+    assert_eq!(manifest[0]["event_id"], "$m2-1");
+    assert_eq!(manifest[0]["key"], "media/k1");
+    assert_eq!(manifest[0]["hash"], "h1");
+}
+
+#[test]
+fn translate_lang_tags_are_validated() {
+    use super::translate::normalize_lang;
+    assert_eq!(normalize_lang(" pt-BR ").unwrap(), "pt-BR");
+    assert_eq!(normalize_lang("zh-Hant").unwrap(), "zh-Hant");
+    for bad in ["", "e", "--help", "en_US", "en--US", "de;rm"] {
+        assert!(normalize_lang(bad).is_err(), "{bad:?} should be rejected");
+    }
+}
+
+#[test]
+fn translation_cache_is_dropped_when_source_changes() {
+    use super::translate::{cached_translation, store_translation};
+    let db = crate::db::local::LocalDb::open_in_memory().unwrap();
+    let conn = db.conn();
+    conn.execute(
+        "INSERT INTO message (id, conversation_id, sender_id, ciphertext, content, sent_at) \
+         VALUES ('m1', 'c1', 'alice', x'00', 'hello', '2024-01-01T10:00:00Z')",
+        [],
+    ).unwrap();
+    store_translation(conn, "m1", "de", "hallo").unwrap();
+    assert_eq!(cached_translation(conn, "m1", "de").unwrap().as_deref(), Some("hallo"));
+
+    // Unrelated column updates keep the cache.
+    conn.execute("UPDATE message SET delivered = 1 WHERE id = 'm1'", []).unwrap();
+    assert!(cached_translation(conn, "m1", "de").unwrap().is_some());
+
+    // An edit drops it.
+    conn.execute("UPDATE message SET content = 'hi' WHERE id = 'm1'", []).unwrap();
+    assert!(cached_translation(conn, "m1", "de").unwrap().is_none());
+
+    // So does eviction.
+    store_translation(conn, "m1", "de", "hallo").unwrap();
+    conn.execute("DELETE FROM message WHERE id = 'm1'", []).unwrap();
+    assert!(cached_translation(conn, "m1", "de").unwrap().is_none());
+}
+
+#[test]
+fn activity_rollup_follows_inserts_and_evictions() {
+    use super::activity::{activity, most_active};
+    use super::ActivityRange;
+    use chrono::TimeZone;
+    let db = crate::db::local::LocalDb::open_in_memory().unwrap();
+    let conn = db.conn();
+    let insert = |id: &str, conv: &str, sent_at: &str| {
+        conn.execute(
+            "INSERT INTO message (id, conversation_id, sender_id, ciphertext, content, sent_at) \
+             VALUES (?1, ?2, 'alice', x'00', 'hi', ?3)",
+            rusqlite::params![id, conv, sent_at],
+        ).unwrap();
+    };
+    insert("a1", "c1", "2024-03-10T09:05:00.123456789+00:00");
+    insert("a2", "c1", "2024-03-10T09:59:59Z");
+    insert("a3", "c1", "2024-03-10T11:30:00+02:00");
+    insert("a4", "c1", "2024-03-08T23:00:00Z");
+    insert("b1", "c2", "2024-03-10T10:00:00Z");
+    // Unparseable timestamps are stored but not counted.
+    insert("x1", "c1", "yesterday");
+    let now = chrono::Utc.with_ymd_and_hms(2024, 3, 10, 10, 15, 0).unwrap();
+
+    let day = activity(conn, "c1", ActivityRange::Day, now).unwrap();
+    assert_eq!(day.buckets.len(), 24);
+    assert_eq!(day.buckets[0].start, "2024-03-09T11:00:00Z");
+    assert_eq!(day.buckets[22].start, "2024-03-10T09:00:00Z");
+    assert_eq!(day.buckets[22].count, 3);
+    assert_eq!(day.total, 3);
+
+    let week = activity(conn, "c1", ActivityRange::Week, now).unwrap();
+    assert_eq!(week.buckets.len(), 7);
+    assert_eq!(week.buckets[6].start, "2024-03-10T00:00:00Z");
+    assert_eq!(week.buckets[6].count, 3);
+    assert_eq!(week.buckets[4].count, 1);
+    assert_eq!(week.total, 4);
+
+    let top = most_active(conn, ActivityRange::Day, 10, now).unwrap();
+    let top: Vec<_> = top.iter().map(|c| (c.channel_id.as_str(), c.count)).collect();
+    assert_eq!(top, [("c1", 3), ("c2", 1)]);
+
+    // Eviction takes messages back out; an emptied hour disappears.
+    conn.execute("DELETE FROM message WHERE id IN ('a1', 'a2', 'a3')", []).unwrap();
+    assert_eq!(activity(conn, "c1", ActivityRange::Day, now).unwrap().total, 0);
+    let rows: i64 = conn
+        .query_row("SELECT COUNT(*) FROM message_activity WHERE conversation_id = 'c1'", [], |r| r.get(0))
+        .unwrap();
+    assert_eq!(rows, 1);
+}
@@ -0,0 +1,263 @@
+//! On-device message translation through a user-configured local backend.
+//!
+//! Translation never leaves the device: the backend is an executable the user
+//! points Pollis at (an Argos / Bergamot / llama.cpp wrapper, say). It is run
+//! with the target language as its only argument, the decrypted text on
+//! stdin, and the translation read back from stdout. No shell is involved, so
+//! the configured value is a path, not a command line.
+//!
+//! Everything here is device-local and lives in the encrypted local DB:
+//!   - the backend path and each conversation's target language are
+//!     `ui_state` rows (never synced, like the retention window);
+//!   - translations are cached in `message_translation`, keyed by
+//!     (message, language). Triggers in `local_schema.sql` drop a message's
+//!     cached translations when its content changes (edit, redaction) or the
+//!     row is evicted, so a translation never outlives its source text.
+//!
+//! Attachment payloads translate their caption only. Mobile builds can't
+//! spawn a process, so `translate_message` errors there.
+
+use std::sync::Arc;
+use std::time::Duration;
+
+use rusqlite::{Connection, OptionalExtension};
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+use super::export::split_attachments;
+
+/// `ui_state` key holding the backend executable path.
+const BACKEND_KEY: &str = "translation_backend";
+
+/// `ui_state` key prefix for a conversation's target language.
+const LANG_KEY_PREFIX: &str = "translation_lang:";
+
+/// How long a backend gets per message before it is killed.
+const BACKEND_TIMEOUT: Duration = Duration::from_secs(30);
+
+/// Accept BCP 47-shaped tags only (`en`, `pt-BR`, `zh-Hant`). The tag is
+/// passed as an argv entry, so this also keeps it from reading as a flag.
+pub(super) fn normalize_lang(lang: &str) -> Result<String> {
+    let lang = lang.trim();
+    let valid = (2..=35).contains(&lang.len())
+        && lang.split('-').all(|part| {
+            !part.is_empty() && part.len() <= 8 && part.chars().all(|c| c.is_ascii_alphanumeric())
+        });
+    if !valid {
+        return Err(Error::Other(anyhow::anyhow!("invalid language tag '{lang}'")));
+    }
+    Ok(lang.to_string())
+}
+
+fn get_ui_state(conn: &Connection, key: &str) -> Result<Option<String>> {
+    Ok(conn
+        .query_row(
+            "SELECT value FROM ui_state WHERE key = ?1",
+            rusqlite::params![key],
+            |row| row.get(0),
+        )
+        .optional()?)
+}
+
+fn set_ui_state(conn: &Connection, key: &str, value: Option<&str>) -> Result<()> {
+    match value {
+        Some(v) => conn.execute(
+            "INSERT INTO ui_state (key, value, updated_at) VALUES (?1, ?2, datetime('now')) \
+             ON CONFLICT(key) DO UPDATE SET value = ?2, updated_at = datetime('now')",
+            rusqlite::params![key, v],
+        )?,
+        None => conn.execute("DELETE FROM ui_state WHERE key = ?1", rusqlite::params![key])?,
+    };
+    Ok(())
+}
+
+pub(super) fn cached_translation(
+    conn: &Connection,
+    message_id: &str,
+    target_lang: &str,
+) -> Result<Option<String>> {
+    Ok(conn
+        .query_row(
+            "SELECT content FROM message_translation WHERE message_id = ?1 AND target_lang = ?2",
+            rusqlite::params![message_id, target_lang],
+            |row| row.get(0),
+        )
+        .optional()?)
+}
+
+pub(super) fn store_translation(
+    conn: &Connection,
+    message_id: &str,
+    target_lang: &str,
+    content: &str,
+) -> Result<()> {
+    conn.execute(
+        "INSERT INTO message_translation (message_id, target_lang, content) VALUES (?1, ?2, ?3) \
+         ON CONFLICT(message_id, target_lang) DO UPDATE SET content = ?3, created_at = datetime('now')",
+        rusqlite::params![message_id, target_lang, content],
+    )?;
+    Ok(())
+}
+
+/// The configured backend path, if any.
+pub async fn get_translation_backend(state: &Arc<AppState>) -> Result<Option<String>> {
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    get_ui_state(db.conn(), BACKEND_KEY)
+}
+
+/// Set (or with `None` / blank, clear) the backend executable path.
+pub async fn set_translation_backend(path: Option<String>, state: &Arc<AppState>) -> Result<()> {
+    let path = path.map(|p| p.trim().to_string()).filter(|p| !p.is_empty());
+    if let Some(p) = &path {
+        if !std::path::Path::new(p).is_absolute() {
+            return Err(Error::Other(anyhow::anyhow!("translation backend must be an absolute path")));
+        }
+    }
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    set_ui_state(db.conn(), BACKEND_KEY, path.as_deref())
+}
+
+/// The target language set for `conversation_id`, if any.
+pub async fn get_conversation_translation_language(
+    conversation_id: String,
+    state: &Arc<AppState>,
+) -> Result<Option<String>> {
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    get_ui_state(db.conn(), &format!("{LANG_KEY_PREFIX}{conversation_id}"))
+}
+
+/// Set (or with `None`, clear) the target language for `conversation_id`.
+pub async fn set_conversation_translation_language(
+    conversation_id: String,
+    target_lang: Option<String>,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let target_lang = target_lang.as_deref().map(normalize_lang).transpose()?;
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    set_ui_state(
+        db.conn(),
+        &format!("{LANG_KEY_PREFIX}{conversation_id}"),
+        target_lang.as_deref(),
+    )
+}
+
+#[cfg(not(any(target_os = "ios", target_os = "android")))]
+async fn run_backend(backend: &str, target_lang: &str, text: &str) -> Result<String> {
+    use tokio::io::AsyncWriteExt;
+
+    let mut child = tokio::process::Command::new(backend)
+        .arg(target_lang)
+        .stdin(std::process::Stdio::piped())
+        .stdout(std::process::Stdio::piped())
+        .stderr(std::process::Stdio::piped())
+        .kill_on_drop(true)
+        .spawn()
+        .map_err(|e| Error::Other(anyhow::anyhow!("start translation backend: {e}")))?;
+    // Feed stdin while reading the output, both under the timeout: a backend
+    // that stalls without reading, or fills its stdout pipe before it has read
+    // everything, would otherwise hang the write with no deadline.
+    let stdin = child.stdin.take();
+    let write = async move {
+        if let Some(mut stdin) = stdin {
+            stdin.write_all(text.as_bytes()).await?;
+            // Dropping stdin closes it so the backend sees EOF.
+        }
+        Ok::<(), std::io::Error>(())
+    };
+    let (written, output) = tokio::time::timeout(BACKEND_TIMEOUT, async {
+        tokio::join!(write, child.wait_with_output())
+    })
+    .await
+    .map_err(|_| Error::Other(anyhow::anyhow!("translation backend timed out")))?;
+    let output = output.map_err(|e| Error::Other(anyhow::anyhow!("translation backend: {e}")))?;
+    if !output.status.success() {
+        let stderr = String::from_utf8_lossy(&output.stderr);
+        return Err(Error::Other(anyhow::anyhow!(
+            "translation backend failed ({}): {}",
+            output.status,
+            stderr.trim()
+        )));
+    }
+    written.map_err(|e| Error::Other(anyhow::anyhow!("write to translation backend: {e}")))?;
+    let translated = String::from_utf8(output.stdout)
+        .map_err(|_| Error::Other(anyhow::anyhow!("translation backend returned non-UTF-8 output")))?;
+    Ok(translated.trim_end().to_string())
+}
+
+#[cfg(any(target_os = "ios", target_os = "android"))]
+async fn run_backend(_backend: &str, _target_lang: &str, _text: &str) -> Result<String> {
+    Err(Error::Other(anyhow::anyhow!("local translation is not supported on this platform")))
+}
+
+/// Translate one decrypted message into `target_lang`, or — when that's
+/// `None` — the language set for the message's conversation. Served from the
+/// cache when present; otherwise runs the backend and caches the result.
+pub async fn translate_message(
+    message_id: String,
+    target_lang: Option<String>,
+    state: &Arc<AppState>,
+) -> Result<String> {
+    let (backend, target_lang, text) = {
+        let guard = state.local_db.lock().await;
+        let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+        let conn = db.conn();
+        let row: Option<(String, Option<String>)> = conn
+            .query_row(
+                "SELECT conversation_id, content FROM message WHERE id = ?1 AND deleted_at IS NULL",
+                rusqlite::params![message_id],
+                |row| Ok((row.get(0)?, row.get(1)?)),
+            )
+            .optional()?;
+        let Some((conversation_id, content)) = row else {
+            return Err(Error::NotFound("message".into()));
+        };
+        let target_lang = match target_lang {
+            Some(lang) => normalize_lang(&lang)?,
+            None => get_ui_state(conn, &format!("{LANG_KEY_PREFIX}{conversation_id}"))?
+                .ok_or_else(|| Error::Other(anyhow::anyhow!("no translation language set for this conversation")))?,
+        };
+        if let Some(cached) = cached_translation(conn, &message_id, &target_lang)? {
+            return Ok(cached);
+        }
+        let backend = get_ui_state(conn, BACKEND_KEY)?
+            .ok_or_else(|| Error::Other(anyhow::anyhow!("no translation backend configured")))?;
+        let content = content.unwrap_or_default();
+        let text = match split_attachments(&content) {
+            Some((caption, _)) => caption,
+            None => content,
+        };
+        (backend, target_lang, text)
+    };
+    if text.trim().is_empty() {
+        return Ok(String::new());
+    }
+
+    // The local DB lock is released while the backend runs.
+    let translated = run_backend(&backend, &target_lang, &text).await?;
+
+    let guard = state.local_db.lock().await;
+    let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+    // Re-check the source survived the wait: an edit or redaction that landed
+    // meanwhile must not get a translation of the old text cached against it.
+    let current: Option<Option<String>> = db
+        .conn()
+        .query_row(
+            "SELECT content FROM message WHERE id = ?1 AND deleted_at IS NULL",
+            rusqlite::params![message_id],
+            |row| row.get(0),
+        )
+        .optional()?;
+    let unchanged = match current.flatten() {
+        Some(c) => split_attachments(&c).map(|(caption, _)| caption).unwrap_or(c) == text,
+        None => false,
+    };
+    if unchanged {
+        store_translation(db.conn(), &message_id, &target_lang, &translated)?;
+    }
+    Ok(translated)
+}
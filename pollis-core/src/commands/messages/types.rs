@@ -38,6 +38,23 @@ pub struct ChannelPreview {
     pub last_sender_username: Option<String>,
 }
 
+/// The newest visible message in a conversation, read from the local cache so
+/// the sidebar can render without decrypting anything. `kind` is `"text"` or
+/// `"attachment"`; `snippet` is at most [`PREVIEW_SNIPPET_CHARS`] characters.
+///
+/// [`PREVIEW_SNIPPET_CHARS`]: super::read::PREVIEW_SNIPPET_CHARS
+#[derive(Debug, Serialize, Deserialize)]
+pub struct ConversationPreview {
+    pub conversation_id: String,
+    pub message_id: String,
+    pub sender_id: String,
+    pub sender_username: Option<String>,
+    pub kind: String,
+    pub snippet: String,
+    pub sent_at: String,
+    pub edited_at: Option<String>,
+}
+
 /// A single message row returned by the channel message queries.
 #[derive(Debug, Serialize, Deserialize)]
 pub struct ChannelMessage {
@@ -51,6 +68,10 @@ pub struct ChannelMessage {
     pub sent_at: String,
     pub edited_at: Option<String>,
     pub deleted_at: Option<String>,
+    /// The sender is muted in this channel's group by this account
+    /// (`muted_members` in the synced preferences). Always false in a DM.
+    #[serde(default)]
+    pub sender_muted: bool,
 }
 
 /// Opaque pagination cursor — the (sent_at, id) of the oldest row on the
@@ -71,6 +92,72 @@ pub struct MessagePage {
     pub next_cursor: Option<MessageCursor>,
 }
 
+/// Which messages a filtered timeline shows (`read_filtered_messages`). Every
+/// set field must match; an empty filter is the plain timeline minus deleted
+/// messages.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct MessageFilter {
+    /// Only messages with attachments.
+    #[serde(default)]
+    pub media: bool,
+    /// Only messages whose text contains a web link.
+    #[serde(default)]
+    pub links: bool,
+    /// Only messages from this member.
+    #[serde(default)]
+    pub sender_id: Option<String>,
+    /// Only the conversation's newest `n` messages — the client's unread count,
+    /// since read state isn't stored locally.
+    #[serde(default)]
+    pub unread: Option<i64>,
+}
+
+/// How far back `get_channel_activity` looks: the last 24 hours by hour, or
+/// the last 7 / 30 days by day.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+pub enum ActivityRange {
+    #[serde(rename = "24h")]
+    Day,
+    #[serde(rename = "7d")]
+    Week,
+    #[serde(rename = "30d")]
+    Month,
+}
+
+/// Messages sent in one hour or day, starting at `start` (RFC 3339, UTC).
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ActivityBucket {
+    pub start: String,
+    pub count: i64,
+}
+
+/// A channel's or DM's message counts over an `ActivityRange`, oldest bucket
+/// first, with empty buckets filled in so the list is always the full range.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ChannelActivity {
+    pub channel_id: String,
+    pub range: ActivityRange,
+    pub buckets: Vec<ActivityBucket>,
+    pub total: i64,
+}
+
+/// One row of `get_most_active_channels`.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ActiveChannel {
+    pub channel_id: String,
+    pub count: i64,
+}
+
+/// Ordinary messages one ingest stored for the first time in one conversation,
+/// by sender (one entry per message). A channel ingest catches up the whole
+/// group, so it can report sibling channels too; conversations with nothing
+/// new are left out.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct IngestedMessages {
+    pub conversation_id: String,
+    pub sender_ids: Vec<String>,
+}
+
 /// A search result from the local message cache.
 #[derive(Debug, Serialize, Deserialize)]
 pub struct SearchResult {
@@ -151,9 +151,7 @@ pub async fn ensure_device_cert(
     //    capture its public bytes. Sync openmls work inside a scope.
     let sig_pub_bytes = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| {
-            crate::error::Error::Other(anyhow::anyhow!("Not signed in"))
-        })?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         let provider = PollisProvider::new(db.conn());
         let (_sig_keys, sig_pub_bytes) =
             load_or_create_device_signer(&provider, user_id, device_id)?;
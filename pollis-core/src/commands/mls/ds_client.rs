@@ -23,6 +23,7 @@
 //! simply ignored, so signing every request is harmless in that mode.
 
 use std::sync::Arc;
+use std::time::{Duration, Instant};
 
 use openmls_traits::signatures::Signer;
 use sha2::{Digest, Sha256};
@@ -60,6 +61,89 @@ fn now_unix() -> i64 {
         .unwrap_or(0)
 }
 
+/// Deadline for one DS call, from connect to the last byte of the response,
+/// resends included. DS requests and replies are small JSON, so this only trips
+/// on a hung DS or relay circuit; without it a stalled call would block the
+/// command (and any lock it holds) indefinitely. Bulk bytes go to R2 over
+/// presigned URLs and aren't covered.
+const DS_REQUEST_TIMEOUT: Duration = Duration::from_secs(30);
+
+/// Deadline for metadata calls — LiveKit tokens, presigns, usage, the
+/// maintenance window, storage targets. They answer from memory or one indexed
+/// read, so a slow one is a hung one, and a user is usually waiting on it.
+/// Calls that make the DS reach an outside API (Turso Platform, LiveKit's
+/// `RoomService`) go through [`ds_post`] instead.
+const DS_METADATA_TIMEOUT: Duration = Duration::from_secs(5);
+
+/// [`DS_METADATA_TIMEOUT`] when the overlay is on: building a relay circuit
+/// and crossing its hops costs seconds before the DS sees the request.
+const DS_METADATA_OVERLAY_TIMEOUT: Duration = Duration::from_secs(15);
+
+/// The metadata deadline for `state`'s current route to the DS.
+fn metadata_timeout(state: &AppState) -> Duration {
+    if state.overlay_handle().is_some() {
+        DS_METADATA_OVERLAY_TIMEOUT
+    } else {
+        DS_METADATA_TIMEOUT
+    }
+}
+
+/// Sends of one [`ds_post`] call, counting the first. Every send carries the
+/// same `Idempotency-Key`, so the DS answers a resend with the stored reply
+/// (`pollis_delivery::idempotency`) — but only while the key is still in its
+/// store, which is per instance and gone after a restart unless the DS runs
+/// with `DS_SHARED_STATE=db`. See [`resend_is_safe`].
+const DS_POST_ATTEMPTS: usize = 3;
+
+/// Routes whose handler leaves the same state when it runs twice: an upsert, an
+/// insert that ignores a duplicate, an envelope send (a stored envelope is
+/// answered as a duplicate), or a metadata call that only mints or reads.
+/// Anything else — a key-package claim, a group or channel create, an invite —
+/// would run again on an instance that hasn't seen its `Idempotency-Key`.
+const RESEND_SAFE_PATHS: &[&str] = &[
+    "/v1/messages/send",
+    "/v1/reactions/add",
+    "/v1/pins/add",
+    "/v1/watermarks/advance",
+    "/v1/group-info",
+    "/v1/profile/preferences",
+    "/v1/blocks/add",
+    "/v1/account/check-in",
+    "/v1/usage/me",
+    "/v1/livekit/token",
+    "/v1/livekit/participants",
+    "/v1/livekit/send-data",
+    "/v1/turso/token",
+    "/v1/r2/presign",
+];
+
+/// Whether a send of `path` that may have reached the DS can be made again.
+/// A send that never connected can always be, wherever it goes.
+fn resend_is_safe(path: &str) -> bool {
+    RESEND_SAFE_PATHS.contains(&path)
+}
+
+/// Wait between two sends of one [`ds_post`] call.
+const DS_RESEND_PAUSE: Duration = Duration::from_secs(1);
+
+/// Time left for a resend of a call due at `deadline`, once
+/// [`DS_RESEND_PAUSE`] has passed — `None` when there'd be none.
+fn resend_budget(deadline: Instant, now: Instant) -> Option<Duration> {
+    deadline
+        .checked_duration_since(now + DS_RESEND_PAUSE)
+        .filter(|left| !left.is_zero())
+}
+
+/// Map a send failure to [`Error::Timeout`] when it was the deadline, so
+/// callers and the frontend can tell "slow" from "refused".
+fn send_error(what: String, e: reqwest::Error) -> Error {
+    if e.is_timeout() {
+        Error::Timeout(what)
+    } else {
+        Error::Other(anyhow::anyhow!("{what}: {e}"))
+    }
+}
+
 /// The signing user's id for THIS `AppState`.
 ///
 /// Prefer the instance's own authenticated session (`state.unlock`). Co-located
@@ -87,13 +171,44 @@ async fn current_user_id(state: &Arc<AppState>) -> Result<String> {
 /// slash and no query (e.g. `/v1/group-info`) — it must match what the DS sees,
 /// since it is bound into the signed canonical message.
 ///
+/// A send that couldn't connect is resent (up to [`DS_POST_ATTEMPTS`]) with the
+/// same signature and `Idempotency-Key`. One that may have reached the DS — it
+/// timed out, failed mid-request, or met the DS still running the first — is
+/// resent only when [`resend_is_safe`] says the route may run twice; otherwise
+/// the caller gets the failure.
+///
+/// The whole call, resends included, gets [`DS_REQUEST_TIMEOUT`]; a resend
+/// that couldn't start before it runs out isn't made.
+///
 /// Returns the raw [`reqwest::Response`] so callers map status codes themselves
-/// (e.g. 409 → `LostRace` on the commit path).
+/// (e.g. 409 → `LostRace` on the commit path) — except a maintenance refusal,
+/// which is [`Error::Maintenance`] (`commands::maintenance`).
 pub async fn ds_post(
     state: &Arc<AppState>,
     path: &str,
     body: &serde_json::Value,
 ) -> Result<reqwest::Response> {
+    ds_post_within(state, path, body, DS_REQUEST_TIMEOUT).await
+}
+
+/// [`ds_post`] for metadata calls, under [`DS_METADATA_TIMEOUT`] (or
+/// [`DS_METADATA_OVERLAY_TIMEOUT`] through the overlay).
+pub async fn ds_post_metadata(
+    state: &Arc<AppState>,
+    path: &str,
+    body: &serde_json::Value,
+) -> Result<reqwest::Response> {
+    ds_post_within(state, path, body, metadata_timeout(state)).await
+}
+
+/// [`ds_post`] with the whole call, resends included, bounded by `timeout`.
+async fn ds_post_within(
+    state: &Arc<AppState>,
+    path: &str,
+    body: &serde_json::Value,
+    timeout: Duration,
+) -> Result<reqwest::Response> {
+    let deadline = Instant::now() + timeout;
     let base = state
         .config
         .pollis_delivery_url
@@ -132,20 +247,51 @@ pub async fn ds_post(
     };
 
     let url = format!("{}{}", base.trim_end_matches('/'), path);
+    // One key for every send of this call: a resend after a dropped reply gets
+    // the first reply back instead of applying the write twice.
+    let idempotency_key = ulid::Ulid::new().to_string();
     // First-party DS write — route through the overlay when it is on (§14.2).
     let overlay = state.overlay_handle();
-    let resp = crate::net::overlay::http_client(overlay.as_deref())
-        .post(&url)
-        .header("X-Pollis-User", &user_id)
-        .header("X-Pollis-Device", &device_id)
-        .header("X-Pollis-Timestamp", timestamp.to_string())
-        .header("X-Pollis-Signature", signature_b64)
-        .header(reqwest::header::CONTENT_TYPE, "application/json")
-        .body(body_bytes)
-        .send()
-        .await
-        .map_err(|e| Error::Other(anyhow::anyhow!("ds_post {path}: {e}")))?;
-    Ok(resp)
+    let client = crate::net::overlay::http_client(overlay.as_deref());
+    let mut attempt = 1;
+    let mut left = timeout;
+    loop {
+        let sent = client
+            .post(&url)
+            .header("X-Pollis-User", &user_id)
+            .header("X-Pollis-Device", &device_id)
+            .header("X-Pollis-Timestamp", timestamp.to_string())
+            .header("X-Pollis-Signature", &signature_b64)
+            .header("Idempotency-Key", &idempotency_key)
+            .header(reqwest::header::CONTENT_TYPE, "application/json")
+            .body(body_bytes.clone())
+            .timeout(left)
+            .send()
+            .await;
+        let resend =
+            resend_budget(deadline, Instant::now()).filter(|_| attempt < DS_POST_ATTEMPTS);
+        let retry = resend.is_some()
+            && match &sent {
+                // Never reached the DS.
+                Err(e) if e.is_connect() => true,
+                Err(e) => (e.is_timeout() || e.is_request()) && resend_is_safe(path),
+                // The first send is still running on the DS: wait it out.
+                Ok(resp) => {
+                    resp.status() == reqwest::StatusCode::CONFLICT
+                        && resp.headers().contains_key(reqwest::header::RETRY_AFTER)
+                        && resend_is_safe(path)
+                }
+            };
+        if !retry {
+            let resp = sent.map_err(|e| send_error(format!("ds_post {path}"), e))?;
+            // A maintenance refusal becomes `Error::Maintenance` (and raises
+            // the banner); every other reply passes through.
+            return crate::commands::maintenance::observe(resp).await;
+        }
+        attempt += 1;
+        left = resend.unwrap_or(left);
+        tokio::time::sleep(DS_RESEND_PAUSE).await;
+    }
 }
 
 /// Claim one of `target_user_id`'s (optionally a specific device's) unclaimed
@@ -196,7 +342,7 @@ pub async fn ds_claim_key_package(
 /// Ask the DS to mint a LiveKit **participant** token for `room`. `kind` selects
 /// the identity scheme (`"realtime"` / `"voice"` / `"view"`) — the user+device
 /// halves are always derived server-side from the verified signer, so a client
-/// cannot mint a token as another user/device. Device-signed via [`ds_post`].
+/// cannot mint a token as another user/device. Device-signed via [`ds_post_metadata`].
 /// Returns `(token, ws_url)`. Replaces the on-device `livekit_jwt::make_token`
 /// (which held the LiveKit API secret).
 pub async fn ds_livekit_token(
@@ -205,7 +351,7 @@ pub async fn ds_livekit_token(
     kind: &str,
 ) -> Result<(String, String)> {
     let body = serde_json::json!({ "room": room, "kind": kind });
-    let resp = ds_post(state, "/v1/livekit/token", &body).await?;
+    let resp = ds_post_metadata(state, "/v1/livekit/token", &body).await?;
     let status = resp.status();
     if !status.is_success() {
         let txt = resp.text().await.unwrap_or_default();
@@ -226,7 +372,8 @@ pub async fn ds_livekit_token(
 /// Fan out a **content-free** control `payload` to a LiveKit `room` via the DS's
 /// server-side `RoomService/SendData` (the admin secret stays server-side).
 /// Replaces the on-device `make_admin_token` + Twirp POST. Best-effort — the DS
-/// treats a room with no participants (404) as success.
+/// treats a room with no participants (404) as success. Goes through [`ds_post`]:
+/// the DS waits on LiveKit's API before it answers.
 pub async fn ds_livekit_send_data(
     state: &Arc<AppState>,
     room: &str,
@@ -247,7 +394,8 @@ pub async fn ds_livekit_send_data(
 /// List a voice room's roster via the DS (server-side `ListParticipants`).
 /// Returns `(identity, display_name)` pairs; internal participants are already
 /// filtered server-side. Replaces `room_service_list_participants`. Desktop-only
-/// — mobile has no Rust-side voice roster (see `livekit_stub`).
+/// — mobile has no Rust-side voice roster (see `livekit_stub`). Goes through
+/// [`ds_post`], like [`ds_livekit_send_data`].
 #[cfg(feature = "media")]
 pub async fn ds_livekit_participants(
     state: &Arc<AppState>,
@@ -285,7 +433,8 @@ pub async fn ds_livekit_participants(
 /// Mint a short-TTL **read-only** Turso token via the DS. Returns `(token,
 /// expires_in_secs)`. Device-signed. Any error (incl. 503 when the DS has no
 /// Turso Platform credentials) lets the caller fall back to the baked read-only
-/// token, so an unconfigured deploy still reads. See #393.
+/// token, so an unconfigured deploy still reads. See #393. Goes through
+/// [`ds_post`]: the DS mints the token with a Turso Platform API call.
 pub async fn ds_turso_token(state: &Arc<AppState>) -> Result<(String, u64)> {
     let resp = ds_post(state, "/v1/turso/token", &serde_json::json!({})).await?;
     let status = resp.status();
@@ -411,6 +560,58 @@ fn delivery_base(state: &Arc<AppState>) -> Result<String> {
         .ok_or_else(|| Error::Other(anyhow::anyhow!("pollis_delivery_url not configured")))
 }
 
+/// GET `/v1/maintenance` — the DS's maintenance window. Unauthenticated, like
+/// `/v1/limits`.
+pub async fn ds_maintenance_status(
+    state: &Arc<AppState>,
+) -> Result<crate::commands::maintenance::MaintenanceStatus> {
+    let url = format!("{}/v1/maintenance", delivery_base(state)?);
+    let overlay = state.overlay_handle();
+    let resp = crate::net::overlay::http_client(overlay.as_deref())
+        .get(&url)
+        .timeout(metadata_timeout(state))
+        .send()
+        .await
+        .map_err(|e| send_error("ds_maintenance_status".to_string(), e))?;
+    if !resp.status().is_success() {
+        let s = resp.status();
+        let txt = resp.text().await.unwrap_or_default();
+        return Err(Error::Other(anyhow::anyhow!("ds_maintenance_status {s}: {txt}")));
+    }
+    resp.json()
+        .await
+        .map_err(|e| Error::Other(anyhow::anyhow!("ds_maintenance_status decode: {e}")))
+}
+
+/// GET `/v1/storage/targets` — the storage targets a group owner can pin
+/// attachments to. Unauthenticated, like `/v1/limits`.
+pub async fn ds_storage_targets(
+    state: &Arc<AppState>,
+) -> Result<Vec<crate::commands::groups::StorageTarget>> {
+    #[derive(serde::Deserialize)]
+    struct Targets {
+        targets: Vec<crate::commands::groups::StorageTarget>,
+    }
+    let url = format!("{}/v1/storage/targets", delivery_base(state)?);
+    let overlay = state.overlay_handle();
+    let resp = crate::net::overlay::http_client(overlay.as_deref())
+        .get(&url)
+        .timeout(metadata_timeout(state))
+        .send()
+        .await
+        .map_err(|e| send_error("ds_storage_targets".to_string(), e))?;
+    if !resp.status().is_success() {
+        let s = resp.status();
+        let txt = resp.text().await.unwrap_or_default();
+        return Err(Error::Other(anyhow::anyhow!("ds_storage_targets {s}: {txt}")));
+    }
+    let parsed: Targets = resp
+        .json()
+        .await
+        .map_err(|e| Error::Other(anyhow::anyhow!("ds_storage_targets decode: {e}")))?;
+    Ok(parsed.targets)
+}
+
 /// POST `body` (JSON) to `{pollis_delivery_url}{path}` with NO auth headers — the
 /// pre-identity OTP endpoints (`request-otp` / `verify-otp`), which the DS gates
 /// by the OTP itself, not a device signature or a session. Returns the raw
@@ -426,9 +627,10 @@ pub async fn ds_post_plain(
         .post(&url)
         .header(reqwest::header::CONTENT_TYPE, "application/json")
         .json(body)
+        .timeout(DS_REQUEST_TIMEOUT)
         .send()
         .await
-        .map_err(|e| Error::Other(anyhow::anyhow!("ds_post_plain {path}: {e}")))
+        .map_err(|e| send_error(format!("ds_post_plain {path}"), e))
 }
 
 /// Sign-free sibling of [`ds_post`] for the OTP-session-gated bootstrap writes
@@ -451,9 +653,10 @@ pub async fn ds_post_session(
         .header("X-Pollis-Session", session_token)
         .header(reqwest::header::CONTENT_TYPE, "application/json")
         .body(body_bytes)
+        .timeout(DS_REQUEST_TIMEOUT)
         .send()
         .await
-        .map_err(|e| Error::Other(anyhow::anyhow!("ds_post_session {path}: {e}")))
+        .map_err(|e| send_error(format!("ds_post_session {path}"), e))
 }
 
 /// [`ds_post_session`] for bootstrap writes that must NOT silently fail: any
@@ -474,3 +677,39 @@ pub async fn ds_post_session_ok(
     }
     Ok(())
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn resends_stay_inside_the_call_deadline() {
+        let now = Instant::now();
+        // A fast failure early in a 30 s call leaves room for a resend.
+        assert_eq!(
+            resend_budget(now + DS_REQUEST_TIMEOUT, now),
+            Some(DS_REQUEST_TIMEOUT - DS_RESEND_PAUSE)
+        );
+        // A metadata call gets its resend too, inside the same 5 s.
+        assert_eq!(
+            resend_budget(now + DS_METADATA_TIMEOUT, now),
+            Some(DS_METADATA_TIMEOUT - DS_RESEND_PAUSE)
+        );
+        // The overlay's budget leaves more room than the direct one.
+        assert!(DS_METADATA_OVERLAY_TIMEOUT > DS_METADATA_TIMEOUT);
+        // A send that used up the deadline (timed out) isn't resent.
+        assert_eq!(resend_budget(now, now), None);
+        assert_eq!(resend_budget(now + DS_RESEND_PAUSE, now), None);
+        assert_eq!(resend_budget(now, now + DS_REQUEST_TIMEOUT), None);
+    }
+
+    #[test]
+    fn only_rerunnable_routes_are_resent() {
+        assert!(resend_is_safe("/v1/messages/send"));
+        assert!(resend_is_safe("/v1/r2/presign"));
+        // Each of these would apply again on an instance without the key.
+        assert!(!resend_is_safe("/v1/key-packages/claim"));
+        assert!(!resend_is_safe("/v1/groups/create"));
+        assert!(!resend_is_safe("/v1/commits"));
+    }
+}
@@ -324,9 +324,7 @@ async fn external_join_attempt(
     //    join only adds self.
     let (commit_bytes, new_group_info_bytes): (Vec<u8>, Option<Vec<u8>>) = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| {
-            crate::error::Error::Other(anyhow::anyhow!("Not signed in"))
-        })?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         let provider = PollisProvider::new(db.conn());
 
         let mut env_reader: &[u8] = &group_info_bytes;
@@ -508,9 +506,7 @@ pub async fn init_mls_group(
     // publish_group_info call below (which re-acquires it).
     {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| {
-            crate::error::Error::Other(anyhow::anyhow!("Not signed in"))
-        })?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         let provider = PollisProvider::new(db.conn());
 
         let (sig_keys, sig_pub_bytes) =
@@ -609,9 +605,7 @@ pub async fn forget_local_mls_group(
     group_id: &str,
 ) -> crate::error::Result<()> {
     let guard = state.local_db.lock().await;
-    let db = guard.as_ref().ok_or_else(|| {
-        crate::error::Error::Other(anyhow::anyhow!("Not signed in"))
-    })?;
+    let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
     let provider = PollisProvider::new(db.conn());
     let mls_group_id = GroupId::from_slice(group_id.as_bytes());
 
@@ -873,9 +867,7 @@ async fn process_pending_commits_locked_impl(
     // 1. Get the current epoch from the local group.
     let has_group = {
         let guard = state.local_db.lock().await;
-        let db = guard.as_ref().ok_or_else(|| {
-            crate::error::Error::Other(anyhow::anyhow!("Not signed in"))
-        })?;
+        let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
         let provider = PollisProvider::new(db.conn());
         let group_id = GroupId::from_slice(mls_group_id.as_bytes());
         MlsGroup::load(provider.storage(), &group_id)
@@ -3,7 +3,8 @@
 //! The KeyPackage pool (build + publish + replenish) that precedes any MLS
 //! group operation. `ensure_mls_key_package` rotates this device's pool on
 //! login; `replenish_key_packages` tops it up after welcomes consume packages.
-//! Both route owner-scoped writes through the Delivery Service.
+//! Both route owner-scoped writes through the Delivery Service, and both size
+//! the pool from recent demand ([`pool_target`]).
 
 use openmls::prelude::*;
 use openmls_rust_crypto::RustCrypto;
@@ -20,6 +21,41 @@ use super::provider::{make_credential, parse_credential_user_id, PollisProvider,
 
 // ── Key-package pool ──────────────────────────────────────────────────────────
 
+/// Pool floor: what a device that is never added to anything keeps on hand.
+const POOL_MIN: i64 = 5;
+/// Pool ceiling, bounding both remote storage and login-time build work.
+const POOL_MAX: i64 = 50;
+/// How far back [`recent_claims`] looks.
+const DEMAND_WINDOW: &str = "-7 days";
+
+/// How many unclaimed packages this device should keep published, given how
+/// many were claimed from it over [`DEMAND_WINDOW`]: a week's demand, clamped
+/// to `POOL_MIN..=POOL_MAX`. A busy device stays invitable between its own
+/// welcome polls; a quiet one doesn't litter the table.
+pub(super) fn pool_target(recent_claims: i64) -> i64 {
+    recent_claims.clamp(POOL_MIN, POOL_MAX)
+}
+
+/// Packages claimed from this device within [`DEMAND_WINDOW`] (`claimed_at`,
+/// migration 000021, stamped by the DS claim). A READ, so it stays direct on
+/// the remote handle. Rows claimed before the migration have no timestamp and
+/// don't count.
+async fn recent_claims(state: &Arc<AppState>, user_id: &str, device_id: &str) -> Result<i64> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn.query(
+        "SELECT COUNT(*) FROM mls_key_package \
+         WHERE user_id = ?1 AND device_id = ?2 AND claimed = 1 \
+           AND claimed_at >= datetime('now', ?3)",
+        libsql::params![user_id, device_id, DEMAND_WINDOW],
+    ).await?;
+    let n = if let Some(row) = rows.next().await? {
+        row.get(0)?
+    } else {
+        0
+    };
+    Ok(n)
+}
+
 /// Shape `(ref_hash, key_package_bytes)` pairs into the DS write-API
 /// `packages` array: each `key_package` is base64 (STANDARD), since the bytes
 /// are binary (the domain convention — see `pollis_delivery::devices`).
@@ -47,9 +83,7 @@ async fn build_one_key_package(
     device_id: &str,
 ) -> Result<(String, Vec<u8>)> {
     let guard = state.local_db.lock().await;
-    let db = guard.as_ref().ok_or_else(|| {
-        crate::error::Error::Other(anyhow::anyhow!("Not signed in"))
-    })?;
+    let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
     let provider = PollisProvider::new(db.conn());
 
     let (sig_keys, sig_pub_bytes) =
@@ -82,7 +116,8 @@ async fn build_one_key_package(
 }
 
 /// Rotate key packages: delete unclaimed packages for this device from the
-/// remote table and publish TARGET fresh ones backed by the current local DB.
+/// remote table and publish [`pool_target`] fresh ones backed by the current
+/// local DB.
 ///
 /// Called from `initialize_identity` on every login.  Only deletes packages
 /// for the current `device_id` — other devices' packages are left intact.
@@ -91,12 +126,12 @@ pub async fn ensure_mls_key_package(
     user_id: &str,
     device_id: &str,
 ) -> Result<()> {
-    const TARGET: i64 = 5;
+    let target = pool_target(recent_claims(state, user_id, device_id).await?);
 
-    // Build TARGET fresh packages locally (their private keys live in the local
+    // Build `target` fresh packages locally (their private keys live in the local
     // DB) before any remote write, so the DS replenish is a single batched call.
-    let mut pairs: Vec<(String, Vec<u8>)> = Vec::with_capacity(TARGET as usize);
-    for _ in 0..TARGET {
+    let mut pairs: Vec<(String, Vec<u8>)> = Vec::with_capacity(target as usize);
+    for _ in 0..target {
         pairs.push(build_one_key_package(state, user_id, device_id).await?);
     }
 
@@ -135,7 +170,8 @@ pub async fn ensure_mls_key_package(
     Ok(())
 }
 
-/// Top-up key packages for this device to TARGET without deleting existing ones.
+/// Top-up key packages for this device to [`pool_target`] without deleting
+/// existing ones.
 /// Called after processing welcomes (which consume KPs) so the device stays
 /// reachable for future group invites.
 pub(super) async fn replenish_key_packages(
@@ -143,7 +179,7 @@ pub(super) async fn replenish_key_packages(
     user_id: &str,
     device_id: &str,
 ) -> Result<()> {
-    const TARGET: i64 = 5;
+    let target = pool_target(recent_claims(state, user_id, device_id).await?);
 
     // Counting remaining packages is a READ — it stays direct on the local
     // libsql handle even when DS writes are enabled.
@@ -162,7 +198,7 @@ pub(super) async fn replenish_key_packages(
         n
     };
 
-    let needed = TARGET - remaining;
+    let needed = target - remaining;
     if needed <= 0 {
         return Ok(());
     }
@@ -234,3 +270,15 @@ pub fn validate_key_package(
 
     Ok(hex::encode(hash_ref.as_slice()))
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn pool_follows_demand_within_bounds() {
+        assert_eq!(pool_target(0), POOL_MIN);
+        assert_eq!(pool_target(12), 12);
+        assert_eq!(pool_target(10_000), POOL_MAX);
+    }
+}
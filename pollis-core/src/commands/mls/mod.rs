@@ -9,6 +9,7 @@ mod ds_client;
 mod group_state;
 pub mod invariants;
 mod key_packages;
+mod obligations;
 mod provider;
 mod reconcile;
 mod sweep;
@@ -27,9 +28,9 @@ pub use device::{
 
 // ── Signed Delivery-Service write client (4 `X-Pollis-*` headers) ────────────
 pub(crate) use ds_client::{
-    ds_claim_key_package, ds_livekit_send_data, ds_livekit_token, ds_post, ds_post_ok,
-    ds_post_plain, ds_post_session_ok, ds_post_signed_or_session, ds_post_signed_or_session_ok,
-    ds_turso_token,
+    ds_claim_key_package, ds_livekit_send_data, ds_livekit_token, ds_post, ds_post_metadata,
+    ds_post_ok, ds_post_plain, ds_post_session_ok, ds_post_signed_or_session,
+    ds_post_signed_or_session_ok, ds_turso_token,
 };
 // Desktop-only (voice roster); mobile has no Rust-side participants path.
 #[cfg(feature = "media")]
@@ -51,7 +52,12 @@ pub use group_state::{
 };
 
 // ── Cold-launch / post-reconnect sweep ──────────────────────────────────────
-pub use sweep::catch_up_all_mls_groups;
+pub use sweep::{catch_up_all_mls_groups, send_preflight};
+pub use obligations::{
+    get_crypto_obligations, retry_crypto_obligations, run_crypto_obligations,
+    subscribe_crypto_obligation_events, CryptoObligation, CryptoObligations, ObligationKind,
+    ObligationStatus,
+};
 
 // ── Reconcile + self-repair ──────────────────────────────────────────────────
 pub use reconcile::{
@@ -0,0 +1,409 @@
+//! Crypto obligations: the MLS work a device owes after being offline.
+//!
+//! While a device is away, other devices and members keep moving. It comes
+//! back owing work:
+//!
+//!   - **device certs**: a sibling device may have rotated the account
+//!     identity, so this account's device certs need re-signing
+//!     ([`resign_stale_device_certs`](super::resign_stale_device_certs));
+//!   - **welcomes**: groups and DMs it was added to wait as Welcomes;
+//!   - **key packages**: those Welcomes (and any adds that claimed a package)
+//!     drained its published pool, which needs topping up;
+//!   - **conversations**: every group and DM has commits and messages to catch
+//!     up on, and possibly a dropped remove/eviction to redo (the reconcile
+//!     backstop in `sweep.rs`).
+//!
+//! [`catch_up_all_mls_groups`](super::catch_up_all_mls_groups) enumerates
+//! these into a queue and runs it in dependency order. An obligation whose
+//! dependency failed is `blocked` instead of being run against state that
+//! can't be right (a conversation can't be caught up before its Welcome is
+//! applied). Failures don't stop the rest of the queue; they stay in it until
+//! [`retry_crypto_obligations`] re-runs the failed and blocked ones.
+//!
+//! Each change is pushed to the sink registered with
+//! [`subscribe_crypto_obligation_events`] (the status bar). One queue per
+//! process, for the signed-in user; runs never overlap.
+
+use std::sync::{Arc, Mutex as StdMutex};
+
+use serde::Serialize;
+
+use crate::error::Result;
+use crate::sink::EventSink;
+use crate::state::AppState;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ObligationKind {
+    DeviceCerts,
+    Welcomes,
+    KeyPackages,
+    Conversation,
+}
+
+impl ObligationKind {
+    /// Obligations of these kinds must have succeeded first.
+    fn depends_on(self) -> &'static [ObligationKind] {
+        match self {
+            Self::DeviceCerts | Self::Welcomes => &[],
+            // Welcomes consume packages, and packages are only worth
+            // publishing under a cert other devices accept.
+            Self::KeyPackages => &[Self::DeviceCerts, Self::Welcomes],
+            // A Welcome may be what creates the conversation's local group.
+            Self::Conversation => &[Self::Welcomes],
+        }
+    }
+
+    fn label(self) -> &'static str {
+        match self {
+            Self::DeviceCerts => "device certificates",
+            Self::Welcomes => "welcomes",
+            Self::KeyPackages => "key packages",
+            Self::Conversation => "conversation catch-up",
+        }
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ObligationStatus {
+    Pending,
+    Running,
+    Done,
+    Failed,
+    /// Not run because a dependency failed.
+    Blocked,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct CryptoObligation {
+    /// The kind (`welcomes`, …), or `conversation:<mls group id>`.
+    pub id: String,
+    pub kind: ObligationKind,
+    /// The MLS group id (group id or DM channel id) for `conversation`.
+    pub conversation_id: Option<String>,
+    pub status: ObligationStatus,
+    pub error: Option<String>,
+}
+
+/// The queue as the frontend sees it.
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct CryptoObligations {
+    pub user_id: Option<String>,
+    pub obligations: Vec<CryptoObligation>,
+    /// A run or retry is working through the queue.
+    pub running: bool,
+}
+
+static QUEUE: StdMutex<Option<CryptoObligations>> = StdMutex::new(None);
+static SINK: StdMutex<Option<Arc<dyn EventSink<CryptoObligations>>>> = StdMutex::new(None);
+/// Held for a whole run so a retry (or a second sweep) waits its turn.
+static RUN: tokio::sync::Mutex<()> = tokio::sync::Mutex::const_new(());
+
+/// Route queue changes to `sink`, replacing any previous subscriber. The
+/// current queue is sent straight away so a late subscriber isn't stale.
+pub fn subscribe_crypto_obligation_events(sink: Arc<dyn EventSink<CryptoObligations>>) {
+    let _ = sink.send(get_crypto_obligations());
+    if let Ok(mut guard) = SINK.lock() {
+        *guard = Some(sink);
+    }
+}
+
+/// The queue as last run (empty before the first sweep).
+pub fn get_crypto_obligations() -> CryptoObligations {
+    QUEUE
+        .lock()
+        .ok()
+        .and_then(|g| g.clone())
+        .unwrap_or_default()
+}
+
+/// Change the queue and tell the subscriber.
+fn update(f: impl FnOnce(&mut CryptoObligations)) {
+    let snapshot = match QUEUE.lock() {
+        Ok(mut guard) => {
+            let queue = guard.get_or_insert_with(CryptoObligations::default);
+            f(queue);
+            queue.clone()
+        }
+        Err(_) => return,
+    };
+    let sink = SINK.lock().ok().and_then(|g| g.clone());
+    if let Some(sink) = sink {
+        let _ = sink.send(snapshot);
+    }
+}
+
+fn obligation(kind: ObligationKind, conversation_id: Option<&str>) -> CryptoObligation {
+    let id = match (kind, conversation_id) {
+        (ObligationKind::Conversation, Some(cid)) => format!("conversation:{cid}"),
+        _ => serde_json::to_value(kind)
+            .ok()
+            .and_then(|v| v.as_str().map(str::to_owned))
+            .unwrap_or_default(),
+    };
+    CryptoObligation {
+        id,
+        kind,
+        conversation_id: conversation_id.map(str::to_owned),
+        status: ObligationStatus::Pending,
+        error: None,
+    }
+}
+
+/// The queue in run order. Welcomes and key packages are per device, so
+/// without a `device_id` they aren't owed (and don't block anything).
+fn plan(has_device: bool, group_ids: &[String], dm_ids: &[String]) -> Vec<CryptoObligation> {
+    let mut queue = vec![obligation(ObligationKind::DeviceCerts, None)];
+    if has_device {
+        queue.push(obligation(ObligationKind::Welcomes, None));
+        queue.push(obligation(ObligationKind::KeyPackages, None));
+    }
+    for cid in group_ids.iter().chain(dm_ids) {
+        queue.push(obligation(ObligationKind::Conversation, Some(cid)));
+    }
+    queue
+}
+
+/// The first dependency of `queue[idx]` that failed or is itself blocked.
+/// A dependency that isn't queued counts as met.
+fn blocked_by(queue: &[CryptoObligation], idx: usize) -> Option<ObligationKind> {
+    queue[idx].kind.depends_on().iter().copied().find(|dep| {
+        queue.iter().any(|o| {
+            o.kind == *dep
+                && matches!(
+                    o.status,
+                    ObligationStatus::Failed | ObligationStatus::Blocked
+                )
+        })
+    })
+}
+
+/// Enumerate everything `user_id` owes and run it. Errors only when the
+/// queue can't be enumerated; per-obligation failures are in the result.
+pub async fn run_crypto_obligations(
+    state: &Arc<AppState>,
+    user_id: &str,
+) -> Result<CryptoObligations> {
+    let _run = RUN.lock().await;
+    let device_id = state.device_id.lock().await.clone();
+    let (group_ids, dm_ids) = list_conversations(state, user_id).await?;
+    eprintln!(
+        "[mls-sweep] {user_id}: {} group(s), {} dm(s)",
+        group_ids.len(),
+        dm_ids.len()
+    );
+
+    let queue = plan(device_id.is_some(), &group_ids, &dm_ids);
+    update(|q| {
+        q.user_id = Some(user_id.to_string());
+        q.obligations = queue;
+    });
+    execute(state, user_id, device_id.as_deref()).await;
+    Ok(get_crypto_obligations())
+}
+
+/// Re-run the failed and blocked obligations, in order. With no queue for
+/// `user_id` yet, this is a full run.
+pub async fn retry_crypto_obligations(
+    state: &Arc<AppState>,
+    user_id: String,
+) -> Result<CryptoObligations> {
+    if get_crypto_obligations().user_id.as_deref() != Some(user_id.as_str()) {
+        return run_crypto_obligations(state, &user_id).await;
+    }
+    let _run = RUN.lock().await;
+    let device_id = state.device_id.lock().await.clone();
+    update(|q| {
+        for o in &mut q.obligations {
+            if matches!(
+                o.status,
+                ObligationStatus::Failed | ObligationStatus::Blocked
+            ) {
+                o.status = ObligationStatus::Pending;
+                o.error = None;
+            }
+        }
+    });
+    execute(state, &user_id, device_id.as_deref()).await;
+    Ok(get_crypto_obligations())
+}
+
+/// Work through the pending obligations. Caller holds [`RUN`].
+async fn execute(state: &Arc<AppState>, user_id: &str, device_id: Option<&str>) {
+    update(|q| q.running = true);
+    let len = get_crypto_obligations().obligations.len();
+    for idx in 0..len {
+        let queue = get_crypto_obligations().obligations;
+        let Some(item) = queue.get(idx).cloned() else {
+            break;
+        };
+        if item.status != ObligationStatus::Pending {
+            continue;
+        }
+        if let Some(dep) = blocked_by(&queue, idx) {
+            set_status(
+                idx,
+                ObligationStatus::Blocked,
+                Some(format!("waiting on {}", dep.label())),
+            );
+            continue;
+        }
+
+        set_status(idx, ObligationStatus::Running, None);
+        match perform(state, user_id, device_id, &item).await {
+            Ok(()) => set_status(idx, ObligationStatus::Done, None),
+            Err(e) => {
+                eprintln!("[mls-sweep] {}: {e}", item.id);
+                set_status(idx, ObligationStatus::Failed, Some(e.to_string()));
+            }
+        }
+    }
+    update(|q| q.running = false);
+}
+
+fn set_status(idx: usize, status: ObligationStatus, error: Option<String>) {
+    update(|q| {
+        if let Some(o) = q.obligations.get_mut(idx) {
+            o.status = status;
+            o.error = error;
+        }
+    });
+}
+
+async fn perform(
+    state: &Arc<AppState>,
+    user_id: &str,
+    device_id: Option<&str>,
+    item: &CryptoObligation,
+) -> Result<()> {
+    let need_device = || {
+        device_id.ok_or_else(|| {
+            crate::error::Error::Other(anyhow::anyhow!("device_id not set — login incomplete"))
+        })
+    };
+    match item.kind {
+        ObligationKind::DeviceCerts => {
+            // Re-signing needs the account identity key; a device that
+            // doesn't hold it has nothing to sign.
+            if crate::commands::account_identity::has_local_account_identity(
+                state.as_ref(),
+                user_id,
+            )
+            .await?
+            {
+                super::resign_stale_device_certs(state, user_id).await?;
+            }
+        }
+        ObligationKind::Welcomes => {
+            super::poll_mls_welcomes_inner(state, user_id, need_device()?).await?;
+        }
+        ObligationKind::KeyPackages => {
+            super::key_packages::replenish_key_packages(state, user_id, need_device()?).await?;
+        }
+        ObligationKind::Conversation => {
+            let Some(cid) = item.conversation_id.as_deref() else {
+                return Ok(());
+            };
+            // Route through the group-level interleaved catch-up (not a bare
+            // commit-only replay) so a returning offline member decrypts every
+            // message sealed at an epoch it's about to advance past. If it
+            // fails, the backstop waits for the retry: reconcile must not
+            // advance the epoch past messages that weren't ingested.
+            crate::commands::messages::catch_up_mls_group_interleaved(state, cid, user_id).await?;
+            // Backstop a dropped remove/eviction commit (issue #430 P1).
+            super::sweep::reconcile_backstop(state, cid, user_id).await?;
+        }
+    }
+    Ok(())
+}
+
+/// Every MLS conversation `user_id` is in: groups (the MLS group id IS the
+/// group id) and DMs (it IS the dm_channel_id).
+async fn list_conversations(
+    state: &Arc<AppState>,
+    user_id: &str,
+) -> Result<(Vec<String>, Vec<String>)> {
+    let conn = state.remote_db.conn().await?;
+
+    let mut group_ids: Vec<String> = Vec::new();
+    let mut rows = conn
+        .query(
+            "SELECT g.id FROM groups g \
+             JOIN group_member gm ON gm.group_id = g.id \
+             WHERE gm.user_id = ?1",
+            libsql::params![user_id.to_string()],
+        )
+        .await?;
+    while let Some(row) = rows.next().await? {
+        group_ids.push(row.get::<String>(0)?);
+    }
+    drop(rows);
+
+    let mut dm_ids: Vec<String> = Vec::new();
+    let mut rows = conn
+        .query(
+            "SELECT dm_channel_id FROM dm_channel_member WHERE user_id = ?1",
+            libsql::params![user_id.to_string()],
+        )
+        .await?;
+    while let Some(row) = rows.next().await? {
+        dm_ids.push(row.get::<String>(0)?);
+    }
+    drop(rows);
+
+    Ok((group_ids, dm_ids))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn ids(v: &[&str]) -> Vec<String> {
+        v.iter().map(|s| s.to_string()).collect()
+    }
+
+    #[test]
+    fn plan_orders_device_work_before_conversations() {
+        let queue = plan(true, &ids(&["g1"]), &ids(&["d1"]));
+        let order: Vec<&str> = queue.iter().map(|o| o.id.as_str()).collect();
+        assert_eq!(
+            order,
+            [
+                "device_certs",
+                "welcomes",
+                "key_packages",
+                "conversation:g1",
+                "conversation:d1"
+            ]
+        );
+
+        // Without a device there are no device-scoped obligations.
+        let queue = plan(false, &ids(&["g1"]), &[]);
+        assert!(queue.iter().all(|o| o.kind != ObligationKind::Welcomes));
+        assert_eq!(blocked_by(&queue, 1), None);
+    }
+
+    #[test]
+    fn a_failed_dependency_blocks_only_its_dependents() {
+        let mut queue = plan(true, &ids(&["g1"]), &[]);
+        queue[1].status = ObligationStatus::Failed;
+        // Key packages and the conversation wait on welcomes…
+        assert_eq!(blocked_by(&queue, 2), Some(ObligationKind::Welcomes));
+        assert_eq!(blocked_by(&queue, 3), Some(ObligationKind::Welcomes));
+
+        // …while a failed cert re-sign holds back only the key packages.
+        queue[1].status = ObligationStatus::Done;
+        queue[0].status = ObligationStatus::Failed;
+        assert_eq!(blocked_by(&queue, 2), Some(ObligationKind::DeviceCerts));
+        assert_eq!(blocked_by(&queue, 3), None);
+    }
+
+    #[test]
+    fn obligations_serialize_in_snake_case() {
+        let json = serde_json::to_value(&plan(true, &ids(&["g1"]), &[])[3]).unwrap();
+        assert_eq!(json["id"], "conversation:g1");
+        assert_eq!(json["kind"], "conversation");
+        assert_eq!(json["status"], "pending");
+    }
+}
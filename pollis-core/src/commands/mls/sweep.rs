@@ -5,7 +5,9 @@
 //! sequence (`poll_mls_welcomes_inner` once + `catch_up_mls_group_interleaved`
 //! per group) so the local MLS state matches the server's published epoch —
 //! decrypting every message sealed en route, not just replaying commits — before
-//! the user can take any MLS-powered action.
+//! the user can take any MLS-powered action. The sequence runs as the crypto
+//! obligations queue ([`super::obligations`]), which also re-signs stale device
+//! certs and tops up key packages, and reports progress per conversation.
 //!
 //! Closes the cold-launch race documented in issue #371 scenario 5: between
 //! sign-in / unlock and the first time any per-call catch-up fires, a user
@@ -15,7 +17,8 @@
 //!
 //! Best-effort per group: a single group's failure (e.g. revoked device,
 //! transient Turso error) logs and continues to the next so one bad row
-//! never blocks the rest of the sweep.
+//! never blocks the rest of the sweep. It stays in the queue as `failed`
+//! until the user retries it.
 //!
 //! ## Eviction/remove reconcile backstop (issue #430 P1)
 //!
@@ -30,10 +33,20 @@
 //! So after catching a group up, the sweep also runs a reconcile backstop:
 //! it retries the dropped remove/eviction so the removed device is actually
 //! pruned from the local tree. It is gated behind a cheap local-vs-roster
-//! pre-check ([`local_tree_has_stale_leaf`]) so in steady state — when the tree
+//! pre-check ([`local_tree_diff`]) so in steady state — when the tree
 //! already matches the roster — it costs only two lightweight SELECTs and a
 //! local MLS load, never the heavyweight reconcile (KP claims, account-key
 //! pinning, commit crypto).
+//!
+//! ## Send preflight
+//!
+//! The join-side mirror, run by `send_message` just before it encrypts
+//! ([`send_preflight`]). If the roster holds a device that is not in the local
+//! tree yet but has a key package waiting, the add that should have brought it
+//! in was dropped, and a message sealed now would be unreadable to that device
+//! forever. So the preflight runs reconcile first, which adds the device and
+//! sends its Welcome, and the message then goes out at the new epoch. The same
+//! pre-check gates it, so a send to an in-sync group pays only the SELECTs.
 
 use std::collections::HashSet;
 use std::sync::Arc;
@@ -46,80 +59,7 @@ use crate::state::AppState;
 use super::provider::{parse_credential_device_id, parse_credential_user_id, PollisProvider};
 
 pub async fn catch_up_all_mls_groups(state: &Arc<AppState>, user_id: &str) -> Result<()> {
-    let device_id = state.device_id.lock().await.clone();
-    if let Some(ref did) = device_id {
-        if let Err(e) =
-            crate::commands::mls::poll_mls_welcomes_inner(state, user_id, did).await
-        {
-            eprintln!("[mls-sweep] poll_mls_welcomes: {e}");
-        }
-    }
-
-    let conn = state.remote_db.conn().await?;
-
-    let mut group_ids: Vec<String> = Vec::new();
-    let mut rows = conn
-        .query(
-            "SELECT g.id FROM groups g \
-             JOIN group_member gm ON gm.group_id = g.id \
-             WHERE gm.user_id = ?1",
-            libsql::params![user_id.to_string()],
-        )
-        .await?;
-    while let Some(row) = rows.next().await? {
-        group_ids.push(row.get::<String>(0)?);
-    }
-    drop(rows);
-
-    let mut dm_ids: Vec<String> = Vec::new();
-    let mut rows = conn
-        .query(
-            "SELECT dm_channel_id FROM dm_channel_member WHERE user_id = ?1",
-            libsql::params![user_id.to_string()],
-        )
-        .await?;
-    while let Some(row) = rows.next().await? {
-        dm_ids.push(row.get::<String>(0)?);
-    }
-    drop(rows);
-
-    eprintln!(
-        "[mls-sweep] {user_id}: {} group(s), {} dm(s)",
-        group_ids.len(),
-        dm_ids.len()
-    );
-
-    // Regular groups: mls_group_id IS the group id. Route through the group-level
-    // interleaved catch-up (not a bare commit-only replay) so a returning offline
-    // member decrypts every message sealed at an epoch it's about to advance past,
-    // rather than losing anything sent before a membership change during its
-    // offline window. Interleaved ingest still advances the group to head, so the
-    // cold-launch guarantee (#371) is preserved.
-    for gid in &group_ids {
-        if let Err(e) =
-            crate::commands::messages::catch_up_mls_group_interleaved(state, gid, user_id).await
-        {
-            eprintln!("[mls-sweep] catch_up_mls_group for group {gid}: {e}");
-        }
-        // Backstop a dropped remove/eviction commit (issue #430 P1).
-        if let Err(e) = reconcile_backstop(state, gid, user_id).await {
-            eprintln!("[mls-sweep] reconcile backstop for group {gid}: {e}");
-        }
-    }
-
-    // DMs: mls_group_id IS the dm_channel_id — a single-conversation MLS group.
-    for did in &dm_ids {
-        if let Err(e) =
-            crate::commands::messages::catch_up_mls_group_interleaved(state, did, user_id).await
-        {
-            eprintln!("[mls-sweep] catch_up_mls_group for dm {did}: {e}");
-        }
-        // Backstop a dropped remove/eviction commit (issue #430 P1).
-        if let Err(e) = reconcile_backstop(state, did, user_id).await {
-            eprintln!("[mls-sweep] reconcile backstop for dm {did}: {e}");
-        }
-    }
-
+    super::obligations::run_crypto_obligations(state, user_id).await?;
     Ok(())
 }
 
@@ -132,7 +72,7 @@ pub async fn catch_up_all_mls_groups(state: &Arc<AppState>, user_id: &str) -> Re
 ///
 /// Cheap in steady state: the heavyweight [`reconcile_group_mls_impl`] only runs
 /// when the local tree still holds a leaf the roster no longer justifies
-/// ([`local_tree_has_stale_leaf`]). When the tree already matches the roster this
+/// ([`local_tree_diff`]). When the tree already matches the roster this
 /// is a near-no-op (two SELECTs + a local MLS load, no DS round-trips).
 ///
 /// Ordering / locking: the caller runs the interleaved ingesting catch-up for
@@ -143,12 +83,12 @@ pub async fn catch_up_all_mls_groups(state: &Arc<AppState>, user_id: &str) -> Re
 /// `mls_group_lock` for its whole body, and its own lost-race converge re-runs
 /// the interleaved catch-up — so there is no deadlock and no epoch advanced past
 /// an un-ingested message.
-async fn reconcile_backstop(
+pub(super) async fn reconcile_backstop(
     state: &Arc<AppState>,
     conversation_id: &str,
     user_id: &str,
 ) -> Result<()> {
-    if !local_tree_has_stale_leaf(state, conversation_id).await? {
+    if !local_tree_diff(state, conversation_id).await?.stale_leaf {
         return Ok(());
     }
 
@@ -159,20 +99,51 @@ async fn reconcile_backstop(
     Ok(())
 }
 
-/// Cheap pre-check for the reconcile backstop: does the LOCAL MLS ratchet tree
-/// still contain a leaf that a declarative reconcile would evict — a device whose
-/// user has left the roster, or whose `user_device` row is gone?
+/// Make sure every roster device that can be added is in the tree before
+/// `send_message` seals a message for `conversation_id` (the MLS group id).
+/// See the module docs. Runs the full reconcile only when
+/// [`local_tree_diff`] reports a `missing_device`.
+pub async fn send_preflight(
+    state: &Arc<AppState>,
+    conversation_id: &str,
+    user_id: &str,
+) -> Result<()> {
+    if !local_tree_diff(state, conversation_id).await?.missing_device {
+        return Ok(());
+    }
+
+    eprintln!(
+        "[mls] send preflight {conversation_id}: roster device missing from local tree — reconciling before send"
+    );
+    crate::commands::mls::reconcile_group_mls_impl(state, conversation_id, user_id).await?;
+    Ok(())
+}
+
+/// Where the LOCAL ratchet tree disagrees with the roster, as far as a
+/// declarative reconcile would act on it.
+#[derive(Default)]
+struct TreeDiff {
+    /// A leaf reconcile would evict: its user left the roster, or its
+    /// `user_device` row is gone.
+    stale_leaf: bool,
+    /// A roster device with no leaf but an unclaimed key package, so
+    /// reconcile could add it now.
+    missing_device: bool,
+}
+
+/// Cheap pre-check for the reconcile backstop and the send preflight.
 ///
-/// Returns `false` (the steady-state answer) using only a local MLS load and two
-/// lightweight roster/device SELECTs — never the DS claim / account-key-pin /
-/// commit-crypto work of a full reconcile. The "stale" test mirrors
+/// Returns the all-false steady-state answer using only a local MLS load and a
+/// few lightweight roster/device/key-package SELECTs — never the DS claim /
+/// account-key-pin / commit-crypto work of a full reconcile. The tests mirror
 /// `reconcile_group_mls_impl`'s roster + `valid_devices` derivation exactly, so
-/// it flags precisely the leaves reconcile would drop and never misses a pending
-/// eviction.
-async fn local_tree_has_stale_leaf(
+/// they flag precisely the leaves reconcile would drop and never miss a pending
+/// eviction. A device without key packages is not reported missing: reconcile
+/// couldn't add it either, and every send would otherwise pay for a no-op.
+async fn local_tree_diff(
     state: &Arc<AppState>,
     conversation_id: &str,
-) -> Result<bool> {
+) -> Result<TreeDiff> {
     // 1. Local tree membership — a local SQLite read, no network. Scope the
     //    !Send provider/group so neither crosses an await.
     let tree_members: Vec<(String, String)> = {
@@ -180,7 +151,7 @@ async fn local_tree_has_stale_leaf(
         let db = match guard.as_ref() {
             Some(db) => db,
             // No local group open (never joined / already forgotten): nothing to evict.
-            None => return Ok(false),
+            None => return Ok(TreeDiff::default()),
         };
         let provider = PollisProvider::new(db.conn());
         let group_id = GroupId::from_slice(conversation_id.as_bytes());
@@ -194,11 +165,11 @@ async fn local_tree_has_stale_leaf(
                 })
                 .collect(),
             // Missing / unreadable local group: nothing this device can evict.
-            _ => return Ok(false),
+            _ => return Ok(TreeDiff::default()),
         }
     };
     if tree_members.is_empty() {
-        return Ok(false);
+        return Ok(TreeDiff::default());
     }
 
     let conn = state.remote_db.conn().await?;
@@ -245,6 +216,7 @@ async fn local_tree_has_stale_leaf(
     //    same `user_device` snapshot reconcile uses to drop revoked single
     //    devices of a still-present user.
     let mut valid_devices: HashSet<(String, String)> = HashSet::new();
+    let mut addable_devices: HashSet<(String, String)> = HashSet::new();
     {
         let safe_ids: Vec<String> = roster
             .iter()
@@ -266,13 +238,32 @@ async fn local_tree_has_stale_leaf(
             while let Some(row) = rows.next().await? {
                 valid_devices.insert((row.get::<String>(0)?, row.get::<String>(1)?));
             }
+            drop(rows);
+
+            let query = format!(
+                "SELECT DISTINCT user_id, device_id FROM mls_key_package \
+                 WHERE claimed = 0 AND device_id IS NOT NULL AND user_id IN ({in_clause})"
+            );
+            let mut rows = conn.query(&query, ()).await?;
+            while let Some(row) = rows.next().await? {
+                addable_devices.insert((row.get::<String>(0)?, row.get::<String>(1)?));
+            }
         }
     }
 
     // 4. A leaf is stale iff its user left the roster OR its device row is gone —
     //    exactly the leaves reconcile would remove. Any such leaf means a
     //    remove/eviction commit was dropped and must be retried.
-    Ok(tree_members
+    let stale_leaf = tree_members
         .iter()
-        .any(|(uid, did)| !roster.contains(uid) || !valid_devices.contains(&(uid.clone(), did.clone()))))
+        .any(|(uid, did)| !roster.contains(uid) || !valid_devices.contains(&(uid.clone(), did.clone())));
+
+    // 5. A device is missing iff it is registered for a roster user, has a key
+    //    package to add it with, and has no leaf — an add that was dropped.
+    let in_tree: HashSet<&(String, String)> = tree_members.iter().collect();
+    let missing_device = addable_devices
+        .iter()
+        .any(|d| valid_devices.contains(d) && !in_tree.contains(d));
+
+    Ok(TreeDiff { stale_leaf, missing_device })
 }
@@ -25,9 +25,7 @@ use super::provider::PollisProvider;
 /// `StagedWelcome::new_from_welcome`.
 pub async fn apply_welcome(state: &Arc<AppState>, welcome_bytes: &[u8]) -> Result<()> {
     let guard = state.local_db.lock().await;
-    let db = guard.as_ref().ok_or_else(|| {
-        crate::error::Error::Other(anyhow::anyhow!("Not signed in"))
-    })?;
+    let db = guard.as_ref().ok_or(crate::error::Error::NotSignedIn)?;
     let provider = PollisProvider::new(db.conn());
 
     let mut reader: &[u8] = welcome_bytes;
@@ -2,6 +2,14 @@ pub mod account_identity;
 pub mod auth;
 pub mod pin;
 pub mod blocks;
+pub mod contacts;
+// Local crash reports: the scrubbed panic hook + list/acknowledge/export.
+pub mod crash;
+// Send → decrypt latency of received messages, kept in memory for diagnostics.
+pub mod delivery_latency;
+// This device's own envelopes checked on their way back: drops, replays, edits.
+pub mod delivery_canary;
+pub mod local_backup;
 pub mod device_enrollment;
 pub mod user;
 pub mod groups;
@@ -19,12 +27,18 @@ pub mod livekit;
 // LiveKit tokens are minted server-side by the DS now (#393); no on-device
 // signer remains. Token/SendData/roster requests go through the DS client
 // helpers in `commands::mls` (`ds_livekit_*`).
+// Link policy (open/confirm/block, tracking-parameter stripping, preview
+// eligibility) applied by every message surface before opening or previewing.
+pub mod links;
 // LiveKit realtime signalling (wake-up) payload builders — pure serde_json,
 // no native deps, always compiled (same rationale as `livekit_jwt`). Both the
 // media `livekit::publish` and the headless `livekit_stub` build their wire
 // payloads here so the metadata-minimized shape (§5) has one source of truth
 // and unit-tests on every target.
 pub mod livekit_signalling;
+// DS maintenance window: refused writes become `Error::Maintenance` and the
+// banner is told when the window opens and closes.
+pub mod maintenance;
 pub mod mls;
 // Runtime application of the closed-overlay relay mode (design §14): the engine
 // behind get/set_overlay_mode that live-routes control-plane traffic through the
@@ -34,7 +48,12 @@ pub mod overlay;
 // send_message fanout. Pure libsql + reqwest, so it compiles on every target.
 pub mod push;
 pub mod r2;
+// Message reminders, synced via the sealed preferences blob like `sidebar`.
+pub mod reminders;
 pub mod safety;
+pub mod sidebar;
+// Cold-start stage timings recorded by the shell's setup hook.
+pub mod startup;
 pub mod transparency;
 pub mod turso_token;
 #[cfg(feature = "media")]
@@ -38,6 +38,14 @@ pub async fn get_overlay_mode(state: &Arc<AppState>) -> Result<String> {
     Ok(mode_to_str(current_mode(state)))
 }
 
+/// Per-relay dial latency (smoothed + recent history) and which relay new
+/// circuits currently prefer — diagnostics for the latency-aware pool (see
+/// `net::relay_latency`). Covers every relay dialed this process; empty until
+/// the overlay has dialed one.
+pub async fn get_relay_latency() -> Result<Vec<crate::net::relay_latency::RelayLatencyReport>> {
+    Ok(crate::net::relay_latency::global().report())
+}
+
 /// Parse `mode` (`"off"` | `"prefer"` | `"strict"`, case-insensitive) and APPLY
 /// it live. A no-op when the mode is unchanged. Unlike the fail-safe env parse,
 /// an unknown value here is a hard error — the UI passes a known value and a typo
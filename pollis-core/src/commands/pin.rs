@@ -572,6 +572,9 @@ pub async fn unlock(
         eprintln!("[pin] unlock: resign_stale_device_certs failed (non-fatal): {e}");
     }
 
+    // Unlocking is the activity signal for the inactive-account policy.
+    crate::commands::user::inactivity_check_in(state, &user_id).await;
+
     Ok(UnlockOutcome {
         user_id: unlocked.user_id,
         attempts_remaining: None,
@@ -657,6 +660,34 @@ async fn unlock_inner(
     })
 }
 
+/// Check `pin` for `user_id` and return the unwrapped keys, counting a wrong
+/// PIN against the lockout like `unlock` does. For commands that re-prompt
+/// for the PIN before a sensitive change (`account_identity::rotate_identity`).
+pub(crate) async fn verify_pin(state: &AppState, user_id: &str, pin: &str) -> Result<UnlockState> {
+    validate_pin(pin)?;
+    unlock_inner(state.keystore.as_ref(), user_id, pin).await
+}
+
+/// Replace the PIN-wrapped account identity key with `account_id_key`,
+/// wrapped under the same PIN and salt so `db_key_wrapped` and `pin_meta`
+/// stay valid. The caller has already checked `pin` with [`verify_pin`].
+pub(crate) async fn rewrap_account_id_key(
+    state: &AppState,
+    user_id: &str,
+    pin: &str,
+    account_id_key: &[u8],
+) -> Result<()> {
+    let keystore = state.keystore.as_ref();
+    let meta = load_pin_meta(keystore, user_id)
+        .await?
+        .ok_or_else(|| Error::Other(anyhow::anyhow!("PIN not set for user {user_id}")))?;
+    let kek = derive_kek(pin, &meta.salt, meta.m_cost_kib, meta.t_cost, meta.p_cost)?;
+    let blob = wrap_bytes(&kek, account_id_key)?;
+    keystore
+        .store_for_user(ACCOUNT_ID_KEY_WRAPPED_SLOT, user_id, &blob)
+        .await
+}
+
 /// Drop the in-memory unlock state and close the open local DB. The
 /// next DB-touching command will fail with "Not signed in" until
 /// `unlock` runs. Accounts index is left intact — this is the "screen
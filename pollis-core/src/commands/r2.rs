@@ -1,10 +1,11 @@
 use serde::{Deserialize, Serialize};
 
-use std::collections::HashMap;
+use std::collections::{HashMap, HashSet};
 use std::path::{Path, PathBuf};
 use std::sync::{Arc, Mutex as StdMutex, OnceLock};
 
 use crate::error::{Error, Result};
+use crate::sink::EventSink;
 use crate::state::AppState;
 
 // ── On-disk media cache ───────────────────────────────────────────────────
@@ -274,20 +275,152 @@ pub async fn upload_file(
     content_type: String,
     state: &Arc<AppState>,
 ) -> Result<UploadResult> {
-    let put_url = presign_r2(state, "put", &key).await?;
+    let put_url = presign_r2_put(state, &key, &content_type, data.len(), None, None).await?;
     let overlay = state.overlay_handle();
-    r2_put_url(overlay.as_deref(), &put_url, data, &content_type).await?;
+    r2_put_url(overlay.as_deref(), &put_url, data.clone(), &content_type).await?;
+    // Avatar keys are stable, so replace the cached copy now — otherwise the
+    // old image would be served until the entry goes stale. No ETag: the next
+    // revalidation does one full fetch.
+    if let Some(db) = state.local_db.lock().await.as_ref() {
+        let _ = store_cached_avatar(db.conn(), &key, None, &data);
+    }
     let url = format!("{}/{}", state.config.r2_endpoint.trim_end_matches('/'), key);
     Ok(UploadResult { key, url })
 }
 
+/// Download an avatar / group icon, through the local avatar cache.
+///
+/// A cached copy younger than `AVATAR_CACHE_FRESH_SECS` is returned without
+/// touching the network. An older one is revalidated with `If-None-Match`
+/// (R2 answers `304` when the object is unchanged). When R2 can't be reached
+/// at all — offline, DS down — the cached copy is served stale, so avatars
+/// keep rendering offline.
 pub async fn download_file(
     key: String,
     state: &Arc<AppState>,
 ) -> Result<Vec<u8>> {
-    let get_url = presign_r2(state, "get", &key).await?;
-    let overlay = state.overlay_handle();
-    r2_get_url(overlay.as_deref(), &get_url).await
+    let cached = {
+        let guard = state.local_db.lock().await;
+        match guard.as_ref() {
+            Some(db) => cached_avatar(db.conn(), &key)?,
+            None => None,
+        }
+    };
+    if let Some(c) = &cached {
+        if c.fresh {
+            return Ok(c.data.clone());
+        }
+    }
+
+    let etag = cached.as_ref().and_then(|c| c.etag.clone());
+    let fetched = async {
+        let get_url = presign_r2(state, "get", &key).await?;
+        let overlay = state.overlay_handle();
+        r2_get_url_revalidate(overlay.as_deref(), &get_url, etag.as_deref()).await
+    }
+    .await;
+
+    let guard = state.local_db.lock().await;
+    let conn = guard.as_ref().map(|db| db.conn());
+    match (fetched, cached) {
+        (Ok(Revalidated::Fetched { data, etag }), _) => {
+            if let Some(conn) = conn {
+                if let Err(e) = store_cached_avatar(conn, &key, etag.as_deref(), &data) {
+                    eprintln!("[download_file] cache write failed for {key}: {e}");
+                }
+            }
+            Ok(data)
+        }
+        (Ok(Revalidated::NotModified), Some(c)) => {
+            if let Some(conn) = conn {
+                let _ = touch_cached_avatar(conn, &key);
+            }
+            Ok(c.data)
+        }
+        (Ok(Revalidated::NotModified), None) => Err(Error::Other(anyhow::anyhow!(
+            "R2 returned 304 for an unconditional request"
+        ))),
+        (Ok(Revalidated::Gone), _) => {
+            if let Some(conn) = conn {
+                let _ = conn.execute(
+                    "DELETE FROM avatar_cache WHERE key = ?1",
+                    rusqlite::params![key],
+                );
+            }
+            Err(Error::NotFound(key))
+        }
+        (Err(e), Some(c)) => {
+            eprintln!("[download_file] serving cached {key}, revalidation failed: {e}");
+            Ok(c.data)
+        }
+        (Err(e), None) => Err(e),
+    }
+}
+
+// ── Avatar cache (local DB) ────────────────────────────────────────────────
+//
+// `download_file` objects (avatars, group icons) are cached in the local DB's
+// `avatar_cache` table, keyed by R2 object key — encrypted at rest with the
+// rest of the local DB (SQLCipher) and scoped to the signed-in user. Keys are
+// stable (`avatars/{userId}`), so the cache revalidates by ETag rather than
+// trusting the key. Other users' avatar changes show up within
+// `AVATAR_CACHE_FRESH_SECS`; the user's own upload replaces their entry
+// immediately (`upload_file`).
+
+/// How long a cached object is served without asking R2.
+const AVATAR_CACHE_FRESH_SECS: i64 = 600;
+
+/// Entries not fetched for this many days are dropped on the next write.
+const AVATAR_CACHE_TTL_DAYS: i64 = 30;
+
+struct CachedAvatar {
+    etag: Option<String>,
+    data: Vec<u8>,
+    /// Fetched or revalidated within `AVATAR_CACHE_FRESH_SECS`.
+    fresh: bool,
+}
+
+fn cached_avatar(conn: &rusqlite::Connection, key: &str) -> Result<Option<CachedAvatar>> {
+    use rusqlite::OptionalExtension;
+    Ok(conn
+        .query_row(
+            "SELECT etag, data, fetched_at > datetime('now', ?2) FROM avatar_cache WHERE key = ?1",
+            rusqlite::params![key, format!("-{AVATAR_CACHE_FRESH_SECS} seconds")],
+            |row| {
+                Ok(CachedAvatar {
+                    etag: row.get(0)?,
+                    data: row.get(1)?,
+                    fresh: row.get(2)?,
+                })
+            },
+        )
+        .optional()?)
+}
+
+fn store_cached_avatar(
+    conn: &rusqlite::Connection,
+    key: &str,
+    etag: Option<&str>,
+    data: &[u8],
+) -> Result<()> {
+    conn.execute(
+        "INSERT INTO avatar_cache (key, etag, data, fetched_at) VALUES (?1, ?2, ?3, datetime('now')) \
+         ON CONFLICT(key) DO UPDATE SET etag = ?2, data = ?3, fetched_at = datetime('now')",
+        rusqlite::params![key, etag, data],
+    )?;
+    conn.execute(
+        "DELETE FROM avatar_cache WHERE fetched_at < datetime('now', ?1)",
+        rusqlite::params![format!("-{AVATAR_CACHE_TTL_DAYS} days")],
+    )?;
+    Ok(())
+}
+
+fn touch_cached_avatar(conn: &rusqlite::Connection, key: &str) -> Result<()> {
+    conn.execute(
+        "UPDATE avatar_cache SET fetched_at = datetime('now') WHERE key = ?1",
+        rusqlite::params![key],
+    )?;
+    Ok(())
 }
 
 // ── Media upload (convergent encryption + cross-user dedup) ───────────────
@@ -316,10 +449,18 @@ pub struct MediaUploadResult {
 ///
 /// Dedup check against Turso's `attachment_object` table before uploading, so
 /// the second upload of the same file by any user skips the R2 PUT entirely.
+///
+/// `group_id` names the group the attachment is sent to, `dm_channel_id` the DM
+/// (the DS wants one of them). When the group's owner pinned it to a storage
+/// target (`groups.storage_target`), the object goes there under a
+/// `media@<target>/` key, and dedup only counts an existing object in that
+/// same store.
 pub async fn upload_media(
     path: String,
     filename: String,
     content_type: String,
+    group_id: Option<String>,
+    dm_channel_id: Option<String>,
     state: &Arc<AppState>,
 ) -> Result<MediaUploadResult> {
     // Read plaintext from disk.
@@ -332,11 +473,22 @@ pub async fn upload_media(
     let hash_bytes = sha256_bytes(&data);
     let content_hash = hex::encode(hash_bytes);
 
+    // The group's residency policy, from the owner-written group row.
+    let storage_target = match &group_id {
+        Some(gid) => group_storage_target(state, gid).await?,
+        None => None,
+    };
+
     // Deterministic R2 key: same content → same path in R2.
     // Sanitise the filename so the URL path only contains chars that are safe
     // in both URLs and S3 keys without percent-encoding.  The content_hash is
     // the actual uniqueness anchor, so the filename here is decorative.
-    let r2_key = format!("media/{}/{}.enc", content_hash, sanitize_key_segment(&filename));
+    let r2_key = format!(
+        "{}/{}/{}.enc",
+        media_prefix(storage_target.as_deref()),
+        content_hash,
+        sanitize_key_segment(&filename)
+    );
     let r2_url = format!("{}/{}", state.config.r2_endpoint.trim_end_matches('/'), r2_key);
 
     // Derive encryption key and nonce from the content hash (convergent).
@@ -355,14 +507,18 @@ pub async fn upload_media(
         (None, None, None)
     };
 
-    // Check Turso for an existing object with the same content hash.
+    // Check Turso for an existing object with the same content hash, in the
+    // same store.
     let already_uploaded = {
         let conn = state.remote_db.conn().await?;
         let mut rows = conn.query(
-            "SELECT 1 FROM attachment_object WHERE content_hash = ?1",
+            "SELECT r2_key FROM attachment_object WHERE content_hash = ?1",
             libsql::params![content_hash.clone()],
         ).await?;
-        rows.next().await?.is_some()
+        match rows.next().await? {
+            Some(row) => key_prefix(&row.get::<String>(0)?) == key_prefix(&r2_key),
+            None => false,
+        }
     };
 
     if !already_uploaded {
@@ -370,7 +526,15 @@ pub async fn upload_media(
         // presigned PUT (the client holds no R2 credentials).
         let ciphertext = encrypt_chunked(&data, &enc_key, &enc_nonce);
 
-        let put_url = presign_r2(state, "put", &r2_key).await?;
+        let put_url = presign_r2_put(
+            state,
+            &r2_key,
+            "application/octet-stream",
+            ciphertext.len(),
+            group_id.as_deref(),
+            dm_channel_id.as_deref(),
+        )
+        .await?;
         let overlay = state.overlay_handle();
         r2_put_url(overlay.as_deref(), &put_url, ciphertext, "application/octet-stream").await?;
 
@@ -396,6 +560,33 @@ pub async fn upload_media(
     })
 }
 
+/// `groups.storage_target` for `group_id`; `None` for the default bucket.
+async fn group_storage_target(state: &Arc<AppState>, group_id: &str) -> Result<Option<String>> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn.query(
+        "SELECT storage_target FROM groups WHERE id = ?1",
+        libsql::params![group_id.to_string()],
+    ).await?;
+    Ok(match rows.next().await? {
+        Some(row) => row.get::<Option<String>>(0)?,
+        None => None,
+    })
+}
+
+/// `media`, or `media@<target>` for a pinned group — the DS routes a presign
+/// on such a key to that target.
+fn media_prefix(storage_target: Option<&str>) -> String {
+    match storage_target {
+        Some(t) => format!("media@{t}"),
+        None => "media".to_string(),
+    }
+}
+
+/// The first path segment of an object key (`media`, `media@eu`, …).
+fn key_prefix(key: &str) -> &str {
+    key.split_once('/').map_or(key, |(prefix, _)| prefix)
+}
+
 // ── Media download (decrypt on the way out) ───────────────────────────────
 
 /// Download and decrypt a media attachment.
@@ -413,14 +604,339 @@ pub async fn download_media(
         .map_err(|_| Error::Other(anyhow::anyhow!("content_hash must be 32 hex bytes")))?;
 
     let (enc_key, enc_nonce) = derive_attachment_key(&hash_array);
+    let mut dl = PartialDownload::open(&content_hash, &enc_key, &enc_nonce).await;
 
     // DS-minted presigned GET — the client holds no R2 credentials. The URL only
     // ever exposes convergently-encrypted ciphertext; confidentiality comes from
-    // MLS key distribution, not the R2 ACL (see broker.rs).
-    let get_url = presign_r2(state, "get", &r2_key).await?;
+    // MLS key distribution, not the R2 ACL (see broker.rs). Re-presigned per
+    // attempt so a slow transfer can't outlive its URL.
     let overlay = state.overlay_handle();
-    let ciphertext = r2_get_url(overlay.as_deref(), &get_url).await?;
-    decrypt_chunked(&ciphertext, &enc_key, &enc_nonce)
+    let mut attempt = 1;
+    loop {
+        let fetched = async {
+            let get_url = presign_r2(state, "get", &r2_key).await?;
+            fetch_range(overlay.as_deref(), &get_url, &mut dl).await
+        }
+        .await;
+        match fetched {
+            Ok(()) => break,
+            Err(Error::Network(e)) if attempt < DOWNLOAD_ATTEMPTS => {
+                eprintln!(
+                    "[download_media] {content_hash}: attempt {attempt} interrupted at {} bytes, resuming: {e}",
+                    dl.offset
+                );
+                tokio::time::sleep(std::time::Duration::from_secs(1 << attempt)).await;
+                attempt += 1;
+            }
+            Err(e) => {
+                // Keep the `.part` for a network failure (the next call resumes);
+                // anything else means its bytes can't be trusted.
+                if !matches!(e, Error::Network(_)) {
+                    dl.discard().await;
+                }
+                return Err(e);
+            }
+        }
+    }
+
+    dl.finish(&hash_array).await
+}
+
+// ── Resumable media download ──────────────────────────────────────────────
+//
+// `download_media` streams the ciphertext instead of buffering one GET. Bytes
+// are appended to `<hash>.part` in the media cache dir as they arrive — the
+// same convergent ciphertext R2 holds, so nothing readable touches disk — and
+// each complete AEAD chunk is decrypted as soon as it lands. An interrupted
+// transfer resumes from the `.part` length with `Range: bytes=<n>-`, either on
+// the next attempt of the same call or on a later call in the same session
+// (unlock wipes the cache dir, `.part` files included). The assembled plaintext is checked against the content hash carried in the MLS
+// payload before it's returned. Progress goes to the sink registered with
+// `subscribe_media_download_events`.
+
+/// Attempts per `download_media` call before a network error is surfaced.
+const DOWNLOAD_ATTEMPTS: u32 = 4;
+
+/// Progress is reported at most once per this many received bytes.
+const PROGRESS_STEP_BYTES: u64 = 1024 * 1024;
+
+/// One progress update for an attachment download, keyed by content hash.
+#[derive(Debug, Clone, Serialize)]
+pub struct MediaDownloadProgress {
+    pub content_hash: String,
+    /// Ciphertext bytes on hand, including any resumed from a `.part`.
+    pub received: u64,
+    /// Ciphertext size, once R2 has reported it.
+    pub total: Option<u64>,
+}
+
+static DOWNLOAD_SINK: StdMutex<Option<Arc<dyn EventSink<MediaDownloadProgress>>>> =
+    StdMutex::new(None);
+
+/// Route download progress to `sink`, replacing any previous subscriber.
+pub fn subscribe_media_download_events(sink: Arc<dyn EventSink<MediaDownloadProgress>>) {
+    if let Ok(mut guard) = DOWNLOAD_SINK.lock() {
+        *guard = Some(sink);
+    }
+}
+
+fn emit_download_progress(progress: MediaDownloadProgress) {
+    let sink = DOWNLOAD_SINK.lock().ok().and_then(|g| g.clone());
+    if let Some(sink) = sink {
+        let _ = sink.send(progress);
+    }
+}
+
+/// Decrypts `encrypt_chunked` output incrementally: bytes are pushed as they
+/// arrive and every complete `CHUNK_SIZE + TAG_SIZE` block is opened at once.
+struct ChunkDecryptor {
+    cipher: aes_gcm::Aes256Gcm,
+    base_nonce: [u8; 12],
+    index: u32,
+    pending: Vec<u8>,
+    plaintext: Vec<u8>,
+}
+
+impl ChunkDecryptor {
+    fn new(key: &[u8; 32], base_nonce: &[u8; 12]) -> Self {
+        use aes_gcm::{aead::KeyInit, Aes256Gcm, Key};
+        Self {
+            cipher: Aes256Gcm::new(Key::<Aes256Gcm>::from_slice(key)),
+            base_nonce: *base_nonce,
+            index: 0,
+            pending: Vec::new(),
+            plaintext: Vec::new(),
+        }
+    }
+
+    fn push(&mut self, bytes: &[u8]) -> Result<()> {
+        self.pending.extend_from_slice(bytes);
+        let chunk_ct_size = CHUNK_SIZE + TAG_SIZE;
+        while self.pending.len() >= chunk_ct_size {
+            let chunk: Vec<u8> = self.pending.drain(..chunk_ct_size).collect();
+            self.open(&chunk)?;
+        }
+        Ok(())
+    }
+
+    /// Open the trailing short chunk (if any) and return the plaintext.
+    fn finish(mut self) -> Result<Vec<u8>> {
+        if !self.pending.is_empty() {
+            let chunk = std::mem::take(&mut self.pending);
+            self.open(&chunk)?;
+        }
+        Ok(self.plaintext)
+    }
+
+    fn open(&mut self, chunk_ct: &[u8]) -> Result<()> {
+        use aes_gcm::{aead::Aead, Nonce};
+        let mut nonce_bytes = self.base_nonce;
+        let idx = self.index.to_le_bytes();
+        nonce_bytes[0] ^= idx[0];
+        nonce_bytes[1] ^= idx[1];
+        nonce_bytes[2] ^= idx[2];
+        nonce_bytes[3] ^= idx[3];
+        let pt = self
+            .cipher
+            .decrypt(Nonce::from_slice(&nonce_bytes), chunk_ct)
+            .map_err(|_| {
+                Error::Other(anyhow::anyhow!(
+                    "attachment decryption failed (chunk {})",
+                    self.index
+                ))
+            })?;
+        self.plaintext.extend_from_slice(&pt);
+        self.index += 1;
+        Ok(())
+    }
+}
+
+/// Content hashes whose `.part` file currently has a writer.
+static ACTIVE_PARTS: OnceLock<StdMutex<HashSet<String>>> = OnceLock::new();
+
+fn active_parts() -> &'static StdMutex<HashSet<String>> {
+    ACTIVE_PARTS.get_or_init(|| StdMutex::new(HashSet::new()))
+}
+
+/// An attachment download in progress: the decryptor plus the `.part` file
+/// mirroring every ciphertext byte received so far. Without a media cache dir
+/// (or on any file error) it carries on in memory, just without resume.
+struct PartialDownload {
+    content_hash: String,
+    key: [u8; 32],
+    base_nonce: [u8; 12],
+    /// Holds this hash's slot in `active_parts`.
+    claimed: bool,
+    part_path: Option<PathBuf>,
+    file: Option<tokio::fs::File>,
+    offset: u64,
+    total: Option<u64>,
+    last_reported: u64,
+    decryptor: ChunkDecryptor,
+}
+
+impl PartialDownload {
+    /// Pick up an existing `.part` for `content_hash`, replaying its bytes
+    /// through the decryptor. A `.part` that doesn't decrypt is dropped.
+    async fn open(content_hash: &str, key: &[u8; 32], base_nonce: &[u8; 12]) -> Self {
+        // One writer per `.part`: a concurrent download of the same hash (e.g.
+        // Save while the inline render is still fetching) runs in memory only.
+        let claimed = active_parts()
+            .lock()
+            .map(|mut active| active.insert(content_hash.to_string()))
+            .unwrap_or(false);
+        let part_path = media_cache_dir()
+            .ok()
+            .filter(|_| claimed)
+            .map(|d| d.join(format!("{content_hash}.part")));
+        let mut dl = Self {
+            content_hash: content_hash.to_string(),
+            key: *key,
+            base_nonce: *base_nonce,
+            claimed,
+            part_path,
+            file: None,
+            offset: 0,
+            total: None,
+            last_reported: 0,
+            decryptor: ChunkDecryptor::new(key, base_nonce),
+        };
+        let Some(path) = dl.part_path.clone() else {
+            return dl;
+        };
+        if let Ok(existing) = tokio::fs::read(&path).await {
+            if dl.decryptor.push(&existing).is_ok() {
+                dl.offset = existing.len() as u64;
+            } else {
+                dl.decryptor = ChunkDecryptor::new(key, base_nonce);
+                let _ = tokio::fs::remove_file(&path).await;
+            }
+        }
+        dl.file = tokio::fs::OpenOptions::new()
+            .create(true)
+            .append(true)
+            .open(&path)
+            .await
+            .ok();
+        dl
+    }
+
+    async fn append(&mut self, bytes: &[u8]) -> Result<()> {
+        use tokio::io::AsyncWriteExt;
+        self.decryptor.push(bytes)?;
+        if let Some(file) = self.file.as_mut() {
+            if file.write_all(bytes).await.is_err() {
+                // Resume is an optimisation; fall back to memory only.
+                self.file = None;
+                if let Some(path) = &self.part_path {
+                    let _ = tokio::fs::remove_file(path).await;
+                }
+            }
+        }
+        self.offset += bytes.len() as u64;
+        if self.offset - self.last_reported >= PROGRESS_STEP_BYTES {
+            self.report();
+        }
+        Ok(())
+    }
+
+    /// The server ignored the range and is sending from byte 0.
+    async fn restart(&mut self) {
+        self.decryptor = ChunkDecryptor::new(&self.key, &self.base_nonce);
+        self.offset = 0;
+        self.last_reported = 0;
+        if let Some(file) = self.file.as_mut() {
+            if file.set_len(0).await.is_err() {
+                self.file = None;
+            }
+        }
+    }
+
+    fn report(&mut self) {
+        self.last_reported = self.offset;
+        emit_download_progress(MediaDownloadProgress {
+            content_hash: self.content_hash.clone(),
+            received: self.offset,
+            total: self.total,
+        });
+    }
+
+    async fn discard(&mut self) {
+        self.file = None;
+        if let Some(path) = &self.part_path {
+            let _ = tokio::fs::remove_file(path).await;
+        }
+    }
+
+    /// Open the last chunk, verify the plaintext against the content hash and
+    /// drop the `.part`.
+    async fn finish(mut self, content_hash: &[u8; 32]) -> Result<Vec<u8>> {
+        self.report();
+        self.discard().await;
+        let plaintext = self.decryptor.finish()?;
+        if sha256_bytes(&plaintext) != *content_hash {
+            return Err(Error::Other(anyhow::anyhow!(
+                "attachment integrity check failed: content hash mismatch"
+            )));
+        }
+        Ok(plaintext)
+    }
+}
+
+impl Drop for PartialDownload {
+    fn drop(&mut self) {
+        if self.claimed {
+            if let Ok(mut active) = active_parts().lock() {
+                active.remove(&self.content_hash);
+            }
+        }
+    }
+}
+
+/// GET the rest of the object from `dl.offset` and stream it into `dl`.
+/// Network failures surface as `Error::Network` so the caller can resume.
+async fn fetch_range(
+    overlay: Option<&pollis_relay::OverlayHandle>,
+    url: &str,
+    dl: &mut PartialDownload,
+) -> Result<()> {
+    let mut req = crate::net::overlay::http_client(overlay).get(url);
+    if dl.offset > 0 {
+        req = req.header("Range", format!("bytes={}-", dl.offset));
+    }
+    let mut resp = req.send().await?;
+    let status = resp.status();
+    match status.as_u16() {
+        // Range starts at or past the end: the `.part` already holds it all
+        // (the hash check in `finish` catches a `.part` that's wrong).
+        416 if dl.offset > 0 => {
+            dl.total = Some(dl.offset);
+            return Ok(());
+        }
+        206 => {
+            dl.total = resp
+                .headers()
+                .get("content-range")
+                .and_then(|v| v.to_str().ok())
+                .and_then(|v| v.rsplit('/').next())
+                .and_then(|t| t.parse().ok());
+        }
+        _ if status.is_success() => {
+            if dl.offset > 0 {
+                dl.restart().await;
+            }
+            dl.total = resp.content_length();
+        }
+        _ => {
+            let body = resp.text().await.unwrap_or_default();
+            return Err(Error::Other(anyhow::anyhow!("R2 download failed: {} — {}", status, body)));
+        }
+    }
+    dl.report();
+    while let Some(bytes) = resp.chunk().await? {
+        dl.append(&bytes).await?;
+    }
+    Ok(())
 }
 
 /// Resolve a media attachment to a loopback HTTP URL the webview can use
@@ -679,27 +1195,11 @@ fn encrypt_chunked(data: &[u8], key: &[u8; 32], base_nonce: &[u8; 12]) -> Vec<u8
     out
 }
 
-/// Decrypt ciphertext produced by `encrypt_chunked`.
+/// Decrypt ciphertext produced by `encrypt_chunked` in one go.
 fn decrypt_chunked(ciphertext: &[u8], key: &[u8; 32], base_nonce: &[u8; 12]) -> Result<Vec<u8>> {
-    use aes_gcm::{aead::{Aead, KeyInit}, Aes256Gcm, Key, Nonce};
-
-    let cipher = Aes256Gcm::new(Key::<Aes256Gcm>::from_slice(key));
-    let chunk_ct_size = CHUNK_SIZE + TAG_SIZE;
-    let mut out = Vec::with_capacity(ciphertext.len());
-
-    for (i, chunk_ct) in ciphertext.chunks(chunk_ct_size).enumerate() {
-        let mut nonce_bytes = *base_nonce;
-        let idx = (i as u32).to_le_bytes();
-        nonce_bytes[0] ^= idx[0];
-        nonce_bytes[1] ^= idx[1];
-        nonce_bytes[2] ^= idx[2];
-        nonce_bytes[3] ^= idx[3];
-        let pt = cipher.decrypt(Nonce::from_slice(&nonce_bytes), chunk_ct)
-            .map_err(|_| Error::Other(anyhow::anyhow!("attachment decryption failed (chunk {i})")))?;
-        out.extend_from_slice(&pt);
-    }
-
-    Ok(out)
+    let mut decryptor = ChunkDecryptor::new(key, base_nonce);
+    decryptor.push(ciphertext)?;
+    decryptor.finish()
 }
 
 /// Keep only characters that are safe in a URL path segment without encoding.
@@ -741,7 +1241,40 @@ fn compute_image_meta(data: &[u8]) -> anyhow::Result<(String, u32, u32)> {
 /// `key` and return the ready-to-use URL. Device-signed via [`ds_post`].
 async fn presign_r2(state: &Arc<AppState>, operation: &str, key: &str) -> Result<String> {
     let body = serde_json::json!({ "operation": operation, "key": key });
-    let resp = crate::commands::mls::ds_post(state, "/v1/r2/presign", &body).await?;
+    request_presign(state, operation, &body).await
+}
+
+/// Presign a PUT of exactly `size` bytes of `content_type`. The DS checks both
+/// against its upload policy (image types and caps for avatars / icons,
+/// `.enc` ciphertext for media) and signs them into the URL, so the upload in
+/// [`r2_put_url`] must send that same Content-Type and body. An attachment
+/// names its group or DM, so the DS can hold it to the conversation's
+/// residency policy.
+async fn presign_r2_put(
+    state: &Arc<AppState>,
+    key: &str,
+    content_type: &str,
+    size: usize,
+    group_id: Option<&str>,
+    dm_channel_id: Option<&str>,
+) -> Result<String> {
+    let body = serde_json::json!({
+        "operation": "put",
+        "key": key,
+        "content_type": content_type,
+        "size": size,
+        "group_id": group_id,
+        "dm_channel_id": dm_channel_id,
+    });
+    request_presign(state, "put", &body).await
+}
+
+async fn request_presign(
+    state: &Arc<AppState>,
+    operation: &str,
+    body: &serde_json::Value,
+) -> Result<String> {
+    let resp = crate::commands::mls::ds_post_metadata(state, "/v1/r2/presign", body).await?;
     let status = resp.status();
     if !status.is_success() {
         let txt = resp.text().await.unwrap_or_default();
@@ -760,8 +1293,8 @@ async fn presign_r2(state: &Arc<AppState>, operation: &str, key: &str) -> Result
     Ok(parsed.url)
 }
 
-/// PUT `data` to a presigned URL. Content-Type is set at request time (the broker
-/// signs only `host`, so it is deliberately left unsigned). Routes through the
+/// PUT `data` to a presigned URL. The Content-Type and length must match what
+/// [`presign_r2_put`] declared — both are signed into the URL. Routes through the
 /// overlay when on — R2 is a first-party, allowlisted host (§14.2).
 async fn r2_put_url(
     overlay: Option<&pollis_relay::OverlayHandle>,
@@ -783,15 +1316,48 @@ async fn r2_put_url(
     Ok(())
 }
 
-/// GET the bytes at a presigned URL. Routes through the overlay when on.
-async fn r2_get_url(overlay: Option<&pollis_relay::OverlayHandle>, url: &str) -> Result<Vec<u8>> {
-    let resp = crate::net::overlay::http_client(overlay).get(url).send().await?;
-    if !resp.status().is_success() {
-        let status = resp.status();
+/// The result of a conditional GET.
+enum Revalidated {
+    Fetched { data: Vec<u8>, etag: Option<String> },
+    /// `304` — the cached copy is current.
+    NotModified,
+    /// `404` — the object no longer exists.
+    Gone,
+}
+
+/// GET a presigned URL, with `If-None-Match` when an ETag is known. The
+/// broker signs only `host`, so the extra header doesn't break the signature.
+/// Routes through the overlay when on.
+async fn r2_get_url_revalidate(
+    overlay: Option<&pollis_relay::OverlayHandle>,
+    url: &str,
+    etag: Option<&str>,
+) -> Result<Revalidated> {
+    let mut req = crate::net::overlay::http_client(overlay).get(url);
+    if let Some(etag) = etag {
+        req = req.header("If-None-Match", etag);
+    }
+    let resp = req.send().await?;
+    let status = resp.status();
+    if status.as_u16() == 304 {
+        return Ok(Revalidated::NotModified);
+    }
+    if status.as_u16() == 404 {
+        return Ok(Revalidated::Gone);
+    }
+    if !status.is_success() {
         let body = resp.text().await.unwrap_or_default();
         return Err(Error::Other(anyhow::anyhow!("R2 download failed: {} — {}", status, body)));
     }
-    Ok(resp.bytes().await?.to_vec())
+    let etag = resp
+        .headers()
+        .get("etag")
+        .and_then(|v| v.to_str().ok())
+        .map(str::to_string);
+    Ok(Revalidated::Fetched {
+        data: resp.bytes().await?.to_vec(),
+        etag,
+    })
 }
 
 /// DELETE the object at a presigned URL. A 404 counts as success (already gone).
@@ -805,3 +1371,66 @@ async fn r2_delete_url(overlay: Option<&pollis_relay::OverlayHandle>, url: &str)
     let body = resp.text().await.unwrap_or_default();
     Err(Error::Other(anyhow::anyhow!("R2 delete failed: {} — {}", status, body)))
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn avatar_cache_goes_stale_and_prunes() {
+        let db = crate::db::local::LocalDb::open_in_memory().unwrap();
+        let conn = db.conn();
+        store_cached_avatar(conn, "avatars/u1", Some("\"e1\""), b"png").unwrap();
+        let c = cached_avatar(conn, "avatars/u1").unwrap().unwrap();
+        assert!(c.fresh);
+        assert_eq!(c.etag.as_deref(), Some("\"e1\""));
+        assert_eq!(c.data, b"png");
+
+        conn.execute(
+            "UPDATE avatar_cache SET fetched_at = datetime('now', '-1 hour')",
+            [],
+        )
+        .unwrap();
+        assert!(!cached_avatar(conn, "avatars/u1").unwrap().unwrap().fresh);
+        touch_cached_avatar(conn, "avatars/u1").unwrap();
+        assert!(cached_avatar(conn, "avatars/u1").unwrap().unwrap().fresh);
+
+        // A write prunes entries untouched past the TTL.
+        conn.execute(
+            "UPDATE avatar_cache SET fetched_at = datetime('now', '-31 days')",
+            [],
+        )
+        .unwrap();
+        store_cached_avatar(conn, "icons/g1", None, b"gif").unwrap();
+        assert!(cached_avatar(conn, "avatars/u1").unwrap().is_none());
+        assert!(cached_avatar(conn, "icons/g1").unwrap().is_some());
+    }
+
+    #[test]
+    fn chunk_decryptor_matches_one_shot_across_uneven_pushes() {
+        // Two full chunks plus a short tail, fed in network-sized pieces that
+        // straddle chunk boundaries.
+        let data: Vec<u8> = (0..2 * CHUNK_SIZE + 1234).map(|i| (i % 251) as u8).collect();
+        let (key, nonce) = derive_attachment_key(&sha256_bytes(&data));
+        let ciphertext = encrypt_chunked(&data, &key, &nonce);
+
+        let mut decryptor = ChunkDecryptor::new(&key, &nonce);
+        for piece in ciphertext.chunks(65_537) {
+            decryptor.push(piece).unwrap();
+        }
+        assert_eq!(decryptor.finish().unwrap(), data);
+        assert_eq!(decrypt_chunked(&ciphertext, &key, &nonce).unwrap(), data);
+    }
+
+    #[test]
+    fn chunk_decryptor_rejects_tampered_chunk() {
+        let data = vec![7u8; CHUNK_SIZE + 10];
+        let (key, nonce) = derive_attachment_key(&sha256_bytes(&data));
+        let mut ciphertext = encrypt_chunked(&data, &key, &nonce);
+        ciphertext[5] ^= 1;
+
+        let mut decryptor = ChunkDecryptor::new(&key, &nonce);
+        // The first chunk fails as soon as it's complete, before the tail arrives.
+        assert!(decryptor.push(&ciphertext[..CHUNK_SIZE + TAG_SIZE]).is_err());
+    }
+}
@@ -0,0 +1,192 @@
+//! Message reminders ("remind me in 2 hours / tomorrow 9am").
+//!
+//! Stored under the `reminders` key of the synced preferences blob
+//! (`user_preferences`, sealed under the account key — see `user.rs`), the same
+//! way `sidebar.rs` keeps pins: every linked device reads the same list and the
+//! server only ever holds ciphertext. Locally the list sits in the preferences
+//! mirror inside the SQLCipher database.
+//!
+//! `at` is an absolute instant (unix ms). "Tomorrow 9am" is resolved against
+//! the device's time zone when the reminder is made, so a device in another
+//! zone fires it at the same moment rather than at its own 9am.
+//!
+//! Firing is the frontend's job (`useReminderScheduler`): it arms one timer for
+//! the earliest reminder, raises the notification and deletes the entry, which
+//! stops the other devices firing it again once they next read preferences.
+
+use std::sync::Arc;
+
+use serde::{Deserialize, Serialize};
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+/// Preferences key holding the `Vec<Reminder>`.
+const REMINDERS_KEY: &str = "reminders";
+/// Pending reminders per account. Keeps the synced blob small.
+const MAX_REMINDERS: usize = 100;
+/// Characters of the message kept for the notification body.
+const PREVIEW_CHARS: usize = 120;
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Reminder {
+    pub id: String,
+    pub message_id: String,
+    /// Group channel id or DM channel id the message lives in.
+    pub conversation_id: String,
+    /// When to fire, unix ms.
+    pub at: i64,
+    pub created_at: i64,
+    /// Start of the message text, from the local message store. Filled in on
+    /// read, never synced — `None` when this device doesn't have the message.
+    #[serde(default, skip_deserializing, skip_serializing_if = "Option::is_none")]
+    pub preview: Option<String>,
+}
+
+fn reminders_from_preferences(preferences_json: &str) -> Vec<Reminder> {
+    serde_json::from_str::<serde_json::Value>(preferences_json)
+        .ok()
+        .and_then(|v| v.get(REMINDERS_KEY).cloned())
+        .and_then(|v| serde_json::from_value(v).ok())
+        .unwrap_or_default()
+}
+
+/// Fill `preview` from the local message store.
+async fn with_previews(state: &Arc<AppState>, mut reminders: Vec<Reminder>) -> Vec<Reminder> {
+    let guard = state.local_db.lock().await;
+    let Some(db) = guard.as_ref() else {
+        return reminders;
+    };
+    for reminder in &mut reminders {
+        reminder.preview = db
+            .conn()
+            .query_row(
+                "SELECT content FROM message WHERE id = ?1 AND deleted_at IS NULL",
+                [&reminder.message_id],
+                |row| row.get::<_, Option<String>>(0),
+            )
+            .ok()
+            .flatten()
+            .map(|text| text.chars().take(PREVIEW_CHARS).collect());
+    }
+    reminders
+}
+
+/// Read-modify-write the list through the synced preferences path. Returned
+/// sorted by `at`.
+async fn update_reminders(
+    user_id: String,
+    state: &Arc<AppState>,
+    f: impl FnOnce(&mut Vec<Reminder>) -> Result<()>,
+) -> Result<Vec<Reminder>> {
+    let current = crate::commands::user::get_preferences(user_id.clone(), state).await?;
+    let mut prefs = match serde_json::from_str::<serde_json::Value>(&current) {
+        Ok(serde_json::Value::Object(map)) => map,
+        _ => serde_json::Map::new(),
+    };
+    let mut reminders = reminders_from_preferences(&current);
+    f(&mut reminders)?;
+    reminders.sort_by_key(|r| r.at);
+    prefs.insert(REMINDERS_KEY.to_string(), serde_json::to_value(&reminders)?);
+    crate::commands::user::save_preferences(
+        user_id,
+        serde_json::Value::Object(prefs).to_string(),
+        state,
+    )
+    .await?;
+    Ok(with_previews(state, reminders).await)
+}
+
+/// Pending reminders, earliest first. Reads preferences remote-first (see
+/// `get_preferences`) so reminders made on another device show up here.
+pub async fn list_reminders(user_id: String, state: &Arc<AppState>) -> Result<Vec<Reminder>> {
+    let current = crate::commands::user::get_preferences(user_id, state).await?;
+    let mut reminders = reminders_from_preferences(&current);
+    reminders.sort_by_key(|r| r.at);
+    Ok(with_previews(state, reminders).await)
+}
+
+/// Remind the user about `message_id` at `at` (unix ms). A second reminder for
+/// the same message replaces the first.
+pub async fn create_reminder(
+    user_id: String,
+    message_id: String,
+    at: i64,
+    state: &Arc<AppState>,
+) -> Result<Reminder> {
+    if at <= 0 {
+        return Err(Error::Other(anyhow::anyhow!("invalid reminder time")));
+    }
+    let conversation_id = {
+        let guard = state.local_db.lock().await;
+        let db = guard.as_ref().ok_or(Error::NotSignedIn)?;
+        db.conn()
+            .query_row(
+                "SELECT conversation_id FROM message WHERE id = ?1",
+                [&message_id],
+                |row| row.get::<_, String>(0),
+            )
+            .map_err(|_| Error::NotFound(format!("message {message_id}")))?
+    };
+    let reminder = Reminder {
+        id: ulid::Ulid::new().to_string(),
+        message_id,
+        conversation_id,
+        at,
+        created_at: chrono::Utc::now().timestamp_millis(),
+        preview: None,
+    };
+    let id = reminder.id.clone();
+    let saved = update_reminders(user_id, state, |reminders| {
+        reminders.retain(|r| r.message_id != reminder.message_id);
+        if reminders.len() >= MAX_REMINDERS {
+            return Err(Error::Conflict(format!(
+                "at most {MAX_REMINDERS} pending reminders"
+            )));
+        }
+        reminders.push(reminder);
+        Ok(())
+    })
+    .await?;
+    saved
+        .into_iter()
+        .find(|r| r.id == id)
+        .ok_or_else(|| Error::Other(anyhow::anyhow!("reminder not saved")))
+}
+
+/// Drop a reminder — after it fires, or when the user cancels it. Unknown ids
+/// are ignored (another device may have fired it first).
+pub async fn delete_reminder(
+    user_id: String,
+    reminder_id: String,
+    state: &Arc<AppState>,
+) -> Result<Vec<Reminder>> {
+    update_reminders(user_id, state, |reminders| {
+        reminders.retain(|r| r.id != reminder_id);
+        Ok(())
+    })
+    .await
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn reads_the_reminders_key() {
+        let reminders = reminders_from_preferences(
+            r#"{"accent_color":"x","reminders":[{"id":"r1","message_id":"m1","conversation_id":"c1","at":5,"created_at":1,"preview":"not synced"}]}"#,
+        );
+        assert_eq!(reminders.len(), 1);
+        assert_eq!(reminders[0].at, 5);
+        // The preview is local-only; a synced copy of it is ignored.
+        assert!(reminders[0].preview.is_none());
+    }
+
+    #[test]
+    fn missing_or_malformed_key_is_empty() {
+        assert!(reminders_from_preferences("{}").is_empty());
+        assert!(reminders_from_preferences(r#"{"reminders":5}"#).is_empty());
+        assert!(reminders_from_preferences("not json").is_empty());
+    }
+}
@@ -79,8 +79,12 @@ fn combined(my_fp: &str, peer_fp: &str) -> String {
     } else {
         (peer_fp, my_fp)
     };
-    let joined = format!("{a}{b}");
-    joined
+    blocks(&format!("{a}{b}"))
+}
+
+/// Group a digit string into space-separated 5-digit blocks for display.
+fn blocks(digits: &str) -> String {
+    digits
         .as_bytes()
         .chunks(5)
         .map(|c| std::str::from_utf8(c).unwrap_or(""))
@@ -102,7 +106,7 @@ pub(crate) async fn fetch_account_key(
     let row = rows
         .next()
         .await?
-        .ok_or_else(|| Error::Other(anyhow::anyhow!("user {user_id} not found")))?;
+        .ok_or_else(|| Error::NotFound(format!("user {user_id}")))?;
     let pubkey: Option<Vec<u8>> = row.get::<Option<Vec<u8>>>(0).ok().flatten();
     let pubkey = pubkey
         .ok_or_else(|| Error::Other(anyhow::anyhow!("user {user_id} has no account_id_pub")))?;
@@ -139,7 +143,7 @@ pub async fn get_safety_number(
     let guard = state.local_db.lock().await;
     let db = guard
         .as_ref()
-        .ok_or_else(|| Error::Other(anyhow::anyhow!("Not signed in")))?;
+        .ok_or(Error::NotSignedIn)?;
     let pin: Option<(Vec<u8>, i64)> = db
         .conn()
         .query_row(
@@ -165,6 +169,27 @@ pub async fn get_safety_number(
     })
 }
 
+#[derive(Debug, Serialize)]
+pub struct IdentityFingerprint {
+    /// 30 decimal digits, grouped into 5-digit blocks for display.
+    pub fingerprint: String,
+}
+
+/// The signed-in user's own fingerprint, from the account key on this device
+/// rather than the (untrusted) published copy. It is this user's half of every
+/// safety number they appear in, so a contact comparing numbers will find these
+/// 30 digits as one of the two halves.
+pub async fn get_identity_fingerprint(
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<IdentityFingerprint> {
+    let key = crate::commands::account_identity::load_account_id_key(state, &user_id).await?;
+    let fp = fingerprint(key.verifying_key().as_bytes(), user_id.as_bytes());
+    Ok(IdentityFingerprint {
+        fingerprint: blocks(&fp),
+    })
+}
+
 /// Snapshot of every contact for whom the local user has a TOFU pin row,
 /// keyed by peer user id. `verified=true` means they were explicitly marked
 /// verified by the user; `key_changed=true` means the pin exists but the
@@ -188,7 +213,7 @@ pub async fn list_peer_verifications(
         let guard = state.local_db.lock().await;
         let db = guard
             .as_ref()
-            .ok_or_else(|| Error::Other(anyhow::anyhow!("Not signed in")))?;
+            .ok_or(Error::NotSignedIn)?;
         let mut stmt = db.conn().prepare(
             "SELECT peer_user_id, account_id_pub, verified FROM contact_verification",
         )?;
@@ -242,7 +267,7 @@ pub async fn set_contact_verified(
     let guard = state.local_db.lock().await;
     let db = guard
         .as_ref()
-        .ok_or_else(|| Error::Other(anyhow::anyhow!("Not signed in")))?;
+        .ok_or(Error::NotSignedIn)?;
     db.conn().execute(
         "INSERT INTO contact_verification \
            (peer_user_id, account_id_pub, identity_version, verified) \
@@ -257,10 +282,56 @@ pub async fn set_contact_verified(
     Ok(())
 }
 
+/// Whether the peer's key moved from the pinned one to `server_pub` only by
+/// rotations the previous key signed (`account_key_log.rotation_sig`,
+/// migration 000020). Such a move is the peer's own doing, so the pin can
+/// follow it without a warning. Any read failure counts as "no".
+async fn follows_signed_rotation(
+    conn: &libsql::Connection,
+    peer_user_id: &str,
+    pinned_pub: &[u8],
+    pinned_version: i64,
+    server_pub: &[u8],
+) -> bool {
+    let steps = async {
+        let mut rows = conn
+            .query(
+                "SELECT identity_version, account_id_pub, rotation_sig FROM account_key_log \
+                 WHERE user_id = ?1 AND identity_version > ?2 ORDER BY identity_version",
+                libsql::params![peer_user_id, pinned_version],
+            )
+            .await?;
+        let mut steps = Vec::new();
+        while let Some(row) = rows.next().await? {
+            steps.push((
+                row.get::<i64>(0)?,
+                row.get::<Vec<u8>>(1)?,
+                row.get::<Option<Vec<u8>>>(2).ok().flatten(),
+            ));
+        }
+        Ok::<_, libsql::Error>(steps)
+    };
+    match steps.await {
+        Ok(steps) => crate::commands::account_identity::verify_rotation_chain(
+            peer_user_id,
+            pinned_pub,
+            pinned_version,
+            &steps,
+            server_pub,
+        ),
+        Err(e) => {
+            eprintln!("[safety] rotation chain for {peer_user_id} unreadable: {e}");
+            false
+        }
+    }
+}
+
 /// TOFU pin + change detection for the DM/reconcile path. First sight of a
 /// peer's key is pinned silently. A later mismatch updates the pin and
 /// clears `verified` (advisory — the caller does not block delivery, the
-/// next profile open shows "changed").
+/// next profile open shows "changed"), unless the peer reached the new key
+/// by signed rotations ([`follows_signed_rotation`]), in which case the pin
+/// moves and `verified` carries over.
 pub async fn check_and_pin_account_key(
     state: &Arc<AppState>,
     peer_user_id: &str,
@@ -272,22 +343,44 @@ pub async fn check_and_pin_account_key(
         Err(_) => return Ok(()),
     };
 
+    let pinned: Option<(Vec<u8>, i64)> = {
+        let guard = state.local_db.lock().await;
+        let db = guard
+            .as_ref()
+            .ok_or(Error::NotSignedIn)?;
+        db.conn()
+            .query_row(
+                "SELECT account_id_pub, identity_version FROM contact_verification \
+                 WHERE peer_user_id = ?1",
+                rusqlite::params![peer_user_id],
+                |r| Ok((r.get::<_, Vec<u8>>(0)?, r.get::<_, i64>(1)?)),
+            )
+            .ok()
+    };
+    // Checked before re-taking the local-DB lock: it reads Turso.
+    let rotated = match &pinned {
+        Some((p, v)) if *p != peer_pub => {
+            follows_signed_rotation(&conn, peer_user_id, p, *v, &peer_pub).await
+        }
+        _ => false,
+    };
+
     let guard = state.local_db.lock().await;
     let db = guard
         .as_ref()
-        .ok_or_else(|| Error::Other(anyhow::anyhow!("Not signed in")))?;
-    let pinned: Option<Vec<u8>> = db
-        .conn()
-        .query_row(
-            "SELECT account_id_pub FROM contact_verification WHERE peer_user_id = ?1",
-            rusqlite::params![peer_user_id],
-            |r| r.get::<_, Vec<u8>>(0),
-        )
-        .ok();
+        .ok_or(Error::NotSignedIn)?;
 
     let mut key_did_change = false;
     match pinned {
-        Some(p) if p == peer_pub => {}
+        Some((p, _)) if p == peer_pub => {}
+        Some(_) if rotated => {
+            db.conn().execute(
+                "UPDATE contact_verification SET \
+                   account_id_pub = ?2, identity_version = ?3, updated_at = datetime('now') \
+                 WHERE peer_user_id = ?1",
+                rusqlite::params![peer_user_id, peer_pub, peer_version],
+            )?;
+        }
         Some(_) => {
             eprintln!(
                 "[safety] account_id_pub for {peer_user_id} changed — clearing verified status"
@@ -341,7 +434,8 @@ pub async fn check_and_pin_account_key(
 /// existing pin that no longer matches the server's current key is
 /// updated, has its `verified` flag cleared, and emits a `KeyChanged`
 /// event so any open conversation surface (DM, group, channel) can show
-/// the banner.
+/// the banner — unless the peer signed its way to the new key
+/// ([`follows_signed_rotation`]), which only moves the pin.
 ///
 /// Callers should exclude their own user_id — the local user is not a
 /// peer and has no `contact_verification` row.
@@ -391,32 +485,64 @@ pub async fn batch_check_and_pin_account_keys(
     }
 
     // 2. One local-DB SELECT for existing pins covering this batch.
-    let mut changed: Vec<(String, i64)> = Vec::new();
+    let mut existing: std::collections::HashMap<String, (Vec<u8>, i64)> =
+        std::collections::HashMap::new();
     {
         let guard = state.local_db.lock().await;
         let db = guard
             .as_ref()
-            .ok_or_else(|| Error::Other(anyhow::anyhow!("Not signed in")))?;
-        let mut existing: std::collections::HashMap<String, Vec<u8>> =
-            std::collections::HashMap::new();
-        {
-            let mut stmt = db.conn().prepare(
-                "SELECT peer_user_id, account_id_pub FROM contact_verification",
-            )?;
-            let rows = stmt
-                .query_map([], |r| {
-                    Ok((r.get::<_, String>(0)?, r.get::<_, Vec<u8>>(1)?))
-                })?
-                .collect::<std::result::Result<Vec<_>, rusqlite::Error>>()?;
-            for (id, pubkey) in rows {
-                existing.insert(id, pubkey);
+            .ok_or(Error::NotSignedIn)?;
+        let mut stmt = db.conn().prepare(
+            "SELECT peer_user_id, account_id_pub, identity_version FROM contact_verification",
+        )?;
+        let rows = stmt
+            .query_map([], |r| {
+                Ok((
+                    r.get::<_, String>(0)?,
+                    r.get::<_, Vec<u8>>(1)?,
+                    r.get::<_, i64>(2)?,
+                ))
+            })?
+            .collect::<std::result::Result<Vec<_>, rusqlite::Error>>()?;
+        for (id, pubkey, version) in rows {
+            existing.insert(id, (pubkey, version));
+        }
+    }
+
+    // 3. Mismatched pins that a signed rotation chain explains. Rare, so one
+    //    Turso read per such peer, with the local-DB lock released.
+    let mut rotated: std::collections::HashSet<String> = std::collections::HashSet::new();
+    for (peer_id, (server_pub, _)) in &server_keys {
+        if let Some((p, v)) = existing.get(peer_id) {
+            if p != server_pub
+                && follows_signed_rotation(&conn, peer_id, p, *v, server_pub).await
+            {
+                rotated.insert(peer_id.clone());
             }
         }
+    }
+
+    // 4. Write the pins.
+    let mut changed: Vec<(String, i64)> = Vec::new();
+    {
+        let guard = state.local_db.lock().await;
+        let db = guard
+            .as_ref()
+            .ok_or(Error::NotSignedIn)?;
         for (peer_id, (server_pub, server_version)) in &server_keys {
             match existing.get(peer_id) {
-                Some(p) if p == server_pub => {
+                Some((p, _)) if p == server_pub => {
                     // No change — leave verified flag alone.
                 }
+                Some(_) if rotated.contains(peer_id) => {
+                    db.conn().execute(
+                        "UPDATE contact_verification SET \
+                           account_id_pub = ?2, identity_version = ?3, \
+                           updated_at = datetime('now') \
+                         WHERE peer_user_id = ?1",
+                        rusqlite::params![peer_id, server_pub, *server_version],
+                    )?;
+                }
                 Some(_) => {
                     db.conn().execute(
                         "UPDATE contact_verification SET \
@@ -442,7 +568,7 @@ pub async fn batch_check_and_pin_account_keys(
         }
     }
 
-    // 3. Emit one KeyChanged event per changed peer. Done outside the
+    // 5. Emit one KeyChanged event per changed peer. Done outside the
     //    local-DB guard so we never hold the rusqlite lock across an
     //    await on the LiveKit mutex.
     if !changed.is_empty() {
@@ -486,4 +612,13 @@ mod tests {
         assert_eq!(combined(&x, &y), combined(&y, &x));
         assert_eq!(combined(&x, &y).replace(' ', "").len(), 60);
     }
+
+    #[test]
+    fn own_fingerprint_is_a_half_of_the_safety_number() {
+        let x = fingerprint(&[1u8; 32], b"alice");
+        let y = fingerprint(&[2u8; 32], b"bob");
+        let number = combined(&x, &y);
+        assert_eq!(blocks(&x).len(), 35);
+        assert!(number.starts_with(&blocks(&x)) || number.ends_with(&blocks(&x)));
+    }
 }
@@ -0,0 +1,165 @@
+//! Pinned conversations and custom sidebar order.
+//!
+//! Stored under the `sidebar_order` key of the synced preferences blob
+//! (`user_preferences`, sealed under the account key — see `user.rs`), so the
+//! order follows the user to their other devices without the server learning
+//! it. Shape: `{"pinned": [id, …], "order": [id, …]}`; ids are group ids and
+//! DM channel ids in one list (both ULIDs, so they never collide).
+//!
+//! `list_user_groups_with_channels`, `list_user_groups` and `list_dm_channels`
+//! apply the order on the way out: pinned entries first (in pin order), then
+//! entries in `order`, then everything else in its natural order. They read the
+//! local preferences mirror, so ordering works offline and costs no round trip.
+
+use std::sync::Arc;
+
+use serde::{Deserialize, Serialize};
+
+use crate::error::{Error, Result};
+use crate::state::AppState;
+
+/// Preferences key holding the [`SidebarOrder`].
+const SIDEBAR_ORDER_KEY: &str = "sidebar_order";
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct SidebarOrder {
+    #[serde(default)]
+    pub pinned: Vec<String>,
+    #[serde(default)]
+    pub order: Vec<String>,
+}
+
+impl SidebarOrder {
+    fn from_preferences(preferences_json: &str) -> Self {
+        serde_json::from_str::<serde_json::Value>(preferences_json)
+            .ok()
+            .and_then(|v| v.get(SIDEBAR_ORDER_KEY).cloned())
+            .and_then(|v| serde_json::from_value(v).ok())
+            .unwrap_or_default()
+    }
+
+    pub fn is_pinned(&self, id: &str) -> bool {
+        self.pinned.iter().any(|p| p == id)
+    }
+
+    /// Sort key: pinned by pin position, then by `order` position, then last.
+    fn rank(&self, id: &str) -> (u8, usize) {
+        if let Some(i) = self.pinned.iter().position(|p| p == id) {
+            return (0, i);
+        }
+        if let Some(i) = self.order.iter().position(|p| p == id) {
+            return (1, i);
+        }
+        (2, 0)
+    }
+
+    /// Stable-sort `items` into sidebar order.
+    pub(crate) fn sort<T>(&self, items: &mut [T], id: impl Fn(&T) -> &str) {
+        items.sort_by_key(|item| self.rank(id(item)));
+    }
+}
+
+/// The order from the local preferences mirror. Default (no custom order)
+/// when the local DB isn't open or nothing is saved.
+pub(crate) async fn load_sidebar_order(state: &Arc<AppState>) -> SidebarOrder {
+    let guard = state.local_db.lock().await;
+    let Some(db) = guard.as_ref() else {
+        return SidebarOrder::default();
+    };
+    db.conn()
+        .query_row("SELECT preferences FROM preferences LIMIT 1", [], |row| {
+            row.get::<_, String>(0)
+        })
+        .map(|p| SidebarOrder::from_preferences(&p))
+        .unwrap_or_default()
+}
+
+/// Read-modify-write the order through the synced preferences path.
+async fn update_sidebar_order(
+    user_id: String,
+    state: &Arc<AppState>,
+    f: impl FnOnce(&mut SidebarOrder),
+) -> Result<SidebarOrder> {
+    let current = crate::commands::user::get_preferences(user_id.clone(), state).await?;
+    let mut prefs = match serde_json::from_str::<serde_json::Value>(&current) {
+        Ok(serde_json::Value::Object(map)) => map,
+        _ => serde_json::Map::new(),
+    };
+    let mut order = SidebarOrder::from_preferences(&current);
+    f(&mut order);
+    prefs.insert(
+        SIDEBAR_ORDER_KEY.to_string(),
+        serde_json::to_value(&order).map_err(|e| Error::Other(e.into()))?,
+    );
+    crate::commands::user::save_preferences(
+        user_id,
+        serde_json::Value::Object(prefs).to_string(),
+        state,
+    )
+    .await?;
+    Ok(order)
+}
+
+pub async fn get_sidebar_order(state: &Arc<AppState>) -> Result<SidebarOrder> {
+    Ok(load_sidebar_order(state).await)
+}
+
+/// Pin `target_id` (a group or DM channel) to the top of its sidebar section,
+/// or with `pinned = false` unpin it. New pins go below existing ones.
+pub async fn pin_conversation(
+    user_id: String,
+    target_id: String,
+    pinned: bool,
+    state: &Arc<AppState>,
+) -> Result<SidebarOrder> {
+    update_sidebar_order(user_id, state, |order| {
+        order.pinned.retain(|p| p != &target_id);
+        if pinned {
+            order.pinned.push(target_id);
+        }
+    })
+    .await
+}
+
+/// Replace the custom order (after a drag-reorder). `ids` is the full sidebar
+/// order; pins keep their own order and are dropped from `ids` if present.
+pub async fn set_sidebar_order(
+    user_id: String,
+    ids: Vec<String>,
+    state: &Arc<AppState>,
+) -> Result<SidebarOrder> {
+    update_sidebar_order(user_id, state, |order| {
+        let pinned = std::mem::take(&mut order.pinned);
+        let mut seen = std::collections::HashSet::new();
+        order.order = ids
+            .into_iter()
+            .filter(|id| !pinned.contains(id) && seen.insert(id.clone()))
+            .collect();
+        order.pinned = pinned;
+    })
+    .await
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn pinned_then_ordered_then_natural() {
+        let order = SidebarOrder::from_preferences(
+            r##"{"accent_color":"#fff","sidebar_order":{"pinned":["d"],"order":["c","a"]}}"##,
+        );
+        let mut ids = vec!["a", "b", "c", "d", "e"];
+        order.sort(&mut ids, |s| *s);
+        assert_eq!(ids, ["d", "c", "a", "b", "e"]);
+        assert!(order.is_pinned("d"));
+        assert!(!order.is_pinned("a"));
+    }
+
+    #[test]
+    fn missing_or_malformed_key_is_default() {
+        assert!(SidebarOrder::from_preferences("{}").pinned.is_empty());
+        assert!(SidebarOrder::from_preferences(r#"{"sidebar_order":5}"#).order.is_empty());
+        assert!(SidebarOrder::from_preferences("not json").order.is_empty());
+    }
+}
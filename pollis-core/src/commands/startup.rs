@@ -0,0 +1,38 @@
+//! Cold-start stage timings.
+//!
+//! The shell's setup hook wraps each startup stage (remote DB connect, overlay
+//! apply, media server, …) in [`record_startup_stage`], which logs the
+//! duration and keeps it in memory for this process. [`get_startup_timings`]
+//! returns them in the order they finished, so a slow launch can be read off
+//! the log or from the frontend without a profiler. Stages run concurrently
+//! where they're independent, so their durations overlap rather than sum.
+
+use std::sync::Mutex;
+use std::time::Instant;
+
+use serde::Serialize;
+
+static STAGES: Mutex<Vec<StartupStage>> = Mutex::new(Vec::new());
+
+#[derive(Debug, Clone, Serialize)]
+pub struct StartupStage {
+    pub name: String,
+    pub ms: u64,
+}
+
+/// Record that `name` ran from `started` until now.
+pub fn record_startup_stage(name: &str, started: Instant) {
+    let ms = started.elapsed().as_millis() as u64;
+    eprintln!("[startup] {name}: {ms} ms");
+    if let Ok(mut stages) = STAGES.lock() {
+        stages.push(StartupStage {
+            name: name.to_string(),
+            ms,
+        });
+    }
+}
+
+/// Every stage recorded so far in this process.
+pub fn get_startup_timings() -> Vec<StartupStage> {
+    STAGES.lock().map(|s| s.clone()).unwrap_or_default()
+}
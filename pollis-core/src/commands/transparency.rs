@@ -519,7 +519,7 @@ async fn load_pinned_key(
     let guard = state.local_db.lock().await;
     let db = guard
         .as_ref()
-        .ok_or_else(|| Error::Other(anyhow::anyhow!("Not signed in")))?;
+        .ok_or(Error::NotSignedIn)?;
     let pin = db
         .conn()
         .query_row(
@@ -2,7 +2,16 @@ use serde::{Deserialize, Serialize};
 
 use std::sync::Arc;
 
-use crate::error::Result;
+use aes_gcm::aead::generic_array::GenericArray;
+use aes_gcm::aead::Aead;
+use aes_gcm::{Aes256Gcm, KeyInit};
+use base64::Engine as _;
+use hkdf::Hkdf;
+use rand::RngCore;
+use sha2::Sha256;
+use zeroize::Zeroizing;
+
+use crate::error::{Error, Result};
 use crate::state::AppState;
 
 #[derive(Debug, Serialize, Deserialize)]
@@ -60,14 +69,153 @@ pub async fn update_user_profile(
     Ok(())
 }
 
+// ── Preferences sealing ──────────────────────────────────────────────────────
+//
+// The synced preferences blob is encrypted before it leaves the device, under
+// a key every one of the user's devices can derive and the server can't: HKDF
+// over the account identity private key (held by every enrolled device, see
+// `account_identity.rs`). Stored as `SEALED_PREFS_PREFIX` + base64(nonce ||
+// AES-256-GCM ciphertext) in the same `user_preferences.preferences` column.
+// Rows without the prefix are legacy plaintext and are read as-is; the next
+// save seals them.
+
+/// Marks a sealed preferences row. Versioned so the derivation can change.
+const SEALED_PREFS_PREFIX: &str = "pollis-sealed-v1:";
+
+/// HKDF info — binds the derived key to preference sealing only.
+const PREFS_HKDF_INFO: &[u8] = b"pollis-settings-sync-v1";
+
+const PREFS_NONCE_LEN: usize = 12;
+
+/// Derive the preferences key from the account identity private key. The
+/// user id is the salt so two accounts never share a key even if (somehow)
+/// they shared identity material.
+fn preferences_key(account_key: &[u8; 32], user_id: &str) -> Zeroizing<[u8; 32]> {
+    let hk = Hkdf::<Sha256>::new(Some(user_id.as_bytes()), account_key);
+    let mut out = Zeroizing::new([0u8; 32]);
+    hk.expand(PREFS_HKDF_INFO, out.as_mut())
+        .expect("HKDF-SHA256 expand 32 bytes is always valid");
+    out
+}
+
+pub(crate) fn seal_preferences(key: &[u8; 32], preferences_json: &str) -> Result<String> {
+    let mut nonce = [0u8; PREFS_NONCE_LEN];
+    rand::rngs::OsRng.fill_bytes(&mut nonce);
+    let cipher = Aes256Gcm::new(GenericArray::from_slice(key));
+    let ct = cipher
+        .encrypt(GenericArray::from_slice(&nonce), preferences_json.as_bytes())
+        .map_err(|e| Error::Crypto(format!("preferences encrypt: {e}")))?;
+    let mut blob = Vec::with_capacity(PREFS_NONCE_LEN + ct.len());
+    blob.extend_from_slice(&nonce);
+    blob.extend_from_slice(&ct);
+    Ok(format!(
+        "{SEALED_PREFS_PREFIX}{}",
+        base64::engine::general_purpose::STANDARD.encode(blob)
+    ))
+}
+
+/// Inverse of [`seal_preferences`]. Unsealed (legacy plaintext) input is
+/// returned unchanged.
+pub(crate) fn open_preferences(key: &[u8; 32], stored: &str) -> Result<String> {
+    let Some(encoded) = stored.strip_prefix(SEALED_PREFS_PREFIX) else {
+        return Ok(stored.to_string());
+    };
+    let blob = base64::engine::general_purpose::STANDARD
+        .decode(encoded)
+        .map_err(|e| Error::Crypto(format!("preferences decode: {e}")))?;
+    if blob.len() < PREFS_NONCE_LEN {
+        return Err(Error::Crypto("preferences blob too short".into()));
+    }
+    let (nonce, ct) = blob.split_at(PREFS_NONCE_LEN);
+    let cipher = Aes256Gcm::new(GenericArray::from_slice(key));
+    let pt = cipher
+        .decrypt(GenericArray::from_slice(nonce), ct)
+        .map_err(|e| Error::Crypto(format!("preferences decrypt: {e}")))?;
+    String::from_utf8(pt).map_err(|e| Error::Crypto(format!("preferences utf8: {e}")))
+}
+
+/// Per-key merge: start from `remote`, overwrite with every top-level key in
+/// `incoming`. Keeps keys a newer client wrote that this (older) client
+/// doesn't know about. Falls back to `incoming` when either side isn't a
+/// JSON object.
+pub(crate) fn merge_preferences(remote: &str, incoming: &str) -> String {
+    let (Ok(serde_json::Value::Object(mut base)), Ok(serde_json::Value::Object(next))) = (
+        serde_json::from_str::<serde_json::Value>(remote),
+        serde_json::from_str::<serde_json::Value>(incoming),
+    ) else {
+        return incoming.to_string();
+    };
+    for (k, v) in next {
+        base.insert(k, v);
+    }
+    serde_json::Value::Object(base).to_string()
+}
+
+async fn load_preferences_key(
+    state: &Arc<AppState>,
+    user_id: &str,
+) -> Result<Zeroizing<[u8; 32]>> {
+    let signing = crate::commands::account_identity::load_account_id_key(state, user_id).await?;
+    let account_key = Zeroizing::new(signing.to_bytes());
+    Ok(preferences_key(&account_key, user_id))
+}
+
+/// Read the remote preferences row with an account key that is about to be
+/// replaced (`account_identity::rotate_identity`). `None` when there's no row.
+pub(crate) async fn open_remote_preferences_with(
+    state: &Arc<AppState>,
+    user_id: &str,
+    account_key: &[u8],
+) -> Result<Option<String>> {
+    let account_key: Zeroizing<[u8; 32]> = Zeroizing::new(
+        account_key
+            .try_into()
+            .map_err(|_| Error::Crypto("account key has wrong length".into()))?,
+    );
+    let key = preferences_key(&account_key, user_id);
+    match fetch_remote_preferences(state, user_id).await? {
+        Some(stored) => Ok(Some(open_preferences(&key, &stored)?)),
+        None => Ok(None),
+    }
+}
+
+/// Seal `preferences_json` under the account key held now and replace the
+/// remote row with it — no merge, the caller already holds the whole blob.
+/// Used after a rotation, when the remote row is still sealed under the old
+/// key and so can't be merged over.
+pub(crate) async fn reseal_preferences(
+    state: &Arc<AppState>,
+    user_id: &str,
+    preferences_json: &str,
+) -> Result<()> {
+    let key = load_preferences_key(state, user_id).await?;
+    upsert_local_preferences(state, preferences_json).await;
+    let body = serde_json::json!({
+        "user_id": user_id,
+        "preferences": seal_preferences(&key, preferences_json)?,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/profile/preferences", &body).await
+}
+
 pub async fn get_preferences(
     user_id: String,
     state: &Arc<AppState>,
 ) -> Result<String> {
     // Remote is authoritative so changes made on another device are visible
     // immediately on this one. The local row is a last-known-good cache used
-    // only when the remote read fails (offline / flaky connection).
-    match fetch_remote_preferences(&state, &user_id).await {
+    // when the remote read fails (offline / flaky connection) or the sealed
+    // blob can't be opened (identity locked, or reset — a rotation re-seals
+    // it, see `reseal_preferences`).
+    let remote = match fetch_remote_preferences(&state, &user_id).await {
+        Ok(Some(stored)) if stored.starts_with(SEALED_PREFS_PREFIX) => {
+            match load_preferences_key(state, &user_id).await {
+                Ok(key) => open_preferences(&key, &stored).map(Some),
+                Err(e) => Err(e),
+            }
+        }
+        other => other,
+    };
+    match remote {
         Ok(Some(prefs)) => {
             upsert_local_preferences(state, &prefs).await;
             Ok(prefs)
@@ -119,11 +267,28 @@ pub async fn save_preferences(
 ) -> Result<()> {
     upsert_local_preferences(state, &preferences_json).await;
 
+    // Never write plaintext: without the account key (e.g. locked behind the
+    // PIN) the remote write fails and only the local cache above is updated.
+    let key = load_preferences_key(state, &user_id).await?;
+
+    // Merge over what other devices wrote so keys this build doesn't know
+    // about survive. An unreadable remote row is simply overwritten.
+    let merged = match fetch_remote_preferences(state, &user_id).await {
+        Ok(Some(stored)) => match open_preferences(&key, &stored) {
+            Ok(remote) => merge_preferences(&remote, &preferences_json),
+            Err(_) => preferences_json.clone(),
+        },
+        _ => preferences_json.clone(),
+    };
+    if merged != preferences_json {
+        upsert_local_preferences(state, &merged).await;
+    }
+
     // Route the remote preferences upsert through the Delivery Service. The
     // local cache above is written unconditionally beforehand.
     let body = serde_json::json!({
         "user_id": user_id,
-        "preferences": preferences_json,
+        "preferences": seal_preferences(&key, &merged)?,
     });
     crate::commands::mls::ds_post_ok(state, "/v1/profile/preferences", &body).await?;
 
@@ -168,6 +333,105 @@ pub async fn search_user_by_username(
     }
 }
 
+/// The user's opt-in inactive-account policy (`inactivity_policy`). Enforced
+/// by the Delivery Service: after `inactive_days` without an unlock it emails a
+/// warning, and with `delete_recovery` it deletes the Secret Key backup once
+/// that warning goes unanswered.
+#[derive(Debug, Serialize, Deserialize)]
+pub struct InactivityPolicy {
+    pub inactive_days: u32,
+    pub delete_recovery: bool,
+    pub last_active_at: String,
+    pub notified_at: Option<String>,
+    pub recovery_deleted_at: Option<String>,
+}
+
+pub async fn get_inactivity_policy(
+    user_id: String,
+    state: &Arc<AppState>,
+) -> Result<Option<InactivityPolicy>> {
+    let conn = state.remote_db.conn().await?;
+    let mut rows = conn
+        .query(
+            "SELECT inactive_days, delete_recovery, last_active_at, notified_at, recovery_deleted_at \
+             FROM inactivity_policy WHERE user_id = ?1",
+            libsql::params![user_id],
+        )
+        .await?;
+    if let Some(row) = rows.next().await? {
+        return Ok(Some(InactivityPolicy {
+            inactive_days: row.get::<i64>(0)? as u32,
+            delete_recovery: row.get::<i64>(1)? != 0,
+            last_active_at: row.get(2)?,
+            notified_at: row.get(3)?,
+            recovery_deleted_at: row.get(4)?,
+        }));
+    }
+    Ok(None)
+}
+
+/// Set (`Some(days)`) or clear (`None`) the policy. The DS bounds `days` to
+/// 30–730.
+pub async fn set_inactivity_policy(
+    user_id: String,
+    inactive_days: Option<u32>,
+    delete_recovery: bool,
+    state: &Arc<AppState>,
+) -> Result<()> {
+    let body = serde_json::json!({
+        "user_id": user_id,
+        "inactive_days": inactive_days,
+        "delete_recovery": delete_recovery,
+    });
+    crate::commands::mls::ds_post_ok(state, "/v1/account/inactivity-policy", &body).await
+}
+
+/// Tell the DS this account is in use, cancelling any pending inactivity
+/// warning. Sent once per unlock — never on a timer. Best-effort.
+pub(crate) async fn inactivity_check_in(state: &Arc<AppState>, user_id: &str) {
+    let body = serde_json::json!({ "user_id": user_id });
+    if let Err(e) = crate::commands::mls::ds_post_ok(state, "/v1/account/check-in", &body).await {
+        eprintln!("[user] inactivity check-in failed (non-fatal): {e}");
+    }
+}
+
+/// One UTC day of the user's usage as the Delivery Service counted it.
+#[derive(Debug, Serialize, Deserialize)]
+pub struct UsageDay {
+    pub day: String,
+    pub envelopes: i64,
+    pub envelope_bytes: i64,
+    pub attachments_presigned: i64,
+    pub attachment_bytes: i64,
+}
+
+/// The user's own usage, newest day first. `enabled` is false on instances
+/// that don't count usage, where `days` is always empty.
+#[derive(Debug, Serialize, Deserialize)]
+pub struct UsageReport {
+    pub enabled: bool,
+    pub days: Vec<UsageDay>,
+}
+
+/// The signed-in user's usage for the last `days` days (DS default 30, max
+/// 366), via `POST /v1/usage/me`.
+pub async fn get_my_usage(
+    user_id: String,
+    days: Option<u32>,
+    state: &Arc<AppState>,
+) -> Result<UsageReport> {
+    let body = serde_json::json!({ "user_id": user_id, "days": days });
+    let resp = crate::commands::mls::ds_post_metadata(state, "/v1/usage/me", &body).await?;
+    let status = resp.status();
+    if !status.is_success() {
+        let txt = resp.text().await.unwrap_or_default();
+        return Err(Error::Other(anyhow::anyhow!("get_my_usage {status}: {txt}")));
+    }
+    resp.json()
+        .await
+        .map_err(|e| Error::Other(anyhow::anyhow!("get_my_usage decode: {e}")))
+}
+
 #[cfg(test)]
 mod tests {
     use rusqlite::Connection;
@@ -442,4 +706,51 @@ mod tests {
         );
         assert!(result.is_err(), "duplicate email should violate UNIQUE constraint");
     }
+
+    // ── preferences sealing ────────────────────────────────────────────────
+
+    #[test]
+    fn sealed_preferences_round_trip_and_hide_plaintext() {
+        let key = super::preferences_key(&[7u8; 32], "alice");
+        let json = r#"{"theme":"dark","notifications":true}"#;
+        let sealed = super::seal_preferences(&key, json).unwrap();
+        assert!(sealed.starts_with(super::SEALED_PREFS_PREFIX));
+        assert!(!sealed.contains("theme"));
+        assert_eq!(super::open_preferences(&key, &sealed).unwrap(), json);
+    }
+
+    #[test]
+    fn sealed_preferences_bind_to_account_key_and_user() {
+        let key = super::preferences_key(&[7u8; 32], "alice");
+        let sealed = super::seal_preferences(&key, "{}").unwrap();
+        let other_account = super::preferences_key(&[8u8; 32], "alice");
+        let other_user = super::preferences_key(&[7u8; 32], "bob");
+        assert!(super::open_preferences(&other_account, &sealed).is_err());
+        assert!(super::open_preferences(&other_user, &sealed).is_err());
+    }
+
+    #[test]
+    fn legacy_plaintext_preferences_pass_through() {
+        let key = super::preferences_key(&[7u8; 32], "alice");
+        let legacy = r#"{"theme":"light"}"#;
+        assert_eq!(super::open_preferences(&key, legacy).unwrap(), legacy);
+    }
+
+    #[test]
+    fn merge_preferences_keeps_unknown_remote_keys() {
+        let merged = super::merge_preferences(
+            r#"{"theme":"dark","future_key":[1,2]}"#,
+            r#"{"theme":"light","font_size":14}"#,
+        );
+        let v: serde_json::Value = serde_json::from_str(&merged).unwrap();
+        assert_eq!(v["theme"], "light");
+        assert_eq!(v["font_size"], 14);
+        assert_eq!(v["future_key"], serde_json::json!([1, 2]));
+    }
+
+    #[test]
+    fn merge_preferences_falls_back_to_incoming_on_non_objects() {
+        assert_eq!(super::merge_preferences("not json", r#"{"a":1}"#), r#"{"a":1}"#);
+        assert_eq!(super::merge_preferences(r#"{"a":1}"#, "[]"), "[]");
+    }
 }
@@ -33,6 +33,7 @@ use super::streams::start_mic_stream;
 use super::types::{
     user_id_from_voice_identity, JoinTimings, VoiceEvent, VoiceWarmup, VOICE_WARMUP_TTL,
 };
+use super::vad::Vad;
 
 /// Build the per-device LiveKit identity for a voice participant:
 /// `voice-{user_id}:{device_id}` when a device id is known (the normal case
@@ -304,10 +305,12 @@ pub async fn join_voice_channel(
     // Both are independent and individually expensive on cold starts. Running
     // them with `tokio::join!` cuts the user-visible delay to ~max(net, mic).
     let (frame_tx, mut frame_rx) = tokio::sync::mpsc::unbounded_channel::<(Vec<i16>, u32)>();
-    let is_muted = {
+    let (is_muted, input_gate) = {
         let voice = state.voice.lock().await;
         voice.is_muted.store(false, Ordering::Relaxed);
-        Arc::clone(&voice.is_muted)
+        voice.input_gate.apply(&audio_processing);
+        voice.input_gate.set_ptt_held(false);
+        (Arc::clone(&voice.is_muted), Arc::clone(&voice.input_gate))
     };
 
     let input_device_clone = input_device.clone();
@@ -482,6 +485,8 @@ pub async fn join_voice_channel(
         // ── Mic frame task: rebuffer to exact 10ms, run APM, capture_frame ────
         // Speaking detection runs on the post-APM peak so the indicator follows
         // the user's effective level (after AGC + NS) rather than raw input.
+        // The same detector feeds the transmit gate (push-to-talk / auto-mute);
+        // a held-back frame is sent as silence. See `vad.rs`.
         let audio_source_task = audio_source.clone();
         let voice_arc_frame = Arc::clone(&state.voice);
         let local_identity_for_speaking = local_identity.clone();
@@ -490,9 +495,10 @@ pub async fn join_voice_channel(
         let frame_task = tokio::spawn(async move {
             let chunk_size = (mic_rate / 100) as usize;
             let mut buf: Vec<i16> = Vec::new();
-            let mut speak_hold: u32 = 0; // counts down after speech stops (12 × 10ms = 120ms hold)
-            let mut onset_frames: u32 = 0; // consecutive above-threshold frames; 2 required to (re)trigger
+            let mut vad = Vad::default();
             let mut is_speaking = false;
+            // `None` until the first frame, so the renderer hears the initial state.
+            let mut transmitting: Option<bool> = None;
 
             // Live multi-band meter for our own tile. Sink cloned once so the
             // per-frame emit never re-locks the shared VoiceState mutex. Emit
@@ -525,30 +531,33 @@ pub async fn join_voice_channel(
                         }
                     }
 
-                    let peak = chunk.iter().map(|&s| s.abs()).max().unwrap_or(0);
-
-                    // Speaking detection: require 2 consecutive above-threshold frames to trigger,
-                    // preventing single trailing spikes from resetting the hold counter.
-                    if peak > 1000 {
-                        onset_frames += 1;
-                        if onset_frames >= 2 {
-                            speak_hold = 12;
-                        }
-                    } else {
-                        onset_frames = 0;
-                        if speak_hold > 0 {
-                            speak_hold -= 1;
-                        }
+                    let peak = chunk.iter().map(|&s| s.saturating_abs()).max().unwrap_or(0);
+                    vad.push(peak, input_gate.threshold());
+                    let open = input_gate.transmit(&vad);
+                    if !open {
+                        chunk.fill(0);
                     }
-                    let now_speaking = speak_hold > 0;
-                    if now_speaking != is_speaking {
-                        is_speaking = now_speaking;
+
+                    // Shown as speaking only while the audio actually goes out.
+                    let now_speaking = open && vad.speaking();
+                    let transmit_changed = transmitting != Some(open);
+                    transmitting = Some(open);
+                    if now_speaking != is_speaking || transmit_changed {
                         let voice = voice_arc_frame.lock().await;
                         if let Some(ch) = &voice.channel {
-                            if is_speaking {
-                                let _ = ch.send(VoiceEvent::SpeakingStarted { identity: local_identity_for_speaking.clone() });
-                            } else {
-                                let _ = ch.send(VoiceEvent::SpeakingStopped { identity: local_identity_for_speaking.clone() });
+                            if transmit_changed {
+                                let _ = ch.send(VoiceEvent::TransmitChanged {
+                                    identity: local_identity_for_speaking.clone(),
+                                    transmitting: open,
+                                });
+                            }
+                            if now_speaking != is_speaking {
+                                is_speaking = now_speaking;
+                                if is_speaking {
+                                    let _ = ch.send(VoiceEvent::SpeakingStarted { identity: local_identity_for_speaking.clone() });
+                                } else {
+                                    let _ = ch.send(VoiceEvent::SpeakingStopped { identity: local_identity_for_speaking.clone() });
+                                }
                             }
                         }
                     }
@@ -952,6 +961,7 @@ async fn release_voice_resources(
             *slot = None;
         }
         voice.is_muted.store(false, Ordering::Relaxed);
+        voice.input_gate.set_ptt_held(false);
         voice.current_input_device = None;
         voice.e2ee_key_provider = None;
         voice.e2ee_mls_group_id = None;
@@ -1034,6 +1044,16 @@ pub async fn toggle_voice_mute(state: &Arc<AppState>) -> Result<bool> {
     Ok(new_muted)
 }
 
+/// Press (`true`) or release the push-to-talk key. Only opens the mic while
+/// the input mode is push-to-talk; a press in voice-activity mode is ignored.
+/// The frame task picks the change up on its next 10 ms frame and reports it
+/// as `TransmitChanged`.
+pub async fn set_voice_push_to_talk(pressed: bool, state: &Arc<AppState>) -> Result<()> {
+    let voice = state.voice.lock().await;
+    voice.input_gate.set_ptt_held(pressed);
+    Ok(())
+}
+
 /// Set the per-user output gain multiplier for a remote participant.
 ///
 /// `user_id` is the bare user id (no `voice-` prefix, no `:device_id`
@@ -1229,6 +1249,9 @@ pub async fn set_voice_audio_processing(
 ) -> Result<()> {
     let mut voice = state.voice.lock().await;
 
+    // Input mode / VAD settings apply live, in or out of a call.
+    voice.input_gate.apply(&config);
+
     // Mic rate is fixed at session start; reuse it for the denoiser-rate check.
     let mic_rate = voice.apm.as_ref().map(|a| a.sample_rate_hz());
 
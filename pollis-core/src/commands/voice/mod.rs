@@ -9,6 +9,7 @@ mod lifecycle;
 mod playback;
 mod streams;
 mod types;
+mod vad;
 
 // ── Shared types / state ─────────────────────────────────────────────────────
 pub use types::{
@@ -16,6 +17,10 @@ pub use types::{
     TrackBuffers, VoiceEvent, VoiceState, VoiceWarmup,
 };
 
+// ── Transmit gate (input mode / VAD settings carried on `ApmConfig`) ────────
+pub use vad::{InputGate, InputMode, DEFAULT_SENSITIVITY};
+pub(crate) use vad::Vad;
+
 // ── cpal stream builders (used by voice_test.rs) ─────────────────────────────
 pub(crate) use streams::{start_mic_stream, start_speaker_stream};
 
@@ -27,5 +32,5 @@ pub use devices::list_audio_devices;
 pub use lifecycle::{
     get_last_join_timings, join_voice_channel, leave_voice_channel, prepare_voice_connection,
     set_remote_user_volume, set_voice_audio_processing, set_voice_input_device,
-    set_voice_output_device, subscribe_voice_events, toggle_voice_mute,
+    set_voice_output_device, set_voice_push_to_talk, subscribe_voice_events, toggle_voice_mute,
 };
@@ -22,6 +22,8 @@ use crate::commands::{
     voice_denoiser,
 };
 
+use super::vad::InputGate;
+
 /// Warm-cached LiveKit credentials for a single channel. Issued by
 /// `prepare_voice_connection` on user "intent" (hover, route entry) and
 /// consumed by `join_voice_channel` to skip the synchronous JWT mint.
@@ -94,6 +96,11 @@ pub enum VoiceEvent {
     /// not publishing audio. Lets the renderer swap the mute toggle for a
     /// "listening only" indicator. Only ever carries the local identity.
     MicAvailability { identity: String, available: bool },
+    /// Whether the local mic is going out, as opposed to being held back by
+    /// push-to-talk or auto-mute (`vad.rs`). Separate from `Muted`, which is
+    /// the user's own toggle and is visible to peers. Only ever carries the
+    /// local identity; emitted on change, starting with the first frame.
+    TransmitChanged { identity: String, transmitting: bool },
     SpeakingStarted { identity: String },
     SpeakingStopped { identity: String },
     /// Per-source multi-band audio levels (one 0..1 value per band),
@@ -261,6 +268,9 @@ pub struct VoiceState {
     pub room_task: Option<tokio::task::JoinHandle<()>>,
     pub playback: Arc<Mutex<PlaybackState>>,
     pub is_muted: Arc<AtomicBool>,
+    /// Push-to-talk / auto-mute settings and the held PTT key, read by the mic
+    /// frame task per frame. See `vad.rs`.
+    pub input_gate: Arc<InputGate>,
     /// Set while a `join_voice_channel` call is in flight. Blocks a second
     /// concurrent invocation from racing the first and tripping LiveKit's
     /// DuplicateIdentity eviction (which would disconnect both attempts).
@@ -304,6 +314,7 @@ impl VoiceState {
             room_task: None,
             playback: Arc::new(Mutex::new(PlaybackState::new())),
             is_muted: Arc::new(AtomicBool::new(false)),
+            input_gate: Arc::new(InputGate::default()),
             joining: Arc::new(AtomicBool::new(false)),
             current_input_device: None,
             apm: None,
@@ -0,0 +1,241 @@
+//! Local voice activity detection and the transmit gate.
+//!
+//! The mic frame task (`lifecycle.rs`) feeds every post-APM 10 ms frame's peak
+//! through [`Vad`], which drives the local speaking indicator, then asks
+//! [`InputGate::transmit`] whether the frame goes out. A closed gate sends
+//! silence rather than muting the LiveKit publication: the manual mute keeps
+//! sole ownership of the publication's muted flag, and peers see the gate the
+//! way they see anyone go quiet — their speaking indicator, which they derive
+//! from the audio they receive, drops.
+//!
+//! Two ways to close it, both from [`ApmConfig`](crate::commands::voice_apm::ApmConfig):
+//!
+//!   - **push-to-talk** — open only while the PTT key is held
+//!     (`set_voice_push_to_talk`);
+//!   - **auto-mute** — in voice-activity mode, close after
+//!     `vad_auto_mute_secs` without speech and reopen on the next onset. The
+//!     detector needs two loud frames to trigger, so the first 10 ms of that
+//!     onset goes out as silence.
+//!
+//! Settings live in atomics so the frame loop never takes a lock.
+
+use std::sync::atomic::{AtomicBool, AtomicU16, AtomicU8, Ordering};
+
+use serde::{Deserialize, Serialize};
+
+use crate::commands::voice_apm::ApmConfig;
+
+/// Default [`ApmConfig::vad_sensitivity`]. Maps to about -30 dBFS, the fixed
+/// threshold the speaking indicator used before it was configurable.
+pub const DEFAULT_SENSITIVITY: u8 = 50;
+
+/// Consecutive above-threshold frames needed to (re)trigger speech, so a single
+/// trailing spike can't reset the hold.
+const ONSET_FRAMES: u32 = 2;
+/// Frames speech is held after the level drops (12 × 10 ms = 120 ms).
+const HOLD_FRAMES: u32 = 12;
+/// 10 ms frames per second.
+const FRAMES_PER_SEC: u32 = 100;
+
+/// How the local mic decides when to transmit.
+#[derive(Clone, Copy, Debug, Default, Serialize, Deserialize, PartialEq, Eq)]
+#[serde(rename_all = "snake_case")]
+pub enum InputMode {
+    /// Always transmitting (subject to auto-mute).
+    #[default]
+    VoiceActivity,
+    /// Transmitting only while the push-to-talk key is held.
+    PushToTalk,
+}
+
+/// Peak (i16) a frame must exceed to count as speech. `sensitivity` is 0..=100:
+/// higher picks up quieter speech. Linear in dB from -10 dBFS (0) to -50 dBFS
+/// (100).
+pub fn threshold(sensitivity: u8) -> i16 {
+    let dbfs = -10.0 - 0.4 * f32::from(sensitivity.min(100));
+    (32_767.0 * 10f32.powf(dbfs / 20.0)) as i16
+}
+
+/// Speech detector over per-frame peaks: [`ONSET_FRAMES`] to trigger,
+/// [`HOLD_FRAMES`] to release.
+#[derive(Debug, Default)]
+pub struct Vad {
+    onset: u32,
+    hold: u32,
+    speaking: bool,
+    /// Frames since speech last ended (0 while speaking).
+    silent: u32,
+}
+
+impl Vad {
+    /// Feed one 10 ms frame's peak. Returns the new state when it changed.
+    pub fn push(&mut self, peak: i16, threshold: i16) -> Option<bool> {
+        if peak > threshold {
+            self.onset += 1;
+            if self.onset >= ONSET_FRAMES {
+                self.hold = HOLD_FRAMES;
+            }
+        } else {
+            self.onset = 0;
+            self.hold = self.hold.saturating_sub(1);
+        }
+        let now = self.hold > 0;
+        if now {
+            self.silent = 0;
+        } else {
+            self.silent = self.silent.saturating_add(1);
+        }
+        if now == self.speaking {
+            return None;
+        }
+        self.speaking = now;
+        Some(now)
+    }
+
+    pub fn speaking(&self) -> bool {
+        self.speaking
+    }
+}
+
+/// Live transmit settings shared between the voice commands and the mic frame
+/// task. One per `VoiceState`; survives across calls like `is_muted`.
+#[derive(Debug)]
+pub struct InputGate {
+    push_to_talk: AtomicBool,
+    ptt_held: AtomicBool,
+    sensitivity: AtomicU8,
+    auto_mute_secs: AtomicU16,
+}
+
+impl Default for InputGate {
+    fn default() -> Self {
+        Self {
+            push_to_talk: AtomicBool::new(false),
+            ptt_held: AtomicBool::new(false),
+            sensitivity: AtomicU8::new(DEFAULT_SENSITIVITY),
+            auto_mute_secs: AtomicU16::new(0),
+        }
+    }
+}
+
+impl InputGate {
+    /// Take the input settings from `config` (at join and on every
+    /// `set_voice_audio_processing`). Leaving push-to-talk drops a held key.
+    pub fn apply(&self, config: &ApmConfig) {
+        let ptt = config.input_mode == InputMode::PushToTalk;
+        self.push_to_talk.store(ptt, Ordering::Relaxed);
+        if !ptt {
+            self.ptt_held.store(false, Ordering::Relaxed);
+        }
+        self.sensitivity
+            .store(config.vad_sensitivity.min(100), Ordering::Relaxed);
+        self.auto_mute_secs
+            .store(config.vad_auto_mute_secs, Ordering::Relaxed);
+    }
+
+    /// Record the push-to-talk key. Ignored outside push-to-talk, so a stale
+    /// press can't carry over into a later switch to it.
+    pub fn set_ptt_held(&self, held: bool) {
+        let held = held && self.push_to_talk.load(Ordering::Relaxed);
+        self.ptt_held.store(held, Ordering::Relaxed);
+    }
+
+    /// Current speech threshold, from the sensitivity setting.
+    pub fn threshold(&self) -> i16 {
+        threshold(self.sensitivity.load(Ordering::Relaxed))
+    }
+
+    /// Whether the frame `vad` just classified should be sent.
+    pub fn transmit(&self, vad: &Vad) -> bool {
+        if self.push_to_talk.load(Ordering::Relaxed) {
+            return self.ptt_held.load(Ordering::Relaxed);
+        }
+        let secs = self.auto_mute_secs.load(Ordering::Relaxed);
+        secs == 0 || vad.speaking || vad.silent < u32::from(secs) * FRAMES_PER_SEC
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const LOUD: i16 = 5_000;
+
+    fn config(mode: InputMode, auto_mute_secs: u16) -> ApmConfig {
+        ApmConfig {
+            input_mode: mode,
+            vad_auto_mute_secs: auto_mute_secs,
+            ..ApmConfig::default()
+        }
+    }
+
+    #[test]
+    fn sensitivity_maps_to_a_threshold() {
+        // The default sits where the fixed threshold (1000) was.
+        assert!((threshold(DEFAULT_SENSITIVITY) - 1_000).abs() < 100);
+        assert!(threshold(100) < threshold(50));
+        assert!(threshold(0) > threshold(50));
+        assert_eq!(threshold(200), threshold(100));
+    }
+
+    #[test]
+    fn onset_and_hold() {
+        let mut vad = Vad::default();
+        let t = threshold(DEFAULT_SENSITIVITY);
+        // One spike isn't speech; two frames are.
+        assert_eq!(vad.push(LOUD, t), None);
+        assert_eq!(vad.push(0, t), None);
+        assert_eq!(vad.push(LOUD, t), None);
+        assert_eq!(vad.push(LOUD, t), Some(true));
+        // Held for HOLD_FRAMES of quiet, then released.
+        for _ in 0..HOLD_FRAMES - 1 {
+            assert_eq!(vad.push(0, t), None);
+        }
+        assert_eq!(vad.push(0, t), Some(false));
+    }
+
+    #[test]
+    fn auto_mute_closes_after_silence_and_reopens_on_speech() {
+        let gate = InputGate::default();
+        gate.apply(&config(InputMode::VoiceActivity, 1));
+        let t = gate.threshold();
+        let mut vad = Vad::default();
+        for _ in 0..FRAMES_PER_SEC - 1 {
+            vad.push(0, t);
+            assert!(gate.transmit(&vad));
+        }
+        vad.push(0, t);
+        assert!(!gate.transmit(&vad));
+        vad.push(LOUD, t);
+        assert!(!gate.transmit(&vad));
+        vad.push(LOUD, t);
+        assert!(gate.transmit(&vad));
+
+        // Off: never closes.
+        gate.apply(&config(InputMode::VoiceActivity, 0));
+        let mut vad = Vad::default();
+        for _ in 0..10 * FRAMES_PER_SEC {
+            vad.push(0, t);
+        }
+        assert!(gate.transmit(&vad));
+    }
+
+    #[test]
+    fn push_to_talk_follows_the_key() {
+        let gate = InputGate::default();
+        gate.apply(&config(InputMode::PushToTalk, 0));
+        let mut vad = Vad::default();
+        vad.push(LOUD, gate.threshold());
+        vad.push(LOUD, gate.threshold());
+        assert!(
+            !gate.transmit(&vad),
+            "speech alone doesn't open push-to-talk"
+        );
+        gate.set_ptt_held(true);
+        assert!(gate.transmit(&vad));
+        // Switching back to voice activity forgets the held key.
+        gate.apply(&config(InputMode::VoiceActivity, 0));
+        gate.apply(&config(InputMode::PushToTalk, 0));
+        assert!(!gate.transmit(&vad));
+    }
+}
@@ -29,6 +29,8 @@
 use serde::{Deserialize, Serialize};
 use std::sync::Arc;
 
+use crate::commands::voice::{InputMode, DEFAULT_SENSITIVITY};
+
 #[cfg(not(target_os = "windows"))]
 use webrtc_audio_processing::{
     config::{
@@ -94,6 +96,23 @@ pub struct ApmConfig {
     /// Lives in this struct (rather than its own) so the wire shape stays
     /// flat and `set_voice_audio_processing` is a single round-trip.
     pub click_suppression: bool,
+    /// Voice activity or push-to-talk. Not an APM stage: the mic frame task's
+    /// transmit gate (`voice::vad`) reads it, and it rides here for the same
+    /// one-round-trip reason as `click_suppression`.
+    #[serde(default)]
+    pub input_mode: InputMode,
+    /// How quiet speech may be and still count, 0..=100 (higher = quieter).
+    /// Drives the local speaking indicator and auto-mute.
+    #[serde(default = "default_vad_sensitivity")]
+    pub vad_sensitivity: u8,
+    /// In voice-activity mode, stop transmitting after this many seconds
+    /// without speech; the next onset reopens. 0 = off.
+    #[serde(default)]
+    pub vad_auto_mute_secs: u16,
+}
+
+fn default_vad_sensitivity() -> u8 {
+    DEFAULT_SENSITIVITY
 }
 
 impl Default for ApmConfig {
@@ -109,6 +128,9 @@ impl Default for ApmConfig {
             ns_level: NsLevel::High,
             aec_enabled: true,
             click_suppression: false,
+            input_mode: InputMode::VoiceActivity,
+            vad_sensitivity: DEFAULT_SENSITIVITY,
+            vad_auto_mute_secs: 0,
         }
     }
 }
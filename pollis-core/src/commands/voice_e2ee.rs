@@ -230,7 +230,7 @@ async fn catch_up_mls_group(state: &Arc<AppState>, mls_group_id: &str, user_id:
         )
         .await
         {
-            Ok(()) => eprintln!("[voice-e2ee] catch-up: DM ingest OK {mls_group_id}"),
+            Ok(_) => eprintln!("[voice-e2ee] catch-up: DM ingest OK {mls_group_id}"),
             Err(e) => {
                 eprintln!("[voice-e2ee] catch-up ingest_dm for {mls_group_id}: {e}")
             }
@@ -327,7 +327,7 @@ async fn catch_up_mls_group(state: &Arc<AppState>, mls_group_id: &str, user_id:
     for ch in channel_ids {
         match crate::commands::messages::ingest_channel_envelopes_inner(state, user_id, &ch).await
         {
-            Ok(()) => eprintln!("[voice-e2ee] catch-up: channel ingest OK {ch}"),
+            Ok(_) => eprintln!("[voice-e2ee] catch-up: channel ingest OK {ch}"),
             Err(e) => eprintln!("[voice-e2ee] catch-up ingest_channel for {ch}: {e}"),
         }
     }
@@ -380,7 +380,7 @@ async fn derive_voice_key_for_group(
     let guard = state.local_db.lock().await;
     let db = guard
         .as_ref()
-        .ok_or_else(|| Error::Other(anyhow::anyhow!("Not signed in")))?;
+        .ok_or(Error::NotSignedIn)?;
     let provider = PollisProvider::new(db.conn());
     let group_id = GroupId::from_slice(mls_group_id.as_bytes());
 
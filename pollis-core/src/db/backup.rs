@@ -0,0 +1,553 @@
+//! Local snapshot backups of the per-user encrypted database.
+//!
+//! Snapshots are taken on unlock (at most once per [`SNAPSHOT_MIN_INTERVAL_HOURS`])
+//! and on demand — never on a timer. Each one is a consistent copy written with
+//! `sqlcipher_export` into an attached file, so it is encrypted under the SAME
+//! key as the live DB and is useless off-device without it. The copy is
+//! integrity-checked before it is kept; a failed check deletes it.
+//!
+//! Restoring never rewinds key material or trust state: `kv`, `identity_key`,
+//! `mls_kv` and `contact_verification` are left untouched, because putting an
+//! old MLS epoch back would strand the device in a group it can no longer
+//! follow, and an old TOFU pin or `verified` flag would judge a later key
+//! change against stale trust. Every other table's snapshot rows are
+//! written back over the live ones; rows added since the snapshot are kept
+//! (keyless tables are replaced wholesale). `message` is the exception: a
+//! restore only fills gaps, so a live row — in particular one redacted since
+//! the snapshot — always wins over the snapshot's copy, and no translation
+//! outlives the text it came from. Messages in a conversation whose history
+//! was deleted (`forgotten_conversation`) are never restored. The caller
+//! re-runs retention afterwards so rows the windows had evicted don't come
+//! back either.
+
+use std::path::{Path, PathBuf};
+
+use rusqlite::{Connection, OptionalExtension};
+use serde::{Deserialize, Serialize};
+
+use crate::error::{Error, Result};
+
+/// `ui_state` key holding how many snapshots to keep (text integer).
+const BACKUP_COUNT_KEY: &str = "local_backup_count";
+
+/// Snapshots kept when the user hasn't chosen. `0` disables snapshots.
+pub const DEFAULT_BACKUP_COUNT: i64 = 3;
+
+/// Upper bound on the retention count — each snapshot is a full DB copy.
+pub const MAX_BACKUP_COUNT: i64 = 10;
+
+/// Minimum age of the newest snapshot before an unlock takes another one.
+pub const SNAPSHOT_MIN_INTERVAL_HOURS: i64 = 24;
+
+/// Tables a restore must never overwrite: schema bookkeeping, key material,
+/// the TOFU pins in `contact_verification`, `forgotten_conversation` (an older
+/// snapshot would un-forget history), and
+/// `message_activity`, which is derived from `message` and rebuilt once the
+/// messages are back.
+const RESTORE_SKIP_TABLES: [&str; 6] = [
+    "kv",
+    "identity_key",
+    "mls_kv",
+    "contact_verification",
+    "forgotten_conversation",
+    "message_activity",
+];
+
+/// Tables a restore only adds missing rows to. A live `message` row may have
+/// been redacted (`content = NULL`) since the snapshot; overwriting it would
+/// bring the plaintext back.
+const RESTORE_FILL_ONLY_TABLES: [&str; 1] = ["message"];
+
+/// Snapshot file timestamp format. Sorts lexicographically in time order.
+const TIMESTAMP_FORMAT: &str = "%Y%m%dT%H%M%SZ";
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct LocalBackup {
+    /// Identifies the snapshot for `restore_local_backup`, e.g. `20260101T120000Z`.
+    pub timestamp: String,
+    pub size_bytes: u64,
+}
+
+/// Directory the snapshots for every account on this device live in.
+pub fn backups_dir() -> PathBuf {
+    super::local::dirs_path().join("backups")
+}
+
+fn file_prefix(user_id: &str) -> String {
+    format!("pollis_{user_id}_")
+}
+
+fn snapshot_path(dir: &Path, user_id: &str, timestamp: &str) -> PathBuf {
+    dir.join(format!("{}{timestamp}.db", file_prefix(user_id)))
+}
+
+/// Read how many snapshots to keep. Absent ⇒ [`DEFAULT_BACKUP_COUNT`].
+pub fn get_backup_count(conn: &Connection) -> Result<i64> {
+    let raw: Option<String> = conn
+        .query_row(
+            "SELECT value FROM ui_state WHERE key = ?1",
+            rusqlite::params![BACKUP_COUNT_KEY],
+            |row| row.get(0),
+        )
+        .optional()?;
+    Ok(raw
+        .and_then(|v| v.trim().parse::<i64>().ok())
+        .unwrap_or(DEFAULT_BACKUP_COUNT))
+}
+
+/// Set how many snapshots to keep (`0..=MAX_BACKUP_COUNT`; `0` disables them).
+/// Existing snapshots beyond the new count are pruned by the caller.
+pub fn set_backup_count(conn: &Connection, count: i64) -> Result<()> {
+    if !(0..=MAX_BACKUP_COUNT).contains(&count) {
+        return Err(Error::Other(anyhow::anyhow!(
+            "invalid local_backup_count {count}: must be between 0 and {MAX_BACKUP_COUNT}"
+        )));
+    }
+    conn.execute(
+        "INSERT INTO ui_state (key, value, updated_at) VALUES (?1, ?2, datetime('now')) \
+         ON CONFLICT(key) DO UPDATE SET value = ?2, updated_at = datetime('now')",
+        rusqlite::params![BACKUP_COUNT_KEY, count.to_string()],
+    )?;
+    Ok(())
+}
+
+/// List this user's snapshots in `dir`, newest first.
+pub fn list_backups(dir: &Path, user_id: &str) -> Result<Vec<LocalBackup>> {
+    let prefix = file_prefix(user_id);
+    let entries = match std::fs::read_dir(dir) {
+        Ok(entries) => entries,
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+        Err(e) => return Err(Error::Other(anyhow::anyhow!("read backups dir: {e}"))),
+    };
+
+    let mut backups = Vec::new();
+    for entry in entries.flatten() {
+        let name = entry.file_name().to_string_lossy().into_owned();
+        let Some(timestamp) = name
+            .strip_prefix(&prefix)
+            .and_then(|rest| rest.strip_suffix(".db"))
+        else {
+            continue;
+        };
+        if chrono::NaiveDateTime::parse_from_str(timestamp, TIMESTAMP_FORMAT).is_err() {
+            continue;
+        }
+        let size_bytes = entry.metadata().map(|m| m.len()).unwrap_or(0);
+        backups.push(LocalBackup { timestamp: timestamp.to_string(), size_bytes });
+    }
+    backups.sort_by(|a, b| b.timestamp.cmp(&a.timestamp));
+    Ok(backups)
+}
+
+/// Write a verified snapshot of `conn` into `dir`, then prune down to the
+/// configured count. Returns `None` when snapshots are disabled (count `0`).
+pub fn create_backup(conn: &Connection, dir: &Path, user_id: &str) -> Result<Option<LocalBackup>> {
+    let keep = get_backup_count(conn)?;
+    if keep == 0 {
+        return Ok(None);
+    }
+    std::fs::create_dir_all(dir)
+        .map_err(|e| Error::Other(anyhow::anyhow!("create backups dir: {e}")))?;
+
+    let timestamp = chrono::Utc::now().format(TIMESTAMP_FORMAT).to_string();
+    let path = snapshot_path(dir, user_id, &timestamp);
+    if path.exists() {
+        // Two snapshots inside the same second — the existing one is current.
+        prune_backups(dir, user_id, keep)?;
+        return Ok(list_backups(dir, user_id)?.into_iter().find(|b| b.timestamp == timestamp));
+    }
+
+    // ATTACH without a KEY clause reuses the main database's key, so the
+    // exported copy is encrypted exactly like the live file.
+    conn.execute(
+        "ATTACH DATABASE ?1 AS snapshot",
+        rusqlite::params![path.to_string_lossy()],
+    )?;
+    let exported = conn
+        .query_row("SELECT sqlcipher_export('snapshot')", [], |_| Ok(()))
+        .map_err(Error::from)
+        .and_then(|()| check_integrity(conn, "snapshot"));
+    conn.execute_batch("DETACH DATABASE snapshot")?;
+    if let Err(e) = exported {
+        let _ = std::fs::remove_file(&path);
+        return Err(e);
+    }
+
+    prune_backups(dir, user_id, keep)?;
+    let size_bytes = std::fs::metadata(&path).map(|m| m.len()).unwrap_or(0);
+    Ok(Some(LocalBackup { timestamp, size_bytes }))
+}
+
+/// Take a snapshot unless the newest one is younger than
+/// [`SNAPSHOT_MIN_INTERVAL_HOURS`]. The unlock-time entry point.
+pub fn create_backup_if_due(conn: &Connection, dir: &Path, user_id: &str) -> Result<Option<LocalBackup>> {
+    if let Some(newest) = list_backups(dir, user_id)?.into_iter().next() {
+        if let Ok(taken) = chrono::NaiveDateTime::parse_from_str(&newest.timestamp, TIMESTAMP_FORMAT) {
+            let age = chrono::Utc::now().naive_utc() - taken;
+            if age < chrono::Duration::hours(SNAPSHOT_MIN_INTERVAL_HOURS) {
+                return Ok(None);
+            }
+        }
+    }
+    create_backup(conn, dir, user_id)
+}
+
+/// Delete all but the newest `keep` snapshots for this user.
+pub fn prune_backups(dir: &Path, user_id: &str, keep: i64) -> Result<()> {
+    let keep = usize::try_from(keep.max(0)).unwrap_or(0);
+    for stale in list_backups(dir, user_id)?.into_iter().skip(keep) {
+        let path = snapshot_path(dir, user_id, &stale.timestamp);
+        if let Err(e) = std::fs::remove_file(&path) {
+            eprintln!("[backup] prune {}: {e}", path.display());
+        }
+    }
+    Ok(())
+}
+
+/// Write the snapshot taken at `timestamp` back into `conn`. Key-material
+/// tables are skipped (see module docs). Returns the number of rows written.
+/// Refuses snapshots that fail an integrity check or were written under a
+/// different local schema version.
+pub fn restore_backup(conn: &Connection, dir: &Path, user_id: &str, timestamp: &str) -> Result<usize> {
+    if chrono::NaiveDateTime::parse_from_str(timestamp, TIMESTAMP_FORMAT).is_err() {
+        return Err(Error::Other(anyhow::anyhow!("invalid backup timestamp: {timestamp}")));
+    }
+    let path = snapshot_path(dir, user_id, timestamp);
+    if !path.exists() {
+        return Err(Error::Other(anyhow::anyhow!("no backup at {timestamp}")));
+    }
+
+    conn.execute(
+        "ATTACH DATABASE ?1 AS snapshot",
+        rusqlite::params![path.to_string_lossy()],
+    )?;
+    let restored = restore_attached(conn);
+    conn.execute_batch("DETACH DATABASE snapshot")?;
+    restored
+}
+
+fn restore_attached(conn: &Connection) -> Result<usize> {
+    check_integrity(conn, "snapshot")?;
+
+    let version_of = |schema: &str| -> Result<Option<String>> {
+        Ok(conn
+            .query_row(
+                &format!("SELECT value FROM {schema}.kv WHERE key = 'schema_version'"),
+                [],
+                |row| row.get(0),
+            )
+            .optional()?)
+    };
+    if version_of("snapshot")? != version_of("main")? {
+        return Err(Error::Other(anyhow::anyhow!(
+            "backup was written by a different local schema version"
+        )));
+    }
+
+    let tables: Vec<String> = {
+        let mut stmt = conn.prepare(
+            "SELECT s.name FROM snapshot.sqlite_master s
+             JOIN main.sqlite_master m ON m.name = s.name AND m.type = 'table'
+             WHERE s.type = 'table' AND s.name NOT LIKE 'sqlite_%'
+             ORDER BY s.name",
+        )?;
+        let rows = stmt.query_map([], |row| row.get::<_, String>(0))?;
+        rows.collect::<rusqlite::Result<_>>()?
+    };
+
+    let tx = conn.unchecked_transaction()?;
+    let mut written = 0;
+    for table in tables.iter().filter(|t| !RESTORE_SKIP_TABLES.contains(&t.as_str())) {
+        // A keyless table (e.g. the single-row `preferences`) can't be merged
+        // row-by-row without duplicating, so the snapshot's copy replaces it.
+        let key_columns: i64 = tx.query_row(
+            "SELECT COUNT(*) FROM pragma_table_info(?1, 'snapshot') WHERE pk > 0",
+            rusqlite::params![table],
+            |row| row.get(0),
+        )?;
+        if key_columns == 0 {
+            tx.execute(&format!("DELETE FROM main.\"{table}\""), [])?;
+        }
+        let conflict = if RESTORE_FILL_ONLY_TABLES.contains(&table.as_str()) {
+            "IGNORE"
+        } else {
+            "REPLACE"
+        };
+        // Deleted history stays deleted: no snapshot message from a forgotten
+        // conversation at or before the moment it was forgotten.
+        let filter = if table == "message" {
+            " AS s WHERE NOT EXISTS (SELECT 1 FROM main.forgotten_conversation f
+                 WHERE f.conversation_id = s.conversation_id
+                   AND julianday(s.sent_at) <= julianday(f.forgotten_at))"
+        } else {
+            ""
+        };
+        written += tx.execute(
+            &format!(
+                "INSERT OR {conflict} INTO main.\"{table}\" SELECT * FROM snapshot.\"{table}\"{filter}"
+            ),
+            [],
+        )?;
+    }
+    // The snapshot's translations may belong to messages that were redacted
+    // or evicted since; only ones whose source text is still here are kept.
+    tx.execute(
+        "DELETE FROM main.message_translation WHERE message_id NOT IN
+             (SELECT id FROM main.message WHERE content IS NOT NULL)",
+        [],
+    )?;
+    super::local::rebuild_message_activity(&tx)?;
+    tx.commit()?;
+    Ok(written)
+}
+
+fn check_integrity(conn: &Connection, schema: &str) -> Result<()> {
+    let verdict: String =
+        conn.query_row(&format!("PRAGMA {schema}.quick_check"), [], |row| row.get(0))?;
+    if verdict != "ok" {
+        return Err(Error::Other(anyhow::anyhow!("backup integrity check failed: {verdict}")));
+    }
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::db::local::LocalDb;
+
+    fn temp_dir() -> PathBuf {
+        let dir = std::env::temp_dir().join(format!("pollis-backup-test-{}", ulid::Ulid::new()));
+        std::fs::create_dir_all(&dir).unwrap();
+        dir
+    }
+
+    fn db() -> LocalDb {
+        let db = LocalDb::open_in_memory().expect("in-memory db");
+        db.conn()
+            .execute("INSERT INTO kv (key, value) VALUES ('schema_version', 'test')", [])
+            .unwrap();
+        db
+    }
+
+    fn insert_message(conn: &Connection, id: &str) {
+        conn.execute(
+            "INSERT INTO message (id, conversation_id, sender_id, ciphertext, content, sent_at)
+             VALUES (?1, 'c1', 'u1', X'00', 'hi', '2026-01-01T00:00:00Z')",
+            rusqlite::params![id],
+        )
+        .unwrap();
+    }
+
+    fn message_count(conn: &Connection) -> i64 {
+        conn.query_row("SELECT COUNT(*) FROM message", [], |r| r.get(0)).unwrap()
+    }
+
+    #[test]
+    fn backup_count_defaults_and_validates() {
+        let db = db();
+        assert_eq!(get_backup_count(db.conn()).unwrap(), DEFAULT_BACKUP_COUNT);
+        set_backup_count(db.conn(), 5).unwrap();
+        assert_eq!(get_backup_count(db.conn()).unwrap(), 5);
+        assert!(set_backup_count(db.conn(), MAX_BACKUP_COUNT + 1).is_err());
+        assert!(set_backup_count(db.conn(), -1).is_err());
+    }
+
+    #[test]
+    fn disabled_count_skips_snapshot() {
+        let db = db();
+        let dir = temp_dir();
+        set_backup_count(db.conn(), 0).unwrap();
+        assert!(create_backup(db.conn(), &dir, "u1").unwrap().is_none());
+        assert!(list_backups(&dir, "u1").unwrap().is_empty());
+        std::fs::remove_dir_all(dir).ok();
+    }
+
+    #[test]
+    fn restore_brings_back_deleted_rows_and_keeps_newer_ones() {
+        let db = db();
+        let dir = temp_dir();
+        insert_message(db.conn(), "m1");
+
+        let backup = create_backup(db.conn(), &dir, "u1").unwrap().expect("snapshot taken");
+        assert_eq!(list_backups(&dir, "u1").unwrap().len(), 1);
+        // Snapshots are scoped per user.
+        assert!(list_backups(&dir, "u2").unwrap().is_empty());
+
+        db.conn().execute("DELETE FROM message", []).unwrap();
+        insert_message(db.conn(), "m2");
+
+        restore_backup(db.conn(), &dir, "u1", &backup.timestamp).unwrap();
+        assert_eq!(message_count(db.conn()), 2);
+        std::fs::remove_dir_all(dir).ok();
+    }
+
+    #[test]
+    fn restore_keeps_redactions_made_after_the_snapshot() {
+        let db = db();
+        let dir = temp_dir();
+        insert_message(db.conn(), "m1");
+        db.conn()
+            .execute(
+                "INSERT INTO message_translation (message_id, target_lang, content) VALUES ('m1', 'de', 'hallo')",
+                [],
+            )
+            .unwrap();
+        let backup = create_backup(db.conn(), &dir, "u1").unwrap().expect("snapshot taken");
+
+        // Same statement as a redaction (edit_delete.rs / ingest.rs).
+        db.conn()
+            .execute(
+                "UPDATE message SET content = NULL, deleted_at = '2026-01-02T00:00:00Z' WHERE id = 'm1'",
+                [],
+            )
+            .unwrap();
+        restore_backup(db.conn(), &dir, "u1", &backup.timestamp).unwrap();
+
+        let content: Option<String> = db
+            .conn()
+            .query_row("SELECT content FROM message WHERE id = 'm1'", [], |r| r.get(0))
+            .unwrap();
+        assert!(content.is_none());
+        let translations: i64 = db
+            .conn()
+            .query_row("SELECT COUNT(*) FROM message_translation", [], |r| r.get(0))
+            .unwrap();
+        assert_eq!(translations, 0);
+        std::fs::remove_dir_all(dir).ok();
+    }
+
+    #[test]
+    fn restore_leaves_activity_counts_matching_messages() {
+        let db = db();
+        let dir = temp_dir();
+        insert_message(db.conn(), "m1");
+        insert_message(db.conn(), "m2");
+        let backup = create_backup(db.conn(), &dir, "u1").unwrap().expect("snapshot taken");
+
+        db.conn().execute("DELETE FROM message WHERE id = 'm2'", []).unwrap();
+        insert_message(db.conn(), "m3");
+        restore_backup(db.conn(), &dir, "u1", &backup.timestamp).unwrap();
+
+        let counted: i64 = db
+            .conn()
+            .query_row(
+                "SELECT count FROM message_activity
+                 WHERE conversation_id = 'c1' AND hour = '2026-01-01T00:00:00Z'",
+                [],
+                |r| r.get(0),
+            )
+            .unwrap();
+        assert_eq!(message_count(db.conn()), 3);
+        assert_eq!(counted, 3);
+        std::fs::remove_dir_all(dir).ok();
+    }
+
+    #[test]
+    fn restore_keeps_deleted_history_deleted() {
+        let db = db();
+        let dir = temp_dir();
+        insert_message(db.conn(), "m1");
+        insert_message(db.conn(), "m2");
+        let backup = create_backup(db.conn(), &dir, "u1").unwrap().expect("snapshot taken");
+
+        // What leave_group does with "delete history".
+        crate::db::local::delete_conversation_messages(db.conn(), &["c1".to_string()]).unwrap();
+        restore_backup(db.conn(), &dir, "u1", &backup.timestamp).unwrap();
+        assert_eq!(message_count(db.conn()), 0);
+
+        // A message that arrives after the forget (e.g. on rejoining) is kept.
+        db.conn()
+            .execute(
+                "INSERT INTO message (id, conversation_id, sender_id, ciphertext, content, sent_at)
+                 VALUES ('m3', 'c1', 'u1', X'00', 'back', '2999-01-01T00:00:00Z')",
+                [],
+            )
+            .unwrap();
+        restore_backup(db.conn(), &dir, "u1", &backup.timestamp).unwrap();
+        assert_eq!(message_count(db.conn()), 1);
+        std::fs::remove_dir_all(dir).ok();
+    }
+
+    #[test]
+    fn restore_never_touches_mls_state() {
+        let db = db();
+        let dir = temp_dir();
+        db.conn()
+            .execute("INSERT INTO mls_kv (scope, key, value) VALUES ('group', X'01', X'AA')", [])
+            .unwrap();
+        let backup = create_backup(db.conn(), &dir, "u1").unwrap().expect("snapshot taken");
+
+        db.conn()
+            .execute("UPDATE mls_kv SET value = X'BB' WHERE key = X'01'", [])
+            .unwrap();
+        restore_backup(db.conn(), &dir, "u1", &backup.timestamp).unwrap();
+
+        let value: Vec<u8> = db
+            .conn()
+            .query_row("SELECT value FROM mls_kv WHERE key = X'01'", [], |r| r.get(0))
+            .unwrap();
+        assert_eq!(value, vec![0xBB]);
+        std::fs::remove_dir_all(dir).ok();
+    }
+
+    #[test]
+    fn restore_never_rolls_back_contact_trust() {
+        let db = db();
+        let dir = temp_dir();
+        db.conn()
+            .execute(
+                "INSERT INTO contact_verification (peer_user_id, account_id_pub, identity_version, verified)
+                 VALUES ('peer', X'01', 1, 1)",
+                [],
+            )
+            .unwrap();
+        let backup = create_backup(db.conn(), &dir, "u1").unwrap().expect("snapshot taken");
+
+        // The peer rotated their key: new pin, verification cleared.
+        db.conn()
+            .execute(
+                "UPDATE contact_verification
+                 SET account_id_pub = X'02', identity_version = 2, verified = 0
+                 WHERE peer_user_id = 'peer'",
+                [],
+            )
+            .unwrap();
+        restore_backup(db.conn(), &dir, "u1", &backup.timestamp).unwrap();
+
+        let (pin, version, verified): (Vec<u8>, i64, i64) = db
+            .conn()
+            .query_row(
+                "SELECT account_id_pub, identity_version, verified FROM contact_verification
+                 WHERE peer_user_id = 'peer'",
+                [],
+                |r| Ok((r.get(0)?, r.get(1)?, r.get(2)?)),
+            )
+            .unwrap();
+        assert_eq!((pin, version, verified), (vec![0x02], 2, 0));
+        std::fs::remove_dir_all(dir).ok();
+    }
+
+    #[test]
+    fn prune_keeps_newest() {
+        let dir = temp_dir();
+        for ts in ["20260101T000000Z", "20260102T000000Z", "20260103T000000Z"] {
+            std::fs::write(snapshot_path(&dir, "u1", ts), b"x").unwrap();
+        }
+        prune_backups(&dir, "u1", 2).unwrap();
+        let left: Vec<String> = list_backups(&dir, "u1")
+            .unwrap()
+            .into_iter()
+            .map(|b| b.timestamp)
+            .collect();
+        assert_eq!(left, vec!["20260103T000000Z", "20260102T000000Z"]);
+        std::fs::remove_dir_all(dir).ok();
+    }
+
+    #[test]
+    fn restore_rejects_malformed_timestamp() {
+        let db = db();
+        let dir = temp_dir();
+        assert!(restore_backup(db.conn(), &dir, "u1", "../../etc/passwd").is_err());
+        std::fs::remove_dir_all(dir).ok();
+    }
+}
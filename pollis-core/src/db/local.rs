@@ -180,6 +180,21 @@ pub fn set_message_retention_days(conn: &Connection, days: i64) -> Result<()> {
     Ok(())
 }
 
+/// Recount `message_activity` from `message`. The triggers keep it current
+/// row by row; this is for bulk writes that bypass them, like a snapshot
+/// restore.
+pub fn rebuild_message_activity(conn: &Connection) -> Result<()> {
+    conn.execute_batch(
+        "DELETE FROM message_activity;
+         INSERT INTO message_activity (conversation_id, hour, count)
+         SELECT conversation_id, strftime('%Y-%m-%dT%H:00:00Z', sent_at) AS h, COUNT(*)
+         FROM message
+         WHERE h IS NOT NULL
+         GROUP BY conversation_id, h;",
+    )?;
+    Ok(())
+}
+
 /// Delete local messages older than the configured retention window, then
 /// reclaim the freed pages. Returns the number of rows deleted. A retention of
 /// `0` (Forever) is a no-op. Only the `message` table is touched — `mls_kv`
@@ -200,6 +215,50 @@ pub fn evict_old_messages(conn: &Connection) -> Result<usize> {
     Ok(deleted)
 }
 
+/// Delete one conversation's local messages sent before `cutoff` (RFC 3339,
+/// the format `sent_at` is written in), then reclaim the freed pages. Used for
+/// admin-set channel retention, which — unlike the device window above — is
+/// about when the message was sent, not when it landed here.
+pub fn evict_conversation_messages_before(
+    conn: &Connection,
+    conversation_id: &str,
+    cutoff: &str,
+) -> Result<usize> {
+    let deleted = conn.execute(
+        "DELETE FROM message WHERE conversation_id = ?1 AND sent_at < ?2",
+        rusqlite::params![conversation_id, cutoff],
+    )?;
+    if deleted > 0 {
+        reclaim(conn)?;
+    }
+    Ok(deleted)
+}
+
+/// Delete every local message in the given conversations, then reclaim the
+/// freed pages. Used when leaving a group with "delete history": the group's
+/// channels are unreachable afterwards, so their copies would only sit here.
+/// Each conversation is recorded in `forgotten_conversation` so restoring an
+/// older snapshot doesn't bring the deleted messages back.
+pub fn delete_conversation_messages(conn: &Connection, conversation_ids: &[String]) -> Result<usize> {
+    let forgotten_at = chrono::Utc::now().to_rfc3339_opts(chrono::SecondsFormat::Secs, true);
+    let mut deleted = 0;
+    for id in conversation_ids {
+        conn.execute(
+            "INSERT INTO forgotten_conversation (conversation_id, forgotten_at) VALUES (?1, ?2)
+             ON CONFLICT(conversation_id) DO UPDATE SET forgotten_at = ?2",
+            rusqlite::params![id, forgotten_at],
+        )?;
+        deleted += conn.execute(
+            "DELETE FROM message WHERE conversation_id = ?1",
+            rusqlite::params![id],
+        )?;
+    }
+    if deleted > 0 {
+        reclaim(conn)?;
+    }
+    Ok(deleted)
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -366,6 +425,48 @@ mod tests {
         assert_eq!(message_ids(conn), vec!["recent".to_string()]);
     }
 
+    #[test]
+    fn conversation_eviction_is_keyed_on_sent_at_and_scoped() {
+        let db = db();
+        let conn = db.conn();
+        for (id, conv, sent_at) in [
+            ("old", "c1", "2024-01-01T00:00:00+00:00"),
+            ("new", "c1", "2024-03-01T00:00:00+00:00"),
+            ("other", "c2", "2024-01-01T00:00:00+00:00"),
+        ] {
+            conn.execute(
+                "INSERT INTO message (id, conversation_id, sender_id, ciphertext, sent_at)
+                 VALUES (?1, ?2, 'sender1', X'00', ?3)",
+                rusqlite::params![id, conv, sent_at],
+            )
+            .unwrap();
+        }
+
+        let deleted =
+            evict_conversation_messages_before(conn, "c1", "2024-02-01T00:00:00+00:00").unwrap();
+        assert_eq!(deleted, 1);
+        assert_eq!(message_ids(conn), vec!["new".to_string(), "other".to_string()]);
+    }
+
+    #[test]
+    fn conversation_delete_is_scoped() {
+        let db = db();
+        let conn = db.conn();
+        for (id, conv) in [("a", "c1"), ("b", "c2"), ("c", "c3")] {
+            conn.execute(
+                "INSERT INTO message (id, conversation_id, sender_id, ciphertext, sent_at)
+                 VALUES (?1, ?2, 'sender1', X'00', '2024-01-01T00:00:00+00:00')",
+                rusqlite::params![id, conv],
+            )
+            .unwrap();
+        }
+
+        let deleted =
+            delete_conversation_messages(conn, &["c1".to_string(), "c2".to_string()]).unwrap();
+        assert_eq!(deleted, 2);
+        assert_eq!(message_ids(conn), vec!["c".to_string()]);
+    }
+
     #[test]
     fn set_retention_rejects_invalid_values() {
         let db = db();
@@ -397,6 +498,33 @@ mod tests {
         assert_eq!(after, 2, "converted to INCREMENTAL (2)");
     }
 
+    #[test]
+    fn message_pages_read_off_the_index() {
+        let db = db();
+        let conn = db.conn();
+        // Same statements as `messages::read` (first page, then a cursor page).
+        for sql in [
+            "SELECT id FROM message WHERE conversation_id = ?1
+             ORDER BY sent_at DESC, id DESC LIMIT ?2",
+            "SELECT id FROM message WHERE conversation_id = ?1
+               AND (sent_at < ?2 OR (sent_at = ?2 AND id < ?3))
+             ORDER BY sent_at DESC, id DESC LIMIT ?4",
+        ] {
+            let mut stmt = conn.prepare(&format!("EXPLAIN QUERY PLAN {sql}")).unwrap();
+            let params = vec![rusqlite::types::Null; stmt.parameter_count()];
+            let plan: Vec<String> = stmt
+                .query_map(rusqlite::params_from_iter(params), |row| row.get(3))
+                .unwrap()
+                .collect::<std::result::Result<_, _>>()
+                .unwrap();
+            assert!(
+                plan.iter().any(|step| step.contains("idx_message_conversation_page")),
+                "{plan:?}"
+            );
+            assert!(plan.iter().all(|step| !step.contains("TEMP B-TREE")), "{plan:?}");
+        }
+    }
+
     #[test]
     fn reclaim_runs_after_delete() {
         let db = db();
@@ -405,6 +533,37 @@ mod tests {
         conn.execute("DELETE FROM message", []).unwrap();
         reclaim(conn).expect("reclaim should succeed after a delete");
     }
+
+    #[test]
+    fn activity_backfill_runs_once() {
+        // A cache from before `message_activity`: messages, no rollup, no flag.
+        let conn = Connection::open_in_memory().unwrap();
+        conn.execute_batch(SCHEMA).unwrap();
+        conn.execute(
+            "INSERT INTO message (id, conversation_id, sender_id, ciphertext, sent_at)
+             VALUES ('m1', 'c1', 'u1', X'00', '2024-01-01T10:20:00Z'),
+                    ('m2', 'c1', 'u1', X'00', '2024-01-01T10:40:00Z')",
+            [],
+        ).unwrap();
+        conn.execute_batch(
+            "DELETE FROM message_activity;
+             DELETE FROM kv WHERE key = 'message_activity_backfilled';",
+        ).unwrap();
+
+        let count = |conn: &Connection| -> i64 {
+            conn.query_row(
+                "SELECT count FROM message_activity
+                 WHERE conversation_id = 'c1' AND hour = '2024-01-01T10:00:00Z'",
+                [],
+                |row| row.get(0),
+            ).unwrap()
+        };
+        conn.execute_batch(SCHEMA).unwrap();
+        assert_eq!(count(&conn), 2);
+        // Re-opening doesn't count the same messages again.
+        conn.execute_batch(SCHEMA).unwrap();
+        assert_eq!(count(&conn), 2);
+    }
 }
 
 pub fn dirs_path() -> std::path::PathBuf {
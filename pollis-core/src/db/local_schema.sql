@@ -28,7 +28,15 @@ CREATE TABLE IF NOT EXISTS message (
     deleted_at TEXT
 );
 
-CREATE INDEX IF NOT EXISTS idx_message_conversation ON message(conversation_id, sent_at);
+-- Message pages order by `(sent_at, id)`; with `id` in the index the page is
+-- read straight off it instead of sorting ties in a temp B-tree. Supersedes
+-- `idx_message_conversation (conversation_id, sent_at)`, its prefix.
+CREATE INDEX IF NOT EXISTS idx_message_conversation_page ON message(conversation_id, sent_at, id);
+DROP INDEX IF EXISTS idx_message_conversation;
+
+-- Deleting an attachment message checks the sender's other messages for
+-- references to the same blob (`filter_orphaned_locally`).
+CREATE INDEX IF NOT EXISTS idx_message_sender ON message(sender_id);
 
 -- Eviction scan index: lookback/retention sweeps delete by received_at. Run on
 -- every open (this schema is re-applied each open) so existing DBs gain it
@@ -90,3 +98,129 @@ CREATE TABLE IF NOT EXISTS contact_verification (
     updated_at       TEXT NOT NULL DEFAULT (datetime('now'))
 );
 
+
+-- Device-local translations of decrypted messages (see
+-- commands/messages/translate.rs). Keyed by (message, target language). The
+-- triggers drop a message's translations when its content changes (edit or
+-- redaction) or the row is deleted (eviction), so a cached translation never
+-- outlives the text it was made from. Additive: re-applied on every open.
+CREATE TABLE IF NOT EXISTS message_translation (
+    message_id  TEXT NOT NULL,
+    target_lang TEXT NOT NULL,
+    content     TEXT NOT NULL,
+    created_at  TEXT NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (message_id, target_lang)
+);
+
+CREATE TRIGGER IF NOT EXISTS message_translation_on_update
+AFTER UPDATE OF content ON message
+BEGIN
+    DELETE FROM message_translation WHERE message_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS message_translation_on_delete
+AFTER DELETE ON message
+BEGIN
+    DELETE FROM message_translation WHERE message_id = OLD.id;
+END;
+
+-- Local-only contact nicknames and notes (commands/contacts.rs). Never synced;
+-- a row exists only while at least one field is set.
+CREATE TABLE IF NOT EXISTS contact_note (
+    user_id    TEXT PRIMARY KEY,
+    nickname   TEXT,
+    notes      TEXT,
+    updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Avatars and group icons fetched by `download_file` (commands/r2.rs), keyed by
+-- R2 object key, so they render offline and aren't re-downloaded on every
+-- launch. Revalidated by ETag once older than a few minutes; rows untouched
+-- for 30 days are pruned on write.
+CREATE TABLE IF NOT EXISTS avatar_cache (
+    key        TEXT PRIMARY KEY,
+    etag       TEXT,
+    data       BLOB NOT NULL,
+    fetched_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Group timeline notices — member joined / left, channel created
+-- (commands/groups/events.rs). Derived on this device from the remote roster
+-- and channel list; never sent, never synced. `occurred_at` is RFC 3339 so it
+-- sorts with `message.sent_at`.
+CREATE TABLE IF NOT EXISTS group_event (
+    id           TEXT PRIMARY KEY,
+    group_id     TEXT NOT NULL,
+    kind         TEXT NOT NULL,
+    subject_id   TEXT NOT NULL,
+    subject_name TEXT,
+    occurred_at  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_group_event_group ON group_event(group_id, occurred_at);
+
+-- Which group each channel belongs to, recorded by ingest
+-- (commands/messages/ingest.rs) so local reads can flag senders muted in that
+-- group (`muted_members` in the preferences) without a remote lookup. DMs have
+-- no row. Additive: re-applied on every open.
+CREATE TABLE IF NOT EXISTS conversation_group (
+    conversation_id TEXT PRIMARY KEY,
+    group_id        TEXT NOT NULL
+);
+
+-- Conversations whose history this device deleted ("delete history" on leave,
+-- forget_group_history), with when. A snapshot restore skips this table and
+-- never brings back a message in one of them sent at or before `forgotten_at`
+-- (db/backup.rs), so deleted plaintext stays deleted even though older
+-- snapshots still hold it. Additive: re-applied on every open.
+CREATE TABLE IF NOT EXISTS forgotten_conversation (
+    conversation_id TEXT PRIMARY KEY,
+    forgotten_at    TEXT NOT NULL
+);
+
+-- Messages per conversation per UTC hour (commands/messages/activity.rs), for
+-- activity sparklines and "most active channels" without scanning history.
+-- `hour` is the RFC 3339 start of the hour `sent_at` falls in. The triggers
+-- keep it in step with `message` as rows are ingested, sent and evicted; a
+-- snapshot restore skips the table and recounts it afterwards
+-- (db::local::rebuild_message_activity). Redaction only sets `deleted_at`, so
+-- a redacted message still counts. Rows with an unparseable `sent_at` are left out rather than failing
+-- the insert. Additive: re-applied on every open.
+CREATE TABLE IF NOT EXISTS message_activity (
+    conversation_id TEXT NOT NULL,
+    hour            TEXT NOT NULL,
+    count           INTEGER NOT NULL,
+    PRIMARY KEY (conversation_id, hour)
+);
+CREATE INDEX IF NOT EXISTS idx_message_activity_hour ON message_activity(hour);
+
+CREATE TRIGGER IF NOT EXISTS message_activity_on_insert
+AFTER INSERT ON message
+WHEN strftime('%Y-%m-%dT%H:00:00Z', NEW.sent_at) IS NOT NULL
+BEGIN
+    INSERT INTO message_activity (conversation_id, hour, count)
+    VALUES (NEW.conversation_id, strftime('%Y-%m-%dT%H:00:00Z', NEW.sent_at), 1)
+    ON CONFLICT (conversation_id, hour) DO UPDATE SET count = count + 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS message_activity_on_delete
+AFTER DELETE ON message
+WHEN strftime('%Y-%m-%dT%H:00:00Z', OLD.sent_at) IS NOT NULL
+BEGIN
+    UPDATE message_activity SET count = count - 1
+    WHERE conversation_id = OLD.conversation_id
+      AND hour = strftime('%Y-%m-%dT%H:00:00Z', OLD.sent_at);
+    DELETE FROM message_activity
+    WHERE conversation_id = OLD.conversation_id
+      AND hour = strftime('%Y-%m-%dT%H:00:00Z', OLD.sent_at)
+      AND count <= 0;
+END;
+
+-- One-time fill from the messages a database already held before the table
+-- existed; the `kv` flag keeps later opens from counting them twice.
+INSERT INTO message_activity (conversation_id, hour, count)
+SELECT conversation_id, strftime('%Y-%m-%dT%H:00:00Z', sent_at) AS h, COUNT(*)
+FROM message
+WHERE h IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM kv WHERE key = 'message_activity_backfilled')
+GROUP BY conversation_id, h;
+INSERT OR IGNORE INTO kv (key, value) VALUES ('message_activity_backfilled', '1');
@@ -0,0 +1,19 @@
+-- Two-step group ownership transfer. The current owner proposes a new owner
+-- (who must already be a member); nothing changes hands until the target
+-- accepts through the DS, which then moves `groups.owner_id` and promotes them
+-- to admin in one transaction. At most one pending proposal per group — a new
+-- proposal replaces the old row.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): a new table only.
+-- A previously-shipped app never reads it.
+
+CREATE TABLE IF NOT EXISTS group_ownership_transfer (
+    group_id     TEXT PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    from_user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id   TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at   TEXT NOT NULL DEFAULT (datetime('now')),
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_ownership_transfer_to
+    ON group_ownership_transfer (to_user_id);
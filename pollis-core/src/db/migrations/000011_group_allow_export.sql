@@ -0,0 +1,10 @@
+-- Per-group policy for admin message export (`export_channel_messages`).
+-- Defaults to allowed; an admin can switch it off for sensitive groups via
+-- `POST /v1/groups/update` with `allow_export: false`. The check runs on the
+-- exporting client against this column — the DS never sees message plaintext,
+-- so it can't enforce the export itself, only who may flip the flag.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): one NOT NULL column
+-- with a default. A previously-shipped app never reads it.
+
+ALTER TABLE groups ADD COLUMN allow_export INTEGER NOT NULL DEFAULT 1;
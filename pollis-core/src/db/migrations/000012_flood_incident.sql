@@ -0,0 +1,22 @@
+-- Flood-detection incidents recorded by the DS (`pollis-delivery/src/flood.rs`).
+--
+-- One row per trip of a send heuristic (too many sends to one conversation, or
+-- a burst of one identical ciphertext), written when the sender is muted — not
+-- for each send refused during the mute. `user_id` is the authenticated sender,
+-- which the DS already knows for every signed write; no ciphertext or digest is
+-- kept. The DS prunes rows older than 30 days on each insert. Operators read
+-- them with `scripts/db-usage.sh`.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): a new table that
+-- no client reads.
+CREATE TABLE IF NOT EXISTS flood_incident (
+    id              TEXT PRIMARY KEY,
+    user_id         TEXT NOT NULL,
+    conversation_id TEXT NOT NULL,
+    reason          TEXT NOT NULL,
+    muted_until     TEXT NOT NULL,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Pruning and the "recent incidents" listing both scan by time.
+CREATE INDEX IF NOT EXISTS idx_flood_incident_created ON flood_incident(created_at);
@@ -0,0 +1,25 @@
+-- Outbound group webhooks (`pollis-delivery/src/webhooks.rs`).
+--
+-- One row per admin-registered target. `events` is a comma-separated subset of
+-- `message_count,member_joined,channel_created`. There is deliberately no
+-- secret column: clients read this DB with a read-only token, so the DS
+-- derives each webhook's signing secret from a server-only key and the row id
+-- instead of storing it. `last_status` (HTTP status, NULL for a network error)
+-- / `last_delivery_at` / `failure_count` (consecutive failed deliveries) are
+-- written by the DS after each delivery for the admin UI.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): a new table that
+-- older clients never read. Rows go with their group (ON DELETE CASCADE).
+CREATE TABLE IF NOT EXISTS group_webhook (
+    id               TEXT PRIMARY KEY,
+    group_id         TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    url              TEXT NOT NULL,
+    events           TEXT NOT NULL,
+    created_by       TEXT NOT NULL,
+    created_at       TEXT NOT NULL DEFAULT (datetime('now')),
+    last_delivery_at TEXT,
+    last_status      INTEGER,
+    failure_count    INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_webhook_group ON group_webhook(group_id);
@@ -0,0 +1,20 @@
+-- Per-user inactive-account policy (`pollis-delivery/src/inactivity.rs`).
+--
+-- Opt-in. `inactive_days` is how long the account may go without a check-in
+-- (sent once per unlock) before the DS emails a warning; `notified_at` records
+-- that warning and is cleared by the next check-in. With `delete_recovery` set,
+-- the account's Secret Key backup (`account_recovery`) is deleted once the
+-- warning has gone unanswered for the DS grace period, and
+-- `recovery_deleted_at` records when. Written only by the DS.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): a new table that
+-- older clients never read. Rows go with their user (ON DELETE CASCADE).
+CREATE TABLE IF NOT EXISTS inactivity_policy (
+    user_id             TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    inactive_days       INTEGER NOT NULL,
+    delete_recovery     INTEGER NOT NULL DEFAULT 0,
+    last_active_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    notified_at         TEXT,
+    recovery_deleted_at TEXT,
+    updated_at          TEXT NOT NULL DEFAULT (datetime('now'))
+);
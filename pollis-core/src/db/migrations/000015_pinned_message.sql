@@ -0,0 +1,18 @@
+-- Pinned messages per conversation (`pollis-delivery/src/messages.rs`,
+-- `/v1/pins/*`).
+--
+-- Ids only: the pinned message's content stays in each member's local
+-- `message` table, so a pin reveals which message was pinned and by whom —
+-- the same metadata a reaction row already carries — and nothing more. At
+-- most `LIMIT_MAX_PINS_PER_CONVERSATION` rows per conversation (DS-enforced).
+-- Only the pinner or a group admin may remove a pin. Written only by the DS.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): a new table that
+-- older clients never read. Rows go with their pinner (ON DELETE CASCADE).
+CREATE TABLE IF NOT EXISTS pinned_message (
+    conversation_id TEXT NOT NULL,
+    message_id      TEXT NOT NULL,
+    pinned_by       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    pinned_at       TEXT NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (conversation_id, message_id)
+);
@@ -0,0 +1,13 @@
+-- Per-channel retention window, in days. NULL (the default) keeps history
+-- for as long as the relay's normal envelope GC does. An admin sets it via
+-- `POST /v1/channels/update` with `retention_days` (0 clears it).
+--
+-- Enforced on both sides: the DS deletes the channel's envelopes older than
+-- the window during envelope GC, and every member's client deletes local
+-- messages older than it in its eviction sweep (`run_message_eviction`).
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): one nullable
+-- column. A previously-shipped app never reads it and simply keeps its local
+-- history.
+
+ALTER TABLE channels ADD COLUMN retention_days INTEGER;
@@ -0,0 +1,22 @@
+-- Per-user daily usage counters for operators billing an instance
+-- (`pollis-delivery/src/usage.rs`). Written only by the DS, and only when
+-- `USAGE_ACCOUNTING` is on: one row per user per UTC day, incremented on each
+-- message send (envelopes + ciphertext bytes) and attachment upload presign
+-- (count + bound size). Counters only — no conversation or recipient — so the
+-- billing export can't be joined back to who talked to whom. Users read their
+-- own rows via `POST /v1/usage/me`; operators export via `GET /v1/usage/export`.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): a new table that
+-- no client reads directly. Rows go with their user (ON DELETE CASCADE).
+CREATE TABLE IF NOT EXISTS usage_daily (
+    user_id               TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day                   TEXT NOT NULL,
+    envelopes             INTEGER NOT NULL DEFAULT 0,
+    envelope_bytes        INTEGER NOT NULL DEFAULT 0,
+    attachments_presigned INTEGER NOT NULL DEFAULT 0,
+    attachment_bytes      INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+-- The export scans a date range across every user.
+CREATE INDEX IF NOT EXISTS idx_usage_daily_day ON usage_daily(day);
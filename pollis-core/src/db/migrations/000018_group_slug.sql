@@ -0,0 +1,23 @@
+-- Server-side group slug reservations (`pollis-delivery/src/slugs.rs`). A slug
+-- is the canonical `/g/<slug>` form the DS derives with `normalize_slug`; the
+-- primary key makes it globally unique, so two clients racing to create
+-- "Book Club" can't both get `book-club`.
+--
+-- Lifecycle: `POST /v1/slugs/reserve` inserts a row with `group_id` NULL for
+-- the reserving user; `POST /v1/groups/create` with that `slug` claims it by
+-- setting `group_id` in the same transaction as the group insert. An unclaimed
+-- reservation older than ten minutes may be taken over by anyone. Deleting the
+-- group releases its slug (ON DELETE CASCADE).
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): a new table.
+-- Groups created before it have no row and keep their name-derived slug;
+-- reservation still refuses a slug one of them derives to.
+CREATE TABLE IF NOT EXISTS group_slug (
+    slug        TEXT PRIMARY KEY,
+    user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_id    TEXT REFERENCES groups(id) ON DELETE CASCADE,
+    reserved_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Slug lookup by group (search, and the legacy-name check skips claimed groups).
+CREATE INDEX IF NOT EXISTS idx_group_slug_group ON group_slug(group_id);
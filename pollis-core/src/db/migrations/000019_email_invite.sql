@@ -0,0 +1,32 @@
+-- Group invites addressed to an email that has no Pollis account yet
+-- (`pollis-delivery/src/email_invites.rs`). Written only by the DS.
+--
+-- `POST /v1/email-invites/create` (admin only) stores a row and emails the
+-- address a signed link. When that address signs up (verify-otp creates the
+-- account) every row for it becomes an ordinary pending `group_invite`; the
+-- signed link lets someone who signed up under a different address redeem one
+-- (`POST /v1/email-invites/redeem`). Rows expire after 30 days.
+--
+-- `email_invite_opt_out` holds the SHA-256 of an address that clicked the
+-- email's opt-out link, so the DS never emails it an invite again without
+-- keeping the address itself.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): two new tables no
+-- client reads directly.
+CREATE TABLE IF NOT EXISTS email_invite (
+    id         TEXT PRIMARY KEY,
+    group_id   TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    inviter_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email      TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (datetime('now')),
+    UNIQUE (group_id, email)
+);
+
+-- Conversion on signup looks rows up by address; the rate limit counts by inviter.
+CREATE INDEX IF NOT EXISTS idx_email_invite_email ON email_invite(email);
+CREATE INDEX IF NOT EXISTS idx_email_invite_inviter ON email_invite(inviter_id, created_at);
+
+CREATE TABLE IF NOT EXISTS email_invite_opt_out (
+    email_hash TEXT PRIMARY KEY,
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
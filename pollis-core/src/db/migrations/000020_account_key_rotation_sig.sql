@@ -0,0 +1,17 @@
+-- Old-key signature over a rotation (`rotate_identity`). A rotation made while
+-- the previous account key is still held is signed by that key over the
+-- canonical statement in `account_identity::rotation_signed_payload` (user id,
+-- new version, old and new public keys). Peers holding a pin for an earlier
+-- version walk the signed rows up to the current key and re-pin without
+-- clearing `verified`. NULL for version 1, for every row written before this
+-- migration, and for a soft reset (`reset_identity`), where the old key is lost
+-- — those still surface as a key change.
+--
+-- The DS verifies the signature before appending. `verifiable-log-builder`
+-- reads `account_key_log` by explicit column list, so published leaves are
+-- unchanged.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): one nullable
+-- column. A previously-shipped app never reads it.
+
+ALTER TABLE account_key_log ADD COLUMN rotation_sig BLOB;
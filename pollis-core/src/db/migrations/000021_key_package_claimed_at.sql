@@ -0,0 +1,13 @@
+-- When a key package was claimed. The DS stamps it in the same `UPDATE` that
+-- flips `claimed` (`devices::apply_claim_key_package`). The owning device
+-- counts its claims over the last week to size its pool
+-- (`key_packages::pool_target`): a device that gets added to groups often keeps
+-- more packages on hand, and a quiet one stays at the floor.
+--
+-- NULL for every row claimed before this migration, which just reads as no
+-- recent demand.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): one nullable
+-- column. A previously-shipped app never reads it.
+
+ALTER TABLE mls_key_package ADD COLUMN claimed_at TEXT;
@@ -0,0 +1,25 @@
+-- Indexes for the hot queries EXPLAIN QUERY PLAN showed scanning or sorting.
+-- `remote.rs` tests re-run EXPLAIN over these statements so a later schema or
+-- query change that loses the index fails in CI instead of in production.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): indexes only. A
+-- previously-shipped app's queries keep working, and get faster.
+
+-- Per-device KeyPackage claim (`devices::apply_claim_key_package`), the
+-- owning device's pool count, and its recent-claims read
+-- (`key_packages::recent_claims`). `idx_mls_kp_user` is `(user_id, claimed)`:
+-- it can't use `device_id`, and the claim's `ORDER BY created_at` built a temp
+-- B-tree on every add-member. This serves the filter and the order.
+CREATE INDEX IF NOT EXISTS idx_mls_kp_device_claim
+    ON mls_key_package (user_id, device_id, claimed, created_at);
+
+-- Account deletion (`account::apply_delete_account`) deletes the user's
+-- envelopes by `sender_id`, which was a full scan of the largest table.
+CREATE INDEX IF NOT EXISTS idx_envelope_sender
+    ON message_envelope (sender_id);
+
+-- `get_pending_ownership_transfers` matches `to_user_id = ?1 OR from_user_id
+-- = ?1`. With only the `to_user_id` index SQLite can't split the OR and
+-- scanned the table; with both it unions two index lookups.
+CREATE INDEX IF NOT EXISTS idx_group_ownership_transfer_from
+    ON group_ownership_transfer (from_user_id);
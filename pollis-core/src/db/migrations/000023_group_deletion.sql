@@ -0,0 +1,22 @@
+-- Two-phase group deletion (`pollis-delivery/src/group_purge.rs`).
+--
+-- `POST /v1/groups/delete` no longer deletes: the owner's request lands here
+-- with `purge_after` set a grace period out (7 days), during which members see
+-- it in their group timeline and the owner may withdraw it with
+-- `POST /v1/groups/delete/cancel` (which deletes the row). Once `purge_after`
+-- passes, the DS purge sweep deletes the group, its channels and their message
+-- envelopes. At most one pending deletion per group. Written only by the DS.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): a new table only.
+-- A previously-shipped app never reads it; its "delete group" now schedules
+-- the deletion instead of performing it.
+
+CREATE TABLE IF NOT EXISTS group_deletion (
+    group_id     TEXT PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    requested_by TEXT NOT NULL,
+    requested_at TEXT NOT NULL DEFAULT (datetime('now')),
+    purge_after  TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_deletion_purge_after
+    ON group_deletion (purge_after);
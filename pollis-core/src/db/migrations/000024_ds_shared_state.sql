@@ -0,0 +1,30 @@
+-- Coordination state for running more than one DS instance against the same
+-- database (`pollis-delivery/src/ratelimit.rs`, `lease.rs`, `flood.rs`).
+--
+-- `ds_rate_window` holds the fixed-window rate-limit and flood counters when
+-- the DS runs with `DS_SHARED_STATE=db`; each row is one key's current window.
+-- `ds_lease` names which instance runs each periodic sweep (inactivity, group
+-- purge) so a scaled-out DS doesn't send the same warning email twice. The DS
+-- prunes lapsed windows itself. Neither table holds user content.
+--
+-- The flood mute check reads `flood_incident` by sender in shared mode, hence
+-- the index.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): new tables that
+-- no client reads, and a new index.
+CREATE TABLE IF NOT EXISTS ds_rate_window (
+    key          TEXT PRIMARY KEY,
+    window_start INTEGER NOT NULL,
+    count        INTEGER NOT NULL
+);
+
+-- Pruning scans by window start.
+CREATE INDEX IF NOT EXISTS idx_ds_rate_window_start ON ds_rate_window(window_start);
+
+CREATE TABLE IF NOT EXISTS ds_lease (
+    name       TEXT PRIMARY KEY,
+    holder     TEXT NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_flood_incident_user ON flood_incident(user_id, muted_until);
@@ -0,0 +1,13 @@
+-- Per-group attachment residency (`pollis-delivery/src/residency.rs`).
+-- `storage_target` names one of the S3-compatible stores the deployment
+-- declares (`STORAGE_TARGETS`); NULL keeps the group on the default bucket.
+-- Only the group owner can set it, via `POST /v1/groups/update` with
+-- `storage_target`. Clients read it to pick where a new attachment goes, and
+-- the DS refuses a presigned upload for the group that goes anywhere else.
+-- Attachments uploaded before a change stay where they are.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): one nullable
+-- column. A previously-shipped app never reads it and keeps uploading to the
+-- default bucket without naming the group, which the DS doesn't check.
+
+ALTER TABLE groups ADD COLUMN storage_target TEXT;
@@ -0,0 +1,30 @@
+-- Idempotency keys shared by every DS instance on one database
+-- (`pollis-delivery/src/idempotency.rs`), used when the DS runs with
+-- `DS_SHARED_STATE=db`. A client resends a write whose reply it lost with the
+-- same `Idempotency-Key`; with the keys in one instance's memory, a resend that
+-- lands on another instance (or after a restart) ran the write again — a second
+-- key-package claim, a group create that bounced off its primary key as a 500.
+--
+-- One row per `(user, device, key)`: the request fingerprint, and once the
+-- write finished its `2xx` reply (`status` NULL while it is still running).
+-- `until` is when the row may go — a short lease while in flight, the key TTL
+-- (a day by default) once done. The DS prunes lapsed rows itself. Replies are
+-- DS metadata (ids, timestamps, a claimed public key package), never message
+-- plaintext.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): a new table that
+-- no client reads.
+CREATE TABLE IF NOT EXISTS ds_idempotency (
+    user_id      TEXT NOT NULL,
+    device_id    TEXT NOT NULL,
+    key          TEXT NOT NULL,
+    fingerprint  BLOB NOT NULL,
+    status       INTEGER,
+    content_type TEXT,
+    body         BLOB,
+    until        INTEGER NOT NULL,
+    PRIMARY KEY (user_id, device_id, key)
+);
+
+-- Pruning scans by expiry.
+CREATE INDEX IF NOT EXISTS idx_ds_idempotency_until ON ds_idempotency(until);
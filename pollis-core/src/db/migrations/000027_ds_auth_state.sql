@@ -0,0 +1,48 @@
+-- Sign-in state shared by every DS instance on one database, used when the DS
+-- runs with `DS_SHARED_STATE=db` (`pollis-delivery/src/otp.rs`, `session.rs`,
+-- `email_change.rs`). Kept in one instance's memory, a sign-in code only
+-- verified on the instance that emailed it, and the bootstrap session it
+-- minted only opened the bootstrap writes on that instance — so a scaled-out
+-- DS needed proxy affinity on `/v1/auth/*`.
+--
+-- `ds_otp` holds one pending code per `(scope, email)`: `scope` is `signup` or
+-- `email_change`, the two stores the DS keeps apart so a change to an address
+-- can't collide with a signup for it. Only `SHA-256(salt || code)` is stored.
+-- `ds_session` holds the bootstrap sessions by `SHA-256(token)`; the raw token
+-- is never at rest. `ds_email_change` binds a pending email change to the
+-- account that asked for it. Rows are deleted when used, and the DS prunes
+-- lapsed ones itself. Email addresses are stored as typed, lowercased; no
+-- message content.
+--
+-- Additive + backward-compatible (CLAUDE.md migration rule): new tables that
+-- no client reads.
+CREATE TABLE IF NOT EXISTS ds_otp (
+    scope        TEXT NOT NULL,
+    email        TEXT NOT NULL,
+    code_hash    BLOB NOT NULL,
+    salt         BLOB NOT NULL,
+    expires_at   INTEGER NOT NULL,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    last_sent_at INTEGER NOT NULL,
+    PRIMARY KEY (scope, email)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ds_otp_expires ON ds_otp(expires_at);
+
+CREATE TABLE IF NOT EXISTS ds_session (
+    token_hash BLOB PRIMARY KEY,
+    user_id    TEXT NOT NULL,
+    email      TEXT NOT NULL,
+    device_id  TEXT NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ds_session_expires ON ds_session(expires_at);
+
+CREATE TABLE IF NOT EXISTS ds_email_change (
+    email        TEXT PRIMARY KEY,
+    requester    TEXT NOT NULL,
+    requested_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ds_email_change_requested ON ds_email_change(requested_at);
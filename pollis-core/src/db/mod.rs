@@ -1,3 +1,4 @@
+pub mod backup;
 pub mod local;
 pub mod remote;
 
@@ -78,9 +79,102 @@ pub const POST_BASELINE_MIGRATIONS: &[(u32, &str, &str)] = &[
         "directory_index",
         include_str!("migrations/000009_directory_index.sql"),
     ),
+    (
+        10,
+        "group_ownership_transfer",
+        include_str!("migrations/000010_group_ownership_transfer.sql"),
+    ),
+    (
+        11,
+        "group_allow_export",
+        include_str!("migrations/000011_group_allow_export.sql"),
+    ),
+    (
+        12,
+        "flood_incident",
+        include_str!("migrations/000012_flood_incident.sql"),
+    ),
+    (
+        13,
+        "group_webhook",
+        include_str!("migrations/000013_group_webhook.sql"),
+    ),
+    (
+        14,
+        "inactivity_policy",
+        include_str!("migrations/000014_inactivity_policy.sql"),
+    ),
+    (
+        15,
+        "pinned_message",
+        include_str!("migrations/000015_pinned_message.sql"),
+    ),
+    (
+        16,
+        "channel_retention",
+        include_str!("migrations/000016_channel_retention.sql"),
+    ),
+    (
+        17,
+        "usage_daily",
+        include_str!("migrations/000017_usage_daily.sql"),
+    ),
+    (
+        18,
+        "group_slug",
+        include_str!("migrations/000018_group_slug.sql"),
+    ),
+    (
+        19,
+        "email_invite",
+        include_str!("migrations/000019_email_invite.sql"),
+    ),
+    (
+        20,
+        "account_key_rotation_sig",
+        include_str!("migrations/000020_account_key_rotation_sig.sql"),
+    ),
+    (
+        21,
+        "key_package_claimed_at",
+        include_str!("migrations/000021_key_package_claimed_at.sql"),
+    ),
+    (
+        22,
+        "hot_path_indexes",
+        include_str!("migrations/000022_hot_path_indexes.sql"),
+    ),
+    (
+        23,
+        "group_deletion",
+        include_str!("migrations/000023_group_deletion.sql"),
+    ),
+    (
+        24,
+        "ds_shared_state",
+        include_str!("migrations/000024_ds_shared_state.sql"),
+    ),
+    (
+        25,
+        "group_storage_target",
+        include_str!("migrations/000025_group_storage_target.sql"),
+    ),
+    (
+        26,
+        "ds_idempotency",
+        include_str!("migrations/000026_ds_idempotency.sql"),
+    ),
+    (
+        27,
+        "ds_auth_state",
+        include_str!("migrations/000027_ds_auth_state.sql"),
+    ),
 ];
 
 pub mod queries {
     pub const MESSAGES_BY_SENDER: &str = include_str!("queries/messages_by_sender.sql");
     pub const CHANNEL_PREVIEWS: &str = include_str!("queries/channel_previews.sql");
+    /// Runs against the LOCAL DB (`local_schema.sql`), unlike the two above.
+    pub const LOCAL_CONVERSATION_PREVIEWS: &str =
+        include_str!("queries/local_conversation_previews.sql");
 }
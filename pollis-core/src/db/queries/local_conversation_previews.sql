@@ -0,0 +1,26 @@
+-- LOCAL DB. Newest visible message per conversation with the sender's cached
+-- username, most-recently-active first. Reads the already-decrypted `content`
+-- column, so the sidebar renders without touching MLS or the network. Deleted
+-- and undecrypted rows are skipped, so an edit or delete is reflected on the
+-- next read with no separate preview to maintain.
+SELECT
+    latest.conversation_id,
+    latest.id,
+    latest.sender_id,
+    uc.username,
+    latest.content,
+    latest.sent_at,
+    latest.edited_at
+FROM (
+    SELECT m.conversation_id, m.id, m.sender_id, m.content, m.sent_at, m.edited_at,
+           ROW_NUMBER() OVER (
+               PARTITION BY m.conversation_id
+               ORDER BY m.sent_at DESC, m.id DESC
+           ) AS rn
+    FROM message m
+    WHERE m.deleted_at IS NULL
+      AND m.content IS NOT NULL
+) latest
+LEFT JOIN user_cache uc ON uc.id = latest.sender_id
+WHERE latest.rn = 1
+ORDER BY latest.sent_at DESC, latest.id DESC
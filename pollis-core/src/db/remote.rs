@@ -587,6 +587,87 @@ mod tests {
         assert_eq!(channels, 0, "channel rows should cascade delete");
     }
 
+    // ── Ownership transfer ───────────────────────────────────────────────────
+
+    fn db_with_ownership_transfer() -> Connection {
+        let conn = db();
+        let (_, _, sql) = crate::db::POST_BASELINE_MIGRATIONS
+            .iter()
+            .find(|(_, name, _)| *name == "group_ownership_transfer")
+            .expect("migration registered");
+        conn.execute_batch(sql).unwrap();
+        conn.execute("INSERT INTO users (id, email, username) VALUES ('u1', 'a@x.com', 'u1')", []).unwrap();
+        conn.execute("INSERT INTO users (id, email, username) VALUES ('u2', 'b@x.com', 'u2')", []).unwrap();
+        conn.execute("INSERT INTO users (id, email, username) VALUES ('u3', 'c@x.com', 'u3')", []).unwrap();
+        conn.execute("INSERT INTO groups (id, name, owner_id) VALUES ('g1', 'G', 'u1')", []).unwrap();
+        conn.execute("INSERT INTO group_member (group_id, user_id, role) VALUES ('g1', 'u1', 'admin')", []).unwrap();
+        conn.execute("INSERT INTO group_member (group_id, user_id, role) VALUES ('g1', 'u2', 'member')", []).unwrap();
+        conn
+    }
+
+    // Same statement the DS accept-ownership write runs.
+    const ACCEPT_OWNERSHIP: &str = "UPDATE groups SET owner_id = ?2
+         WHERE id = ?1
+           AND owner_id = (SELECT from_user_id FROM group_ownership_transfer
+                           WHERE group_id = ?1 AND to_user_id = ?2)";
+
+    #[test]
+    fn ownership_transfer_moves_owner_only_for_pending_target() {
+        let conn = db_with_ownership_transfer();
+        conn.execute(
+            "INSERT INTO group_ownership_transfer (group_id, from_user_id, to_user_id) VALUES ('g1', 'u1', 'u2')",
+            [],
+        ).unwrap();
+
+        // Someone other than the pending target can't redeem it.
+        let moved = conn.execute(ACCEPT_OWNERSHIP, rusqlite::params!["g1", "u3"]).unwrap();
+        assert_eq!(moved, 0);
+
+        let moved = conn.execute(ACCEPT_OWNERSHIP, rusqlite::params!["g1", "u2"]).unwrap();
+        assert_eq!(moved, 1);
+        let owner: String = conn.query_row(
+            "SELECT owner_id FROM groups WHERE id = 'g1'",
+            [],
+            |row| row.get(0),
+        ).unwrap();
+        assert_eq!(owner, "u2");
+    }
+
+    #[test]
+    fn ownership_transfer_is_void_once_proposer_no_longer_owns() {
+        let conn = db_with_ownership_transfer();
+        conn.execute(
+            "INSERT INTO group_ownership_transfer (group_id, from_user_id, to_user_id) VALUES ('g1', 'u1', 'u2')",
+            [],
+        ).unwrap();
+        conn.execute("UPDATE groups SET owner_id = 'u3' WHERE id = 'g1'", []).unwrap();
+
+        let moved = conn.execute(ACCEPT_OWNERSHIP, rusqlite::params!["g1", "u2"]).unwrap();
+        assert_eq!(moved, 0);
+    }
+
+    #[test]
+    fn ownership_transfer_rejects_self_and_cascades_with_group() {
+        let conn = db_with_ownership_transfer();
+        let self_transfer = conn.execute(
+            "INSERT INTO group_ownership_transfer (group_id, from_user_id, to_user_id) VALUES ('g1', 'u1', 'u1')",
+            [],
+        );
+        assert!(self_transfer.is_err(), "CHECK must reject a transfer to oneself");
+
+        conn.execute(
+            "INSERT INTO group_ownership_transfer (group_id, from_user_id, to_user_id) VALUES ('g1', 'u1', 'u2')",
+            [],
+        ).unwrap();
+        conn.execute("DELETE FROM groups WHERE id = 'g1'", []).unwrap();
+        let remaining: i64 = conn.query_row(
+            "SELECT COUNT(*) FROM group_ownership_transfer",
+            [],
+            |row| row.get(0),
+        ).unwrap();
+        assert_eq!(remaining, 0);
+    }
+
     // ── Invites ──────────────────────────────────────────────────────────────
 
     #[test]
@@ -810,4 +891,87 @@ mod tests {
 
         assert_eq!(undelivered, 2, "2 undelivered envelopes should remain");
     }
+
+    // ── Query plans ──────────────────────────────────────────────────────────
+
+    /// Baseline plus every registered migration: the schema prod runs.
+    fn migrated_db() -> Connection {
+        let conn = db();
+        for (_, _, sql) in crate::db::POST_BASELINE_MIGRATIONS {
+            conn.execute_batch(sql).unwrap();
+        }
+        conn
+    }
+
+    // Hot statements, copied from where they run. Each must be served by an
+    // index: a full table scan here is a regression the next migration or
+    // query edit introduced.
+    const HOT_QUERIES: &[&str] = &[
+        // devices::apply_claim_key_package (per-device claim)
+        "SELECT ref_hash FROM mls_key_package
+         WHERE user_id = ?1 AND device_id = ?2 AND claimed = 0
+         ORDER BY created_at ASC LIMIT 1",
+        // key_packages::recent_claims
+        "SELECT COUNT(*) FROM mls_key_package
+         WHERE user_id = ?1 AND device_id = ?2 AND claimed = 1
+           AND claimed_at >= datetime('now', ?3)",
+        // ingest: envelopes past this device's watermark
+        "SELECT id, sender_id, ciphertext, reply_to_id, target_message_id, sent_at, type
+         FROM message_envelope
+         WHERE conversation_id = ?1
+           AND sent_at > COALESCE((SELECT last_fetched_at FROM conversation_watermark
+                                   WHERE conversation_id = ?1 AND user_id = ?2 AND device_id = ?3), '')
+         ORDER BY sent_at ASC, id ASC",
+        // DS edit: the latest edit of a message
+        "SELECT id FROM message_envelope
+         WHERE conversation_id = ?1 AND target_message_id = ?2 AND type = 'edit'",
+        // account::apply_delete_account
+        "DELETE FROM message_envelope WHERE sender_id = ?1",
+        // groups::ownership::get_pending_ownership_transfers
+        "SELECT t.group_id FROM group_ownership_transfer t
+         WHERE t.to_user_id = ?1 OR t.from_user_id = ?1",
+        "SELECT group_id FROM group_member WHERE user_id = ?1",
+        "SELECT dm_channel_id FROM dm_channel_member WHERE user_id = ?1",
+        "SELECT id FROM channels WHERE group_id = ?1",
+        "SELECT conversation_id FROM message_envelope WHERE id = ?1",
+        "SELECT identity_version FROM account_key_log
+         WHERE user_id = ?1 AND identity_version > ?2 ORDER BY identity_version",
+        "SELECT message_id FROM pinned_message WHERE conversation_id = ?1",
+    ];
+
+    #[test]
+    fn hot_queries_use_an_index() {
+        let conn = migrated_db();
+        for sql in HOT_QUERIES {
+            let mut stmt = conn.prepare(&format!("EXPLAIN QUERY PLAN {sql}")).unwrap();
+            let params = vec![rusqlite::types::Null; stmt.parameter_count()];
+            let plan: Vec<String> = stmt
+                .query_map(rusqlite::params_from_iter(params), |row| row.get(3))
+                .unwrap()
+                .collect::<Result<_, _>>()
+                .unwrap();
+            for step in &plan {
+                assert!(
+                    !(step.starts_with("SCAN ") && !step.contains("INDEX")),
+                    "full scan in plan {plan:?} for:\n{sql}"
+                );
+            }
+        }
+    }
+
+    #[test]
+    fn key_package_claim_needs_no_sort() {
+        let conn = migrated_db();
+        let plan: Vec<String> = conn
+            .prepare(&format!("EXPLAIN QUERY PLAN {}", HOT_QUERIES[0]))
+            .unwrap()
+            .query_map(rusqlite::params!["u1", "d1"], |row| row.get(3))
+            .unwrap()
+            .collect::<Result<_, _>>()
+            .unwrap();
+        assert!(
+            plan.iter().all(|step| !step.contains("TEMP B-TREE")),
+            "claim should read packages in created_at order off the index: {plan:?}"
+        );
+    }
 }
@@ -35,6 +35,37 @@ pub enum Error {
     #[error("client_outdated")]
     ClientOutdated,
 
+    // Typed stand-ins for the stringly errors commands used to build with
+    // `anyhow!`. The Display text is unchanged from those strings — the
+    // frontend still receives (and in places matches on) the message — but
+    // Rust callers can now match the variant instead of the text.
+
+    /// The local DB is closed: no user is unlocked (see `pin::lock`).
+    #[error("Not signed in")]
+    NotSignedIn,
+
+    /// A remote row the command needs doesn't exist. Carries the noun,
+    /// e.g. `NotFound("channel".into())` → "channel not found".
+    #[error("{0} not found")]
+    NotFound(String),
+
+    /// The write would duplicate state that already exists (already a
+    /// member, invite already pending, …). Carries the full message.
+    #[error("{0}")]
+    Conflict(String),
+
+    /// A network call hit its deadline (see `ds_client::DS_REQUEST_TIMEOUT` and
+    /// `DS_METADATA_TIMEOUT`).
+    /// Carries what was being done, e.g. `"ds_post /v1/messages/send"`.
+    #[error("{0} timed out")]
+    Timeout(String),
+
+    /// The DS refused a write because it is in a maintenance window (see
+    /// `commands::maintenance`). The frontend holds the write and retries
+    /// once the window closes, matching on this text.
+    #[error("Server maintenance: writes are paused")]
+    Maintenance,
+
     #[error("{0}")]
     Other(#[from] anyhow::Error),
 }
@@ -60,3 +91,28 @@ impl Serialize for Error {
 }
 
 pub type Result<T> = std::result::Result<T, Error>;
+
+#[cfg(test)]
+mod tests {
+    use super::Error;
+
+    // The frontend gets the Display string, so the typed variants must render
+    // exactly what the old `anyhow!` call sites did.
+    #[test]
+    fn typed_errors_keep_their_wire_text() {
+        assert_eq!(Error::NotSignedIn.to_string(), "Not signed in");
+        assert_eq!(Error::NotFound("channel".into()).to_string(), "channel not found");
+        assert_eq!(
+            Error::Conflict("you already own this group".into()).to_string(),
+            "you already own this group"
+        );
+        assert_eq!(
+            Error::Timeout("ds_post /v1/pins/add".into()).to_string(),
+            "ds_post /v1/pins/add timed out"
+        );
+        assert_eq!(
+            Error::Maintenance.to_string(),
+            "Server maintenance: writes are paused"
+        );
+    }
+}
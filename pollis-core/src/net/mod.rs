@@ -6,3 +6,4 @@
 
 pub mod directory;
 pub mod overlay;
+pub mod relay_latency;
@@ -15,7 +15,6 @@
 use std::future::Future;
 use std::net::SocketAddr;
 use std::pin::Pin;
-use std::sync::atomic::{AtomicUsize, Ordering};
 use std::sync::{Arc, Mutex, Weak};
 use std::task::{Context, Poll};
 use std::time::{Duration, Instant};
@@ -29,6 +28,7 @@ use tokio::task::JoinHandle;
 use tower_service::Service;
 
 use super::directory;
+use super::relay_latency::{self, RelayLatency};
 
 use pollis_relay::circuit::{Circuit, CircuitFactory, Hop};
 use pollis_relay::client::ClientIdentity;
@@ -180,8 +180,10 @@ struct RelayEndpoint {
 /// connect retries it), matching `RemoteDb::with_retry`. Selection is *fail-open*:
 /// healthy endpoints are tried first, but if all are marked dead they are still
 /// tried (a transient outage that marked the whole pool dead must never wedge it
-/// permanently). A rotating start index (`next_start`) spreads load across healthy
-/// endpoints instead of always hammering endpoint 0.
+/// permanently). Among healthy endpoints the order is latency-aware: every dial
+/// is timed into a shared [`RelayLatency`] and the fastest endpoint is preferred,
+/// with hysteresis and on-connect re-probing of stale ones (see
+/// [`relay_latency`]) — again no background poll.
 struct RealRelayFactory {
     /// Weak so the factory (owned by the shim task, owned by `AppState.overlay`)
     /// does not form a reference cycle back into `AppState`.
@@ -193,9 +195,10 @@ struct RealRelayFactory {
     /// `std::sync::Mutex` (never held across an await) — the guard is dropped
     /// before any I/O.
     health: Mutex<Vec<Option<Instant>>>,
-    /// Rotating start offset for load spread: each `connect` bumps this so the
-    /// pool doesn't always begin at endpoint 0.
-    next_start: AtomicUsize,
+    /// Dial-latency history + the sticky fastest-endpoint preference.
+    /// [`relay_latency::global`] in production (so it survives a directory
+    /// refresh swapping the factory); tests inject a fresh one.
+    latency: Arc<RelayLatency>,
     /// How long a failed endpoint stays dead. `RELAY_DEAD_COOLDOWN` in production;
     /// tests inject a short value to exercise recovery.
     cooldown: Duration,
@@ -209,6 +212,7 @@ impl RealRelayFactory {
     fn new(
         state: Weak<AppState>,
         endpoints: Vec<RelayEndpoint>,
+        latency: Arc<RelayLatency>,
         cooldown: Duration,
         dial_timeout: Duration,
     ) -> Self {
@@ -218,35 +222,28 @@ impl RealRelayFactory {
             endpoints,
             identity: AsyncMutex::new(None),
             health: Mutex::new(vec![None; n]),
-            next_start: AtomicUsize::new(0),
+            latency,
             cooldown,
             dial_timeout,
         }
     }
 
     /// The order to try endpoints in for the next dial: healthy endpoints first
-    /// (rotated by `next_start` so load spreads), then any still in cooldown
+    /// (latency-ordered by [`RelayLatency::order`]), then any still in cooldown
     /// (fail-open — always tried, so a fully-dead pool is never permanently
-    /// wedged). Returns endpoint indices. The health lock is taken and dropped
-    /// here; no I/O happens under it.
+    /// wedged). Returns endpoint indices. The locks are taken and dropped here;
+    /// no I/O happens under them.
     fn candidate_order(&self) -> Vec<usize> {
-        let n = self.endpoints.len();
-        let start = self.next_start.fetch_add(1, Ordering::Relaxed) % n;
         let now = Instant::now();
-        let health = self.health.lock().unwrap();
-        let mut healthy = Vec::with_capacity(n);
-        let mut dead = Vec::new();
-        for i in 0..n {
-            let idx = (start + i) % n;
-            let in_cooldown = health[idx].is_some_and(|until| until > now);
-            if in_cooldown {
-                dead.push(idx);
-            } else {
-                healthy.push(idx);
-            }
-        }
-        healthy.extend(dead);
-        healthy
+        let (healthy, dead): (Vec<usize>, Vec<usize>) = {
+            let health = self.health.lock().unwrap();
+            (0..self.endpoints.len())
+                .partition(|&idx| !health[idx].is_some_and(|until| until > now))
+        };
+        let addrs: Vec<String> = self.endpoints.iter().map(|e| e.addr.clone()).collect();
+        let mut order = self.latency.order(&addrs, &healthy, now);
+        order.extend(dead);
+        order
     }
 
     /// Mark endpoint `idx` dead until `now + cooldown` (failed dial).
@@ -317,6 +314,7 @@ impl CircuitFactory for RealRelayFactory {
         let mut last_err = None;
         for idx in self.candidate_order() {
             let dial = self.dial_endpoint(&self.endpoints[idx], &identity, host, port);
+            let started = Instant::now();
             let outcome = match tokio::time::timeout(self.dial_timeout, dial).await {
                 Ok(res) => res,
                 Err(_) => Err(anyhow::anyhow!(
@@ -327,10 +325,13 @@ impl CircuitFactory for RealRelayFactory {
             match outcome {
                 Ok(stream) => {
                     self.mark_healthy(idx);
+                    self.latency
+                        .record(&self.endpoints[idx].addr, Some(started.elapsed()));
                     return Ok(stream);
                 }
                 Err(e) => {
                     self.mark_dead(idx);
+                    self.latency.record(&self.endpoints[idx].addr, None);
                     last_err = Some(e);
                 }
             }
@@ -581,6 +582,7 @@ async fn fetch_and_build_pool(
     let factory: Arc<dyn CircuitFactory> = Arc::new(RealRelayFactory::new(
         state.clone(),
         endpoints,
+        relay_latency::global(),
         cooldown,
         dial_timeout,
     ));
@@ -663,6 +665,7 @@ async fn build_directory_factory(state: &Arc<AppState>) -> Arc<dyn CircuitFactor
             let empty: Arc<dyn CircuitFactory> = Arc::new(RealRelayFactory::new(
                 weak_state.clone(),
                 Vec::new(),
+                relay_latency::global(),
                 RELAY_DEAD_COOLDOWN,
                 RELAY_DIAL_TIMEOUT,
             ));
@@ -702,6 +705,7 @@ pub(crate) async fn start_overlay_shim(
         Arc::new(RealRelayFactory::new(
             Arc::downgrade(state),
             load_relay_endpoints(&state.config),
+            relay_latency::global(),
             RELAY_DEAD_COOLDOWN,
             RELAY_DIAL_TIMEOUT,
         ))
@@ -1510,6 +1514,7 @@ mod tests {
         RealRelayFactory::new(
             Arc::downgrade(state),
             endpoints,
+            Arc::default(),
             cooldown,
             Duration::from_secs(2),
         )
@@ -1528,7 +1533,8 @@ mod tests {
         let state = provisioned_state(cfg(OverlayMode::Prefer, None)).await;
 
         // relayA is an unreachable address at index 0 (tried first on the first
-        // connect: start offset is 0); relayB is the live pool member at index 1.
+        // connect: both are unmeasured, so they're probed in pool order); relayB
+        // is the live pool member at index 1.
         let endpoints = vec![
             endpoint("127.0.0.1:1".into(), relay_b.cert.clone()),
             endpoint(relay_b.addr.to_string(), relay_b.cert.clone()),
@@ -1621,8 +1627,8 @@ mod tests {
         assert_eq!(relay0.stats.dials(), 0, "dead endpoint 0 skipped while in cooldown");
         assert_eq!(relay1.stats.dials(), 1, "healthy endpoint 1 served the connect");
 
-        // After the cooldown expires, endpoint 0 is eligible again; over a few
-        // rotating connects it is retried and dials.
+        // After the cooldown expires, endpoint 0 is eligible again; never having
+        // been measured, it leads the next connect as the latency probe.
         tokio::time::sleep(cooldown + Duration::from_millis(50)).await;
         for _ in 0..4 {
             factory.connect(ORIGIN_NAME, origin.port()).await.unwrap();
@@ -1657,11 +1663,11 @@ mod tests {
         assert!(factory.health.lock().unwrap()[0].is_none());
     }
 
-    /// LOAD SPREAD: with two healthy relays, repeated connects do not all land on
-    /// endpoint 0 — the rotating start index deals them out deterministically.
+    /// LATENCY: with two healthy, measured relays, new connects go to the faster
+    /// one, and each dial lands in the latency history.
     #[tokio::test]
-    async fn pool_spreads_load_across_healthy_relays() {
-        let origin = spawn_plain_http("spread-target").await;
+    async fn pool_prefers_the_faster_relay() {
+        let origin = spawn_plain_http("latency-target").await;
         let relay0 = spawn_pool_relay();
         let relay1 = spawn_pool_relay();
         let state = provisioned_state(cfg(OverlayMode::Prefer, None)).await;
@@ -1672,14 +1678,23 @@ mod tests {
         ];
         let factory = pool_factory(&state, endpoints, Duration::from_secs(30));
 
-        const N: u64 = 6;
+        // Seed history so both are measured (no probe) and endpoint 1 is far
+        // faster than any loopback jitter could overturn.
+        let addr0 = relay0.addr.to_string();
+        let addr1 = relay1.addr.to_string();
+        factory.latency.record(&addr0, Some(Duration::from_secs(2)));
+        factory.latency.record(&addr1, Some(Duration::from_millis(1)));
+
+        const N: u64 = 4;
         for _ in 0..N {
             factory.connect(ORIGIN_NAME, origin.port()).await.unwrap();
         }
-        // Rotation alternates the first-tried (and, both being up, dialed)
-        // endpoint, so each takes exactly half — deterministic, never flaky.
-        assert_eq!(relay0.stats.dials(), N / 2, "endpoint 0 took its share");
-        assert_eq!(relay1.stats.dials(), N / 2, "endpoint 1 took its share");
-        assert_eq!(relay0.stats.dials() + relay1.stats.dials(), N);
+        assert_eq!(relay0.stats.dials(), 0, "the slow relay is not dialed");
+        assert_eq!(relay1.stats.dials(), N, "the fast relay takes every connect");
+
+        let report = factory.latency.report();
+        let fast = report.iter().find(|r| r.addr == addr1).unwrap();
+        assert!(fast.preferred);
+        assert_eq!(fast.history.len() as u64, N + 1);
     }
 }
@@ -0,0 +1,261 @@
+//! Latency-aware relay selection for the overlay pool (design
+//! `docs/relay-overlay-design.md` §14.1).
+//!
+//! Every real dial through [`RealRelayFactory`](super::overlay) is timed (QUIC
+//! handshake + CONNECT — what a call actually pays for that relay), and the pool
+//! prefers the fastest healthy endpoint. Measurement is **passive**: there is no
+//! background probe loop (CLAUDE.md forbids periodic keepalives). Instead, an
+//! endpoint with no sample yet, or whose newest sample is older than
+//! [`STALE_AFTER`], is put first on the next real connect — that connect *is*
+//! the probe, and if the endpoint is slow or dead the pool fails over as usual.
+//!
+//! **Hysteresis.** The preferred endpoint is sticky: another endpoint only takes
+//! over when its smoothed latency beats the preferred one by more than
+//! [`SWITCH_MARGIN`] (relative) *and* [`SWITCH_MIN_MS`] (absolute), so two
+//! relays a few ms apart don't flap on jitter.
+//!
+//! State is keyed by endpoint address and lives for the process
+//! ([`global`]), so history survives the directory refresh swapping in a fresh
+//! factory. `get_relay_latency` (`commands/overlay.rs`) reports it for
+//! diagnostics.
+
+use std::collections::{HashMap, VecDeque};
+use std::sync::{Arc, Mutex, OnceLock};
+use std::time::{Duration, Instant, SystemTime, UNIX_EPOCH};
+
+use serde::Serialize;
+
+/// Smoothing factor for the per-endpoint EWMA (weight of the newest sample).
+const EWMA_ALPHA: f64 = 0.3;
+
+/// A challenger must be this much faster (fraction of the preferred EWMA)…
+const SWITCH_MARGIN: f64 = 0.2;
+
+/// …and at least this many ms faster, before the preference moves.
+const SWITCH_MIN_MS: f64 = 10.0;
+
+/// A measurement older than this is re-probed on the next connect.
+pub(crate) const STALE_AFTER: Duration = Duration::from_secs(10 * 60);
+
+/// Samples kept per endpoint for diagnostics.
+const HISTORY_LEN: usize = 20;
+
+/// One dial outcome, for diagnostics.
+#[derive(Debug, Clone, Serialize)]
+pub struct LatencySample {
+    /// Unix seconds.
+    pub at: i64,
+    /// Dial round-trip in ms; `None` for a failed or timed-out dial.
+    pub rtt_ms: Option<u32>,
+}
+
+/// Per-endpoint diagnostics returned by `get_relay_latency`.
+#[derive(Debug, Clone, Serialize)]
+pub struct RelayLatencyReport {
+    pub addr: String,
+    /// Smoothed dial latency; `None` until the first successful dial.
+    pub ewma_ms: Option<f64>,
+    /// Whether new calls currently go to this endpoint first.
+    pub preferred: bool,
+    /// Oldest first.
+    pub history: Vec<LatencySample>,
+}
+
+#[derive(Default)]
+struct EndpointLatency {
+    ewma_ms: Option<f64>,
+    /// When the newest sample (success or failure) was taken.
+    last_sample: Option<Instant>,
+    history: VecDeque<LatencySample>,
+}
+
+#[derive(Default)]
+struct Inner {
+    endpoints: HashMap<String, EndpointLatency>,
+    preferred: Option<String>,
+}
+
+/// Latency history + the sticky preference. Cheap to lock: never held across
+/// an await.
+#[derive(Default)]
+pub struct RelayLatency {
+    inner: Mutex<Inner>,
+}
+
+/// The process-wide store the production factories share.
+pub(crate) fn global() -> Arc<RelayLatency> {
+    static GLOBAL: OnceLock<Arc<RelayLatency>> = OnceLock::new();
+    GLOBAL.get_or_init(Arc::default).clone()
+}
+
+impl RelayLatency {
+    /// Record a dial to `addr`: `Some(rtt)` on success, `None` on failure.
+    pub(crate) fn record(&self, addr: &str, rtt: Option<Duration>) {
+        let mut inner = self.inner.lock().unwrap();
+        let entry = inner.endpoints.entry(addr.to_string()).or_default();
+        let rtt_ms = rtt.map(|d| d.as_secs_f64() * 1000.0);
+        if let Some(ms) = rtt_ms {
+            entry.ewma_ms = Some(match entry.ewma_ms {
+                Some(prev) => EWMA_ALPHA * ms + (1.0 - EWMA_ALPHA) * prev,
+                None => ms,
+            });
+        }
+        entry.last_sample = Some(Instant::now());
+        if entry.history.len() == HISTORY_LEN {
+            entry.history.pop_front();
+        }
+        entry.history.push_back(LatencySample {
+            at: SystemTime::now()
+                .duration_since(UNIX_EPOCH)
+                .map(|d| d.as_secs() as i64)
+                .unwrap_or(0),
+            rtt_ms: rtt_ms.map(|ms| ms.round() as u32),
+        });
+    }
+
+    /// Order `healthy` endpoint indices (into `addrs`) for the next dial: one
+    /// unmeasured/stale endpoint first as the probe, then the (sticky) preferred
+    /// endpoint, then the rest by latency. Updates the preference.
+    pub(crate) fn order(&self, addrs: &[String], healthy: &[usize], now: Instant) -> Vec<usize> {
+        let mut guard = self.inner.lock().unwrap();
+        let inner = &mut *guard;
+
+        let probe = healthy.iter().copied().find(|&i| {
+            match inner.endpoints.get(&addrs[i]).and_then(|e| e.last_sample) {
+                Some(at) => now.saturating_duration_since(at) > STALE_AFTER,
+                None => true,
+            }
+        });
+
+        let mut measured: Vec<(usize, f64)> = healthy
+            .iter()
+            .filter_map(|&i| {
+                let ms = inner.endpoints.get(&addrs[i]).and_then(|e| e.ewma_ms)?;
+                Some((i, ms))
+            })
+            .collect();
+        measured.sort_by(|a, b| a.1.total_cmp(&b.1));
+
+        // Keep the preference unless it's unhealthy/unmeasured or clearly beaten.
+        let current = inner
+            .preferred
+            .as_ref()
+            .and_then(|p| measured.iter().find(|(i, _)| &addrs[*i] == p).copied());
+        let preferred = match (current, measured.first().copied()) {
+            (Some((cur, cur_ms)), Some((_, best_ms)))
+                if cur_ms - best_ms <= (cur_ms * SWITCH_MARGIN).max(SWITCH_MIN_MS) =>
+            {
+                Some(cur)
+            }
+            (_, best) => best.map(|(i, _)| i),
+        };
+        if let Some(p) = preferred {
+            inner.preferred = Some(addrs[p].clone());
+        }
+
+        let mut out = Vec::with_capacity(healthy.len());
+        out.extend(probe);
+        out.extend(preferred.filter(|p| Some(*p) != probe));
+        for (i, _) in measured {
+            if !out.contains(&i) {
+                out.push(i);
+            }
+        }
+        // Unmeasured endpoints other than the probe go last, in pool order.
+        for &i in healthy {
+            if !out.contains(&i) {
+                out.push(i);
+            }
+        }
+        out
+    }
+
+    /// Diagnostics for every endpoint dialed this process, by address.
+    pub(crate) fn report(&self) -> Vec<RelayLatencyReport> {
+        let inner = self.inner.lock().unwrap();
+        let mut out: Vec<RelayLatencyReport> = inner
+            .endpoints
+            .iter()
+            .map(|(addr, e)| RelayLatencyReport {
+                addr: addr.clone(),
+                ewma_ms: e.ewma_ms,
+                preferred: inner.preferred.as_deref() == Some(addr.as_str()),
+                history: e.history.iter().cloned().collect(),
+            })
+            .collect();
+        out.sort_by(|a, b| a.addr.cmp(&b.addr));
+        out
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn addrs() -> Vec<String> {
+        vec!["a:1".into(), "b:1".into(), "c:1".into()]
+    }
+
+    fn ms(n: u64) -> Option<Duration> {
+        Some(Duration::from_millis(n))
+    }
+
+    #[test]
+    fn unmeasured_endpoints_are_probed_first() {
+        let lat = RelayLatency::default();
+        let addrs = addrs();
+        assert_eq!(lat.order(&addrs, &[0, 1, 2], Instant::now()), [0, 1, 2]);
+        lat.record("a:1", ms(50));
+        assert_eq!(lat.order(&addrs, &[0, 1, 2], Instant::now())[0], 1);
+    }
+
+    #[test]
+    fn prefers_fastest_with_hysteresis() {
+        let lat = RelayLatency::default();
+        let addrs = addrs();
+        lat.record("a:1", ms(100));
+        lat.record("b:1", ms(90));
+        lat.record("c:1", ms(300));
+        // b is fastest → preferred.
+        assert_eq!(lat.order(&addrs, &[0, 1, 2], Instant::now()), [1, 0, 2]);
+
+        // a gets a little faster than b — within the margin, so b stays.
+        for _ in 0..10 {
+            lat.record("a:1", ms(80));
+        }
+        assert_eq!(lat.order(&addrs, &[0, 1, 2], Instant::now())[0], 1);
+
+        // a pulls clearly ahead → the preference moves.
+        for _ in 0..10 {
+            lat.record("a:1", ms(30));
+        }
+        assert_eq!(lat.order(&addrs, &[0, 1, 2], Instant::now())[0], 0);
+        let report = lat.report();
+        assert!(report.iter().find(|r| r.addr == "a:1").unwrap().preferred);
+        assert_eq!(report.iter().filter(|r| r.preferred).count(), 1);
+    }
+
+    #[test]
+    fn unhealthy_preferred_is_passed_over() {
+        let lat = RelayLatency::default();
+        let addrs = addrs();
+        lat.record("a:1", ms(10));
+        lat.record("b:1", ms(50));
+        lat.record("c:1", ms(60));
+        assert_eq!(lat.order(&addrs, &[0, 1, 2], Instant::now())[0], 0);
+        // a is in its dead cooldown (not in `healthy`).
+        assert_eq!(lat.order(&addrs, &[1, 2], Instant::now()), [1, 2]);
+    }
+
+    #[test]
+    fn stale_measurement_is_reprobed() {
+        let lat = RelayLatency::default();
+        let addrs = addrs();
+        lat.record("a:1", ms(10));
+        lat.record("b:1", ms(50));
+        lat.record("c:1", ms(60));
+        let later = Instant::now() + STALE_AFTER + Duration::from_secs(1);
+        // Everything is stale; the first stale endpoint leads as the probe.
+        assert_eq!(lat.order(&addrs, &[1, 2], later)[0], 1);
+    }
+}
@@ -94,6 +94,12 @@ pub enum RealtimeEvent {
         conversation_id: Option<String>,
         message_id: String,
     },
+    /// Sent to a channel's group room (or a DM room) when a message is pinned
+    /// or unpinned, so members refetch the pinned list. Routing only — who
+    /// pinned what is read from `pinned_message` on refetch.
+    PinsChanged {
+        conversation_id: String,
+    },
     /// Sent to a user's personal inbox room when one of their OTHER devices
     /// has just posted a `device_enrollment_request` row and is waiting for
     /// approval. Interrupts the UI on every receiving device so the user can
@@ -166,12 +166,23 @@ impl AppState {
         // settings toggle uses (design §14: boot = construct DBs direct, then
         // apply the mode). Off-by-default: with the overlay off this is
         // byte-for-byte the pre-overlay direct path and no shim is ever started.
-        let remote_db = Arc::new(RemoteDb::connect(&config.turso_url, &config.turso_token).await?);
+        //
         // Read-only commit-log DB when configured; otherwise reuse remote_db so
-        // behavior is unchanged pre-cutover.
-        let log_db = match (&config.log_db_url, &config.log_db_token) {
-            (Some(url), Some(token)) => Arc::new(RemoteDb::connect(url, token).await?),
-            _ => Arc::clone(&remote_db),
+        // behavior is unchanged pre-cutover. The two are independent, so they
+        // connect concurrently.
+        let (remote_db, log_db) = match (&config.log_db_url, &config.log_db_token) {
+            (Some(url), Some(token)) => {
+                let (remote, log) = tokio::try_join!(
+                    RemoteDb::connect(&config.turso_url, &config.turso_token),
+                    RemoteDb::connect(url, token),
+                )?;
+                (Arc::new(remote), Arc::new(log))
+            }
+            _ => {
+                let remote =
+                    Arc::new(RemoteDb::connect(&config.turso_url, &config.turso_token).await?);
+                (Arc::clone(&remote), remote)
+            }
         };
         let state = Self::new_with_parts(
             config,
@@ -355,6 +366,16 @@ impl AppState {
             eprintln!("[state] startup message eviction failed (non-fatal): {e}");
         }
 
+        // Daily local snapshot, taken on unlock rather than on a timer. Skipped
+        // for a freshly created/wiped DB so an empty copy can't rotate the
+        // useful snapshots out. Best-effort, like the sweep above.
+        if !mls_empty {
+            let dir = crate::db::backup::backups_dir();
+            if let Err(e) = crate::db::backup::create_backup_if_due(db.conn(), &dir, user_id) {
+                eprintln!("[state] startup local backup failed (non-fatal): {e}");
+            }
+        }
+
         *self.local_db.lock().await = Some(db);
         // Scope the media cache to this user. Two clients on the same machine
         // (dev workflow) otherwise share `app_data_dir/media-cache` but each
@@ -120,6 +120,11 @@ pub struct RotateIdentityBody {
     pub salt: String,
     pub nonce: String,
     pub wrapped_key: String,
+    /// Planned rotation only: the outgoing key's Ed25519 signature over
+    /// [`rotation_signed_payload`], base64 (STANDARD). Absent for a soft reset,
+    /// whose old key is lost.
+    #[serde(default)]
+    pub rotation_sig: Option<String>,
     /// Self-scope: when signed it must equal the authenticated user.
     #[serde(default)]
     pub user_id: Option<String>,
@@ -137,6 +142,8 @@ pub enum RotateOutcome {
     /// (or `based_on_version` was stale). `head_version` is the current head; the
     /// client must re-read and retry. No fork was created.
     Conflict { head_version: i64 },
+    /// `rotation_sig` doesn't verify under the key at `based_on_version`.
+    BadSignature,
 }
 
 /// POST /v1/account/rotate-identity — rotate a user's account identity key,
@@ -174,9 +181,65 @@ pub(crate) fn rotate_outcome_response(outcome: RotateOutcome) -> Result<Response
             Json(serde_json::json!({ "status": "conflict", "head_version": head_version })),
         )
             .into_response(),
+        RotateOutcome::BadSignature => bad_request("rotation_sig does not verify"),
     })
 }
 
+/// Domain separator for a rotation signature. Identical to pollis-core's
+/// `account_identity::ROTATION_DOMAIN` — bump in lock-step.
+const ROTATION_DOMAIN: &[u8] = b"pollis-account-key-rotation-v1\x00";
+
+/// Verbatim port of pollis-core's `account_identity::rotation_signed_payload`:
+///
+///   domain_separator (31 bytes, trailing NUL included)
+///   u8  user_id_len   ||  user_id bytes
+///   u32 new_version   (big-endian)
+///   old account_id_pub (32 bytes) || new account_id_pub (32 bytes)
+fn rotation_signed_payload(
+    user_id: &str,
+    new_version: i64,
+    old_pub: &[u8],
+    new_pub: &[u8],
+) -> Option<Vec<u8>> {
+    if user_id.len() > u8::MAX as usize || old_pub.len() != 32 || new_pub.len() != 32 {
+        return None;
+    }
+    let version = u32::try_from(new_version).ok()?;
+    let mut out = Vec::with_capacity(ROTATION_DOMAIN.len() + 1 + user_id.len() + 4 + 64);
+    out.extend_from_slice(ROTATION_DOMAIN);
+    out.push(user_id.len() as u8);
+    out.extend_from_slice(user_id.as_bytes());
+    out.extend_from_slice(&version.to_be_bytes());
+    out.extend_from_slice(old_pub);
+    out.extend_from_slice(new_pub);
+    Some(out)
+}
+
+/// Check `sig` by `old_pub` over the rotation to `new_pub` at `new_version`.
+/// Malformed input is `false`, never an accept.
+fn verify_rotation_sig(
+    user_id: &str,
+    new_version: i64,
+    old_pub: &[u8],
+    new_pub: &[u8],
+    sig: &[u8],
+) -> bool {
+    use ed25519_dalek::{Signature, VerifyingKey};
+    let Some(payload) = rotation_signed_payload(user_id, new_version, old_pub, new_pub) else {
+        return false;
+    };
+    let Ok(pk) = <[u8; 32]>::try_from(old_pub) else {
+        return false;
+    };
+    let Ok(vk) = VerifyingKey::from_bytes(&pk) else {
+        return false;
+    };
+    let Ok(sig) = Signature::from_slice(sig) else {
+        return false;
+    };
+    vk.verify_strict(&payload, &sig).is_ok()
+}
+
 /// Append `account_key_log` at `based_on_version + 1` IFF that version is the
 /// current head, then bump `users.identity_version` and rewrap
 /// `account_recovery` in the SAME transaction. The CAS is the conditional INSERT
@@ -210,15 +273,29 @@ pub async fn apply_rotate_identity(
     let nonce = b64_decode(&body.nonce)?;
     let wrapped = b64_decode(&body.wrapped_key)?;
     let new_version = body.based_on_version + 1;
+    let rotation_sig = body.rotation_sig.as_deref().map(b64_decode).transpose()?;
 
     let tx = conn.transaction().await?;
 
+    // A signed rotation must be signed by the key it replaces: the log row at
+    // `based_on_version`. If that isn't the head, the CAS below fails anyway.
+    if let Some(sig) = &rotation_sig {
+        let old_pub = key_log_pub(&tx, &actor, body.based_on_version).await?;
+        let valid = old_pub.is_some_and(|old| {
+            verify_rotation_sig(&actor, new_version, &old, &pub_bytes, sig)
+        });
+        if !valid {
+            drop(tx);
+            return Ok(RotateOutcome::BadSignature);
+        }
+    }
+
     // The atomic CAS: append at `new_version` only if `based_on_version` is the
     // current head of THIS user's account_key_log.
     let affected = tx
         .execute(
-            "INSERT INTO account_key_log (user_id, account_id_pub, identity_version) \
-             SELECT ?1, ?2, ?3 \
+            "INSERT INTO account_key_log (user_id, account_id_pub, identity_version, rotation_sig) \
+             SELECT ?1, ?2, ?3, ?5 \
              WHERE ?4 = (SELECT COALESCE(MAX(identity_version), 0) \
                          FROM account_key_log WHERE user_id = ?1) \
              ON CONFLICT(user_id, identity_version) DO NOTHING",
@@ -226,7 +303,8 @@ pub async fn apply_rotate_identity(
                 actor.clone(),
                 pub_bytes.clone(),
                 new_version,
-                body.based_on_version
+                body.based_on_version,
+                rotation_sig
             ],
         )
         .await?;
@@ -265,6 +343,25 @@ pub async fn apply_rotate_identity(
     Ok(RotateOutcome::Applied { new_version })
 }
 
+/// The `account_id_pub` a user's `account_key_log` records at `version`.
+async fn key_log_pub(
+    conn: &Connection,
+    user_id: &str,
+    version: i64,
+) -> anyhow::Result<Option<Vec<u8>>> {
+    let mut rows = conn
+        .query(
+            "SELECT account_id_pub FROM account_key_log \
+             WHERE user_id = ?1 AND identity_version = ?2",
+            libsql::params![user_id.to_string(), version],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) => Some(row.get(0)?),
+        None => None,
+    })
+}
+
 /// The current head of a user's `account_key_log` = `MAX(identity_version)` (0
 /// for a user with no log rows yet). `&Transaction` derefs to `&Connection`.
 async fn current_key_log_head(conn: &Connection, user_id: &str) -> anyhow::Result<i64> {
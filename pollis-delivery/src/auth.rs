@@ -52,8 +52,9 @@
 //! tolerate device/server clock skew without a time-sync handshake, narrow
 //! enough that a captured signature is only briefly replayable. The body-hash
 //! binding already prevents cross-request replay; the window bounds *identical*
-//! request replay. A true nonce/once-store would close the window entirely but
-//! needs shared write state the DS deliberately avoids — out of scope here.
+//! request replay. Envelope writes additionally remember the envelope ids they
+//! accepted for twice this window ([`crate::replay`]), so a byte-for-byte
+//! replay of a send or edit is refused even inside it.
 //!
 //! ## Never fail open
 //!
@@ -72,8 +73,8 @@ use crate::error::AuthRejection;
 /// docs for the tradeoff.
 pub const REPLAY_WINDOW_SECS: i64 = 300;
 
-const H_USER: &str = "x-pollis-user";
-const H_DEVICE: &str = "x-pollis-device";
+pub(crate) const H_USER: &str = "x-pollis-user";
+pub(crate) const H_DEVICE: &str = "x-pollis-device";
 const H_TIMESTAMP: &str = "x-pollis-timestamp";
 pub(crate) const H_SIGNATURE: &str = "x-pollis-signature";
 
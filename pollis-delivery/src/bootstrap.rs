@@ -84,7 +84,7 @@ pub async fn establish_identity(
     headers: HeaderMap,
     body: Bytes,
 ) -> Response {
-    let claims = match verify_session(&headers, &state.sessions, now_unix()) {
+    let claims = match verify_session(&headers, &state.sessions, now_unix()).await {
         Ok(c) => c,
         Err(rej) => return rej.into_response(),
     };
@@ -191,7 +191,7 @@ pub async fn register_device(
     headers: HeaderMap,
     body: Bytes,
 ) -> Response {
-    let claims = match verify_session(&headers, &state.sessions, now_unix()) {
+    let claims = match verify_session(&headers, &state.sessions, now_unix()).await {
         Ok(c) => c,
         Err(rej) => return rej.into_response(),
     };
@@ -336,9 +336,10 @@ pub async fn publish_device_cert(
     let session_token = crate::session::session_token(&headers)
         .filter(|t| !t.is_empty())
         .map(|t| t.to_string());
-    let session_claims = session_token
-        .as_ref()
-        .and_then(|t| state.sessions.resolve(t, now));
+    let session_claims = match &session_token {
+        Some(t) => state.sessions.resolve(t, now).await,
+        None => None,
+    };
 
     let (user_id, device_id, invalidate_token) = match session_claims {
         Some(claims) => {
@@ -379,7 +380,7 @@ pub async fn publish_device_cert(
             // Single-use through the pivot: invalidate the bootstrap session if
             // one was used (gate a). Gate (b) had no session to spend.
             if let Some(token) = invalidate_token {
-                state.sessions.invalidate(&token);
+                state.sessions.invalidate(&token).await;
             }
             ok_status()
         }
@@ -499,7 +500,7 @@ pub async fn enrollment_request(
     headers: HeaderMap,
     body: Bytes,
 ) -> Response {
-    let claims = match verify_session(&headers, &state.sessions, now_unix()) {
+    let claims = match verify_session(&headers, &state.sessions, now_unix()).await {
         Ok(c) => c,
         Err(rej) => return rej.into_response(),
     };
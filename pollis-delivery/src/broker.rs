@@ -49,6 +49,10 @@ use libsql::Connection;
 use serde::{Deserialize, Serialize};
 
 use crate::error::{AppError, AuthRejection};
+use crate::residency;
+use crate::storage::PutBinding;
+use crate::uploads::UploadCategory;
+use crate::usage::{record_if_enabled, UsageDelta};
 use crate::writes::{bad_request, gate, is_member, ok_json, Authed};
 use crate::AppState;
 
@@ -70,7 +74,8 @@ pub struct BrokerConfig {
     pub livekit_url: Option<String>,
     /// R2 S3 endpoint, e.g. `https://<acct>.r2.cloudflarestorage.com` (a trailing
     /// `/<bucket>` path segment is fine — the presigner uses only the host). Read
-    /// from `R2_ENDPOINT`, falling back to the established `R2_S3_ENDPOINT`.
+    /// from `R2_ENDPOINT`, falling back to the established `R2_S3_ENDPOINT`, then
+    /// `S3_ENDPOINT`. Any SigV4 S3 store works (MinIO, …) — see [`crate::storage`].
     pub r2_endpoint: Option<String>,
     /// R2 region — SigV4 scope; defaults to `auto` (env `R2_REGION`).
     pub r2_region: String,
@@ -103,11 +108,18 @@ impl BrokerConfig {
             // Accept the established client env names (`R2_S3_ENDPOINT` /
             // `R2_SECRET_KEY`) so the DS reuses the same Doppler secrets instead
             // of duplicating them under new keys.
-            r2_endpoint: var("R2_ENDPOINT").or_else(|| var("R2_S3_ENDPOINT")),
-            r2_region: var("R2_REGION").unwrap_or_else(|| "auto".to_string()),
-            r2_bucket: var("R2_BUCKET"),
-            r2_access_key_id: var("R2_ACCESS_KEY_ID"),
-            r2_secret_access_key: var("R2_SECRET_ACCESS_KEY").or_else(|| var("R2_SECRET_KEY")),
+            // Self-hosters on MinIO etc. can use the generic `S3_*` names instead.
+            r2_endpoint: var("R2_ENDPOINT")
+                .or_else(|| var("R2_S3_ENDPOINT"))
+                .or_else(|| var("S3_ENDPOINT")),
+            r2_region: var("R2_REGION")
+                .or_else(|| var("S3_REGION"))
+                .unwrap_or_else(|| "auto".to_string()),
+            r2_bucket: var("R2_BUCKET").or_else(|| var("S3_BUCKET")),
+            r2_access_key_id: var("R2_ACCESS_KEY_ID").or_else(|| var("S3_ACCESS_KEY_ID")),
+            r2_secret_access_key: var("R2_SECRET_ACCESS_KEY")
+                .or_else(|| var("R2_SECRET_KEY"))
+                .or_else(|| var("S3_SECRET_ACCESS_KEY")),
             turso_platform_token: var("TURSO_PLATFORM_TOKEN"),
             turso_org: var("TURSO_ORG"),
             turso_db: var("TURSO_DB"),
@@ -115,7 +127,7 @@ impl BrokerConfig {
     }
 
     /// All three LiveKit fields present → the token endpoint can sign.
-    fn livekit_ready(&self) -> Option<(&str, &str, &str)> {
+    pub(crate) fn livekit_ready(&self) -> Option<(&str, &str, &str)> {
         Some((
             self.livekit_api_key.as_deref()?,
             self.livekit_api_secret.as_deref()?,
@@ -124,7 +136,7 @@ impl BrokerConfig {
     }
 
     /// All R2 fields present → the presign endpoint can sign.
-    fn r2_ready(&self) -> Option<(&str, &str, &str, &str)> {
+    pub(crate) fn r2_ready(&self) -> Option<(&str, &str, &str, &str)> {
         Some((
             self.r2_endpoint.as_deref()?,
             self.r2_bucket.as_deref()?,
@@ -514,8 +526,9 @@ pub async fn livekit_send_data(
 ///
 /// Shared by the client-facing `/v1/livekit/send-data` endpoint and by
 /// **server-side emitters** — notably the enrollment-request inbox
-/// notification (`bootstrap::enrollment_request`), which the requesting device
-/// CANNOT send itself: it is pre-enrollment, so its `local_db` is closed and it
+/// notification (`bootstrap::enrollment_request`) and the `new_message` ping
+/// after a send (`messages::ping_new_message`). The enrollment requester
+/// CANNOT send its nudge itself: it is pre-enrollment, so its `local_db` is closed and it
 /// has no MLS signing credential, and a client-side device-signed send-data
 /// fails with "not signed in for DS request signing". The DS holds the LiveKit
 /// admin secret, so it emits that nudge here. Returns `Err(reason)` on any
@@ -749,26 +762,47 @@ pub struct R2PresignBody {
     pub operation: String,
     /// The R2 object key (within the bucket), e.g. `media/<hash>/<file>.enc`.
     pub key: String,
-    /// Optional content type — accepted for forward-compat; the presigned URL
-    /// signs only `host`, so the client sets Content-Type at upload time.
+    /// PUT only (required there, except from clients before the
+    /// [`crate::uploads`] cutoff): the Content-Type the upload will carry.
+    /// Checked against the key's category and signed into the URL. Ignored for
+    /// GET / DELETE.
     #[serde(default)]
     pub content_type: Option<String>,
+    /// PUT only (required there): the exact body length in bytes, checked
+    /// against the category's cap and signed into the URL.
+    #[serde(default)]
+    pub size: Option<u64>,
     /// No-auth path only — see [`resolve_user`]. Unused beyond the auth gate
     /// (presign has no per-object authz), kept for shape-symmetry with the other
     /// broker endpoint.
     #[serde(default)]
     pub user_id: Option<String>,
+    /// Attachment PUT only: the group the upload is for, whose residency policy
+    /// the key must follow ([`crate::residency`]). An attachment PUT names this
+    /// or `dm_channel_id`; only a shipped client's undeclared PUT may name
+    /// neither.
+    #[serde(default)]
+    pub group_id: Option<String>,
+    /// Attachment PUT only: the DM the upload is for. DMs aren't pinned, so the
+    /// key must be a default-store one.
+    #[serde(default)]
+    pub dm_channel_id: Option<String>,
 }
 
 /// Default presigned-URL lifetime, in seconds.
 const PRESIGN_EXPIRES_SECS: u64 = 900;
 
-/// POST /v1/r2/presign — return a SigV4 presigned URL for a GET or PUT against
-/// the configured R2 bucket. Requires an authenticated device (when auth is
+/// POST /v1/r2/presign — return a presigned URL for a GET or PUT against the
+/// configured object store (R2 or another S3 by default; see [`crate::storage`]). Requires an authenticated device (when auth is
 /// enforced, [`gate`] rejects an unsigned request with 401). There is NO
 /// per-object conversation check — see the module docs: the bucket holds only
 /// convergently-encrypted ciphertext, so the gate exists to stop anonymous
-/// access, not to enforce read authz.
+/// access, not to enforce read authz. A PUT must declare `content_type` and
+/// `size`, which have to satisfy [`crate::uploads::UploadPolicy::check_put`] and
+/// are bound into the URL. A key that names a storage target
+/// (`media@<target>/…`) is signed for that target instead, and an attachment
+/// PUT that names its group must follow the group's residency policy — see
+/// [`crate::residency`].
 pub async fn r2_presign(
     State(state): State<AppState>,
     method: Method,
@@ -795,30 +829,89 @@ pub async fn r2_presign(
     if parsed.key.trim().is_empty() {
         return Ok(bad_request("key required"));
     }
-
-    let (endpoint, bucket, access_key, secret_key) = match state.broker.r2_ready() {
-        Some(t) => t,
-        None => return Ok(not_configured("r2")),
+    let mut category = None;
+    let put = if http_method == "PUT" {
+        match (parsed.content_type.as_deref(), parsed.size) {
+            (Some(content_type), Some(size)) => {
+                match state.uploads.check_put(&parsed.key, content_type, size) {
+                    Ok(c) => category = Some(c),
+                    Err(msg) => return Ok(bad_request(&msg)),
+                }
+                Some(PutBinding { content_type, size })
+            }
+            // A client from before uploads were bound, while the operator's
+            // `UPLOAD_ALLOW_UNBOUND` window lasts: presign unbound, but still
+            // only into an upload location.
+            (None, None) if state.uploads.allows_unbound_put(now_unix()) => {
+                match UploadCategory::for_key(&parsed.key) {
+                    Some(c) => category = Some(c),
+                    None => return Ok(bad_request("key is not in an upload location")),
+                }
+                None
+            }
+            _ => return Ok(bad_request("content_type and size required for put")),
+        }
+    } else {
+        None
     };
 
     // On the no-auth path there's no signed identity; the auth gate already
-    // enforced presence when `require_auth` is on. Resolve only to validate the
+    // enforced presence when `require_auth` is on. Resolve to validate the
     // no-auth body shape (and reject an empty/absent user_id there).
-    if let Err(resp) = resolve_user(&authed, parsed.user_id.as_deref()) {
-        return Ok(resp);
+    let user_id = match resolve_user(&authed, parsed.user_id.as_deref()) {
+        Ok(u) => u,
+        Err(resp) => return Ok(resp),
+    };
+
+    let target = match residency::target_of_key(&parsed.key) {
+        Some(id) => match (UploadCategory::for_key(&parsed.key), state.storage_targets.get(id)) {
+            (Some(UploadCategory::Attachment), Some(t)) => Some(t),
+            _ => return Ok(bad_request("unknown storage target")),
+        },
+        None => None,
+    };
+    if category == Some(UploadCategory::Attachment) {
+        let conn = state.db.conn()?;
+        if let Some(resp) = residency::check_put(
+            &conn,
+            authed.as_deref(),
+            &user_id,
+            &parsed.key,
+            parsed.group_id.as_deref(),
+            parsed.dm_channel_id.as_deref(),
+            put.is_none(),
+        )
+        .await?
+        {
+            return Ok(resp);
+        }
     }
 
-    let url = presign_r2_url(
-        endpoint,
-        bucket,
-        &state.broker.r2_region,
-        access_key,
-        secret_key,
-        http_method,
-        &parsed.key,
-        PRESIGN_EXPIRES_SECS,
-        &amz_datetime(),
-    );
+    let url = match target {
+        Some(t) => t.presign(http_method, &parsed.key, PRESIGN_EXPIRES_SECS, &amz_datetime(), put),
+        None => match state.storage.presign(
+            &state.broker,
+            http_method,
+            &parsed.key,
+            PRESIGN_EXPIRES_SECS,
+            now_unix(),
+            &amz_datetime(),
+            put,
+        ) {
+            Some(url) => url,
+            None => return Ok(not_configured("r2")),
+        },
+    };
+
+    if let (Some(UploadCategory::Attachment), Some(put)) = (category, put) {
+        let delta = UsageDelta {
+            attachments_presigned: 1,
+            attachment_bytes: put.size as i64,
+            ..UsageDelta::default()
+        };
+        let conn = state.db.conn()?;
+        record_if_enabled(&state, &conn, &user_id, delta).await;
+    }
 
     Ok(ok_json(serde_json::json!({
         "url": url,
@@ -854,6 +947,29 @@ pub fn presign_r2_url(
     key: &str,
     expires: u64,
     datetime: &str,
+) -> String {
+    presign_r2_url_with_headers(
+        endpoint, bucket, region, access_key, secret_key, method, key, expires, datetime, &[],
+    )
+}
+
+/// [`presign_r2_url`], additionally signing `headers` (lowercase name, value).
+/// The request must then carry exactly those values or the store rejects the
+/// signature — how a PUT is held to its declared `content-type` and
+/// `content-length` (see [`crate::uploads`]). With no extra headers the URL is
+/// identical to [`presign_r2_url`]'s.
+#[allow(clippy::too_many_arguments)]
+pub fn presign_r2_url_with_headers(
+    endpoint: &str,
+    bucket: &str,
+    region: &str,
+    access_key: &str,
+    secret_key: &str,
+    method: &str,
+    key: &str,
+    expires: u64,
+    datetime: &str,
+    headers: &[(&str, &str)],
 ) -> String {
     let date = &datetime[..8];
     let host = host_of(endpoint);
@@ -866,6 +982,12 @@ pub fn presign_r2_url(
         uri_encode(key, false)
     );
 
+    // Signed headers, sorted by name as SigV4 requires; `host` is always one.
+    let mut signed: Vec<(&str, &str)> = vec![("host", host)];
+    signed.extend_from_slice(headers);
+    signed.sort_by(|a, b| a.0.cmp(b.0));
+    let signed_headers = signed.iter().map(|(k, _)| *k).collect::<Vec<_>>().join(";");
+
     let credential = format!("{access_key}/{date}/{region}/s3/aws4_request");
     // Canonical query: params sorted by name, values URI-encoded (the credential
     // `/`s become %2F). X-Amz-Signature is NOT part of the canonical query.
@@ -875,7 +997,7 @@ pub fn presign_r2_url(
             ("X-Amz-Credential", uri_encode(&credential, true)),
             ("X-Amz-Date", datetime.to_string()),
             ("X-Amz-Expires", expires.to_string()),
-            ("X-Amz-SignedHeaders", "host".to_string()),
+            ("X-Amz-SignedHeaders", uri_encode(&signed_headers, true)),
         ];
         params.sort_by(|a, b| a.0.cmp(b.0));
         params
@@ -885,8 +1007,10 @@ pub fn presign_r2_url(
             .join("&")
     };
 
-    let canonical_headers = format!("host:{host}\n");
-    let signed_headers = "host";
+    let canonical_headers: String = signed
+        .iter()
+        .map(|(k, v)| format!("{k}:{}\n", v.trim()))
+        .collect();
     let payload_hash = "UNSIGNED-PAYLOAD";
     let canonical_request = format!(
         "{method}\n{canonical_uri}\n{canonical_query}\n{canonical_headers}\n{signed_headers}\n{payload_hash}"
@@ -928,7 +1052,7 @@ fn host_of(url: &str) -> &str {
 /// AWS-style percent-encoding (RFC 3986). Unreserved chars pass through; when
 /// `encode_slash` is false, `/` is preserved (used for path segments). Matches
 /// the canonical encoding S3 SigV4 requires.
-fn uri_encode(s: &str, encode_slash: bool) -> String {
+pub(crate) fn uri_encode(s: &str, encode_slash: bool) -> String {
     let mut out = String::with_capacity(s.len());
     for &b in s.as_bytes() {
         let keep = b.is_ascii_alphanumeric()
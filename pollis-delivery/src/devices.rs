@@ -314,7 +314,8 @@ pub fn claim_outcome_response(outcome: ClaimOutcome) -> Response {
 /// `UPDATE … RETURNING` the client ran directly before the DS seam: it selects
 /// the OLDEST unclaimed package (`ORDER BY created_at ASC LIMIT 1`) matching the
 /// target — `user_id` only, or `user_id AND device_id` when a device is named —
-/// and flips its `claimed` flag in one statement.
+/// and flips its `claimed` flag in one statement. It also stamps `claimed_at`,
+/// which the owning device reads back to size its pool.
 ///
 /// Atomicity: the `WHERE claimed = 0` subquery is re-evaluated under the single
 /// libsql writer at statement-execution time, so two concurrent claims of the
@@ -329,7 +330,7 @@ pub async fn apply_claim_key_package(
         Some(device_id) => {
             conn.query(
                 "UPDATE mls_key_package \
-                 SET claimed = 1 \
+                 SET claimed = 1, claimed_at = datetime('now') \
                  WHERE ref_hash = ( \
                      SELECT ref_hash FROM mls_key_package \
                      WHERE user_id = ?1 AND device_id = ?2 AND claimed = 0 \
@@ -343,7 +344,7 @@ pub async fn apply_claim_key_package(
         None => {
             conn.query(
                 "UPDATE mls_key_package \
-                 SET claimed = 1 \
+                 SET claimed = 1, claimed_at = datetime('now') \
                  WHERE ref_hash = ( \
                      SELECT ref_hash FROM mls_key_package \
                      WHERE user_id = ?1 AND claimed = 0 \
@@ -15,6 +15,11 @@
 //! to equal the recorded requester. So a different signed user can never consume
 //! someone else's pending change, and the email is ALWAYS bound from the
 //! signature — never the body. The write lands on the MAIN DB (`state.db`).
+//!
+//! **Store:** in memory by default, like the signup OTP store. With
+//! `DS_SHARED_STATE=db` the codes go to `ds_otp` under the `email_change`
+//! scope and the requester bindings to `ds_email_change` (migration 000027) —
+//! see [`EmailChangeStore::shared`].
 
 use std::collections::HashMap;
 use std::sync::{Arc, Mutex};
@@ -29,6 +34,7 @@ use axum::{
 };
 use serde::Deserialize;
 
+use crate::db::Db;
 use crate::error::{AppError, AuthRejection};
 use crate::otp::{normalize_email, process_request_otp, OtpConfig, OtpStore, VerifyOutcome};
 use crate::writes::{bad_request, gate};
@@ -48,15 +54,42 @@ pub struct EmailChangeStore {
     /// normalized `new_email` → the device-signed `user_id` that requested it.
     /// Keyed identically to the OTP store so request/verify always agree.
     requesters: Arc<Mutex<HashMap<String, String>>>,
+    /// `Some` → bindings live in `ds_email_change`; `requesters` is only the
+    /// fallback while the DB is unreachable.
+    shared: Option<Arc<Db>>,
 }
 
+/// How long a shared requester binding outlives its request. Far past any
+/// code's TTL, so a binding is never pruned while its code can still verify.
+const SHARED_BINDING_SECS: u64 = 24 * 60 * 60;
+
 impl EmailChangeStore {
+    /// A store whose codes and bindings live in `db`, shared by every DS
+    /// instance on that database.
+    pub fn shared(db: Arc<Db>) -> Self {
+        Self {
+            otp: OtpStore::shared(Arc::clone(&db), "email_change"),
+            shared: Some(db),
+            ..Self::default()
+        }
+    }
+
     /// Record `requester` as the one asking to change to `new_email`, then
     /// prepare + send the OTP (reusing [`process_request_otp`] — DEV_OTP / Resend
     /// / throttle all honored). The binding is overwritten on each request so the
     /// latest requester wins.
     pub async fn request(&self, cfg: &OtpConfig, requester: &str, new_email: &str) {
-        {
+        let bound = match &self.shared {
+            Some(db) => match shared_bind(db, requester, new_email, now_unix()).await {
+                Ok(()) => true,
+                Err(e) => {
+                    tracing::warn!("shared email-change store unavailable, binding locally: {e:#}");
+                    false
+                }
+            },
+            None => false,
+        };
+        if !bound {
             let mut g = self
                 .requesters
                 .lock()
@@ -67,7 +100,14 @@ impl EmailChangeStore {
     }
 
     /// The recorded requester for `new_email`, if any.
-    fn requester_of(&self, new_email: &str) -> Option<String> {
+    async fn requester_of(&self, new_email: &str) -> Option<String> {
+        if let Some(db) = &self.shared {
+            match shared_requester(db, new_email).await {
+                Ok(Some(r)) => return Some(r),
+                Ok(None) => {}
+                Err(e) => tracing::warn!("email-change requester lookup: {e:#}"),
+            }
+        }
         self.requesters
             .lock()
             .expect("email-change requesters mutex poisoned")
@@ -77,7 +117,12 @@ impl EmailChangeStore {
 
     /// Drop the requester binding for `new_email` (after the OTP is consumed or
     /// the change is finalized/refused).
-    fn clear(&self, new_email: &str) {
+    async fn clear(&self, new_email: &str) {
+        if let Some(db) = &self.shared {
+            if let Err(e) = shared_clear(db, new_email).await {
+                tracing::warn!("email-change clear: {e:#}");
+            }
+        }
         self.requesters
             .lock()
             .expect("email-change requesters mutex poisoned")
@@ -85,6 +130,53 @@ impl EmailChangeStore {
     }
 }
 
+/// Record `requester` for `new_email` in `ds_email_change` (latest wins), and
+/// drop bindings nobody finished within [`SHARED_BINDING_SECS`].
+async fn shared_bind(db: &Db, requester: &str, new_email: &str, now: u64) -> anyhow::Result<()> {
+    let conn = db.conn()?;
+    conn.execute(
+        "INSERT INTO ds_email_change (email, requester, requested_at) VALUES (?1, ?2, ?3) \
+         ON CONFLICT(email) DO UPDATE SET \
+             requester = excluded.requester, requested_at = excluded.requested_at",
+        libsql::params![
+            normalize_email(new_email),
+            requester.to_string(),
+            now as i64
+        ],
+    )
+    .await?;
+    conn.execute(
+        "DELETE FROM ds_email_change WHERE requested_at < ?1",
+        libsql::params![now.saturating_sub(SHARED_BINDING_SECS) as i64],
+    )
+    .await?;
+    Ok(())
+}
+
+async fn shared_requester(db: &Db, new_email: &str) -> anyhow::Result<Option<String>> {
+    let mut rows = db
+        .conn()?
+        .query(
+            "SELECT requester FROM ds_email_change WHERE email = ?1",
+            libsql::params![normalize_email(new_email)],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) => Some(row.get(0)?),
+        None => None,
+    })
+}
+
+async fn shared_clear(db: &Db, new_email: &str) -> anyhow::Result<()> {
+    db.conn()?
+        .execute(
+            "DELETE FROM ds_email_change WHERE email = ?1",
+            libsql::params![normalize_email(new_email)],
+        )
+        .await?;
+    Ok(())
+}
+
 fn now_unix() -> u64 {
     SystemTime::now()
         .duration_since(UNIX_EPOCH)
@@ -231,7 +323,7 @@ pub async fn apply_verify_email_change(
     // Binding gate FIRST — the device-signed caller MUST be the one who requested
     // this change. Checked before the OTP so a different user can't even burn the
     // attempt counter (or learn anything) against someone else's pending change.
-    match store.requester_of(trimmed) {
+    match store.requester_of(trimmed).await {
         Some(r) if r == authed => {}
         _ => return Ok(EmailChangeOutcome::Mismatch),
     }
@@ -240,12 +332,16 @@ pub async fn apply_verify_email_change(
     // the `users.email` write below succeeds, so a transient/config DB failure
     // returns a clean 5xx and the same code still works on retry instead of being
     // burned and disguised as "invalid code" (#518). Wrong-guess accounting stands.
-    match store.otp.check(trimmed, code, cfg.max_attempts, now_unix()) {
+    match store
+        .otp
+        .check(trimmed, code, cfg.max_attempts, now_unix())
+        .await
+    {
         VerifyOutcome::Ok => {}
         VerifyOutcome::LockedOut => {
             // check() already deleted the code on lockout; drop the binding too so a
             // retry must re-request.
-            store.clear(trimmed);
+            store.clear(trimmed).await;
             return Ok(EmailChangeOutcome::LockedOut);
         }
         VerifyOutcome::Invalid | VerifyOutcome::Expired | VerifyOutcome::NotFound => {
@@ -266,8 +362,8 @@ pub async fn apply_verify_email_change(
     if rows.next().await?.is_some() {
         // Correct code, but the target email is taken. Consume it (the binding is
         // dropped, so a retry must re-request anyway) and report the conflict.
-        store.otp.consume(trimmed);
-        store.clear(trimmed);
+        store.otp.consume(trimmed).await;
+        store.clear(trimmed).await;
         return Ok(EmailChangeOutcome::EmailTaken);
     }
 
@@ -277,8 +373,8 @@ pub async fn apply_verify_email_change(
     )
     .await?;
     // Applied: consume the code (single-use) now that the write has succeeded.
-    store.otp.consume(trimmed);
-    store.clear(trimmed);
+    store.otp.consume(trimmed).await;
+    store.clear(trimmed).await;
     Ok(EmailChangeOutcome::Updated)
 }
 
@@ -0,0 +1,600 @@
+//! Email invites — inviting someone to a group before they have an account.
+//!
+//! `send_group_invite` on the client resolves a username or email to a user.
+//! When an email matches nobody, it calls `POST /v1/email-invites/create`
+//! instead, and the DS:
+//!
+//!   1. checks the inviter is a group admin (re-derived), the address has no
+//!      account and hasn't opted out, and the inviter is under
+//!      [`EmailInviteConfig::daily_max`] email invites in the last 24h;
+//!   2. stores an `email_invite` row (migration 000019) and emails the address
+//!      a link carrying `<invite id>.<signature>`, plus a signed opt-out link.
+//!
+//! The invite applies itself on signup: when verify-otp creates an account, every
+//! row for that address becomes an ordinary pending `group_invite`
+//! ([`apply_pending_for_new_account`]), which the app already lists. Someone who
+//! signed up under a different address pastes the link's token into the app
+//! instead (`POST /v1/email-invites/redeem`). Rows older than
+//! [`INVITE_TTL_DAYS`] are ignored and deleted on the next create.
+//!
+//! An opted-out address is stored only as a SHA-256 hash. A create for it still
+//! answers 200 and sends nothing, so an admin can't probe who opted out.
+//!
+//! Off until both `RESEND_API_KEY` and `EMAIL_INVITE_LINK_KEY` are set: create
+//! 503s without them.
+
+use axum::{
+    body::Bytes,
+    extract::{Query, State},
+    http::{header, HeaderMap, Method, StatusCode, Uri},
+    response::{IntoResponse, Response},
+    Json,
+};
+use base64::Engine as _;
+use hmac::{Hmac, Mac};
+use libsql::Connection;
+use serde::Deserialize;
+use sha2::{Digest, Sha256};
+
+use crate::error::AppError;
+use crate::otp::{normalize_email, send_email};
+use crate::redact::mask_email;
+use crate::writes::{bad_request, gate, ok_json, outcome_response, resolve_actor, WriteOutcome};
+use crate::AppState;
+
+/// Days an unanswered email invite stays redeemable.
+pub const INVITE_TTL_DAYS: u32 = 30;
+
+/// Email-invite settings, read from DS env by [`EmailInviteConfig::from_env`].
+#[derive(Clone)]
+pub struct EmailInviteConfig {
+    /// Resend key for the invite email. NEVER logged.
+    pub resend_api_key: Option<String>,
+    /// HMAC key signing invite and opt-out links. NEVER logged.
+    pub link_key: Option<String>,
+    /// Where the invite link points; the token is appended as `?token=`.
+    pub link_base: String,
+    /// Public DS origin for the opt-out link.
+    pub public_url: String,
+    /// Email invites one user may send per rolling 24h.
+    pub daily_max: u32,
+}
+
+impl Default for EmailInviteConfig {
+    fn default() -> Self {
+        Self {
+            resend_api_key: None,
+            link_key: None,
+            link_base: "https://pollis.com/invite".to_string(),
+            public_url: "https://ds.pollis.com".to_string(),
+            daily_max: 20,
+        }
+    }
+}
+
+impl EmailInviteConfig {
+    /// Build from DS environment. Env: the shared `RESEND_API_KEY`,
+    /// `EMAIL_INVITE_LINK_KEY`, `EMAIL_INVITE_LINK_BASE`, `DS_PUBLIC_URL`,
+    /// `EMAIL_INVITE_DAILY_MAX`.
+    pub fn from_env() -> Self {
+        let var = |k: &str| std::env::var(k).ok().filter(|s| !s.is_empty());
+        let mut cfg = Self {
+            resend_api_key: var("RESEND_API_KEY"),
+            link_key: var("EMAIL_INVITE_LINK_KEY"),
+            ..Self::default()
+        };
+        if let Some(v) = var("EMAIL_INVITE_LINK_BASE") {
+            cfg.link_base = v;
+        }
+        if let Some(v) = var("DS_PUBLIC_URL") {
+            cfg.public_url = v.trim_end_matches('/').to_string();
+        }
+        if let Some(v) = var("EMAIL_INVITE_DAILY_MAX").and_then(|s| s.parse().ok()) {
+            cfg.daily_max = v;
+        }
+        cfg
+    }
+}
+
+// ── Link signing ─────────────────────────────────────────────────────────────
+
+/// URL-safe HMAC-SHA256 of `purpose:value` under `key`.
+fn sign(key: &str, purpose: &str, value: &str) -> String {
+    let mut mac =
+        Hmac::<Sha256>::new_from_slice(key.as_bytes()).expect("hmac accepts any key length");
+    mac.update(purpose.as_bytes());
+    mac.update(b":");
+    mac.update(value.as_bytes());
+    base64::engine::general_purpose::URL_SAFE_NO_PAD.encode(mac.finalize().into_bytes())
+}
+
+/// Constant-time check of a signature produced by [`sign`].
+fn verify(key: &str, purpose: &str, value: &str, sig: &str) -> bool {
+    let Ok(raw) = base64::engine::general_purpose::URL_SAFE_NO_PAD.decode(sig) else {
+        return false;
+    };
+    let mut mac =
+        Hmac::<Sha256>::new_from_slice(key.as_bytes()).expect("hmac accepts any key length");
+    mac.update(purpose.as_bytes());
+    mac.update(b":");
+    mac.update(value.as_bytes());
+    mac.verify_slice(&raw).is_ok()
+}
+
+/// `<invite id>.<signature>` — what the invite link carries.
+pub fn invite_token(key: &str, invite_id: &str) -> String {
+    format!("{invite_id}.{}", sign(key, "invite", invite_id))
+}
+
+/// The invite id inside a token, if its signature holds.
+pub fn parse_invite_token(key: &str, token: &str) -> Option<String> {
+    let (id, sig) = token.trim().split_once('.')?;
+    verify(key, "invite", id, sig).then(|| id.to_string())
+}
+
+fn email_hash(email: &str) -> String {
+    let digest = Sha256::digest(email.as_bytes());
+    digest.iter().map(|b| format!("{b:02x}")).collect()
+}
+
+/// Body of the invite email.
+fn invite_text(inviter: &str, group: &str, link: &str, opt_out: &str) -> String {
+    format!(
+        "{inviter} invited you to join \"{group}\" on Pollis, an end-to-end encrypted \
+         messenger.\n\n\
+         Install Pollis and sign in with this email address, and the invite will be \
+         waiting for you. If you sign in with a different address, open this link or \
+         paste it into Find Group:\n\n{link}\n\n\
+         The invite expires in {INVITE_TTL_DAYS} days.\n\n\
+         Don't want invites like this? Stop all Pollis invite emails to this address:\n{opt_out}\n"
+    )
+}
+
+// ── POST /v1/email-invites/create ────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct CreateEmailInviteBody {
+    pub id: String,
+    pub group_id: String,
+    pub email: String,
+    /// The inviter; bound to the authenticated user when signed.
+    #[serde(default)]
+    pub inviter_id: Option<String>,
+}
+
+/// Result of [`apply_create_email_invite`].
+pub enum EmailInviteOutcome {
+    /// Row stored; the handler sends the email.
+    Created {
+        inviter_name: String,
+        group_name: String,
+    },
+    /// Opted out: nothing stored or sent, but answered like `Created`.
+    Suppressed,
+    /// The address already has an account — invite them by username.
+    HasAccount,
+    /// This group already has a live email invite for the address.
+    AlreadyInvited,
+    RateLimited,
+    Forbidden,
+}
+
+pub async fn create(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let config = &state.email_invites;
+    let (Some(resend_key), Some(link_key)) = (&config.resend_api_key, &config.link_key) else {
+        return Ok(unavailable());
+    };
+    let parsed: CreateEmailInviteBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let email = normalize_email(&parsed.email);
+    if !looks_like_email(&email) {
+        return Ok(bad_request("invalid email"));
+    }
+
+    let conn = state.db.conn()?;
+    let (inviter_name, group_name) =
+        match apply_create_email_invite(&conn, authed.as_deref(), &parsed, config.daily_max).await?
+        {
+            EmailInviteOutcome::Created {
+                inviter_name,
+                group_name,
+            } => (inviter_name, group_name),
+            EmailInviteOutcome::Suppressed => return outcome_response(WriteOutcome::Ok),
+            EmailInviteOutcome::HasAccount => {
+                return Ok(conflict("that address already has an account"))
+            }
+            EmailInviteOutcome::AlreadyInvited => {
+                return Ok(conflict("that address already has a pending invite"))
+            }
+            EmailInviteOutcome::RateLimited => {
+                return Ok((
+                    StatusCode::TOO_MANY_REQUESTS,
+                    Json(serde_json::json!({ "error": "too many email invites today" })),
+                )
+                    .into_response())
+            }
+            EmailInviteOutcome::Forbidden => return outcome_response(WriteOutcome::Forbidden),
+        };
+
+    let link = format!(
+        "{}?token={}",
+        config.link_base,
+        invite_token(link_key, &parsed.id)
+    );
+    let opt_out = format!(
+        "{}/v1/email-invites/opt-out?email={}&sig={}",
+        config.public_url,
+        percent_encode(&email),
+        sign(link_key, "opt-out", &email)
+    );
+    let text = invite_text(&inviter_name, &group_name, &link, &opt_out);
+    let subject = format!("{inviter_name} invited you to {group_name} on Pollis");
+    if let Err(e) = send_email(resend_key, &email, &subject, &text).await {
+        tracing::error!("email invite to {} failed: {e:#}", mask_email(&email));
+        // Nothing was delivered, so don't leave a row that blocks a retry.
+        conn.execute(
+            "DELETE FROM email_invite WHERE id = ?1",
+            libsql::params![parsed.id.clone()],
+        )
+        .await?;
+        return Ok((
+            StatusCode::BAD_GATEWAY,
+            Json(serde_json::json!({ "error": "invite email could not be sent" })),
+        )
+            .into_response());
+    }
+    outcome_response(WriteOutcome::Ok)
+}
+
+/// Authorize and store an email invite. Authz: the inviter is a re-derived
+/// group admin. Expired rows are swept first so they neither block a re-invite
+/// nor count toward the rate limit.
+pub async fn apply_create_email_invite(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &CreateEmailInviteBody,
+    daily_max: u32,
+) -> anyhow::Result<EmailInviteOutcome> {
+    let inviter = match resolve_actor(authed, body.inviter_id.as_deref()) {
+        Ok(i) => i,
+        Err(_) => return Ok(EmailInviteOutcome::Forbidden),
+    };
+    if authed.is_some() && !crate::groups::is_admin(conn, &body.group_id, &inviter).await? {
+        return Ok(EmailInviteOutcome::Forbidden);
+    }
+    let email = normalize_email(&body.email);
+
+    let mut rows = conn
+        .query(
+            "SELECT 1 FROM users WHERE lower(email) = ?1",
+            libsql::params![email.clone()],
+        )
+        .await?;
+    if rows.next().await?.is_some() {
+        return Ok(EmailInviteOutcome::HasAccount);
+    }
+    drop(rows);
+
+    let mut rows = conn
+        .query(
+            "SELECT 1 FROM email_invite_opt_out WHERE email_hash = ?1",
+            libsql::params![email_hash(&email)],
+        )
+        .await?;
+    if rows.next().await?.is_some() {
+        return Ok(EmailInviteOutcome::Suppressed);
+    }
+    drop(rows);
+
+    conn.execute(
+        "DELETE FROM email_invite WHERE created_at < datetime('now', ?1)",
+        libsql::params![format!("-{INVITE_TTL_DAYS} days")],
+    )
+    .await?;
+
+    let mut rows = conn
+        .query(
+            "SELECT COUNT(*) FROM email_invite \
+             WHERE inviter_id = ?1 AND created_at > datetime('now', '-1 day')",
+            libsql::params![inviter.clone()],
+        )
+        .await?;
+    let sent_today: i64 = match rows.next().await? {
+        Some(row) => row.get(0)?,
+        None => 0,
+    };
+    drop(rows);
+    if sent_today >= i64::from(daily_max) {
+        return Ok(EmailInviteOutcome::RateLimited);
+    }
+
+    let affected = conn
+        .execute(
+            "INSERT INTO email_invite (id, group_id, inviter_id, email) VALUES (?1, ?2, ?3, ?4) \
+             ON CONFLICT(group_id, email) DO NOTHING",
+            libsql::params![
+                body.id.clone(),
+                body.group_id.clone(),
+                inviter.clone(),
+                email
+            ],
+        )
+        .await?;
+    if affected == 0 {
+        return Ok(EmailInviteOutcome::AlreadyInvited);
+    }
+
+    let mut rows = conn
+        .query(
+            "SELECT u.username, g.name FROM users u, groups g WHERE u.id = ?1 AND g.id = ?2",
+            libsql::params![inviter, body.group_id.clone()],
+        )
+        .await?;
+    let (inviter_name, group_name) = match rows.next().await? {
+        Some(row) => (row.get(0)?, row.get(1)?),
+        None => ("Someone".to_string(), "a group".to_string()),
+    };
+    Ok(EmailInviteOutcome::Created {
+        inviter_name,
+        group_name,
+    })
+}
+
+// ── Signup conversion ────────────────────────────────────────────────────────
+
+/// Turn every live email invite for `email` into a pending `group_invite` for
+/// the account just created for it. Called by verify-otp on a new account.
+pub async fn apply_pending_for_new_account(
+    conn: &Connection,
+    user_id: &str,
+    email: &str,
+) -> anyhow::Result<u64> {
+    let email = normalize_email(email);
+    let tx = conn.transaction().await?;
+    let converted = tx
+        .execute(
+            "INSERT OR IGNORE INTO group_invite (id, group_id, inviter_id, invitee_id, created_at) \
+             SELECT id, group_id, inviter_id, ?1, created_at FROM email_invite \
+             WHERE email = ?2 AND created_at > datetime('now', ?3)",
+            libsql::params![
+                user_id.to_string(),
+                email.clone(),
+                format!("-{INVITE_TTL_DAYS} days")
+            ],
+        )
+        .await?;
+    tx.execute(
+        "DELETE FROM email_invite WHERE email = ?1",
+        libsql::params![email],
+    )
+    .await?;
+    tx.commit().await?;
+    Ok(converted)
+}
+
+// ── POST /v1/email-invites/redeem ────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct RedeemEmailInviteBody {
+    /// `<invite id>.<signature>` from the invite link.
+    pub token: String,
+    /// The redeemer; bound to the authenticated user when signed.
+    #[serde(default)]
+    pub user_id: Option<String>,
+}
+
+/// POST /v1/email-invites/redeem — turn the email invite a link points at into
+/// a pending `group_invite` for the actor. 404 for a bad, expired or already
+/// used token.
+pub async fn redeem(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let Some(link_key) = &state.email_invites.link_key else {
+        return Ok(unavailable());
+    };
+    let parsed: RedeemEmailInviteBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let actor = match resolve_actor(authed.as_deref(), parsed.user_id.as_deref()) {
+        Ok(a) => a,
+        Err(o) => return outcome_response(o),
+    };
+    let Some(invite_id) = parse_invite_token(link_key, &parsed.token) else {
+        return Ok(not_found());
+    };
+    let conn = state.db.conn()?;
+    Ok(match apply_redeem(&conn, &invite_id, &actor).await? {
+        Some(group_id) => ok_json(serde_json::json!({ "group_id": group_id })),
+        None => not_found(),
+    })
+}
+
+/// Move one live email invite to a pending `group_invite` for `user_id`.
+/// Returns its group, or `None` when there is no such live invite.
+pub async fn apply_redeem(
+    conn: &Connection,
+    invite_id: &str,
+    user_id: &str,
+) -> anyhow::Result<Option<String>> {
+    let tx = conn.transaction().await?;
+    let mut rows = tx
+        .query(
+            "SELECT group_id FROM email_invite WHERE id = ?1 AND created_at > datetime('now', ?2)",
+            libsql::params![invite_id.to_string(), format!("-{INVITE_TTL_DAYS} days")],
+        )
+        .await?;
+    let Some(row) = rows.next().await? else {
+        return Ok(None);
+    };
+    let group_id: String = row.get(0)?;
+    drop(rows);
+    tx.execute(
+        "INSERT OR IGNORE INTO group_invite (id, group_id, inviter_id, invitee_id, created_at) \
+         SELECT id, group_id, inviter_id, ?2, created_at FROM email_invite WHERE id = ?1",
+        libsql::params![invite_id.to_string(), user_id.to_string()],
+    )
+    .await?;
+    tx.execute(
+        "DELETE FROM email_invite WHERE id = ?1",
+        libsql::params![invite_id.to_string()],
+    )
+    .await?;
+    tx.commit().await?;
+    Ok(Some(group_id))
+}
+
+// ── GET /v1/email-invites/opt-out ────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct OptOutQuery {
+    pub email: String,
+    pub sig: String,
+}
+
+/// GET /v1/email-invites/opt-out?email=…&sig=… — the link at the foot of every
+/// invite email. Records the address's hash and drops its pending invites.
+/// Plain text, because it opens in a browser.
+pub async fn opt_out(
+    State(state): State<AppState>,
+    Query(query): Query<OptOutQuery>,
+) -> Result<Response, AppError> {
+    let Some(link_key) = &state.email_invites.link_key else {
+        return Ok(unavailable());
+    };
+    let email = normalize_email(&query.email);
+    if !verify(link_key, "opt-out", &email, &query.sig) {
+        return Ok(bad_request("invalid link"));
+    }
+    let conn = state.db.conn()?;
+    apply_opt_out(&conn, &email).await?;
+    Ok((
+        StatusCode::OK,
+        [(header::CONTENT_TYPE, "text/plain; charset=utf-8")],
+        "You won't get any more Pollis invite emails at this address.\n",
+    )
+        .into_response())
+}
+
+/// Record `email`'s opt-out and delete its pending email invites.
+pub async fn apply_opt_out(conn: &Connection, email: &str) -> anyhow::Result<()> {
+    let email = normalize_email(email);
+    conn.execute(
+        "INSERT OR IGNORE INTO email_invite_opt_out (email_hash) VALUES (?1)",
+        libsql::params![email_hash(&email)],
+    )
+    .await?;
+    conn.execute(
+        "DELETE FROM email_invite WHERE email = ?1",
+        libsql::params![email],
+    )
+    .await?;
+    Ok(())
+}
+
+// ── small helpers ────────────────────────────────────────────────────────────
+
+fn looks_like_email(email: &str) -> bool {
+    match email.split_once('@') {
+        Some((local, domain)) => {
+            !local.is_empty()
+                && domain.contains('.')
+                && !domain.starts_with('.')
+                && !domain.ends_with('.')
+                && !email.chars().any(|c| c.is_whitespace() || c.is_control())
+        }
+        None => false,
+    }
+}
+
+fn percent_encode(s: &str) -> String {
+    s.bytes()
+        .map(|b| match b {
+            b'A'..=b'Z' | b'a'..=b'z' | b'0'..=b'9' | b'-' | b'.' | b'_' | b'~' => {
+                (b as char).to_string()
+            }
+            _ => format!("%{b:02X}"),
+        })
+        .collect()
+}
+
+fn conflict(msg: &str) -> Response {
+    (
+        StatusCode::CONFLICT,
+        Json(serde_json::json!({ "status": "conflict", "error": msg })),
+    )
+        .into_response()
+}
+
+fn not_found() -> Response {
+    (
+        StatusCode::NOT_FOUND,
+        Json(serde_json::json!({ "error": "invite not found or expired" })),
+    )
+        .into_response()
+}
+
+fn unavailable() -> Response {
+    (
+        StatusCode::SERVICE_UNAVAILABLE,
+        Json(serde_json::json!({ "error": "email invites not configured" })),
+    )
+        .into_response()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn invite_tokens_round_trip_and_reject_tampering() {
+        let token = invite_token("k", "01INVITE");
+        assert_eq!(parse_invite_token("k", &token).as_deref(), Some("01INVITE"));
+        assert_eq!(parse_invite_token("other", &token), None);
+        let forged = token.replacen("01INVITE", "01OTHER", 1);
+        assert_eq!(parse_invite_token("k", &forged), None);
+        assert_eq!(parse_invite_token("k", "no-dot"), None);
+    }
+
+    #[test]
+    fn opt_out_signature_is_bound_to_the_address() {
+        let sig = sign("k", "opt-out", "a@x.com");
+        assert!(verify("k", "opt-out", "a@x.com", &sig));
+        assert!(!verify("k", "opt-out", "b@x.com", &sig));
+        // An invite signature is not an opt-out signature.
+        assert!(!verify(
+            "k",
+            "opt-out",
+            "a@x.com",
+            &sign("k", "invite", "a@x.com")
+        ));
+    }
+
+    #[test]
+    fn email_shape_check() {
+        assert!(looks_like_email("a@x.com"));
+        assert!(!looks_like_email("alice"));
+        assert!(!looks_like_email("a@localhost"));
+        assert!(!looks_like_email("a b@x.com"));
+    }
+}
@@ -0,0 +1,405 @@
+//! Flood detection on message sends, with a temporary mute.
+//!
+//! The per-IP `write` tier in [`crate::ratelimit`] is a coarse backstop: one
+//! client on one IP can still pour a thousand envelopes a minute into a single
+//! conversation. This adds two per-*sender* heuristics on
+//! `POST /v1/messages/send`:
+//!
+//!   - **rate** — more than `per_target_max` sends to one conversation within
+//!     `per_target_window_secs`;
+//!   - **duplicate** — the same ciphertext submitted more than `duplicate_max`
+//!     times within `duplicate_window_secs`. An honest MLS client never
+//!     produces the same ciphertext twice (every send has a fresh nonce and
+//!     generation), so a repeat is a replayed envelope, not a repeated message.
+//!
+//! Tripping either mutes the sender for `mute_secs`: every send (and edit)
+//! from them is refused with `429 {"error":"FLOOD_DETECTED","reason",
+//! "retry_after"}` plus a `Retry-After` header until the mute lapses. The
+//! sender is the *authenticated* user, so sealed sends (whose stored
+//! `sender_id` is a blinded sentinel) are still attributed; on the no-auth
+//! path it's the body's `sender_id`.
+//!
+//! **Incidents:** each trip (not each refused send while muted) is written to
+//! the `flood_incident` table, best-effort — a failed insert is logged and the
+//! 429 still goes out. Rows older than 30 days are pruned on every insert.
+//! `scripts/db-usage.sh` lists recent incidents for operators.
+//!
+//! **Store:** in memory by default, like the rest of the DS's throttling
+//! state, so counters and mutes reset on restart. With `DS_SHARED_STATE=db`
+//! ([`FloodDetector::shared`]) the counters go through the shared
+//! [`RateLimiter`] and a mute is also honored from the sender's latest
+//! `flood_incident` row, so a muted sender can't just land on another instance.
+
+use std::collections::HashMap;
+use std::sync::{Arc, Mutex};
+
+use axum::{
+    http::{header, StatusCode},
+    response::{IntoResponse, Response},
+    Json,
+};
+use libsql::Connection;
+use sha2::{Digest, Sha256};
+use ulid::Ulid;
+
+use crate::db::Db;
+use crate::ratelimit::{RateLimitOutcome, RateLimiter};
+
+/// Flood-detection tunables, read from DS env by [`FloodConfig::from_env`].
+/// A `0` max disables that heuristic.
+#[derive(Clone, Debug)]
+pub struct FloodConfig {
+    /// Max sends by one user to one conversation per window.
+    pub per_target_max: u32,
+    /// Per-conversation window length, seconds.
+    pub per_target_window_secs: u64,
+    /// Max submissions of one identical ciphertext by one user per window.
+    pub duplicate_max: u32,
+    /// Duplicate window length, seconds.
+    pub duplicate_window_secs: u64,
+    /// How long a tripped sender stays muted, seconds (at least 1).
+    pub mute_secs: u64,
+}
+
+impl Default for FloodConfig {
+    fn default() -> Self {
+        // Two messages a second for a full minute is past any human typing
+        // pace; a legitimate retry resubmits an envelope once or twice.
+        Self {
+            per_target_max: 120,
+            per_target_window_secs: 60,
+            duplicate_max: 3,
+            duplicate_window_secs: 60,
+            mute_secs: 300,
+        }
+    }
+}
+
+impl FloodConfig {
+    /// Build from DS environment, falling back to [`Default`] per field. Env:
+    /// `FLOOD_PER_TARGET_MAX`, `FLOOD_PER_TARGET_WINDOW_SECS`,
+    /// `FLOOD_DUPLICATE_MAX`, `FLOOD_DUPLICATE_WINDOW_SECS`, `FLOOD_MUTE_SECS`.
+    pub fn from_env() -> Self {
+        let mut cfg = Self::default();
+        if let Some(v) = env_parse("FLOOD_PER_TARGET_MAX") {
+            cfg.per_target_max = v;
+        }
+        if let Some(v) = env_parse("FLOOD_PER_TARGET_WINDOW_SECS") {
+            cfg.per_target_window_secs = v;
+        }
+        if let Some(v) = env_parse("FLOOD_DUPLICATE_MAX") {
+            cfg.duplicate_max = v;
+        }
+        if let Some(v) = env_parse("FLOOD_DUPLICATE_WINDOW_SECS") {
+            cfg.duplicate_window_secs = v;
+        }
+        if let Some(v) = env_parse::<u64>("FLOOD_MUTE_SECS") {
+            cfg.mute_secs = v.max(1);
+        }
+        cfg
+    }
+}
+
+fn env_parse<T: std::str::FromStr>(key: &str) -> Option<T> {
+    std::env::var(key).ok().and_then(|s| s.parse().ok())
+}
+
+/// Which heuristic tripped.
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+pub enum FloodReason {
+    Rate,
+    Duplicate,
+}
+
+impl FloodReason {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            FloodReason::Rate => "rate",
+            FloodReason::Duplicate => "duplicate",
+        }
+    }
+}
+
+/// The outcome of a flood check.
+#[derive(Debug, PartialEq, Eq)]
+pub enum FloodOutcome {
+    Allowed,
+    /// This send tripped a heuristic; the sender is now muted for
+    /// `retry_after` seconds. The caller records an incident and 429s.
+    Detected {
+        reason: FloodReason,
+        retry_after: u64,
+    },
+    /// The sender is already muted; the caller just 429s.
+    Muted {
+        retry_after: u64,
+    },
+}
+
+/// Above this many muted senders, a check drops lapsed mutes first.
+const PRUNE_THRESHOLD: usize = 10_000;
+
+/// Flood detector. `Clone` is shallow (shared `Arc`s) so it rides on the
+/// `Clone` `AppState`.
+#[derive(Clone, Default)]
+pub struct FloodDetector {
+    counters: RateLimiter,
+    /// Sender → unix second their mute lapses.
+    muted: Arc<Mutex<HashMap<String, u64>>>,
+    /// `Some` → also honor mutes recorded by other instances.
+    shared: Option<Arc<Db>>,
+}
+
+impl FloodDetector {
+    /// A detector whose counters and mutes are shared through `db` by every DS
+    /// instance on it.
+    pub fn shared(db: Arc<Db>) -> Self {
+        Self {
+            counters: RateLimiter::shared(Arc::clone(&db)),
+            muted: Arc::default(),
+            shared: Some(db),
+        }
+    }
+
+    /// `Some(seconds left)` while `sender` is muted. Doesn't count a hit — for
+    /// writes (edits) that honor the mute without feeding the heuristics.
+    pub async fn muted_for(&self, sender: &str, now: u64) -> Option<u64> {
+        if let Some(left) = self.muted_locally(sender, now) {
+            return Some(left);
+        }
+        let db = self.shared.as_ref()?;
+        match shared_mute_until(db, sender, now).await {
+            Ok(Some(until)) => {
+                self.muted
+                    .lock()
+                    .expect("flood mutex poisoned")
+                    .insert(sender.to_string(), until);
+                Some(until - now)
+            }
+            Ok(None) => None,
+            Err(e) => {
+                tracing::warn!("shared flood mute lookup: {e:#}");
+                None
+            }
+        }
+    }
+
+    fn muted_locally(&self, sender: &str, now: u64) -> Option<u64> {
+        let mut muted = self.muted.lock().expect("flood mutex poisoned");
+        match muted.get(sender) {
+            Some(&until) if now < until => Some(until - now),
+            Some(_) => {
+                muted.remove(sender);
+                None
+            }
+            None => None,
+        }
+    }
+
+    /// Record one send by `sender` to `conversation_id` and decide whether it
+    /// goes through.
+    pub async fn check_send(
+        &self,
+        cfg: &FloodConfig,
+        sender: &str,
+        conversation_id: &str,
+        ciphertext: &str,
+        now: u64,
+    ) -> FloodOutcome {
+        if let Some(retry_after) = self.muted_for(sender, now).await {
+            return FloodOutcome::Muted { retry_after };
+        }
+
+        let reason = if self
+            .over(
+                format!("target:{sender}:{conversation_id}"),
+                cfg.per_target_max,
+                cfg.per_target_window_secs,
+                now,
+            )
+            .await
+        {
+            FloodReason::Rate
+        } else if self
+            .over(
+                format!("dup:{sender}:{}", digest(ciphertext)),
+                cfg.duplicate_max,
+                cfg.duplicate_window_secs,
+                now,
+            )
+            .await
+        {
+            FloodReason::Duplicate
+        } else {
+            return FloodOutcome::Allowed;
+        };
+
+        let mut muted = self.muted.lock().expect("flood mutex poisoned");
+        if muted.len() > PRUNE_THRESHOLD {
+            muted.retain(|_, until| now < *until);
+        }
+        muted.insert(sender.to_string(), now + cfg.mute_secs);
+        FloodOutcome::Detected {
+            reason,
+            retry_after: cfg.mute_secs,
+        }
+    }
+
+    /// Count one hit on `key`; `true` once it's past `max`. A `0` max never is.
+    async fn over(&self, key: String, max: u32, window: u64, now: u64) -> bool {
+        max > 0 && self.counters.hit(&key, max, window, now).await == RateLimitOutcome::Limited
+    }
+}
+
+/// The unix second `sender`'s latest recorded mute lapses, if it hasn't yet.
+async fn shared_mute_until(db: &Db, sender: &str, now: u64) -> anyhow::Result<Option<u64>> {
+    let conn = db.conn()?;
+    let mut rows = conn
+        .query(
+            "SELECT CAST(strftime('%s', MAX(muted_until)) AS INTEGER) FROM flood_incident \
+             WHERE user_id = ?1 AND muted_until > datetime(?2, 'unixepoch')",
+            libsql::params![sender.to_string(), now as i64],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) => row
+            .get::<Option<i64>>(0)?
+            .map(|t| t as u64)
+            .filter(|&t| t > now),
+        None => None,
+    })
+}
+
+/// Hex SHA-256 — keys the duplicate counter without holding ciphertexts.
+fn digest(ciphertext: &str) -> String {
+    Sha256::digest(ciphertext.as_bytes())
+        .iter()
+        .map(|b| format!("{b:02x}"))
+        .collect()
+}
+
+/// The `429` for a muted or just-tripped sender. `reason` is `"rate"`,
+/// `"duplicate"`, or `"muted"`.
+pub fn flood_detected(reason: &str, retry_after: u64) -> Response {
+    (
+        StatusCode::TOO_MANY_REQUESTS,
+        [(header::RETRY_AFTER, retry_after.to_string())],
+        Json(serde_json::json!({
+            "error": "FLOOD_DETECTED",
+            "reason": reason,
+            "retry_after": retry_after,
+        })),
+    )
+        .into_response()
+}
+
+/// Write a `flood_incident` row and prune rows past 30 days. Best-effort: the
+/// caller logs a failure rather than failing the request.
+pub async fn record_incident(
+    conn: &Connection,
+    user_id: &str,
+    conversation_id: &str,
+    reason: FloodReason,
+    mute_secs: u64,
+) -> anyhow::Result<()> {
+    conn.execute(
+        "INSERT INTO flood_incident (id, user_id, conversation_id, reason, muted_until) \
+         VALUES (?1, ?2, ?3, ?4, datetime('now', ?5))",
+        libsql::params![
+            Ulid::new().to_string(),
+            user_id.to_string(),
+            conversation_id.to_string(),
+            reason.as_str(),
+            format!("+{mute_secs} seconds"),
+        ],
+    )
+    .await?;
+    conn.execute(
+        "DELETE FROM flood_incident WHERE created_at < datetime('now', '-30 days')",
+        (),
+    )
+    .await?;
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn cfg() -> FloodConfig {
+        FloodConfig {
+            per_target_max: 3,
+            per_target_window_secs: 60,
+            duplicate_max: 2,
+            duplicate_window_secs: 60,
+            mute_secs: 300,
+        }
+    }
+
+    #[tokio::test]
+    async fn rate_trip_mutes_the_sender_everywhere() {
+        let fd = FloodDetector::default();
+        for i in 0..3 {
+            assert_eq!(
+                fd.check_send(&cfg(), "u1", "c1", &format!("mls:{i}"), 1000)
+                    .await,
+                FloodOutcome::Allowed
+            );
+        }
+        assert_eq!(
+            fd.check_send(&cfg(), "u1", "c1", "mls:3", 1000).await,
+            FloodOutcome::Detected {
+                reason: FloodReason::Rate,
+                retry_after: 300
+            }
+        );
+        // Muted in every conversation, not just the flooded one.
+        assert_eq!(
+            fd.check_send(&cfg(), "u1", "c2", "mls:4", 1010).await,
+            FloodOutcome::Muted { retry_after: 290 }
+        );
+        // Other senders are unaffected.
+        assert_eq!(
+            fd.check_send(&cfg(), "u2", "c1", "mls:5", 1010).await,
+            FloodOutcome::Allowed
+        );
+        // The mute lapses.
+        assert_eq!(fd.muted_for("u1", 1300).await, None);
+    }
+
+    #[tokio::test]
+    async fn identical_ciphertext_burst_trips_duplicate() {
+        let fd = FloodDetector::default();
+        assert_eq!(
+            fd.check_send(&cfg(), "u1", "c1", "mls:aa", 1000).await,
+            FloodOutcome::Allowed
+        );
+        assert_eq!(
+            fd.check_send(&cfg(), "u1", "c2", "mls:aa", 1000).await,
+            FloodOutcome::Allowed
+        );
+        assert_eq!(
+            fd.check_send(&cfg(), "u1", "c3", "mls:aa", 1000).await,
+            FloodOutcome::Detected {
+                reason: FloodReason::Duplicate,
+                retry_after: 300
+            }
+        );
+    }
+
+    #[tokio::test]
+    async fn zero_max_disables_a_heuristic() {
+        let fd = FloodDetector::default();
+        let off = FloodConfig {
+            per_target_max: 0,
+            duplicate_max: 0,
+            ..cfg()
+        };
+        for _ in 0..50 {
+            assert_eq!(
+                fd.check_send(&off, "u1", "c1", "mls:aa", 1000).await,
+                FloodOutcome::Allowed
+            );
+        }
+    }
+}
@@ -0,0 +1,145 @@
+//! Second phase of group deletion: purging groups whose grace period is over.
+//!
+//! `POST /v1/groups/delete` only schedules (a `group_deletion` row, see
+//! [`crate::groups::apply_delete_group`]); members keep using the group and
+//! the owner may cancel until `purge_after`. The sweep worker then deletes,
+//! per due group and in one main-DB transaction:
+//!
+//!   - the message envelopes and read watermarks of its channels (envelopes
+//!     carry no FK to `channels`, so CASCADE wouldn't reach them),
+//!   - its channels, memberships and the `groups` row itself (CASCADE takes
+//!     invites, join requests, webhooks and the rest of the group-keyed rows),
+//!
+//! followed by the group's MLS control-plane rows on the log DB. The log-DB
+//! delete is best-effort, like [`crate::groups::purge_member_welcomes`]: the
+//! rows are unreachable once the group is gone, and commit-log pruning bounds
+//! them anyway.
+//!
+//! Local history is never touched here — each member's device keeps or deletes
+//! its own copy (the client offers both during the grace period). The worker
+//! is one in-process loop, like [`crate::inactivity`], and sweeps only on the
+//! instance holding the `group_purge` lease ([`crate::lease`]).
+
+use std::sync::Arc;
+use std::time::Duration;
+
+use libsql::Connection;
+
+use crate::db::Db;
+use crate::lease;
+
+/// Days between the owner scheduling a deletion and the purge.
+pub const GROUP_DELETION_GRACE_DAYS: u32 = 7;
+
+/// Purge sweep settings, read from DS env by [`GroupPurgeConfig::from_env`].
+#[derive(Clone)]
+pub struct GroupPurgeConfig {
+    /// Seconds between sweeps (`0` = the worker doesn't run).
+    pub sweep_secs: u64,
+}
+
+impl Default for GroupPurgeConfig {
+    fn default() -> Self {
+        Self { sweep_secs: 3600 }
+    }
+}
+
+impl GroupPurgeConfig {
+    /// Build from DS environment. Env: `GROUP_PURGE_SWEEP_SECS`.
+    pub fn from_env() -> Self {
+        let mut cfg = Self::default();
+        if let Some(v) = std::env::var("GROUP_PURGE_SWEEP_SECS")
+            .ok()
+            .and_then(|s| s.parse().ok())
+        {
+            cfg.sweep_secs = v;
+        }
+        cfg
+    }
+}
+
+/// Start the sweep worker on the current tokio runtime. Does nothing when
+/// `config.sweep_secs` is `0`. Every instance starts one; only the holder of
+/// the `group_purge` lease sweeps (see [`crate::lease`]).
+pub fn spawn_sweeper(db: Arc<Db>, log_db: Arc<Db>, config: GroupPurgeConfig) {
+    if config.sweep_secs == 0 {
+        return;
+    }
+    tokio::spawn(async move {
+        let mut tick = tokio::time::interval(Duration::from_secs(config.sweep_secs));
+        tick.set_missed_tick_behavior(tokio::time::MissedTickBehavior::Delay);
+        loop {
+            tick.tick().await;
+            if !lease::should_run(&db, "group_purge", config.sweep_secs).await {
+                continue;
+            }
+            match purge_once(&db, &log_db).await {
+                Ok(0) => {}
+                Ok(purged) => tracing::info!(purged, "group purge sweep"),
+                Err(e) => tracing::warn!("group purge sweep: {e:#}"),
+            }
+        }
+    });
+}
+
+/// One pass: purge every group whose `purge_after` has passed. Returns how
+/// many were purged.
+pub async fn purge_once(db: &Db, log_db: &Db) -> anyhow::Result<usize> {
+    let conn = db.conn()?;
+    let mut rows = conn
+        .query(
+            "SELECT group_id FROM group_deletion WHERE purge_after <= datetime('now')",
+            (),
+        )
+        .await?;
+    let mut due: Vec<String> = Vec::new();
+    while let Some(row) = rows.next().await? {
+        due.push(row.get(0)?);
+    }
+    drop(rows);
+
+    let log_conn = log_db.conn()?;
+    for group_id in &due {
+        purge_group(&conn, group_id).await?;
+        if let Err(e) = purge_group_log(&log_conn, group_id).await {
+            tracing::warn!("group purge: MLS log rows for {group_id}: {e:#}");
+        }
+    }
+    Ok(due.len())
+}
+
+async fn purge_group(conn: &Connection, group_id: &str) -> anyhow::Result<()> {
+    let tx = conn.transaction().await?;
+    for sql in [
+        "DELETE FROM message_envelope \
+         WHERE conversation_id IN (SELECT id FROM channels WHERE group_id = ?1)",
+        "DELETE FROM conversation_watermark \
+         WHERE conversation_id IN (SELECT id FROM channels WHERE group_id = ?1)",
+        "DELETE FROM pinned_message \
+         WHERE conversation_id IN (SELECT id FROM channels WHERE group_id = ?1)",
+        "DELETE FROM channels WHERE group_id = ?1",
+        "DELETE FROM group_member WHERE group_id = ?1",
+        "DELETE FROM group_deletion WHERE group_id = ?1",
+        "DELETE FROM groups WHERE id = ?1",
+    ] {
+        tx.execute(sql, libsql::params![group_id.to_string()])
+            .await?;
+    }
+    tx.commit().await?;
+    Ok(())
+}
+
+/// The group's MLS rows (the MLS group is keyed by the group id).
+async fn purge_group_log(log_conn: &Connection, group_id: &str) -> anyhow::Result<()> {
+    for sql in [
+        "DELETE FROM mls_commit_log WHERE conversation_id = ?1",
+        "DELETE FROM mls_group_info WHERE conversation_id = ?1",
+        "DELETE FROM mls_welcome WHERE conversation_id = ?1",
+        "DELETE FROM mls_commit_since WHERE conversation_id = ?1",
+    ] {
+        log_conn
+            .execute(sql, libsql::params![group_id.to_string()])
+            .await?;
+    }
+    Ok(())
+}
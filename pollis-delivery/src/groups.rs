@@ -14,24 +14,38 @@
 //! ## Where the writes land
 //!
 //! Every domain-B table (`groups`, `channels`, `group_member`, `group_invite`,
-//! `group_join_request`, plus the `conversation_watermark` / `message_envelope`
-//! rows a channel-delete cleans up) lives in the **MAIN DB** (`state.db`). So all
-//! `apply_*` fns run on the main connection.
+//! `group_join_request`, `group_ownership_transfer`, `group_deletion`, plus the
+//! `conversation_watermark` / `message_envelope` rows a channel-delete cleans up) lives in the **MAIN DB** (`state.db`). So all
+//! `apply_*` fns run on the main connection. The one log-DB touch is
+//! [`purge_member_welcomes`], which member-remove runs after its main-DB write.
 //!
 //! ## Authorization (the security core)
 //!
 //! `gate` proves *which user* signed; each `apply_*` then proves they're allowed:
-//!   - create group: the actor is the creator (`owner_id` bound to the signer).
+//!   - create group: the actor is the creator (`owner_id` bound to the signer)
+//!     and, when a `slug` is given, holds its reservation (`crate::slugs`).
 //!   - create channel: the actor is a current member of the group.
-//!   - update/delete channel, update/delete group, role change, member remove,
+//!   - update/delete channel, update group, role change, member remove,
 //!     invite create, join-request approve/reject: the actor's role is
 //!     **re-derived server-side** from `group_member` and must be `admin` (member
 //!     remove additionally allows self-removal).
 //!   - invite accept/decline: the actor is the invitee (writes are scoped
 //!     `invitee_id = :actor`).
 //!   - leave group: the actor is a current member (removes only their own row).
+//!   - ownership transfer: proposing requires the actor to be the group's
+//!     `owner_id`; accepting requires the actor to be the pending target (and
+//!     still a member); declining/withdrawing requires the actor to be either
+//!     side of the pending row.
+//!   - delete group (schedule) / cancel deletion: the actor is the group's
+//!     `owner_id`; the purge itself runs later in [`crate::group_purge`].
+//!   - update group's `storage_target`: the actor is also the `owner_id`
+//!     ([`crate::residency`]).
 //!   - join-request create: the actor is the requester.
 //!
+//! Size caps (members / channels per group, groups per user) are checked in
+//! the handlers for create group, create channel, invite accept and
+//! join-request approve, before `apply_*` — see [`crate::limits`].
+//!
 //! On the no-auth path (`authed == None`, only when `POLLIS_DS_REQUIRE_AUTH` is
 //! off) the role/identity checks are skipped and the actor comes from the body,
 //! mirroring `commit::submit`, `writes.rs`, and `messages.rs`.
@@ -46,6 +60,10 @@ use libsql::Connection;
 use serde::Deserialize;
 
 use crate::error::AppError;
+use crate::group_purge::GROUP_DELETION_GRACE_DAYS;
+use crate::limits;
+use crate::slugs;
+use crate::webhooks::GroupEvent;
 use crate::writes::{bad_request, gate, outcome_response, resolve_actor, WriteOutcome};
 use crate::AppState;
 
@@ -72,10 +90,28 @@ async fn group_role(
 }
 
 /// True when the actor is a current admin of `group_id` (re-derived server-side).
-async fn is_admin(conn: &Connection, group_id: &str, user_id: &str) -> anyhow::Result<bool> {
+pub(crate) async fn is_admin(
+    conn: &Connection,
+    group_id: &str,
+    user_id: &str,
+) -> anyhow::Result<bool> {
     Ok(group_role(conn, group_id, user_id).await?.as_deref() == Some("admin"))
 }
 
+/// The group's current `owner_id`, or `None` if the group doesn't exist.
+async fn group_owner(conn: &Connection, group_id: &str) -> anyhow::Result<Option<String>> {
+    let mut rows = conn
+        .query(
+            "SELECT owner_id FROM groups WHERE id = ?1",
+            libsql::params![group_id.to_string()],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) => Some(row.get::<String>(0)?),
+        None => None,
+    })
+}
+
 /// The group that owns a channel, or `None` if the channel doesn't exist.
 async fn channel_group_id(conn: &Connection, channel_id: &str) -> anyhow::Result<Option<String>> {
     let mut rows = conn
@@ -135,6 +171,11 @@ pub struct CreateGroupBody {
     /// When present, also create a default `Voice Chat` voice channel with this id.
     #[serde(default)]
     pub default_voice_channel_id: Option<String>,
+    /// A slug the owner reserved via `POST /v1/slugs/reserve`, claimed for this
+    /// group in the same transaction. Absent → the group claims the slug its
+    /// name derives to (`slugs::claim_name_slug`).
+    #[serde(default)]
+    pub slug: Option<String>,
     pub created_at: String,
 }
 
@@ -154,12 +195,20 @@ pub async fn create_group(
         Err(_) => return Ok(bad_request("invalid body")),
     };
     let conn = state.db.conn()?;
+    if let Some(owner) = authed.as_deref().or(parsed.owner_id.as_deref()) {
+        if let Some(resp) = limits::check_groups(&conn, &state.limits, owner).await? {
+            return Ok(resp);
+        }
+    }
     outcome_response(apply_create_group(&conn, authed.as_deref(), &parsed).await?)
 }
 
-/// INSERT the group, its creator's admin `group_member`, and any default
-/// channels — all in one transaction. Authz: a signed request may only create a
-/// group it owns (`owner_id` bound to the signer).
+/// INSERT the group, its creator's admin `group_member`, any default channels,
+/// and the claim on its slug — all in one transaction. Authz: a signed
+/// request may only create a group it owns (`owner_id` bound to the signer),
+/// and only with a slug that owner holds an unclaimed reservation for. Without
+/// a `slug` the group claims the one its name derives to, and is refused when
+/// another group or a live reservation holds it.
 pub async fn apply_create_group(
     conn: &Connection,
     authed: Option<&str>,
@@ -181,6 +230,16 @@ pub async fn apply_create_group(
         ],
     )
     .await?;
+    let claimed = match &body.slug {
+        Some(slug) => slugs::claim_slug(&tx, slug, &owner, &body.id).await?,
+        // A client shipped before reservations: claim the name's slug so it
+        // can't create a second group under one already taken.
+        None => slugs::claim_name_slug(&tx, &body.name, &owner, &body.id).await?,
+    };
+    if !claimed {
+        tx.rollback().await?;
+        return Ok(WriteOutcome::Forbidden);
+    }
     tx.execute(
         "INSERT INTO group_member (group_id, user_id, role) VALUES (?1, ?2, 'admin')",
         libsql::params![body.id.clone(), owner.clone()],
@@ -219,6 +278,14 @@ pub struct UpdateGroupBody {
     pub description: Option<String>,
     #[serde(default)]
     pub icon_url: Option<String>,
+    /// Per-group message-export policy (`groups.allow_export`).
+    #[serde(default)]
+    pub allow_export: Option<bool>,
+    /// Attachment residency (`groups.storage_target`): a declared storage
+    /// target id, or `""` for the default store. Owner only. See
+    /// [`crate::residency`].
+    #[serde(default)]
+    pub storage_target: Option<String>,
 }
 
 pub async fn update_group(
@@ -236,11 +303,19 @@ pub async fn update_group(
         Ok(b) => b,
         Err(_) => return Ok(bad_request("invalid body")),
     };
+    // Only a target this deployment declares; the harness has none, so the
+    // check lives here rather than in `apply_update_group`.
+    if let Some(target) = parsed.storage_target.as_deref() {
+        if !target.is_empty() && state.storage_targets.get(target).is_none() {
+            return Ok(bad_request("unknown storage target"));
+        }
+    }
     let conn = state.db.conn()?;
     outcome_response(apply_update_group(&conn, authed.as_deref(), &parsed).await?)
 }
 
-/// Update a group's mutable settings. Authz: the actor is a re-derived admin.
+/// Update a group's mutable settings. Authz: the actor is a re-derived admin,
+/// and the group's owner to change its storage target.
 pub async fn apply_update_group(
     conn: &Connection,
     authed: Option<&str>,
@@ -253,27 +328,52 @@ pub async fn apply_update_group(
     if authed.is_some() && !is_admin(conn, &body.group_id, &requester).await? {
         return Ok(WriteOutcome::Forbidden);
     }
+    if authed.is_some()
+        && body.storage_target.is_some()
+        && group_owner(conn, &body.group_id).await?.as_deref() != Some(requester.as_str())
+    {
+        return Ok(WriteOutcome::Forbidden);
+    }
+    // One transaction: a partial update (name lands, description fails) would
+    // otherwise leave the group half-edited.
+    let tx = conn.transaction().await?;
     if let Some(n) = &body.name {
-        conn.execute(
+        tx.execute(
             "UPDATE groups SET name = ?1 WHERE id = ?2",
             libsql::params![n.clone(), body.group_id.clone()],
         )
         .await?;
     }
     if let Some(d) = &body.description {
-        conn.execute(
+        tx.execute(
             "UPDATE groups SET description = ?1 WHERE id = ?2",
             libsql::params![d.clone(), body.group_id.clone()],
         )
         .await?;
     }
     if let Some(u) = &body.icon_url {
-        conn.execute(
+        tx.execute(
             "UPDATE groups SET icon_url = ?1 WHERE id = ?2",
             libsql::params![u.clone(), body.group_id.clone()],
         )
         .await?;
     }
+    if let Some(allow) = body.allow_export {
+        tx.execute(
+            "UPDATE groups SET allow_export = ?1 WHERE id = ?2",
+            libsql::params![allow as i64, body.group_id.clone()],
+        )
+        .await?;
+    }
+    if let Some(t) = &body.storage_target {
+        let target = (!t.is_empty()).then(|| t.clone());
+        tx.execute(
+            "UPDATE groups SET storage_target = ?1 WHERE id = ?2",
+            libsql::params![target, body.group_id.clone()],
+        )
+        .await?;
+    }
+    tx.commit().await?;
     Ok(WriteOutcome::Ok)
 }
 
@@ -305,7 +405,11 @@ pub async fn delete_group(
     outcome_response(apply_delete_group(&conn, authed.as_deref(), &parsed).await?)
 }
 
-/// Delete a group (CASCADE removes members/channels/invites). Authz: admin.
+/// Schedule the group's deletion: a `group_deletion` row whose `purge_after`
+/// is [`GROUP_DELETION_GRACE_DAYS`] out. Nothing is deleted yet — the purge
+/// sweep ([`crate::group_purge`]) does that once the grace period has passed.
+/// Scheduling again keeps the original date. Authz: the actor is the group's
+/// `owner_id`.
 pub async fn apply_delete_group(
     conn: &Connection,
     authed: Option<&str>,
@@ -315,11 +419,65 @@ pub async fn apply_delete_group(
         Ok(r) => r,
         Err(o) => return Ok(o),
     };
-    if authed.is_some() && !is_admin(conn, &body.group_id, &requester).await? {
+    let owner = match group_owner(conn, &body.group_id).await? {
+        Some(o) => o,
+        None => return Ok(WriteOutcome::Forbidden),
+    };
+    if authed.is_some() && owner != requester {
         return Ok(WriteOutcome::Forbidden);
     }
     conn.execute(
-        "DELETE FROM groups WHERE id = ?1",
+        "INSERT OR IGNORE INTO group_deletion (group_id, requested_by, purge_after)
+         VALUES (?1, ?2, datetime('now', ?3))",
+        libsql::params![
+            body.group_id.clone(),
+            requester,
+            format!("+{GROUP_DELETION_GRACE_DAYS} days")
+        ],
+    )
+    .await?;
+    Ok(WriteOutcome::Ok)
+}
+
+// ── POST /v1/groups/delete/cancel ────────────────────────────────────────────
+
+pub async fn cancel_group_deletion(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: DeleteGroupBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let conn = state.db.conn()?;
+    outcome_response(apply_cancel_group_deletion(&conn, authed.as_deref(), &parsed).await?)
+}
+
+/// Withdraw a scheduled deletion during its grace period. A no-op when none is
+/// pending. Authz: the actor is the group's `owner_id`.
+pub async fn apply_cancel_group_deletion(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &DeleteGroupBody,
+) -> anyhow::Result<WriteOutcome> {
+    let requester = match resolve_actor(authed, body.requester_id.as_deref()) {
+        Ok(r) => r,
+        Err(o) => return Ok(o),
+    };
+    if authed.is_some()
+        && group_owner(conn, &body.group_id).await?.as_deref() != Some(requester.as_str())
+    {
+        return Ok(WriteOutcome::Forbidden);
+    }
+    conn.execute(
+        "DELETE FROM group_deletion WHERE group_id = ?1",
         libsql::params![body.group_id.clone()],
     )
     .await?;
@@ -352,10 +510,26 @@ pub async fn leave_group(
         Err(_) => return Ok(bad_request("invalid body")),
     };
     let conn = state.db.conn()?;
-    outcome_response(apply_leave_group(&conn, authed.as_deref(), &parsed).await?)
+    let outcome = apply_leave_group(&conn, authed.as_deref(), &parsed).await?;
+    if matches!(outcome, WriteOutcome::Ok) {
+        // Best-effort, as in `remove_member`: the membership row is already
+        // gone, so a failure only leaves a Welcome the leave commit superseded.
+        let leaver = authed
+            .as_deref()
+            .or(parsed.user_id.as_deref())
+            .unwrap_or_default();
+        let log_conn = state.log_db.conn()?;
+        if let Err(e) = purge_member_welcomes(&log_conn, &parsed.group_id, leaver).await {
+            eprintln!("[groups/leave] welcome purge for {}: {e}", parsed.group_id);
+        }
+    }
+    outcome_response(outcome)
 }
 
-/// Remove the actor's own membership; if the group is now empty, delete it.
+/// Remove the actor's own membership, their read watermarks in the group's
+/// channels and any pending ownership hand-off naming them; if the group is now
+/// empty, delete it. The handler then purges their undelivered Welcomes
+/// ([`purge_member_welcomes`]), like [`remove_member`].
 /// Authz: the actor is a current member (a signed request may only remove its
 /// OWN row — `user_id` is bound to the signer).
 pub async fn apply_leave_group(
@@ -376,6 +550,21 @@ pub async fn apply_leave_group(
         libsql::params![body.group_id.clone(), user.clone()],
     )
     .await?;
+    // The leaver's read watermarks in the group's channels. Envelope GC only
+    // consults current members', so these would just linger as metadata.
+    tx.execute(
+        "DELETE FROM conversation_watermark WHERE user_id = ?2 \
+         AND conversation_id IN (SELECT id FROM channels WHERE group_id = ?1)",
+        libsql::params![body.group_id.clone(), user.clone()],
+    )
+    .await?;
+    // A hand-off to or from someone no longer in the group can't complete.
+    tx.execute(
+        "DELETE FROM group_ownership_transfer \
+         WHERE group_id = ?1 AND (to_user_id = ?2 OR from_user_id = ?2)",
+        libsql::params![body.group_id.clone(), user.clone()],
+    )
+    .await?;
     let mut count_rows = tx
         .query(
             "SELECT COUNT(*) FROM group_member WHERE group_id = ?1",
@@ -398,6 +587,198 @@ pub async fn apply_leave_group(
     Ok(WriteOutcome::Ok)
 }
 
+// ── POST /v1/groups/transfer-ownership ───────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct TransferOwnershipBody {
+    pub group_id: String,
+    /// The proposed new owner; must be a current member.
+    pub new_owner_id: String,
+    /// The current owner; bound to the authenticated user when signed.
+    #[serde(default)]
+    pub requester_id: Option<String>,
+}
+
+pub async fn transfer_ownership(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: TransferOwnershipBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let conn = state.db.conn()?;
+    outcome_response(apply_transfer_ownership(&conn, authed.as_deref(), &parsed).await?)
+}
+
+/// Propose handing the group to another member. Nothing changes hands yet — the
+/// row in `group_ownership_transfer` waits for the target to accept. A second
+/// proposal replaces the first. Authz: the actor is the current `owner_id`, and
+/// the target is a current member other than the actor.
+pub async fn apply_transfer_ownership(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &TransferOwnershipBody,
+) -> anyhow::Result<WriteOutcome> {
+    let requester = match resolve_actor(authed, body.requester_id.as_deref()) {
+        Ok(r) => r,
+        Err(o) => return Ok(o),
+    };
+    if requester == body.new_owner_id {
+        return Ok(WriteOutcome::Forbidden);
+    }
+    if authed.is_some()
+        && group_owner(conn, &body.group_id).await?.as_deref() != Some(requester.as_str())
+    {
+        return Ok(WriteOutcome::Forbidden);
+    }
+    // Target must be a current member.
+    if group_role(conn, &body.group_id, &body.new_owner_id)
+        .await?
+        .is_none()
+    {
+        return Ok(WriteOutcome::Forbidden);
+    }
+    conn.execute(
+        "INSERT INTO group_ownership_transfer (group_id, from_user_id, to_user_id)
+         VALUES (?1, ?2, ?3)
+         ON CONFLICT(group_id) DO UPDATE SET
+             from_user_id = excluded.from_user_id,
+             to_user_id = excluded.to_user_id,
+             created_at = datetime('now')",
+        libsql::params![body.group_id.clone(), requester, body.new_owner_id.clone()],
+    )
+    .await?;
+    Ok(WriteOutcome::Ok)
+}
+
+// ── POST /v1/groups/accept-ownership ─────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct OwnershipResponseBody {
+    pub group_id: String,
+    /// The responder; bound to the authenticated user when signed.
+    #[serde(default)]
+    pub user_id: Option<String>,
+}
+
+pub async fn accept_ownership(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: OwnershipResponseBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let conn = state.db.conn()?;
+    outcome_response(apply_accept_ownership(&conn, authed.as_deref(), &parsed).await?)
+}
+
+/// Complete a pending transfer: move `groups.owner_id`, make the new owner an
+/// admin, and clear the pending row — one transaction. Authz: the actor is the
+/// pending `to_user_id` and still a member. The owner swap is conditional on the
+/// proposer still owning the group, so a transfer proposed by a since-replaced
+/// owner can't be redeemed.
+pub async fn apply_accept_ownership(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &OwnershipResponseBody,
+) -> anyhow::Result<WriteOutcome> {
+    let user = match resolve_actor(authed, body.user_id.as_deref()) {
+        Ok(u) => u,
+        Err(o) => return Ok(o),
+    };
+    if authed.is_some() && group_role(conn, &body.group_id, &user).await?.is_none() {
+        return Ok(WriteOutcome::Forbidden);
+    }
+    let tx = conn.transaction().await?;
+    let moved = tx
+        .execute(
+            "UPDATE groups SET owner_id = ?2
+             WHERE id = ?1
+               AND owner_id = (SELECT from_user_id FROM group_ownership_transfer
+                               WHERE group_id = ?1 AND to_user_id = ?2)",
+            libsql::params![body.group_id.clone(), user.clone()],
+        )
+        .await?;
+    if moved == 0 {
+        tx.rollback().await?;
+        return Ok(WriteOutcome::Forbidden);
+    }
+    tx.execute(
+        "UPDATE group_member SET role = 'admin' WHERE group_id = ?1 AND user_id = ?2",
+        libsql::params![body.group_id.clone(), user],
+    )
+    .await?;
+    tx.execute(
+        "DELETE FROM group_ownership_transfer WHERE group_id = ?1",
+        libsql::params![body.group_id.clone()],
+    )
+    .await?;
+    tx.commit().await?;
+    Ok(WriteOutcome::Ok)
+}
+
+// ── POST /v1/groups/decline-ownership ────────────────────────────────────────
+
+pub async fn decline_ownership(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: OwnershipResponseBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let conn = state.db.conn()?;
+    outcome_response(apply_decline_ownership(&conn, authed.as_deref(), &parsed).await?)
+}
+
+/// Drop a pending transfer. Authz: the actor is either side of it — the target
+/// declining, or the proposer withdrawing (the delete is scoped to rows that
+/// name the actor).
+pub async fn apply_decline_ownership(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &OwnershipResponseBody,
+) -> anyhow::Result<WriteOutcome> {
+    let user = match resolve_actor(authed, body.user_id.as_deref()) {
+        Ok(u) => u,
+        Err(o) => return Ok(o),
+    };
+    let removed = conn
+        .execute(
+            "DELETE FROM group_ownership_transfer
+             WHERE group_id = ?1 AND (to_user_id = ?2 OR from_user_id = ?2)",
+            libsql::params![body.group_id.clone(), user],
+        )
+        .await?;
+    if removed == 0 {
+        return Ok(WriteOutcome::Forbidden);
+    }
+    Ok(WriteOutcome::Ok)
+}
+
 // ── POST /v1/channels/create ─────────────────────────────────────────────────
 
 #[derive(Deserialize)]
@@ -429,7 +810,17 @@ pub async fn create_channel(
         Err(_) => return Ok(bad_request("invalid body")),
     };
     let conn = state.db.conn()?;
-    outcome_response(apply_create_channel(&conn, authed.as_deref(), &parsed).await?)
+    if let Some(resp) = limits::check_channels(&conn, &state.limits, &parsed.group_id).await? {
+        return Ok(resp);
+    }
+    let outcome = apply_create_channel(&conn, authed.as_deref(), &parsed).await?;
+    if matches!(outcome, WriteOutcome::Ok) {
+        state.webhooks.emit(GroupEvent::ChannelCreated {
+            group_id: parsed.group_id.clone(),
+            channel_id: parsed.id.clone(),
+        });
+    }
+    outcome_response(outcome)
 }
 
 /// INSERT a channel. Authz: the actor is a current member of the owning group.
@@ -470,8 +861,15 @@ pub struct UpdateChannelBody {
     pub name: Option<String>,
     #[serde(default)]
     pub description: Option<String>,
+    /// Channel retention window in days (`channels.retention_days`); `0`
+    /// clears it. Envelope GC and every member's client enforce it.
+    #[serde(default)]
+    pub retention_days: Option<i64>,
 }
 
+/// Longest retention window an admin can set, in days.
+pub const MAX_CHANNEL_RETENTION_DAYS: i64 = 3650;
+
 pub async fn update_channel(
     State(state): State<AppState>,
     method: Method,
@@ -487,11 +885,17 @@ pub async fn update_channel(
         Ok(b) => b,
         Err(_) => return Ok(bad_request("invalid body")),
     };
+    if let Some(days) = parsed.retention_days {
+        if !(0..=MAX_CHANNEL_RETENTION_DAYS).contains(&days) {
+            return Ok(bad_request("retention_days out of range"));
+        }
+    }
     let conn = state.db.conn()?;
     outcome_response(apply_update_channel(&conn, authed.as_deref(), &parsed).await?)
 }
 
-/// Update a channel's name/description. Authz: admin of the owning group.
+/// Update a channel's name/description/retention. Authz: admin of the owning
+/// group.
 pub async fn apply_update_channel(
     conn: &Connection,
     authed: Option<&str>,
@@ -508,20 +912,31 @@ pub async fn apply_update_channel(
     if authed.is_some() && !is_admin(conn, &group_id, &requester).await? {
         return Ok(WriteOutcome::Forbidden);
     }
+    // One transaction, same reasoning as `apply_update_group`.
+    let tx = conn.transaction().await?;
     if let Some(n) = &body.name {
-        conn.execute(
+        tx.execute(
             "UPDATE channels SET name = ?1 WHERE id = ?2",
             libsql::params![n.clone(), body.channel_id.clone()],
         )
         .await?;
     }
     if let Some(d) = &body.description {
-        conn.execute(
+        tx.execute(
             "UPDATE channels SET description = ?1 WHERE id = ?2",
             libsql::params![d.clone(), body.channel_id.clone()],
         )
         .await?;
     }
+    if let Some(days) = body.retention_days {
+        let days = (days > 0).then_some(days);
+        tx.execute(
+            "UPDATE channels SET retention_days = ?1 WHERE id = ?2",
+            libsql::params![days, body.channel_id.clone()],
+        )
+        .await?;
+    }
+    tx.commit().await?;
     Ok(WriteOutcome::Ok)
 }
 
@@ -553,7 +968,7 @@ pub async fn delete_channel(
     outcome_response(apply_delete_channel(&conn, authed.as_deref(), &parsed).await?)
 }
 
-/// Delete a channel and its envelopes/watermarks in one transaction. Authz:
+/// Delete a channel and its envelopes/watermarks/pins in one transaction. Authz:
 /// admin of the owning group (a destructive op).
 pub async fn apply_delete_channel(
     conn: &Connection,
@@ -582,6 +997,11 @@ pub async fn apply_delete_channel(
         libsql::params![body.channel_id.clone()],
     )
     .await?;
+    tx.execute(
+        "DELETE FROM pinned_message WHERE conversation_id = ?1",
+        libsql::params![body.channel_id.clone()],
+    )
+    .await?;
     tx.execute(
         "DELETE FROM channels WHERE id = ?1",
         libsql::params![body.channel_id.clone()],
@@ -619,7 +1039,16 @@ pub async fn remove_member(
         Err(_) => return Ok(bad_request("invalid body")),
     };
     let conn = state.db.conn()?;
-    outcome_response(apply_remove_member(&conn, authed.as_deref(), &parsed).await?)
+    let outcome = apply_remove_member(&conn, authed.as_deref(), &parsed).await?;
+    if matches!(outcome, WriteOutcome::Ok) {
+        // Best-effort: the membership row is already gone, so a failure here
+        // only leaves a stale Welcome the removal commit has superseded.
+        let log_conn = state.log_db.conn()?;
+        if let Err(e) = purge_member_welcomes(&log_conn, &parsed.group_id, &parsed.user_id).await {
+            eprintln!("[members/remove] welcome purge for {}: {e}", parsed.group_id);
+        }
+    }
+    outcome_response(outcome)
 }
 
 /// Remove a member. Authz: the actor removes themselves (leave) OR is a
@@ -639,14 +1068,43 @@ pub async fn apply_remove_member(
     {
         return Ok(WriteOutcome::Forbidden);
     }
-    conn.execute(
+    let tx = conn.transaction().await?;
+    tx.execute(
         "DELETE FROM group_member WHERE group_id = ?1 AND user_id = ?2",
         libsql::params![body.group_id.clone(), body.user_id.clone()],
     )
     .await?;
+    // A pending ownership hand-off to or from the removed member can never
+    // complete now; drop it with the membership.
+    tx.execute(
+        "DELETE FROM group_ownership_transfer \
+         WHERE group_id = ?1 AND (to_user_id = ?2 OR from_user_id = ?2)",
+        libsql::params![body.group_id.clone(), body.user_id.clone()],
+    )
+    .await?;
+    tx.commit().await?;
     Ok(WriteOutcome::Ok)
 }
 
+/// Delete the removed member's *undelivered* Welcomes for this group from the
+/// commit-log DB, so a device that was offline during the removal can't pick
+/// up a stale invitation into the tree. Delivered rows are left alone — they
+/// are history, and the remover's MLS commit already evicted those leaves.
+/// Pure conn-level write reused by the harness.
+pub async fn purge_member_welcomes(
+    log_conn: &Connection,
+    group_id: &str,
+    user_id: &str,
+) -> anyhow::Result<u64> {
+    Ok(log_conn
+        .execute(
+            "DELETE FROM mls_welcome \
+             WHERE conversation_id = ?1 AND recipient_id = ?2 AND delivered = 0",
+            libsql::params![group_id.to_string(), user_id.to_string()],
+        )
+        .await?)
+}
+
 // ── POST /v1/members/role ────────────────────────────────────────────────────
 
 #[derive(Deserialize)]
@@ -795,7 +1253,32 @@ pub async fn accept_invite(
         Err(_) => return Ok(bad_request("invalid body")),
     };
     let conn = state.db.conn()?;
-    outcome_response(apply_accept_invite(&conn, authed.as_deref(), &parsed).await?)
+    // Caps only for an invite addressed to the actor; anything else falls
+    // through to `apply_accept_invite`'s 403.
+    let mut group_id: Option<String> = None;
+    if let Some(user) = authed.as_deref().or(parsed.user_id.as_deref()) {
+        let mut rows = conn
+            .query(
+                "SELECT group_id FROM group_invite WHERE id = ?1 AND invitee_id = ?2",
+                libsql::params![parsed.invite_id.clone(), user.to_string()],
+            )
+            .await?;
+        group_id = match rows.next().await? {
+            Some(row) => Some(row.get(0)?),
+            None => None,
+        };
+        drop(rows);
+        if let Some(group_id) = &group_id {
+            if let Some(resp) = limits::check_join(&conn, &state.limits, group_id, user).await? {
+                return Ok(resp);
+            }
+        }
+    }
+    let outcome = apply_accept_invite(&conn, authed.as_deref(), &parsed).await?;
+    if let (WriteOutcome::Ok, Some(group_id)) = (&outcome, group_id) {
+        state.webhooks.emit(GroupEvent::MemberJoined { group_id });
+    }
+    outcome_response(outcome)
 }
 
 /// Accept an invite: add the actor as a member and delete the invite, in one
@@ -966,7 +1449,36 @@ pub async fn approve_join_request(
         Err(_) => return Ok(bad_request("invalid body")),
     };
     let conn = state.db.conn()?;
-    outcome_response(apply_approve_join_request(&conn, authed.as_deref(), &parsed).await?)
+    let mut rows = conn
+        .query(
+            "SELECT group_id, requester_id FROM group_join_request WHERE id = ?1 AND status = 'pending'",
+            libsql::params![parsed.request_id.clone()],
+        )
+        .await?;
+    let pending: Option<(String, String)> = match rows.next().await? {
+        Some(row) => Some((row.get(0)?, row.get(1)?)),
+        None => None,
+    };
+    drop(rows);
+    if let Some((group_id, requester_id)) = &pending {
+        // Only an admin learns the group is full; anyone else gets the 403.
+        let may_approve = match authed.as_deref() {
+            Some(a) => is_admin(&conn, group_id, a).await?,
+            None => true,
+        };
+        if may_approve {
+            if let Some(resp) =
+                limits::check_join(&conn, &state.limits, group_id, requester_id).await?
+            {
+                return Ok(resp);
+            }
+        }
+    }
+    let outcome = apply_approve_join_request(&conn, authed.as_deref(), &parsed).await?;
+    if let (WriteOutcome::Ok, Some((group_id, _))) = (&outcome, pending) {
+        state.webhooks.emit(GroupEvent::MemberJoined { group_id });
+    }
+    outcome_response(outcome)
 }
 
 /// Approve a pending join request: add the requester as a member and flip the row
@@ -0,0 +1,599 @@
+//! Idempotency keys for mutating RPCs.
+//!
+//! A client that loses the response to a write can't tell "never arrived" from
+//! "done, reply dropped". Retrying blind double-applies the second case: a
+//! second `/v1/key-packages/claim` consumes another package, a second group or
+//! channel create bounces off the primary key as a 500 instead of returning the
+//! row it already made. So every `ds_post` carries an `Idempotency-Key` (a
+//! ULID minted per call and kept across its retries), and this middleware
+//! remembers what the DS answered:
+//!
+//!   - **first sighting** — the key is marked in flight and the request runs.
+//!     A `2xx` is stored; anything else releases the key, so a retry after a
+//!     `401`, a `409` or a DB error runs again rather than replaying a failure.
+//!   - **repeat, done** — the stored status and body come back verbatim with
+//!     `Idempotent-Replayed: true`; the handler doesn't run.
+//!   - **repeat, still running** — `409 {"error":"IDEMPOTENCY_IN_PROGRESS"}`
+//!     with `Retry-After: 1`.
+//!   - **same key, different request** (path or body) —
+//!     `422 {"error":"IDEMPOTENCY_KEY_REUSED"}`.
+//!
+//! Keys are scoped to the `X-Pollis-User` / `X-Pollis-Device` pair; a request
+//! without both (the pre-enrollment bootstrap writes) is passed through
+//! untouched. With auth enforced, a stored response is only served to a
+//! request whose signature verifies — the handler would check it anyway, and
+//! a stored body must not go to someone who merely copied the headers.
+//!
+//! **Store:** in memory by default, like [`crate::replay`] — one instance,
+//! and a restart forgets every key. With `DS_SHARED_STATE=db` the keys live in
+//! the shared `ds_idempotency` table (migration 000026) instead, so a resend
+//! that lands on another instance, or on this one after a restart, still gets
+//! the first reply — see [`IdempotencyStore::shared`]. The client only resends
+//! routes that are safe to run twice regardless (`ds_client::resend_is_safe`
+//! in pollis-core), since it can't tell which store the DS runs.
+
+use std::collections::HashMap;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::{Arc, Mutex};
+
+use axum::{
+    body::{to_bytes, Body},
+    extract::{Request, State},
+    http::{header, HeaderValue, StatusCode},
+    middleware::Next,
+    response::{IntoResponse, Response},
+    Json,
+};
+use sha2::{Digest, Sha256};
+
+use crate::auth::{self, H_DEVICE, H_USER};
+use crate::db::Db;
+use crate::AppState;
+
+pub const HEADER: &str = "idempotency-key";
+pub const REPLAYED_HEADER: &str = "idempotent-replayed";
+
+/// Longest key accepted. A ULID is 26.
+const MAX_KEY_LEN: usize = 128;
+/// Largest request body the middleware buffers to fingerprint. Matches axum's
+/// default JSON body limit, which every keyed route is under.
+const MAX_REQUEST_BYTES: usize = 2 * 1024 * 1024;
+/// Largest response stored. A bigger one is returned but not remembered.
+const MAX_STORED_BYTES: usize = 64 * 1024;
+/// How long a shared-store key stays in flight before another request may
+/// take it over. Longer than any request runs; it only matters when the
+/// instance running the first one died before finishing it.
+const SHARED_IN_FLIGHT_SECS: u64 = 120;
+/// In shared mode, every this-many keyed requests an instance deletes lapsed
+/// rows.
+const SHARED_PRUNE_EVERY: u64 = 1_000;
+
+/// Idempotency tunables, read from DS env by [`IdempotencyConfig::from_env`].
+#[derive(Clone, Debug)]
+pub struct IdempotencyConfig {
+    /// How long a completed key's response is kept, seconds. `0` disables the
+    /// middleware.
+    pub ttl_secs: u64,
+    /// Most keys remembered at once; past it new keys run without being stored.
+    pub max_keys: usize,
+}
+
+impl Default for IdempotencyConfig {
+    fn default() -> Self {
+        Self {
+            ttl_secs: 24 * 60 * 60,
+            max_keys: 100_000,
+        }
+    }
+}
+
+impl IdempotencyConfig {
+    /// Build from DS environment, falling back to [`Default`] per field. Env:
+    /// `IDEMPOTENCY_TTL_SECS`, `IDEMPOTENCY_MAX_KEYS`.
+    pub fn from_env() -> Self {
+        let mut cfg = Self::default();
+        if let Some(v) = env_parse("IDEMPOTENCY_TTL_SECS") {
+            cfg.ttl_secs = v;
+        }
+        if let Some(v) = env_parse::<usize>("IDEMPOTENCY_MAX_KEYS") {
+            cfg.max_keys = v.max(1);
+        }
+        cfg
+    }
+}
+
+fn env_parse<T: std::str::FromStr>(key: &str) -> Option<T> {
+    std::env::var(key).ok().and_then(|s| s.parse().ok())
+}
+
+#[derive(Clone, Debug)]
+struct Stored {
+    status: StatusCode,
+    content_type: Option<HeaderValue>,
+    body: Vec<u8>,
+}
+
+#[derive(Clone, Debug)]
+struct Entry {
+    /// sha256 over method, path and body.
+    fingerprint: [u8; 32],
+    /// `None` while the first request is still running.
+    response: Option<Stored>,
+    /// Unix second the entry may be dropped.
+    until: u64,
+}
+
+/// What [`IdempotencyStore::begin`] found.
+#[derive(Debug)]
+enum Begin {
+    /// First sighting; now in flight.
+    Fresh,
+    InProgress,
+    Reused,
+    Done(Stored),
+    /// Table full; run without remembering.
+    Untracked,
+}
+
+/// `(user, device, key)` → entry, in memory or (see [`Self::shared`]) in the
+/// shared DB. `Clone` is shallow (shared `Arc`s) so it rides on the `Clone`
+/// `AppState`.
+#[derive(Clone, Default)]
+pub struct IdempotencyStore {
+    entries: Arc<Mutex<HashMap<(String, String, String), Entry>>>,
+    /// `Some` → keys live in `ds_idempotency`; `entries` is only the fallback
+    /// while the DB is unreachable.
+    shared: Option<Arc<Db>>,
+    /// Shared-mode keyed requests on this instance, for pacing the prune.
+    begun: Arc<AtomicU64>,
+}
+
+impl IdempotencyStore {
+    /// A store whose keys live in `db`'s `ds_idempotency` table, shared by
+    /// every DS instance on that database.
+    pub fn shared(db: Arc<Db>) -> Self {
+        Self {
+            shared: Some(db),
+            ..Self::default()
+        }
+    }
+
+    /// [`Self::begin_local`] in whichever store this is. A shared-store error
+    /// is logged and the key kept in memory instead — dedupe on this instance
+    /// beats failing the write.
+    async fn begin(
+        &self,
+        cfg: &IdempotencyConfig,
+        scope: &(String, String, String),
+        fingerprint: [u8; 32],
+        now: u64,
+    ) -> Begin {
+        let Some(db) = &self.shared else {
+            return self.begin_local(cfg, scope, fingerprint, now);
+        };
+        match shared_begin(db, scope, fingerprint, now).await {
+            Ok(found) => {
+                if self.begun.fetch_add(1, Ordering::Relaxed) % SHARED_PRUNE_EVERY == 0 {
+                    if let Err(e) = shared_prune(db, now).await {
+                        tracing::warn!("idempotency prune: {e:#}");
+                    }
+                }
+                found
+            }
+            Err(e) => {
+                tracing::warn!(
+                    "shared idempotency store unavailable, keeping the key locally: {e:#}"
+                );
+                self.begin_local(cfg, scope, fingerprint, now)
+            }
+        }
+    }
+
+    async fn finish(
+        &self,
+        cfg: &IdempotencyConfig,
+        scope: &(String, String, String),
+        stored: Stored,
+        now: u64,
+    ) {
+        if let Some(db) = &self.shared {
+            if let Err(e) = shared_finish(db, cfg, scope, &stored, now).await {
+                tracing::warn!("idempotency finish: {e:#}");
+            }
+        }
+        // A no-op unless `begin` fell back to memory.
+        self.finish_local(cfg, scope, stored, now);
+    }
+
+    async fn release(&self, scope: &(String, String, String)) {
+        if let Some(db) = &self.shared {
+            if let Err(e) = shared_release(db, scope).await {
+                tracing::warn!("idempotency release: {e:#}");
+            }
+        }
+        self.release_local(scope);
+    }
+
+    fn begin_local(
+        &self,
+        cfg: &IdempotencyConfig,
+        scope: &(String, String, String),
+        fingerprint: [u8; 32],
+        now: u64,
+    ) -> Begin {
+        let mut entries = self.entries.lock().expect("idempotency mutex poisoned");
+        if let Some(e) = entries.get(scope).filter(|e| now < e.until) {
+            if e.fingerprint != fingerprint {
+                return Begin::Reused;
+            }
+            return match &e.response {
+                Some(stored) => Begin::Done(stored.clone()),
+                None => Begin::InProgress,
+            };
+        }
+        if entries.len() >= cfg.max_keys {
+            entries.retain(|_, e| now < e.until);
+        }
+        if entries.len() >= cfg.max_keys {
+            tracing::warn!("idempotency table full; running a keyed request untracked");
+            return Begin::Untracked;
+        }
+        entries.insert(
+            scope.clone(),
+            Entry {
+                fingerprint,
+                response: None,
+                until: now + cfg.ttl_secs,
+            },
+        );
+        Begin::Fresh
+    }
+
+    fn finish_local(
+        &self,
+        cfg: &IdempotencyConfig,
+        scope: &(String, String, String),
+        stored: Stored,
+        now: u64,
+    ) {
+        let mut entries = self.entries.lock().expect("idempotency mutex poisoned");
+        if let Some(e) = entries.get_mut(scope) {
+            e.response = Some(stored);
+            e.until = now + cfg.ttl_secs;
+        }
+    }
+
+    fn release_local(&self, scope: &(String, String, String)) {
+        self.entries
+            .lock()
+            .expect("idempotency mutex poisoned")
+            .remove(scope);
+    }
+}
+
+/// Take `scope` in the shared table, or report what holds it. One conditional
+/// upsert claims a new key or takes over a lapsed row, so two instances racing
+/// on one key can't both see [`Begin::Fresh`].
+async fn shared_begin(
+    db: &Db,
+    scope: &(String, String, String),
+    fingerprint: [u8; 32],
+    now: u64,
+) -> anyhow::Result<Begin> {
+    let conn = db.conn()?;
+    let (user, device, key) = scope;
+    let taken = conn
+        .execute(
+            "INSERT INTO ds_idempotency (user_id, device_id, key, fingerprint, until) \
+             VALUES (?1, ?2, ?3, ?4, ?5) \
+             ON CONFLICT(user_id, device_id, key) DO UPDATE SET \
+                 fingerprint = excluded.fingerprint, status = NULL, \
+                 content_type = NULL, body = NULL, until = excluded.until \
+             WHERE ds_idempotency.until <= ?6",
+            libsql::params![
+                user.clone(),
+                device.clone(),
+                key.clone(),
+                fingerprint.to_vec(),
+                (now + SHARED_IN_FLIGHT_SECS) as i64,
+                now as i64,
+            ],
+        )
+        .await?;
+    if taken > 0 {
+        return Ok(Begin::Fresh);
+    }
+    let mut rows = conn
+        .query(
+            "SELECT fingerprint, status, content_type, body FROM ds_idempotency \
+             WHERE user_id = ?1 AND device_id = ?2 AND key = ?3",
+            libsql::params![user.clone(), device.clone(), key.clone()],
+        )
+        .await?;
+    // Released between the two statements: the first request failed, so this
+    // one runs untracked rather than racing a retry for the key.
+    let Some(row) = rows.next().await? else {
+        return Ok(Begin::Untracked);
+    };
+    if row.get::<Vec<u8>>(0)? != fingerprint {
+        return Ok(Begin::Reused);
+    }
+    let Some(status) = row.get::<Option<i64>>(1)? else {
+        return Ok(Begin::InProgress);
+    };
+    Ok(Begin::Done(Stored {
+        status: u16::try_from(status)
+            .ok()
+            .and_then(|s| StatusCode::from_u16(s).ok())
+            .ok_or_else(|| anyhow::anyhow!("stored status {status} out of range"))?,
+        content_type: row
+            .get::<Option<String>>(2)?
+            .and_then(|ct| HeaderValue::from_str(&ct).ok()),
+        body: row.get::<Option<Vec<u8>>>(3)?.unwrap_or_default(),
+    }))
+}
+
+async fn shared_finish(
+    db: &Db,
+    cfg: &IdempotencyConfig,
+    scope: &(String, String, String),
+    stored: &Stored,
+    now: u64,
+) -> anyhow::Result<()> {
+    let (user, device, key) = scope;
+    db.conn()?
+        .execute(
+            "UPDATE ds_idempotency SET status = ?4, content_type = ?5, body = ?6, until = ?7 \
+             WHERE user_id = ?1 AND device_id = ?2 AND key = ?3",
+            libsql::params![
+                user.clone(),
+                device.clone(),
+                key.clone(),
+                i64::from(stored.status.as_u16()),
+                stored
+                    .content_type
+                    .as_ref()
+                    .and_then(|ct| ct.to_str().ok())
+                    .map(str::to_string),
+                stored.body.clone(),
+                (now + cfg.ttl_secs) as i64,
+            ],
+        )
+        .await?;
+    Ok(())
+}
+
+async fn shared_release(db: &Db, scope: &(String, String, String)) -> anyhow::Result<()> {
+    let (user, device, key) = scope;
+    db.conn()?
+        .execute(
+            "DELETE FROM ds_idempotency WHERE user_id = ?1 AND device_id = ?2 AND key = ?3",
+            libsql::params![user.clone(), device.clone(), key.clone()],
+        )
+        .await?;
+    Ok(())
+}
+
+async fn shared_prune(db: &Db, now: u64) -> anyhow::Result<()> {
+    db.conn()?
+        .execute(
+            "DELETE FROM ds_idempotency WHERE until <= ?1",
+            libsql::params![now as i64],
+        )
+        .await?;
+    Ok(())
+}
+
+/// Axum middleware: dedupe keyed `POST`s per the module docs. Requests without
+/// an `Idempotency-Key` header go straight through.
+pub async fn idempotent(State(state): State<AppState>, req: Request, next: Next) -> Response {
+    let cfg = &state.idempotency_config;
+    if cfg.ttl_secs == 0 || req.method() != axum::http::Method::POST {
+        return next.run(req).await;
+    }
+    let Some(key) = req.headers().get(HEADER) else {
+        return next.run(req).await;
+    };
+    let key = match key.to_str() {
+        Ok(k)
+            if !k.is_empty()
+                && k.len() <= MAX_KEY_LEN
+                && k.bytes().all(|b| b.is_ascii_graphic()) =>
+        {
+            k.to_string()
+        }
+        _ => return error(StatusCode::BAD_REQUEST, "IDEMPOTENCY_KEY_INVALID"),
+    };
+    let value = |name: &str| {
+        req.headers()
+            .get(name)
+            .and_then(|v| v.to_str().ok())
+            .filter(|v| !v.is_empty())
+            .map(str::to_string)
+    };
+    let (Some(user), Some(device)) = (value(H_USER), value(H_DEVICE)) else {
+        return next.run(req).await;
+    };
+    let scope = (user, device, key);
+
+    let (parts, body) = req.into_parts();
+    let Ok(body) = to_bytes(body, MAX_REQUEST_BYTES).await else {
+        return error(StatusCode::PAYLOAD_TOO_LARGE, "BODY_TOO_LARGE");
+    };
+    let path = parts.uri.path().to_string();
+    let fingerprint = fingerprint(parts.method.as_str(), &path, &body);
+    let now = auth::now_unix().max(0) as u64;
+
+    match state.idempotency.begin(cfg, &scope, fingerprint, now).await {
+        Begin::Reused => return error(StatusCode::UNPROCESSABLE_ENTITY, "IDEMPOTENCY_KEY_REUSED"),
+        Begin::InProgress => {
+            let mut resp = error(StatusCode::CONFLICT, "IDEMPOTENCY_IN_PROGRESS");
+            resp.headers_mut()
+                .insert(header::RETRY_AFTER, HeaderValue::from_static("1"));
+            return resp;
+        }
+        Begin::Done(stored) => {
+            if state.require_auth {
+                let verified = match state.db.conn() {
+                    Ok(conn) => auth::verify_request(
+                        &conn,
+                        &parts.headers,
+                        parts.method.as_str(),
+                        &path,
+                        &body,
+                        auth::now_unix(),
+                    )
+                    .await
+                    .is_ok_and(|u| u == scope.0),
+                    Err(_) => false,
+                };
+                if !verified {
+                    // Let the handler reject it the usual way.
+                    return next.run(Request::from_parts(parts, Body::from(body))).await;
+                }
+            }
+            return replay(stored);
+        }
+        Begin::Untracked => {
+            return next.run(Request::from_parts(parts, Body::from(body))).await;
+        }
+        Begin::Fresh => {}
+    }
+
+    let resp = next.run(Request::from_parts(parts, Body::from(body))).await;
+    if !resp.status().is_success() {
+        state.idempotency.release(&scope).await;
+        return resp;
+    }
+    let (resp_parts, resp_body) = resp.into_parts();
+    let bytes = match to_bytes(resp_body, MAX_STORED_BYTES).await {
+        Ok(b) => b,
+        Err(_) => {
+            // Too big to keep (or the body errored). The write already happened,
+            // so a retry with this key would re-run it: say so rather than guess.
+            state.idempotency.release(&scope).await;
+            tracing::warn!(path = %path, "keyed response over the store limit; not remembered");
+            return error(StatusCode::INTERNAL_SERVER_ERROR, "RESPONSE_TOO_LARGE");
+        }
+    };
+    state
+        .idempotency
+        .finish(
+            cfg,
+            &scope,
+            Stored {
+                status: resp_parts.status,
+                content_type: resp_parts.headers.get(header::CONTENT_TYPE).cloned(),
+                body: bytes.to_vec(),
+            },
+            now,
+        )
+        .await;
+    Response::from_parts(resp_parts, Body::from(bytes))
+}
+
+fn fingerprint(method: &str, path: &str, body: &[u8]) -> [u8; 32] {
+    let mut h = Sha256::new();
+    h.update(method.as_bytes());
+    h.update([0]);
+    h.update(path.as_bytes());
+    h.update([0]);
+    h.update(body);
+    h.finalize().into()
+}
+
+fn replay(stored: Stored) -> Response {
+    let mut resp = Response::new(Body::from(stored.body));
+    *resp.status_mut() = stored.status;
+    if let Some(ct) = stored.content_type {
+        resp.headers_mut().insert(header::CONTENT_TYPE, ct);
+    }
+    resp.headers_mut()
+        .insert(REPLAYED_HEADER, HeaderValue::from_static("true"));
+    resp
+}
+
+fn error(status: StatusCode, code: &str) -> Response {
+    (status, Json(serde_json::json!({ "error": code }))).into_response()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn cfg() -> IdempotencyConfig {
+        IdempotencyConfig {
+            ttl_secs: 60,
+            max_keys: 2,
+        }
+    }
+
+    fn scope(key: &str) -> (String, String, String) {
+        ("u1".into(), "d1".into(), key.into())
+    }
+
+    fn ok(body: &str) -> Stored {
+        Stored {
+            status: StatusCode::OK,
+            content_type: None,
+            body: body.as_bytes().to_vec(),
+        }
+    }
+
+    #[test]
+    fn in_flight_then_done_then_expired() {
+        let s = IdempotencyStore::default();
+        let fp = fingerprint("POST", "/v1/x", b"{}");
+        assert!(matches!(
+            s.begin_local(&cfg(), &scope("k"), fp, 100),
+            Begin::Fresh
+        ));
+        assert!(matches!(
+            s.begin_local(&cfg(), &scope("k"), fp, 101),
+            Begin::InProgress
+        ));
+        s.finish_local(&cfg(), &scope("k"), ok("done"), 102);
+        match s.begin_local(&cfg(), &scope("k"), fp, 103) {
+            Begin::Done(stored) => assert_eq!(stored.body, b"done"),
+            other => panic!("expected Done, got {other:?}"),
+        }
+        assert!(matches!(
+            s.begin_local(&cfg(), &scope("k"), fp, 162),
+            Begin::Fresh
+        ));
+    }
+
+    #[test]
+    fn reuse_release_and_capacity() {
+        let s = IdempotencyStore::default();
+        let a = fingerprint("POST", "/v1/x", b"a");
+        let b = fingerprint("POST", "/v1/x", b"b");
+        assert!(matches!(
+            s.begin_local(&cfg(), &scope("k1"), a, 100),
+            Begin::Fresh
+        ));
+        assert!(matches!(
+            s.begin_local(&cfg(), &scope("k1"), b, 100),
+            Begin::Reused
+        ));
+        s.release_local(&scope("k1"));
+        assert!(matches!(
+            s.begin_local(&cfg(), &scope("k1"), b, 100),
+            Begin::Fresh
+        ));
+        assert!(matches!(
+            s.begin_local(&cfg(), &scope("k2"), a, 100),
+            Begin::Fresh
+        ));
+        // Full with live keys: a third runs untracked.
+        assert!(matches!(
+            s.begin_local(&cfg(), &scope("k3"), a, 100),
+            Begin::Untracked
+        ));
+        // Once they expire there is room again.
+        assert!(matches!(
+            s.begin_local(&cfg(), &scope("k3"), a, 200),
+            Begin::Fresh
+        ));
+    }
+}
@@ -0,0 +1,421 @@
+//! Inactive-account policy — an opt-in dead-man's switch for the account's
+//! server-held recovery material.
+//!
+//! A user sets `inactive_days` (and optionally `delete_recovery`) with
+//! `POST /v1/account/inactivity-policy`. Each unlock sends one
+//! `POST /v1/account/check-in`, which stamps `last_active_at` and cancels any
+//! pending warning. Any other device-signed write counts as activity too
+//! ([`record_activity`], at most one stamp per user an hour), so a client that
+//! stays unlocked for months never looks inactive and nothing client-side has
+//! to keep checking in. The sweep worker then, per policy:
+//!
+//!   1. **Warns** once `last_active_at` is older than `inactive_days`: emails
+//!      the account address and stamps `notified_at`. A warning that can't be
+//!      sent (no `RESEND_API_KEY`, Resend error) is NOT stamped, so step 2 can
+//!      never run on an account that was never told.
+//!   2. **Deletes the recovery backup** (only with `delete_recovery`) once a
+//!      warning has gone unanswered for [`InactivityConfig::grace_days`]: the
+//!      `account_recovery` row goes, `recovery_deleted_at` is stamped and a
+//!      `recovery_deleted_inactive` security event is logged, in one
+//!      transaction. Signing back in still works on an enrolled device; losing
+//!      every device after this means the Secret Key can no longer restore the
+//!      account.
+//!
+//! Envelopes are per-conversation, not per-recipient, so there is nothing
+//! user-owned to purge there — a user's undelivered backlog is other members'
+//! history too, and envelope GC already handles it. Nothing here touches local
+//! device data either; that lives only on the device.
+//!
+//! The worker is one in-process loop, like [`crate::webhooks`]. Every DS
+//! instance runs one, but only the holder of the `inactivity` sweep lease
+//! ([`crate::lease`]) sweeps, so a scaled-out DS doesn't warn twice.
+
+use std::collections::HashMap;
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+
+use axum::{
+    body::Bytes,
+    extract::State,
+    http::{HeaderMap, Method, Uri},
+    response::Response,
+};
+use libsql::Connection;
+use serde::Deserialize;
+
+use crate::db::Db;
+use crate::error::AppError;
+use crate::lease;
+use crate::otp::send_email;
+use crate::redact::mask_email;
+use crate::writes::{bad_request, gate, outcome_response, resolve_actor, WriteOutcome};
+use crate::AppState;
+
+/// Shortest window a user may pick, so a short trip can't trip the switch.
+pub const MIN_INACTIVE_DAYS: u32 = 30;
+/// Longest window a user may pick.
+pub const MAX_INACTIVE_DAYS: u32 = 730;
+
+/// Inactivity sweep settings, read from DS env by [`InactivityConfig::from_env`].
+#[derive(Clone)]
+pub struct InactivityConfig {
+    /// Seconds between sweeps (`0` = the worker doesn't run).
+    pub sweep_secs: u64,
+    /// Days between the warning email and the recovery-backup deletion.
+    pub grace_days: u32,
+    /// Resend key for the warning email. `None` → warnings aren't sent, and so
+    /// nothing is ever deleted. NEVER logged.
+    pub resend_api_key: Option<String>,
+}
+
+impl Default for InactivityConfig {
+    fn default() -> Self {
+        Self {
+            sweep_secs: 3600,
+            grace_days: 14,
+            resend_api_key: None,
+        }
+    }
+}
+
+impl InactivityConfig {
+    /// Build from DS environment. Env: `INACTIVITY_SWEEP_SECS`,
+    /// `INACTIVITY_GRACE_DAYS`, and the shared `RESEND_API_KEY`.
+    pub fn from_env() -> Self {
+        let var = |k: &str| std::env::var(k).ok().filter(|s| !s.is_empty());
+        let mut cfg = Self {
+            resend_api_key: var("RESEND_API_KEY"),
+            ..Self::default()
+        };
+        if let Some(v) = var("INACTIVITY_SWEEP_SECS").and_then(|s| s.parse().ok()) {
+            cfg.sweep_secs = v;
+        }
+        if let Some(v) = var("INACTIVITY_GRACE_DAYS").and_then(|s| s.parse().ok()) {
+            cfg.grace_days = v;
+        }
+        cfg
+    }
+}
+
+// ── POST /v1/account/inactivity-policy ───────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct InactivityPolicyBody {
+    /// `None` removes the policy.
+    #[serde(default)]
+    pub inactive_days: Option<u32>,
+    #[serde(default)]
+    pub delete_recovery: bool,
+    /// Self-scope: when signed it must equal the authenticated user.
+    #[serde(default)]
+    pub user_id: Option<String>,
+}
+
+/// POST /v1/account/inactivity-policy — set or clear the actor's own policy.
+/// Self-scoped. An out-of-range `inactive_days` is a 400.
+pub async fn set_policy(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: InactivityPolicyBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    if let Some(days) = parsed.inactive_days {
+        if !(MIN_INACTIVE_DAYS..=MAX_INACTIVE_DAYS).contains(&days) {
+            return Ok(bad_request("inactive_days out of range"));
+        }
+    }
+    let conn = state.db.conn()?;
+    outcome_response(apply_set_policy(&conn, authed.as_deref(), &parsed).await?)
+}
+
+/// Upsert (or delete) the actor's policy. Saving counts as activity: the
+/// window restarts and any pending warning or past deletion stamp is cleared.
+pub async fn apply_set_policy(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &InactivityPolicyBody,
+) -> anyhow::Result<WriteOutcome> {
+    let actor = match resolve_actor(authed, body.user_id.as_deref()) {
+        Ok(a) => a,
+        Err(o) => return Ok(o),
+    };
+    match body.inactive_days {
+        None => {
+            conn.execute(
+                "DELETE FROM inactivity_policy WHERE user_id = ?1",
+                libsql::params![actor],
+            )
+            .await?;
+        }
+        Some(days) => {
+            conn.execute(
+                "INSERT INTO inactivity_policy (user_id, inactive_days, delete_recovery) \
+                 VALUES (?1, ?2, ?3) \
+                 ON CONFLICT(user_id) DO UPDATE SET \
+                    inactive_days = excluded.inactive_days, \
+                    delete_recovery = excluded.delete_recovery, \
+                    last_active_at = datetime('now'), \
+                    notified_at = NULL, \
+                    recovery_deleted_at = NULL, \
+                    updated_at = datetime('now')",
+                libsql::params![actor, days as i64, body.delete_recovery as i64],
+            )
+            .await?;
+        }
+    }
+    Ok(WriteOutcome::Ok)
+}
+
+// ── POST /v1/account/check-in ────────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct CheckInBody {
+    /// Self-scope: when signed it must equal the authenticated user.
+    #[serde(default)]
+    pub user_id: Option<String>,
+}
+
+/// POST /v1/account/check-in — record that the actor unlocked a device.
+/// Self-scoped; a no-op for users without a policy.
+pub async fn check_in(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: CheckInBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let conn = state.db.conn()?;
+    outcome_response(apply_check_in(&conn, authed.as_deref(), &parsed).await?)
+}
+
+/// Stamp `last_active_at` and cancel a pending warning.
+pub async fn apply_check_in(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &CheckInBody,
+) -> anyhow::Result<WriteOutcome> {
+    let actor = match resolve_actor(authed, body.user_id.as_deref()) {
+        Ok(a) => a,
+        Err(o) => return Ok(o),
+    };
+    stamp_active(conn, &actor).await?;
+    Ok(WriteOutcome::Ok)
+}
+
+async fn stamp_active(conn: &Connection, user_id: &str) -> anyhow::Result<()> {
+    conn.execute(
+        "UPDATE inactivity_policy SET last_active_at = datetime('now'), notified_at = NULL \
+         WHERE user_id = ?1",
+        libsql::params![user_id.to_string()],
+    )
+    .await?;
+    Ok(())
+}
+
+// ── Activity from signed writes ──────────────────────────────────────────────
+
+/// Least time between two [`record_activity`] stamps for one user. The
+/// shortest window is [`MIN_INACTIVE_DAYS`], so an hour's lag decides nothing.
+const ACTIVITY_STAMP_SECS: u64 = 3600;
+
+/// Users tracked before [`ActivityStamps`] drops the ones due again.
+const ACTIVITY_PRUNE_AT: usize = 10_000;
+
+/// When this instance last stamped each user's activity, so a busy user costs
+/// one UPDATE an hour rather than one per write. Per instance: another instance
+/// stamping the same user in the same hour is a harmless repeat. Shallow-
+/// `Clone` (shared `Arc`).
+#[derive(Clone, Default)]
+pub struct ActivityStamps {
+    inner: Arc<Mutex<HashMap<String, u64>>>,
+}
+
+impl ActivityStamps {
+    /// Whether `user_id` is due a stamp at `now` (unix seconds); a `true`
+    /// answer counts as the stamp.
+    pub fn due(&self, user_id: &str, now: u64) -> bool {
+        let mut guard = self.inner.lock().expect("activity stamps mutex poisoned");
+        if let Some(&at) = guard.get(user_id) {
+            if now.saturating_sub(at) < ACTIVITY_STAMP_SECS {
+                return false;
+            }
+        }
+        if guard.len() >= ACTIVITY_PRUNE_AT {
+            guard.retain(|_, at| now.saturating_sub(*at) < ACTIVITY_STAMP_SECS);
+        }
+        guard.insert(user_id.to_string(), now);
+        true
+    }
+}
+
+/// Count a verified device-signed write by `user_id` as activity: the same
+/// stamp as a check-in, throttled by [`ActivityStamps`]. Best-effort — a failed
+/// stamp is logged and never fails the write.
+pub(crate) async fn record_activity(state: &AppState, user_id: &str) {
+    if !state.activity.due(user_id, crate::ratelimit::now_unix()) {
+        return;
+    }
+    let stamped = match state.db.conn() {
+        Ok(conn) => stamp_active(&conn, user_id).await,
+        Err(e) => Err(e),
+    };
+    if let Err(e) = stamped {
+        tracing::warn!("inactivity: activity stamp: {e:#}");
+    }
+}
+
+// ── Sweep ────────────────────────────────────────────────────────────────────
+
+/// What one [`sweep_once`] did, for logs and tests.
+#[derive(Debug, Default, PartialEq, Eq)]
+pub struct SweepReport {
+    pub warned: usize,
+    pub recovery_deleted: usize,
+}
+
+/// Start the sweep worker on the current tokio runtime. Does nothing when
+/// `config.sweep_secs` is `0`. Every instance starts one; only the holder of
+/// the `inactivity` lease sweeps (see [`crate::lease`]).
+pub fn spawn_sweeper(db: Arc<Db>, config: InactivityConfig) {
+    if config.sweep_secs == 0 {
+        return;
+    }
+    tokio::spawn(async move {
+        let mut tick = tokio::time::interval(Duration::from_secs(config.sweep_secs));
+        tick.set_missed_tick_behavior(tokio::time::MissedTickBehavior::Delay);
+        loop {
+            tick.tick().await;
+            if !lease::should_run(&db, "inactivity", config.sweep_secs).await {
+                continue;
+            }
+            match sweep_once(&db, &config).await {
+                Ok(report) if report != SweepReport::default() => {
+                    tracing::info!(
+                        warned = report.warned,
+                        recovery_deleted = report.recovery_deleted,
+                        "inactivity sweep"
+                    );
+                }
+                Ok(_) => {}
+                Err(e) => tracing::warn!("inactivity sweep: {e:#}"),
+            }
+        }
+    });
+}
+
+/// One pass: send due warnings, then delete recovery backups whose warning
+/// has outlived the grace period.
+pub async fn sweep_once(db: &Db, config: &InactivityConfig) -> anyhow::Result<SweepReport> {
+    let conn = db.conn()?;
+    let mut report = SweepReport::default();
+
+    let mut rows = conn
+        .query(
+            "SELECT p.user_id, u.email, p.inactive_days, p.delete_recovery \
+             FROM inactivity_policy p JOIN users u ON u.id = p.user_id \
+             WHERE p.notified_at IS NULL \
+               AND p.last_active_at < datetime('now', '-' || p.inactive_days || ' days')",
+            (),
+        )
+        .await?;
+    let mut due: Vec<(String, String, i64, bool)> = Vec::new();
+    while let Some(row) = rows.next().await? {
+        due.push((
+            row.get(0)?,
+            row.get(1)?,
+            row.get(2)?,
+            row.get::<i64>(3)? != 0,
+        ));
+    }
+    for (user_id, email, days, delete_recovery) in due {
+        let Some(key) = &config.resend_api_key else {
+            tracing::warn!(
+                "RESEND_API_KEY unset — inactivity warning NOT sent for {}",
+                mask_email(&email)
+            );
+            continue;
+        };
+        let text = warning_text(days, delete_recovery, config.grace_days);
+        if let Err(e) = send_email(key, &email, "Your Pollis account is inactive", &text).await {
+            tracing::error!(
+                "inactivity warning failed for {}: {e:#}",
+                mask_email(&email)
+            );
+            continue;
+        }
+        conn.execute(
+            "UPDATE inactivity_policy SET notified_at = datetime('now') WHERE user_id = ?1",
+            libsql::params![user_id],
+        )
+        .await?;
+        report.warned += 1;
+    }
+
+    let mut rows = conn
+        .query(
+            "SELECT user_id FROM inactivity_policy \
+             WHERE delete_recovery = 1 AND recovery_deleted_at IS NULL \
+               AND notified_at IS NOT NULL AND notified_at < datetime('now', ?1)",
+            libsql::params![format!("-{} days", config.grace_days)],
+        )
+        .await?;
+    let mut expired: Vec<String> = Vec::new();
+    while let Some(row) = rows.next().await? {
+        expired.push(row.get(0)?);
+    }
+    for user_id in expired {
+        let tx = conn.transaction().await?;
+        tx.execute(
+            "DELETE FROM account_recovery WHERE user_id = ?1",
+            libsql::params![user_id.clone()],
+        )
+        .await?;
+        tx.execute(
+            "UPDATE inactivity_policy SET recovery_deleted_at = datetime('now') WHERE user_id = ?1",
+            libsql::params![user_id.clone()],
+        )
+        .await?;
+        tx.execute(
+            "INSERT INTO security_event (id, user_id, kind) VALUES (?1, ?2, 'recovery_deleted_inactive')",
+            libsql::params![ulid::Ulid::new().to_string(), user_id],
+        )
+        .await?;
+        tx.commit().await?;
+        report.recovery_deleted += 1;
+    }
+
+    Ok(report)
+}
+
+fn warning_text(days: i64, delete_recovery: bool, grace_days: u32) -> String {
+    let mut text = format!(
+        "Your Pollis account hasn't been unlocked on any device for {days} days, \
+         which is the limit you set in your inactive-account policy.\n\n"
+    );
+    if delete_recovery {
+        text.push_str(&format!(
+            "If it stays inactive for another {grace_days} days, your Secret Key recovery \
+             backup will be deleted. After that, losing all of your devices means the \
+             account can't be restored.\n\n"
+        ));
+    }
+    text.push_str("Unlock Pollis on any of your devices to cancel this.");
+    text
+}
@@ -0,0 +1,45 @@
+//! Which DS instance served a request.
+//!
+//! With more than one DS behind the same hostname, a log line or a metrics
+//! scrape is only useful if it says which process produced it. Every instance
+//! gets an id at startup — `DS_INSTANCE_ID` when the orchestrator sets one
+//! (a container or pod name), otherwise a fresh ULID — and [`tag`] puts it on
+//! every request: a `ds` tracing span carrying `instance`, and an
+//! `X-Pollis-Instance` response header. `/version` and `/metrics` report it
+//! too, and [`crate::lease`] uses it as the sweep lease holder.
+
+use std::sync::OnceLock;
+
+use axum::extract::Request;
+use axum::http::HeaderValue;
+use axum::middleware::Next;
+use axum::response::Response;
+use tracing::Instrument as _;
+use ulid::Ulid;
+
+pub const HEADER: &str = "x-pollis-instance";
+
+static ID: OnceLock<String> = OnceLock::new();
+
+/// This process's instance id. Read once; stable for the process lifetime.
+pub fn id() -> &'static str {
+    ID.get_or_init(|| {
+        std::env::var("DS_INSTANCE_ID")
+            .ok()
+            .map(|s| s.trim().to_string())
+            .filter(|s| !s.is_empty())
+            .unwrap_or_else(|| Ulid::new().to_string())
+    })
+}
+
+/// Axum middleware: run the request inside a span naming this instance and
+/// stamp the instance id on the response. Outermost, so it covers the
+/// rate-limiter's 429s too.
+pub async fn tag(req: Request, next: Next) -> Response {
+    let span = tracing::info_span!("ds", instance = id());
+    let mut resp = next.run(req).instrument(span).await;
+    if let Ok(v) = HeaderValue::from_str(id()) {
+        resp.headers_mut().insert(HEADER, v);
+    }
+    resp
+}
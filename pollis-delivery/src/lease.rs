@@ -0,0 +1,55 @@
+//! Sweep leases: which DS instance runs a periodic job.
+//!
+//! The inactivity and group-purge sweepers start on every instance. Run twice,
+//! the inactivity sweep would email the same warning twice and two purges would
+//! race over the same rows, so each tick first takes the job's lease in
+//! `ds_lease` (migration 000024). The holder renews it every tick; another
+//! instance only takes over once it has lapsed, i.e. after the holder stopped
+//! ticking for `ttl_secs`.
+//!
+//! A lease failure (the table isn't there yet, a DB blip) runs the sweep anyway
+//! and logs it — that's the single-instance behavior, and both sweeps are
+//! idempotent against their own rows; only the warning email could repeat.
+
+use crate::db::Db;
+
+/// Take or renew the `name` lease for `holder` until `now + ttl_secs`. `true`
+/// when `holder` now holds it; `false` while another holder's lease is live.
+pub async fn acquire(
+    db: &Db,
+    name: &str,
+    holder: &str,
+    ttl_secs: u64,
+    now: u64,
+) -> anyhow::Result<bool> {
+    let conn = db.conn()?;
+    let changed = conn
+        .execute(
+            "INSERT INTO ds_lease (name, holder, expires_at) VALUES (?1, ?2, ?3) \
+             ON CONFLICT(name) DO UPDATE SET holder = excluded.holder, \
+                 expires_at = excluded.expires_at \
+             WHERE ds_lease.holder = excluded.holder OR ds_lease.expires_at <= ?4",
+            libsql::params![
+                name.to_string(),
+                holder.to_string(),
+                (now + ttl_secs) as i64,
+                now as i64,
+            ],
+        )
+        .await?;
+    Ok(changed > 0)
+}
+
+/// Whether this instance should run this tick of the `name` sweep, which ticks
+/// every `interval_secs`. The lease outlives two ticks, so one missed tick
+/// doesn't hand it over.
+pub async fn should_run(db: &Db, name: &str, interval_secs: u64) -> bool {
+    let now = crate::ratelimit::now_unix();
+    match acquire(db, name, crate::instance::id(), interval_secs * 2 + 1, now).await {
+        Ok(held) => held,
+        Err(e) => {
+            tracing::warn!("sweep lease {name}: {e:#}; running this tick anyway");
+            true
+        }
+    }
+}
@@ -28,23 +28,39 @@ pub mod commit;
 pub mod db;
 pub mod devices;
 pub mod email_change;
+pub mod email_invites;
 pub mod error;
+pub mod flood;
+pub mod group_purge;
 pub mod groups;
 pub mod headers;
+pub mod idempotency;
+pub mod inactivity;
+pub mod instance;
+pub mod lease;
+pub mod limits;
+pub mod maintenance;
 pub mod messages;
 pub mod otp;
 pub mod profile;
 pub mod ratelimit;
 pub mod redact;
+pub mod replay;
+pub mod residency;
 pub mod session;
+pub mod slugs;
+pub mod storage;
+pub mod uploads;
+pub mod usage;
+pub mod webhooks;
 pub mod writes;
 
 use std::sync::Arc;
 
 use axum::{
     body::Bytes,
-    extract::{Path, Query, State},
-    http::{HeaderMap, Method, StatusCode, Uri},
+    extract::{DefaultBodyLimit, Path, Query, State},
+    http::{header, HeaderMap, Method, StatusCode, Uri},
     middleware::{from_fn, from_fn_with_state},
     response::{IntoResponse, Response},
     routing::{get, post},
@@ -67,10 +83,12 @@ pub struct AppState {
     pub log_db: Arc<Db>,
     /// When true, `POST /v1/commits` requires a valid device signature.
     pub require_auth: bool,
-    /// In-memory OTP store (request-otp / verify-otp). Shallow-`Clone` (shared
+    /// OTP store (request-otp / verify-otp), in memory unless
+    /// [`Self::with_shared_state`] moved it to the DB. Shallow-`Clone` (shared
     /// `Arc`), so every `AppState` clone sees the same codes.
     pub otp: otp::OtpStore,
-    /// In-memory OTP-session store gating the bootstrap writes.
+    /// OTP-session store gating the bootstrap writes; in memory or the DB,
+    /// like `otp`.
     pub sessions: session::SessionStore,
     /// OTP/session tunables + the Resend key (DS env).
     pub otp_config: otp::OtpConfig,
@@ -80,11 +98,54 @@ pub struct AppState {
     /// Authorized-secrets broker config (#393) — LiveKit + R2 secrets read from
     /// DS env. Default all-`None`; the matching endpoint 503s until configured.
     pub broker: broker::BrokerConfig,
-    /// In-memory per-IP rate limiter for the signup-OTP endpoints. Shallow-`Clone`
-    /// (shared `Arc`), so every `AppState` clone shares the same counters.
+    /// Per-IP rate limiter for the signup-OTP endpoints, in memory unless
+    /// [`Self::with_shared_state`] moved it to the DB. Shallow-`Clone` (shared
+    /// `Arc`), so every `AppState` clone shares the same counters.
     pub ratelimit: ratelimit::RateLimiter,
     /// Per-IP rate-limit tunables (DS env).
     pub ratelimit_config: ratelimit::RateLimitConfig,
+    /// Group size caps (DS env), served at `GET /v1/limits`.
+    pub limits: limits::LimitsConfig,
+    /// Per-sender flood detector + mutes for message sends. Shallow-`Clone`
+    /// (shared `Arc`), like `ratelimit`.
+    pub flood: flood::FloodDetector,
+    /// Flood-detection tunables (DS env).
+    pub flood_config: flood::FloodConfig,
+    /// Recent envelope ids per conversation, for refusing replays. Shallow-
+    /// `Clone` (shared `Arc`), like `flood`.
+    pub replay: replay::ReplayGuard,
+    /// Replay-protection tunables (DS env).
+    pub replay_config: replay::ReplayConfig,
+    /// Responses to keyed writes, for answering a retry without re-running it,
+    /// in memory unless [`Self::with_shared_state`] moved them to the DB.
+    /// Shallow-`Clone` (shared `Arc`), like `replay`.
+    pub idempotency: idempotency::IdempotencyStore,
+    /// Idempotency-key tunables (DS env).
+    pub idempotency_config: idempotency::IdempotencyConfig,
+    /// Object store the presign endpoint signs for (DS env). Default `S3`,
+    /// i.e. the broker's R2 credentials.
+    pub storage: storage::ObjectStorage,
+    /// What presigned PUTs may upload: per-category types and size caps (DS env).
+    pub uploads: uploads::UploadPolicy,
+    /// Extra S3-compatible stores a group can pin its attachments to (DS env).
+    /// Default: none.
+    pub storage_targets: residency::StorageTargets,
+    /// Outbound group-webhook settings (DS env). Default: disabled.
+    pub webhook_config: webhooks::WebhookConfig,
+    /// Queue to the webhook dispatch worker. Default: inert (drops events).
+    pub webhooks: webhooks::WebhookDispatcher,
+    /// Per-user usage accounting (DS env). Default: off.
+    pub usage: usage::UsageConfig,
+    /// Invites emailed to addresses without an account (DS env). Default: off.
+    pub email_invites: email_invites::EmailInviteConfig,
+    /// Live maintenance switch (refuses writes while open). Shallow-`Clone`
+    /// (shared `Arc`), like `replay`.
+    pub maintenance: maintenance::Maintenance,
+    /// Maintenance start state + admin token (DS env).
+    pub maintenance_config: maintenance::MaintenanceConfig,
+    /// When each user's signed writes last counted as activity for their
+    /// inactivity policy. Shallow-`Clone` (shared `Arc`), like `replay`.
+    pub activity: inactivity::ActivityStamps,
 }
 
 impl AppState {
@@ -109,6 +170,23 @@ impl AppState {
             broker: broker::BrokerConfig::default(),
             ratelimit: ratelimit::RateLimiter::default(),
             ratelimit_config: ratelimit::RateLimitConfig::default(),
+            limits: limits::LimitsConfig::default(),
+            flood: flood::FloodDetector::default(),
+            flood_config: flood::FloodConfig::default(),
+            replay: replay::ReplayGuard::default(),
+            replay_config: replay::ReplayConfig::default(),
+            idempotency: idempotency::IdempotencyStore::default(),
+            idempotency_config: idempotency::IdempotencyConfig::default(),
+            storage: storage::ObjectStorage::default(),
+            uploads: uploads::UploadPolicy::default(),
+            storage_targets: residency::StorageTargets::default(),
+            webhook_config: webhooks::WebhookConfig::default(),
+            webhooks: webhooks::WebhookDispatcher::default(),
+            usage: usage::UsageConfig::default(),
+            email_invites: email_invites::EmailInviteConfig::default(),
+            maintenance: maintenance::Maintenance::default(),
+            maintenance_config: maintenance::MaintenanceConfig::default(),
+            activity: inactivity::ActivityStamps::default(),
         }
     }
 
@@ -133,6 +211,104 @@ impl AppState {
         self.ratelimit_config = config;
         self
     }
+
+    /// Override the group size caps. Builder so `main` can thread DS env (and
+    /// tests can set tiny caps), mirroring [`Self::with_ratelimit_config`].
+    pub fn with_limits_config(mut self, config: limits::LimitsConfig) -> Self {
+        self.limits = config;
+        self
+    }
+
+    /// Override the flood-detection config. Builder so `main` can thread DS env
+    /// (and tests can set tiny thresholds), mirroring [`Self::with_ratelimit_config`].
+    pub fn with_flood_config(mut self, config: flood::FloodConfig) -> Self {
+        self.flood_config = config;
+        self
+    }
+
+    /// Override the replay-protection config. Builder so `main` can thread DS
+    /// env (and tests can shrink the window), mirroring [`Self::with_flood_config`].
+    pub fn with_replay_config(mut self, config: replay::ReplayConfig) -> Self {
+        self.replay_config = config;
+        self
+    }
+
+    /// Override the idempotency-key config. Builder so `main` can thread DS env
+    /// (and tests can shrink the TTL), mirroring [`Self::with_replay_config`].
+    pub fn with_idempotency_config(mut self, config: idempotency::IdempotencyConfig) -> Self {
+        self.idempotency_config = config;
+        self
+    }
+
+    /// Override the object-storage backend. Builder so `main` can thread DS env
+    /// (and tests can point `fs` at a temp dir), mirroring [`Self::with_broker_config`].
+    pub fn with_storage(mut self, storage: storage::ObjectStorage) -> Self {
+        self.storage = storage;
+        self
+    }
+
+    /// Override the upload policy. Builder so `main` can thread DS env (and tests
+    /// can set tiny caps), mirroring [`Self::with_limits_config`].
+    pub fn with_upload_policy(mut self, policy: uploads::UploadPolicy) -> Self {
+        self.uploads = policy;
+        self
+    }
+
+    /// Override the declared storage targets. Builder so `main` can thread DS
+    /// env (and tests can declare a fake one), mirroring [`Self::with_storage`].
+    pub fn with_storage_targets(mut self, targets: residency::StorageTargets) -> Self {
+        self.storage_targets = targets;
+        self
+    }
+
+    /// Override the usage-accounting config. Builder so `main` can thread DS
+    /// env (and tests can turn recording on), mirroring [`Self::with_upload_policy`].
+    pub fn with_usage_config(mut self, config: usage::UsageConfig) -> Self {
+        self.usage = config;
+        self
+    }
+
+    /// Override the email-invite config (Resend + link-signing keys). Builder so
+    /// `main` can thread DS env, mirroring [`Self::with_usage_config`].
+    pub fn with_email_invite_config(mut self, config: email_invites::EmailInviteConfig) -> Self {
+        self.email_invites = config;
+        self
+    }
+
+    /// Override the maintenance config, opening the window now when
+    /// `config.initial` says so. Builder so `main` can thread DS env (and tests
+    /// can start in maintenance), mirroring [`Self::with_usage_config`].
+    pub fn with_maintenance_config(mut self, config: maintenance::MaintenanceConfig) -> Self {
+        self.maintenance.set(config.initial.clone());
+        self.maintenance_config = config;
+        self
+    }
+
+    /// Keep the rate-limit and flood counters (and flood mutes), the
+    /// idempotency keys, and the sign-in codes, bootstrap sessions and
+    /// email-change codes in the main DB instead of this process, so several
+    /// DS instances on one database share them (`DS_SHARED_STATE=db`,
+    /// migrations 000024, 000026 and 000027). Builder like
+    /// [`Self::with_storage`]; tests use it to run two "instances" on one file.
+    pub fn with_shared_state(mut self) -> Self {
+        self.ratelimit = ratelimit::RateLimiter::shared(Arc::clone(&self.db));
+        self.flood = flood::FloodDetector::shared(Arc::clone(&self.db));
+        self.idempotency = idempotency::IdempotencyStore::shared(Arc::clone(&self.db));
+        self.otp = otp::OtpStore::shared(Arc::clone(&self.db), "signup");
+        self.sessions = session::SessionStore::shared(Arc::clone(&self.db));
+        self.email_change = email_change::EmailChangeStore::shared(Arc::clone(&self.db));
+        self
+    }
+
+    /// Enable outbound webhooks with `config`, starting the dispatch worker on
+    /// the current tokio runtime (a no-op without a signing key). Builder so
+    /// `main` can thread DS env (and tests can allow loopback targets),
+    /// mirroring [`Self::with_storage`].
+    pub fn with_webhooks(mut self, config: webhooks::WebhookConfig) -> Self {
+        self.webhooks = webhooks::WebhookDispatcher::spawn(Arc::clone(&self.db), config.clone());
+        self.webhook_config = config;
+        self
+    }
 }
 
 /// Read the `POLLIS_DS_REQUIRE_AUTH` gate. `true`/`1` (case-insensitive) → on;
@@ -169,7 +345,41 @@ pub fn build_router_with_log_db(db: Arc<Db>, log_db: Arc<Db>) -> Router {
     let state = AppState::new_with_log_db(db, log_db, require_auth)
         .with_otp_config(otp::OtpConfig::from_env())
         .with_broker_config(broker::BrokerConfig::from_env())
-        .with_ratelimit_config(ratelimit::RateLimitConfig::from_env());
+        .with_ratelimit_config(ratelimit::RateLimitConfig::from_env())
+        .with_limits_config(limits::LimitsConfig::from_env())
+        .with_flood_config(flood::FloodConfig::from_env())
+        .with_replay_config(replay::ReplayConfig::from_env())
+        .with_idempotency_config(idempotency::IdempotencyConfig::from_env())
+        .with_storage(storage::ObjectStorage::from_env())
+        .with_upload_policy(uploads::UploadPolicy::from_env())
+        .with_storage_targets(residency::StorageTargets::from_env())
+        .with_usage_config(usage::UsageConfig::from_env())
+        .with_email_invite_config(email_invites::EmailInviteConfig::from_env())
+        .with_maintenance_config(maintenance::MaintenanceConfig::from_env())
+        .with_webhooks(webhooks::WebhookConfig::from_env());
+    let shared_state = ratelimit::shared_state_from_env();
+    tracing::info!(
+        instance = instance::id(),
+        shared_state,
+        "pollis-delivery counters: {}",
+        if shared_state {
+            "SHARED (DS_SHARED_STATE=db — rate limits, flood mutes, idempotency keys and sign-in state in the main DB)"
+        } else {
+            "IN-MEMORY (single instance; set DS_SHARED_STATE=db to scale out)"
+        }
+    );
+    let state = if shared_state {
+        state.with_shared_state()
+    } else {
+        state
+    };
+    slugs::spawn_backfill(Arc::clone(&state.db));
+    inactivity::spawn_sweeper(Arc::clone(&state.db), inactivity::InactivityConfig::from_env());
+    group_purge::spawn_sweeper(
+        Arc::clone(&state.db),
+        Arc::clone(&state.log_db),
+        group_purge::GroupPurgeConfig::from_env(),
+    );
     build_router_with_state(state)
 }
 
@@ -179,6 +389,17 @@ pub fn build_router_with_state(state: AppState) -> Router {
     Router::new()
         .route("/health", get(health))
         .route("/version", get(version))
+        .route("/metrics", get(metrics))
+        // Size caps (members / channels per group, groups per user). Open, like
+        // `/version` — they're deployment config, not secrets. See `limits`.
+        .route("/v1/limits", get(limits::get_limits))
+        // Storage targets a group owner can pin attachments to (ids and
+        // labels, no credentials). Open, like `/v1/limits`. See `residency`.
+        .route("/v1/storage/targets", get(residency::list_targets))
+        // Maintenance window: open status read, bearer-token admin switch. See
+        // `maintenance`.
+        .route("/v1/maintenance", get(maintenance::get_status))
+        .route(maintenance::ADMIN_PATH, post(maintenance::set_status))
         .route("/v1/commits", post(submit))
         .route("/v1/commits/:conversation_id", get(commits))
         .route("/v1/group-info", post(writes::group_info))
@@ -193,6 +414,8 @@ pub fn build_router_with_state(state: AppState) -> Router {
         .route("/v1/messages/delete", post(messages::delete_message))
         .route("/v1/reactions/add", post(messages::add_reaction))
         .route("/v1/reactions/remove", post(messages::remove_reaction))
+        .route("/v1/pins/add", post(messages::pin_message))
+        .route("/v1/pins/remove", post(messages::unpin_message))
         .route("/v1/watermarks/advance", post(messages::advance_watermark))
         .route("/v1/envelopes/gc", post(messages::envelope_gc))
         .route("/v1/attachments/register", post(messages::register_attachment))
@@ -202,7 +425,15 @@ pub fn build_router_with_state(state: AppState) -> Router {
         .route("/v1/groups/create", post(groups::create_group))
         .route("/v1/groups/update", post(groups::update_group))
         .route("/v1/groups/delete", post(groups::delete_group))
+        .route("/v1/groups/delete/cancel", post(groups::cancel_group_deletion))
         .route("/v1/groups/leave", post(groups::leave_group))
+        .route("/v1/groups/transfer-ownership", post(groups::transfer_ownership))
+        .route("/v1/groups/accept-ownership", post(groups::accept_ownership))
+        .route("/v1/groups/decline-ownership", post(groups::decline_ownership))
+        // Canonical group slugs: preview the normalized form, then reserve it
+        // before `/v1/groups/create` claims it (migration 000018).
+        .route("/v1/slugs/normalize", post(slugs::normalize))
+        .route("/v1/slugs/reserve", post(slugs::reserve))
         .route("/v1/channels/create", post(groups::create_channel))
         .route("/v1/channels/update", post(groups::update_channel))
         .route("/v1/channels/delete", post(groups::delete_channel))
@@ -211,9 +442,18 @@ pub fn build_router_with_state(state: AppState) -> Router {
         .route("/v1/invites/create", post(groups::create_invite))
         .route("/v1/invites/accept", post(groups::accept_invite))
         .route("/v1/invites/decline", post(groups::decline_invite))
+        // Invites to an address with no account: emailed with a signed link,
+        // converted to a `group_invite` on signup or redeem. The opt-out link
+        // opens in a browser, so it's an unsigned GET checked by its HMAC.
+        .route("/v1/email-invites/create", post(email_invites::create))
+        .route("/v1/email-invites/redeem", post(email_invites::redeem))
+        .route("/v1/email-invites/opt-out", get(email_invites::opt_out))
         .route("/v1/join-requests/create", post(groups::create_join_request))
         .route("/v1/join-requests/approve", post(groups::approve_join_request))
         .route("/v1/join-requests/reject", post(groups::reject_join_request))
+        // Outbound group webhooks — admin-registered, MAIN DB.
+        .route("/v1/webhooks/create", post(webhooks::create_webhook))
+        .route("/v1/webhooks/delete", post(webhooks::delete_webhook))
         // Domain C (#419) — profile / preferences / blocks / DMs. All land on
         // the MAIN DB.
         .route("/v1/profile/update", post(profile::update_profile))
@@ -246,6 +486,13 @@ pub fn build_router_with_state(state: AppState) -> Router {
         .route("/v1/enrollment/approve", post(account::approve_enrollment))
         .route("/v1/enrollment/reject", post(account::reject_enrollment))
         .route("/v1/devices/revoke", post(account::revoke_device))
+        // Opt-in inactive-account policy + the per-unlock check-in that resets it.
+        .route("/v1/account/inactivity-policy", post(inactivity::set_policy))
+        .route("/v1/account/check-in", post(inactivity::check_in))
+        // Per-user usage accounting: a user's own counters (device-signed) and
+        // the operator's billing export (bearer `USAGE_EXPORT_TOKEN`).
+        .route("/v1/usage/me", post(usage::my_usage))
+        .route("/v1/usage/export", get(usage::export_usage))
         // Logout device removal (bucket-C C4) — DEVICE-SIGNED DELETE of the
         // signer's OWN `user_device` row. Distinct from `/v1/devices/revoke`
         // (which tombstones): logout must re-register cleanly on next sign-in.
@@ -287,11 +534,30 @@ pub fn build_router_with_state(state: AppState) -> Router {
         .route("/v1/livekit/participants", post(broker::livekit_participants))
         .route("/v1/turso/token", post(broker::turso_token))
         .route("/v1/r2/presign", post(broker::r2_presign))
+        // `fs` object storage — where `/v1/r2/presign` URLs point when
+        // STORAGE_BACKEND=fs. Authorized by the presigned query string, not a
+        // device signature (clients hit it exactly like an S3 URL). 404 under s3.
+        .route(
+            "/v1/blobs/*key",
+            get(storage::blob)
+                .put(storage::blob)
+                .delete(storage::blob)
+                .layer(DefaultBodyLimit::max(state.storage.max_object_bytes())),
+        )
+        // Retry dedupe for writes carrying an `Idempotency-Key`. Innermost, so a
+        // replayed response still counts against the rate limit.
+        .layer(from_fn_with_state(state.clone(), idempotency::idempotent))
+        // Maintenance refuses writes before they reach the idempotency store,
+        // so a held write's retry after the window runs for real.
+        .layer(from_fn_with_state(state.clone(), maintenance::gate))
         // Hardening middleware (#345). Rate limiting runs first (inner); security
         // headers are added last so they wrap every response, including the
         // rate-limiter's own 429s and any error replies.
         .layer(from_fn_with_state(state.clone(), ratelimit::rate_limit))
         .layer(from_fn(headers::security_headers))
+        // Outermost: every response, 429s included, names the instance that
+        // produced it, and every log line under it carries `instance`.
+        .layer(from_fn(instance::tag))
         .with_state(state)
 }
 
@@ -309,11 +575,41 @@ pub const GIT_SHA: &str = match option_env!("GIT_SHA") {
     None => "unknown",
 };
 
-/// GET /version — the running build's git SHA. Open (no auth), like `/health`.
+/// GET /version — the running build's git SHA and which instance answered.
+/// Open (no auth), like `/health`.
 async fn version() -> impl IntoResponse {
     (
         StatusCode::OK,
-        Json(serde_json::json!({ "service": "pollis-delivery", "sha": GIT_SHA })),
+        Json(serde_json::json!({
+            "service": "pollis-delivery",
+            "sha": GIT_SHA,
+            "instance": instance::id(),
+        })),
+    )
+}
+
+/// GET /metrics — in-process counters in the Prometheus text format. Open, like
+/// `/version`: counts only, nothing per user or per conversation. Behind one
+/// hostname a scrape reaches whichever instance the proxy picks, so
+/// `pollis_ds_instance_info` says which one these counters belong to.
+async fn metrics(State(state): State<AppState>) -> impl IntoResponse {
+    use replay::ReplayReason::{Replayed, Stale};
+    let instance_id = instance::id();
+    let body = format!(
+        "# HELP pollis_ds_instance_info The DS instance serving this scrape.\n\
+         # TYPE pollis_ds_instance_info gauge\n\
+         pollis_ds_instance_info{{instance=\"{instance_id}\",sha=\"{GIT_SHA}\"}} 1\n\
+         # HELP pollis_ds_envelope_replays_rejected_total Envelope writes refused as replays.\n\
+         # TYPE pollis_ds_envelope_replays_rejected_total counter\n\
+         pollis_ds_envelope_replays_rejected_total{{reason=\"replayed\"}} {}\n\
+         pollis_ds_envelope_replays_rejected_total{{reason=\"stale\"}} {}\n",
+        state.replay.rejected(Replayed),
+        state.replay.rejected(Stale),
+    );
+    (
+        StatusCode::OK,
+        [(header::CONTENT_TYPE, "text/plain; version=0.0.4")],
+        body,
     )
 }
 
@@ -345,7 +641,10 @@ async fn submit(
         )
         .await
         {
-            Ok(user_id) => Some(user_id),
+            Ok(user_id) => {
+                inactivity::record_activity(&state, &user_id).await;
+                Some(user_id)
+            }
             Err(rej) => return Ok(rej.into_response()),
         }
     } else {
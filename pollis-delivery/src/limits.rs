@@ -0,0 +1,173 @@
+//! Per-deployment size limits on groups.
+//!
+//! Four caps, checked by the handlers before the write that would cross them:
+//!
+//!   - **members per group** — invite accept and join-request approve.
+//!   - **channels per group** — channel create (default channels created with
+//!     a new group are well under any sane cap and aren't counted).
+//!   - **groups per user** — group create, invite accept, join-request approve
+//!     (the joining user is the one whose count grows).
+//!   - **pins per conversation** — pin
+//!     ([`crate::messages::apply_pin_message_capped`]); re-pinning an already
+//!     pinned message isn't counted.
+//!
+//! A cap of `0` means unlimited. A rejected write is a `409` with a structured
+//! body (`{"error":"limit_exceeded","limit":"<name>","max":N}`) so the client
+//! can tell it apart from an authz `403`. `GET /v1/limits` serves the current
+//! config so clients can preflight (grey out "New channel" at the cap) instead
+//! of discovering it on submit.
+//!
+//! The checks are count-then-write, not transactional with the write: two
+//! racing joins can both pass at `max - 1`. The caps exist to bound MLS group
+//! size and abuse, not as an exact quota, so overshooting by a race is fine.
+//! The pin cap is the exception: its count and insert are one statement, since
+//! pins are cheap enough that a burst of racing clicks would otherwise overrun
+//! it.
+//!
+//! The checks sit in the axum handlers, not in `apply_*`, so the in-process
+//! test harness (which calls `apply_*` directly) runs without caps; the pin
+//! handler passes its cap to `apply_pin_message_capped`.
+
+use axum::{
+    extract::State,
+    http::StatusCode,
+    response::{IntoResponse, Response},
+    Json,
+};
+use libsql::Connection;
+use serde::Serialize;
+
+use crate::AppState;
+
+/// Size caps, read from DS env by [`LimitsConfig::from_env`]. `0` = unlimited.
+#[derive(Clone, Debug, Serialize)]
+pub struct LimitsConfig {
+    /// Max `group_member` rows per group.
+    pub max_members_per_group: u32,
+    /// Max `channels` rows per group.
+    pub max_channels_per_group: u32,
+    /// Max groups one user may belong to.
+    pub max_groups_per_user: u32,
+    /// Max `pinned_message` rows per channel or DM.
+    pub max_pins_per_conversation: u32,
+}
+
+impl Default for LimitsConfig {
+    fn default() -> Self {
+        // Members: every add is an MLS commit carrying a Welcome, and every
+        // send fans out to the whole tree — a few hundred is where that starts
+        // to hurt on slow devices. Channels and groups are abuse backstops.
+        // Pins: past a screenful the pinned list stops being a shortlist.
+        Self {
+            max_members_per_group: 500,
+            max_channels_per_group: 200,
+            max_groups_per_user: 200,
+            max_pins_per_conversation: 50,
+        }
+    }
+}
+
+impl LimitsConfig {
+    /// Build from DS environment, falling back to [`Default`] per field. Env:
+    /// `LIMIT_MAX_MEMBERS_PER_GROUP`, `LIMIT_MAX_CHANNELS_PER_GROUP`,
+    /// `LIMIT_MAX_GROUPS_PER_USER`, `LIMIT_MAX_PINS_PER_CONVERSATION`.
+    pub fn from_env() -> Self {
+        let mut cfg = Self::default();
+        if let Some(v) = env_u32("LIMIT_MAX_MEMBERS_PER_GROUP") {
+            cfg.max_members_per_group = v;
+        }
+        if let Some(v) = env_u32("LIMIT_MAX_CHANNELS_PER_GROUP") {
+            cfg.max_channels_per_group = v;
+        }
+        if let Some(v) = env_u32("LIMIT_MAX_GROUPS_PER_USER") {
+            cfg.max_groups_per_user = v;
+        }
+        if let Some(v) = env_u32("LIMIT_MAX_PINS_PER_CONVERSATION") {
+            cfg.max_pins_per_conversation = v;
+        }
+        cfg
+    }
+}
+
+fn env_u32(key: &str) -> Option<u32> {
+    std::env::var(key).ok().and_then(|s| s.parse().ok())
+}
+
+/// `409` for a write that would cross `limit`.
+pub(crate) fn limit_exceeded(limit: &str, max: u32) -> Response {
+    (
+        StatusCode::CONFLICT,
+        Json(serde_json::json!({ "error": "limit_exceeded", "limit": limit, "max": max })),
+    )
+        .into_response()
+}
+
+async fn count(conn: &Connection, sql: &str, id: &str) -> anyhow::Result<u32> {
+    let mut rows = conn.query(sql, libsql::params![id.to_string()]).await?;
+    let n: i64 = match rows.next().await? {
+        Some(row) => row.get(0)?,
+        None => 0,
+    };
+    Ok(n.max(0) as u32)
+}
+
+/// `Some(409)` when `group_id` already has `max_members_per_group` members.
+pub(crate) async fn check_members(
+    conn: &Connection,
+    cfg: &LimitsConfig,
+    group_id: &str,
+) -> anyhow::Result<Option<Response>> {
+    let max = cfg.max_members_per_group;
+    if max == 0 {
+        return Ok(None);
+    }
+    let n = count(conn, "SELECT COUNT(*) FROM group_member WHERE group_id = ?1", group_id).await?;
+    Ok((n >= max).then(|| limit_exceeded("max_members_per_group", max)))
+}
+
+/// `Some(409)` when `group_id` already has `max_channels_per_group` channels.
+pub(crate) async fn check_channels(
+    conn: &Connection,
+    cfg: &LimitsConfig,
+    group_id: &str,
+) -> anyhow::Result<Option<Response>> {
+    let max = cfg.max_channels_per_group;
+    if max == 0 {
+        return Ok(None);
+    }
+    let n = count(conn, "SELECT COUNT(*) FROM channels WHERE group_id = ?1", group_id).await?;
+    Ok((n >= max).then(|| limit_exceeded("max_channels_per_group", max)))
+}
+
+/// `Some(409)` when `user_id` already belongs to `max_groups_per_user` groups.
+pub(crate) async fn check_groups(
+    conn: &Connection,
+    cfg: &LimitsConfig,
+    user_id: &str,
+) -> anyhow::Result<Option<Response>> {
+    let max = cfg.max_groups_per_user;
+    if max == 0 {
+        return Ok(None);
+    }
+    let n = count(conn, "SELECT COUNT(*) FROM group_member WHERE user_id = ?1", user_id).await?;
+    Ok((n >= max).then(|| limit_exceeded("max_groups_per_user", max)))
+}
+
+/// Both join-side caps for adding `user_id` to `group_id`.
+pub(crate) async fn check_join(
+    conn: &Connection,
+    cfg: &LimitsConfig,
+    group_id: &str,
+    user_id: &str,
+) -> anyhow::Result<Option<Response>> {
+    if let Some(resp) = check_members(conn, cfg, group_id).await? {
+        return Ok(Some(resp));
+    }
+    check_groups(conn, cfg, user_id).await
+}
+
+/// `GET /v1/limits` — the current caps, unauthenticated (they're not secret
+/// and a pre-enrollment client may want them).
+pub async fn get_limits(State(state): State<AppState>) -> Json<LimitsConfig> {
+    Json(state.limits.clone())
+}
@@ -11,6 +11,15 @@
 //!   DEV_OTP             dev/harness override — skip the email send and force this
 //!                       exact OTP code (optional).
 //!   OTP_TTL_SECS        OTP lifetime in seconds (optional, default 600).
+//!   DS_SHARED_STATE     `db` → keep rate-limit/flood counters, idempotency
+//!                       keys and sign-in codes/sessions in the main DB so
+//!                       several instances can share them (optional, default
+//!                       in-memory; see `docs/deployments.md`).
+//!   DS_INSTANCE_ID      this instance's name in logs, `/version` and `/metrics`
+//!                       (optional, default a random ULID).
+//!   STORAGE_TARGETS     ids of extra S3 stores groups may pin attachments to,
+//!                       each configured by `STORAGE_TARGET_<ID>_*` (optional;
+//!                       see `residency`).
 //!
 //! `RESEND_API_KEY` / `DEV_OTP` / `OTP_TTL_SECS` are read by
 //! `OtpConfig::from_env` inside `build_router_with_log_db`.
@@ -78,7 +87,10 @@ async fn main() -> Result<()> {
     let listener = tokio::net::TcpListener::bind(("0.0.0.0", port))
         .await
         .with_context(|| format!("bind 0.0.0.0:{port}"))?;
-    tracing::info!("pollis-delivery listening on 0.0.0.0:{port}");
+    tracing::info!(
+        instance = pollis_delivery::instance::id(),
+        "pollis-delivery listening on 0.0.0.0:{port}"
+    );
 
     axum::serve(listener, app)
         .with_graceful_shutdown(shutdown_signal())
@@ -0,0 +1,259 @@
+//! Maintenance mode: refuse writes, keep serving reads.
+//!
+//! While a maintenance window is open every mutating request (anything but
+//! `GET`/`HEAD`/`OPTIONS`, minus the read-only `POST`s in [`EXEMPT`]) is
+//! answered `503` with a structured body and a `Retry-After`:
+//!
+//! ```json
+//! { "error": "MAINTENANCE", "eta": 1767225600, "message": "DB migration" }
+//! ```
+//!
+//! plus `X-Pollis-Maintenance: 1`. `eta` (unix seconds, optional) is the
+//! operator's estimate of when writes reopen. Clients show a banner, hold their
+//! writes and try again at the ETA; `GET /v1/maintenance` reports the current
+//! window (open, like `/v1/limits`).
+//!
+//! Opened and closed two ways:
+//!   - at start: `POLLIS_DS_MAINTENANCE=1`, with optional
+//!     `POLLIS_DS_MAINTENANCE_ETA` and `POLLIS_DS_MAINTENANCE_MESSAGE`;
+//!   - live: `POST /v1/admin/maintenance` with `Authorization: Bearer
+//!     <MAINTENANCE_ADMIN_TOKEN>` and `{ "enabled", "eta"?, "message"? }`. The
+//!     route 503s (as "not configured") without the token.
+//!
+//! **Store:** in-memory, like [`crate::replay`]. A restart reverts to the env
+//! setting, which is what a deploy that needs the window wants anyway. The
+//! admin switch only flips the instance it reaches; a scaled-out DS sets
+//! `POLLIS_DS_MAINTENANCE*` in env instead.
+
+use std::sync::{Arc, RwLock};
+
+use axum::{
+    extract::{Request, State},
+    http::{header, HeaderMap, HeaderValue, Method, StatusCode},
+    middleware::Next,
+    response::{IntoResponse, Response},
+    Json,
+};
+use serde::{Deserialize, Serialize};
+
+use crate::auth::now_unix;
+use crate::AppState;
+
+pub const ADMIN_PATH: &str = "/v1/admin/maintenance";
+/// Set (to `1`) on every maintenance refusal, so a client can tell it from the
+/// broker's "not configured" 503s without reading the body.
+pub const HEADER: &str = "x-pollis-maintenance";
+
+/// `POST`s that only read, so they stay open during maintenance: voice and
+/// realtime keep working, and clients can still mint read tokens and presign
+/// downloads. A presigned upload is harmless on its own — the
+/// `/v1/attachments/register` that would reference it is refused.
+const EXEMPT: &[&str] = &[
+    ADMIN_PATH,
+    "/v1/usage/me",
+    "/v1/slugs/normalize",
+    "/v1/livekit/token",
+    "/v1/livekit/send-data",
+    "/v1/livekit/participants",
+    "/v1/turso/token",
+    "/v1/r2/presign",
+];
+
+/// `Retry-After` when the window has no ETA (or it has passed), seconds.
+const DEFAULT_RETRY_SECS: i64 = 60;
+/// Longest `message` accepted from the admin route.
+const MAX_MESSAGE_CHARS: usize = 280;
+
+/// An open maintenance window. Also the admin route's request body (with
+/// `enabled`) and the status route's reply.
+#[derive(Clone, Debug, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct MaintenanceStatus {
+    pub enabled: bool,
+    /// When writes are expected back, unix seconds.
+    #[serde(default)]
+    pub eta: Option<i64>,
+    /// Operator note shown in the client banner.
+    #[serde(default)]
+    pub message: Option<String>,
+}
+
+/// Maintenance settings, read from DS env by [`MaintenanceConfig::from_env`].
+#[derive(Clone, Debug, Default)]
+pub struct MaintenanceConfig {
+    /// Window open at start.
+    pub initial: MaintenanceStatus,
+    /// Bearer token for `POST /v1/admin/maintenance`. `None` → the route 503s.
+    /// NEVER logged.
+    pub admin_token: Option<String>,
+}
+
+impl MaintenanceConfig {
+    /// Build from DS environment. Env: `POLLIS_DS_MAINTENANCE` (`true`/`1`
+    /// opens the window at start), `POLLIS_DS_MAINTENANCE_ETA`,
+    /// `POLLIS_DS_MAINTENANCE_MESSAGE`, `MAINTENANCE_ADMIN_TOKEN`.
+    pub fn from_env() -> Self {
+        let var = |k: &str| std::env::var(k).ok().filter(|s| !s.is_empty());
+        Self {
+            initial: MaintenanceStatus {
+                enabled: matches!(
+                    var("POLLIS_DS_MAINTENANCE").as_deref(),
+                    Some("true") | Some("TRUE") | Some("True") | Some("1")
+                ),
+                eta: var("POLLIS_DS_MAINTENANCE_ETA").and_then(|s| s.parse().ok()),
+                message: var("POLLIS_DS_MAINTENANCE_MESSAGE"),
+            },
+            admin_token: var("MAINTENANCE_ADMIN_TOKEN"),
+        }
+    }
+}
+
+/// The live switch. `Clone` is shallow (shared `Arc`) so it rides on the
+/// `Clone` `AppState`.
+#[derive(Clone, Default)]
+pub struct Maintenance {
+    inner: Arc<RwLock<MaintenanceStatus>>,
+}
+
+impl Maintenance {
+    pub fn status(&self) -> MaintenanceStatus {
+        self.inner.read().map(|s| s.clone()).unwrap_or_default()
+    }
+
+    pub fn set(&self, status: MaintenanceStatus) {
+        if let Ok(mut s) = self.inner.write() {
+            *s = status;
+        }
+    }
+}
+
+/// Whether maintenance refuses this request.
+fn is_mutating(method: &Method, path: &str) -> bool {
+    !matches!(*method, Method::GET | Method::HEAD | Method::OPTIONS) && !EXEMPT.contains(&path)
+}
+
+/// Seconds until `eta`, or [`DEFAULT_RETRY_SECS`] without one.
+fn retry_after_secs(eta: Option<i64>, now: i64) -> i64 {
+    match eta {
+        Some(eta) if eta > now => eta - now,
+        _ => DEFAULT_RETRY_SECS,
+    }
+}
+
+/// The `503` every refused write gets.
+fn maintenance_response(status: &MaintenanceStatus) -> Response {
+    let retry = retry_after_secs(status.eta, now_unix());
+    let mut resp = (
+        StatusCode::SERVICE_UNAVAILABLE,
+        Json(serde_json::json!({
+            "error": "MAINTENANCE",
+            "eta": status.eta,
+            "message": status.message,
+        })),
+    )
+        .into_response();
+    if let Ok(v) = HeaderValue::from_str(&retry.to_string()) {
+        resp.headers_mut().insert(header::RETRY_AFTER, v);
+    }
+    resp.headers_mut().insert(HEADER, HeaderValue::from_static("1"));
+    resp
+}
+
+/// Middleware: refuse writes while a window is open.
+pub async fn gate(State(state): State<AppState>, req: Request, next: Next) -> Response {
+    if is_mutating(req.method(), req.uri().path()) {
+        let status = state.maintenance.status();
+        if status.enabled {
+            return maintenance_response(&status);
+        }
+    }
+    next.run(req).await
+}
+
+/// GET /v1/maintenance — the current window. Open, so a client can check it
+/// without signing anything.
+pub async fn get_status(State(state): State<AppState>) -> Json<MaintenanceStatus> {
+    Json(state.maintenance.status())
+}
+
+/// POST /v1/admin/maintenance — open, update or close the window.
+/// Operator-only: `Authorization: Bearer <MAINTENANCE_ADMIN_TOKEN>`. Replies
+/// with the new status.
+pub async fn set_status(
+    State(state): State<AppState>,
+    headers: HeaderMap,
+    Json(body): Json<MaintenanceStatus>,
+) -> Response {
+    let Some(expected) = state.maintenance_config.admin_token.as_deref() else {
+        return (
+            StatusCode::SERVICE_UNAVAILABLE,
+            Json(serde_json::json!({ "error": "maintenance admin not configured" })),
+        )
+            .into_response();
+    };
+    let presented = headers
+        .get(header::AUTHORIZATION)
+        .and_then(|v| v.to_str().ok())
+        .and_then(|v| v.strip_prefix("Bearer "))
+        .unwrap_or("");
+    if !constant_time_eq(presented.as_bytes(), expected.as_bytes()) {
+        return (
+            StatusCode::UNAUTHORIZED,
+            Json(serde_json::json!({ "error": "unauthorized" })),
+        )
+            .into_response();
+    }
+
+    let status = if body.enabled {
+        MaintenanceStatus {
+            enabled: true,
+            eta: body.eta,
+            message: body
+                .message
+                .map(|m| m.trim().chars().take(MAX_MESSAGE_CHARS).collect::<String>())
+                .filter(|m| !m.is_empty()),
+        }
+    } else {
+        MaintenanceStatus::default()
+    };
+    tracing::warn!(
+        enabled = status.enabled,
+        eta = ?status.eta,
+        "maintenance mode {}",
+        if status.enabled { "ON" } else { "OFF" }
+    );
+    state.maintenance.set(status.clone());
+    Json(status).into_response()
+}
+
+/// Constant-time byte compare, so the admin token can't be guessed by timing.
+fn constant_time_eq(a: &[u8], b: &[u8]) -> bool {
+    if a.len() != b.len() {
+        return false;
+    }
+    let mut diff: u8 = 0;
+    for (x, y) in a.iter().zip(b.iter()) {
+        diff |= x ^ y;
+    }
+    diff == 0
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn only_writes_are_refused() {
+        assert!(is_mutating(&Method::POST, "/v1/messages/send"));
+        assert!(is_mutating(&Method::PUT, "/v1/blobs/media/x"));
+        assert!(!is_mutating(&Method::GET, "/v1/commits/c1"));
+        assert!(!is_mutating(&Method::POST, "/v1/turso/token"));
+        assert!(!is_mutating(&Method::POST, ADMIN_PATH));
+    }
+
+    #[test]
+    fn retry_after_counts_down_to_the_eta() {
+        assert_eq!(retry_after_secs(Some(1_120), 1_000), 120);
+        assert_eq!(retry_after_secs(Some(900), 1_000), DEFAULT_RETRY_SECS);
+        assert_eq!(retry_after_secs(None, 1_000), DEFAULT_RETRY_SECS);
+    }
+}
@@ -1,4 +1,4 @@
-//! Domain A — message envelopes, edits/deletes, reactions, watermarks, and
+//! Domain A — message envelopes, edits/deletes, reactions, pins, watermarks, and
 //! attachment dedup rows. The **reference vertical slice** for Goal B (#419):
 //! every later write-domain (B/C/D) copies the shape established here.
 //!
@@ -42,6 +42,8 @@
 //!     owning group (a permission check, not an author check).
 //!   - reactions: the user is a member, and may only write/remove their OWN
 //!     reaction (`user_id` is bound to the authenticated user).
+//!   - pins: the user is a member of the conversation. A pin may be removed
+//!     only by whoever pinned it or, in a group channel, a group admin.
 //!   - watermark: the row is per `(conversation, user, device)`; the user may
 //!     only advance their own.
 //!   - envelope GC: the user is a member. (See the TODO on [`apply_envelope_gc`]
@@ -55,16 +57,23 @@
 use axum::{
     body::Bytes,
     extract::State,
-    http::{HeaderMap, Method, Uri},
-    response::Response,
+    http::{HeaderMap, Method, StatusCode, Uri},
+    response::{IntoResponse, Response},
+    Json,
 };
 use libsql::Connection;
 use serde::Deserialize;
 use ulid::Ulid;
 
 use crate::error::AppError;
+use crate::flood::{flood_detected, record_incident, FloodOutcome};
+use crate::limits;
+use crate::ratelimit::now_unix;
+use crate::replay::{replay_rejected, ReplayOutcome};
+use crate::usage::{record_if_enabled, UsageDelta};
+use crate::webhooks::GroupEvent;
 use crate::writes::{
-    bad_request, gate, is_member, outcome_response, resolve_actor, WriteOutcome,
+    bad_request, gate, is_member, ok_json, outcome_response, resolve_actor, WriteOutcome,
 };
 use crate::AppState;
 
@@ -118,6 +127,19 @@ DELETE FROM message_envelope
      )
    )";
 
+// Channel retention (`channels.retention_days`, migration 000016): an admin-set
+// window on top of the TTL/watermark GC above. Kept as its own statement so the
+// copied predicate stays untouched. NULL retention makes the bound NULL, which
+// deletes nothing.
+const CLEANUP_CHANNEL_RETENTION: &str = "\
+DELETE FROM message_envelope
+ WHERE conversation_id = ?1
+   AND sent_at < (
+     SELECT datetime('now', '-' || retention_days || ' days')
+       FROM channels
+      WHERE id = ?1 AND retention_days IS NOT NULL
+   )";
+
 // ── Shared authz helpers ─────────────────────────────────────────────────────
 
 /// The conversation a message belongs to, resolved from any envelope carrying
@@ -232,12 +254,240 @@ pub async fn send_message(
         Ok(b) => b,
         Err(_) => return Ok(bad_request("invalid body")),
     };
+    // Replay check first, so a replayed envelope can't get its real sender
+    // muted for duplicates by the flood check below.
+    if let Some(resp) = refuse_replay(&state, &parsed.conversation_id, &parsed.id) {
+        return Ok(resp);
+    }
+    let result = send_admitted(&state, authed, &parsed).await;
+    release_unless_written(&state, &parsed.conversation_id, &parsed.id, &result);
+    result
+}
+
+/// The send after [`refuse_replay`] admitted its id: flood check, write, and
+/// the post-write fan-out.
+async fn send_admitted(
+    state: &AppState,
+    authed: Option<String>,
+    parsed: &SendMessageBody,
+) -> Result<Response, AppError> {
+    let conn = state.db.conn()?;
+    // Flood check before the write, keyed on the authenticated user (so sealed
+    // sends are still attributed) or, on the no-auth path, the body's sender.
+    let sender = authed
+        .clone()
+        .or_else(|| parsed.sender_id.clone())
+        .unwrap_or_default();
+    match state
+        .flood
+        .check_send(
+            &state.flood_config,
+            &sender,
+            &parsed.conversation_id,
+            &parsed.ciphertext,
+            now_unix(),
+        )
+        .await
+    {
+        FloodOutcome::Allowed => {}
+        FloodOutcome::Muted { retry_after } => return Ok(flood_detected("muted", retry_after)),
+        FloodOutcome::Detected {
+            reason,
+            retry_after,
+        } => {
+            tracing::warn!(
+                user_id = %sender,
+                conversation_id = %parsed.conversation_id,
+                reason = reason.as_str(),
+                "flood detected on send; sender muted for {retry_after}s"
+            );
+            if let Err(e) =
+                record_incident(&conn, &sender, &parsed.conversation_id, reason, retry_after).await
+            {
+                tracing::warn!("record flood incident: {e}");
+            }
+            return Ok(flood_detected(reason.as_str(), retry_after));
+        }
+    }
+    let outcome = match store_envelope(&conn, authed.as_deref(), parsed).await? {
+        StoreOutcome::Stored => WriteOutcome::Ok,
+        StoreOutcome::Forbidden => WriteOutcome::Forbidden,
+        // A resend of an envelope that's already stored — most likely a retry
+        // whose first reply was lost, landing on another instance than the one
+        // that wrote it. Already delivered, so no second ping or webhook.
+        StoreOutcome::AlreadyStored => {
+            return Ok(ok_json(serde_json::json!({ "status": "ok", "duplicate": true })));
+        }
+        StoreOutcome::IdTaken => return Ok(envelope_id_taken()),
+    };
+    if matches!(outcome, WriteOutcome::Ok) {
+        state.webhooks.emit(GroupEvent::MessagePosted {
+            conversation_id: parsed.conversation_id.clone(),
+        });
+        let delta = UsageDelta {
+            envelopes: 1,
+            envelope_bytes: parsed.ciphertext.len() as i64,
+            ..UsageDelta::default()
+        };
+        record_if_enabled(state, &conn, &sender, delta).await;
+        if ping_new_message(state, &parsed.conversation_id).await {
+            return Ok(ok_json(serde_json::json!({ "status": "ok", "notified": true })));
+        }
+    }
+    outcome_response(outcome)
+}
+
+/// How long a send waits on its `new_message` ping before answering without
+/// `notified`. Short: the envelope is already stored, this only decides who
+/// publishes the wake-up.
+const NEW_MESSAGE_PING_TIMEOUT: std::time::Duration = std::time::Duration::from_secs(2);
+
+/// Wake the conversation's online members once the envelope is written. The
+/// sender's client used to publish this after its DS write returned, so a
+/// client that died (or lost the network) between the two left the message
+/// stored but nobody nudged until their next poll. Same content-free payload
+/// and room the client used (`livekit_signalling::new_message_payload`): a
+/// group channel pings its group's room with `channel_id`, a DM pings its own
+/// room with `conversation_id`.
+///
+/// The ping runs in its own task, so it still goes out when the sender's
+/// connection drops and the request future is cancelled. The send waits on that
+/// task for up to [`NEW_MESSAGE_PING_TIMEOUT`] and returns whether the ping
+/// reached LiveKit — `false` when LiveKit isn't configured, the send failed, or
+/// it ran out of time. The response then omits `notified` and the client
+/// publishes itself; a ping that lands late as well is a harmless duplicate
+/// wake-up.
+async fn ping_new_message(state: &AppState, conversation_id: &str) -> bool {
+    if state.broker.livekit_ready().is_none() {
+        return false;
+    }
+    let task_state = state.clone();
+    let task_conversation = conversation_id.to_string();
+    let ping = tokio::spawn(async move {
+        let group_id = channel_group(&task_state, &task_conversation)
+            .await
+            .map_err(|e| format!("resolve room: {e}"))?;
+        let (room, payload) = new_message_ping(&task_conversation, group_id);
+        crate::broker::room_send_data(&task_state, &room, &payload).await
+    });
+    match tokio::time::timeout(NEW_MESSAGE_PING_TIMEOUT, ping).await {
+        Ok(Ok(Ok(()))) => true,
+        Ok(Ok(Err(e))) => {
+            tracing::warn!(%conversation_id, "new_message ping: {e}");
+            false
+        }
+        Ok(Err(e)) => {
+            tracing::warn!(%conversation_id, "new_message ping: task failed: {e}");
+            false
+        }
+        Err(_) => {
+            tracing::warn!(%conversation_id, "new_message ping: timed out");
+            false
+        }
+    }
+}
+
+/// The owning group of `conversation_id` when it is a group channel; `None`
+/// for a DM.
+async fn channel_group(
+    state: &AppState,
+    conversation_id: &str,
+) -> Result<Option<String>, AppError> {
     let conn = state.db.conn()?;
-    outcome_response(apply_send_message(&conn, authed.as_deref(), &parsed).await?)
+    let mut rows = conn
+        .query(
+            "SELECT group_id FROM channels WHERE id = ?1",
+            libsql::params![conversation_id.to_string()],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) => Some(row.get::<String>(0)?),
+        None => None,
+    })
+}
+
+/// `(room, payload)` for the `new_message` ping — see [`ping_new_message`].
+pub fn new_message_ping(
+    conversation_id: &str,
+    group_id: Option<String>,
+) -> (String, serde_json::Value) {
+    match group_id {
+        Some(group_id) => (
+            group_id,
+            serde_json::json!({
+                "type": "new_message",
+                "channel_id": conversation_id,
+                "conversation_id": null,
+            }),
+        ),
+        None => (
+            conversation_id.to_string(),
+            serde_json::json!({
+                "type": "new_message",
+                "channel_id": null,
+                "conversation_id": conversation_id,
+            }),
+        ),
+    }
+}
+
+/// `Some(409)` when `envelope_id` is a replay for `conversation_id` (see
+/// [`crate::replay`]); otherwise the id is now taken.
+fn refuse_replay(state: &AppState, conversation_id: &str, envelope_id: &str) -> Option<Response> {
+    match state
+        .replay
+        .admit(&state.replay_config, conversation_id, envelope_id, now_unix())
+    {
+        ReplayOutcome::Fresh => None,
+        ReplayOutcome::Rejected(reason) => {
+            tracing::warn!(
+                conversation_id = %conversation_id,
+                reason = reason.as_str(),
+                "envelope replay refused"
+            );
+            Some(replay_rejected(reason))
+        }
+    }
+}
+
+/// Give back an id [`refuse_replay`] took when the write didn't land (refused
+/// or failed), so an honest retry of it isn't taken for a replay.
+fn release_unless_written(
+    state: &AppState,
+    conversation_id: &str,
+    envelope_id: &str,
+    result: &Result<Response, AppError>,
+) {
+    if !matches!(result, Ok(resp) if resp.status().is_success()) {
+        state.replay.release(conversation_id, envelope_id);
+    }
+}
+
+/// What [`store_envelope`] did with a send.
+pub(crate) enum StoreOutcome {
+    Stored,
+    Forbidden,
+    /// The id is already stored with this conversation and ciphertext: the
+    /// send already happened.
+    AlreadyStored,
+    /// The id is already stored with other content.
+    IdTaken,
+}
+
+/// `409 {"error":"ENVELOPE_ID_TAKEN"}` — a send reusing a stored envelope's id
+/// for different content. Not a retry, so the client must not treat it as
+/// delivered.
+fn envelope_id_taken() -> Response {
+    (
+        StatusCode::CONFLICT,
+        Json(serde_json::json!({ "error": "ENVELOPE_ID_TAKEN" })),
+    )
+        .into_response()
 }
 
 /// INSERT a `type='message'` envelope (the send). Authz: the authenticated user
-/// is a current member of the conversation.
+/// is a current member of the conversation. A resend of an envelope that's
+/// already stored is `Ok` and writes nothing — see [`store_envelope`].
 ///
 /// Sender binding depends on sealing (issue #331,
 /// `docs/metadata-minimization-design.md` §2):
@@ -255,6 +505,24 @@ pub async fn apply_send_message(
     authed: Option<&str>,
     body: &SendMessageBody,
 ) -> anyhow::Result<WriteOutcome> {
+    match store_envelope(conn, authed, body).await? {
+        StoreOutcome::Stored | StoreOutcome::AlreadyStored => Ok(WriteOutcome::Ok),
+        StoreOutcome::Forbidden => Ok(WriteOutcome::Forbidden),
+        StoreOutcome::IdTaken => anyhow::bail!("envelope id {} already used", body.id),
+    }
+}
+
+/// [`apply_send_message`], telling a first write from a resend. Delivery is
+/// at-least-once: a client retries a send until some instance answers, so the
+/// same envelope may arrive more than once, on any instance. The primary key
+/// keeps it to one row; a resend with the same conversation and ciphertext is
+/// [`StoreOutcome::AlreadyStored`] rather than a constraint error, and
+/// receivers dedupe by envelope id besides.
+pub(crate) async fn store_envelope(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &SendMessageBody,
+) -> anyhow::Result<StoreOutcome> {
     let sealed = body.sealed != 0;
     // `member_check_user` is whose membership we verify; `stored_sender` is what
     // lands in the `sender_id` column.
@@ -274,28 +542,46 @@ pub async fn apply_send_message(
         // request to send only as itself.
         match resolve_actor(authed, body.sender_id.as_deref()) {
             Ok(s) => (s.clone(), s),
-            Err(o) => return Ok(o),
+            Err(_) => return Ok(StoreOutcome::Forbidden),
         }
     };
     if authed.is_some() && !is_member(conn, &body.conversation_id, &member_check_user).await? {
-        return Ok(WriteOutcome::Forbidden);
+        return Ok(StoreOutcome::Forbidden);
     }
-    conn.execute(
-        "INSERT INTO message_envelope \
-             (id, conversation_id, sender_id, ciphertext, reply_to_id, sent_at, sealed) \
-         VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)",
-        libsql::params![
-            body.id.clone(),
-            body.conversation_id.clone(),
-            stored_sender,
-            body.ciphertext.clone(),
-            body.reply_to_id.clone(),
-            body.sent_at.clone(),
-            body.sealed,
-        ],
-    )
-    .await?;
-    Ok(WriteOutcome::Ok)
+    let inserted = conn
+        .execute(
+            "INSERT INTO message_envelope \
+                 (id, conversation_id, sender_id, ciphertext, reply_to_id, sent_at, sealed) \
+             VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7) \
+             ON CONFLICT(id) DO NOTHING",
+            libsql::params![
+                body.id.clone(),
+                body.conversation_id.clone(),
+                stored_sender,
+                body.ciphertext.clone(),
+                body.reply_to_id.clone(),
+                body.sent_at.clone(),
+                body.sealed,
+            ],
+        )
+        .await?;
+    if inserted > 0 {
+        return Ok(StoreOutcome::Stored);
+    }
+    let mut rows = conn
+        .query(
+            "SELECT conversation_id = ?2 AND ciphertext = ?3 FROM message_envelope WHERE id = ?1",
+            libsql::params![
+                body.id.clone(),
+                body.conversation_id.clone(),
+                body.ciphertext.clone(),
+            ],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) if row.get::<i64>(0)? != 0 => StoreOutcome::AlreadyStored,
+        _ => StoreOutcome::IdTaken,
+    })
 }
 
 // ── POST /v1/messages/edit ───────────────────────────────────────────────────
@@ -326,8 +612,31 @@ pub async fn edit_message(
         Ok(b) => b,
         Err(_) => return Ok(bad_request("invalid body")),
     };
+    // A flood mute covers edits too, so a muted sender can't keep pushing
+    // content by rewriting one message.
+    let sender = authed
+        .clone()
+        .or_else(|| parsed.sender_id.clone())
+        .unwrap_or_default();
+    if let Some(retry_after) = state.flood.muted_for(&sender, now_unix()).await {
+        return Ok(flood_detected("muted", retry_after));
+    }
+    // Replaying an older edit would delete the newer one and restore its text.
+    if let Some(resp) = refuse_replay(&state, &parsed.conversation_id, &parsed.envelope_id) {
+        return Ok(resp);
+    }
+    let result = edit_admitted(&state, authed.as_deref(), &parsed).await;
+    release_unless_written(&state, &parsed.conversation_id, &parsed.envelope_id, &result);
+    result
+}
+
+async fn edit_admitted(
+    state: &AppState,
+    authed: Option<&str>,
+    parsed: &EditMessageBody,
+) -> Result<Response, AppError> {
     let conn = state.db.conn()?;
-    outcome_response(apply_edit_message(&conn, authed.as_deref(), &parsed).await?)
+    outcome_response(apply_edit_message(&conn, authed, parsed).await?)
 }
 
 /// Replace the single pending edit envelope (DELETE prior + INSERT new) in one
@@ -424,7 +733,7 @@ pub async fn delete_message(
 ///
 /// **Self-branch** (`msg_sender_id == actor`): gated on **membership** only.
 /// Removes the original envelope (unscoped — a sealed row has no matchable
-/// sender) and any pending edit; writes **no** tombstone. A non-author member can
+/// sender), any pending edit and its pin; writes **no** tombstone. A non-author member can
 /// thus remove a not-yet-fetched envelope (an accepted availability trade, #607),
 /// but cannot forge a *delete appearance*: making other members drop an
 /// already-fetched copy requires either a valid E2EE redaction (honored on ingest
@@ -433,7 +742,7 @@ pub async fn delete_message(
 ///
 /// **Admin-branch** (`msg_sender_id != actor`): the actor must be a group admin
 /// of the channel (a re-derived permission check, not an author check). Removes
-/// the envelope + pending edit and writes a `type='delete'` tombstone so every
+/// the envelope + pending edit + pin and writes a `type='delete'` tombstone so every
 /// member soft-deletes on next ingest. Server-authorized moderation.
 pub async fn apply_delete_message(
     conn: &Connection,
@@ -468,6 +777,11 @@ pub async fn apply_delete_message(
             libsql::params![body.message_id.clone()],
         )
         .await?;
+        tx.execute(
+            "DELETE FROM pinned_message WHERE conversation_id = ?1 AND message_id = ?2",
+            libsql::params![body.conversation_id.clone(), body.message_id.clone()],
+        )
+        .await?;
         tx.commit().await?;
         return Ok(WriteOutcome::Ok);
     }
@@ -494,6 +808,11 @@ pub async fn apply_delete_message(
         libsql::params![body.message_id.clone()],
     )
     .await?;
+    tx.execute(
+        "DELETE FROM pinned_message WHERE conversation_id = ?1 AND message_id = ?2",
+        libsql::params![body.conversation_id.clone(), body.message_id.clone()],
+    )
+    .await?;
     tx.execute(
         "INSERT INTO message_envelope \
              (id, conversation_id, sender_id, ciphertext, sent_at, type, target_message_id) \
@@ -618,6 +937,179 @@ pub async fn apply_remove_reaction(
     Ok(WriteOutcome::Ok)
 }
 
+// ── POST /v1/pins/add  &  /v1/pins/remove ────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct PinBody {
+    pub conversation_id: String,
+    pub message_id: String,
+    /// No-auth fallback for the pinning / unpinning user.
+    #[serde(default)]
+    pub user_id: Option<String>,
+}
+
+pub async fn pin_message(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: PinBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let conn = state.db.conn()?;
+    let max = state.limits.max_pins_per_conversation;
+    match apply_pin_message_capped(&conn, authed.as_deref(), &parsed, max).await? {
+        PinOutcome::Written(outcome) => outcome_response(outcome),
+        PinOutcome::AtCap => Ok(limits::limit_exceeded("max_pins_per_conversation", max)),
+    }
+}
+
+pub async fn unpin_message(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: PinBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let conn = state.db.conn()?;
+    outcome_response(apply_unpin_message(&conn, authed.as_deref(), &parsed).await?)
+}
+
+/// Pin `message_id` in `conversation_id`. Re-pinning is a no-op that keeps the
+/// original pinner. As with reactions, a message whose envelope has aged out
+/// can still be pinned; while the envelope exists it must belong to the named
+/// conversation, so a pin can't be filed under a room the message isn't in.
+/// No pin cap — see [`apply_pin_message_capped`].
+pub async fn apply_pin_message(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &PinBody,
+) -> anyhow::Result<WriteOutcome> {
+    let outcome = apply_pin_message_capped(conn, authed, body, 0).await?;
+    Ok(match outcome {
+        PinOutcome::Written(outcome) => outcome,
+        // Only reachable with a cap.
+        PinOutcome::AtCap => WriteOutcome::Ok,
+    })
+}
+
+/// What [`apply_pin_message_capped`] did.
+pub enum PinOutcome {
+    Written(WriteOutcome),
+    /// The conversation already has `max_pins` pins and this message isn't one.
+    AtCap,
+}
+
+/// [`apply_pin_message`] under the `max_pins_per_conversation` cap (`0` =
+/// unlimited). The count and the insert are one statement, so two pins racing
+/// for the last slot can't both take it.
+pub async fn apply_pin_message_capped(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &PinBody,
+    max_pins: u32,
+) -> anyhow::Result<PinOutcome> {
+    let user = match resolve_actor(authed, body.user_id.as_deref()) {
+        Ok(u) => u,
+        Err(o) => return Ok(PinOutcome::Written(o)),
+    };
+    if authed.is_some() {
+        if !is_member(conn, &body.conversation_id, &user).await? {
+            return Ok(PinOutcome::Written(WriteOutcome::Forbidden));
+        }
+        if let Some(conv) = conversation_for_message(conn, &body.message_id).await? {
+            if conv != body.conversation_id {
+                return Ok(PinOutcome::Written(WriteOutcome::Forbidden));
+            }
+        }
+    }
+    let inserted = conn
+        .execute(
+            "INSERT INTO pinned_message (conversation_id, message_id, pinned_by, pinned_at) \
+             SELECT ?1, ?2, ?3, ?4 \
+             WHERE ?5 = 0 \
+                OR (SELECT COUNT(*) FROM pinned_message WHERE conversation_id = ?1) < ?5 \
+             ON CONFLICT (conversation_id, message_id) DO NOTHING",
+            libsql::params![
+                body.conversation_id.clone(),
+                body.message_id.clone(),
+                user,
+                now_rfc3339(),
+                i64::from(max_pins)
+            ],
+        )
+        .await?;
+    if inserted == 0 && max_pins > 0 && !is_pinned(conn, body).await? {
+        return Ok(PinOutcome::AtCap);
+    }
+    Ok(PinOutcome::Written(WriteOutcome::Ok))
+}
+
+/// Whether `body.message_id` is pinned in `body.conversation_id`.
+async fn is_pinned(conn: &Connection, body: &PinBody) -> anyhow::Result<bool> {
+    let mut rows = conn
+        .query(
+            "SELECT 1 FROM pinned_message WHERE conversation_id = ?1 AND message_id = ?2",
+            libsql::params![body.conversation_id.clone(), body.message_id.clone()],
+        )
+        .await?;
+    Ok(rows.next().await?.is_some())
+}
+
+/// Remove a pin. Allowed for the member who pinned it and, in a group channel,
+/// any admin of the owning group; DMs have no admins, so there it's the pinner
+/// only. Removing a pin that doesn't exist succeeds.
+pub async fn apply_unpin_message(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &PinBody,
+) -> anyhow::Result<WriteOutcome> {
+    let user = match resolve_actor(authed, body.user_id.as_deref()) {
+        Ok(u) => u,
+        Err(o) => return Ok(o),
+    };
+    if authed.is_some() {
+        let mut rows = conn
+            .query(
+                "SELECT pinned_by FROM pinned_message \
+                 WHERE conversation_id = ?1 AND message_id = ?2",
+                libsql::params![body.conversation_id.clone(), body.message_id.clone()],
+            )
+            .await?;
+        let pinned_by: String = match rows.next().await? {
+            Some(row) => row.get(0)?,
+            None => return Ok(WriteOutcome::Ok),
+        };
+        if pinned_by != user {
+            match channel_group_role(conn, &body.conversation_id, &user).await? {
+                Some(role) if role == "admin" => {}
+                _ => return Ok(WriteOutcome::Forbidden),
+            }
+        }
+    }
+    conn.execute(
+        "DELETE FROM pinned_message WHERE conversation_id = ?1 AND message_id = ?2",
+        libsql::params![body.conversation_id.clone(), body.message_id.clone()],
+    )
+    .await?;
+    Ok(WriteOutcome::Ok)
+}
+
 // ── POST /v1/watermarks/advance ──────────────────────────────────────────────
 
 #[derive(Deserialize)]
@@ -708,8 +1200,8 @@ pub async fn envelope_gc(
     outcome_response(apply_envelope_gc(&conn, authed.as_deref(), &parsed).await?)
 }
 
-/// Run the TTL-or-watermark envelope GC for a conversation. Authz: the actor is
-/// a current member.
+/// Run the TTL-or-watermark envelope GC for a conversation, then the channel's
+/// retention window if an admin set one. Authz: the actor is a current member.
 ///
 /// TODO(#419): GC should be gated on the MIN watermark across ALL members rather
 /// than triggered by whichever member happens to ingest. The SQL already
@@ -737,6 +1229,13 @@ pub async fn apply_envelope_gc(
     };
     conn.execute(sql, libsql::params![body.conversation_id.clone()])
         .await?;
+    if !body.is_dm {
+        conn.execute(
+            CLEANUP_CHANNEL_RETENTION,
+            libsql::params![body.conversation_id.clone()],
+        )
+        .await?;
+    }
     Ok(WriteOutcome::Ok)
 }
 
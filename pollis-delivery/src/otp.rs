@@ -8,11 +8,13 @@
 //! [`OtpConfig::max_attempts`], compares in constant time, and is deleted on the
 //! first success (single-use).
 //!
-//! **Store:** in-memory (the DS is single-container — mirrors the OTP map the
-//! client used to keep). Behind [`OtpStore`] so a scaled-out DS can swap it for a
-//! Turso table without touching the handlers.
+//! **Store:** in memory by default (mirrors the OTP map the client used to
+//! keep), so a code only verifies on the instance that issued it. With
+//! `DS_SHARED_STATE=db` codes live in the shared `ds_otp` table (migration
+//! 000027) and verify on any instance — see [`OtpStore::shared`].
 
 use std::collections::HashMap;
+use std::sync::atomic::{AtomicU64, Ordering};
 use std::sync::{Arc, Mutex};
 use std::time::{SystemTime, UNIX_EPOCH};
 
@@ -28,6 +30,7 @@ use serde::Deserialize;
 use sha2::{Digest, Sha256};
 use ulid::Ulid;
 
+use crate::db::Db;
 use crate::redact::mask_email;
 use crate::session::SessionStore;
 use crate::writes::bad_request;
@@ -92,13 +95,23 @@ struct OtpRecord {
     locked: bool,
 }
 
-/// In-memory OTP store keyed on the normalized email. `Clone` is shallow (shared
-/// `Arc`) so it rides on the `Clone` `AppState`.
+/// OTP store keyed on the normalized email, in memory or (see [`Self::shared`])
+/// in the shared DB. `Clone` is shallow (shared `Arc`s) so it rides on the
+/// `Clone` `AppState`.
 #[derive(Clone, Default)]
 pub struct OtpStore {
     inner: Arc<Mutex<HashMap<String, OtpRecord>>>,
+    /// `Some((db, scope))` → codes live in `ds_otp` under `scope`; `inner` is
+    /// only the fallback while the DB is unreachable.
+    shared: Option<(Arc<Db>, &'static str)>,
+    /// Shared-mode codes prepared on this instance, for pacing the prune.
+    prepared: Arc<AtomicU64>,
 }
 
+/// In shared mode, every this-many prepared codes an instance deletes expired
+/// ones.
+const SHARED_PRUNE_EVERY: u64 = 100;
+
 /// Normalize an email for store keying so request/verify always agree: trim +
 /// lowercase. (The `users` table is still queried with the as-typed address.)
 /// `pub(crate)` so the email-change store keys its requester map identically.
@@ -145,9 +158,80 @@ pub enum VerifyOutcome {
 }
 
 impl OtpStore {
+    /// A store whose codes live in `db`'s `ds_otp` table under `scope`, shared
+    /// by every DS instance on that database. Each store the DS keeps apart
+    /// (signup, email change) takes its own scope.
+    pub fn shared(db: Arc<Db>, scope: &'static str) -> Self {
+        Self {
+            shared: Some((db, scope)),
+            ..Self::default()
+        }
+    }
+
     /// Store a fresh `code` for `email` (replacing any prior one), unless the
     /// last send is within the resend-throttle window (→ [`PrepareOutcome::Throttled`]).
-    pub fn prepare(
+    /// A shared-store error is logged and the code kept in memory instead.
+    pub async fn prepare(
+        &self,
+        email: &str,
+        code: &str,
+        ttl_secs: u64,
+        resend_throttle_secs: u64,
+        now: u64,
+    ) -> PrepareOutcome {
+        let Some((db, scope)) = &self.shared else {
+            return self.prepare_local(email, code, ttl_secs, resend_throttle_secs, now);
+        };
+        match shared_prepare(db, scope, email, code, ttl_secs, resend_throttle_secs, now).await {
+            Ok(outcome) => {
+                if self.prepared.fetch_add(1, Ordering::Relaxed) % SHARED_PRUNE_EVERY == 0 {
+                    if let Err(e) = shared_prune(db, now).await {
+                        tracing::warn!("otp prune: {e:#}");
+                    }
+                }
+                outcome
+            }
+            Err(e) => {
+                tracing::warn!("shared otp store unavailable, keeping the code locally: {e:#}");
+                self.prepare_local(email, code, ttl_secs, resend_throttle_secs, now)
+            }
+        }
+    }
+
+    /// Check a submitted `code` — see [`Self::check_local`] for the rules, which
+    /// the shared store follows too. A shared-store error falls back to the
+    /// memory store, which only knows codes prepared during an outage.
+    pub async fn check(
+        &self,
+        email: &str,
+        code: &str,
+        max_attempts: u32,
+        now: u64,
+    ) -> VerifyOutcome {
+        let Some((db, scope)) = &self.shared else {
+            return self.check_local(email, code, max_attempts, now);
+        };
+        match shared_check(db, scope, email, code, max_attempts, now).await {
+            Ok(VerifyOutcome::NotFound) => self.check_local(email, code, max_attempts, now),
+            Ok(outcome) => outcome,
+            Err(e) => {
+                tracing::warn!("shared otp store unavailable, checking locally: {e:#}");
+                self.check_local(email, code, max_attempts, now)
+            }
+        }
+    }
+
+    /// Consume (single-use) the OTP for `email` — see [`Self::consume_local`].
+    pub async fn consume(&self, email: &str) {
+        if let Some((db, scope)) = &self.shared {
+            if let Err(e) = shared_delete(db, scope, email).await {
+                tracing::warn!("otp consume: {e:#}");
+            }
+        }
+        self.consume_local(email);
+    }
+
+    fn prepare_local(
         &self,
         email: &str,
         code: &str,
@@ -189,7 +273,7 @@ impl OtpStore {
     /// mint succeed, so a transient/config failure downstream (e.g. a bad DB token)
     /// can't permanently burn a valid code and masquerade as "invalid code" (#518).
     /// Wrong-guess accounting (attempts + lockout) is never rolled back.
-    pub fn check(&self, email: &str, code: &str, max_attempts: u32, now: u64) -> VerifyOutcome {
+    fn check_local(&self, email: &str, code: &str, max_attempts: u32, now: u64) -> VerifyOutcome {
         let key = normalize_email(email);
         let mut guard = self.inner.lock().expect("otp store mutex poisoned");
         let rec = match guard.get_mut(&key) {
@@ -222,13 +306,131 @@ impl OtpStore {
     /// session mint have succeeded. Idempotent — a no-op if the record is already
     /// gone. Pairs with [`OtpStore::check`] to make consumption contingent on the
     /// whole verify-otp operation succeeding (#518).
-    pub fn consume(&self, email: &str) {
+    fn consume_local(&self, email: &str) {
         let key = normalize_email(email);
         let mut guard = self.inner.lock().expect("otp store mutex poisoned");
         guard.remove(&key);
     }
 }
 
+/// [`OtpStore::prepare_local`] against `ds_otp`: one conditional upsert, so two
+/// instances racing on one address can't both send.
+async fn shared_prepare(
+    db: &Db,
+    scope: &str,
+    email: &str,
+    code: &str,
+    ttl_secs: u64,
+    resend_throttle_secs: u64,
+    now: u64,
+) -> anyhow::Result<PrepareOutcome> {
+    let mut salt = [0u8; 16];
+    OsRng.fill_bytes(&mut salt);
+    let stored = db
+        .conn()?
+        .execute(
+            "INSERT INTO ds_otp (scope, email, code_hash, salt, expires_at, attempts, last_sent_at) \
+             VALUES (?1, ?2, ?3, ?4, ?5, 0, ?6) \
+             ON CONFLICT(scope, email) DO UPDATE SET \
+                 code_hash = excluded.code_hash, salt = excluded.salt, \
+                 expires_at = excluded.expires_at, attempts = 0, \
+                 last_sent_at = excluded.last_sent_at \
+             WHERE NOT (?6 < ds_otp.expires_at AND ?6 - ds_otp.last_sent_at < ?7)",
+            libsql::params![
+                scope.to_string(),
+                normalize_email(email),
+                salted_hash(&salt, code).to_vec(),
+                salt.to_vec(),
+                now.saturating_add(ttl_secs) as i64,
+                now as i64,
+                resend_throttle_secs as i64,
+            ],
+        )
+        .await?;
+    Ok(if stored > 0 {
+        PrepareOutcome::Send(code.to_string())
+    } else {
+        PrepareOutcome::Throttled
+    })
+}
+
+/// [`OtpStore::check_local`] against `ds_otp`. A wrong guess is counted with
+/// one atomic increment, so concurrent guesses on several instances still lock
+/// out after `max_attempts`.
+async fn shared_check(
+    db: &Db,
+    scope: &str,
+    email: &str,
+    code: &str,
+    max_attempts: u32,
+    now: u64,
+) -> anyhow::Result<VerifyOutcome> {
+    let conn = db.conn()?;
+    let key = normalize_email(email);
+    let mut rows = conn
+        .query(
+            "SELECT code_hash, salt, expires_at FROM ds_otp WHERE scope = ?1 AND email = ?2",
+            libsql::params![scope.to_string(), key.clone()],
+        )
+        .await?;
+    let Some(row) = rows.next().await? else {
+        return Ok(VerifyOutcome::NotFound);
+    };
+    let code_hash: Vec<u8> = row.get(0)?;
+    let salt: [u8; 16] = row
+        .get::<Vec<u8>>(1)?
+        .try_into()
+        .map_err(|_| anyhow::anyhow!("stored otp salt has the wrong length"))?;
+    let expires_at = row.get::<i64>(2)?.max(0) as u64;
+    drop(rows);
+    if now > expires_at {
+        shared_delete(db, scope, email).await?;
+        return Ok(VerifyOutcome::Expired);
+    }
+    if constant_time_eq(&salted_hash(&salt, code), &code_hash) {
+        return Ok(VerifyOutcome::Ok);
+    }
+    let mut rows = conn
+        .query(
+            "UPDATE ds_otp SET attempts = attempts + 1 WHERE scope = ?1 AND email = ?2 \
+             RETURNING attempts",
+            libsql::params![scope.to_string(), key],
+        )
+        .await?;
+    // Consumed or locked out elsewhere in the meantime.
+    let Some(row) = rows.next().await? else {
+        return Ok(VerifyOutcome::NotFound);
+    };
+    let attempts = row.get::<i64>(0)?.max(0) as u64;
+    drop(rows);
+    if attempts > u64::from(max_attempts) {
+        shared_delete(db, scope, email).await?;
+        return Ok(VerifyOutcome::LockedOut);
+    }
+    Ok(VerifyOutcome::Invalid)
+}
+
+async fn shared_delete(db: &Db, scope: &str, email: &str) -> anyhow::Result<()> {
+    db.conn()?
+        .execute(
+            "DELETE FROM ds_otp WHERE scope = ?1 AND email = ?2",
+            libsql::params![scope.to_string(), normalize_email(email)],
+        )
+        .await?;
+    Ok(())
+}
+
+/// Drop expired codes — they outlive their expiry only when nobody tried them.
+async fn shared_prune(db: &Db, now: u64) -> anyhow::Result<()> {
+    db.conn()?
+        .execute(
+            "DELETE FROM ds_otp WHERE expires_at < ?1",
+            libsql::params![now as i64],
+        )
+        .await?;
+    Ok(())
+}
+
 fn now_unix() -> u64 {
     SystemTime::now()
         .duration_since(UNIX_EPOCH)
@@ -271,7 +473,15 @@ pub async fn process_request_otp(otp: &OtpStore, cfg: &OtpConfig, email: &str) {
         None => format!("{:06}", OsRng.gen_range(0..1_000_000u32)),
     };
 
-    let outcome = otp.prepare(email, &code, cfg.ttl_secs, cfg.resend_throttle_secs, now_unix());
+    let outcome = otp
+        .prepare(
+            email,
+            &code,
+            cfg.ttl_secs,
+            cfg.resend_throttle_secs,
+            now_unix(),
+        )
+        .await;
 
     match outcome {
         PrepareOutcome::Throttled => {}
@@ -295,12 +505,29 @@ pub async fn process_request_otp(otp: &OtpStore, cfg: &OtpConfig, email: &str) {
 }
 
 async fn send_otp_email(api_key: &str, email: &str, code: &str) -> anyhow::Result<()> {
+    send_email(
+        api_key,
+        email,
+        "Your Pollis sign-in code",
+        &format!("Your verification code is: {code}\n\nThis code expires in 10 minutes."),
+    )
+    .await
+}
+
+/// Send a plain-text email through Resend. Shared with the inactive-account
+/// warnings in [`crate::inactivity`].
+pub(crate) async fn send_email(
+    api_key: &str,
+    to: &str,
+    subject: &str,
+    text: &str,
+) -> anyhow::Result<()> {
     let client = reqwest::Client::new();
     let body = serde_json::json!({
         "from": "Pollis <noreply@mail.pollis.com>",
-        "to": [email],
-        "subject": "Your Pollis sign-in code",
-        "text": format!("Your verification code is: {code}\n\nThis code expires in 10 minutes."),
+        "to": [to],
+        "subject": subject,
+        "text": text,
     });
     let resp = client
         .post("https://api.resend.com/emails")
@@ -401,7 +628,7 @@ pub async fn apply_verify_otp(
     // clean 5xx and the *same* code still works on retry, instead of being burned
     // and disguised as "invalid code" (#518). Wrong/expired/locked codes are
     // rejected here and their attempt accounting stands.
-    match otp.check(email, code, cfg.max_attempts, now_unix()) {
+    match otp.check(email, code, cfg.max_attempts, now_unix()).await {
         VerifyOutcome::Ok => {}
         VerifyOutcome::LockedOut => return Ok(VerifyOtpResult::LockedOut),
         VerifyOutcome::Invalid | VerifyOutcome::Expired | VerifyOutcome::NotFound => {
@@ -441,16 +668,25 @@ pub async fn apply_verify_otp(
                 libsql::params![user_id.clone(), email.to_string(), default_username.clone()],
             )
             .await?;
+            // Invites emailed to this address before it had an account become
+            // ordinary pending invites. Best-effort: signup never fails on it.
+            if let Err(e) =
+                crate::email_invites::apply_pending_for_new_account(conn, &user_id, email).await
+            {
+                tracing::warn!("email invites for {}: {e:#}", mask_email(email));
+            }
             (user_id, default_username, false, true)
         }
     };
 
     let now = now_unix();
-    let session_token = sessions.mint(&user_id, email, device_id, cfg.session_ttl_secs, now);
+    let session_token = sessions
+        .mint(&user_id, email, device_id, cfg.session_ttl_secs, now)
+        .await;
 
     // Single-use: consume ONLY now that the account exists and the session is
     // minted — everything that can fail has already succeeded (#518).
-    otp.consume(email);
+    otp.consume(email).await;
 
     Ok(VerifyOtpResult::Ok {
         user_id,
@@ -542,47 +778,56 @@ mod tests {
     #[test]
     fn check_does_not_consume_a_correct_code_consume_does() {
         let store = OtpStore::default();
-        store.prepare("a@x.com", "123456", 600, 0, 1000);
+        store.prepare_local("a@x.com", "123456", 600, 0, 1000);
         // A correct code checks Ok — and stays valid; checking again still Ok (the
         // #518 fix: check alone must not burn the code).
-        assert_eq!(store.check("a@x.com", "123456", 5, 1000), VerifyOutcome::Ok);
-        assert_eq!(store.check("a@x.com", "123456", 5, 1000), VerifyOutcome::Ok);
+        assert_eq!(
+            store.check_local("a@x.com", "123456", 5, 1000),
+            VerifyOutcome::Ok
+        );
+        assert_eq!(
+            store.check_local("a@x.com", "123456", 5, 1000),
+            VerifyOutcome::Ok
+        );
         // Single-use is enforced by consume, not by check.
-        store.consume("a@x.com");
+        store.consume_local("a@x.com");
         assert_eq!(
-            store.check("a@x.com", "123456", 5, 1000),
+            store.check_local("a@x.com", "123456", 5, 1000),
             VerifyOutcome::NotFound
         );
         // consume is idempotent.
-        store.consume("a@x.com");
+        store.consume_local("a@x.com");
     }
 
     #[test]
     fn lockout_after_six_wrong_then_correct_fails() {
         let store = OtpStore::default();
-        store.prepare("a@x.com", "123456", 600, 0, 1000);
+        store.prepare_local("a@x.com", "123456", 600, 0, 1000);
         // 5 wrong guesses are merely invalid.
         for _ in 0..5 {
             assert_eq!(
-                store.check("a@x.com", "000000", 5, 1000),
+                store.check_local("a@x.com", "000000", 5, 1000),
                 VerifyOutcome::Invalid
             );
         }
         // The 6th locks out and deletes the code.
         assert_eq!(
-            store.check("a@x.com", "000000", 5, 1000),
+            store.check_local("a@x.com", "000000", 5, 1000),
             VerifyOutcome::LockedOut
         );
         // The correct code no longer works.
-        assert_ne!(store.check("a@x.com", "123456", 5, 1000), VerifyOutcome::Ok);
+        assert_ne!(
+            store.check_local("a@x.com", "123456", 5, 1000),
+            VerifyOutcome::Ok
+        );
     }
 
     #[test]
     fn expired_code_rejected() {
         let store = OtpStore::default();
-        store.prepare("a@x.com", "123456", 600, 0, 1000);
+        store.prepare_local("a@x.com", "123456", 600, 0, 1000);
         assert_eq!(
-            store.check("a@x.com", "123456", 5, 2000),
+            store.check_local("a@x.com", "123456", 5, 2000),
             VerifyOutcome::Expired
         );
     }
@@ -591,11 +836,11 @@ mod tests {
     fn throttle_skips_resend() {
         let store = OtpStore::default();
         assert!(matches!(
-            store.prepare("a@x.com", "111111", 600, 30, 1000),
+            store.prepare_local("a@x.com", "111111", 600, 30, 1000),
             PrepareOutcome::Send(_)
         ));
         assert!(matches!(
-            store.prepare("a@x.com", "222222", 600, 30, 1010),
+            store.prepare_local("a@x.com", "222222", 600, 30, 1010),
             PrepareOutcome::Throttled
         ));
     }
@@ -609,7 +854,8 @@ mod tests {
         let otp = OtpStore::default();
         let sessions = SessionStore::default();
         let cfg = OtpConfig::default();
-        otp.prepare("a@x.com", "123456", cfg.ttl_secs, 0, now_unix());
+        otp.prepare("a@x.com", "123456", cfg.ttl_secs, 0, now_unix())
+            .await;
 
         let first =
             apply_verify_otp(&conn, &otp, &sessions, &cfg, "a@x.com", "123456", "dev-1").await;
@@ -635,7 +881,8 @@ mod tests {
         let otp = OtpStore::default();
         let sessions = SessionStore::default();
         let cfg = OtpConfig::default();
-        otp.prepare("a@x.com", "123456", cfg.ttl_secs, 0, now_unix());
+        otp.prepare("a@x.com", "123456", cfg.ttl_secs, 0, now_unix())
+            .await;
 
         let first =
             apply_verify_otp(&conn, &otp, &sessions, &cfg, "a@x.com", "123456", "dev-1").await;
@@ -654,7 +901,8 @@ mod tests {
         let otp = OtpStore::default();
         let sessions = SessionStore::default();
         let cfg = OtpConfig::default();
-        otp.prepare("a@x.com", "123456", cfg.ttl_secs, 0, now_unix());
+        otp.prepare("a@x.com", "123456", cfg.ttl_secs, 0, now_unix())
+            .await;
 
         for _ in 0..cfg.max_attempts {
             let r =
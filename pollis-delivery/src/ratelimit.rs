@@ -9,10 +9,12 @@
 //! called for (`docs/otp-server-bootstrap-design.md`: "Per-email resend
 //! throttle + IP throttle").
 //!
-//! **Store:** in-memory fixed-window counters (the DS is single-container, same
-//! as the OTP/session stores). Behind [`RateLimiter`] so a scaled-out DS can
-//! swap it for a shared store without touching the handlers. Reusable beyond the
-//! OTP endpoints — `check` is keyed by an arbitrary bucket string.
+//! **Store:** fixed-window counters, in memory by default (one DS instance,
+//! same as the OTP/session stores). With `DS_SHARED_STATE=db` they live in the
+//! shared `ds_rate_window` table (migration 000024) instead, so every instance
+//! behind the same hostname draws on one budget per key — see
+//! [`RateLimiter::shared`]. Reusable beyond the OTP endpoints — [`RateLimiter::hit`]
+//! is keyed by an arbitrary bucket string.
 //!
 //! **Client IP:** the DS terminates TLS at a reverse proxy (Cloudflare) and
 //! serves plain HTTP, so the socket peer is the proxy, not the client. The real
@@ -23,6 +25,7 @@
 //! rather than silently disabled.
 
 use std::collections::HashMap;
+use std::sync::atomic::{AtomicU64, Ordering};
 use std::sync::{Arc, Mutex};
 use std::time::{SystemTime, UNIX_EPOCH};
 
@@ -34,6 +37,7 @@ use axum::{
     Json,
 };
 
+use crate::db::Db;
 use crate::AppState;
 
 /// Rate-limit tunables for the OTP endpoints, read from DS env in
@@ -121,11 +125,17 @@ struct Window {
     window_start: u64,
 }
 
-/// In-memory per-key fixed-window rate limiter. `Clone` is shallow (shared
-/// `Arc`) so it rides on the `Clone` `AppState`.
+/// Per-key fixed-window rate limiter, in memory or (see [`Self::shared`]) in
+/// the shared DB. `Clone` is shallow (shared `Arc`s) so it rides on the
+/// `Clone` `AppState`.
 #[derive(Clone, Default)]
 pub struct RateLimiter {
     inner: Arc<Mutex<HashMap<String, Window>>>,
+    /// `Some` → counters live in `ds_rate_window`; `inner` is only the
+    /// fallback while the DB is unreachable.
+    shared: Option<Arc<Db>>,
+    /// Shared-mode hits on this instance, for pacing the prune.
+    hits: Arc<AtomicU64>,
 }
 
 /// Above this many tracked keys, a `check` opportunistically drops windows whose
@@ -133,9 +143,63 @@ pub struct RateLimiter {
 /// bound on a long-lived container.
 const PRUNE_THRESHOLD: usize = 10_000;
 
+/// In shared mode, every this-many hits an instance deletes windows that
+/// started more than [`SHARED_PRUNE_AGE_SECS`] ago.
+const SHARED_PRUNE_EVERY: u64 = 1_000;
+
+/// Longer than any window the DS configures, so a prune never resets a live
+/// window.
+const SHARED_PRUNE_AGE_SECS: u64 = 24 * 60 * 60;
+
+/// Which store the DS keeps its counters in: `DS_SHARED_STATE=db` → the shared
+/// DB (required for more than one instance); unset or anything else → memory.
+pub fn shared_state_from_env() -> bool {
+    matches!(
+        std::env::var("DS_SHARED_STATE").ok().as_deref(),
+        Some("db") | Some("DB")
+    )
+}
+
 impl RateLimiter {
-    /// Record one hit for `key` and report whether it is within `max` per
-    /// `window_secs`. Fixed window: the first hit starts a window; once the
+    /// A limiter whose counters live in `db`'s `ds_rate_window` table, shared
+    /// by every DS instance on that database.
+    pub fn shared(db: Arc<Db>) -> Self {
+        Self {
+            shared: Some(db),
+            ..Self::default()
+        }
+    }
+
+    /// Record one hit for `key` in whichever store this limiter uses. Same
+    /// semantics as [`Self::check`]. A shared-store error is logged and the
+    /// hit counted in memory instead — throttling per instance beats either
+    /// failing every write or not throttling at all.
+    pub async fn hit(&self, key: &str, max: u32, window_secs: u64, now: u64) -> RateLimitOutcome {
+        let Some(db) = &self.shared else {
+            return self.check(key, max, window_secs, now);
+        };
+        match shared_hit(db, key, window_secs, now).await {
+            Ok(count) => {
+                if self.hits.fetch_add(1, Ordering::Relaxed) % SHARED_PRUNE_EVERY == 0 {
+                    if let Err(e) = shared_prune(db, now).await {
+                        tracing::warn!("rate-limit prune: {e:#}");
+                    }
+                }
+                if count > u64::from(max) {
+                    RateLimitOutcome::Limited
+                } else {
+                    RateLimitOutcome::Allowed
+                }
+            }
+            Err(e) => {
+                tracing::warn!("shared rate limit unavailable, counting locally: {e:#}");
+                self.check(key, max, window_secs, now)
+            }
+        }
+    }
+
+    /// Record one hit for `key` in this instance's memory and report whether it
+    /// is within `max` per `window_secs`. Fixed window: the first hit starts a window; once the
     /// window elapses the counter resets. A key over its limit stays [`Limited`]
     /// until its window rolls over.
     ///
@@ -164,6 +228,38 @@ impl RateLimiter {
     }
 }
 
+/// One atomic upsert: start a window at `now` for a new or elapsed key,
+/// otherwise count into the current one. SQLite evaluates every `SET` against
+/// the old row, so both `CASE`s see the same `window_start`. Returns the count.
+async fn shared_hit(db: &Db, key: &str, window_secs: u64, now: u64) -> anyhow::Result<u64> {
+    let conn = db.conn()?;
+    let mut rows = conn
+        .query(
+            "INSERT INTO ds_rate_window (key, window_start, count) VALUES (?1, ?2, 1) \
+             ON CONFLICT(key) DO UPDATE SET \
+                 count = CASE WHEN ?2 - window_start >= ?3 THEN 1 ELSE count + 1 END, \
+                 window_start = CASE WHEN ?2 - window_start >= ?3 THEN ?2 ELSE window_start END \
+             RETURNING count",
+            libsql::params![key.to_string(), now as i64, window_secs as i64],
+        )
+        .await?;
+    let row = rows
+        .next()
+        .await?
+        .ok_or_else(|| anyhow::anyhow!("rate-limit upsert returned no row"))?;
+    Ok(row.get::<i64>(0)?.max(0) as u64)
+}
+
+async fn shared_prune(db: &Db, now: u64) -> anyhow::Result<()> {
+    db.conn()?
+        .execute(
+            "DELETE FROM ds_rate_window WHERE window_start < ?1",
+            libsql::params![now.saturating_sub(SHARED_PRUNE_AGE_SECS) as i64],
+        )
+        .await?;
+    Ok(())
+}
+
 /// The client IP for rate-limit keying. Prefers `CF-Connecting-IP` (Cloudflare
 /// sets it and a client cannot forge it through Cloudflare), then the first
 /// `X-Forwarded-For` hop. Absent both (local/dev/test), returns a shared
@@ -192,7 +288,7 @@ fn header_str<'a>(headers: &'a HeaderMap, name: &str) -> Option<&'a str> {
         .filter(|s| !s.is_empty())
 }
 
-fn now_unix() -> u64 {
+pub(crate) fn now_unix() -> u64 {
     SystemTime::now()
         .duration_since(UNIX_EPOCH)
         .map(|d| d.as_secs())
@@ -243,7 +339,8 @@ pub async fn rate_limit(State(state): State<AppState>, req: Request, next: Next)
         let ip = client_ip(req.headers());
         if state
             .ratelimit
-            .check(&format!("{tier}:{ip}"), max, window, now_unix())
+            .hit(&format!("{tier}:{ip}"), max, window, now_unix())
+            .await
             == RateLimitOutcome::Limited
         {
             return too_many_requests();
@@ -0,0 +1,266 @@
+//! Replay protection for envelope writes (`POST /v1/messages/send` and
+//! `/v1/messages/edit`).
+//!
+//! Request signing ([`crate::auth`]) binds the body hash, so a captured request
+//! can't be altered — but within the ±[`REPLAY_WINDOW_SECS`] timestamp window it
+//! can be resubmitted byte-for-byte. For a send that mostly bounced off the
+//! `message_envelope` primary key as a 500, and not at all once envelope GC had
+//! deleted the row; for an edit, replaying an older edit deletes a newer one and
+//! re-inserts the old text. This closes both by remembering every envelope id
+//! the DS accepted, per conversation (the recipient), for `window_secs`:
+//!
+//!   - **replayed** — the id is already in the conversation's recent-ID table;
+//!   - **stale** — the id is a ULID minted more than `window_secs` ago, so it is
+//!     past what the table still remembers and can't be checked against it.
+//!
+//! Either refuses the write with `409 {"error":"REPLAYED","reason"}` and bumps
+//! a counter exported at `GET /metrics`. The check runs before the flood
+//! heuristics, so an attacker replaying someone's envelope can't get *them*
+//! muted for duplicates. A write that doesn't go through releases its id, so
+//! a legitimate retry after a 403 or a DB error isn't mistaken for a replay.
+//!
+//! The table only has to outlive what auth still accepts: a request signed at
+//! `ts` verifies until `ts + 300`, and `ts` was at most 300s ahead of the
+//! server when it first arrived, so the default 600s window (twice the auth
+//! window) covers every replay auth lets through. Each id is kept until
+//! `max(seen, ulid time) + window_secs`, after which the stale check takes
+//! over. Ids that aren't ULIDs (old clients, tests) get the table check only.
+//!
+//! **Store:** in-memory — a restart is longer than the auth window anyway. On
+//! a scaled-out DS each instance remembers only what it accepted, so a replay
+//! sent to another instance gets past this table; it then meets the stored
+//! row in [`crate::messages::store_envelope`] and writes nothing.
+
+use std::collections::HashMap;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::{Arc, Mutex};
+
+use axum::{
+    http::StatusCode,
+    response::{IntoResponse, Response},
+    Json,
+};
+use ulid::Ulid;
+
+use crate::auth::REPLAY_WINDOW_SECS;
+
+/// Replay-protection tunables, read from DS env by [`ReplayConfig::from_env`].
+#[derive(Clone, Debug)]
+pub struct ReplayConfig {
+    /// How long an accepted envelope id is remembered, seconds. `0` disables
+    /// the check.
+    pub window_secs: u64,
+    /// Most ids remembered per conversation; past it the oldest are dropped
+    /// (and logged, since a replay of a dropped id is no longer caught).
+    pub max_ids_per_conversation: usize,
+}
+
+impl Default for ReplayConfig {
+    fn default() -> Self {
+        Self {
+            window_secs: 2 * REPLAY_WINDOW_SECS as u64,
+            // Thirteen sends a second into one conversation for the whole
+            // window — well past the flood detector's per-conversation cap.
+            max_ids_per_conversation: 8192,
+        }
+    }
+}
+
+impl ReplayConfig {
+    /// Build from DS environment, falling back to [`Default`] per field. Env:
+    /// `ENVELOPE_REPLAY_WINDOW_SECS`, `ENVELOPE_REPLAY_MAX_IDS`.
+    pub fn from_env() -> Self {
+        let mut cfg = Self::default();
+        if let Some(v) = env_parse("ENVELOPE_REPLAY_WINDOW_SECS") {
+            cfg.window_secs = v;
+        }
+        if let Some(v) = env_parse::<usize>("ENVELOPE_REPLAY_MAX_IDS") {
+            cfg.max_ids_per_conversation = v.max(1);
+        }
+        cfg
+    }
+}
+
+fn env_parse<T: std::str::FromStr>(key: &str) -> Option<T> {
+    std::env::var(key).ok().and_then(|s| s.parse().ok())
+}
+
+/// Why a write was refused.
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+pub enum ReplayReason {
+    Replayed,
+    Stale,
+}
+
+impl ReplayReason {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            ReplayReason::Replayed => "replayed",
+            ReplayReason::Stale => "stale",
+        }
+    }
+}
+
+/// The outcome of [`ReplayGuard::admit`].
+#[derive(Debug, PartialEq, Eq)]
+pub enum ReplayOutcome {
+    /// First sighting; the id is now remembered.
+    Fresh,
+    Rejected(ReplayReason),
+}
+
+/// Above this many tracked conversations, an admit drops fully expired ones.
+const PRUNE_THRESHOLD: usize = 10_000;
+
+/// In-memory recent-ID table per conversation plus the rejection counters.
+/// `Clone` is shallow (shared `Arc`s) so it rides on the `Clone` `AppState`.
+#[derive(Clone, Default)]
+pub struct ReplayGuard {
+    /// Conversation → envelope id → unix second the id may be forgotten.
+    seen: Arc<Mutex<HashMap<String, HashMap<String, u64>>>>,
+    replayed: Arc<AtomicU64>,
+    stale: Arc<AtomicU64>,
+}
+
+impl ReplayGuard {
+    /// Check `envelope_id` for `conversation_id` and, if fresh, remember it.
+    pub fn admit(
+        &self,
+        cfg: &ReplayConfig,
+        conversation_id: &str,
+        envelope_id: &str,
+        now: u64,
+    ) -> ReplayOutcome {
+        if cfg.window_secs == 0 {
+            return ReplayOutcome::Fresh;
+        }
+        let minted = Ulid::from_string(envelope_id)
+            .ok()
+            .map(|u| u.timestamp_ms() / 1000);
+        if minted.is_some_and(|t| t + cfg.window_secs < now) {
+            self.stale.fetch_add(1, Ordering::Relaxed);
+            return ReplayOutcome::Rejected(ReplayReason::Stale);
+        }
+
+        let mut seen = self.seen.lock().expect("replay mutex poisoned");
+        if seen.len() > PRUNE_THRESHOLD {
+            seen.retain(|_, ids| ids.values().any(|&until| now < until));
+        }
+        let ids = seen.entry(conversation_id.to_string()).or_default();
+        if matches!(ids.get(envelope_id), Some(&until) if now < until) {
+            self.replayed.fetch_add(1, Ordering::Relaxed);
+            return ReplayOutcome::Rejected(ReplayReason::Replayed);
+        }
+        if ids.len() >= cfg.max_ids_per_conversation {
+            ids.retain(|_, until| now < *until);
+        }
+        while ids.len() >= cfg.max_ids_per_conversation {
+            let Some(oldest) = ids
+                .iter()
+                .min_by_key(|(_, until)| **until)
+                .map(|(id, _)| id.clone())
+            else {
+                break;
+            };
+            ids.remove(&oldest);
+            tracing::warn!(
+                conversation_id = %conversation_id,
+                "replay table full; forgetting a live envelope id"
+            );
+        }
+        let until = minted.unwrap_or(now).max(now) + cfg.window_secs;
+        ids.insert(envelope_id.to_string(), until);
+        ReplayOutcome::Fresh
+    }
+
+    /// Forget an id [`Self::admit`] took for a write that then didn't happen.
+    pub fn release(&self, conversation_id: &str, envelope_id: &str) {
+        let mut seen = self.seen.lock().expect("replay mutex poisoned");
+        if let Some(ids) = seen.get_mut(conversation_id) {
+            ids.remove(envelope_id);
+        }
+    }
+
+    /// Rejections so far, by reason, since the DS started.
+    pub fn rejected(&self, reason: ReplayReason) -> u64 {
+        match reason {
+            ReplayReason::Replayed => self.replayed.load(Ordering::Relaxed),
+            ReplayReason::Stale => self.stale.load(Ordering::Relaxed),
+        }
+    }
+}
+
+/// The `409` for a refused replay.
+pub fn replay_rejected(reason: ReplayReason) -> Response {
+    (
+        StatusCode::CONFLICT,
+        Json(serde_json::json!({
+            "error": "REPLAYED",
+            "reason": reason.as_str(),
+        })),
+    )
+        .into_response()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn cfg() -> ReplayConfig {
+        ReplayConfig {
+            window_secs: 600,
+            max_ids_per_conversation: 3,
+        }
+    }
+
+    #[test]
+    fn second_sighting_is_a_replay_until_the_window_lapses() {
+        let g = ReplayGuard::default();
+        assert_eq!(g.admit(&cfg(), "c1", "m1", 1000), ReplayOutcome::Fresh);
+        assert_eq!(
+            g.admit(&cfg(), "c1", "m1", 1300),
+            ReplayOutcome::Rejected(ReplayReason::Replayed)
+        );
+        // Per conversation: the same id elsewhere is a different envelope.
+        assert_eq!(g.admit(&cfg(), "c2", "m1", 1300), ReplayOutcome::Fresh);
+        assert_eq!(g.admit(&cfg(), "c1", "m1", 1600), ReplayOutcome::Fresh);
+        assert_eq!(g.rejected(ReplayReason::Replayed), 1);
+    }
+
+    #[test]
+    fn old_ulid_is_stale() {
+        let g = ReplayGuard::default();
+        let minted = Ulid::from_parts(1_000_000, 7).to_string();
+        assert_eq!(g.admit(&cfg(), "c1", &minted, 1_500), ReplayOutcome::Fresh);
+        assert_eq!(
+            g.admit(&cfg(), "c1", &minted, 1_601),
+            ReplayOutcome::Rejected(ReplayReason::Stale)
+        );
+        assert_eq!(g.rejected(ReplayReason::Stale), 1);
+    }
+
+    #[test]
+    fn release_and_capacity() {
+        let g = ReplayGuard::default();
+        assert_eq!(g.admit(&cfg(), "c1", "m1", 1000), ReplayOutcome::Fresh);
+        g.release("c1", "m1");
+        assert_eq!(g.admit(&cfg(), "c1", "m1", 1001), ReplayOutcome::Fresh);
+        assert_eq!(g.admit(&cfg(), "c1", "m2", 1002), ReplayOutcome::Fresh);
+        assert_eq!(g.admit(&cfg(), "c1", "m3", 1003), ReplayOutcome::Fresh);
+        // Full: the oldest (m1) makes room for m4.
+        assert_eq!(g.admit(&cfg(), "c1", "m4", 1004), ReplayOutcome::Fresh);
+        assert_eq!(g.admit(&cfg(), "c1", "m1", 1005), ReplayOutcome::Fresh);
+    }
+
+    #[test]
+    fn zero_window_disables() {
+        let g = ReplayGuard::default();
+        let off = ReplayConfig {
+            window_secs: 0,
+            ..cfg()
+        };
+        for _ in 0..3 {
+            assert_eq!(g.admit(&off, "c1", "m1", 1000), ReplayOutcome::Fresh);
+        }
+    }
+}
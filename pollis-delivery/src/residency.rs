@@ -0,0 +1,322 @@
+//! Per-group attachment residency.
+//!
+//! By default every attachment lands in the one bucket the broker signs for
+//! ([`crate::storage`]). A deployment can also declare extra S3-compatible
+//! **storage targets** — an EU-only R2 bucket, an on-prem MinIO — and a group's
+//! owner can pin the group's attachments to one of them (`groups.storage_target`,
+//! migration 000025, written only through the device-signed
+//! `POST /v1/groups/update`). `GET /v1/storage/targets` lists what's declared
+//! (id, label, region — never credentials).
+//!
+//! The target travels in the object key: a pinned upload is
+//! `media@<target>/<hash>/<file>.enc` instead of `media/<hash>/<file>.enc`.
+//! The key is already what the MLS-encrypted attachment payload carries, so
+//! every later GET / DELETE presign routes to the right store without the
+//! reader knowing anything about the group it came from.
+//!
+//! The client picks the target from the group row when it presigns the upload
+//! and names the conversation; [`check_put`] holds every attachment PUT to a
+//! policy:
+//!
+//!   - `group_id`: the uploader must be a member, and the key's target must be
+//!     the group's — a pinned group refuses unpinned keys and vice versa;
+//!   - `dm_channel_id`: the DM must exist (and, signed, the uploader be in it);
+//!     DMs have no policy, so the key must not name a target;
+//!   - neither: only a shipped client's undeclared PUT ([`crate::uploads`])
+//!     may leave the conversation out, and only while the uploader is in no
+//!     pinned group — otherwise it could be one of that group's attachments.
+//!
+//! Env: `STORAGE_TARGETS` (comma-separated ids, `[a-z0-9-]`), then per id
+//! (upper-cased, `-` → `_`): `STORAGE_TARGET_<ID>_ENDPOINT`, `_BUCKET`,
+//! `_ACCESS_KEY_ID`, `_SECRET_ACCESS_KEY` (all required), `_REGION` (default
+//! `auto`) and `_LABEL` (default the id). A target missing a required setting
+//! is logged and left out.
+
+use std::sync::Arc;
+
+use axum::{
+    extract::State,
+    http::StatusCode,
+    response::{IntoResponse, Response},
+    Json,
+};
+use libsql::Connection;
+use serde::Serialize;
+
+use crate::error::AuthRejection;
+use crate::storage::{presign_s3, PutBinding};
+use crate::writes::{bad_request, is_member};
+use crate::AppState;
+
+/// One operator-declared S3-compatible store.
+#[derive(Clone)]
+pub struct StorageTarget {
+    pub id: String,
+    /// Shown to group owners, e.g. "EU (Frankfurt)".
+    pub label: String,
+    pub region: String,
+    pub endpoint: String,
+    pub bucket: String,
+    pub access_key_id: String,
+    /// NEVER logged or served.
+    pub secret_access_key: String,
+}
+
+/// The declared targets. Shallow-`Clone` (shared `Arc`). Default: none, so
+/// every key that names a target is refused.
+#[derive(Clone, Default)]
+pub struct StorageTargets(Arc<Vec<StorageTarget>>);
+
+/// What `GET /v1/storage/targets` serves per target.
+#[derive(Serialize)]
+struct TargetInfo<'a> {
+    id: &'a str,
+    label: &'a str,
+    region: &'a str,
+}
+
+impl StorageTargets {
+    pub fn new(targets: Vec<StorageTarget>) -> Self {
+        Self(Arc::new(targets))
+    }
+
+    /// Build from DS env (see the module docs).
+    pub fn from_env() -> Self {
+        let var = |k: &str| std::env::var(k).ok().filter(|s| !s.trim().is_empty());
+        let Some(ids) = var("STORAGE_TARGETS") else {
+            return Self::default();
+        };
+        let mut targets = Vec::new();
+        for id in ids.split(',').map(str::trim).filter(|s| !s.is_empty()) {
+            if !valid_id(id) {
+                tracing::error!(
+                    target_id = id,
+                    "STORAGE_TARGETS: ids are [a-z0-9-]; skipping"
+                );
+                continue;
+            }
+            let prefix = format!(
+                "STORAGE_TARGET_{}_",
+                id.to_ascii_uppercase().replace('-', "_")
+            );
+            let setting = |name: &str| var(&format!("{prefix}{name}"));
+            match (
+                setting("ENDPOINT"),
+                setting("BUCKET"),
+                setting("ACCESS_KEY_ID"),
+                setting("SECRET_ACCESS_KEY"),
+            ) {
+                (Some(endpoint), Some(bucket), Some(access_key_id), Some(secret_access_key)) => {
+                    targets.push(StorageTarget {
+                        id: id.to_string(),
+                        label: setting("LABEL").unwrap_or_else(|| id.to_string()),
+                        region: setting("REGION").unwrap_or_else(|| "auto".to_string()),
+                        endpoint,
+                        bucket,
+                        access_key_id,
+                        secret_access_key,
+                    })
+                }
+                _ => tracing::error!(
+                    target_id = id,
+                    "storage target needs {prefix}ENDPOINT, _BUCKET, _ACCESS_KEY_ID and _SECRET_ACCESS_KEY; skipping"
+                ),
+            }
+        }
+        Self::new(targets)
+    }
+
+    pub fn get(&self, id: &str) -> Option<&StorageTarget> {
+        self.0.iter().find(|t| t.id == id)
+    }
+}
+
+impl StorageTarget {
+    /// A SigV4 presigned URL on this target, bound to `put` when given.
+    pub fn presign(
+        &self,
+        method: &str,
+        key: &str,
+        expires: u64,
+        datetime: &str,
+        put: Option<PutBinding<'_>>,
+    ) -> String {
+        presign_s3(
+            &self.endpoint,
+            &self.bucket,
+            &self.region,
+            &self.access_key_id,
+            &self.secret_access_key,
+            method,
+            key,
+            expires,
+            datetime,
+            put,
+        )
+    }
+}
+
+fn valid_id(id: &str) -> bool {
+    !id.is_empty()
+        && id.len() <= 32
+        && id
+            .bytes()
+            .all(|b| b.is_ascii_lowercase() || b.is_ascii_digit() || b == b'-')
+}
+
+/// The storage target a key names (`media@<target>/…`), if any.
+pub fn target_of_key(key: &str) -> Option<&str> {
+    let (prefix, _) = key.split_once('/')?;
+    prefix.split_once('@').map(|(_, target)| target)
+}
+
+/// The group's pinned target: `Ok(None)` for a missing group,
+/// `Ok(Some(None))` for the default store.
+pub async fn group_target(
+    conn: &Connection,
+    group_id: &str,
+) -> anyhow::Result<Option<Option<String>>> {
+    let mut rows = conn
+        .query(
+            "SELECT storage_target FROM groups WHERE id = ?1",
+            libsql::params![group_id.to_string()],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) => Some(row.get::<Option<String>>(0)?),
+        None => None,
+    })
+}
+
+/// `409` for an upload whose key doesn't match the group's policy. Carries the
+/// policy so a client holding a stale group row can retry without re-reading it.
+fn residency_mismatch(policy: Option<&str>) -> Response {
+    (
+        StatusCode::CONFLICT,
+        Json(serde_json::json!({ "error": "RESIDENCY_MISMATCH", "storage_target": policy })),
+    )
+        .into_response()
+}
+
+/// Whether `user_id` is in the DM `dm_channel_id`; with no `user_id`, whether
+/// the DM has anyone in it at all (it exists).
+async fn in_dm(
+    conn: &Connection,
+    dm_channel_id: &str,
+    user_id: Option<&str>,
+) -> anyhow::Result<bool> {
+    let mut rows = conn
+        .query(
+            "SELECT 1 FROM dm_channel_member
+             WHERE dm_channel_id = ?1 AND (?2 IS NULL OR user_id = ?2) LIMIT 1",
+            libsql::params![dm_channel_id.to_string(), user_id.map(str::to_string)],
+        )
+        .await?;
+    Ok(rows.next().await?.is_some())
+}
+
+/// Whether any group `user_id` belongs to pins its attachments.
+async fn in_pinned_group(conn: &Connection, user_id: &str) -> anyhow::Result<bool> {
+    let mut rows = conn
+        .query(
+            "SELECT 1 FROM group_member gm JOIN groups g ON g.id = gm.group_id
+             WHERE gm.user_id = ?1 AND g.storage_target IS NOT NULL LIMIT 1",
+            libsql::params![user_id.to_string()],
+        )
+        .await?;
+    Ok(rows.next().await?.is_some())
+}
+
+/// Hold an attachment PUT on `key` to its conversation's residency policy (see
+/// the module docs). `Some(response)` refuses it. `authed` is the verified
+/// signer; on the no-auth path membership isn't checked. `unbound` is a shipped
+/// client's undeclared PUT, the only kind allowed to name no conversation.
+#[allow(clippy::too_many_arguments)]
+pub async fn check_put(
+    conn: &Connection,
+    authed: Option<&str>,
+    user_id: &str,
+    key: &str,
+    group_id: Option<&str>,
+    dm_channel_id: Option<&str>,
+    unbound: bool,
+) -> anyhow::Result<Option<Response>> {
+    let target = target_of_key(key);
+    match (group_id, dm_channel_id) {
+        (Some(group_id), _) => {
+            if authed.is_some() && !is_member(conn, group_id, user_id).await? {
+                return Ok(Some(AuthRejection::Forbidden.into_response()));
+            }
+            Ok(match group_target(conn, group_id).await? {
+                None => Some(bad_request("unknown group")),
+                Some(policy) if policy.as_deref() != target => {
+                    Some(residency_mismatch(policy.as_deref()))
+                }
+                Some(_) => None,
+            })
+        }
+        (None, Some(dm_channel_id)) => {
+            if !in_dm(conn, dm_channel_id, authed.map(|_| user_id)).await? {
+                return Ok(Some(match authed {
+                    Some(_) => AuthRejection::Forbidden.into_response(),
+                    None => bad_request("unknown dm channel"),
+                }));
+            }
+            Ok(target.map(|_| residency_mismatch(None)))
+        }
+        (None, None) => {
+            if target.is_some() {
+                return Ok(Some(bad_request("group_id required for a pinned upload")));
+            }
+            if !unbound {
+                return Ok(Some(bad_request(
+                    "group_id or dm_channel_id required for an attachment upload",
+                )));
+            }
+            if in_pinned_group(conn, user_id).await? {
+                return Ok(Some(bad_request(
+                    "a group you're in pins its attachments; update Pollis to upload",
+                )));
+            }
+            Ok(None)
+        }
+    }
+}
+
+/// GET /v1/storage/targets — the declared targets, for the owner's picker.
+/// Unauthenticated, like `/v1/limits`: ids and labels aren't secret.
+pub async fn list_targets(State(state): State<AppState>) -> Json<serde_json::Value> {
+    let targets: Vec<TargetInfo<'_>> = state
+        .storage_targets
+        .0
+        .iter()
+        .map(|t| TargetInfo {
+            id: &t.id,
+            label: &t.label,
+            region: &t.region,
+        })
+        .collect();
+    Json(serde_json::json!({ "targets": targets }))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn target_comes_from_the_key_prefix() {
+        assert_eq!(target_of_key("media@eu/abc/f.enc"), Some("eu"));
+        assert_eq!(target_of_key("media/abc/f.enc"), None);
+        assert_eq!(target_of_key("media@eu"), None);
+        assert_eq!(target_of_key("media/abc@eu/f.enc"), None);
+    }
+
+    #[test]
+    fn ids_are_lowercase_slugs() {
+        assert!(valid_id("eu-west"));
+        assert!(valid_id("onprem2"));
+        assert!(!valid_id("EU"));
+        assert!(!valid_id("eu/west"));
+        assert!(!valid_id(""));
+    }
+}
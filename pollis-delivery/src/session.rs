@@ -11,11 +11,14 @@
 //! the stored record — NEVER from the request body — the same property
 //! `resolve_actor` gives the device-signature path.
 //!
-//! **Store:** in-memory (the DS is single-container, mirroring the OTP store).
-//! Behind a small struct so a horizontally-scaled DS can swap it for a Turso
-//! `otp_session` table without touching the handlers.
+//! **Store:** in memory by default, mirroring the OTP store, so the bootstrap
+//! writes must reach the instance that verified the code. With
+//! `DS_SHARED_STATE=db` sessions live in the shared `ds_session` table
+//! (migration 000027), still keyed by the token hash, and any instance
+//! honors them — see [`SessionStore::shared`].
 
 use std::collections::HashMap;
+use std::sync::atomic::{AtomicU64, Ordering};
 use std::sync::{Arc, Mutex};
 
 use axum::http::HeaderMap;
@@ -23,6 +26,7 @@ use rand::rngs::OsRng;
 use rand::RngCore;
 use sha2::{Digest, Sha256};
 
+use crate::db::Db;
 use crate::error::AuthRejection;
 
 /// Header carrying the raw session token on bootstrap requests.
@@ -47,14 +51,23 @@ pub struct SessionClaims {
     pub device_id: String,
 }
 
-/// In-memory session store keyed by `SHA-256(token)` so the raw token is never
-/// at rest. `Clone` is shallow (shared `Arc`) so it rides on the `Clone`
-/// `AppState`.
+/// Session store keyed by `SHA-256(token)` so the raw token is never at rest,
+/// in memory or (see [`Self::shared`]) in the shared DB. `Clone` is shallow
+/// (shared `Arc`s) so it rides on the `Clone` `AppState`.
 #[derive(Clone, Default)]
 pub struct SessionStore {
     inner: Arc<Mutex<HashMap<[u8; 32], SessionRecord>>>,
+    /// `Some` → sessions live in `ds_session`; `inner` is only the fallback
+    /// while the DB is unreachable.
+    shared: Option<Arc<Db>>,
+    /// Shared-mode sessions minted on this instance, for pacing the prune.
+    minted: Arc<AtomicU64>,
 }
 
+/// In shared mode, every this-many minted sessions an instance deletes expired
+/// ones (a bootstrap that never reached cert publish leaves its row behind).
+const SHARED_PRUNE_EVERY: u64 = 100;
+
 fn hash_token(token: &str) -> [u8; 32] {
     let mut h = Sha256::new();
     h.update(token.as_bytes());
@@ -72,10 +85,20 @@ fn hex_lower(bytes: &[u8]) -> String {
 }
 
 impl SessionStore {
+    /// A store whose sessions live in `db`'s `ds_session` table, shared by
+    /// every DS instance on that database.
+    pub fn shared(db: Arc<Db>) -> Self {
+        Self {
+            shared: Some(db),
+            ..Self::default()
+        }
+    }
+
     /// Mint a fresh session for `(user_id, email, device_id)` valid for
     /// `ttl_secs` from `now`. Returns the raw token to hand the client exactly
-    /// once; only its hash is retained here.
-    pub fn mint(
+    /// once; only its hash is retained here. A shared-store error is logged and
+    /// the session kept in memory instead.
+    pub async fn mint(
         &self,
         user_id: &str,
         email: &str,
@@ -92,6 +115,23 @@ impl SessionStore {
             device_id: device_id.to_string(),
             expires_at: now.saturating_add(ttl_secs),
         };
+        if let Some(db) = &self.shared {
+            match shared_insert(db, &hash_token(&token), &record).await {
+                Ok(()) => {
+                    if self.minted.fetch_add(1, Ordering::Relaxed) % SHARED_PRUNE_EVERY == 0 {
+                        if let Err(e) = shared_prune(db, now).await {
+                            tracing::warn!("session prune: {e:#}");
+                        }
+                    }
+                    return token;
+                }
+                Err(e) => {
+                    tracing::warn!(
+                        "shared session store unavailable, keeping the session locally: {e:#}"
+                    );
+                }
+            }
+        }
         self.inner
             .lock()
             .expect("session store mutex poisoned")
@@ -101,7 +141,32 @@ impl SessionStore {
 
     /// Resolve a raw token to its live claims, or `None` if unknown/expired. An
     /// expired record is removed on lookup.
-    pub fn resolve(&self, token: &str, now: u64) -> Option<SessionClaims> {
+    pub async fn resolve(&self, token: &str, now: u64) -> Option<SessionClaims> {
+        if let Some(db) = &self.shared {
+            match shared_resolve(db, &hash_token(token), now).await {
+                Ok(Some(claims)) => return Some(claims),
+                // Not in the table: it may have been minted during an outage.
+                Ok(None) => {}
+                Err(e) => {
+                    tracing::warn!("shared session store unavailable, resolving locally: {e:#}")
+                }
+            }
+        }
+        self.resolve_local(token, now)
+    }
+
+    /// Single-use teardown: drop the token so it can't be replayed (called when
+    /// the bootstrap sequence completes at cert publish).
+    pub async fn invalidate(&self, token: &str) {
+        if let Some(db) = &self.shared {
+            if let Err(e) = shared_delete(db, &hash_token(token)).await {
+                tracing::warn!("session invalidate: {e:#}");
+            }
+        }
+        self.invalidate_local(token);
+    }
+
+    fn resolve_local(&self, token: &str, now: u64) -> Option<SessionClaims> {
         let key = hash_token(token);
         let mut guard = self.inner.lock().expect("session store mutex poisoned");
         match guard.get(&key) {
@@ -118,9 +183,7 @@ impl SessionStore {
         }
     }
 
-    /// Single-use teardown: drop the token so it can't be replayed (called when
-    /// the bootstrap sequence completes at cert publish).
-    pub fn invalidate(&self, token: &str) {
+    fn invalidate_local(&self, token: &str) {
         self.inner
             .lock()
             .expect("session store mutex poisoned")
@@ -128,6 +191,72 @@ impl SessionStore {
     }
 }
 
+async fn shared_insert(db: &Db, key: &[u8; 32], record: &SessionRecord) -> anyhow::Result<()> {
+    db.conn()?
+        .execute(
+            "INSERT INTO ds_session (token_hash, user_id, email, device_id, expires_at) \
+             VALUES (?1, ?2, ?3, ?4, ?5)",
+            libsql::params![
+                key.to_vec(),
+                record.user_id.clone(),
+                record.email.clone(),
+                record.device_id.clone(),
+                record.expires_at as i64,
+            ],
+        )
+        .await?;
+    Ok(())
+}
+
+/// [`SessionStore::resolve_local`] against `ds_session`: an expired row is
+/// deleted on lookup.
+async fn shared_resolve(
+    db: &Db,
+    key: &[u8; 32],
+    now: u64,
+) -> anyhow::Result<Option<SessionClaims>> {
+    let mut rows = db
+        .conn()?
+        .query(
+            "SELECT user_id, email, device_id, expires_at FROM ds_session WHERE token_hash = ?1",
+            libsql::params![key.to_vec()],
+        )
+        .await?;
+    let Some(row) = rows.next().await? else {
+        return Ok(None);
+    };
+    if now > row.get::<i64>(3)?.max(0) as u64 {
+        drop(rows);
+        shared_delete(db, key).await?;
+        return Ok(None);
+    }
+    Ok(Some(SessionClaims {
+        user_id: row.get(0)?,
+        email: row.get(1)?,
+        device_id: row.get(2)?,
+    }))
+}
+
+async fn shared_delete(db: &Db, key: &[u8; 32]) -> anyhow::Result<()> {
+    db.conn()?
+        .execute(
+            "DELETE FROM ds_session WHERE token_hash = ?1",
+            libsql::params![key.to_vec()],
+        )
+        .await?;
+    Ok(())
+}
+
+async fn shared_prune(db: &Db, now: u64) -> anyhow::Result<()> {
+    db.conn()?
+        .execute(
+            "DELETE FROM ds_session WHERE expires_at < ?1",
+            libsql::params![now as i64],
+        )
+        .await?;
+    Ok(())
+}
+
 /// Pull the raw session token off the request headers.
 pub fn session_token(headers: &HeaderMap) -> Option<&str> {
     headers.get(SESSION_HEADER)?.to_str().ok()
@@ -137,7 +266,7 @@ pub fn session_token(headers: &HeaderMap) -> Option<&str> {
 /// authenticated [`SessionClaims`] (bind `user_id` from here, never the body) or
 /// [`AuthRejection::Unauthorized`] for a missing/unknown/expired token. Never
 /// fails open.
-pub fn verify_session(
+pub async fn verify_session(
     headers: &HeaderMap,
     store: &SessionStore,
     now: u64,
@@ -146,42 +275,45 @@ pub fn verify_session(
     if token.is_empty() {
         return Err(AuthRejection::Unauthorized);
     }
-    store.resolve(token, now).ok_or(AuthRejection::Unauthorized)
+    store
+        .resolve(token, now)
+        .await
+        .ok_or(AuthRejection::Unauthorized)
 }
 
 #[cfg(test)]
 mod tests {
     use super::*;
 
-    #[test]
-    fn mint_then_resolve_binds_user() {
+    #[tokio::test]
+    async fn mint_then_resolve_binds_user() {
         let store = SessionStore::default();
-        let token = store.mint("u1", "u1@x.com", "dev1", 600, 1000);
-        let claims = store.resolve(&token, 1000).expect("live");
+        let token = store.mint("u1", "u1@x.com", "dev1", 600, 1000).await;
+        let claims = store.resolve(&token, 1000).await.expect("live");
         assert_eq!(claims.user_id, "u1");
         assert_eq!(claims.device_id, "dev1");
     }
 
-    #[test]
-    fn expired_token_rejected_and_removed() {
+    #[tokio::test]
+    async fn expired_token_rejected_and_removed() {
         let store = SessionStore::default();
-        let token = store.mint("u1", "u1@x.com", "dev1", 600, 1000);
-        assert!(store.resolve(&token, 2000).is_none());
+        let token = store.mint("u1", "u1@x.com", "dev1", 600, 1000).await;
+        assert!(store.resolve(&token, 2000).await.is_none());
         // Removed on lookup — a later in-window check still fails.
-        assert!(store.resolve(&token, 1000).is_none());
+        assert!(store.resolve(&token, 1000).await.is_none());
     }
 
-    #[test]
-    fn invalidate_makes_token_unusable() {
+    #[tokio::test]
+    async fn invalidate_makes_token_unusable() {
         let store = SessionStore::default();
-        let token = store.mint("u1", "u1@x.com", "dev1", 600, 1000);
-        store.invalidate(&token);
-        assert!(store.resolve(&token, 1000).is_none());
+        let token = store.mint("u1", "u1@x.com", "dev1", 600, 1000).await;
+        store.invalidate(&token).await;
+        assert!(store.resolve(&token, 1000).await.is_none());
     }
 
-    #[test]
-    fn unknown_token_rejected() {
+    #[tokio::test]
+    async fn unknown_token_rejected() {
         let store = SessionStore::default();
-        assert!(store.resolve("deadbeef", 1000).is_none());
+        assert!(store.resolve("deadbeef", 1000).await.is_none());
     }
 }
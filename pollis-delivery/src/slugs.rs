@@ -0,0 +1,421 @@
+//! Group slugs — one canonical form, reserved server-side.
+//!
+//! Before this module the `/g/<slug>` form was derived on each client (the
+//! frontend's `deriveSlug`, pollis-core's `derive_slug`) and never stored, so
+//! two clients creating "Book Club" at once both got `book-club`. Now:
+//!
+//!   - [`normalize_slug`] is the canonical derivation: lowercase, Latin
+//!     diacritics and fullwidth forms folded to ASCII, whitespace/`_` to `-`,
+//!     everything else dropped, capped at [`MAX_SLUG_LEN`].
+//!     `POST /v1/slugs/normalize` exposes it so clients preview the same
+//!     result the DS will reserve.
+//!   - `POST /v1/slugs/reserve` atomically inserts a `group_slug` row
+//!     (migration 000018) for the actor. The primary key is the race guard;
+//!     the upsert only takes over a row that is the actor's own or an
+//!     unclaimed reservation older than [`RESERVATION_TTL_MINUTES`].
+//!   - `POST /v1/groups/create` with `slug` claims the reservation in the same
+//!     transaction as the group insert (see `groups::apply_create_group`).
+//!     Without one (a client shipped before reservations) the group claims
+//!     the slug its name derives to, and is refused when that's taken.
+//!   - Groups created before migration 000018 get their name-derived row from
+//!     [`backfill_legacy_slugs`], run once at DS start ([`spawn_backfill`]),
+//!     so reserving never has to scan group names.
+//!
+//! Channel slugs stay client-derived: they are only unique within a group,
+//! and `CreateChannel` already checks the group's channel list.
+
+use std::sync::Arc;
+
+use axum::{
+    body::Bytes,
+    extract::State,
+    http::{HeaderMap, Method, StatusCode, Uri},
+    response::{IntoResponse, Response},
+    Json,
+};
+use libsql::Connection;
+use serde::Deserialize;
+
+use crate::db::Db;
+use crate::error::AppError;
+use crate::writes::{bad_request, gate, ok_json, outcome_response, resolve_actor, WriteOutcome};
+use crate::AppState;
+
+/// Longest slug the DS will normalize to or reserve.
+pub const MAX_SLUG_LEN: usize = 48;
+/// How long an unclaimed reservation holds its slug.
+pub const RESERVATION_TTL_MINUTES: u32 = 10;
+
+/// Slugs no group may take: the app's own top-level routes and names that
+/// would read as the service speaking.
+pub const RESERVED_SLUGS: &[&str] = &[
+    "admin",
+    "api",
+    "c",
+    "create-channel",
+    "create-group",
+    "g",
+    "help",
+    "me",
+    "new",
+    "null",
+    "pollis",
+    "search-group",
+    "settings",
+    "start-dm",
+    "support",
+    "system",
+    "undefined",
+    "voice",
+];
+
+/// Canonical slug for `input`, or `None` when nothing usable is left.
+pub fn normalize_slug(input: &str) -> Option<String> {
+    let mut out = String::with_capacity(input.len());
+    for c in input.chars().flat_map(char::to_lowercase) {
+        match fold_char(c) {
+            Some(folded) => out.push_str(folded),
+            None if c.is_ascii_lowercase() || c.is_ascii_digit() => out.push(c),
+            None if c.is_whitespace() || c == '-' || c == '_' => {
+                if !out.is_empty() && !out.ends_with('-') {
+                    out.push('-');
+                }
+            }
+            None => {}
+        }
+        if out.len() >= MAX_SLUG_LEN {
+            break;
+        }
+    }
+    out.truncate(MAX_SLUG_LEN);
+    let slug = out.trim_matches('-').to_string();
+    if slug.is_empty() {
+        None
+    } else {
+        Some(slug)
+    }
+}
+
+/// True when `slug` (already normalized) is on [`RESERVED_SLUGS`].
+pub fn is_reserved(slug: &str) -> bool {
+    RESERVED_SLUGS.contains(&slug)
+}
+
+/// ASCII spelling of a lowercase Latin letter with a diacritic, or of a
+/// fullwidth ASCII letter/digit. `None` for everything else.
+fn fold_char(c: char) -> Option<&'static str> {
+    const DIGITS: [&str; 10] = ["0", "1", "2", "3", "4", "5", "6", "7", "8", "9"];
+    const LETTERS: [&str; 26] = [
+        "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r",
+        "s", "t", "u", "v", "w", "x", "y", "z",
+    ];
+    Some(match c {
+        // Fullwidth forms (U+FF10–FF19, U+FF41–FF5A); uppercase ones were
+        // lowercased into this range already.
+        '\u{ff10}'..='\u{ff19}' => DIGITS[c as usize - 0xff10],
+        '\u{ff41}'..='\u{ff5a}' => LETTERS[c as usize - 0xff41],
+        'à' | 'á' | 'â' | 'ã' | 'ä' | 'å' | 'ā' | 'ă' | 'ą' => "a",
+        'æ' => "ae",
+        'ç' | 'ć' | 'č' => "c",
+        'ď' | 'đ' | 'ð' => "d",
+        'è' | 'é' | 'ê' | 'ë' | 'ē' | 'ė' | 'ę' | 'ě' => "e",
+        'ğ' => "g",
+        'ì' | 'í' | 'î' | 'ï' | 'ī' | 'į' | 'ı' => "i",
+        'ł' | 'ľ' => "l",
+        'ñ' | 'ń' | 'ň' => "n",
+        'ò' | 'ó' | 'ô' | 'õ' | 'ö' | 'ø' | 'ō' | 'ő' => "o",
+        'œ' => "oe",
+        'ř' => "r",
+        'ś' | 'š' | 'ş' => "s",
+        'ß' => "ss",
+        'ť' | 'ţ' => "t",
+        'þ' => "th",
+        'ù' | 'ú' | 'û' | 'ü' | 'ū' | 'ů' | 'ű' => "u",
+        'ý' | 'ÿ' => "y",
+        'ź' | 'ż' | 'ž' => "z",
+        _ => return None,
+    })
+}
+
+/// Result of a reservation attempt.
+pub enum ReserveOutcome {
+    /// The actor now holds the slug (normalized form returned).
+    Reserved(String),
+    /// Nothing usable after normalization.
+    Invalid,
+    /// On [`RESERVED_SLUGS`].
+    ReservedWord,
+    /// Held by another user's live reservation or an existing group.
+    Taken,
+    Forbidden,
+}
+
+// ── POST /v1/slugs/normalize ─────────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct NormalizeSlugBody {
+    pub input: String,
+}
+
+/// POST /v1/slugs/normalize — the canonical slug for `input` plus whether it
+/// is a reserved word. Pure: nothing is written and availability isn't checked
+/// (that's what reserving is for).
+pub async fn normalize(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    if let Err(resp) = gate(&state, &headers, &method, &uri, &body).await? {
+        return Ok(resp);
+    }
+    let parsed: NormalizeSlugBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let slug = normalize_slug(&parsed.input);
+    let reserved = slug.as_deref().is_some_and(is_reserved);
+    Ok(ok_json(serde_json::json!({
+        "slug": slug,
+        "reserved": reserved,
+    })))
+}
+
+// ── POST /v1/slugs/reserve ───────────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct ReserveSlugBody {
+    /// Raw slug or group name; normalized before reserving.
+    pub slug: String,
+    /// The reserving user; bound to the authenticated user when signed.
+    #[serde(default)]
+    pub user_id: Option<String>,
+}
+
+pub async fn reserve(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: ReserveSlugBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let conn = state.db.conn()?;
+    Ok(
+        match apply_reserve_slug(&conn, authed.as_deref(), &parsed).await? {
+            ReserveOutcome::Reserved(slug) => ok_json(serde_json::json!({ "slug": slug })),
+            ReserveOutcome::Invalid => bad_request("slug must contain a letter or number"),
+            ReserveOutcome::ReservedWord => bad_request("slug is reserved"),
+            ReserveOutcome::Taken => (
+                StatusCode::CONFLICT,
+                Json(serde_json::json!({ "status": "conflict", "error": "slug taken" })),
+            )
+                .into_response(),
+            ReserveOutcome::Forbidden => outcome_response(WriteOutcome::Forbidden)?,
+        },
+    )
+}
+
+/// Normalize `body.slug` and reserve it for the actor. Re-reserving your own
+/// slug refreshes it.
+pub async fn apply_reserve_slug(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &ReserveSlugBody,
+) -> anyhow::Result<ReserveOutcome> {
+    let actor = match resolve_actor(authed, body.user_id.as_deref()) {
+        Ok(a) => a,
+        Err(_) => return Ok(ReserveOutcome::Forbidden),
+    };
+    let Some(slug) = normalize_slug(&body.slug) else {
+        return Ok(ReserveOutcome::Invalid);
+    };
+    if is_reserved(&slug) {
+        return Ok(ReserveOutcome::ReservedWord);
+    }
+    let affected = conn
+        .execute(
+            "INSERT INTO group_slug (slug, user_id, group_id, reserved_at) \
+             VALUES (?1, ?2, NULL, datetime('now')) \
+             ON CONFLICT(slug) DO UPDATE SET \
+                user_id = excluded.user_id, reserved_at = excluded.reserved_at \
+             WHERE group_slug.group_id IS NULL \
+               AND (group_slug.user_id = excluded.user_id \
+                    OR group_slug.reserved_at < datetime('now', ?3))",
+            libsql::params![
+                slug.clone(),
+                actor,
+                format!("-{RESERVATION_TTL_MINUTES} minutes"),
+            ],
+        )
+        .await?;
+    Ok(if affected == 0 {
+        ReserveOutcome::Taken
+    } else {
+        ReserveOutcome::Reserved(slug)
+    })
+}
+
+/// Claim `slug` for `group_id` on behalf of `user_id`, who must hold the
+/// unclaimed reservation. Returns false when they don't. Runs inside the
+/// group-create transaction.
+pub(crate) async fn claim_slug(
+    conn: &Connection,
+    slug: &str,
+    user_id: &str,
+    group_id: &str,
+) -> anyhow::Result<bool> {
+    let affected = conn
+        .execute(
+            "UPDATE group_slug SET group_id = ?3 \
+             WHERE slug = ?1 AND user_id = ?2 AND group_id IS NULL",
+            libsql::params![slug.to_string(), user_id.to_string(), group_id.to_string()],
+        )
+        .await?;
+    Ok(affected == 1)
+}
+
+/// Claim the slug `name` derives to for `group_id` — the create path of a
+/// client that didn't reserve one. Like reserving, it takes over the owner's
+/// own or an expired unclaimed reservation. Returns false when another group
+/// or a live reservation holds it; true with nothing written when the name
+/// has no usable slug or derives to a reserved word. Runs inside the
+/// group-create transaction.
+pub(crate) async fn claim_name_slug(
+    conn: &Connection,
+    name: &str,
+    user_id: &str,
+    group_id: &str,
+) -> anyhow::Result<bool> {
+    let Some(slug) = normalize_slug(name).filter(|s| !is_reserved(s)) else {
+        return Ok(true);
+    };
+    let affected = conn
+        .execute(
+            "INSERT INTO group_slug (slug, user_id, group_id, reserved_at) \
+             VALUES (?1, ?2, ?3, datetime('now')) \
+             ON CONFLICT(slug) DO UPDATE SET \
+                user_id = excluded.user_id, group_id = excluded.group_id, \
+                reserved_at = excluded.reserved_at \
+             WHERE group_slug.group_id IS NULL \
+               AND (group_slug.user_id = excluded.user_id \
+                    OR group_slug.reserved_at < datetime('now', ?4))",
+            libsql::params![
+                slug,
+                user_id.to_string(),
+                group_id.to_string(),
+                format!("-{RESERVATION_TTL_MINUTES} minutes"),
+            ],
+        )
+        .await?;
+    Ok(affected == 1)
+}
+
+/// Give every group without a `group_slug` row the slug its name derives to,
+/// oldest group first, taking over any unclaimed reservation of it. Groups
+/// that predate migration 000018 were addressed by their name; this makes
+/// their slug a row like any other. A name with no usable slug, a reserved
+/// word, or a slug an older group already holds is left without one. Returns
+/// how many rows were written.
+pub async fn backfill_legacy_slugs(conn: &Connection) -> anyhow::Result<usize> {
+    let mut rows = conn
+        .query(
+            "SELECT g.id, g.name, g.owner_id FROM groups g \
+             WHERE NOT EXISTS (SELECT 1 FROM group_slug s WHERE s.group_id = g.id) \
+             ORDER BY g.created_at, g.id",
+            (),
+        )
+        .await?;
+    let mut legacy: Vec<(String, String, String)> = Vec::new();
+    while let Some(row) = rows.next().await? {
+        legacy.push((row.get(0)?, row.get(1)?, row.get(2)?));
+    }
+    drop(rows);
+
+    let mut written = 0;
+    for (group_id, name, owner_id) in legacy {
+        let Some(slug) = normalize_slug(&name).filter(|s| !is_reserved(s)) else {
+            continue;
+        };
+        let affected = conn
+            .execute(
+                "INSERT INTO group_slug (slug, user_id, group_id, reserved_at) \
+                 VALUES (?1, ?2, ?3, datetime('now')) \
+                 ON CONFLICT(slug) DO UPDATE SET \
+                    user_id = excluded.user_id, group_id = excluded.group_id, \
+                    reserved_at = excluded.reserved_at \
+                 WHERE group_slug.group_id IS NULL",
+                libsql::params![slug, owner_id, group_id.clone()],
+            )
+            .await;
+        match affected {
+            Ok(n) => written += n as usize,
+            // E.g. an owner whose `users` row is gone; the rest still go in.
+            Err(e) => tracing::warn!(%group_id, "slug backfill: {e}"),
+        }
+    }
+    Ok(written)
+}
+
+/// Run [`backfill_legacy_slugs`] once on the current tokio runtime. Every
+/// instance runs it at start; it only fills missing rows, so overlapping runs
+/// are harmless.
+pub fn spawn_backfill(db: Arc<Db>) {
+    tokio::spawn(async move {
+        let result = match db.conn() {
+            Ok(conn) => backfill_legacy_slugs(&conn).await,
+            Err(e) => Err(e),
+        };
+        match result {
+            Ok(0) => {}
+            Ok(written) => tracing::info!(written, "group slug backfill"),
+            Err(e) => tracing::warn!("group slug backfill: {e:#}"),
+        }
+    });
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn normalize_matches_the_client_derivation_for_ascii() {
+        assert_eq!(normalize_slug("Test Group").as_deref(), Some("test-group"));
+        assert_eq!(
+            normalize_slug("Hello, World!").as_deref(),
+            Some("hello-world")
+        );
+        assert_eq!(normalize_slug("  a   b  ").as_deref(), Some("a-b"));
+        assert_eq!(normalize_slug("-a---b_c-").as_deref(), Some("a-b-c"));
+    }
+
+    #[test]
+    fn normalize_folds_diacritics_and_fullwidth_forms() {
+        assert_eq!(normalize_slug("Café Crème").as_deref(), Some("cafe-creme"));
+        assert_eq!(normalize_slug("Straße").as_deref(), Some("strasse"));
+        assert_eq!(normalize_slug("ＰＯＬＬＩＳ１").as_deref(), Some("pollis1"));
+    }
+
+    #[test]
+    fn normalize_rejects_empty_and_caps_length() {
+        assert_eq!(normalize_slug("!!! ???"), None);
+        assert_eq!(normalize_slug("日本語"), None);
+        let long = "a".repeat(200);
+        assert_eq!(normalize_slug(&long).unwrap().len(), MAX_SLUG_LEN);
+    }
+
+    #[test]
+    fn reserved_words_cover_app_routes() {
+        assert!(is_reserved("settings"));
+        assert!(is_reserved("create-group"));
+        assert!(!is_reserved("book-club"));
+    }
+}
@@ -0,0 +1,437 @@
+//! Object storage behind `POST /v1/r2/presign`.
+//!
+//! Clients never talk to a storage API: they ask the DS for a short-lived
+//! presigned URL and do a plain GET/PUT/DELETE against it (see [`crate::broker`]
+//! and pollis-core's `r2.rs`). So "which object store" is purely a DS concern —
+//! which URL the presign endpoint hands back. [`ObjectStorage`] is that seam,
+//! selected by `STORAGE_BACKEND`:
+//!
+//!   - **`s3`** (default) — any SigV4 S3 endpoint: Cloudflare R2, MinIO,
+//!     Garage, AWS. Credentials are the broker's `R2_*` / `S3_*` env (see
+//!     [`crate::broker::BrokerConfig`]); point `R2_ENDPOINT` at MinIO and set
+//!     `R2_REGION` to its region (usually `us-east-1`). Path-style URLs, so no
+//!     virtual-host DNS is needed.
+//!   - **`fs`** — objects live under a directory on the DS host, for fully
+//!     offline self-hosted deployments. Presigned URLs point back at the DS's
+//!     own `/v1/blobs/*key` routes ([`blob`]), authorized by an HMAC over
+//!     method, key and expiry instead of SigV4. Env: `STORAGE_FS_ROOT`
+//!     (required), `STORAGE_FS_PUBLIC_URL` (the DS base URL clients reach, e.g.
+//!     `https://pollis.example.org`; required), `STORAGE_FS_SIGNING_KEY`
+//!     (optional — random per process otherwise, which only costs outstanding
+//!     URLs on restart), `STORAGE_FS_MAX_OBJECT_BYTES` (default 100 MiB).
+//!
+//! The bucket holds only convergently-encrypted ciphertext either way, so the
+//! `fs` backend needs no more authz than the presign gate already gives. PUT
+//! URLs are bound to the declared upload ([`PutBinding`], see
+//! [`crate::uploads`]) under both backends.
+//!
+//! Attachments of a group pinned to an extra S3 store bypass this selection:
+//! their `media@<target>/…` keys are signed for that store (see
+//! [`crate::residency`]).
+
+use std::path::{Path as FsPath, PathBuf};
+use std::sync::Arc;
+
+use axum::{
+    body::Bytes,
+    extract::{Path, Query, State},
+    http::{header, HeaderMap, Method, StatusCode},
+    response::{IntoResponse, Response},
+    Json,
+};
+use hmac::{Hmac, Mac};
+use rand::RngCore;
+use serde::Deserialize;
+use sha2::{Digest, Sha256};
+
+use crate::broker::{presign_r2_url_with_headers, uri_encode, BrokerConfig};
+use crate::uploads::{sniff_image, UploadCategory};
+use crate::AppState;
+
+/// Default cap on one `fs` object, bytes.
+const DEFAULT_MAX_OBJECT_BYTES: usize = 100 * 1024 * 1024;
+
+/// Where presigned URLs point. See the module docs.
+#[derive(Clone, Default)]
+pub enum ObjectStorage {
+    /// SigV4 presign against the broker's S3 endpoint (R2, MinIO, …).
+    #[default]
+    S3,
+    /// HMAC-signed URLs to this DS's `/v1/blobs` routes, backed by a directory.
+    Filesystem(FsStorage),
+}
+
+/// The `fs` backend's settings.
+#[derive(Clone)]
+pub struct FsStorage {
+    pub root: PathBuf,
+    /// DS base URL as clients reach it, no trailing slash.
+    pub public_url: String,
+    /// HMAC key for blob URLs. NEVER logged.
+    pub signing_key: Arc<Vec<u8>>,
+    pub max_object_bytes: usize,
+}
+
+/// What a PUT URL is held to: the store refuses a body of another length, and
+/// under `s3` another Content-Type.
+#[derive(Clone, Copy, Debug)]
+pub struct PutBinding<'a> {
+    pub content_type: &'a str,
+    pub size: u64,
+}
+
+impl ObjectStorage {
+    /// Select the backend from DS env. An `fs` selection missing its required
+    /// settings logs and falls back to `s3` (whose presign then 503s unless the
+    /// S3 secrets are set), so a typo can't take the DS down at startup.
+    pub fn from_env() -> Self {
+        let var = |k: &str| std::env::var(k).ok().filter(|s| !s.is_empty());
+        match var("STORAGE_BACKEND").as_deref() {
+            None | Some("s3") | Some("r2") => ObjectStorage::S3,
+            Some("fs") => match (var("STORAGE_FS_ROOT"), var("STORAGE_FS_PUBLIC_URL")) {
+                (Some(root), Some(public_url)) => {
+                    let signing_key = var("STORAGE_FS_SIGNING_KEY")
+                        .map(String::into_bytes)
+                        .unwrap_or_else(|| {
+                            let mut k = vec![0u8; 32];
+                            rand::rngs::OsRng.fill_bytes(&mut k);
+                            k
+                        });
+                    ObjectStorage::Filesystem(FsStorage {
+                        root: PathBuf::from(root),
+                        public_url: public_url.trim_end_matches('/').to_string(),
+                        signing_key: Arc::new(signing_key),
+                        max_object_bytes: var("STORAGE_FS_MAX_OBJECT_BYTES")
+                            .and_then(|s| s.parse().ok())
+                            .unwrap_or(DEFAULT_MAX_OBJECT_BYTES),
+                    })
+                }
+                _ => {
+                    tracing::error!(
+                        "STORAGE_BACKEND=fs needs STORAGE_FS_ROOT and STORAGE_FS_PUBLIC_URL; using s3"
+                    );
+                    ObjectStorage::S3
+                }
+            },
+            Some(other) => {
+                tracing::error!(backend = other, "unknown STORAGE_BACKEND; using s3");
+                ObjectStorage::S3
+            }
+        }
+    }
+
+    /// A presigned URL for `method` (`GET`/`PUT`/`DELETE`) on `key`, valid for
+    /// `expires` seconds from `now`, bound to `put` when given. `None` when the
+    /// backend isn't configured. `datetime` is the SigV4 timestamp (same
+    /// instant as `now`).
+    #[allow(clippy::too_many_arguments)]
+    pub fn presign(
+        &self,
+        broker: &BrokerConfig,
+        method: &str,
+        key: &str,
+        expires: u64,
+        now: u64,
+        datetime: &str,
+        put: Option<PutBinding<'_>>,
+    ) -> Option<String> {
+        match self {
+            ObjectStorage::S3 => {
+                let (endpoint, bucket, access_key, secret_key) = broker.r2_ready()?;
+                Some(presign_s3(
+                    endpoint,
+                    bucket,
+                    &broker.r2_region,
+                    access_key,
+                    secret_key,
+                    method,
+                    key,
+                    expires,
+                    datetime,
+                    put,
+                ))
+            }
+            ObjectStorage::Filesystem(fs) => {
+                Some(fs.presign(method, key, now + expires, put.map(|p| p.size)))
+            }
+        }
+    }
+
+    /// Max request body for the `/v1/blobs` routes (`0` under `s3`, where
+    /// they only ever answer 404).
+    pub fn max_object_bytes(&self) -> usize {
+        match self {
+            ObjectStorage::S3 => 0,
+            ObjectStorage::Filesystem(fs) => fs.max_object_bytes,
+        }
+    }
+}
+
+/// A SigV4 presigned URL against one S3 endpoint, with `put`'s length and
+/// Content-Type as signed headers. Shared by the default store and the
+/// per-group targets in [`crate::residency`].
+#[allow(clippy::too_many_arguments)]
+pub(crate) fn presign_s3(
+    endpoint: &str,
+    bucket: &str,
+    region: &str,
+    access_key: &str,
+    secret_key: &str,
+    method: &str,
+    key: &str,
+    expires: u64,
+    datetime: &str,
+    put: Option<PutBinding<'_>>,
+) -> String {
+    let size = put.map(|p| p.size.to_string());
+    let headers: Vec<(&str, &str)> = match (&put, &size) {
+        (Some(p), Some(size)) => {
+            vec![("content-length", size.as_str()), ("content-type", p.content_type)]
+        }
+        _ => Vec::new(),
+    };
+    presign_r2_url_with_headers(
+        endpoint, bucket, region, access_key, secret_key, method, key, expires, datetime, &headers,
+    )
+}
+
+impl FsStorage {
+    /// `{public_url}/v1/blobs/{key}?expires=…[&len=…]&sig=…`. `len` pins a
+    /// PUT's body length; the signature covers it.
+    pub fn presign(&self, method: &str, key: &str, expires_at: u64, len: Option<u64>) -> String {
+        let len_param = len.map(|n| format!("&len={n}")).unwrap_or_default();
+        format!(
+            "{}/v1/blobs/{}?expires={expires_at}{len_param}&sig={}",
+            self.public_url,
+            uri_encode(key, false),
+            hex(&self.mac(method, key, expires_at, len).finalize().into_bytes()),
+        )
+    }
+
+    fn mac(&self, method: &str, key: &str, expires_at: u64, len: Option<u64>) -> Hmac<Sha256> {
+        let mut mac =
+            Hmac::<Sha256>::new_from_slice(&self.signing_key).expect("hmac accepts any key length");
+        mac.update(format!("{method}\n{key}\n{expires_at}").as_bytes());
+        if let Some(len) = len {
+            mac.update(format!("\n{len}").as_bytes());
+        }
+        mac
+    }
+
+    /// Constant-time check of a blob URL's signature and expiry.
+    fn verify(
+        &self,
+        method: &str,
+        key: &str,
+        expires_at: u64,
+        len: Option<u64>,
+        sig_hex: &str,
+        now: u64,
+    ) -> bool {
+        if now >= expires_at {
+            return false;
+        }
+        match unhex(sig_hex) {
+            Some(sig) => self.mac(method, key, expires_at, len).verify_slice(&sig).is_ok(),
+            None => false,
+        }
+    }
+
+    /// The on-disk path for `key`, or `None` if the key could escape `root`.
+    fn path_for(&self, key: &str) -> Option<PathBuf> {
+        safe_key(key).then(|| self.root.join(key))
+    }
+}
+
+/// Object keys are `/`-separated relative paths (`media/<hash>/<file>.enc`,
+/// `avatars/<user>/<file>`); reject anything that could leave the root.
+fn safe_key(key: &str) -> bool {
+    !key.is_empty()
+        && key.len() <= 1024
+        && !key.contains(['\\', '\0'])
+        && key
+            .split('/')
+            .all(|seg| !seg.is_empty() && seg != "." && seg != "..")
+}
+
+#[derive(Deserialize)]
+pub struct BlobQuery {
+    expires: u64,
+    #[serde(default)]
+    len: Option<u64>,
+    sig: String,
+}
+
+fn blob_error(status: StatusCode, msg: &str) -> Response {
+    (status, Json(serde_json::json!({ "error": msg }))).into_response()
+}
+
+/// GET/PUT/DELETE /v1/blobs/*key — the `fs` backend's object endpoint. Not
+/// device-signed: the presigned query string is the authorization, exactly as
+/// with an S3 URL. GET answers `If-None-Match` with `304` (the ETag is the
+/// content's SHA-256); DELETE of a missing object is `404`, which clients treat
+/// as already gone. A PUT must match the signed `len`, and a PUT to an image key
+/// must actually be one of the allowed images ([`crate::uploads`]).
+pub async fn blob(
+    State(state): State<AppState>,
+    method: Method,
+    Path(key): Path<String>,
+    Query(q): Query<BlobQuery>,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Response {
+    let ObjectStorage::Filesystem(fs) = &state.storage else {
+        return blob_error(StatusCode::NOT_FOUND, "not found");
+    };
+    if !fs.verify(
+        method.as_str(),
+        &key,
+        q.expires,
+        q.len,
+        &q.sig,
+        crate::ratelimit::now_unix(),
+    ) {
+        return blob_error(StatusCode::FORBIDDEN, "invalid or expired signature");
+    }
+    let Some(path) = fs.path_for(&key) else {
+        return blob_error(StatusCode::BAD_REQUEST, "invalid key");
+    };
+
+    if method == Method::PUT {
+        if q.len.is_some_and(|len| len != body.len() as u64) {
+            return blob_error(StatusCode::BAD_REQUEST, "body does not match the presigned size");
+        }
+        let is_image = UploadCategory::for_key(&key).is_some_and(UploadCategory::is_image);
+        if is_image && sniff_image(&body).is_none() {
+            return blob_error(StatusCode::BAD_REQUEST, "not an allowed image type");
+        }
+    }
+
+    let result = match method {
+        Method::GET => get_blob(&path, &headers).await,
+        Method::PUT => put_blob(&path, body).await,
+        Method::DELETE => delete_blob(&path).await,
+        _ => return blob_error(StatusCode::METHOD_NOT_ALLOWED, "method not allowed"),
+    };
+    result.unwrap_or_else(|e| {
+        tracing::error!(error = %e, "blob {method} failed");
+        blob_error(StatusCode::INTERNAL_SERVER_ERROR, "storage error")
+    })
+}
+
+async fn get_blob(path: &FsPath, headers: &HeaderMap) -> std::io::Result<Response> {
+    let data = match tokio::fs::read(path).await {
+        Ok(d) => d,
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => {
+            return Ok(blob_error(StatusCode::NOT_FOUND, "not found"));
+        }
+        Err(e) => return Err(e),
+    };
+    let etag = format!("\"{}\"", hex(&Sha256::digest(&data)));
+    let fresh = headers
+        .get(header::IF_NONE_MATCH)
+        .and_then(|v| v.to_str().ok())
+        .is_some_and(|v| v == etag);
+    if fresh {
+        return Ok((StatusCode::NOT_MODIFIED, [(header::ETAG, etag)]).into_response());
+    }
+    Ok((
+        StatusCode::OK,
+        [
+            (header::ETAG, etag),
+            (header::CONTENT_TYPE, "application/octet-stream".to_string()),
+        ],
+        data,
+    )
+        .into_response())
+}
+
+/// Write via a temp file + rename so a reader never sees a partial object.
+async fn put_blob(path: &FsPath, body: Bytes) -> std::io::Result<Response> {
+    if let Some(parent) = path.parent() {
+        tokio::fs::create_dir_all(parent).await?;
+    }
+    let name = path.file_name().unwrap_or_default().to_string_lossy();
+    let tmp = path.with_file_name(format!(".{name}.tmp-{}", ulid::Ulid::new()));
+    tokio::fs::write(&tmp, &body).await?;
+    if let Err(e) = tokio::fs::rename(&tmp, path).await {
+        let _ = tokio::fs::remove_file(&tmp).await;
+        return Err(e);
+    }
+    Ok(StatusCode::OK.into_response())
+}
+
+async fn delete_blob(path: &FsPath) -> std::io::Result<Response> {
+    match tokio::fs::remove_file(path).await {
+        Ok(()) => Ok(StatusCode::NO_CONTENT.into_response()),
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => {
+            Ok(blob_error(StatusCode::NOT_FOUND, "not found"))
+        }
+        Err(e) => Err(e),
+    }
+}
+
+fn hex(bytes: &[u8]) -> String {
+    bytes.iter().map(|b| format!("{b:02x}")).collect()
+}
+
+fn unhex(s: &str) -> Option<Vec<u8>> {
+    if s.len() % 2 != 0 {
+        return None;
+    }
+    (0..s.len())
+        .step_by(2)
+        .map(|i| u8::from_str_radix(s.get(i..i + 2)?, 16).ok())
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn fs() -> FsStorage {
+        FsStorage {
+            root: PathBuf::from("/srv/pollis"),
+            public_url: "https://ds.example.org".to_string(),
+            signing_key: Arc::new(b"k".to_vec()),
+            max_object_bytes: DEFAULT_MAX_OBJECT_BYTES,
+        }
+    }
+
+    #[test]
+    fn signature_binds_method_key_and_expiry() {
+        let fs = fs();
+        let url = fs.presign("GET", "media/abc/f.enc", 2000, None);
+        let sig = url.split("sig=").nth(1).unwrap();
+        assert!(url.starts_with("https://ds.example.org/v1/blobs/media/abc/f.enc?expires=2000&"));
+        assert!(fs.verify("GET", "media/abc/f.enc", 2000, None, sig, 1000));
+        assert!(!fs.verify("PUT", "media/abc/f.enc", 2000, None, sig, 1000));
+        assert!(!fs.verify("GET", "media/abc/g.enc", 2000, None, sig, 1000));
+        assert!(!fs.verify("GET", "media/abc/f.enc", 3000, None, sig, 1000));
+        // Expired.
+        assert!(!fs.verify("GET", "media/abc/f.enc", 2000, None, sig, 2000));
+    }
+
+    #[test]
+    fn put_signature_binds_the_length() {
+        let fs = fs();
+        let url = fs.presign("PUT", "media/abc/f.enc", 2000, Some(512));
+        let sig = url.split("sig=").nth(1).unwrap();
+        assert!(url.contains("?expires=2000&len=512&sig="));
+        assert!(fs.verify("PUT", "media/abc/f.enc", 2000, Some(512), sig, 1000));
+        // Dropping or changing the length invalidates the URL.
+        assert!(!fs.verify("PUT", "media/abc/f.enc", 2000, None, sig, 1000));
+        assert!(!fs.verify("PUT", "media/abc/f.enc", 2000, Some(513), sig, 1000));
+    }
+
+    #[test]
+    fn keys_cannot_escape_the_root() {
+        let fs = fs();
+        assert!(fs.path_for("media/abc/f.enc").is_some());
+        assert!(fs.path_for("../etc/passwd").is_none());
+        assert!(fs.path_for("media/../../x").is_none());
+        assert!(fs.path_for("/etc/passwd").is_none());
+        assert!(fs.path_for("media//x").is_none());
+        assert!(fs.path_for("media\\x").is_none());
+    }
+}
@@ -0,0 +1,292 @@
+//! What a presigned PUT (`POST /v1/r2/presign`, `operation: "put"`) may upload.
+//!
+//! The key prefix picks the category, and each category has its own content
+//! types, extensions and size cap:
+//!
+//!   - **`avatars/<user>`, `group-icons/<group>/<file>`** — public, unencrypted
+//!     images rendered straight into `<img>`. PNG, JPEG, GIF or WebP only (no
+//!     SVG: it can carry script). A file extension, when the key has one, must
+//!     be one of those too. Capped at `UPLOAD_MAX_IMAGE_BYTES` (default 10 MiB).
+//!   - **`media/<hash>/<file>.enc`** — attachment ciphertext. The real type is
+//!     inside the encrypted manifest, so the object itself is always
+//!     `application/octet-stream` with an `.enc` key. Capped at
+//!     `UPLOAD_MAX_ATTACHMENT_BYTES` (default 100 MiB). `media@<target>/…` is
+//!     the same category in a group's pinned store ([`crate::residency`]).
+//!
+//! Any other prefix can't be uploaded to. GET and DELETE presigns are
+//! unaffected.
+//!
+//! Clients shipped before this policy declare no type or size. Whether their
+//! PUTs still get a presign is the operator's call, not the client's: only
+//! while `UPLOAD_ALLOW_UNBOUND` (an end date) is in the future does a PUT
+//! with neither get an unbound URL, and still only inside an upload location
+//! ([`UploadPolicy::allows_unbound_put`]). Nothing a caller sends widens it.
+//! Unset, every PUT must declare both.
+//!
+//! The declared type and size aren't just checked here, they're bound into the
+//! URL ([`crate::storage::ObjectStorage::presign`]): SigV4 signs `content-type`
+//! and `content-length`, and the `fs` backend's HMAC covers the length and the
+//! blob route re-checks it and sniffs image bytes. A client that presigns a
+//! 1 KB PNG and PUTs something else gets the store's rejection, so a bound PUT
+//! never writes anything that breaks the policy. An unbound one can, which is
+//! why the window has an end date: once it passes, every object written from
+//! then on was checked.
+
+/// Default cap on an avatar or group icon, bytes.
+const DEFAULT_MAX_IMAGE_BYTES: u64 = 10 * 1024 * 1024;
+/// Default cap on one attachment's ciphertext, bytes.
+const DEFAULT_MAX_ATTACHMENT_BYTES: u64 = 100 * 1024 * 1024;
+
+const IMAGE_CONTENT_TYPES: &[&str] = &["image/png", "image/jpeg", "image/gif", "image/webp"];
+const IMAGE_EXTENSIONS: &[&str] = &["png", "jpg", "jpeg", "gif", "webp"];
+
+/// What kind of object a key holds, from its prefix.
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+pub enum UploadCategory {
+    Avatar,
+    GroupIcon,
+    Attachment,
+}
+
+impl UploadCategory {
+    /// `None` for a key outside every upload prefix.
+    pub fn for_key(key: &str) -> Option<Self> {
+        let (prefix, rest) = key.split_once('/')?;
+        if rest.is_empty() {
+            return None;
+        }
+        match prefix.split_once('@') {
+            None => match prefix {
+                "avatars" => Some(UploadCategory::Avatar),
+                "group-icons" => Some(UploadCategory::GroupIcon),
+                "media" => Some(UploadCategory::Attachment),
+                _ => None,
+            },
+            // Only attachments can be pinned to a storage target.
+            Some(("media", target)) if !target.is_empty() => Some(UploadCategory::Attachment),
+            Some(_) => None,
+        }
+    }
+
+    /// Public images, as opposed to ciphertext.
+    pub fn is_image(self) -> bool {
+        matches!(self, UploadCategory::Avatar | UploadCategory::GroupIcon)
+    }
+}
+
+/// Upload size caps and the unbound-PUT window, read from DS env by
+/// [`UploadPolicy::from_env`].
+#[derive(Clone, Debug)]
+pub struct UploadPolicy {
+    pub max_image_bytes: u64,
+    pub max_attachment_bytes: u64,
+    /// Unix seconds until which a PUT may leave out its type and size;
+    /// `None` (the default) never allows it.
+    pub allow_unbound_until: Option<u64>,
+}
+
+impl Default for UploadPolicy {
+    fn default() -> Self {
+        Self {
+            max_image_bytes: DEFAULT_MAX_IMAGE_BYTES,
+            max_attachment_bytes: DEFAULT_MAX_ATTACHMENT_BYTES,
+            allow_unbound_until: None,
+        }
+    }
+}
+
+/// Unix seconds for an `UPLOAD_ALLOW_UNBOUND` value: an RFC 3339 instant, or
+/// a `YYYY-MM-DD` date meaning the start of that UTC day.
+pub fn parse_end_date(v: &str) -> Option<u64> {
+    let v = v.trim();
+    let at = match chrono::DateTime::parse_from_rfc3339(v) {
+        Ok(t) => t.timestamp(),
+        Err(_) => chrono::NaiveDate::parse_from_str(v, "%Y-%m-%d")
+            .ok()?
+            .and_hms_opt(0, 0, 0)?
+            .and_utc()
+            .timestamp(),
+    };
+    u64::try_from(at).ok()
+}
+
+impl UploadPolicy {
+    /// `UPLOAD_MAX_IMAGE_BYTES`, `UPLOAD_MAX_ATTACHMENT_BYTES`,
+    /// `UPLOAD_ALLOW_UNBOUND`; unset or unparsable falls back to the default.
+    pub fn from_env() -> Self {
+        let d = Self::default();
+        let num = |k: &str, default: u64| {
+            std::env::var(k)
+                .ok()
+                .and_then(|s| s.parse().ok())
+                .unwrap_or(default)
+        };
+        Self {
+            max_image_bytes: num("UPLOAD_MAX_IMAGE_BYTES", d.max_image_bytes),
+            max_attachment_bytes: num("UPLOAD_MAX_ATTACHMENT_BYTES", d.max_attachment_bytes),
+            allow_unbound_until: std::env::var("UPLOAD_ALLOW_UNBOUND")
+                .ok()
+                .and_then(|v| parse_end_date(&v))
+                .or(d.allow_unbound_until),
+        }
+    }
+
+    /// Whether a PUT presign may leave out `content_type` and `size` at
+    /// `now` (unix seconds): only before the operator's end date.
+    pub fn allows_unbound_put(&self, now: u64) -> bool {
+        self.allow_unbound_until.is_some_and(|until| now < until)
+    }
+
+    pub fn max_bytes(&self, category: UploadCategory) -> u64 {
+        match category {
+            UploadCategory::Avatar | UploadCategory::GroupIcon => self.max_image_bytes,
+            UploadCategory::Attachment => self.max_attachment_bytes,
+        }
+    }
+
+    /// Validate a PUT of `size` bytes of `content_type` to `key`. The error is
+    /// the message for the `400`.
+    pub fn check_put(
+        &self,
+        key: &str,
+        content_type: &str,
+        size: u64,
+    ) -> Result<UploadCategory, String> {
+        let category = UploadCategory::for_key(key)
+            .ok_or_else(|| "key is not in an upload location".to_string())?;
+        let file = key.rsplit('/').next().unwrap_or(key);
+        let ext = file
+            .rsplit_once('.')
+            .map(|(_, e)| e.to_ascii_lowercase());
+
+        if category.is_image() {
+            if !IMAGE_CONTENT_TYPES.contains(&content_type) {
+                return Err(format!("content type {content_type} is not allowed for images"));
+            }
+            if let Some(ext) = ext {
+                if !IMAGE_EXTENSIONS.contains(&ext.as_str()) {
+                    return Err(format!("extension .{ext} is not allowed for images"));
+                }
+            }
+        } else {
+            if content_type != "application/octet-stream" {
+                return Err("attachments must be application/octet-stream".to_string());
+            }
+            if ext.as_deref() != Some("enc") {
+                return Err("attachment keys must end in .enc".to_string());
+            }
+        }
+
+        if size == 0 {
+            return Err("size must be positive".to_string());
+        }
+        let max = self.max_bytes(category);
+        if size > max {
+            return Err(format!("size {size} exceeds the {max} byte limit"));
+        }
+        Ok(category)
+    }
+}
+
+/// The image type `bytes` starts with, if it's one of [`IMAGE_CONTENT_TYPES`].
+pub fn sniff_image(bytes: &[u8]) -> Option<&'static str> {
+    if bytes.starts_with(&[0x89, b'P', b'N', b'G', 0x0d, 0x0a, 0x1a, 0x0a]) {
+        return Some("image/png");
+    }
+    if bytes.starts_with(&[0xff, 0xd8, 0xff]) {
+        return Some("image/jpeg");
+    }
+    if bytes.starts_with(b"GIF87a") || bytes.starts_with(b"GIF89a") {
+        return Some("image/gif");
+    }
+    if bytes.len() >= 12 && bytes.starts_with(b"RIFF") && &bytes[8..12] == b"WEBP" {
+        return Some("image/webp");
+    }
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn categories_follow_the_key_prefix() {
+        assert_eq!(UploadCategory::for_key("avatars/u1"), Some(UploadCategory::Avatar));
+        assert_eq!(
+            UploadCategory::for_key("group-icons/g1/1-icon.png"),
+            Some(UploadCategory::GroupIcon)
+        );
+        assert_eq!(
+            UploadCategory::for_key("media/abc/f.enc"),
+            Some(UploadCategory::Attachment)
+        );
+        assert_eq!(
+            UploadCategory::for_key("media@eu/abc/f.enc"),
+            Some(UploadCategory::Attachment)
+        );
+        assert_eq!(UploadCategory::for_key("media@/abc/f.enc"), None);
+        assert_eq!(UploadCategory::for_key("avatars@eu/u1"), None);
+        assert_eq!(UploadCategory::for_key("avatars/"), None);
+        assert_eq!(UploadCategory::for_key("backups/u1"), None);
+        assert_eq!(UploadCategory::for_key("avatars"), None);
+    }
+
+    #[test]
+    fn unbound_puts_end_at_the_configured_date() {
+        assert!(!UploadPolicy::default().allows_unbound_put(0));
+
+        let until = parse_end_date("2026-07-01").unwrap();
+        assert_eq!(until, 1_782_864_000);
+        let policy = UploadPolicy {
+            allow_unbound_until: Some(until),
+            ..UploadPolicy::default()
+        };
+        assert!(policy.allows_unbound_put(until - 1));
+        assert!(!policy.allows_unbound_put(until));
+
+        assert_eq!(parse_end_date("2026-07-01T00:00:00Z"), Some(until));
+        assert_eq!(parse_end_date("soon"), None);
+    }
+
+    #[test]
+    fn check_put_enforces_type_extension_and_size() {
+        let policy = UploadPolicy::default();
+        assert_eq!(
+            policy.check_put("avatars/u1", "image/png", 1000),
+            Ok(UploadCategory::Avatar)
+        );
+        assert!(policy.check_put("group-icons/g1/1-icon.JPG", "image/jpeg", 1000).is_ok());
+        assert!(policy.check_put("media/abc/f.enc", "application/octet-stream", 1000).is_ok());
+
+        assert!(policy.check_put("avatars/u1", "image/svg+xml", 1000).is_err());
+        assert!(policy.check_put("avatars/u1", "text/html", 1000).is_err());
+        assert!(policy.check_put("group-icons/g1/1-icon.html", "image/png", 1000).is_err());
+        assert!(policy.check_put("media/abc/f.png", "application/octet-stream", 1000).is_err());
+        assert!(policy.check_put("media/abc/f.enc", "image/png", 1000).is_err());
+        assert!(policy.check_put("other/x", "image/png", 1000).is_err());
+        assert!(policy.check_put("avatars/u1", "image/png", 0).is_err());
+        assert!(policy
+            .check_put("avatars/u1", "image/png", DEFAULT_MAX_IMAGE_BYTES + 1)
+            .is_err());
+        assert!(policy
+            .check_put(
+                "media/abc/f.enc",
+                "application/octet-stream",
+                DEFAULT_MAX_IMAGE_BYTES + 1
+            )
+            .is_ok());
+    }
+
+    #[test]
+    fn sniff_recognises_the_allowed_images_only() {
+        assert_eq!(
+            sniff_image(&[0x89, b'P', b'N', b'G', 0x0d, 0x0a, 0x1a, 0x0a, 0]),
+            Some("image/png")
+        );
+        assert_eq!(sniff_image(&[0xff, 0xd8, 0xff, 0xe0]), Some("image/jpeg"));
+        assert_eq!(sniff_image(b"GIF89a...."), Some("image/gif"));
+        assert_eq!(sniff_image(b"RIFF\0\0\0\0WEBPVP8 "), Some("image/webp"));
+        assert_eq!(sniff_image(b"<svg xmlns="), None);
+        assert_eq!(sniff_image(b""), None);
+    }
+}
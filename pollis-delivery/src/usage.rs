@@ -0,0 +1,306 @@
+//! Per-user usage accounting for operators who bill for an instance.
+//!
+//! Off unless `USAGE_ACCOUNTING` is set. When on, the DS adds to one
+//! `usage_daily` row per user per UTC day as it serves requests:
+//!
+//!   - **envelopes** / **envelope_bytes** — `POST /v1/messages/send`, counted
+//!     against the authenticated sender (sealed sends too: the DS knows who
+//!     signed the request even when the stored `sender_id` is blinded).
+//!   - **attachments_presigned** / **attachment_bytes** — `POST /v1/r2/presign`
+//!     PUTs to `media/`, at the size the URL is bound to.
+//!
+//! Rows hold counters only — no conversation, recipient or key — so a billing
+//! export can't be joined back to who talked to whom. Recording is best-effort:
+//! a failed upsert is logged and never fails the request it was counting.
+//!
+//! Users read their own rows through `POST /v1/usage/me`. Billing pipelines
+//! pull every user's rows for a date range from `GET /v1/usage/export` as
+//! NDJSON, authorized by `USAGE_EXPORT_TOKEN` (the route 503s without it).
+
+use axum::{
+    body::Bytes,
+    extract::{Query, State},
+    http::{header, HeaderMap, Method, StatusCode, Uri},
+    response::{IntoResponse, Response},
+    Json,
+};
+use libsql::Connection;
+use serde::{Deserialize, Serialize};
+
+use crate::error::AppError;
+use crate::writes::{bad_request, gate, ok_json, outcome_response, resolve_actor};
+use crate::AppState;
+
+/// Default and maximum number of days `POST /v1/usage/me` returns.
+pub const DEFAULT_USAGE_DAYS: u32 = 30;
+pub const MAX_USAGE_DAYS: u32 = 366;
+/// Longest date range one export may cover.
+pub const MAX_EXPORT_DAYS: i64 = 93;
+
+/// Usage accounting settings, read from DS env by [`UsageConfig::from_env`].
+#[derive(Clone, Default)]
+pub struct UsageConfig {
+    /// Record usage at all.
+    pub enabled: bool,
+    /// Bearer token for `GET /v1/usage/export`. `None` → the export 503s.
+    /// NEVER logged.
+    pub export_token: Option<String>,
+}
+
+impl UsageConfig {
+    /// Build from DS environment. Env: `USAGE_ACCOUNTING` (`true`/`1` turns
+    /// recording on), `USAGE_EXPORT_TOKEN`.
+    pub fn from_env() -> Self {
+        let var = |k: &str| std::env::var(k).ok().filter(|s| !s.is_empty());
+        Self {
+            enabled: matches!(
+                var("USAGE_ACCOUNTING").as_deref(),
+                Some("true") | Some("TRUE") | Some("True") | Some("1")
+            ),
+            export_token: var("USAGE_EXPORT_TOKEN"),
+        }
+    }
+}
+
+/// What one request adds to the actor's row for today.
+#[derive(Clone, Copy, Debug, Default)]
+pub struct UsageDelta {
+    pub envelopes: i64,
+    pub envelope_bytes: i64,
+    pub attachments_presigned: i64,
+    pub attachment_bytes: i64,
+}
+
+/// One user's counters for one UTC day (`YYYY-MM-DD`).
+#[derive(Debug, Serialize, PartialEq, Eq)]
+pub struct UsageDay {
+    pub day: String,
+    pub envelopes: i64,
+    pub envelope_bytes: i64,
+    pub attachments_presigned: i64,
+    pub attachment_bytes: i64,
+}
+
+/// Add `delta` to `user_id`'s row for today, creating it on first use.
+pub async fn record_usage(
+    conn: &Connection,
+    user_id: &str,
+    delta: UsageDelta,
+) -> anyhow::Result<()> {
+    conn.execute(
+        "INSERT INTO usage_daily \
+             (user_id, day, envelopes, envelope_bytes, attachments_presigned, attachment_bytes) \
+         VALUES (?1, date('now'), ?2, ?3, ?4, ?5) \
+         ON CONFLICT(user_id, day) DO UPDATE SET \
+            envelopes = envelopes + excluded.envelopes, \
+            envelope_bytes = envelope_bytes + excluded.envelope_bytes, \
+            attachments_presigned = attachments_presigned + excluded.attachments_presigned, \
+            attachment_bytes = attachment_bytes + excluded.attachment_bytes",
+        libsql::params![
+            user_id.to_string(),
+            delta.envelopes,
+            delta.envelope_bytes,
+            delta.attachments_presigned,
+            delta.attachment_bytes,
+        ],
+    )
+    .await?;
+    Ok(())
+}
+
+/// [`record_usage`] when accounting is on; a failure is logged, not returned.
+pub async fn record_if_enabled(
+    state: &AppState,
+    conn: &Connection,
+    user_id: &str,
+    delta: UsageDelta,
+) {
+    if !state.usage.enabled || user_id.is_empty() {
+        return;
+    }
+    if let Err(e) = record_usage(conn, user_id, delta).await {
+        tracing::warn!("record usage: {e}");
+    }
+}
+
+/// `user_id`'s rows for the last `days` days (today included), newest first.
+pub async fn usage_for_user(
+    conn: &Connection,
+    user_id: &str,
+    days: u32,
+) -> anyhow::Result<Vec<UsageDay>> {
+    let mut rows = conn
+        .query(
+            "SELECT day, envelopes, envelope_bytes, attachments_presigned, attachment_bytes \
+             FROM usage_daily \
+             WHERE user_id = ?1 AND day > date('now', ?2) \
+             ORDER BY day DESC",
+            libsql::params![user_id.to_string(), format!("-{days} days")],
+        )
+        .await?;
+    let mut out = Vec::new();
+    while let Some(row) = rows.next().await? {
+        out.push(UsageDay {
+            day: row.get(0)?,
+            envelopes: row.get(1)?,
+            envelope_bytes: row.get(2)?,
+            attachments_presigned: row.get(3)?,
+            attachment_bytes: row.get(4)?,
+        });
+    }
+    Ok(out)
+}
+
+// ── POST /v1/usage/me ────────────────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct MyUsageBody {
+    /// How many days back, today included. Default [`DEFAULT_USAGE_DAYS`].
+    #[serde(default)]
+    pub days: Option<u32>,
+    /// Self-scope: when signed it must equal the authenticated user.
+    #[serde(default)]
+    pub user_id: Option<String>,
+}
+
+/// POST /v1/usage/me — the actor's own daily usage. Self-scoped. `enabled`
+/// tells the client whether an empty list means "nothing used" or "not
+/// counted here".
+pub async fn my_usage(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: MyUsageBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let days = parsed.days.unwrap_or(DEFAULT_USAGE_DAYS);
+    if !(1..=MAX_USAGE_DAYS).contains(&days) {
+        return Ok(bad_request("days out of range"));
+    }
+    let actor = match resolve_actor(authed.as_deref(), parsed.user_id.as_deref()) {
+        Ok(a) => a,
+        Err(o) => return outcome_response(o),
+    };
+    let conn = state.db.conn()?;
+    let rows = usage_for_user(&conn, &actor, days).await?;
+    Ok(ok_json(serde_json::json!({
+        "enabled": state.usage.enabled,
+        "days": rows,
+    })))
+}
+
+// ── GET /v1/usage/export ─────────────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct ExportQuery {
+    /// First day, `YYYY-MM-DD`.
+    pub from: String,
+    /// Last day, inclusive. Defaults to `from`.
+    #[serde(default)]
+    pub to: Option<String>,
+}
+
+/// GET /v1/usage/export?from=YYYY-MM-DD[&to=YYYY-MM-DD] — every user's rows in
+/// the range as NDJSON, one `{user_id, day, …counters}` object per line,
+/// ordered by day then user. Operator-only: `Authorization: Bearer
+/// <USAGE_EXPORT_TOKEN>`.
+pub async fn export_usage(
+    State(state): State<AppState>,
+    headers: HeaderMap,
+    Query(query): Query<ExportQuery>,
+) -> Result<Response, AppError> {
+    let Some(expected) = state.usage.export_token.as_deref() else {
+        return Ok((
+            StatusCode::SERVICE_UNAVAILABLE,
+            Json(serde_json::json!({ "error": "usage export not configured" })),
+        )
+            .into_response());
+    };
+    let presented = headers
+        .get(header::AUTHORIZATION)
+        .and_then(|v| v.to_str().ok())
+        .and_then(|v| v.strip_prefix("Bearer "))
+        .unwrap_or("");
+    if !constant_time_eq(presented.as_bytes(), expected.as_bytes()) {
+        return Ok((
+            StatusCode::UNAUTHORIZED,
+            Json(serde_json::json!({ "error": "unauthorized" })),
+        )
+            .into_response());
+    }
+
+    let to = query.to.clone().unwrap_or_else(|| query.from.clone());
+    let (Some(from_day), Some(to_day)) = (parse_day(&query.from), parse_day(&to)) else {
+        return Ok(bad_request("from/to must be YYYY-MM-DD"));
+    };
+    let span = (to_day - from_day).num_days();
+    if !(0..MAX_EXPORT_DAYS).contains(&span) {
+        return Ok(bad_request("date range out of bounds"));
+    }
+
+    let conn = state.db.conn()?;
+    let mut rows = conn
+        .query(
+            "SELECT user_id, day, envelopes, envelope_bytes, attachments_presigned, attachment_bytes \
+             FROM usage_daily WHERE day >= ?1 AND day <= ?2 \
+             ORDER BY day, user_id",
+            libsql::params![query.from.clone(), to],
+        )
+        .await?;
+    let mut out = String::new();
+    while let Some(row) = rows.next().await? {
+        let line = serde_json::json!({
+            "user_id": row.get::<String>(0)?,
+            "day": row.get::<String>(1)?,
+            "envelopes": row.get::<i64>(2)?,
+            "envelope_bytes": row.get::<i64>(3)?,
+            "attachments_presigned": row.get::<i64>(4)?,
+            "attachment_bytes": row.get::<i64>(5)?,
+        });
+        out.push_str(&line.to_string());
+        out.push('\n');
+    }
+    Ok((
+        StatusCode::OK,
+        [(header::CONTENT_TYPE, "application/x-ndjson")],
+        out,
+    )
+        .into_response())
+}
+
+fn parse_day(s: &str) -> Option<chrono::NaiveDate> {
+    chrono::NaiveDate::parse_from_str(s, "%Y-%m-%d").ok()
+}
+
+/// Constant-time byte compare, so the export token can't be guessed by timing.
+fn constant_time_eq(a: &[u8], b: &[u8]) -> bool {
+    if a.len() != b.len() {
+        return false;
+    }
+    let mut diff: u8 = 0;
+    for (x, y) in a.iter().zip(b.iter()) {
+        diff |= x ^ y;
+    }
+    diff == 0
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parse_day_accepts_iso_dates_only() {
+        assert!(parse_day("2026-10-17").is_some());
+        assert!(parse_day("2026-13-01").is_none());
+        assert!(parse_day("17/10/2026").is_none());
+        assert!(parse_day("2026-10-17' OR 1=1").is_none());
+    }
+}
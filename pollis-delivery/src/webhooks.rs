@@ -0,0 +1,758 @@
+//! Outbound group webhooks — signed, content-free event notifications.
+//!
+//! A group admin registers a URL (`POST /v1/webhooks/create`) subscribed to some
+//! of [`EVENTS`]; the DS then POSTs a small JSON notification there whenever one
+//! happens, so a CI bot or dashboard can react to group activity without ever
+//! being a member. Nothing in a notification comes from message content — the
+//! DS never has any:
+//!
+//!   - `message_count` — `{channel_id, count, window_secs}`: sends to a channel,
+//!     batched per [`WebhookConfig::message_batch_secs`] window (one event per
+//!     active channel per window, never one per message, so the target doesn't
+//!     get per-message timing);
+//!   - `member_joined` — `{member_count}` after an invite accept or join-request
+//!     approve (no user id);
+//!   - `channel_created` — `{channel_id}`.
+//!
+//! Every body is `{id, event, group_id, created_at, data}`, sent with
+//! `X-Pollis-Event`, `X-Pollis-Delivery` (the `id`) and
+//! `X-Pollis-Signature: t=<unix>,v1=<hex HMAC-SHA256(secret, "<t>.<body>")>`.
+//!
+//! ## Secrets are derived, never stored
+//!
+//! Clients read the main DB with a read-only token, so a secret column in
+//! `group_webhook` would be readable by every user. Instead each webhook's secret
+//! is `hex(HMAC-SHA256(WEBHOOK_SIGNING_KEY, "pollis-webhook-v1\n<id>"))`: the DS
+//! recomputes it per delivery and returns it exactly once, in the create
+//! response. Rotating a secret is delete + re-create. Without
+//! `WEBHOOK_SIGNING_KEY` the create endpoint 503s and nothing is dispatched.
+//!
+//! ## Delivery
+//!
+//! Handlers hand events to [`WebhookDispatcher`] (an in-process queue on the
+//! instance that took the write, so no event is queued twice) and return
+//! immediately. The
+//! worker POSTs with a 10 s timeout and retries a failed delivery after 10 s,
+//! 1 min, 5 min and 30 min; the final result lands in the row's
+//! `last_status` / `last_delivery_at` / `failure_count` for the admin UI.
+//! Queued events and pending retries are lost on restart.
+//!
+//! Targets must be `https://` and resolve to a public address: the DS resolves
+//! the host itself, refuses loopback / private / link-local / CGNAT ranges, and
+//! pins the connection to the checked address (no redirects followed), so an
+//! admin can't aim the DS at its own network. `WEBHOOK_ALLOW_PRIVATE_TARGETS`
+//! lifts that for self-hosted setups and tests.
+
+use std::collections::HashMap;
+use std::net::{IpAddr, SocketAddr};
+use std::sync::Arc;
+use std::time::Duration;
+
+use axum::{
+    body::Bytes,
+    extract::State,
+    http::{HeaderMap, Method, StatusCode, Uri},
+    response::{IntoResponse, Response},
+    Json,
+};
+use hmac::{Hmac, Mac};
+use libsql::Connection;
+use serde::Deserialize;
+use sha2::Sha256;
+use tokio::sync::mpsc;
+use ulid::Ulid;
+
+use crate::db::Db;
+use crate::error::{AppError, AuthRejection};
+use crate::limits;
+use crate::ratelimit::now_unix;
+use crate::writes::{bad_request, gate, ok_json, outcome_response, resolve_actor, WriteOutcome};
+use crate::AppState;
+
+/// Event names a webhook can subscribe to.
+pub const EVENTS: &[&str] = &["message_count", "member_joined", "channel_created"];
+
+const MAX_URL_LEN: usize = 2048;
+
+/// Waits before each retry of a failed delivery.
+const RETRY_DELAYS_SECS: &[u64] = &[10, 60, 300, 1800];
+
+const DELIVERY_TIMEOUT: Duration = Duration::from_secs(10);
+
+/// Webhook settings, read from DS env by [`WebhookConfig::from_env`].
+#[derive(Clone)]
+pub struct WebhookConfig {
+    /// Key every webhook secret is derived from. `None` → webhooks disabled.
+    /// NEVER logged.
+    pub signing_key: Option<Arc<Vec<u8>>>,
+    /// Allow `http://` and non-public targets (self-hosting, tests).
+    pub allow_private_targets: bool,
+    /// Max webhooks per group (`0` = unlimited).
+    pub max_per_group: u32,
+    /// `message_count` batching window, seconds.
+    pub message_batch_secs: u64,
+}
+
+impl Default for WebhookConfig {
+    fn default() -> Self {
+        Self {
+            signing_key: None,
+            allow_private_targets: false,
+            max_per_group: 5,
+            message_batch_secs: 60,
+        }
+    }
+}
+
+impl WebhookConfig {
+    /// Build from DS environment. Env: `WEBHOOK_SIGNING_KEY` (enables the
+    /// feature), `WEBHOOK_ALLOW_PRIVATE_TARGETS`, `WEBHOOK_MAX_PER_GROUP`,
+    /// `WEBHOOK_MESSAGE_BATCH_SECS`.
+    pub fn from_env() -> Self {
+        let var = |k: &str| std::env::var(k).ok().filter(|s| !s.is_empty());
+        let mut cfg = Self {
+            signing_key: var("WEBHOOK_SIGNING_KEY").map(|k| Arc::new(k.into_bytes())),
+            allow_private_targets: matches!(
+                var("WEBHOOK_ALLOW_PRIVATE_TARGETS").as_deref(),
+                Some("true") | Some("TRUE") | Some("True") | Some("1")
+            ),
+            ..Self::default()
+        };
+        if let Some(v) = var("WEBHOOK_MAX_PER_GROUP").and_then(|s| s.parse().ok()) {
+            cfg.max_per_group = v;
+        }
+        if let Some(v) = var("WEBHOOK_MESSAGE_BATCH_SECS").and_then(|s| s.parse().ok()) {
+            cfg.message_batch_secs = v;
+        }
+        cfg
+    }
+
+    /// The signing secret for webhook `id`, or `None` when webhooks are off.
+    pub fn secret_for(&self, id: &str) -> Option<String> {
+        let key = self.signing_key.as_ref()?;
+        let mut mac = Hmac::<Sha256>::new_from_slice(key).expect("hmac accepts any key length");
+        mac.update(format!("pollis-webhook-v1\n{id}").as_bytes());
+        Some(hex(&mac.finalize().into_bytes()))
+    }
+}
+
+/// `t=<unix>,v1=<hex>` — what a receiver recomputes from its secret.
+pub fn signature_header(secret: &str, timestamp: u64, body: &[u8]) -> String {
+    let mut mac =
+        Hmac::<Sha256>::new_from_slice(secret.as_bytes()).expect("hmac accepts any key length");
+    mac.update(format!("{timestamp}.").as_bytes());
+    mac.update(body);
+    format!("t={timestamp},v1={}", hex(&mac.finalize().into_bytes()))
+}
+
+fn hex(bytes: &[u8]) -> String {
+    bytes.iter().map(|b| format!("{b:02x}")).collect()
+}
+
+// ── Target validation ────────────────────────────────────────────────────────
+
+/// Whether `ip` is globally routable — not loopback, private, link-local,
+/// CGNAT, unique-local, unspecified, broadcast or documentation space. An IPv6
+/// address that carries an IPv4 one (mapped, NAT64 `64:ff9b::/96`, 6to4
+/// `2002::/16`) is judged by the IPv4 address it reaches.
+fn is_public(ip: IpAddr) -> bool {
+    match ip {
+        IpAddr::V4(v4) => {
+            let o = v4.octets();
+            !(v4.is_private()
+                || v4.is_loopback()
+                || v4.is_link_local()
+                || v4.is_unspecified()
+                || v4.is_broadcast()
+                || v4.is_documentation()
+                || o[0] == 0
+                || o[0] >= 240
+                || (o[0] == 100 && (o[1] & 0xc0) == 64))
+        }
+        IpAddr::V6(v6) => {
+            if let Some(v4) = v6.to_ipv4_mapped() {
+                return is_public(IpAddr::V4(v4));
+            }
+            let seg = v6.segments();
+            let o = v6.octets();
+            // NAT64 well-known prefix: the last 32 bits are the target.
+            if seg[..6] == [0x64, 0xff9b, 0, 0, 0, 0] {
+                return is_public(IpAddr::V4([o[12], o[13], o[14], o[15]].into()));
+            }
+            // 6to4: the relay delivers to the IPv4 address after the prefix.
+            if seg[0] == 0x2002 {
+                return is_public(IpAddr::V4([o[2], o[3], o[4], o[5]].into()));
+            }
+            !(v6.is_loopback()
+                || v6.is_unspecified()
+                || (seg[0] & 0xfe00) == 0xfc00
+                || (seg[0] & 0xffc0) == 0xfe80
+                // Documentation.
+                || (seg[0] == 0x2001 && seg[1] == 0x0db8)
+                // Local-use NAT64 (64:ff9b:1::/48), translated by a site's own gateway.
+                || (seg[0] == 0x64 && seg[1] == 0xff9b && seg[2] == 1))
+        }
+    }
+}
+
+/// Registration-time checks on a target URL. DNS is checked again at delivery
+/// (a name can change what it resolves to).
+fn validate_url(url: &str, allow_private: bool) -> Result<(), &'static str> {
+    if url.len() > MAX_URL_LEN {
+        return Err("url too long");
+    }
+    let parsed = reqwest::Url::parse(url).map_err(|_| "invalid url")?;
+    match parsed.scheme() {
+        "https" => {}
+        "http" if allow_private => {}
+        _ => return Err("url must be https"),
+    }
+    // The row is readable by every client; credentials don't belong in it.
+    if !parsed.username().is_empty() || parsed.password().is_some() {
+        return Err("url must not contain credentials");
+    }
+    let host = parsed.host_str().ok_or("url has no host")?;
+    if let Ok(ip) = host
+        .trim_start_matches('[')
+        .trim_end_matches(']')
+        .parse::<IpAddr>()
+    {
+        if !allow_private && !is_public(ip) {
+            return Err("url must point to a public address");
+        }
+    }
+    Ok(())
+}
+
+// ── Dispatcher ───────────────────────────────────────────────────────────────
+
+/// Something a webhook may be told about. Handlers emit these after a
+/// successful write; the worker resolves subscribers.
+pub enum GroupEvent {
+    /// A message envelope was stored for `conversation_id` (a channel or a DM;
+    /// DMs have no group and are dropped at flush).
+    MessagePosted {
+        conversation_id: String,
+    },
+    MemberJoined {
+        group_id: String,
+    },
+    ChannelCreated {
+        group_id: String,
+        channel_id: String,
+    },
+}
+
+/// Handle to the dispatch worker. The default (tests, webhooks disabled) drops
+/// every event. Shallow-`Clone`, like [`crate::flood::FloodDetector`].
+#[derive(Clone, Default)]
+pub struct WebhookDispatcher {
+    tx: Option<mpsc::UnboundedSender<GroupEvent>>,
+}
+
+impl WebhookDispatcher {
+    /// Start the worker on the current tokio runtime. Returns the inert default
+    /// when `config` has no signing key.
+    pub fn spawn(db: Arc<Db>, config: WebhookConfig) -> Self {
+        if config.signing_key.is_none() {
+            return Self::default();
+        }
+        let (tx, rx) = mpsc::unbounded_channel();
+        tokio::spawn(run(db, config, rx));
+        Self { tx: Some(tx) }
+    }
+
+    /// Queue `event`. Never blocks or fails the caller's write.
+    pub fn emit(&self, event: GroupEvent) {
+        if let Some(tx) = &self.tx {
+            let _ = tx.send(event);
+        }
+    }
+}
+
+async fn run(db: Arc<Db>, config: WebhookConfig, mut rx: mpsc::UnboundedReceiver<GroupEvent>) {
+    // conversation_id → sends since the last flush.
+    let mut counts: HashMap<String, u64> = HashMap::new();
+    let mut tick = tokio::time::interval(Duration::from_secs(config.message_batch_secs.max(1)));
+    tick.set_missed_tick_behavior(tokio::time::MissedTickBehavior::Delay);
+    loop {
+        tokio::select! {
+            event = rx.recv() => match event {
+                None => break,
+                Some(GroupEvent::MessagePosted { conversation_id }) => {
+                    *counts.entry(conversation_id).or_default() += 1;
+                }
+                Some(GroupEvent::MemberJoined { group_id }) => {
+                    match member_count(&db, &group_id).await {
+                        Ok(n) => {
+                            let data = serde_json::json!({ "member_count": n });
+                            dispatch(&db, &config, &group_id, "member_joined", data).await;
+                        }
+                        Err(e) => tracing::warn!("webhook member count: {e}"),
+                    }
+                }
+                Some(GroupEvent::ChannelCreated { group_id, channel_id }) => {
+                    let data = serde_json::json!({ "channel_id": channel_id });
+                    dispatch(&db, &config, &group_id, "channel_created", data).await;
+                }
+            },
+            _ = tick.tick() => {
+                for (conversation_id, count) in counts.drain() {
+                    match channel_group(&db, &conversation_id).await {
+                        Ok(Some(group_id)) => {
+                            let data = serde_json::json!({
+                                "channel_id": conversation_id,
+                                "count": count,
+                                "window_secs": config.message_batch_secs,
+                            });
+                            dispatch(&db, &config, &group_id, "message_count", data).await;
+                        }
+                        Ok(None) => {}
+                        Err(e) => tracing::warn!("webhook channel lookup: {e}"),
+                    }
+                }
+            }
+        }
+    }
+}
+
+async fn member_count(db: &Db, group_id: &str) -> anyhow::Result<i64> {
+    let conn = db.conn()?;
+    let mut rows = conn
+        .query(
+            "SELECT COUNT(*) FROM group_member WHERE group_id = ?1",
+            libsql::params![group_id.to_string()],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) => row.get(0)?,
+        None => 0,
+    })
+}
+
+async fn channel_group(db: &Db, channel_id: &str) -> anyhow::Result<Option<String>> {
+    let conn = db.conn()?;
+    let mut rows = conn
+        .query(
+            "SELECT group_id FROM channels WHERE id = ?1",
+            libsql::params![channel_id.to_string()],
+        )
+        .await?;
+    Ok(match rows.next().await? {
+        Some(row) => Some(row.get::<String>(0)?),
+        None => None,
+    })
+}
+
+/// Fan `event` out to every webhook of `group_id` subscribed to it.
+async fn dispatch(
+    db: &Arc<Db>,
+    config: &WebhookConfig,
+    group_id: &str,
+    event: &'static str,
+    data: serde_json::Value,
+) {
+    let targets = match subscribers(db, group_id, event).await {
+        Ok(t) => t,
+        Err(e) => {
+            tracing::warn!("webhook subscribers for {group_id}: {e}");
+            return;
+        }
+    };
+    for (webhook_id, url) in targets {
+        let Some(secret) = config.secret_for(&webhook_id) else {
+            return;
+        };
+        let delivery_id = Ulid::new().to_string();
+        let body = serde_json::json!({
+            "id": delivery_id,
+            "event": event,
+            "group_id": group_id,
+            "created_at": now_unix(),
+            "data": data,
+        });
+        let body = serde_json::to_vec(&body).expect("json value serializes");
+        tokio::spawn(deliver(
+            Arc::clone(db),
+            config.allow_private_targets,
+            webhook_id,
+            url,
+            secret,
+            event,
+            delivery_id,
+            body,
+        ));
+    }
+}
+
+async fn subscribers(
+    db: &Db,
+    group_id: &str,
+    event: &str,
+) -> anyhow::Result<Vec<(String, String)>> {
+    let conn = db.conn()?;
+    let mut rows = conn
+        .query(
+            "SELECT id, url, events FROM group_webhook WHERE group_id = ?1",
+            libsql::params![group_id.to_string()],
+        )
+        .await?;
+    let mut out = Vec::new();
+    while let Some(row) = rows.next().await? {
+        let events: String = row.get(2)?;
+        if events.split(',').any(|e| e == event) {
+            out.push((row.get(0)?, row.get(1)?));
+        }
+    }
+    Ok(out)
+}
+
+/// POST one notification, retrying on failure, then record the outcome.
+#[allow(clippy::too_many_arguments)]
+async fn deliver(
+    db: Arc<Db>,
+    allow_private: bool,
+    webhook_id: String,
+    url: String,
+    secret: String,
+    event: &'static str,
+    delivery_id: String,
+    body: Vec<u8>,
+) {
+    let mut last_status: Option<u16> = None;
+    let mut delivered = false;
+    for attempt in 0..=RETRY_DELAYS_SECS.len() {
+        if attempt > 0 {
+            tokio::time::sleep(Duration::from_secs(RETRY_DELAYS_SECS[attempt - 1])).await;
+        }
+        match post_once(&url, &secret, event, &delivery_id, &body, allow_private).await {
+            Ok(status) => {
+                last_status = Some(status.as_u16());
+                if status.is_success() {
+                    delivered = true;
+                    break;
+                }
+            }
+            Err(e) => {
+                last_status = None;
+                tracing::debug!(webhook_id = %webhook_id, "webhook delivery attempt failed: {e}");
+            }
+        }
+    }
+    if !delivered {
+        tracing::warn!(webhook_id = %webhook_id, event, "webhook delivery gave up after retries");
+    }
+    if let Err(e) = record_delivery(&db, &webhook_id, last_status, delivered).await {
+        tracing::warn!("record webhook delivery: {e}");
+    }
+}
+
+async fn post_once(
+    url: &str,
+    secret: &str,
+    event: &str,
+    delivery_id: &str,
+    body: &[u8],
+    allow_private: bool,
+) -> anyhow::Result<reqwest::StatusCode> {
+    let parsed = reqwest::Url::parse(url)?;
+    let host = parsed
+        .host_str()
+        .ok_or_else(|| anyhow::anyhow!("url has no host"))?
+        .to_string();
+    let port = parsed
+        .port_or_known_default()
+        .ok_or_else(|| anyhow::anyhow!("url has no port"))?;
+    let mut builder = reqwest::Client::builder()
+        .redirect(reqwest::redirect::Policy::none())
+        .timeout(DELIVERY_TIMEOUT);
+    match host
+        .trim_start_matches('[')
+        .trim_end_matches(']')
+        .parse::<IpAddr>()
+    {
+        Ok(ip) => {
+            if !allow_private && !is_public(ip) {
+                anyhow::bail!("{host} is not a public address");
+            }
+        }
+        Err(_) => {
+            // Resolve once, check, and pin the connection to the checked address
+            // so a rebinding DNS answer can't swap in an internal one.
+            let addr: SocketAddr = tokio::net::lookup_host((host.as_str(), port))
+                .await?
+                .find(|a| allow_private || is_public(a.ip()))
+                .ok_or_else(|| anyhow::anyhow!("{host} resolves to no public address"))?;
+            builder = builder.resolve(&host, addr);
+        }
+    }
+    let resp = builder
+        .build()?
+        .post(parsed)
+        .header("content-type", "application/json")
+        .header("x-pollis-event", event)
+        .header("x-pollis-delivery", delivery_id)
+        .header(
+            "x-pollis-signature",
+            signature_header(secret, now_unix(), body),
+        )
+        .body(body.to_vec())
+        .send()
+        .await?;
+    Ok(resp.status())
+}
+
+async fn record_delivery(
+    db: &Db,
+    webhook_id: &str,
+    status: Option<u16>,
+    delivered: bool,
+) -> anyhow::Result<()> {
+    let conn = db.conn()?;
+    conn.execute(
+        "UPDATE group_webhook SET last_delivery_at = datetime('now'), last_status = ?2, \
+             failure_count = CASE WHEN ?3 THEN 0 ELSE failure_count + 1 END \
+         WHERE id = ?1",
+        libsql::params![
+            webhook_id.to_string(),
+            status.map(i64::from),
+            delivered as i64
+        ],
+    )
+    .await?;
+    Ok(())
+}
+
+// ── Admin authz ──────────────────────────────────────────────────────────────
+
+async fn is_admin(conn: &Connection, group_id: &str, user_id: &str) -> anyhow::Result<bool> {
+    let mut rows = conn
+        .query(
+            "SELECT 1 FROM group_member WHERE group_id = ?1 AND user_id = ?2 AND role = 'admin'",
+            libsql::params![group_id.to_string(), user_id.to_string()],
+        )
+        .await?;
+    Ok(rows.next().await?.is_some())
+}
+
+fn not_configured() -> Response {
+    (
+        StatusCode::SERVICE_UNAVAILABLE,
+        Json(serde_json::json!({ "error": "webhooks not configured" })),
+    )
+        .into_response()
+}
+
+// ── POST /v1/webhooks/create ─────────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct CreateWebhookBody {
+    pub group_id: String,
+    pub url: String,
+    /// Subset of [`EVENTS`]; at least one.
+    pub events: Vec<String>,
+    #[serde(default)]
+    pub requester_id: Option<String>,
+}
+
+/// Register a webhook. `200 {id, secret}` — the only time the secret is
+/// returned.
+pub async fn create_webhook(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    if state.webhook_config.signing_key.is_none() {
+        return Ok(not_configured());
+    }
+    let parsed: CreateWebhookBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    if parsed.events.is_empty() || parsed.events.iter().any(|e| !EVENTS.contains(&e.as_str())) {
+        return Ok(bad_request("unknown or empty events"));
+    }
+    if let Err(msg) = validate_url(&parsed.url, state.webhook_config.allow_private_targets) {
+        return Ok(bad_request(msg));
+    }
+    let conn = state.db.conn()?;
+    let max = state.webhook_config.max_per_group;
+    if max > 0 {
+        let mut rows = conn
+            .query(
+                "SELECT COUNT(*) FROM group_webhook WHERE group_id = ?1",
+                libsql::params![parsed.group_id.clone()],
+            )
+            .await?;
+        let n: i64 = match rows.next().await? {
+            Some(row) => row.get(0)?,
+            None => 0,
+        };
+        if n >= i64::from(max) {
+            return Ok(limits::limit_exceeded("max_webhooks_per_group", max));
+        }
+    }
+    let id = Ulid::new().to_string();
+    match apply_create_webhook(&conn, authed.as_deref(), &id, &parsed).await? {
+        WriteOutcome::Ok => {
+            let secret = state.webhook_config.secret_for(&id).unwrap_or_default();
+            Ok(ok_json(serde_json::json!({ "id": id, "secret": secret })))
+        }
+        WriteOutcome::Forbidden => Ok(AuthRejection::Forbidden.into_response()),
+    }
+}
+
+/// INSERT a `group_webhook` row. Authz: admin of the group.
+pub async fn apply_create_webhook(
+    conn: &Connection,
+    authed: Option<&str>,
+    id: &str,
+    body: &CreateWebhookBody,
+) -> anyhow::Result<WriteOutcome> {
+    let requester = match resolve_actor(authed, body.requester_id.as_deref()) {
+        Ok(r) => r,
+        Err(o) => return Ok(o),
+    };
+    if authed.is_some() && !is_admin(conn, &body.group_id, &requester).await? {
+        return Ok(WriteOutcome::Forbidden);
+    }
+    // Canonical order, duplicates dropped.
+    let events: Vec<&str> = EVENTS
+        .iter()
+        .copied()
+        .filter(|e| body.events.iter().any(|b| b == e))
+        .collect();
+    conn.execute(
+        "INSERT INTO group_webhook (id, group_id, url, events, created_by) VALUES (?1, ?2, ?3, ?4, ?5)",
+        libsql::params![
+            id.to_string(),
+            body.group_id.clone(),
+            body.url.clone(),
+            events.join(","),
+            requester,
+        ],
+    )
+    .await?;
+    Ok(WriteOutcome::Ok)
+}
+
+// ── POST /v1/webhooks/delete ─────────────────────────────────────────────────
+
+#[derive(Deserialize)]
+pub struct DeleteWebhookBody {
+    pub webhook_id: String,
+    #[serde(default)]
+    pub requester_id: Option<String>,
+}
+
+pub async fn delete_webhook(
+    State(state): State<AppState>,
+    method: Method,
+    uri: Uri,
+    headers: HeaderMap,
+    body: Bytes,
+) -> Result<Response, AppError> {
+    let authed = match gate(&state, &headers, &method, &uri, &body).await? {
+        Ok(a) => a,
+        Err(resp) => return Ok(resp),
+    };
+    let parsed: DeleteWebhookBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return Ok(bad_request("invalid body")),
+    };
+    let conn = state.db.conn()?;
+    outcome_response(apply_delete_webhook(&conn, authed.as_deref(), &parsed).await?)
+}
+
+/// DELETE a webhook. Authz: admin of the webhook's group.
+pub async fn apply_delete_webhook(
+    conn: &Connection,
+    authed: Option<&str>,
+    body: &DeleteWebhookBody,
+) -> anyhow::Result<WriteOutcome> {
+    let requester = match resolve_actor(authed, body.requester_id.as_deref()) {
+        Ok(r) => r,
+        Err(o) => return Ok(o),
+    };
+    let mut rows = conn
+        .query(
+            "SELECT group_id FROM group_webhook WHERE id = ?1",
+            libsql::params![body.webhook_id.clone()],
+        )
+        .await?;
+    let group_id: String = match rows.next().await? {
+        Some(row) => row.get(0)?,
+        None => return Ok(WriteOutcome::Forbidden),
+    };
+    drop(rows);
+    if authed.is_some() && !is_admin(conn, &group_id, &requester).await? {
+        return Ok(WriteOutcome::Forbidden);
+    }
+    conn.execute(
+        "DELETE FROM group_webhook WHERE id = ?1",
+        libsql::params![body.webhook_id.clone()],
+    )
+    .await?;
+    Ok(WriteOutcome::Ok)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn non_public_addresses_are_rejected() {
+        for ip in [
+            "127.0.0.1",
+            "10.1.2.3",
+            "192.168.0.1",
+            "169.254.169.254",
+            "100.64.0.1",
+            "::1",
+            "fd00::1",
+            "fe80::1",
+            "::ffff:10.0.0.1",
+            "64:ff9b::7f00:1",
+            "64:ff9b::a9fe:a9fe",
+            "64:ff9b:1::8.8.8.8",
+            "2002:a00:1::1",
+            "2002:7f00:1::",
+            "2001:db8::1",
+        ] {
+            assert!(!is_public(ip.parse().unwrap()), "{ip}");
+        }
+        for ip in ["1.1.1.1", "2606:4700::1111", "64:ff9b::101:101", "2002:101:101::1"] {
+            assert!(is_public(ip.parse().unwrap()), "{ip}");
+        }
+    }
+
+    #[test]
+    fn url_validation() {
+        assert!(validate_url("https://ci.example.com/hook", false).is_ok());
+        assert!(validate_url("http://ci.example.com/hook", false).is_err());
+        assert!(validate_url("https://user:pw@ci.example.com/hook", false).is_err());
+        assert!(validate_url("https://127.0.0.1/hook", false).is_err());
+        assert!(validate_url("https://[::1]/hook", false).is_err());
+        assert!(validate_url("http://127.0.0.1:8080/hook", true).is_ok());
+    }
+
+    #[test]
+    fn secrets_are_per_webhook_and_need_the_key() {
+        let off = WebhookConfig::default();
+        assert!(off.secret_for("a").is_none());
+        let on = WebhookConfig {
+            signing_key: Some(Arc::new(b"k".to_vec())),
+            ..WebhookConfig::default()
+        };
+        let a = on.secret_for("a").unwrap();
+        assert_eq!(a.len(), 64);
+        assert_eq!(on.secret_for("a").unwrap(), a);
+        assert_ne!(on.secret_for("b").unwrap(), a);
+    }
+}
@@ -69,7 +69,10 @@ pub(crate) async fn gate(
     )
     .await
     {
-        Ok(user_id) => Ok(Ok(Some(user_id))),
+        Ok(user_id) => {
+            crate::inactivity::record_activity(state, &user_id).await;
+            Ok(Ok(Some(user_id)))
+        }
         Err(rej) => Ok(Err(rej.into_response())),
     }
 }
@@ -104,7 +107,7 @@ pub(crate) async fn gate_or_session(
         .duration_since(std::time::UNIX_EPOCH)
         .map(|d| d.as_secs())
         .unwrap_or(0);
-    match crate::session::verify_session(headers, &state.sessions, now) {
+    match crate::session::verify_session(headers, &state.sessions, now).await {
         Ok(claims) => Ok(Ok(Some(claims.user_id))),
         Err(rej) => Ok(Err(rej.into_response())),
     }
@@ -5,7 +5,8 @@
 //! request-otp → verify-otp → establish-identity → register-device →
 //! publish-device-cert happy path, plus the security properties — OTP lockout,
 //! single-use, the establish-identity CAS (no overwrite), session→user binding,
-//! and the cert-validity gate.
+//! and the cert-validity gate. With `DS_SHARED_STATE=db` the same sign-in
+//! spans instances.
 
 use std::sync::Arc;
 
@@ -39,7 +40,8 @@ CREATE TABLE account_key_log (\
   user_id TEXT NOT NULL,\
   account_id_pub BLOB NOT NULL,\
   identity_version INTEGER NOT NULL,\
-  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  rotation_sig BLOB\
 );\
 CREATE UNIQUE INDEX idx_account_key_log_user_version \
   ON account_key_log (user_id, identity_version);\
@@ -93,6 +95,26 @@ CREATE TABLE device_enrollment_request (\
   created_at TEXT NOT NULL DEFAULT (datetime('now')),\
   expires_at TEXT NOT NULL,\
   approved_by_device_id TEXT\
+);\
+CREATE TABLE ds_rate_window (\
+  key TEXT PRIMARY KEY, window_start INTEGER NOT NULL, count INTEGER NOT NULL\
+);\
+CREATE TABLE ds_otp (\
+  scope TEXT NOT NULL,\
+  email TEXT NOT NULL,\
+  code_hash BLOB NOT NULL,\
+  salt BLOB NOT NULL,\
+  expires_at INTEGER NOT NULL,\
+  attempts INTEGER NOT NULL DEFAULT 0,\
+  last_sent_at INTEGER NOT NULL,\
+  PRIMARY KEY (scope, email)\
+);\
+CREATE TABLE ds_session (\
+  token_hash BLOB PRIMARY KEY,\
+  user_id TEXT NOT NULL,\
+  email TEXT NOT NULL,\
+  device_id TEXT NOT NULL,\
+  expires_at INTEGER NOT NULL\
 );";
 
 fn b64(b: &[u8]) -> String {
@@ -842,3 +864,75 @@ async fn enrollment_request_is_session_gated_and_binds_user() {
     assert_eq!(row_device, device_id, "new_device_id must be bound from the session");
     assert_eq!(row_status, "pending");
 }
+
+// ── 9. Shared state — sign-in spans instances ────────────────────────────────
+
+#[tokio::test(flavor = "multi_thread")]
+async fn shared_sign_in_spans_instances() {
+    let db = fresh_db().await;
+    let a = dev_state(Arc::clone(&db)).with_shared_state();
+    let b = dev_state(Arc::clone(&db)).with_shared_state();
+
+    // The code is emailed by one instance and verified by another.
+    let (s, _) = send(&a, "/v1/auth/request-otp", serde_json::json!({ "email": "e@x.com" }), None).await;
+    assert_eq!(s, StatusCode::OK);
+    let (s, v) = send(
+        &b,
+        "/v1/auth/verify-otp",
+        serde_json::json!({ "email": "e@x.com", "code": DEV_CODE, "device_id": "dev-e" }),
+        None,
+    )
+    .await;
+    assert_eq!(s, StatusCode::OK, "a code must verify on any instance: {v}");
+    let user_id = v["user_id"].as_str().unwrap().to_string();
+    let token = v["session_token"].as_str().unwrap().to_string();
+
+    // Single-use holds across instances too.
+    let (s, _) = send(
+        &a,
+        "/v1/auth/verify-otp",
+        serde_json::json!({ "email": "e@x.com", "code": DEV_CODE, "device_id": "dev-e" }),
+        None,
+    )
+    .await;
+    assert_eq!(s, StatusCode::UNAUTHORIZED, "the code was spent on the other instance");
+
+    // The session minted by `b` opens the bootstrap writes on `a`.
+    let account_pub_b = gen_key().verifying_key().to_bytes();
+    let (s, _) = send(
+        &a,
+        "/v1/auth/establish-identity",
+        serde_json::json!({
+            "account_id_pub": b64(&account_pub_b),
+            "salt": b64(&[1u8; 32]),
+            "nonce": b64(&[2u8; 12]),
+            "wrapped_key": b64(&[3u8; 48]),
+        }),
+        Some(&token),
+    )
+    .await;
+    assert_eq!(s, StatusCode::OK, "the session must hold on another instance");
+    assert_eq!(account_pub(&db, &user_id).await.unwrap(), account_pub_b.to_vec());
+
+    // Wrong guesses spread over instances share one lockout counter.
+    send(&a, "/v1/auth/request-otp", serde_json::json!({ "email": "f@x.com" }), None).await;
+    for i in 0..5 {
+        let instance = if i % 2 == 0 { &a } else { &b };
+        let (s, _) = send(
+            instance,
+            "/v1/auth/verify-otp",
+            serde_json::json!({ "email": "f@x.com", "code": "000000", "device_id": "d" }),
+            None,
+        )
+        .await;
+        assert_eq!(s, StatusCode::UNAUTHORIZED);
+    }
+    let (s, _) = send(
+        &b,
+        "/v1/auth/verify-otp",
+        serde_json::json!({ "email": "f@x.com", "code": "000000", "device_id": "d" }),
+        None,
+    )
+    .await;
+    assert_eq!(s, StatusCode::TOO_MANY_REQUESTS);
+}
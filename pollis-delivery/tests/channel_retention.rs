@@ -0,0 +1,153 @@
+//! Channel retention (`channels.retention_days`, migration 000016): only a group
+//! admin may set it through [`apply_update_channel`], and [`apply_envelope_gc`]
+//! then deletes the channel's envelopes older than the window while leaving
+//! channels without one to the TTL/watermark GC.
+
+use std::sync::Arc;
+
+use pollis_delivery::db::Db;
+use pollis_delivery::groups::{apply_update_channel, UpdateChannelBody};
+use pollis_delivery::messages::{apply_envelope_gc, EnvelopeGcBody};
+use pollis_delivery::writes::WriteOutcome;
+
+// Just the tables the update + channel GC paths touch. No devices, so the
+// watermark gate never fires and only the 30-day TTL or retention can delete.
+const SCHEMA: &str = "\
+CREATE TABLE channels (\
+  id TEXT PRIMARY KEY,\
+  group_id TEXT NOT NULL,\
+  name TEXT NOT NULL,\
+  description TEXT,\
+  retention_days INTEGER\
+);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE user_device (user_id TEXT NOT NULL, device_id TEXT NOT NULL);\
+CREATE TABLE conversation_watermark (\
+  conversation_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  device_id TEXT NOT NULL,\
+  last_fetched_at TEXT\
+);\
+CREATE TABLE message_envelope (\
+  id TEXT PRIMARY KEY,\
+  conversation_id TEXT NOT NULL,\
+  sent_at TEXT NOT NULL\
+);\
+INSERT INTO channels (id, group_id, name) VALUES ('c1', 'g1', 'general'), ('c2', 'g1', 'random');\
+INSERT INTO group_member (group_id, user_id, role) VALUES ('g1', 'alice', 'admin'), ('g1', 'bob', 'member');\
+INSERT INTO message_envelope (id, conversation_id, sent_at) VALUES \
+  ('old1', 'c1', datetime('now', '-10 days')),\
+  ('new1', 'c1', datetime('now', '-1 days')),\
+  ('old2', 'c2', datetime('now', '-10 days'));";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+fn set_retention(channel_id: &str, days: i64) -> UpdateChannelBody {
+    UpdateChannelBody {
+        channel_id: channel_id.into(),
+        requester_id: None,
+        name: None,
+        description: None,
+        retention_days: Some(days),
+    }
+}
+
+fn gc(channel_id: &str) -> EnvelopeGcBody {
+    EnvelopeGcBody {
+        conversation_id: channel_id.into(),
+        is_dm: false,
+        actor_id: None,
+    }
+}
+
+async fn envelope_ids(db: &Db) -> Vec<String> {
+    let conn = db.conn().unwrap();
+    let mut rows = conn
+        .query("SELECT id FROM message_envelope ORDER BY id", ())
+        .await
+        .unwrap();
+    let mut out = Vec::new();
+    while let Some(row) = rows.next().await.unwrap() {
+        out.push(row.get(0).unwrap());
+    }
+    out
+}
+
+async fn retention_of(db: &Db, channel_id: &str) -> Option<i64> {
+    let conn = db.conn().unwrap();
+    let mut rows = conn
+        .query(
+            "SELECT retention_days FROM channels WHERE id = ?1",
+            libsql::params![channel_id],
+        )
+        .await
+        .unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn only_admins_set_retention_and_zero_clears_it() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    let outcome = apply_update_channel(&conn, Some("bob"), &set_retention("c1", 7))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    assert_eq!(retention_of(&db, "c1").await, None);
+
+    let outcome = apply_update_channel(&conn, Some("alice"), &set_retention("c1", 7))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(retention_of(&db, "c1").await, Some(7));
+
+    let outcome = apply_update_channel(&conn, Some("alice"), &set_retention("c1", 0))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(retention_of(&db, "c1").await, None);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn gc_deletes_envelopes_past_the_channel_window_only() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    // No retention anywhere: 10-day-old envelopes are inside the 30-day TTL.
+    for channel in ["c1", "c2"] {
+        apply_envelope_gc(&conn, Some("bob"), &gc(channel))
+            .await
+            .unwrap();
+    }
+    assert_eq!(envelope_ids(&db).await, ["new1", "old1", "old2"]);
+
+    apply_update_channel(&conn, Some("alice"), &set_retention("c1", 7))
+        .await
+        .unwrap();
+    for channel in ["c1", "c2"] {
+        apply_envelope_gc(&conn, Some("bob"), &gc(channel))
+            .await
+            .unwrap();
+    }
+    // c1's 10-day-old envelope is gone; its recent one and c2's are kept.
+    assert_eq!(envelope_ids(&db).await, ["new1", "old2"]);
+}
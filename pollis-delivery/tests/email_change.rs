@@ -6,8 +6,9 @@
 //! signature headers and is verified against the seeded `user_device`
 //! `mls_signature_pub`. The OTP only proves control of the NEW mailbox.
 //!
-//! Coverage: the happy path, OTP wrong-code lockout, and the cross-user binding
-//! (a different signed user can't consume someone else's pending change).
+//! Coverage: the happy path, OTP wrong-code lockout, the cross-user binding
+//! (a different signed user can't consume someone else's pending change), and
+//! both holding across instances with `DS_SHARED_STATE=db`.
 
 use std::sync::Arc;
 
@@ -44,6 +45,24 @@ CREATE TABLE user_device (\
   cert_identity_version INTEGER,\
   mls_signature_pub BLOB,\
   revoked_at TEXT\
+);\
+CREATE TABLE ds_rate_window (\
+  key TEXT PRIMARY KEY, window_start INTEGER NOT NULL, count INTEGER NOT NULL\
+);\
+CREATE TABLE ds_otp (\
+  scope TEXT NOT NULL,\
+  email TEXT NOT NULL,\
+  code_hash BLOB NOT NULL,\
+  salt BLOB NOT NULL,\
+  expires_at INTEGER NOT NULL,\
+  attempts INTEGER NOT NULL DEFAULT 0,\
+  last_sent_at INTEGER NOT NULL,\
+  PRIMARY KEY (scope, email)\
+);\
+CREATE TABLE ds_email_change (\
+  email TEXT PRIMARY KEY,\
+  requester TEXT NOT NULL,\
+  requested_at INTEGER NOT NULL\
 );";
 
 fn b64(b: &[u8]) -> String {
@@ -301,3 +320,52 @@ async fn email_change_rejects_cross_user() {
     assert_eq!(email_of(&db, "alice").await, new_email);
     assert_eq!(email_of(&db, "bob").await, "bob@x.com", "bob untouched");
 }
+
+// ── 4. Shared state — request and verify on different instances ───────────────
+
+#[tokio::test(flavor = "multi_thread")]
+async fn email_change_spans_instances() {
+    let db = fresh_db().await;
+    let a = dev_state(Arc::clone(&db)).with_shared_state();
+    let b = dev_state(Arc::clone(&db)).with_shared_state();
+    let alice = gen_signing_key();
+    let mallory = gen_signing_key();
+    seed_user(&db, "alice", "alice@x.com", "dev-a", &alice.verifying_key()).await;
+    seed_user(&db, "mallory", "mallory@x.com", "dev-m", &mallory.verifying_key()).await;
+
+    let new_email = "alice-new@x.com";
+    let s = send_signed(
+        &a,
+        "/v1/auth/request-email-change-otp",
+        "alice",
+        "dev-a",
+        &alice,
+        serde_json::json!({ "new_email": new_email }),
+    )
+    .await;
+    assert_eq!(s, StatusCode::OK);
+
+    // The binding is shared too: another user is refused on the other instance.
+    let s = send_signed(
+        &b,
+        "/v1/auth/verify-email-change",
+        "mallory",
+        "dev-m",
+        &mallory,
+        serde_json::json!({ "new_email": new_email, "code": DEV_CODE }),
+    )
+    .await;
+    assert_eq!(s, StatusCode::FORBIDDEN);
+
+    let s = send_signed(
+        &b,
+        "/v1/auth/verify-email-change",
+        "alice",
+        "dev-a",
+        &alice,
+        serde_json::json!({ "new_email": new_email, "code": DEV_CODE }),
+    )
+    .await;
+    assert_eq!(s, StatusCode::OK, "the code must verify on another instance");
+    assert_eq!(email_of(&db, "alice").await, new_email);
+}
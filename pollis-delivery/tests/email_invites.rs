@@ -0,0 +1,180 @@
+//! Email invites (`pollis-delivery/src/email_invites.rs`, migration 000019):
+//! only admins create them, opted-out addresses are silently skipped, the
+//! per-inviter daily cap holds, and a stored invite becomes a pending
+//! `group_invite` on signup or when its link is redeemed.
+
+use std::sync::Arc;
+
+use pollis_delivery::db::Db;
+use pollis_delivery::email_invites::{
+    apply_create_email_invite, apply_opt_out, apply_pending_for_new_account, apply_redeem,
+    invite_token, parse_invite_token, CreateEmailInviteBody, EmailInviteOutcome,
+};
+
+// Just the tables the email-invite paths touch.
+const SCHEMA: &str = "\
+CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT NOT NULL, username TEXT NOT NULL);\
+CREATE TABLE groups (id TEXT PRIMARY KEY, name TEXT NOT NULL, owner_id TEXT NOT NULL);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE group_invite (\
+  id TEXT PRIMARY KEY,\
+  group_id TEXT NOT NULL,\
+  inviter_id TEXT NOT NULL,\
+  invitee_id TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  status TEXT NOT NULL DEFAULT 'pending'\
+);\
+CREATE TABLE email_invite (\
+  id TEXT PRIMARY KEY,\
+  group_id TEXT NOT NULL,\
+  inviter_id TEXT NOT NULL,\
+  email TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  UNIQUE (group_id, email)\
+);\
+CREATE TABLE email_invite_opt_out (\
+  email_hash TEXT PRIMARY KEY,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);\
+INSERT INTO users (id, email, username) VALUES ('alice', 'alice@x.com', 'alice'), ('bob', 'bob@x.com', 'bob');\
+INSERT INTO groups (id, name, owner_id) VALUES ('g1', 'Book Club', 'alice');\
+INSERT INTO group_member (group_id, user_id, role) VALUES ('g1', 'alice', 'admin'), ('g1', 'bob', 'member');";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+fn invite(id: &str, email: &str) -> CreateEmailInviteBody {
+    CreateEmailInviteBody {
+        id: id.into(),
+        group_id: "g1".into(),
+        email: email.into(),
+        inviter_id: None,
+    }
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn only_admins_invite_and_existing_accounts_are_refused() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    let outcome = apply_create_email_invite(&conn, Some("bob"), &invite("i1", "new@x.com"), 20)
+        .await
+        .unwrap();
+    assert!(matches!(outcome, EmailInviteOutcome::Forbidden));
+
+    let outcome = apply_create_email_invite(&conn, Some("alice"), &invite("i1", "Bob@X.com"), 20)
+        .await
+        .unwrap();
+    assert!(matches!(outcome, EmailInviteOutcome::HasAccount));
+
+    let outcome = apply_create_email_invite(&conn, Some("alice"), &invite("i1", "New@X.com"), 20)
+        .await
+        .unwrap();
+    assert!(matches!(
+        outcome,
+        EmailInviteOutcome::Created { ref group_name, .. } if group_name == "Book Club"
+    ));
+    let outcome = apply_create_email_invite(&conn, Some("alice"), &invite("i2", "new@x.com"), 20)
+        .await
+        .unwrap();
+    assert!(matches!(outcome, EmailInviteOutcome::AlreadyInvited));
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn opt_out_suppresses_and_daily_cap_holds() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    apply_create_email_invite(&conn, Some("alice"), &invite("i1", "quiet@x.com"), 20)
+        .await
+        .unwrap();
+    apply_opt_out(&conn, "Quiet@x.com").await.unwrap();
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM email_invite").await, 0);
+    let outcome = apply_create_email_invite(&conn, Some("alice"), &invite("i2", "quiet@x.com"), 20)
+        .await
+        .unwrap();
+    assert!(matches!(outcome, EmailInviteOutcome::Suppressed));
+
+    for n in 0..2 {
+        let outcome = apply_create_email_invite(
+            &conn,
+            Some("alice"),
+            &invite(&format!("c{n}"), &format!("p{n}@x.com")),
+            2,
+        )
+        .await
+        .unwrap();
+        assert!(matches!(outcome, EmailInviteOutcome::Created { .. }));
+    }
+    let outcome = apply_create_email_invite(&conn, Some("alice"), &invite("c3", "p3@x.com"), 2)
+        .await
+        .unwrap();
+    assert!(matches!(outcome, EmailInviteOutcome::RateLimited));
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn signup_and_redeem_turn_invites_into_pending_ones() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    apply_create_email_invite(&conn, Some("alice"), &invite("i1", "carol@x.com"), 20)
+        .await
+        .unwrap();
+    apply_create_email_invite(&conn, Some("alice"), &invite("i2", "dave@x.com"), 20)
+        .await
+        .unwrap();
+
+    // Carol signs up with the invited address.
+    let converted = apply_pending_for_new_account(&conn, "carol", "CAROL@x.com")
+        .await
+        .unwrap();
+    assert_eq!(converted, 1);
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM group_invite WHERE id = 'i1' AND invitee_id = 'carol'"
+        )
+        .await,
+        1
+    );
+
+    // Dave signed up elsewhere and redeems the link; a second redeem finds nothing.
+    let token = invite_token("k", "i2");
+    let id = parse_invite_token("k", &token).unwrap();
+    assert_eq!(
+        apply_redeem(&conn, &id, "dave2").await.unwrap().as_deref(),
+        Some("g1")
+    );
+    assert_eq!(apply_redeem(&conn, &id, "mallory").await.unwrap(), None);
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM group_invite WHERE id = 'i2' AND invitee_id = 'dave2'"
+        )
+        .await,
+        1
+    );
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM email_invite").await, 0);
+}
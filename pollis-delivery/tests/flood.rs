@@ -0,0 +1,163 @@
+//! Flood detection on `POST /v1/messages/send` (`flood`), driven through the
+//! real axum router with `tower::oneshot` against a local libsql DB. A send that
+//! trips a heuristic is a `429 FLOOD_DETECTED`, mutes the sender, and leaves a
+//! `flood_incident` row; sends during the mute are refused without new rows.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use axum::Router;
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::flood::FloodConfig;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// Just the tables the send path touches.
+const SCHEMA: &str = "\
+CREATE TABLE message_envelope (\
+  id TEXT PRIMARY KEY,\
+  conversation_id TEXT NOT NULL,\
+  sender_id TEXT NOT NULL,\
+  ciphertext TEXT NOT NULL,\
+  reply_to_id TEXT,\
+  sent_at TEXT NOT NULL,\
+  delivered INTEGER NOT NULL DEFAULT 0,\
+  type TEXT NOT NULL DEFAULT 'message',\
+  target_message_id TEXT,\
+  sealed INTEGER NOT NULL DEFAULT 0\
+);\
+CREATE TABLE flood_incident (\
+  id TEXT PRIMARY KEY,\
+  user_id TEXT NOT NULL,\
+  conversation_id TEXT NOT NULL,\
+  reason TEXT NOT NULL,\
+  muted_until TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);";
+
+const TINY: FloodConfig = FloodConfig {
+    per_target_max: 2,
+    per_target_window_secs: 60,
+    duplicate_max: 1,
+    duplicate_window_secs: 60,
+    mute_secs: 300,
+};
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+// Auth off: the no-auth path takes the sender from the body.
+async fn send(
+    router: &Router,
+    id: &str,
+    sender: &str,
+    conversation: &str,
+    ciphertext: &str,
+) -> axum::response::Response {
+    let body = serde_json::json!({
+        "id": id,
+        "conversation_id": conversation,
+        "sender_id": sender,
+        "ciphertext": ciphertext,
+        "sent_at": "2026-01-01T00:00:00+00:00",
+    });
+    let req = Request::builder()
+        .method("POST")
+        .uri("/v1/messages/send")
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap();
+    router.clone().oneshot(req).await.unwrap()
+}
+
+async fn body_json(resp: axum::response::Response) -> serde_json::Value {
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    serde_json::from_slice(&bytes).unwrap()
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn rate_trip_mutes_and_records_one_incident() {
+    let db = fresh_db().await;
+    let router =
+        build_router_with_state(AppState::new(Arc::clone(&db), false).with_flood_config(TINY));
+
+    assert_eq!(
+        send(&router, "m1", "alice", "c1", "mls:01").await.status(),
+        StatusCode::OK
+    );
+    assert_eq!(
+        send(&router, "m2", "alice", "c1", "mls:02").await.status(),
+        StatusCode::OK
+    );
+
+    let resp = send(&router, "m3", "alice", "c1", "mls:03").await;
+    assert_eq!(resp.status(), StatusCode::TOO_MANY_REQUESTS);
+    assert_eq!(resp.headers()["retry-after"], "300");
+    let body = body_json(resp).await;
+    assert_eq!(body["error"], "FLOOD_DETECTED");
+    assert_eq!(body["reason"], "rate");
+
+    // Muted everywhere, and the refusal doesn't add another incident.
+    let resp = send(&router, "m4", "alice", "c2", "mls:04").await;
+    assert_eq!(resp.status(), StatusCode::TOO_MANY_REQUESTS);
+    assert_eq!(body_json(resp).await["reason"], "muted");
+
+    // Someone else in the same conversation is unaffected.
+    assert_eq!(
+        send(&router, "m5", "bob", "c1", "mls:05").await.status(),
+        StatusCode::OK
+    );
+
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM message_envelope").await, 3);
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM flood_incident WHERE user_id = 'alice' AND reason = 'rate'"
+        )
+        .await,
+        1
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn identical_envelope_burst_is_flagged() {
+    let db = fresh_db().await;
+    let router =
+        build_router_with_state(AppState::new(Arc::clone(&db), false).with_flood_config(TINY));
+
+    assert_eq!(
+        send(&router, "m1", "alice", "c1", "mls:aa").await.status(),
+        StatusCode::OK
+    );
+    let resp = send(&router, "m2", "alice", "c2", "mls:aa").await;
+    assert_eq!(resp.status(), StatusCode::TOO_MANY_REQUESTS);
+    assert_eq!(body_json(resp).await["reason"], "duplicate");
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM flood_incident WHERE reason = 'duplicate'"
+        )
+        .await,
+        1
+    );
+}
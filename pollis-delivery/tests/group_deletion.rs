@@ -0,0 +1,208 @@
+//! Two-phase group deletion: `apply_delete_group` schedules (owner only),
+//! `apply_cancel_group_deletion` withdraws, and [`purge_once`] deletes only
+//! groups whose grace period is over — with their channels' envelopes and pins.
+
+use std::sync::Arc;
+
+use pollis_delivery::db::Db;
+use pollis_delivery::group_purge::purge_once;
+use pollis_delivery::groups::{apply_cancel_group_deletion, apply_delete_group, DeleteGroupBody};
+use pollis_delivery::writes::WriteOutcome;
+
+// Just the tables scheduling and the purge touch.
+const SCHEMA: &str = "\
+CREATE TABLE groups (id TEXT PRIMARY KEY, name TEXT NOT NULL, owner_id TEXT NOT NULL);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE channels (id TEXT PRIMARY KEY, group_id TEXT NOT NULL);\
+CREATE TABLE conversation_watermark (\
+  conversation_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  device_id TEXT NOT NULL,\
+  last_fetched_at TEXT NOT NULL,\
+  PRIMARY KEY (conversation_id, user_id, device_id)\
+);\
+CREATE TABLE message_envelope (id TEXT PRIMARY KEY, conversation_id TEXT NOT NULL);\
+CREATE TABLE pinned_message (\
+  conversation_id TEXT NOT NULL,\
+  message_id TEXT NOT NULL,\
+  pinned_by TEXT NOT NULL,\
+  PRIMARY KEY (conversation_id, message_id)\
+);\
+CREATE TABLE group_deletion (\
+  group_id TEXT PRIMARY KEY,\
+  requested_by TEXT NOT NULL,\
+  requested_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  purge_after TEXT NOT NULL\
+);\
+CREATE TABLE mls_commit_log (seq INTEGER PRIMARY KEY, conversation_id TEXT NOT NULL);\
+CREATE TABLE mls_group_info (conversation_id TEXT PRIMARY KEY);\
+CREATE TABLE mls_welcome (id TEXT PRIMARY KEY, conversation_id TEXT NOT NULL);\
+CREATE TABLE mls_commit_since (conversation_id TEXT NOT NULL);\
+INSERT INTO groups (id, name, owner_id) VALUES ('g1', 'one', 'alice'), ('g2', 'two', 'alice');\
+INSERT INTO group_member (group_id, user_id, role) VALUES \
+  ('g1', 'alice', 'admin'), ('g1', 'bob', 'admin'), ('g2', 'alice', 'admin');\
+INSERT INTO channels (id, group_id) VALUES ('c1', 'g1'), ('c2', 'g2');\
+INSERT INTO conversation_watermark VALUES \
+  ('c1', 'alice', 'd1', '2026-01-01'), ('c2', 'alice', 'd1', '2026-01-01');\
+INSERT INTO message_envelope (id, conversation_id) VALUES ('e1', 'c1'), ('e2', 'c1'), ('e3', 'c2');\
+INSERT INTO pinned_message (conversation_id, message_id, pinned_by) VALUES \
+  ('c1', 'e1', 'alice'), ('c2', 'e3', 'alice');\
+INSERT INTO mls_commit_log (conversation_id) VALUES ('g1'), ('g2');";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+fn body(group: &str, requester: &str) -> DeleteGroupBody {
+    DeleteGroupBody {
+        group_id: group.into(),
+        requester_id: Some(requester.into()),
+    }
+}
+
+async fn exec(db: &Db, sql: &str) {
+    db.conn().unwrap().execute_batch(sql).await.unwrap();
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn only_the_owner_schedules_and_nothing_is_deleted_yet() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    // Bob is an admin but not the owner.
+    let outcome = apply_delete_group(&conn, Some("bob"), &body("g1", "bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM group_deletion").await, 0);
+
+    let outcome = apply_delete_group(&conn, Some("alice"), &body("g1", "alice"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM group_deletion \
+             WHERE group_id = 'g1' AND requested_by = 'alice' \
+               AND purge_after > datetime('now', '+6 days')"
+        )
+        .await,
+        1
+    );
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM groups WHERE id = 'g1'").await,
+        1
+    );
+
+    // Inside the grace period the sweep leaves it alone.
+    assert_eq!(purge_once(&db, &db).await.unwrap(), 0);
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM message_envelope").await, 3);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn cancel_withdraws_a_pending_deletion() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    apply_delete_group(&conn, Some("alice"), &body("g1", "alice"))
+        .await
+        .unwrap();
+
+    let outcome = apply_cancel_group_deletion(&conn, Some("bob"), &body("g1", "bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM group_deletion").await, 1);
+
+    let outcome = apply_cancel_group_deletion(&conn, Some("alice"), &body("g1", "alice"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM group_deletion").await, 0);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn purge_removes_due_groups_and_their_envelopes() {
+    let db = fresh_db().await;
+    exec(
+        &db,
+        "INSERT INTO group_deletion (group_id, requested_by, purge_after) \
+         VALUES ('g1', 'alice', datetime('now', '-1 minutes'));",
+    )
+    .await;
+
+    assert_eq!(purge_once(&db, &db).await.unwrap(), 1);
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM groups WHERE id = 'g1'").await,
+        0
+    );
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM channels WHERE group_id = 'g1'").await,
+        0
+    );
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM group_member WHERE group_id = 'g1'"
+        )
+        .await,
+        0
+    );
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM group_deletion").await, 0);
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM mls_commit_log WHERE conversation_id = 'g1'"
+        )
+        .await,
+        0
+    );
+    // The other group is untouched.
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM message_envelope WHERE conversation_id = 'c2'"
+        )
+        .await,
+        1
+    );
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM message_envelope").await, 1);
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM pinned_message WHERE conversation_id = 'c2'"
+        )
+        .await,
+        1
+    );
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM pinned_message").await, 1);
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM conversation_watermark").await,
+        1
+    );
+
+    // Nothing left to do.
+    assert_eq!(purge_once(&db, &db).await.unwrap(), 0);
+}
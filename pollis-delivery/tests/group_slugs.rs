@@ -0,0 +1,197 @@
+//! Group slug reservations (`pollis-delivery/src/slugs.rs`, migration 000018):
+//! one user at a time may hold a slug, a group created before reservations
+//! blocks the slug its name derives to once the startup backfill has run, and
+//! `apply_create_group` only claims a slug its owner reserved — or, without
+//! one, the slug its name derives to.
+
+use std::sync::Arc;
+
+use pollis_delivery::db::Db;
+use pollis_delivery::groups::{apply_create_group, CreateGroupBody};
+use pollis_delivery::slugs::{
+    apply_reserve_slug, backfill_legacy_slugs, ReserveOutcome, ReserveSlugBody,
+};
+use pollis_delivery::writes::WriteOutcome;
+
+// Just the tables reserve + create touch.
+const SCHEMA: &str = "\
+CREATE TABLE groups (\
+  id TEXT PRIMARY KEY,\
+  name TEXT NOT NULL,\
+  description TEXT,\
+  owner_id TEXT NOT NULL,\
+  created_at TEXT NOT NULL\
+);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE group_slug (\
+  slug TEXT PRIMARY KEY,\
+  user_id TEXT NOT NULL,\
+  group_id TEXT,\
+  reserved_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);\
+INSERT INTO groups (id, name, owner_id, created_at) VALUES ('legacy', 'Old Timers', 'carol', '2025-01-01');\
+INSERT INTO groups (id, name, owner_id, created_at) VALUES ('legacy-dup', 'OLD timers', 'dave', '2025-02-01');";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+fn reserve(slug: &str) -> ReserveSlugBody {
+    ReserveSlugBody {
+        slug: slug.into(),
+        user_id: None,
+    }
+}
+
+fn create(id: &str, slug: Option<&str>) -> CreateGroupBody {
+    CreateGroupBody {
+        id: id.into(),
+        name: "Book Club".into(),
+        description: None,
+        owner_id: None,
+        default_text_channel_id: None,
+        default_voice_channel_id: None,
+        slug: slug.map(str::to_string),
+        created_at: "2026-01-01T00:00:00+00:00".into(),
+    }
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn second_user_cannot_take_a_live_reservation() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    let first = apply_reserve_slug(&conn, Some("alice"), &reserve("Book Club"))
+        .await
+        .unwrap();
+    assert!(matches!(first, ReserveOutcome::Reserved(ref s) if s == "book-club"));
+
+    // Re-reserving your own slug is fine; someone else's is taken.
+    let again = apply_reserve_slug(&conn, Some("alice"), &reserve("book-club"))
+        .await
+        .unwrap();
+    assert!(matches!(again, ReserveOutcome::Reserved(_)));
+    let other = apply_reserve_slug(&conn, Some("bob"), &reserve("BOOK  club"))
+        .await
+        .unwrap();
+    assert!(matches!(other, ReserveOutcome::Taken));
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn reserved_words_and_legacy_names_are_refused() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    backfill_legacy_slugs(&conn).await.unwrap();
+
+    let word = apply_reserve_slug(&conn, Some("alice"), &reserve("Settings"))
+        .await
+        .unwrap();
+    assert!(matches!(word, ReserveOutcome::ReservedWord));
+    let legacy = apply_reserve_slug(&conn, Some("alice"), &reserve("old-timers"))
+        .await
+        .unwrap();
+    assert!(matches!(legacy, ReserveOutcome::Taken));
+    let empty = apply_reserve_slug(&conn, Some("alice"), &reserve("???"))
+        .await
+        .unwrap();
+    assert!(matches!(empty, ReserveOutcome::Invalid));
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn create_claims_only_the_owners_reservation() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    apply_reserve_slug(&conn, Some("alice"), &reserve("book-club"))
+        .await
+        .unwrap();
+
+    // Bob can't create a group under Alice's reservation; nothing is written.
+    let outcome = apply_create_group(&conn, Some("bob"), &create("g-bob", Some("book-club")))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    let mut rows = conn
+        .query("SELECT COUNT(*) FROM groups WHERE id = 'g-bob'", ())
+        .await
+        .unwrap();
+    let count: i64 = rows.next().await.unwrap().unwrap().get(0).unwrap();
+    assert_eq!(count, 0);
+
+    let outcome = apply_create_group(&conn, Some("alice"), &create("g1", Some("book-club")))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+
+    // Claimed: no longer refreshable, even by its holder.
+    let again = apply_reserve_slug(&conn, Some("alice"), &reserve("book-club"))
+        .await
+        .unwrap();
+    assert!(matches!(again, ReserveOutcome::Taken));
+}
+
+async fn slug_holder(conn: &libsql::Connection, slug: &str) -> Option<String> {
+    let mut rows = conn
+        .query(
+            "SELECT group_id FROM group_slug WHERE slug = ?1",
+            libsql::params![slug.to_string()],
+        )
+        .await
+        .unwrap();
+    rows.next().await.unwrap().and_then(|r| r.get(0).ok())
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn backfill_gives_the_oldest_legacy_group_its_slug() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    // A reservation that raced in before the backfill ran.
+    apply_reserve_slug(&conn, Some("alice"), &reserve("old-timers"))
+        .await
+        .unwrap();
+
+    assert_eq!(backfill_legacy_slugs(&conn).await.unwrap(), 1);
+    assert_eq!(slug_holder(&conn, "old-timers").await.as_deref(), Some("legacy"));
+
+    // The newer duplicate stays without a row, and a second run is a no-op.
+    assert_eq!(backfill_legacy_slugs(&conn).await.unwrap(), 0);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn create_without_a_slug_claims_the_name() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    let outcome = apply_create_group(&conn, Some("alice"), &create("g1", None))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(slug_holder(&conn, "book-club").await.as_deref(), Some("g1"));
+
+    // A second shipped client can't create another "Book Club".
+    let outcome = apply_create_group(&conn, Some("bob"), &create("g2", None))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    let mut rows = conn
+        .query("SELECT COUNT(*) FROM groups WHERE id = 'g2'", ())
+        .await
+        .unwrap();
+    let count: i64 = rows.next().await.unwrap().unwrap().get(0).unwrap();
+    assert_eq!(count, 0);
+}
@@ -0,0 +1,192 @@
+//! Idempotency keys on writes (`idempotency`), driven through the real axum
+//! router with `tower::oneshot` against a local libsql DB. A retried key-package
+//! claim gets the first claim's package back instead of consuming a second one;
+//! a key reused for a different body is refused; a failed write isn't
+//! remembered. With `DS_SHARED_STATE=db` the retry gets the same answer from
+//! another instance.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use axum::Router;
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// The table the claim touches, plus the shared-state tables (000024, 000026).
+const SCHEMA: &str = "\
+CREATE TABLE mls_key_package (\
+  ref_hash    TEXT PRIMARY KEY,\
+  user_id     TEXT NOT NULL,\
+  key_package BLOB NOT NULL,\
+  claimed     INTEGER NOT NULL DEFAULT 0,\
+  created_at  TEXT NOT NULL DEFAULT (datetime('now')),\
+  device_id   TEXT,\
+  claimed_at  TEXT\
+);\
+INSERT INTO mls_key_package (ref_hash, user_id, key_package, device_id, created_at) \
+  VALUES ('ref-a', 'bob', X'01', 'dev1', '2024-01-01 00:00:00'), \
+         ('ref-b', 'bob', X'02', 'dev1', '2024-01-02 00:00:00');\
+CREATE TABLE ds_rate_window (\
+  key TEXT PRIMARY KEY,\
+  window_start INTEGER NOT NULL,\
+  count INTEGER NOT NULL\
+);\
+CREATE TABLE ds_idempotency (\
+  user_id TEXT NOT NULL,\
+  device_id TEXT NOT NULL,\
+  key TEXT NOT NULL,\
+  fingerprint BLOB NOT NULL,\
+  status INTEGER,\
+  content_type TEXT,\
+  body BLOB,\
+  until INTEGER NOT NULL,\
+  PRIMARY KEY (user_id, device_id, key)\
+);";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+// Auth off; the user/device headers alone scope the key.
+async fn claim(
+    router: &Router,
+    key: Option<&str>,
+    target: &str,
+) -> (StatusCode, bool, serde_json::Value) {
+    let mut req = Request::builder()
+        .method("POST")
+        .uri("/v1/key-packages/claim")
+        .header("content-type", "application/json")
+        .header("x-pollis-user", "alice")
+        .header("x-pollis-device", "alice-dev");
+    if let Some(key) = key {
+        req = req.header("idempotency-key", key);
+    }
+    let body = serde_json::json!({ "target_user_id": target, "target_device_id": "dev1" });
+    let req = req
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap();
+    let resp = router.clone().oneshot(req).await.unwrap();
+    let status = resp.status();
+    let replayed = resp.headers().get("idempotent-replayed").is_some();
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    (
+        status,
+        replayed,
+        serde_json::from_slice(&bytes).unwrap_or_default(),
+    )
+}
+
+async fn unclaimed(db: &Db) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn
+        .query("SELECT COUNT(*) FROM mls_key_package WHERE claimed = 0", ())
+        .await
+        .unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn retried_claim_returns_the_first_package() {
+    let db = fresh_db().await;
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false));
+
+    let (status, replayed, first) = claim(&router, Some("01HZKEY"), "bob").await;
+    assert_eq!(status, StatusCode::OK);
+    assert!(!replayed);
+    assert_eq!(first["ref_hash"], "ref-a");
+
+    let (status, replayed, again) = claim(&router, Some("01HZKEY"), "bob").await;
+    assert_eq!(status, StatusCode::OK);
+    assert!(replayed);
+    assert_eq!(again, first);
+    assert_eq!(
+        unclaimed(&db).await,
+        1,
+        "the retry must not consume a package"
+    );
+
+    // A new key is a new claim.
+    let (_, _, next) = claim(&router, Some("01HZOTHER"), "bob").await;
+    assert_eq!(next["ref_hash"], "ref-b");
+    assert_eq!(unclaimed(&db).await, 0);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn reused_key_and_failures() {
+    let db = fresh_db().await;
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false));
+
+    // A 404 (no package for carol) isn't stored; the same key may run again.
+    let (status, _, _) = claim(&router, Some("k1"), "carol").await;
+    assert_eq!(status, StatusCode::NOT_FOUND);
+    let (status, replayed, _) = claim(&router, Some("k1"), "bob").await;
+    assert_eq!(status, StatusCode::OK);
+    assert!(!replayed);
+
+    // Now k1 belongs to bob's claim: a different body under it is refused.
+    let (status, _, body) = claim(&router, Some("k1"), "carol").await;
+    assert_eq!(status, StatusCode::UNPROCESSABLE_ENTITY);
+    assert_eq!(body["error"], "IDEMPOTENCY_KEY_REUSED");
+
+    let (status, _, body) = claim(&router, Some("has space"), "bob").await;
+    assert_eq!(status, StatusCode::BAD_REQUEST);
+    assert_eq!(body["error"], "IDEMPOTENCY_KEY_INVALID");
+
+    // No key: every request runs.
+    let (status, replayed, _) = claim(&router, None, "bob").await;
+    assert_eq!(status, StatusCode::OK);
+    assert!(!replayed);
+    assert_eq!(unclaimed(&db).await, 0);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn shared_keys_hold_across_instances() {
+    let db = fresh_db().await;
+    let instance =
+        || build_router_with_state(AppState::new(Arc::clone(&db), false).with_shared_state());
+    let a = instance();
+
+    let (status, _, first) = claim(&a, Some("01HZKEY"), "bob").await;
+    assert_eq!(status, StatusCode::OK);
+    assert_eq!(first["ref_hash"], "ref-a");
+
+    // The resend lands on another instance, or on this one after a restart:
+    // either way a router with none of `a`'s memory.
+    let (status, replayed, again) = claim(&instance(), Some("01HZKEY"), "bob").await;
+    assert_eq!(status, StatusCode::OK);
+    assert!(replayed);
+    assert_eq!(again, first);
+    assert_eq!(
+        unclaimed(&db).await,
+        1,
+        "the resend must not consume a package"
+    );
+
+    // A different body under the key is refused there too.
+    let (status, _, body) = claim(&instance(), Some("01HZKEY"), "carol").await;
+    assert_eq!(status, StatusCode::UNPROCESSABLE_ENTITY);
+    assert_eq!(body["error"], "IDEMPOTENCY_KEY_REUSED");
+
+    // A failure is released from the shared table, so the key may run again.
+    let (status, _, _) = claim(&a, Some("k2"), "carol").await;
+    assert_eq!(status, StatusCode::NOT_FOUND);
+    let (status, replayed, next) = claim(&instance(), Some("k2"), "bob").await;
+    assert_eq!(status, StatusCode::OK);
+    assert!(!replayed);
+    assert_eq!(next["ref_hash"], "ref-b");
+}
@@ -0,0 +1,305 @@
+//! Inactive-account policy (`inactivity`): the set / check-in endpoints through
+//! the real axum router, a device-signed write counting as activity, and
+//! [`sweep_once`] directly against a local libsql DB with backdated timestamps.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use base64::Engine as _;
+use ed25519_dalek::{Signer, SigningKey};
+use pollis_delivery::auth::canonical_message;
+use pollis_delivery::db::Db;
+use pollis_delivery::inactivity::{sweep_once, ActivityStamps, InactivityConfig, SweepReport};
+use pollis_delivery::{build_router_with_state, AppState};
+use rand_core::{OsRng, RngCore as _};
+use tower::ServiceExt as _;
+
+// Just the tables the policy and the sweep touch.
+const SCHEMA: &str = "\
+CREATE TABLE users (\
+  id TEXT PRIMARY KEY,\
+  email TEXT NOT NULL UNIQUE\
+);\
+CREATE TABLE user_device (\
+  device_id TEXT PRIMARY KEY,\
+  user_id TEXT NOT NULL,\
+  mls_signature_pub BLOB,\
+  revoked_at TEXT\
+);\
+CREATE TABLE account_recovery (\
+  user_id TEXT PRIMARY KEY,\
+  wrapped_key BLOB NOT NULL\
+);\
+CREATE TABLE security_event (\
+  id TEXT PRIMARY KEY,\
+  user_id TEXT NOT NULL,\
+  kind TEXT NOT NULL,\
+  device_id TEXT,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  metadata TEXT\
+);\
+CREATE TABLE inactivity_policy (\
+  user_id TEXT PRIMARY KEY,\
+  inactive_days INTEGER NOT NULL,\
+  delete_recovery INTEGER NOT NULL DEFAULT 0,\
+  last_active_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  notified_at TEXT,\
+  recovery_deleted_at TEXT,\
+  updated_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);\
+INSERT INTO users (id, email) VALUES ('alice', 'alice@example.com'), ('bob', 'bob@example.com');\
+INSERT INTO account_recovery (user_id, wrapped_key) VALUES ('alice', x'00'), ('bob', x'00');";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+fn post(uri: &str, body: serde_json::Value) -> Request<Body> {
+    Request::builder()
+        .method("POST")
+        .uri(uri)
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap()
+}
+
+/// A POST to `path` signed by `user_id`'s device `device_id`.
+fn signed_post(path: &str, user_id: &str, device_id: &str, key: &SigningKey) -> Request<Body> {
+    let body = b"{}";
+    let ts = pollis_delivery::auth::now_unix();
+    let sig = key.sign(&canonical_message("POST", path, ts, body));
+    Request::builder()
+        .method("POST")
+        .uri(path)
+        .header("content-type", "application/json")
+        .header("X-Pollis-User", user_id)
+        .header("X-Pollis-Device", device_id)
+        .header("X-Pollis-Timestamp", ts.to_string())
+        .header(
+            "X-Pollis-Signature",
+            base64::engine::general_purpose::STANDARD.encode(sig.to_bytes()),
+        )
+        .body(Body::from(body.to_vec()))
+        .unwrap()
+}
+
+async fn exec(db: &Db, sql: &str) {
+    db.conn().unwrap().execute_batch(sql).await.unwrap();
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+fn config() -> InactivityConfig {
+    InactivityConfig {
+        grace_days: 14,
+        ..InactivityConfig::default()
+    }
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn set_policy_validates_range_and_upserts() {
+    let db = fresh_db().await;
+    // Auth off: the no-auth path takes the actor from the body.
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false));
+
+    let resp = router
+        .clone()
+        .oneshot(post(
+            "/v1/account/inactivity-policy",
+            serde_json::json!({ "user_id": "alice", "inactive_days": 7 }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+
+    let resp = router
+        .clone()
+        .oneshot(post(
+            "/v1/account/inactivity-policy",
+            serde_json::json!({ "user_id": "alice", "inactive_days": 90, "delete_recovery": true }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM inactivity_policy \
+             WHERE user_id = 'alice' AND inactive_days = 90 AND delete_recovery = 1"
+        )
+        .await,
+        1
+    );
+
+    let resp = router
+        .oneshot(post(
+            "/v1/account/inactivity-policy",
+            serde_json::json!({ "user_id": "alice", "inactive_days": null }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM inactivity_policy").await,
+        0
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn unsent_warning_is_not_recorded() {
+    let db = fresh_db().await;
+    exec(
+        &db,
+        "INSERT INTO inactivity_policy (user_id, inactive_days, delete_recovery, last_active_at) \
+         VALUES ('alice', 30, 1, datetime('now', '-60 days'));",
+    )
+    .await;
+
+    // No Resend key: the warning can't go out, so it must not be stamped —
+    // otherwise the deletion would follow a warning nobody received.
+    let report = sweep_once(&db, &config()).await.unwrap();
+    assert_eq!(report, SweepReport::default());
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM inactivity_policy WHERE notified_at IS NOT NULL"
+        )
+        .await,
+        0
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn unanswered_warning_deletes_recovery_after_grace_unless_checked_in() {
+    let db = fresh_db().await;
+    exec(
+        &db,
+        "INSERT INTO inactivity_policy \
+            (user_id, inactive_days, delete_recovery, last_active_at, notified_at) \
+         VALUES \
+            ('alice', 30, 1, datetime('now', '-60 days'), datetime('now', '-20 days')), \
+            ('bob', 30, 1, datetime('now', '-60 days'), datetime('now', '-20 days'));",
+    )
+    .await;
+
+    // Bob unlocks a device before the sweep: his warning is cancelled.
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false));
+    let resp = router
+        .oneshot(post(
+            "/v1/account/check-in",
+            serde_json::json!({ "user_id": "bob" }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+
+    let report = sweep_once(&db, &config()).await.unwrap();
+    assert_eq!(
+        report,
+        SweepReport {
+            warned: 0,
+            recovery_deleted: 1
+        }
+    );
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM account_recovery WHERE user_id = 'alice'"
+        )
+        .await,
+        0
+    );
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM account_recovery WHERE user_id = 'bob'"
+        )
+        .await,
+        1
+    );
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM security_event \
+             WHERE user_id = 'alice' AND kind = 'recovery_deleted_inactive'"
+        )
+        .await,
+        1
+    );
+
+    // A second sweep doesn't repeat the deletion.
+    let report = sweep_once(&db, &config()).await.unwrap();
+    assert_eq!(report, SweepReport::default());
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_signed_write_counts_as_activity() {
+    let db = fresh_db().await;
+    let mut secret = [0u8; 32];
+    OsRng.fill_bytes(&mut secret);
+    let key = SigningKey::from_bytes(&secret);
+    let conn = db.conn().unwrap();
+    conn.execute(
+        "INSERT INTO user_device (device_id, user_id, mls_signature_pub) VALUES ('d1', 'alice', ?1)",
+        libsql::params![key.verifying_key().to_bytes().to_vec()],
+    )
+    .await
+    .unwrap();
+    exec(
+        &db,
+        "INSERT INTO inactivity_policy \
+            (user_id, inactive_days, delete_recovery, last_active_at, notified_at) \
+         VALUES ('alice', 30, 1, datetime('now', '-40 days'), datetime('now', '-5 days'));",
+    )
+    .await;
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), true));
+
+    // The pin itself is refused (no body), but the device signed it: Alice is
+    // around, so the stamp moves and the pending warning is cancelled.
+    let resp = router
+        .clone()
+        .oneshot(signed_post("/v1/pins/add", "alice", "d1", &key))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+    let recent = "SELECT COUNT(*) FROM inactivity_policy \
+                  WHERE last_active_at > datetime('now', '-1 minutes') AND notified_at IS NULL";
+    assert_eq!(count(&db, recent).await, 1);
+
+    // Throttled: another write within the hour doesn't stamp again.
+    exec(
+        &db,
+        "UPDATE inactivity_policy SET last_active_at = datetime('now', '-40 days');",
+    )
+    .await;
+    router
+        .oneshot(signed_post("/v1/pins/add", "alice", "d1", &key))
+        .await
+        .unwrap();
+    assert_eq!(count(&db, recent).await, 0);
+}
+
+#[test]
+fn activity_stamps_once_an_hour_per_user() {
+    let stamps = ActivityStamps::default();
+    assert!(stamps.due("alice", 1_000));
+    assert!(!stamps.due("alice", 1_000 + 3_599));
+    assert!(stamps.due("bob", 1_000 + 3_599));
+    assert!(stamps.due("alice", 1_000 + 3_600));
+}
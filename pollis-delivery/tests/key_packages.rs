@@ -24,7 +24,8 @@ CREATE TABLE mls_key_package (\
   key_package BLOB NOT NULL,\
   claimed     INTEGER NOT NULL DEFAULT 0,\
   created_at  TEXT NOT NULL DEFAULT (datetime('now')),\
-  device_id   TEXT\
+  device_id   TEXT,\
+  claimed_at  TEXT\
 );";
 
 async fn fresh_db() -> Arc<Db> {
@@ -162,15 +163,17 @@ async fn concurrent_claims_of_one_package_yield_exactly_one_winner() {
     assert_eq!(claimed, 1, "exactly one claimer may win the single package");
     assert_eq!(none, 7, "every other claimer must see no package — no double-claim");
 
-    // The row is marked claimed exactly once.
+    // The row is marked claimed exactly once, with the claim time stamped for
+    // the owner's pool sizing.
     let conn = db.conn().unwrap();
     let mut rows = conn
         .query(
-            "SELECT claimed FROM mls_key_package WHERE ref_hash = ?1",
+            "SELECT claimed, claimed_at FROM mls_key_package WHERE ref_hash = ?1",
             libsql::params!["ref-solo"],
         )
         .await
         .unwrap();
     let row = rows.next().await.unwrap().expect("row exists");
     assert_eq!(row.get::<i64>(0).unwrap(), 1);
+    assert!(row.get::<Option<String>>(1).unwrap().is_some());
 }
@@ -0,0 +1,173 @@
+//! Signed identity rotation (`apply_rotate_identity` with `rotation_sig`,
+//! migration 000020): a rotation signed by the key at `based_on_version` is
+//! appended with its signature; one signed by any other key, or over another
+//! statement, is refused before the CAS and writes nothing. An unsigned
+//! rotation (the soft reset) still goes through with a NULL signature.
+
+use std::sync::Arc;
+
+use base64::Engine as _;
+use ed25519_dalek::{Signer as _, SigningKey};
+use pollis_delivery::account::{apply_rotate_identity, RotateIdentityBody, RotateOutcome};
+use pollis_delivery::db::Db;
+
+// Just the tables the rotation touches.
+const SCHEMA: &str = "\
+CREATE TABLE users (\
+  id TEXT PRIMARY KEY,\
+  account_id_pub BLOB,\
+  identity_version INTEGER NOT NULL DEFAULT 1\
+);\
+CREATE TABLE account_key_log (\
+  seq INTEGER PRIMARY KEY AUTOINCREMENT,\
+  user_id TEXT NOT NULL,\
+  account_id_pub BLOB NOT NULL,\
+  identity_version INTEGER NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  rotation_sig BLOB\
+);\
+CREATE UNIQUE INDEX idx_account_key_log_user_version \
+  ON account_key_log (user_id, identity_version);\
+CREATE TABLE account_recovery (\
+  user_id TEXT PRIMARY KEY,\
+  identity_version INTEGER NOT NULL,\
+  salt BLOB NOT NULL,\
+  nonce BLOB NOT NULL,\
+  wrapped_key BLOB NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  updated_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);";
+
+fn key(seed: u8) -> SigningKey {
+    SigningKey::from_bytes(&[seed; 32])
+}
+
+/// Fresh DB with `alice` at version 1 under `key(1)`.
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    let conn = db.conn().unwrap();
+    conn.execute_batch(SCHEMA).await.expect("schema");
+    let pub1 = key(1).verifying_key().to_bytes().to_vec();
+    conn.execute(
+        "INSERT INTO users (id, account_id_pub, identity_version) VALUES ('alice', ?1, 1)",
+        libsql::params![pub1.clone()],
+    )
+    .await
+    .unwrap();
+    conn.execute(
+        "INSERT INTO account_key_log (user_id, account_id_pub, identity_version) \
+         VALUES ('alice', ?1, 1)",
+        libsql::params![pub1],
+    )
+    .await
+    .unwrap();
+    Arc::new(db)
+}
+
+/// The statement pollis-core's `rotation_signed_payload` builds, spelled out.
+fn statement(user_id: &str, new_version: u32, old: &SigningKey, new: &SigningKey) -> Vec<u8> {
+    let mut out = b"pollis-account-key-rotation-v1\x00".to_vec();
+    out.push(user_id.len() as u8);
+    out.extend_from_slice(user_id.as_bytes());
+    out.extend_from_slice(&new_version.to_be_bytes());
+    out.extend_from_slice(&old.verifying_key().to_bytes());
+    out.extend_from_slice(&new.verifying_key().to_bytes());
+    out
+}
+
+fn rotate(based_on: i64, new: &SigningKey, sig: Option<Vec<u8>>) -> RotateIdentityBody {
+    let b64 = base64::engine::general_purpose::STANDARD;
+    RotateIdentityBody {
+        based_on_version: based_on,
+        account_id_pub: b64.encode(new.verifying_key().to_bytes()),
+        salt: b64.encode([0u8; 32]),
+        nonce: b64.encode([0u8; 12]),
+        wrapped_key: b64.encode([0u8; 48]),
+        rotation_sig: sig.map(|s| b64.encode(s)),
+        user_id: None,
+    }
+}
+
+async fn log(db: &Db) -> Vec<(i64, Option<Vec<u8>>)> {
+    let conn = db.conn().unwrap();
+    let mut rows = conn
+        .query(
+            "SELECT identity_version, rotation_sig FROM account_key_log \
+             WHERE user_id = 'alice' ORDER BY identity_version",
+            (),
+        )
+        .await
+        .unwrap();
+    let mut out = Vec::new();
+    while let Some(row) = rows.next().await.unwrap() {
+        out.push((row.get(0).unwrap(), row.get::<Option<Vec<u8>>>(1).unwrap()));
+    }
+    out
+}
+
+#[tokio::test]
+async fn signed_rotation_is_stored_with_its_signature() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    let sig = key(1)
+        .sign(&statement("alice", 2, &key(1), &key(2)))
+        .to_bytes()
+        .to_vec();
+
+    let outcome =
+        apply_rotate_identity(&conn, Some("alice"), &rotate(1, &key(2), Some(sig.clone())))
+            .await
+            .unwrap();
+    assert!(matches!(outcome, RotateOutcome::Applied { new_version: 2 }));
+    assert_eq!(log(&db).await, vec![(1, None), (2, Some(sig))]);
+}
+
+#[tokio::test]
+async fn rotation_signed_by_the_wrong_key_writes_nothing() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    // Signed by the new key instead of the one it replaces.
+    let self_signed = key(2)
+        .sign(&statement("alice", 2, &key(1), &key(2)))
+        .to_bytes()
+        .to_vec();
+    let outcome =
+        apply_rotate_identity(&conn, Some("alice"), &rotate(1, &key(2), Some(self_signed)))
+            .await
+            .unwrap();
+    assert!(matches!(outcome, RotateOutcome::BadSignature));
+
+    // Right key, but over a different version: not replayable elsewhere.
+    let wrong_version = key(1)
+        .sign(&statement("alice", 3, &key(1), &key(2)))
+        .to_bytes()
+        .to_vec();
+    let outcome = apply_rotate_identity(
+        &conn,
+        Some("alice"),
+        &rotate(1, &key(2), Some(wrong_version)),
+    )
+    .await
+    .unwrap();
+    assert!(matches!(outcome, RotateOutcome::BadSignature));
+
+    assert_eq!(log(&db).await, vec![(1, None)]);
+}
+
+#[tokio::test]
+async fn unsigned_reset_still_rotates() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    let outcome = apply_rotate_identity(&conn, Some("alice"), &rotate(1, &key(2), None))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, RotateOutcome::Applied { new_version: 2 }));
+    assert_eq!(log(&db).await, vec![(1, None), (2, None)]);
+}
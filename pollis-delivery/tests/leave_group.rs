@@ -0,0 +1,196 @@
+//! `apply_leave_group` against a local libsql DB: the leaver's membership,
+//! their read watermarks in the group's channels and any ownership hand-off
+//! naming them go, everyone else's stay, and the last member out deletes the
+//! group. Through the router, leaving also purges their undelivered Welcomes.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use pollis_delivery::db::Db;
+use pollis_delivery::groups::{apply_leave_group, LeaveGroupBody};
+use pollis_delivery::writes::WriteOutcome;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// Just the tables the leave path touches.
+const SCHEMA: &str = "\
+CREATE TABLE groups (id TEXT PRIMARY KEY, name TEXT NOT NULL);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE channels (id TEXT PRIMARY KEY, group_id TEXT NOT NULL);\
+CREATE TABLE conversation_watermark (\
+  conversation_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  device_id TEXT NOT NULL,\
+  last_fetched_at TEXT NOT NULL,\
+  PRIMARY KEY (conversation_id, user_id, device_id)\
+);\
+CREATE TABLE group_ownership_transfer (\
+  group_id TEXT PRIMARY KEY,\
+  from_user_id TEXT NOT NULL,\
+  to_user_id TEXT NOT NULL\
+);\
+CREATE TABLE mls_welcome (\
+  id TEXT PRIMARY KEY,\
+  conversation_id TEXT NOT NULL,\
+  recipient_id TEXT NOT NULL,\
+  delivered INTEGER NOT NULL DEFAULT 0\
+);\
+INSERT INTO groups (id, name) VALUES ('g1', 'one'), ('g2', 'two');\
+INSERT INTO group_member (group_id, user_id) VALUES ('g1', 'alice'), ('g1', 'bob'), ('g2', 'alice');\
+INSERT INTO channels (id, group_id) VALUES ('c1', 'g1'), ('c2', 'g2');\
+INSERT INTO conversation_watermark VALUES \
+  ('c1', 'alice', 'd1', '2026-01-01'), ('c1', 'alice', 'd2', '2026-01-01'), \
+  ('c1', 'bob', 'd1', '2026-01-01'), ('c2', 'alice', 'd1', '2026-01-01');";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+fn leave(group: &str, user: &str) -> LeaveGroupBody {
+    LeaveGroupBody {
+        group_id: group.into(),
+        user_id: Some(user.into()),
+    }
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn leave_drops_only_the_leavers_watermarks_in_that_group() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    let outcome = apply_leave_group(&conn, Some("alice"), &leave("g1", "alice"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM conversation_watermark WHERE user_id = 'alice' AND conversation_id = 'c1'"
+        )
+        .await,
+        0
+    );
+    // Bob's watermark in the same channel and Alice's in her other group stay.
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM conversation_watermark").await,
+        2
+    );
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM groups").await, 2);
+
+    // Bob is the last one out of g1.
+    apply_leave_group(&conn, Some("bob"), &leave("g1", "bob"))
+        .await
+        .unwrap();
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM groups WHERE id = 'g1'").await,
+        0
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn non_member_cannot_leave() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    let outcome = apply_leave_group(&conn, Some("bob"), &leave("g2", "bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM conversation_watermark").await,
+        4
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn leave_drops_a_hand_off_naming_the_leaver() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    conn.execute_batch(
+        "INSERT INTO group_member (group_id, user_id) VALUES ('g2', 'bob');\
+         INSERT INTO group_ownership_transfer (group_id, from_user_id, to_user_id) VALUES \
+           ('g1', 'alice', 'bob'), ('g2', 'alice', 'bob');",
+    )
+    .await
+    .unwrap();
+
+    apply_leave_group(&conn, Some("bob"), &leave("g1", "bob"))
+        .await
+        .unwrap();
+    // Bob can't take over g1 any more; the g2 proposal is still his to answer.
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM group_ownership_transfer WHERE group_id = 'g1'"
+        )
+        .await,
+        0
+    );
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_ownership_transfer").await,
+        1
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn leave_purges_the_leavers_undelivered_welcomes() {
+    let db = fresh_db().await;
+    db.conn()
+        .unwrap()
+        .execute_batch(
+            "INSERT INTO mls_welcome (id, conversation_id, recipient_id, delivered) VALUES \
+               ('w1', 'g1', 'bob', 0), ('w2', 'g1', 'bob', 1), \
+               ('w3', 'g1', 'alice', 0), ('w4', 'g2', 'bob', 0);",
+        )
+        .await
+        .unwrap();
+    // Auth off: the no-auth path takes the leaver from the body.
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false));
+
+    let req = Request::builder()
+        .method("POST")
+        .uri("/v1/groups/leave")
+        .header("content-type", "application/json")
+        .body(Body::from(
+            serde_json::to_vec(&serde_json::json!({ "group_id": "g1", "user_id": "bob" })).unwrap(),
+        ))
+        .unwrap();
+    let resp = router.oneshot(req).await.unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    // Only Bob's pending Welcome into g1 goes; delivered history stays.
+    let left: Vec<String> = {
+        let conn = db.conn().unwrap();
+        let mut rows = conn
+            .query("SELECT id FROM mls_welcome ORDER BY id", ())
+            .await
+            .unwrap();
+        let mut ids = Vec::new();
+        while let Some(row) = rows.next().await.unwrap() {
+            ids.push(row.get(0).unwrap());
+        }
+        ids
+    };
+    assert_eq!(left, ["w2", "w3", "w4"]);
+}
@@ -0,0 +1,188 @@
+//! Group size caps (`limits`), driven through the real axum router with
+//! `tower::oneshot` against a local libsql DB. A write that would cross a cap
+//! is a `409` with a `limit_exceeded` body and leaves the DB untouched; the
+//! caps themselves are served at `GET /v1/limits`.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::limits::LimitsConfig;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// Just the tables the capped paths touch.
+const SCHEMA: &str = "\
+CREATE TABLE channels (\
+  id TEXT PRIMARY KEY,\
+  group_id TEXT NOT NULL,\
+  name TEXT NOT NULL,\
+  description TEXT,\
+  channel_type TEXT NOT NULL DEFAULT 'text',\
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE group_invite (\
+  id TEXT PRIMARY KEY,\
+  group_id TEXT NOT NULL,\
+  inviter_id TEXT NOT NULL,\
+  invitee_id TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  status TEXT NOT NULL DEFAULT 'pending'\
+);\
+CREATE TABLE conversation_watermark (\
+  conversation_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  device_id TEXT NOT NULL,\
+  last_fetched_at TEXT NOT NULL,\
+  PRIMARY KEY (conversation_id, user_id, device_id)\
+);\
+CREATE TABLE user_device (\
+  device_id TEXT PRIMARY KEY,\
+  user_id TEXT NOT NULL\
+);";
+
+const TINY: LimitsConfig = LimitsConfig {
+    max_members_per_group: 2,
+    max_channels_per_group: 1,
+    max_groups_per_user: 0,
+    max_pins_per_conversation: 1,
+};
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap()).await.expect("local db");
+    db.conn().unwrap().execute_batch(SCHEMA).await.expect("schema");
+    db.conn()
+        .unwrap()
+        .execute_batch(
+            "INSERT INTO group_member (group_id, user_id, role) VALUES \
+               ('g1', 'alice', 'admin'), ('g1', 'bob', 'member');\
+             INSERT INTO channels (id, group_id, name) VALUES ('c1', 'g1', 'general');\
+             INSERT INTO group_invite (id, group_id, inviter_id, invitee_id) \
+               VALUES ('i1', 'g1', 'alice', 'carol');",
+        )
+        .await
+        .unwrap();
+    Arc::new(db)
+}
+
+fn post(uri: &str, body: serde_json::Value) -> Request<Body> {
+    Request::builder()
+        .method("POST")
+        .uri(uri)
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap()
+}
+
+async fn body_json(resp: axum::response::Response) -> serde_json::Value {
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    serde_json::from_slice(&bytes).unwrap()
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn channel_create_over_cap_is_409() {
+    let db = fresh_db().await;
+    // Auth off: the no-auth path takes the actor from the body.
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false).with_limits_config(TINY));
+
+    let resp = router
+        .oneshot(post(
+            "/v1/channels/create",
+            serde_json::json!({
+                "id": "c2",
+                "group_id": "g1",
+                "name": "random",
+                "channel_type": "text",
+                "creator_id": "alice",
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::CONFLICT);
+    let body = body_json(resp).await;
+    assert_eq!(body["error"], "limit_exceeded");
+    assert_eq!(body["limit"], "max_channels_per_group");
+    assert_eq!(body["max"], 1);
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM channels").await, 1);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn invite_accept_into_full_group_is_409() {
+    let db = fresh_db().await;
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false).with_limits_config(TINY));
+
+    let resp = router
+        .oneshot(post(
+            "/v1/invites/accept",
+            serde_json::json!({ "invite_id": "i1", "user_id": "carol" }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::CONFLICT);
+    assert_eq!(body_json(resp).await["limit"], "max_members_per_group");
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_member WHERE user_id = 'carol'").await,
+        0
+    );
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_invite").await,
+        1,
+        "the invite survives so it can be accepted once there's room"
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn zero_cap_is_unlimited() {
+    let db = fresh_db().await;
+    let unlimited = LimitsConfig {
+        max_members_per_group: 0,
+        max_channels_per_group: 0,
+        max_groups_per_user: 0,
+        max_pins_per_conversation: 0,
+    };
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false).with_limits_config(unlimited));
+
+    let resp = router
+        .oneshot(post(
+            "/v1/invites/accept",
+            serde_json::json!({ "invite_id": "i1", "user_id": "carol" }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_member WHERE user_id = 'carol'").await,
+        1
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn limits_endpoint_serves_config() {
+    let db = fresh_db().await;
+    let router = build_router_with_state(AppState::new(db, false).with_limits_config(TINY));
+
+    let req = Request::builder().uri("/v1/limits").body(Body::empty()).unwrap();
+    let resp = router.oneshot(req).await.unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    let body = body_json(resp).await;
+    assert_eq!(body["max_members_per_group"], 2);
+    assert_eq!(body["max_channels_per_group"], 1);
+    assert_eq!(body["max_groups_per_user"], 0);
+}
@@ -0,0 +1,209 @@
+//! Maintenance mode (`maintenance`), driven through the real axum router with
+//! `tower::oneshot` against a local libsql DB. While the window is open writes
+//! are a `503 MAINTENANCE` with a `Retry-After` and leave the DB untouched;
+//! reads still answer; the admin route closes the window live.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{header, Request, StatusCode};
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::maintenance::{MaintenanceConfig, MaintenanceStatus};
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// Just the tables a channel create touches.
+const SCHEMA: &str = "\
+CREATE TABLE channels (\
+  id TEXT PRIMARY KEY,\
+  group_id TEXT NOT NULL,\
+  name TEXT NOT NULL,\
+  description TEXT,\
+  channel_type TEXT NOT NULL DEFAULT 'text',\
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);";
+
+const TOKEN: &str = "op-secret";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    db.conn()
+        .unwrap()
+        .execute_batch(
+            "INSERT INTO group_member (group_id, user_id, role) VALUES ('g1', 'alice', 'admin');",
+        )
+        .await
+        .unwrap();
+    Arc::new(db)
+}
+
+fn in_maintenance(eta: Option<i64>) -> MaintenanceConfig {
+    MaintenanceConfig {
+        initial: MaintenanceStatus {
+            enabled: true,
+            eta,
+            message: Some("moving databases".into()),
+        },
+        admin_token: Some(TOKEN.into()),
+    }
+}
+
+fn post(uri: &str, body: serde_json::Value) -> Request<Body> {
+    Request::builder()
+        .method("POST")
+        .uri(uri)
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap()
+}
+
+fn create_channel() -> Request<Body> {
+    post(
+        "/v1/channels/create",
+        serde_json::json!({
+            "id": "c1",
+            "group_id": "g1",
+            "name": "general",
+            "channel_type": "text",
+            "creator_id": "alice",
+        }),
+    )
+}
+
+async fn body_json(resp: axum::response::Response) -> serde_json::Value {
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    serde_json::from_slice(&bytes).unwrap()
+}
+
+async fn channel_count(db: &Db) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn
+        .query("SELECT COUNT(*) FROM channels", ())
+        .await
+        .unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn writes_are_refused_reads_still_answer() {
+    let db = fresh_db().await;
+    let eta = pollis_delivery::auth::now_unix() + 600;
+    let router = build_router_with_state(
+        AppState::new(Arc::clone(&db), false).with_maintenance_config(in_maintenance(Some(eta))),
+    );
+
+    let resp = router.clone().oneshot(create_channel()).await.unwrap();
+    assert_eq!(resp.status(), StatusCode::SERVICE_UNAVAILABLE);
+    assert_eq!(resp.headers()["x-pollis-maintenance"], "1");
+    let retry: i64 = resp.headers()[header::RETRY_AFTER]
+        .to_str()
+        .unwrap()
+        .parse()
+        .unwrap();
+    assert!(
+        (590..=600).contains(&retry),
+        "Retry-After counts down to the ETA, got {retry}"
+    );
+    let body = body_json(resp).await;
+    assert_eq!(body["error"], "MAINTENANCE");
+    assert_eq!(body["eta"], eta);
+    assert_eq!(body["message"], "moving databases");
+    assert_eq!(channel_count(&db).await, 0);
+
+    let req = Request::builder()
+        .uri("/v1/limits")
+        .body(Body::empty())
+        .unwrap();
+    assert_eq!(
+        router.clone().oneshot(req).await.unwrap().status(),
+        StatusCode::OK
+    );
+
+    let req = Request::builder()
+        .uri("/v1/maintenance")
+        .body(Body::empty())
+        .unwrap();
+    let body = body_json(router.oneshot(req).await.unwrap()).await;
+    assert_eq!(body["enabled"], true);
+    assert_eq!(body["eta"], eta);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn admin_route_closes_the_window() {
+    let db = fresh_db().await;
+    let router = build_router_with_state(
+        AppState::new(Arc::clone(&db), false).with_maintenance_config(in_maintenance(None)),
+    );
+
+    let mut req = post(
+        "/v1/admin/maintenance",
+        serde_json::json!({ "enabled": false }),
+    );
+    req.headers_mut()
+        .insert(header::AUTHORIZATION, "Bearer wrong".parse().unwrap());
+    assert_eq!(
+        router.clone().oneshot(req).await.unwrap().status(),
+        StatusCode::UNAUTHORIZED
+    );
+    assert_eq!(
+        router
+            .clone()
+            .oneshot(create_channel())
+            .await
+            .unwrap()
+            .status(),
+        StatusCode::SERVICE_UNAVAILABLE
+    );
+
+    let mut req = post(
+        "/v1/admin/maintenance",
+        serde_json::json!({ "enabled": false }),
+    );
+    req.headers_mut().insert(
+        header::AUTHORIZATION,
+        format!("Bearer {TOKEN}").parse().unwrap(),
+    );
+    let resp = router.clone().oneshot(req).await.unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    assert_eq!(body_json(resp).await["enabled"], false);
+
+    assert_eq!(
+        router.oneshot(create_channel()).await.unwrap().status(),
+        StatusCode::OK
+    );
+    assert_eq!(channel_count(&db).await, 1);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn admin_route_needs_a_configured_token() {
+    let db = fresh_db().await;
+    let router = build_router_with_state(AppState::new(db, false));
+
+    let mut req = post(
+        "/v1/admin/maintenance",
+        serde_json::json!({ "enabled": true }),
+    );
+    req.headers_mut()
+        .insert(header::AUTHORIZATION, "Bearer anything".parse().unwrap());
+    assert_eq!(
+        router.oneshot(req).await.unwrap().status(),
+        StatusCode::SERVICE_UNAVAILABLE
+    );
+}
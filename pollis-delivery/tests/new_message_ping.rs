@@ -0,0 +1,159 @@
+//! The DS-side `new_message` ping (`messages::new_message_ping`) must route and
+//! shape exactly like the client publish it replaces
+//! (`livekit_signalling::new_message_payload`): receivers filter on these
+//! fields, so a drift here silently drops wake-ups.
+//!
+//! The send response only carries `notified: true` once LiveKit has taken the
+//! ping; otherwise the client publishes it itself.
+
+use std::sync::Arc;
+
+use axum::body::{Body, Bytes};
+use axum::http::{Request, StatusCode};
+use axum::routing::post as post_route;
+use axum::Router;
+use http_body_util::BodyExt as _;
+use pollis_delivery::broker::BrokerConfig;
+use pollis_delivery::db::Db;
+use pollis_delivery::messages::new_message_ping;
+use pollis_delivery::{build_router_with_state, AppState};
+use serde_json::json;
+use tokio::sync::mpsc;
+use tower::ServiceExt as _;
+
+// Just the tables the send path and the room lookup touch.
+const SCHEMA: &str = "\
+CREATE TABLE message_envelope (\
+  id TEXT PRIMARY KEY,\
+  conversation_id TEXT NOT NULL,\
+  sender_id TEXT NOT NULL,\
+  ciphertext TEXT NOT NULL,\
+  reply_to_id TEXT,\
+  sent_at TEXT NOT NULL,\
+  delivered INTEGER NOT NULL DEFAULT 0,\
+  type TEXT NOT NULL DEFAULT 'message',\
+  target_message_id TEXT,\
+  sealed INTEGER NOT NULL DEFAULT 0\
+);\
+CREATE TABLE channels (id TEXT PRIMARY KEY, group_id TEXT NOT NULL);\
+INSERT INTO channels (id, group_id) VALUES ('chan-1', 'group-1');";
+
+#[test]
+fn channel_pings_its_group_room() {
+    let (room, payload) = new_message_ping("chan-1", Some("group-1".to_string()));
+    assert_eq!(room, "group-1");
+    assert_eq!(
+        payload,
+        json!({ "type": "new_message", "channel_id": "chan-1", "conversation_id": null })
+    );
+}
+
+#[test]
+fn dm_pings_its_own_room() {
+    let (room, payload) = new_message_ping("dm-1", None);
+    assert_eq!(room, "dm-1");
+    assert_eq!(
+        payload,
+        json!({ "type": "new_message", "channel_id": null, "conversation_id": "dm-1" })
+    );
+}
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+/// A stand-in LiveKit server answering every SendData with `status`; each
+/// request body is forwarded to the returned channel.
+async fn livekit(status: StatusCode) -> (BrokerConfig, mpsc::UnboundedReceiver<Bytes>) {
+    let (tx, rx) = mpsc::unbounded_channel();
+    let app = Router::new().route(
+        "/twirp/livekit.RoomService/SendData",
+        post_route(move |body: Bytes| {
+            let tx = tx.clone();
+            async move {
+                let _ = tx.send(body);
+                status
+            }
+        }),
+    );
+    let listener = tokio::net::TcpListener::bind("127.0.0.1:0").await.unwrap();
+    let addr = listener.local_addr().unwrap();
+    tokio::spawn(async move {
+        axum::serve(listener, app).await.unwrap();
+    });
+    let config = BrokerConfig {
+        livekit_api_key: Some("key".to_string()),
+        livekit_api_secret: Some("secret".to_string()),
+        livekit_url: Some(format!("ws://{addr}")),
+        ..BrokerConfig::default()
+    };
+    (config, rx)
+}
+
+// Auth off: the no-auth path takes the sender from the body.
+async fn send(router: &Router, id: &str, conversation: &str) -> serde_json::Value {
+    let body = json!({
+        "id": id,
+        "conversation_id": conversation,
+        "sender_id": "alice",
+        "ciphertext": "mls:01",
+        "sent_at": "2026-01-01T00:00:00+00:00",
+    });
+    let req = Request::builder()
+        .method("POST")
+        .uri("/v1/messages/send")
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap();
+    let resp = router.clone().oneshot(req).await.unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    serde_json::from_slice(&bytes).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn notified_only_after_livekit_took_the_ping() {
+    let (config, mut sent) = livekit(StatusCode::OK).await;
+    let router = build_router_with_state(
+        AppState::new(fresh_db().await, false).with_broker_config(config),
+    );
+
+    let body = send(&router, "m1", "chan-1").await;
+    assert_eq!(body["notified"], true);
+    // Already delivered by the time the response came back.
+    let req: serde_json::Value = serde_json::from_slice(&sent.try_recv().unwrap()).unwrap();
+    assert_eq!(req["room"], "group-1");
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_failed_ping_leaves_the_client_to_publish() {
+    let (config, mut sent) = livekit(StatusCode::INTERNAL_SERVER_ERROR).await;
+    let router = build_router_with_state(
+        AppState::new(fresh_db().await, false).with_broker_config(config),
+    );
+
+    let body = send(&router, "m1", "dm-1").await;
+    assert_eq!(body["status"], "ok");
+    assert!(body.get("notified").is_none());
+    assert!(sent.try_recv().is_ok());
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn no_livekit_no_notified() {
+    let router = build_router_with_state(AppState::new(fresh_db().await, false));
+
+    let body = send(&router, "m1", "chan-1").await;
+    assert_eq!(body["status"], "ok");
+    assert!(body.get("notified").is_none());
+}
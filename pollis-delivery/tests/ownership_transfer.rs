@@ -0,0 +1,207 @@
+//! The two-step ownership hand-off against a local libsql DB: only the owner
+//! proposes, only the named target accepts, a proposal outlives its proposer's
+//! ownership only as a dead row, and accepting moves `owner_id` and the admin
+//! role together.
+
+use std::sync::Arc;
+
+use pollis_delivery::db::Db;
+use pollis_delivery::groups::{
+    apply_accept_ownership, apply_decline_ownership, apply_transfer_ownership,
+    OwnershipResponseBody, TransferOwnershipBody,
+};
+use pollis_delivery::writes::WriteOutcome;
+
+// Just the tables the transfer paths touch.
+const SCHEMA: &str = "\
+CREATE TABLE groups (id TEXT PRIMARY KEY, name TEXT NOT NULL, owner_id TEXT NOT NULL);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE group_ownership_transfer (\
+  group_id TEXT PRIMARY KEY,\
+  from_user_id TEXT NOT NULL,\
+  to_user_id TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  CHECK (from_user_id <> to_user_id)\
+);\
+INSERT INTO groups (id, name, owner_id) VALUES ('g1', 'one', 'alice');\
+INSERT INTO group_member (group_id, user_id, role) VALUES \
+  ('g1', 'alice', 'admin'), ('g1', 'bob', 'member'), ('g1', 'carol', 'member');";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+fn propose(requester: &str, new_owner: &str) -> TransferOwnershipBody {
+    TransferOwnershipBody {
+        group_id: "g1".into(),
+        new_owner_id: new_owner.into(),
+        requester_id: Some(requester.into()),
+    }
+}
+
+fn respond(user: &str) -> OwnershipResponseBody {
+    OwnershipResponseBody {
+        group_id: "g1".into(),
+        user_id: Some(user.into()),
+    }
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+async fn text(db: &Db, sql: &str) -> String {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn non_owner_cannot_propose() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    let outcome = apply_transfer_ownership(&conn, Some("bob"), &propose("bob", "carol"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_ownership_transfer").await,
+        0
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn only_the_target_can_accept() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    let outcome = apply_transfer_ownership(&conn, Some("alice"), &propose("alice", "bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+
+    // Carol is a member, but the proposal names Bob.
+    let outcome = apply_accept_ownership(&conn, Some("carol"), &respond("carol"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    assert_eq!(
+        text(&db, "SELECT owner_id FROM groups WHERE id = 'g1'").await,
+        "alice"
+    );
+    assert_eq!(
+        text(&db, "SELECT role FROM group_member WHERE user_id = 'carol'").await,
+        "member"
+    );
+    // Bob's proposal is still there to accept.
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_ownership_transfer").await,
+        1
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn accepting_after_the_proposer_lost_ownership_is_refused() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    apply_transfer_ownership(&conn, Some("alice"), &propose("alice", "bob"))
+        .await
+        .unwrap();
+    // The group changed hands some other way since Alice proposed.
+    conn.execute("UPDATE groups SET owner_id = 'carol' WHERE id = 'g1'", ())
+        .await
+        .unwrap();
+
+    let outcome = apply_accept_ownership(&conn, Some("bob"), &respond("bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    assert_eq!(
+        text(&db, "SELECT owner_id FROM groups WHERE id = 'g1'").await,
+        "carol"
+    );
+    // Nothing of the refused swap stuck.
+    assert_eq!(
+        text(&db, "SELECT role FROM group_member WHERE user_id = 'bob'").await,
+        "member"
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn accepting_moves_owner_and_admin_together() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    apply_transfer_ownership(&conn, Some("alice"), &propose("alice", "bob"))
+        .await
+        .unwrap();
+
+    let outcome = apply_accept_ownership(&conn, Some("bob"), &respond("bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(
+        text(&db, "SELECT owner_id FROM groups WHERE id = 'g1'").await,
+        "bob"
+    );
+    assert_eq!(
+        text(&db, "SELECT role FROM group_member WHERE user_id = 'bob'").await,
+        "admin"
+    );
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_ownership_transfer").await,
+        0
+    );
+    // Redeemed once; a second accept has nothing to redeem.
+    let outcome = apply_accept_ownership(&conn, Some("bob"), &respond("bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn declining_clears_the_proposal() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    apply_transfer_ownership(&conn, Some("alice"), &propose("alice", "bob"))
+        .await
+        .unwrap();
+
+    let outcome = apply_decline_ownership(&conn, Some("bob"), &respond("bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_ownership_transfer").await,
+        0
+    );
+    assert_eq!(
+        text(&db, "SELECT owner_id FROM groups WHERE id = 'g1'").await,
+        "alice"
+    );
+    // Nothing left to decline or accept.
+    let outcome = apply_decline_ownership(&conn, Some("bob"), &respond("bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    let outcome = apply_accept_ownership(&conn, Some("bob"), &respond("bob"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+}
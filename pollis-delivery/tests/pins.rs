@@ -0,0 +1,281 @@
+//! Pinned messages (`/v1/pins/*`): the per-conversation cap through the real
+//! axum router, and the authz in [`apply_pin_message`] / [`apply_unpin_message`]
+//! driven directly with an authenticated actor. Deleting a message or a channel
+//! takes its pins with it.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use pollis_delivery::db::Db;
+use pollis_delivery::groups::{apply_delete_channel, DeleteChannelBody};
+use pollis_delivery::limits::LimitsConfig;
+use pollis_delivery::messages::{
+    apply_delete_message, apply_pin_message, apply_pin_message_capped, apply_unpin_message,
+    DeleteMessageBody, PinBody, PinOutcome,
+};
+use pollis_delivery::writes::WriteOutcome;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// Just the tables the pin and delete paths touch.
+const SCHEMA: &str = "\
+CREATE TABLE channels (\
+  id TEXT PRIMARY KEY,\
+  group_id TEXT NOT NULL,\
+  name TEXT NOT NULL\
+);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE dm_channel_member (\
+  dm_channel_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  PRIMARY KEY (dm_channel_id, user_id)\
+);\
+CREATE TABLE message_envelope (\
+  id TEXT PRIMARY KEY,\
+  conversation_id TEXT NOT NULL,\
+  type TEXT NOT NULL DEFAULT 'message',\
+  target_message_id TEXT\
+);\
+CREATE TABLE conversation_watermark (\
+  conversation_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  device_id TEXT NOT NULL,\
+  last_fetched_at TEXT NOT NULL,\
+  PRIMARY KEY (conversation_id, user_id, device_id)\
+);\
+CREATE TABLE pinned_message (\
+  conversation_id TEXT NOT NULL,\
+  message_id TEXT NOT NULL,\
+  pinned_by TEXT NOT NULL,\
+  pinned_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  PRIMARY KEY (conversation_id, message_id)\
+);\
+INSERT INTO channels (id, group_id, name) VALUES ('c1', 'g1', 'general'), ('c2', 'g1', 'random');\
+INSERT INTO group_member (group_id, user_id, role) VALUES \
+  ('g1', 'alice', 'admin'), ('g1', 'bob', 'member'), ('g1', 'carol', 'member');\
+INSERT INTO dm_channel_member (dm_channel_id, user_id) VALUES ('dm1', 'alice'), ('dm1', 'bob');\
+INSERT INTO message_envelope (id, conversation_id) VALUES ('m1', 'c1'), ('m2', 'c1'), ('m3', 'c2');";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+fn post(uri: &str, body: serde_json::Value) -> Request<Body> {
+    Request::builder()
+        .method("POST")
+        .uri(uri)
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap()
+}
+
+fn pin(conversation_id: &str, message_id: &str) -> PinBody {
+    PinBody {
+        conversation_id: conversation_id.into(),
+        message_id: message_id.into(),
+        user_id: None,
+    }
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn pin_over_cap_is_409_but_repin_is_not() {
+    let db = fresh_db().await;
+    let one_pin = LimitsConfig {
+        max_pins_per_conversation: 1,
+        ..LimitsConfig::default()
+    };
+    // Auth off: the no-auth path takes the actor from the body.
+    let router =
+        build_router_with_state(AppState::new(Arc::clone(&db), false).with_limits_config(one_pin));
+
+    for (message_id, expected) in [
+        ("m1", StatusCode::OK),
+        ("m2", StatusCode::CONFLICT),
+        ("m1", StatusCode::OK),
+    ] {
+        let resp = router
+            .clone()
+            .oneshot(post(
+                "/v1/pins/add",
+                serde_json::json!({
+                    "conversation_id": "c1",
+                    "message_id": message_id,
+                    "user_id": "bob",
+                }),
+            ))
+            .await
+            .unwrap();
+        assert_eq!(resp.status(), expected, "{message_id}");
+    }
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM pinned_message").await, 1);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn pin_requires_membership_and_matching_conversation() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    // Dave isn't in the group.
+    let outcome = apply_pin_message(&conn, Some("dave"), &pin("c1", "m1"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    // m3 was sent to c2, so it can't be pinned in c1.
+    let outcome = apply_pin_message(&conn, Some("bob"), &pin("c1", "m3"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+
+    let outcome = apply_pin_message(&conn, Some("bob"), &pin("c1", "m1"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM pinned_message WHERE message_id = 'm1' AND pinned_by = 'bob'"
+        )
+        .await,
+        1
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn unpin_is_limited_to_pinner_or_admin() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    conn.execute_batch(
+        "INSERT INTO pinned_message (conversation_id, message_id, pinned_by) VALUES \
+           ('c1', 'm1', 'bob'), ('c1', 'm2', 'bob'), ('dm1', 'd1', 'bob');",
+    )
+    .await
+    .unwrap();
+
+    // Another plain member can't take bob's pin down.
+    let outcome = apply_unpin_message(&conn, Some("carol"), &pin("c1", "m1"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+    // A group admin can.
+    let outcome = apply_unpin_message(&conn, Some("alice"), &pin("c1", "m1"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    // So can the pinner.
+    let outcome = apply_unpin_message(&conn, Some("bob"), &pin("c1", "m2"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    // DMs have no admins: only the pinner.
+    let outcome = apply_unpin_message(&conn, Some("alice"), &pin("dm1", "d1"))
+        .await
+        .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Forbidden));
+
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM pinned_message").await, 1);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn capped_pin_fills_the_last_slot_once() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+
+    let outcome = apply_pin_message_capped(&conn, Some("bob"), &pin("c1", "m1"), 1)
+        .await
+        .unwrap();
+    assert!(matches!(outcome, PinOutcome::Written(WriteOutcome::Ok)));
+    let outcome = apply_pin_message_capped(&conn, Some("bob"), &pin("c1", "m2"), 1)
+        .await
+        .unwrap();
+    assert!(matches!(outcome, PinOutcome::AtCap));
+    // Re-pinning the one that holds the slot isn't over the cap.
+    let outcome = apply_pin_message_capped(&conn, Some("bob"), &pin("c1", "m1"), 1)
+        .await
+        .unwrap();
+    assert!(matches!(outcome, PinOutcome::Written(WriteOutcome::Ok)));
+    // The cap is per conversation.
+    let outcome = apply_pin_message_capped(&conn, Some("bob"), &pin("c2", "m3"), 1)
+        .await
+        .unwrap();
+    assert!(matches!(outcome, PinOutcome::Written(WriteOutcome::Ok)));
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM pinned_message").await, 2);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn deleting_a_message_or_channel_drops_its_pins() {
+    let db = fresh_db().await;
+    let conn = db.conn().unwrap();
+    conn.execute_batch(
+        "INSERT INTO pinned_message (conversation_id, message_id, pinned_by) VALUES \
+           ('c1', 'm1', 'bob'), ('c1', 'm2', 'bob'), ('c2', 'm3', 'bob');",
+    )
+    .await
+    .unwrap();
+
+    let outcome = apply_delete_message(
+        &conn,
+        Some("bob"),
+        &DeleteMessageBody {
+            message_id: "m1".into(),
+            conversation_id: "c1".into(),
+            msg_sender_id: Some("bob".into()),
+            actor_id: None,
+        },
+    )
+    .await
+    .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM pinned_message WHERE message_id = 'm1'"
+        )
+        .await,
+        0
+    );
+
+    let outcome = apply_delete_channel(
+        &conn,
+        Some("alice"),
+        &DeleteChannelBody {
+            channel_id: "c2".into(),
+            requester_id: None,
+        },
+    )
+    .await
+    .unwrap();
+    assert!(matches!(outcome, WriteOutcome::Ok));
+    // Only the pin on the surviving message in the surviving channel is left.
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM pinned_message").await, 1);
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM pinned_message WHERE message_id = 'm2'"
+        )
+        .await,
+        1
+    );
+}
@@ -0,0 +1,113 @@
+//! `POST /v1/members/remove`, driven through the real axum router with
+//! `tower::oneshot` against a local libsql DB. Beyond the membership delete,
+//! the removal must purge the member's undelivered Welcomes for that group and
+//! any pending ownership hand-off involving them, and leave everything else.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use pollis_delivery::db::Db;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// Just the tables the remove path touches. The commit log shares the main DB
+// here (`AppState::new`), as it does in single-DB deployments.
+const SCHEMA: &str = "\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE group_ownership_transfer (\
+  group_id TEXT PRIMARY KEY,\
+  from_user_id TEXT NOT NULL,\
+  to_user_id TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);\
+CREATE TABLE mls_welcome (\
+  id TEXT PRIMARY KEY,\
+  conversation_id TEXT NOT NULL,\
+  recipient_id TEXT NOT NULL,\
+  welcome_data BLOB NOT NULL,\
+  delivered INTEGER NOT NULL DEFAULT 0,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  recipient_device_id TEXT\
+);";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap()).await.expect("local db");
+    db.conn().unwrap().execute_batch(SCHEMA).await.expect("schema");
+    Arc::new(db)
+}
+
+fn remove_req(group: &str, user: &str, requester: &str) -> Request<Body> {
+    let body = serde_json::to_vec(&serde_json::json!({
+        "group_id": group,
+        "user_id": user,
+        "requester_id": requester,
+    }))
+    .unwrap();
+    Request::builder()
+        .method("POST")
+        .uri("/v1/members/remove")
+        .header("content-type", "application/json")
+        .body(Body::from(body))
+        .unwrap()
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn remove_purges_pending_welcomes_and_ownership_transfer() {
+    let db = fresh_db().await;
+    db.conn()
+        .unwrap()
+        .execute_batch(
+            "INSERT INTO group_member (group_id, user_id, role) VALUES \
+               ('g1', 'alice', 'admin'), ('g1', 'bob', 'member'), ('g2', 'bob', 'member');\
+             INSERT INTO group_ownership_transfer (group_id, from_user_id, to_user_id) \
+               VALUES ('g1', 'alice', 'bob');\
+             INSERT INTO mls_welcome (id, conversation_id, recipient_id, welcome_data, delivered) VALUES \
+               ('w1', 'g1', 'bob', x'01', 0),\
+               ('w2', 'g1', 'bob', x'02', 1),\
+               ('w3', 'g2', 'bob', x'03', 0),\
+               ('w4', 'g1', 'alice', x'04', 0);",
+        )
+        .await
+        .unwrap();
+    // Auth off: the no-auth path takes the actor from the body.
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false));
+
+    let resp = router.oneshot(remove_req("g1", "bob", "alice")).await.unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_member WHERE group_id = 'g1' AND user_id = 'bob'").await,
+        0
+    );
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM group_member WHERE group_id = 'g2' AND user_id = 'bob'").await,
+        1,
+        "membership in other groups is untouched"
+    );
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM group_ownership_transfer").await, 0);
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM mls_welcome WHERE id = 'w1'").await,
+        0,
+        "undelivered welcome into the group is purged"
+    );
+    assert_eq!(
+        count(&db, "SELECT COUNT(*) FROM mls_welcome WHERE id IN ('w2', 'w3', 'w4')").await,
+        3,
+        "delivered, other-group and other-member welcomes survive"
+    );
+}
@@ -0,0 +1,160 @@
+//! Replay protection on envelope writes (`replay`), driven through the real
+//! axum router with `tower::oneshot` against a local libsql DB. A resubmitted
+//! send or edit is a `409 REPLAYED`, writes nothing, doesn't feed the flood
+//! heuristics, and is counted at `GET /metrics`.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use axum::Router;
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::flood::FloodConfig;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+use ulid::Ulid;
+
+// Just the tables the send and edit paths touch.
+const SCHEMA: &str = "\
+CREATE TABLE message_envelope (\
+  id TEXT PRIMARY KEY,\
+  conversation_id TEXT NOT NULL,\
+  sender_id TEXT NOT NULL,\
+  ciphertext TEXT NOT NULL,\
+  reply_to_id TEXT,\
+  sent_at TEXT NOT NULL,\
+  delivered INTEGER NOT NULL DEFAULT 0,\
+  type TEXT NOT NULL DEFAULT 'message',\
+  target_message_id TEXT,\
+  sealed INTEGER NOT NULL DEFAULT 0\
+);\
+CREATE TABLE flood_incident (\
+  id TEXT PRIMARY KEY,\
+  user_id TEXT NOT NULL,\
+  conversation_id TEXT NOT NULL,\
+  reason TEXT NOT NULL,\
+  muted_until TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+// Auth off: the no-auth path takes the sender from the body.
+async fn post(router: &Router, uri: &str, body: serde_json::Value) -> axum::response::Response {
+    let req = Request::builder()
+        .method("POST")
+        .uri(uri)
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap();
+    router.clone().oneshot(req).await.unwrap()
+}
+
+fn send_body(id: &str, ciphertext: &str) -> serde_json::Value {
+    serde_json::json!({
+        "id": id,
+        "conversation_id": "c1",
+        "sender_id": "alice",
+        "ciphertext": ciphertext,
+        "sent_at": "2026-01-01T00:00:00+00:00",
+    })
+}
+
+fn edit_body(envelope_id: &str, ciphertext: &str) -> serde_json::Value {
+    serde_json::json!({
+        "envelope_id": envelope_id,
+        "conversation_id": "c1",
+        "target_message_id": "m1",
+        "sender_id": "alice",
+        "ciphertext": ciphertext,
+        "sent_at": "2026-01-01T00:00:00+00:00",
+    })
+}
+
+async fn body_json(resp: axum::response::Response) -> serde_json::Value {
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    serde_json::from_slice(&bytes).unwrap()
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn replayed_send_is_refused_without_a_flood_mute() {
+    let db = fresh_db().await;
+    // Any duplicate ciphertext would trip the flood detector.
+    let flood = FloodConfig {
+        duplicate_max: 1,
+        ..FloodConfig::default()
+    };
+    let router =
+        build_router_with_state(AppState::new(Arc::clone(&db), false).with_flood_config(flood));
+    let id = Ulid::new().to_string();
+
+    let resp = post(&router, "/v1/messages/send", send_body(&id, "mls:aa")).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    for _ in 0..3 {
+        let resp = post(&router, "/v1/messages/send", send_body(&id, "mls:aa")).await;
+        assert_eq!(resp.status(), StatusCode::CONFLICT);
+        let body = body_json(resp).await;
+        assert_eq!(body["error"], "REPLAYED");
+        assert_eq!(body["reason"], "replayed");
+    }
+
+    // The real sender is not muted.
+    let other = Ulid::new().to_string();
+    let resp = post(&router, "/v1/messages/send", send_body(&other, "mls:bb")).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM message_envelope").await, 2);
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM flood_incident").await, 0);
+
+    let req = Request::builder()
+        .uri("/metrics")
+        .body(Body::empty())
+        .unwrap();
+    let resp = router.clone().oneshot(req).await.unwrap();
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    let text = String::from_utf8(bytes.to_vec()).unwrap();
+    assert!(text.contains("pollis_ds_envelope_replays_rejected_total{reason=\"replayed\"} 3"));
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn replayed_edit_cannot_roll_back_a_newer_one() {
+    let db = fresh_db().await;
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false));
+    let first = Ulid::new().to_string();
+    let second = Ulid::new().to_string();
+
+    let resp = post(&router, "/v1/messages/edit", edit_body(&first, "mls:v1")).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    let resp = post(&router, "/v1/messages/edit", edit_body(&second, "mls:v2")).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+
+    let resp = post(&router, "/v1/messages/edit", edit_body(&first, "mls:v1")).await;
+    assert_eq!(resp.status(), StatusCode::CONFLICT);
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM message_envelope WHERE type = 'edit' AND ciphertext = 'mls:v2'"
+        )
+        .await,
+        1
+    );
+}
@@ -44,7 +44,8 @@ CREATE TABLE account_key_log (\
   user_id TEXT NOT NULL,\
   account_id_pub BLOB NOT NULL,\
   identity_version INTEGER NOT NULL,\
-  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  rotation_sig BLOB\
 );\
 CREATE UNIQUE INDEX idx_account_key_log_user_version \
   ON account_key_log (user_id, identity_version);\
@@ -0,0 +1,265 @@
+//! Per-group attachment residency (`residency`), driven through the real axum
+//! router: declared storage targets are listed without credentials, a group
+//! is pinned through `/v1/groups/update`, and `/v1/r2/presign` signs
+//! `media@<target>/…` keys for that target and holds attachment PUTs to the
+//! group's policy — including ones that try to leave the group out.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use axum::Router;
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::residency::{StorageTarget, StorageTargets};
+use pollis_delivery::storage::{FsStorage, ObjectStorage};
+use pollis_delivery::uploads::UploadPolicy;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// `groups` as far as the update and presign paths read it, plus migration
+// 000025, and the membership tables the presign checks.
+const SCHEMA: &str = "\
+CREATE TABLE groups (\
+  id TEXT PRIMARY KEY,\
+  name TEXT NOT NULL,\
+  description TEXT,\
+  icon_url TEXT,\
+  owner_id TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  allow_export INTEGER NOT NULL DEFAULT 1,\
+  storage_target TEXT\
+);\
+INSERT INTO groups (id, name, owner_id) VALUES ('g1', 'Pinned', 'alice');\
+INSERT INTO groups (id, name, owner_id) VALUES ('g2', 'Default', 'alice');\
+CREATE TABLE group_member (group_id TEXT NOT NULL, user_id TEXT NOT NULL);\
+CREATE TABLE dm_channel_member (dm_channel_id TEXT NOT NULL, user_id TEXT NOT NULL);\
+INSERT INTO group_member VALUES ('g1', 'alice');\
+INSERT INTO dm_channel_member VALUES ('dm1', 'alice');";
+
+async fn router() -> Router {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    let root = dir.path().join("blobs");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    let storage = ObjectStorage::Filesystem(FsStorage {
+        root,
+        public_url: "http://ds.test".to_string(),
+        signing_key: Arc::new(b"test-signing-key".to_vec()),
+        max_object_bytes: 1024 * 1024,
+    });
+    let targets = StorageTargets::new(vec![StorageTarget {
+        id: "eu".to_string(),
+        label: "EU (Frankfurt)".to_string(),
+        region: "eu-central-1".to_string(),
+        endpoint: "https://eu.store.test".to_string(),
+        bucket: "pollis-eu".to_string(),
+        access_key_id: "AKIDEU".to_string(),
+        secret_access_key: "eu-secret".to_string(),
+    }]);
+    build_router_with_state(
+        AppState::new(Arc::new(db), false)
+            .with_storage(storage)
+            .with_storage_targets(targets)
+            // Open the shipped-client window so `a_shipped_client_cannot_skip_a_pin`
+            // can presign unbound.
+            .with_upload_policy(UploadPolicy {
+                allow_unbound_until: Some(u64::MAX),
+                ..UploadPolicy::default()
+            }),
+    )
+}
+
+// Auth off: the no-auth paths take the actor from the body.
+async fn post(router: &Router, uri: &str, body: serde_json::Value) -> axum::response::Response {
+    let req = Request::builder()
+        .method("POST")
+        .uri(uri)
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap();
+    router.clone().oneshot(req).await.unwrap()
+}
+
+async fn presign_put(
+    router: &Router,
+    key: &str,
+    group_id: Option<&str>,
+) -> axum::response::Response {
+    let body = serde_json::json!({
+        "operation": "put",
+        "key": key,
+        "content_type": "application/octet-stream",
+        "size": 10,
+        "user_id": "alice",
+        "group_id": group_id,
+    });
+    post(router, "/v1/r2/presign", body).await
+}
+
+async fn body_json(resp: axum::response::Response) -> serde_json::Value {
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    serde_json::from_slice(&bytes).unwrap()
+}
+
+async fn pin(router: &Router, group_id: &str, target: &str) -> StatusCode {
+    let body = serde_json::json!({
+        "group_id": group_id,
+        "requester_id": "alice",
+        "storage_target": target,
+    });
+    post(router, "/v1/groups/update", body).await.status()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn targets_are_listed_without_credentials() {
+    let router = router().await;
+    let req = Request::builder()
+        .uri("/v1/storage/targets")
+        .body(Body::empty())
+        .unwrap();
+    let resp = router.clone().oneshot(req).await.unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    let json = body_json(resp).await;
+    assert_eq!(
+        json,
+        serde_json::json!({
+            "targets": [{ "id": "eu", "label": "EU (Frankfurt)", "region": "eu-central-1" }]
+        })
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn only_a_declared_target_can_be_pinned() {
+    let router = router().await;
+    assert_eq!(pin(&router, "g1", "us").await, StatusCode::BAD_REQUEST);
+    assert_eq!(pin(&router, "g1", "eu").await, StatusCode::OK);
+    // `""` goes back to the default store.
+    assert_eq!(pin(&router, "g1", "").await, StatusCode::OK);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_pinned_group_uploads_to_its_target_only() {
+    let router = router().await;
+    assert_eq!(pin(&router, "g1", "eu").await, StatusCode::OK);
+
+    let resp = presign_put(&router, "media@eu/abc/f.enc", Some("g1")).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    let url = body_json(resp).await["url"].as_str().unwrap().to_string();
+    assert!(url.starts_with("https://eu.store.test/pollis-eu/media%40eu/abc/f.enc?"));
+
+    let resp = presign_put(&router, "media/abc/f.enc", Some("g1")).await;
+    assert_eq!(resp.status(), StatusCode::CONFLICT);
+    let json = body_json(resp).await;
+    assert_eq!(json["error"], "RESIDENCY_MISMATCH");
+    assert_eq!(json["storage_target"], "eu");
+
+    // Readers only have the key; it's enough to reach the target.
+    let body =
+        serde_json::json!({ "operation": "get", "key": "media@eu/abc/f.enc", "user_id": "bob" });
+    let resp = post(&router, "/v1/r2/presign", body).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    let url = body_json(resp).await["url"].as_str().unwrap().to_string();
+    assert!(url.starts_with("https://eu.store.test/pollis-eu/"));
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn an_unpinned_group_stays_on_the_default_store() {
+    let router = router().await;
+
+    let resp = presign_put(&router, "media/abc/f.enc", Some("g2")).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    let url = body_json(resp).await["url"].as_str().unwrap().to_string();
+    assert!(url.starts_with("http://ds.test/v1/blobs/media/abc/f.enc?"));
+
+    let resp = presign_put(&router, "media@eu/abc/f.enc", Some("g2")).await;
+    assert_eq!(resp.status(), StatusCode::CONFLICT);
+    assert_eq!(
+        body_json(resp).await["storage_target"],
+        serde_json::Value::Null
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_target_key_needs_a_group_and_a_declared_target() {
+    let router = router().await;
+    assert_eq!(
+        presign_put(&router, "media@eu/abc/f.enc", None)
+            .await
+            .status(),
+        StatusCode::BAD_REQUEST
+    );
+    assert_eq!(
+        presign_put(&router, "media@us/abc/f.enc", Some("g1"))
+            .await
+            .status(),
+        StatusCode::BAD_REQUEST
+    );
+    assert_eq!(
+        presign_put(&router, "media/abc/f.enc", Some("nope"))
+            .await
+            .status(),
+        StatusCode::BAD_REQUEST
+    );
+    // Pinning is for attachments only.
+    let body =
+        serde_json::json!({ "operation": "get", "key": "avatars@eu/u1", "user_id": "alice" });
+    assert_eq!(
+        post(&router, "/v1/r2/presign", body).await.status(),
+        StatusCode::BAD_REQUEST
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn an_attachment_put_must_name_its_conversation() {
+    let router = router().await;
+    assert_eq!(pin(&router, "g1", "eu").await, StatusCode::OK);
+
+    // Leaving the group out doesn't dodge the pin.
+    assert_eq!(
+        presign_put(&router, "media/abc/f.enc", None).await.status(),
+        StatusCode::BAD_REQUEST
+    );
+
+    let dm = |key: &str, dm: &str| {
+        serde_json::json!({
+            "operation": "put",
+            "key": key,
+            "content_type": "application/octet-stream",
+            "size": 10,
+            "user_id": "alice",
+            "dm_channel_id": dm,
+        })
+    };
+    let resp = post(&router, "/v1/r2/presign", dm("media/abc/f.enc", "dm1")).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    // DMs aren't pinned.
+    let resp = post(&router, "/v1/r2/presign", dm("media@eu/abc/f.enc", "dm1")).await;
+    assert_eq!(resp.status(), StatusCode::CONFLICT);
+    // A group passed off as a DM isn't one.
+    let resp = post(&router, "/v1/r2/presign", dm("media/abc/f.enc", "g1")).await;
+    assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_shipped_client_cannot_skip_a_pin() {
+    let router = router().await;
+    // What shipped clients send: no type, no size, no conversation.
+    let legacy =
+        serde_json::json!({ "operation": "put", "key": "media/abc/f.enc", "user_id": "alice" });
+
+    let resp = post(&router, "/v1/r2/presign", legacy.clone()).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+
+    assert_eq!(pin(&router, "g1", "eu").await, StatusCode::OK);
+    let resp = post(&router, "/v1/r2/presign", legacy).await;
+    assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+}
@@ -0,0 +1,240 @@
+//! Several DS instances on one database (`DS_SHARED_STATE=db`), driven as two
+//! real routers over one local libsql file: rate limits and flood mutes hold
+//! across them, a resent envelope is stored once, sweep leases pick one
+//! holder, and every response names its instance.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use axum::Router;
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::flood::FloodConfig;
+use pollis_delivery::ratelimit::RateLimitConfig;
+use pollis_delivery::{build_router_with_state, instance, lease, AppState};
+use tower::ServiceExt as _;
+
+// The send path's tables plus migration 000024.
+const SCHEMA: &str = "\
+CREATE TABLE message_envelope (\
+  id TEXT PRIMARY KEY,\
+  conversation_id TEXT NOT NULL,\
+  sender_id TEXT NOT NULL,\
+  ciphertext TEXT NOT NULL,\
+  reply_to_id TEXT,\
+  sent_at TEXT NOT NULL,\
+  delivered INTEGER NOT NULL DEFAULT 0,\
+  type TEXT NOT NULL DEFAULT 'message',\
+  target_message_id TEXT,\
+  sealed INTEGER NOT NULL DEFAULT 0\
+);\
+CREATE TABLE flood_incident (\
+  id TEXT PRIMARY KEY,\
+  user_id TEXT NOT NULL,\
+  conversation_id TEXT NOT NULL,\
+  reason TEXT NOT NULL,\
+  muted_until TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);\
+CREATE TABLE ds_rate_window (\
+  key TEXT PRIMARY KEY,\
+  window_start INTEGER NOT NULL,\
+  count INTEGER NOT NULL\
+);\
+CREATE TABLE ds_lease (\
+  name TEXT PRIMARY KEY,\
+  holder TEXT NOT NULL,\
+  expires_at INTEGER NOT NULL\
+);";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+/// One "instance": its own in-process state, shared counters in `db`.
+fn instance_on(db: &Arc<Db>, state: impl FnOnce(AppState) -> AppState) -> Router {
+    build_router_with_state(state(
+        AppState::new(Arc::clone(db), false).with_shared_state(),
+    ))
+}
+
+// Auth off: the no-auth path takes the sender from the body.
+async fn send(
+    router: &Router,
+    id: &str,
+    sender: &str,
+    conversation: &str,
+    ciphertext: &str,
+) -> axum::response::Response {
+    let body = serde_json::json!({
+        "id": id,
+        "conversation_id": conversation,
+        "sender_id": sender,
+        "ciphertext": ciphertext,
+        "sent_at": "2026-01-01T00:00:00+00:00",
+    });
+    let req = Request::builder()
+        .method("POST")
+        .uri("/v1/messages/send")
+        .header("content-type", "application/json")
+        .header("cf-connecting-ip", "203.0.113.7")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap();
+    router.clone().oneshot(req).await.unwrap()
+}
+
+async fn body_json(resp: axum::response::Response) -> serde_json::Value {
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    serde_json::from_slice(&bytes).unwrap()
+}
+
+async fn count(db: &Db, sql: &str) -> i64 {
+    let conn = db.conn().unwrap();
+    let mut rows = conn.query(sql, ()).await.unwrap();
+    rows.next().await.unwrap().unwrap().get(0).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn write_budget_is_shared_between_instances() {
+    let db = fresh_db().await;
+    let tight = |s: AppState| {
+        s.with_ratelimit_config(RateLimitConfig {
+            write_max: 2,
+            ..RateLimitConfig::default()
+        })
+    };
+    let a = instance_on(&db, tight);
+    let b = instance_on(&db, tight);
+
+    assert_eq!(
+        send(&a, "m1", "alice", "c1", "mls:01").await.status(),
+        StatusCode::OK
+    );
+    assert_eq!(
+        send(&b, "m2", "alice", "c1", "mls:02").await.status(),
+        StatusCode::OK
+    );
+    // Each instance has only seen one write; together they've seen the budget.
+    assert_eq!(
+        send(&b, "m3", "alice", "c1", "mls:03").await.status(),
+        StatusCode::TOO_MANY_REQUESTS
+    );
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM message_envelope").await, 2);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_mute_on_one_instance_holds_on_the_other() {
+    let db = fresh_db().await;
+    let tiny = |s: AppState| {
+        s.with_flood_config(FloodConfig {
+            per_target_max: 1,
+            ..FloodConfig::default()
+        })
+    };
+    let a = instance_on(&db, tiny);
+    let b = instance_on(&db, tiny);
+
+    assert_eq!(
+        send(&a, "m1", "alice", "c1", "mls:01").await.status(),
+        StatusCode::OK
+    );
+    let resp = send(&a, "m2", "alice", "c1", "mls:02").await;
+    assert_eq!(resp.status(), StatusCode::TOO_MANY_REQUESTS);
+    assert_eq!(body_json(resp).await["reason"], "rate");
+
+    let resp = send(&b, "m3", "alice", "c2", "mls:03").await;
+    assert_eq!(resp.status(), StatusCode::TOO_MANY_REQUESTS);
+    assert_eq!(body_json(resp).await["reason"], "muted");
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM message_envelope").await, 1);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_resend_on_another_instance_is_stored_once() {
+    let db = fresh_db().await;
+    let a = instance_on(&db, |s| s);
+    let b = instance_on(&db, |s| s);
+    let c = instance_on(&db, |s| s);
+
+    let resp = send(&a, "m1", "alice", "c1", "mls:01").await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    assert!(body_json(resp).await.get("duplicate").is_none());
+
+    // The retry of a send whose reply was lost, answered by the other instance.
+    let resp = send(&b, "m1", "alice", "c1", "mls:01").await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    assert_eq!(body_json(resp).await["duplicate"], true);
+
+    // The same id for different content isn't a retry. (A third instance,
+    // whose replay table hasn't seen the id yet.)
+    let resp = send(&c, "m1", "alice", "c1", "mls:02").await;
+    assert_eq!(resp.status(), StatusCode::CONFLICT);
+    assert_eq!(body_json(resp).await["error"], "ENVELOPE_ID_TAKEN");
+
+    assert_eq!(count(&db, "SELECT COUNT(*) FROM message_envelope").await, 1);
+    assert_eq!(
+        count(
+            &db,
+            "SELECT COUNT(*) FROM message_envelope WHERE ciphertext = 'mls:01'"
+        )
+        .await,
+        1
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_sweep_lease_has_one_holder_until_it_lapses() {
+    let db = fresh_db().await;
+
+    assert!(lease::acquire(&db, "inactivity", "a", 60, 1000)
+        .await
+        .unwrap());
+    assert!(!lease::acquire(&db, "inactivity", "b", 60, 1010)
+        .await
+        .unwrap());
+    // The holder renews; another lease name is independent.
+    assert!(lease::acquire(&db, "inactivity", "a", 60, 1050)
+        .await
+        .unwrap());
+    assert!(lease::acquire(&db, "group_purge", "b", 60, 1050)
+        .await
+        .unwrap());
+    // Once the holder stops renewing, the lease passes on.
+    assert!(!lease::acquire(&db, "inactivity", "b", 60, 1100)
+        .await
+        .unwrap());
+    assert!(lease::acquire(&db, "inactivity", "b", 60, 1110)
+        .await
+        .unwrap());
+    assert!(!lease::acquire(&db, "inactivity", "a", 60, 1120)
+        .await
+        .unwrap());
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn responses_and_version_name_the_instance() {
+    let db = fresh_db().await;
+    let a = instance_on(&db, |s| s);
+
+    let resp = send(&a, "m1", "alice", "c1", "mls:01").await;
+    assert_eq!(resp.headers()[instance::HEADER], instance::id());
+
+    let req = Request::builder()
+        .uri("/version")
+        .body(Body::empty())
+        .unwrap();
+    let resp = a.clone().oneshot(req).await.unwrap();
+    assert_eq!(resp.headers()[instance::HEADER], instance::id());
+    assert_eq!(body_json(resp).await["instance"], instance::id());
+}
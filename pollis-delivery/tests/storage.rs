@@ -0,0 +1,261 @@
+//! The `fs` object-storage backend (`storage`), driven through the real axum
+//! router: `POST /v1/r2/presign` hands back a `/v1/blobs/…` URL, and that URL
+//! round-trips an object (PUT → GET → conditional GET → DELETE) against a temp
+//! directory. A URL signed for one method doesn't authorize another, and a
+//! PUT URL only accepts the upload it was presigned for (`uploads`). Shipped
+//! clients' PUT bodies, which declare nothing, get an unbound URL only while
+//! the operator's `UPLOAD_ALLOW_UNBOUND` window is open.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use axum::Router;
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::storage::{FsStorage, ObjectStorage};
+use pollis_delivery::uploads::UploadPolicy;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// An attachment PUT names its conversation (`residency`); the tests upload
+// into alice's DM.
+const SCHEMA: &str = "\
+CREATE TABLE dm_channel_member (dm_channel_id TEXT NOT NULL, user_id TEXT NOT NULL);\
+CREATE TABLE group_member (group_id TEXT NOT NULL, user_id TEXT NOT NULL);\
+CREATE TABLE groups (id TEXT PRIMARY KEY, storage_target TEXT);\
+INSERT INTO dm_channel_member VALUES ('dm1', 'alice');";
+
+async fn router(root: &std::path::Path) -> Router {
+    router_with_policy(root, UploadPolicy::default()).await
+}
+
+async fn router_with_policy(root: &std::path::Path, policy: UploadPolicy) -> Router {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    let storage = ObjectStorage::Filesystem(FsStorage {
+        root: root.to_path_buf(),
+        public_url: "http://ds.test".to_string(),
+        signing_key: Arc::new(b"test-signing-key".to_vec()),
+        max_object_bytes: 1024 * 1024,
+    });
+    build_router_with_state(
+        AppState::new(Arc::new(db), false)
+            .with_storage(storage)
+            .with_upload_policy(policy),
+    )
+}
+
+// Auth off: the no-auth presign path only needs a body `user_id`.
+async fn presign_request(router: &Router, body: serde_json::Value) -> axum::response::Response {
+    let req = Request::builder()
+        .method("POST")
+        .uri("/v1/r2/presign")
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap();
+    router.clone().oneshot(req).await.unwrap()
+}
+
+async fn presign(router: &Router, operation: &str, key: &str) -> String {
+    let body = serde_json::json!({ "operation": operation, "key": key, "user_id": "alice" });
+    presign_url(presign_request(router, body).await).await
+}
+
+async fn presign_put(router: &Router, key: &str, content_type: &str, size: usize) -> String {
+    let body = serde_json::json!({
+        "operation": "put",
+        "key": key,
+        "content_type": content_type,
+        "size": size,
+        "user_id": "alice",
+        "dm_channel_id": "dm1",
+    });
+    presign_url(presign_request(router, body).await).await
+}
+
+async fn presign_url(resp: axum::response::Response) -> String {
+    assert_eq!(resp.status(), StatusCode::OK);
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&bytes).unwrap();
+    let url = json["url"].as_str().unwrap().to_string();
+    // Drive the router with just the path + query.
+    url.strip_prefix("http://ds.test")
+        .expect("fs URL")
+        .to_string()
+}
+
+async fn blob(
+    router: &Router,
+    method: &str,
+    uri: &str,
+    body: Vec<u8>,
+    if_none_match: Option<&str>,
+) -> axum::response::Response {
+    let mut req = Request::builder().method(method).uri(uri);
+    if let Some(etag) = if_none_match {
+        req = req.header("if-none-match", etag);
+    }
+    router
+        .clone()
+        .oneshot(req.body(Body::from(body)).unwrap())
+        .await
+        .unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn fs_backend_round_trips_an_object() {
+    let root = tempfile::tempdir().expect("tempdir");
+    let router = router(root.path()).await;
+    let key = "media/abc123/my file.enc";
+
+    let put = presign_put(&router, key, "application/octet-stream", b"ciphertext".len()).await;
+    assert!(put.starts_with("/v1/blobs/media/abc123/my%20file.enc?expires="));
+    let resp = blob(&router, "PUT", &put, b"ciphertext".to_vec(), None).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    assert_eq!(std::fs::read(root.path().join(key)).unwrap(), b"ciphertext");
+
+    let get = presign(&router, "get", key).await;
+    let resp = blob(&router, "GET", &get, vec![], None).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    let etag = resp.headers()["etag"].to_str().unwrap().to_string();
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    assert_eq!(&bytes[..], b"ciphertext");
+
+    let resp = blob(&router, "GET", &get, vec![], Some(&etag)).await;
+    assert_eq!(resp.status(), StatusCode::NOT_MODIFIED);
+
+    let delete = presign(&router, "delete", key).await;
+    assert_eq!(
+        blob(&router, "DELETE", &delete, vec![], None)
+            .await
+            .status(),
+        StatusCode::NO_CONTENT
+    );
+    assert_eq!(
+        blob(&router, "GET", &get, vec![], None).await.status(),
+        StatusCode::NOT_FOUND
+    );
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_get_url_does_not_authorize_a_put() {
+    let root = tempfile::tempdir().expect("tempdir");
+    let router = router(root.path()).await;
+
+    let get = presign(&router, "get", "media/x/y.enc").await;
+    let resp = blob(&router, "PUT", &get, b"overwrite".to_vec(), None).await;
+    assert_eq!(resp.status(), StatusCode::FORBIDDEN);
+    assert!(!root.path().join("media/x/y.enc").exists());
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn put_presign_enforces_the_upload_policy() {
+    let root = tempfile::tempdir().expect("tempdir");
+    let router = router(root.path()).await;
+
+    for body in [
+        // Only one of type / size.
+        serde_json::json!({
+            "operation": "put", "key": "avatars/alice", "size": 100, "user_id": "alice",
+        }),
+        // SVG isn't an allowed image.
+        serde_json::json!({
+            "operation": "put", "key": "avatars/alice", "content_type": "image/svg+xml",
+            "size": 100, "user_id": "alice",
+        }),
+        // Outside every upload location.
+        serde_json::json!({
+            "operation": "put", "key": "scripts/x.js", "content_type": "image/png",
+            "size": 100, "user_id": "alice",
+        }),
+        // Over the image cap.
+        serde_json::json!({
+            "operation": "put", "key": "avatars/alice", "content_type": "image/png",
+            "size": 11 * 1024 * 1024, "user_id": "alice",
+        }),
+    ] {
+        let resp = presign_request(&router, body.clone()).await;
+        assert_eq!(resp.status(), StatusCode::BAD_REQUEST, "{body}");
+    }
+}
+
+// What shipped clients send: no type, no size.
+fn legacy(key: &str) -> serde_json::Value {
+    serde_json::json!({ "operation": "put", "key": key, "user_id": "alice" })
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn legacy_put_bodies_presign_inside_upload_locations_while_allowed() {
+    let root = tempfile::tempdir().expect("tempdir");
+    let policy = UploadPolicy {
+        allow_unbound_until: Some(u64::MAX),
+        ..UploadPolicy::default()
+    };
+    let router = router_with_policy(root.path(), policy).await;
+
+    let put = presign_url(presign_request(&router, legacy("avatars/alice")).await).await;
+    assert!(!put.contains("&len="), "{put}");
+    let png = [&[0x89, b'P', b'N', b'G', 0x0d, 0x0a, 0x1a, 0x0a][..], b"rest"].concat();
+    let resp = blob(&router, "PUT", &put, png, None).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+
+    let resp = presign_request(&router, legacy("scripts/x.js")).await;
+    assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn legacy_put_bodies_are_refused_without_an_open_window() {
+    let root = tempfile::tempdir().expect("tempdir");
+    // Default policy: no window, whatever the caller leaves out.
+    let router = router(root.path()).await;
+    let resp = presign_request(&router, legacy("avatars/alice")).await;
+    assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+
+    // A window whose end date has passed.
+    let policy = UploadPolicy {
+        allow_unbound_until: Some(1),
+        ..UploadPolicy::default()
+    };
+    let router = router_with_policy(root.path(), policy).await;
+    let resp = presign_request(&router, legacy("avatars/alice")).await;
+    assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn a_put_url_only_accepts_the_presigned_upload() {
+    let root = tempfile::tempdir().expect("tempdir");
+    let router = router(root.path()).await;
+    let png = [&[0x89, b'P', b'N', b'G', 0x0d, 0x0a, 0x1a, 0x0a][..], b"rest"].concat();
+
+    // Longer than declared.
+    let put = presign_put(&router, "media/x/y.enc", "application/octet-stream", 4).await;
+    let resp = blob(&router, "PUT", &put, b"too long".to_vec(), None).await;
+    assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+    assert!(!root.path().join("media/x/y.enc").exists());
+
+    // Declared a PNG, sent HTML of the same length.
+    let put = presign_put(&router, "avatars/alice", "image/png", png.len()).await;
+    let html = b"<script>1</script>"[..png.len()].to_vec();
+    let resp = blob(&router, "PUT", &put, html, None).await;
+    assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+    assert!(!root.path().join("avatars/alice").exists());
+
+    // Stripping the signed length off the URL breaks the signature.
+    let unbound = put.replace(&format!("&len={}", png.len()), "");
+    let resp = blob(&router, "PUT", &unbound, png.clone(), None).await;
+    assert_eq!(resp.status(), StatusCode::FORBIDDEN);
+
+    let resp = blob(&router, "PUT", &put, png.clone(), None).await;
+    assert_eq!(resp.status(), StatusCode::OK);
+    assert_eq!(std::fs::read(root.path().join("avatars/alice")).unwrap(), png);
+}
@@ -0,0 +1,173 @@
+//! Usage accounting (`pollis-delivery/src/usage.rs`) through the real axum
+//! router: sends add to the sender's row for today only when accounting is on,
+//! `/v1/usage/me` returns the caller's own rows, and `/v1/usage/export` needs
+//! the operator token.
+
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::http::{Request, StatusCode};
+use axum::Router;
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::usage::UsageConfig;
+use pollis_delivery::{build_router_with_state, AppState};
+use tower::ServiceExt as _;
+
+// Just the tables the send + usage paths touch.
+const SCHEMA: &str = "\
+CREATE TABLE message_envelope (\
+  id TEXT PRIMARY KEY,\
+  conversation_id TEXT NOT NULL,\
+  sender_id TEXT NOT NULL,\
+  ciphertext TEXT NOT NULL,\
+  reply_to_id TEXT,\
+  sent_at TEXT NOT NULL,\
+  sealed INTEGER NOT NULL DEFAULT 0\
+);\
+CREATE TABLE usage_daily (\
+  user_id TEXT NOT NULL,\
+  day TEXT NOT NULL,\
+  envelopes INTEGER NOT NULL DEFAULT 0,\
+  envelope_bytes INTEGER NOT NULL DEFAULT 0,\
+  attachments_presigned INTEGER NOT NULL DEFAULT 0,\
+  attachment_bytes INTEGER NOT NULL DEFAULT 0,\
+  PRIMARY KEY (user_id, day)\
+);";
+
+const EXPORT_TOKEN: &str = "export-secret";
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    Arc::new(db)
+}
+
+// Auth off: the no-auth path takes the actor from the body.
+fn router(db: &Arc<Db>, enabled: bool) -> Router {
+    let config = UsageConfig {
+        enabled,
+        export_token: Some(EXPORT_TOKEN.to_string()),
+    };
+    build_router_with_state(AppState::new(Arc::clone(db), false).with_usage_config(config))
+}
+
+fn post(uri: &str, body: serde_json::Value) -> Request<Body> {
+    Request::builder()
+        .method("POST")
+        .uri(uri)
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap()
+}
+
+async fn send(router: &Router, id: &str, sender: &str, ciphertext: &str) {
+    let resp = router
+        .clone()
+        .oneshot(post(
+            "/v1/messages/send",
+            serde_json::json!({
+                "id": id,
+                "conversation_id": "c1",
+                "sender_id": sender,
+                "ciphertext": ciphertext,
+                "sent_at": "2026-01-01T00:00:00+00:00",
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+}
+
+async fn json_body(resp: axum::response::Response) -> serde_json::Value {
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    serde_json::from_slice(&bytes).unwrap()
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn sends_are_counted_per_sender_when_enabled() {
+    let db = fresh_db().await;
+    let router = router(&db, true);
+    send(&router, "m1", "alice", "mls:aaaa").await;
+    send(&router, "m2", "alice", "mls:bb").await;
+    send(&router, "m3", "bob", "mls:c").await;
+
+    let resp = router
+        .clone()
+        .oneshot(post(
+            "/v1/usage/me",
+            serde_json::json!({ "user_id": "alice" }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    let body = json_body(resp).await;
+    assert_eq!(body["enabled"], true);
+    let days = body["days"].as_array().unwrap();
+    assert_eq!(days.len(), 1);
+    assert_eq!(days[0]["envelopes"], 2);
+    assert_eq!(days[0]["envelope_bytes"], 14);
+    assert_eq!(days[0]["attachments_presigned"], 0);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn nothing_is_recorded_when_disabled() {
+    let db = fresh_db().await;
+    let router = router(&db, false);
+    send(&router, "m1", "alice", "mls:aaaa").await;
+
+    let resp = router
+        .clone()
+        .oneshot(post(
+            "/v1/usage/me",
+            serde_json::json!({ "user_id": "alice" }),
+        ))
+        .await
+        .unwrap();
+    let body = json_body(resp).await;
+    assert_eq!(body["enabled"], false);
+    assert!(body["days"].as_array().unwrap().is_empty());
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn export_requires_the_operator_token() {
+    let db = fresh_db().await;
+    let router = router(&db, true);
+    send(&router, "m1", "alice", "mls:aaaa").await;
+    send(&router, "m2", "bob", "mls:c").await;
+
+    let today = chrono::Utc::now().format("%Y-%m-%d").to_string();
+    let export = |token: &str| {
+        Request::builder()
+            .method("GET")
+            .uri(format!("/v1/usage/export?from={today}"))
+            .header("authorization", format!("Bearer {token}"))
+            .body(Body::empty())
+            .unwrap()
+    };
+
+    let resp = router.clone().oneshot(export("wrong")).await.unwrap();
+    assert_eq!(resp.status(), StatusCode::UNAUTHORIZED);
+
+    let resp = router.clone().oneshot(export(EXPORT_TOKEN)).await.unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    let lines: Vec<serde_json::Value> = std::str::from_utf8(&bytes)
+        .unwrap()
+        .lines()
+        .map(|l| serde_json::from_str(l).unwrap())
+        .collect();
+    assert_eq!(lines.len(), 2);
+    assert_eq!(lines[0]["user_id"], "alice");
+    assert_eq!(lines[1]["user_id"], "bob");
+    assert_eq!(lines[1]["envelopes"], 1);
+}
@@ -0,0 +1,220 @@
+//! Outbound group webhooks (`webhooks`), driven through the real axum router
+//! with `tower::oneshot` against a local libsql DB. Deliveries go to a
+//! throwaway receiver on 127.0.0.1, which needs `allow_private_targets`.
+
+use std::sync::Arc;
+use std::time::Duration;
+
+use axum::body::{Body, Bytes};
+use axum::http::{HeaderMap, Request, StatusCode};
+use axum::routing::post as post_route;
+use http_body_util::BodyExt as _;
+use pollis_delivery::db::Db;
+use pollis_delivery::webhooks::{signature_header, WebhookConfig};
+use pollis_delivery::{build_router_with_state, AppState};
+use tokio::sync::mpsc;
+use tower::ServiceExt as _;
+
+// Just the tables the webhook paths touch.
+const SCHEMA: &str = "\
+CREATE TABLE channels (\
+  id TEXT PRIMARY KEY,\
+  group_id TEXT NOT NULL,\
+  name TEXT NOT NULL,\
+  description TEXT,\
+  channel_type TEXT NOT NULL DEFAULT 'text',\
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))\
+);\
+CREATE TABLE group_member (\
+  group_id TEXT NOT NULL,\
+  user_id TEXT NOT NULL,\
+  role TEXT NOT NULL DEFAULT 'member',\
+  PRIMARY KEY (group_id, user_id)\
+);\
+CREATE TABLE group_webhook (\
+  id TEXT PRIMARY KEY,\
+  group_id TEXT NOT NULL,\
+  url TEXT NOT NULL,\
+  events TEXT NOT NULL,\
+  created_by TEXT NOT NULL,\
+  created_at TEXT NOT NULL DEFAULT (datetime('now')),\
+  last_delivery_at TEXT,\
+  last_status INTEGER,\
+  failure_count INTEGER NOT NULL DEFAULT 0\
+);";
+
+fn config(allow_private_targets: bool) -> WebhookConfig {
+    WebhookConfig {
+        signing_key: Some(Arc::new(b"test-webhook-signing-key".to_vec())),
+        allow_private_targets,
+        ..WebhookConfig::default()
+    }
+}
+
+async fn fresh_db() -> Arc<Db> {
+    let dir = tempfile::tempdir().expect("tempdir");
+    let path = dir.path().join("delivery.db");
+    std::mem::forget(dir);
+    let db = Db::connect_local(path.to_str().unwrap())
+        .await
+        .expect("local db");
+    db.conn()
+        .unwrap()
+        .execute_batch(SCHEMA)
+        .await
+        .expect("schema");
+    db.conn()
+        .unwrap()
+        .execute_batch(
+            "INSERT INTO group_member (group_id, user_id, role) VALUES ('g1', 'alice', 'admin');",
+        )
+        .await
+        .unwrap();
+    Arc::new(db)
+}
+
+fn post(uri: &str, body: serde_json::Value) -> Request<Body> {
+    Request::builder()
+        .method("POST")
+        .uri(uri)
+        .header("content-type", "application/json")
+        .body(Body::from(serde_json::to_vec(&body).unwrap()))
+        .unwrap()
+}
+
+async fn body_json(resp: axum::response::Response) -> serde_json::Value {
+    let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+    serde_json::from_slice(&bytes).unwrap()
+}
+
+/// Bind a receiver on an ephemeral port; every request's headers and body
+/// are forwarded to the returned channel.
+async fn receiver() -> (String, mpsc::UnboundedReceiver<(HeaderMap, Bytes)>) {
+    let (tx, rx) = mpsc::unbounded_channel();
+    let app = axum::Router::new().route(
+        "/hook",
+        post_route(move |headers: HeaderMap, body: Bytes| {
+            let tx = tx.clone();
+            async move {
+                let _ = tx.send((headers, body));
+                StatusCode::NO_CONTENT
+            }
+        }),
+    );
+    let listener = tokio::net::TcpListener::bind("127.0.0.1:0").await.unwrap();
+    let addr = listener.local_addr().unwrap();
+    tokio::spawn(async move {
+        axum::serve(listener, app).await.unwrap();
+    });
+    (format!("http://{addr}/hook"), rx)
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn channel_created_is_delivered_signed_and_content_free() {
+    let db = fresh_db().await;
+    let (url, mut deliveries) = receiver().await;
+    // Auth off: the no-auth path takes the actor from the body.
+    let router =
+        build_router_with_state(AppState::new(Arc::clone(&db), false).with_webhooks(config(true)));
+
+    let resp = router
+        .clone()
+        .oneshot(post(
+            "/v1/webhooks/create",
+            serde_json::json!({
+                "group_id": "g1",
+                "url": url,
+                "events": ["channel_created"],
+                "requester_id": "alice",
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+    let created = body_json(resp).await;
+    let secret = created["secret"].as_str().unwrap().to_string();
+    assert!(!secret.is_empty());
+
+    let resp = router
+        .oneshot(post(
+            "/v1/channels/create",
+            serde_json::json!({
+                "id": "c1",
+                "group_id": "g1",
+                "name": "general",
+                "channel_type": "text",
+                "creator_id": "alice",
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::OK);
+
+    let (headers, body) = tokio::time::timeout(Duration::from_secs(5), deliveries.recv())
+        .await
+        .expect("delivery within 5s")
+        .expect("receiver open");
+    assert_eq!(headers["x-pollis-event"], "channel_created");
+
+    // `t=<unix>,v1=<hex>` must match a signature recomputed with the secret.
+    let signature = headers["x-pollis-signature"].to_str().unwrap();
+    let timestamp: u64 = signature
+        .strip_prefix("t=")
+        .and_then(|rest| rest.split(',').next())
+        .and_then(|t| t.parse().ok())
+        .expect("timestamp in signature");
+    assert_eq!(signature, signature_header(&secret, timestamp, &body));
+
+    let payload: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(payload["event"], "channel_created");
+    assert_eq!(payload["group_id"], "g1");
+    assert_eq!(payload["data"], serde_json::json!({ "channel_id": "c1" }));
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn create_without_signing_key_is_503() {
+    let db = fresh_db().await;
+    let router = build_router_with_state(AppState::new(Arc::clone(&db), false));
+
+    let resp = router
+        .oneshot(post(
+            "/v1/webhooks/create",
+            serde_json::json!({
+                "group_id": "g1",
+                "url": "https://example.com/hook",
+                "events": ["member_joined"],
+                "requester_id": "alice",
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(resp.status(), StatusCode::SERVICE_UNAVAILABLE);
+}
+
+#[tokio::test(flavor = "multi_thread")]
+async fn private_and_plain_http_targets_are_rejected() {
+    let db = fresh_db().await;
+    let router =
+        build_router_with_state(AppState::new(Arc::clone(&db), false).with_webhooks(config(false)));
+
+    for url in [
+        "http://example.com/hook",
+        "https://127.0.0.1/hook",
+        "https://10.0.0.5/hook",
+    ] {
+        let resp = router
+            .clone()
+            .oneshot(post(
+                "/v1/webhooks/create",
+                serde_json::json!({
+                    "group_id": "g1",
+                    "url": url,
+                    "events": ["member_joined"],
+                    "requester_id": "alice",
+                }),
+            ))
+            .await
+            .unwrap();
+        assert_eq!(resp.status(), StatusCode::BAD_REQUEST, "{url}");
+    }
+}
@@ -0,0 +1,56 @@
+//! `pollis-cli` — headless one-shot client for scripts and smoke tests. The
+//! commands live in [`pollis_tui::cli`]; this is just env, unlock, print.
+//! pollis-core's `eprintln!` chatter stays on stderr, so stdout carries only
+//! the command's output.
+
+use std::process::ExitCode;
+use std::sync::Arc;
+
+use anyhow::{Context, Result};
+use pollis_core::config::Config;
+use pollis_core::state::AppState;
+use pollis_tui::cli;
+
+// Multi-thread runtime for the same reason as the TUI: pollis-core's DB and
+// keystore paths use spawn_blocking.
+#[tokio::main(flavor = "multi_thread")]
+async fn main() -> ExitCode {
+    let args: Vec<String> = std::env::args().skip(1).collect();
+    let cmd = match cli::parse(&args) {
+        Ok(cmd) => cmd,
+        Err(e) => {
+            eprintln!("pollis-cli: {e}\n\n{}", cli::USAGE);
+            return ExitCode::from(2);
+        }
+    };
+    match run(cmd).await {
+        Ok(lines) => {
+            for line in lines {
+                println!("{line}");
+            }
+            ExitCode::SUCCESS
+        }
+        Err(e) => {
+            eprintln!("pollis-cli: {e:#}");
+            ExitCode::FAILURE
+        }
+    }
+}
+
+async fn run(cmd: cli::Command) -> Result<Vec<String>> {
+    let pin = std::env::var("POLLIS_PIN").context("POLLIS_PIN is not set")?;
+    let config = Config::from_env().context(
+        "loading config from env (need TURSO_URL, TURSO_TOKEN, POLLIS_DELIVERY_URL, R2_* placeholders)",
+    )?;
+    let state = Arc::new(
+        AppState::new(config)
+            .await
+            .context("connecting AppState (Turso + keystore)")?,
+    );
+    let boot_mode = state.config.overlay_mode;
+    if let Err(e) = pollis_core::commands::overlay::apply_overlay_mode(&state, boot_mode).await {
+        eprintln!("[overlay] boot apply ({boot_mode:?}) failed, staying direct: {e}");
+    }
+    let profile = cli::unlock(&state, &pin).await?;
+    cli::run(&state, &profile, cmd).await
+}
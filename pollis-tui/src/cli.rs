@@ -0,0 +1,268 @@
+//! Headless one-shot commands (`pollis-cli`).
+//!
+//! The same data/sync/send layer the TUI drives, minus the terminal: unlock,
+//! run one command, print plain tab-separated lines, exit. For operator smoke
+//! tests, scripted announcements, and reproducing a bug without the GUI.
+//!
+//! There is no bearer token to hand it. A Pollis device authenticates with its
+//! enrolled device key, and its local DB is sealed under the PIN — so the CLI
+//! runs against a `POLLIS_DATA_DIR` that was already enrolled (with `pollis`,
+//! or any other client) and unlocks it with `POLLIS_PIN`. Every write still
+//! goes through the DS exactly as the desktop app's does.
+//!
+//! ```text
+//! pollis-cli list                          # id, kind, name — one per line
+//! pollis-cli send <conversation-id> <text>…
+//! pollis-cli history <conversation-id> [--limit N]
+//! ```
+
+use std::sync::Arc;
+
+use anyhow::{bail, Context, Result};
+use pollis_core::commands::auth::UserProfile;
+use pollis_core::state::AppState;
+
+use crate::auth::{self, Boot};
+use crate::{data, send, sync};
+
+/// Default number of messages `history` prints.
+pub const DEFAULT_HISTORY: usize = 50;
+
+pub const USAGE: &str = "\
+usage: pollis-cli list
+       pollis-cli send <conversation-id> <text>...
+       pollis-cli history <conversation-id> [--limit N]
+
+env:   POLLIS_PIN (required), POLLIS_DATA_DIR, plus the TUI's TURSO_* / POLLIS_DELIVERY_URL";
+
+#[derive(Debug, PartialEq, Eq)]
+pub enum Command {
+    /// Every conversation the account can see.
+    List,
+    /// Send `text` to a channel or DM.
+    Send {
+        conversation_id: String,
+        text: String,
+    },
+    /// Print up to `limit` decrypted messages, oldest first.
+    History {
+        conversation_id: String,
+        limit: usize,
+    },
+}
+
+/// Parse the arguments after the program name.
+pub fn parse(args: &[String]) -> Result<Command, String> {
+    let (cmd, rest) = args.split_first().ok_or("missing command")?;
+    match cmd.as_str() {
+        "list" => {
+            if !rest.is_empty() {
+                return Err("list takes no arguments".into());
+            }
+            Ok(Command::List)
+        }
+        "send" => {
+            let (conversation_id, words) =
+                rest.split_first().ok_or("send needs a conversation id")?;
+            let text = words.join(" ");
+            if text.trim().is_empty() {
+                return Err("send needs message text".into());
+            }
+            Ok(Command::Send {
+                conversation_id: conversation_id.clone(),
+                text,
+            })
+        }
+        "history" => {
+            let (conversation_id, flags) = rest
+                .split_first()
+                .ok_or("history needs a conversation id")?;
+            let limit = match flags {
+                [] => DEFAULT_HISTORY,
+                [flag, n] if flag == "--limit" => match n.parse::<usize>() {
+                    Ok(n) if n > 0 => n,
+                    _ => return Err(format!("--limit must be a positive number, got {n}")),
+                },
+                _ => return Err("history takes only --limit N".into()),
+            };
+            Ok(Command::History {
+                conversation_id: conversation_id.clone(),
+                limit,
+            })
+        }
+        other => Err(format!("unknown command: {other}")),
+    }
+}
+
+/// Restore the persisted session and unlock it with `pin`. Refuses a fresh
+/// data dir: enrolling a device is interactive (OTP, Secret Key) and belongs
+/// to the TUI.
+pub async fn unlock(state: &Arc<AppState>, pin: &str) -> Result<UserProfile> {
+    match auth::boot(state).await? {
+        Boot::Returning(profile) => {
+            auth::unlock(state, &profile.id, pin)
+                .await
+                .context("unlock (check POLLIS_PIN)")?;
+            Ok(profile)
+        }
+        Boot::Fresh => {
+            bail!("no enrolled account in POLLIS_DATA_DIR; sign in once with `pollis` first")
+        }
+    }
+}
+
+/// Run `cmd` for the unlocked `profile` and return the lines to print.
+pub async fn run(
+    state: &Arc<AppState>,
+    profile: &UserProfile,
+    cmd: Command,
+) -> Result<Vec<String>> {
+    // One catch-up round first, so a send lands on the current epoch and
+    // history includes whatever was delivered while this device was offline.
+    let tree = sync::sync_once(state, &profile.id).await?;
+    match cmd {
+        Command::List => Ok(list_lines(&tree)),
+        Command::Send {
+            conversation_id,
+            text,
+        } => {
+            if !tree.conversation_ids().contains(&conversation_id) {
+                bail!("unknown conversation: {conversation_id}");
+            }
+            let message = send::send_text(
+                state,
+                &profile.id,
+                Some(profile.username.clone()),
+                &conversation_id,
+                &text,
+            )
+            .await?;
+            Ok(vec![message.id])
+        }
+        Command::History {
+            conversation_id,
+            limit,
+        } => history_lines(state, &profile.id, &tree, &conversation_id, limit).await,
+    }
+}
+
+fn list_lines(tree: &data::ConversationTree) -> Vec<String> {
+    let mut lines = Vec::new();
+    for group in &tree.groups {
+        for channel in &group.channels {
+            lines.push(format!(
+                "{}\tchannel\t{}/{}",
+                channel.id, group.name, channel.name
+            ));
+        }
+    }
+    for (dms, kind) in [(&tree.dm_channels, "dm"), (&tree.dm_requests, "dm-request")] {
+        for dm in dms {
+            let names: Vec<&str> = dm
+                .members
+                .iter()
+                .map(|m| m.username.as_deref().unwrap_or(m.user_id.as_str()))
+                .collect();
+            lines.push(format!("{}\t{kind}\t{}", dm.id, names.join(",")));
+        }
+    }
+    lines
+}
+
+async fn history_lines(
+    state: &Arc<AppState>,
+    user_id: &str,
+    tree: &data::ConversationTree,
+    conversation_id: &str,
+    limit: usize,
+) -> Result<Vec<String>> {
+    let is_channel = tree
+        .groups
+        .iter()
+        .any(|g| g.channels.iter().any(|c| c.id == conversation_id));
+    if !is_channel
+        && !tree
+            .conversation_ids()
+            .iter()
+            .any(|id| id == conversation_id)
+    {
+        bail!("unknown conversation: {conversation_id}");
+    }
+
+    // Pages come newest-first; walk back until we have `limit`, then flip.
+    let mut messages = Vec::new();
+    let mut cursor = None;
+    loop {
+        let page = if is_channel {
+            data::channel_messages(state, user_id, conversation_id, cursor).await?
+        } else {
+            data::dm_messages(state, user_id, conversation_id, cursor).await?
+        };
+        messages.extend(page.messages);
+        cursor = page.next_cursor;
+        if messages.len() >= limit || cursor.is_none() {
+            break;
+        }
+    }
+    messages.truncate(limit);
+    messages.reverse();
+
+    Ok(messages
+        .into_iter()
+        .map(|m| {
+            let sender = m.sender_username.unwrap_or(m.sender_id);
+            let body = if m.deleted_at.is_some() {
+                "[deleted]".to_string()
+            } else {
+                m.content.unwrap_or_else(|| "[undecryptable]".to_string())
+            };
+            // One message per line, whatever the content.
+            format!("{}\t{sender}\t{}", m.sent_at, body.replace('\n', "\\n"))
+        })
+        .collect())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn args(s: &str) -> Vec<String> {
+        s.split_whitespace().map(str::to_string).collect()
+    }
+
+    #[test]
+    fn parses_each_command() {
+        assert_eq!(parse(&args("list")), Ok(Command::List));
+        assert_eq!(
+            parse(&args("send c1 hello   there")),
+            Ok(Command::Send {
+                conversation_id: "c1".into(),
+                text: "hello there".into(),
+            })
+        );
+        assert_eq!(
+            parse(&args("history c1")),
+            Ok(Command::History {
+                conversation_id: "c1".into(),
+                limit: DEFAULT_HISTORY,
+            })
+        );
+        assert_eq!(
+            parse(&args("history c1 --limit 5")),
+            Ok(Command::History {
+                conversation_id: "c1".into(),
+                limit: 5,
+            })
+        );
+    }
+
+    #[test]
+    fn rejects_malformed_input() {
+        assert!(parse(&[]).is_err());
+        assert!(parse(&args("send c1")).is_err());
+        assert!(parse(&args("history c1 --limit 0")).is_err());
+        assert!(parse(&args("history c1 --since 5")).is_err());
+        assert!(parse(&args("list extra")).is_err());
+        assert!(parse(&args("delete c1")).is_err());
+    }
+}
@@ -331,14 +331,20 @@ pub fn build_sidebar_rows(
     rows
 }
 
-/// Title a DM by the member who isn't the current user (falling back to a
-/// generic label if the metadata is missing or it's a self-note).
+/// Title a DM by the member who isn't the current user — their local nickname
+/// when set — falling back to a generic label if the metadata is missing or
+/// it's a self-note.
 fn dm_label(dm: &pollis_core::commands::dm::DmChannel, user_id: &str) -> String {
     let names: Vec<String> = dm
         .members
         .iter()
         .filter(|m| m.user_id != user_id)
-        .map(|m| m.username.clone().unwrap_or_else(|| m.user_id.clone()))
+        .map(|m| {
+            m.nickname
+                .clone()
+                .or_else(|| m.username.clone())
+                .unwrap_or_else(|| m.user_id.clone())
+        })
         .collect();
     if names.is_empty() {
         "Direct message".to_string()
@@ -445,6 +451,8 @@ mod tests {
             owner_id: "owner".to_string(),
             created_at: "t".to_string(),
             current_user_role: "member".to_string(),
+            allow_export: true,
+            pinned: false,
             channels: channels
                 .iter()
                 .map(|(cid, cname)| Channel {
@@ -471,6 +479,7 @@ mod tests {
                     added_by: "me".to_string(),
                     added_at: "t".to_string(),
                     accepted_at: Some("t".to_string()),
+                    nickname: None,
                 },
                 DmChannelMember {
                     user_id: other_user.to_string(),
@@ -479,8 +488,10 @@ mod tests {
                     added_by: "me".to_string(),
                     added_at: "t".to_string(),
                     accepted_at: Some("t".to_string()),
+                    nickname: None,
                 },
             ],
+            pinned: false,
         }
     }
 
@@ -496,6 +507,7 @@ mod tests {
             sent_at: sent_at.to_string(),
             edited_at: None,
             deleted_at: None,
+            sender_muted: false,
         }
     }
 
@@ -12,6 +12,7 @@
 //! - [`enroll`] — multi-device enrollment + Secret-Key recovery wrappers (M4).
 //! - [`send`] — the conversation + group WRITE layer (M3, §8 command→screen map).
 //! - [`sync`] — the §6 polling sync loop that keeps a client caught up (M2).
+//! - [`cli`] — the headless one-shot commands behind the `pollis-cli` binary.
 //!
 //! UI state-machine modules — promoted from binary-only into the library so the
 //! headless in-process e2e tests (`tests/ui_e2e.rs`) can drive the real ratatui
@@ -25,6 +26,7 @@
 //! - [`enroll_flow`] — the pure multi-device enrollment/recovery screen model.
 
 pub mod auth;
+pub mod cli;
 pub mod data;
 pub mod enroll;
 pub mod send;
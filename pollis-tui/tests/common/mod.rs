@@ -760,11 +760,16 @@ async fn delivery_establish_identity(
     headers: HeaderMap,
     body: axum::body::Bytes,
 ) -> Response {
-    let claims =
-        match pollis_delivery::session::verify_session(&headers, &state.sessions, now_u64()) {
-            Ok(c) => c,
-            Err(rej) => return rej.into_response(),
-        };
+    let claims = match pollis_delivery::session::verify_session(
+        &headers,
+        &state.sessions,
+        now_u64(),
+    )
+    .await
+    {
+        Ok(c) => c,
+        Err(rej) => return rej.into_response(),
+    };
     let parsed: pollis_delivery::bootstrap::EstablishIdentityBody =
         match serde_json::from_slice(&body) {
             Ok(b) => b,
@@ -809,11 +814,16 @@ async fn delivery_register_device(
     headers: HeaderMap,
     body: axum::body::Bytes,
 ) -> Response {
-    let claims =
-        match pollis_delivery::session::verify_session(&headers, &state.sessions, now_u64()) {
-            Ok(c) => c,
-            Err(rej) => return rej.into_response(),
-        };
+    let claims = match pollis_delivery::session::verify_session(
+        &headers,
+        &state.sessions,
+        now_u64(),
+    )
+    .await
+    {
+        Ok(c) => c,
+        Err(rej) => return rej.into_response(),
+    };
     let parsed: pollis_delivery::bootstrap::RegisterDeviceBody =
         match serde_json::from_slice(&body) {
             Ok(b) => b,
@@ -868,9 +878,10 @@ async fn delivery_publish_device_cert(
     let session_token = pollis_delivery::session::session_token(&headers)
         .filter(|t| !t.is_empty())
         .map(|t| t.to_string());
-    let session_claims = session_token
-        .as_ref()
-        .and_then(|t| state.sessions.resolve(t, now));
+    let session_claims = match &session_token {
+        Some(t) => state.sessions.resolve(t, now).await,
+        None => None,
+    };
     let (user_id, device_id, invalidate_token) = match session_claims {
         Some(claims) => {
             if parsed.device_id != claims.device_id {
@@ -903,7 +914,7 @@ async fn delivery_publish_device_cert(
     {
         Ok(pollis_delivery::bootstrap::PublishCertOutcome::Applied) => {
             if let Some(token) = invalidate_token {
-                state.sessions.invalidate(&token);
+                state.sessions.invalidate(&token).await;
             }
             ds_ok()
         }
@@ -934,11 +945,16 @@ async fn delivery_enrollment_request(
     headers: HeaderMap,
     body: axum::body::Bytes,
 ) -> Response {
-    let claims =
-        match pollis_delivery::session::verify_session(&headers, &state.sessions, now_u64()) {
-            Ok(c) => c,
-            Err(rej) => return rej.into_response(),
-        };
+    let claims = match pollis_delivery::session::verify_session(
+        &headers,
+        &state.sessions,
+        now_u64(),
+    )
+    .await
+    {
+        Ok(c) => c,
+        Err(rej) => return rej.into_response(),
+    };
     let parsed: pollis_delivery::bootstrap::EnrollmentRequestBody =
         match serde_json::from_slice(&body) {
             Ok(b) => b,
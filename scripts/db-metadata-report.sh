@@ -0,0 +1,147 @@
+#!/usr/bin/env bash
+#
+# Read-only retained-metadata report for a libSQL/Turso database, via the same
+# HTTP pipeline API db-apply.sh uses. For every table it prints the columns, the
+# row count, the oldest row (by the table's timestamp column, when it has one)
+# and the retention rule the code applies. Tables with a time-based rule are
+# flagged OVERDUE when their oldest row is past the window plus a day of grace,
+# which means the cleanup that should remove it isn't running. Examples are the
+# envelope GC that clients trigger on fetch, or the DS's prune-on-insert.
+#
+# Usage: TURSO_URL=libsql://... TURSO_TOKEN=... scripts/db-metadata-report.sh
+#
+# Works against the main DB and the commit-log DB alike; rules for tables the
+# target DB doesn't have are skipped. Never writes. Keep the rules below in step
+# with the DELETEs in pollis-delivery/src when a retention window changes.
+
+set -euo pipefail
+
+: "${TURSO_URL:?must be set (libsql://...)}"
+: "${TURSO_TOKEN:?must be set}"
+
+HTTP_URL="${TURSO_URL/libsql:\/\//https:\/\/}"
+
+post() {
+  curl -sS --fail-with-body -X POST "$HTTP_URL/v2/pipeline" \
+    -H "Authorization: Bearer $TURSO_TOKEN" \
+    -H "Content-Type: application/json" \
+    -d "$1"
+}
+
+# Run one SELECT and print its rows tab-separated.
+query() {
+  post "$(jq -n --arg sql "$1" '{requests: [{type: "execute", stmt: {sql: $sql}}, {type: "close"}]}')" \
+    | jq -r '.results[0].response.result.rows[]? | map(.value // "") | @tsv'
+}
+
+# Time-based windows, in days, keyed on table. Each is enforced by a DELETE in
+# the DS (or, for envelopes, by the client-triggered /v1/envelopes/gc).
+declare -A WINDOW_DAYS=(
+  [message_envelope]=30   # messages.rs CLEANUP_*_ENVELOPES, on sent_at
+  [flood_incident]=30     # flood.rs record_incident, pruned on insert
+  [email_invite]=30       # email_invites.rs INVITE_TTL_DAYS, swept on create
+)
+
+# What keeps every other table bounded. Tables missing here are reported as
+# "no rule recorded" so a new table without a retention story stands out.
+declare -A RULE=(
+  [users]="until account deletion"
+  [user_device]="until device revoke or account deletion"
+  [mls_key_package]="until claimed, device revoke or account deletion"
+  [mls_welcome]="until delivered, member removal or account reset"
+  [mls_commit_log]="compacted below the oldest epoch a member still needs (commit.rs)"
+  [mls_group_info]="latest per group, replaced on each commit"
+  [conversation_watermark]="until leaving the group / DM"
+  [groups]="until deleted or the last member leaves"
+  [group_member]="until leave or removal"
+  [group_invite]="until accepted, declined or the group is deleted"
+  [group_slug]="with the group; unclaimed reservations are taken over after 10 min"
+  [group_ownership_transfer]="until accepted, declined or a party leaves"
+  [channels]="until deleted with the group"
+  [dm_channel]="until the last member leaves"
+  [dm_channel_member]="until leave"
+  [message_reaction]="until removed or the message is deleted"
+  [pinned_message]="until unpinned or the message is deleted"
+  [attachment_object]="until the uploader deletes it"
+  [usage_daily]="kept for billing; no automatic pruning"
+  [email_invite_opt_out]="kept; an opt-out is permanent"
+  [user_block]="until unblocked"
+  [user_preferences]="until account deletion"
+  [account_key_log]="kept; append-only key transparency history"
+  [account_recovery]="until account deletion or the inactivity sweep"
+  [inactivity_policy]="until the user turns it off or the sweep runs"
+  [group_webhook]="until an admin deletes it or the group goes"
+  [group_join_request]="no pruning; removed with the group or account"
+  [device_enrollment_request]="no pruning; removed with the account"
+  [security_event]="no pruning; removed with the account"
+  [push_token]="no pruning; removed with the account"
+  [ds_rate_window]="DS counters; windows older than a day pruned (ratelimit.rs)"
+  [ds_lease]="one row per DS sweep; no user data"
+  [ds_idempotency]="DS replies to keyed writes; lapsed rows pruned (idempotency.rs)"
+  [ds_otp]="pending sign-in codes (hashed); deleted on use, expired ones pruned (otp.rs)"
+  [ds_session]="bootstrap sessions (hashed tokens); deleted at cert publish, expired ones pruned"
+  [ds_email_change]="pending email changes; deleted on use, pruned after a day"
+  [user_groups]="unused since migration 000009; should stay empty"
+  [user_dms]="unused since migration 000009; should stay empty"
+)
+
+TABLES=$(query "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name NOT LIKE '_litestream%' ORDER BY name")
+
+# The column a table's row age is measured by: the first of these it has.
+age_column() {
+  local cols="$1"
+  for c in sent_at created_at reserved_at requested_at day last_fetched_at updated_at; do
+    if printf '%s\n' $cols | grep -qx "$c"; then
+      echo "$c"
+      return
+    fi
+  done
+}
+
+overdue=0
+echo "== Retained metadata (table, rows, oldest, age days, rule) =="
+for t in $TABLES; do
+  cols=$(query "SELECT name FROM pragma_table_info('$t')")
+  rows=$(query "SELECT COUNT(*) FROM \"$t\"")
+  col=$(age_column "$cols")
+  oldest="-"
+  age="-"
+  if [[ -n "$col" && "$rows" != "0" ]]; then
+    IFS=$'\t' read -r oldest age < <(query "SELECT MIN($col), CAST(julianday('now') - julianday(MIN($col)) AS INTEGER) FROM \"$t\"")
+  fi
+
+  if [[ -n "${WINDOW_DAYS[$t]:-}" ]]; then
+    rule="${WINDOW_DAYS[$t]} days on $col"
+    if [[ "$age" != "-" && -n "$age" && "$age" -gt $((WINDOW_DAYS[$t] + 1)) ]]; then
+      rule="$rule  OVERDUE"
+      overdue=$((overdue + 1))
+    fi
+  else
+    rule="${RULE[$t]:-no rule recorded}"
+  fi
+  printf '%-28s %8s  %-26s %6s  %s\n' "$t" "$rows" "$oldest" "$age" "$rule"
+  printf '    columns: %s\n' "$(printf '%s\n' $cols | paste -sd, - | sed 's/,/, /g')"
+done
+
+# Admin-set channel retention (channels.retention_days, migration 000016) is
+# tighter than the 30-day envelope TTL; count envelopes already past it.
+if printf '%s\n' $TABLES | grep -qx channels \
+  && printf '%s\n' $TABLES | grep -qx message_envelope \
+  && query "SELECT name FROM pragma_table_info('channels')" | grep -qx retention_days; then
+  echo
+  echo "== Channels with retention (channel, days, envelopes past it) =="
+  query "SELECT c.id, c.retention_days,
+                (SELECT COUNT(*) FROM message_envelope e
+                  WHERE e.conversation_id = c.id
+                    AND e.sent_at < datetime('now', '-' || (c.retention_days + 1) || ' days'))
+         FROM channels c
+         WHERE c.retention_days IS NOT NULL
+         ORDER BY c.retention_days"
+fi
+
+echo
+if [[ "$overdue" -gt 0 ]]; then
+  echo "$overdue table(s) hold rows past their retention window."
+  exit 2
+fi
+echo "No table holds rows past its retention window."
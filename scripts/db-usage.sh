@@ -0,0 +1,126 @@
+#!/usr/bin/env bash
+#
+# Read-only storage report for a libSQL/Turso database, via the same HTTP
+# pipeline API db-apply.sh uses. Prints row counts for every table, approximate
+# payload bytes for the blob-heavy tables, and the heaviest users by envelope /
+# key-package bytes — enough to spot who is driving usage-based billing and
+# whether retention needs tuning. Also lists the most recent DS flood-detection
+# incidents (senders muted for flooding a conversation) and, when usage
+# accounting is on, the heaviest users from `usage_daily`.
+#
+# Usage: TURSO_URL=libsql://... TURSO_TOKEN=... scripts/db-usage.sh [top_n]
+#
+# Works against the main DB and the commit-log DB alike; sections for tables the
+# target DB doesn't have are skipped. Never writes.
+
+set -euo pipefail
+
+: "${TURSO_URL:?must be set (libsql://...)}"
+: "${TURSO_TOKEN:?must be set}"
+
+HTTP_URL="${TURSO_URL/libsql:\/\//https:\/\/}"
+TOP_N="${1:-10}"
+
+if ! [[ "$TOP_N" =~ ^[0-9]+$ ]]; then
+  echo "top_n must be a positive integer" >&2
+  exit 1
+fi
+
+post() {
+  curl -sS --fail-with-body -X POST "$HTTP_URL/v2/pipeline" \
+    -H "Authorization: Bearer $TURSO_TOKEN" \
+    -H "Content-Type: application/json" \
+    -d "$1"
+}
+
+# Run one SELECT and print its rows tab-separated.
+query() {
+  post "$(jq -n --arg sql "$1" '{requests: [{type: "execute", stmt: {sql: $sql}}, {type: "close"}]}')" \
+    | jq -r '.results[0].response.result.rows[]? | map(.value // "") | @tsv'
+}
+
+TABLES=$(query "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name NOT LIKE '_litestream%' ORDER BY name")
+
+has_table() {
+  printf '%s\n' $TABLES | grep -qx "$1"
+}
+
+echo "== Row counts =="
+for t in $TABLES; do
+  printf '%-32s %s\n' "$t" "$(query "SELECT COUNT(*) FROM \"$t\"")"
+done
+
+# Approximate payload bytes: the column that dominates each table's size. Index
+# and page overhead are not included, so treat these as lower bounds.
+echo
+echo "== Approximate payload bytes =="
+for spec in \
+  "message_envelope:ciphertext" \
+  "mls_key_package:key_package" \
+  "mls_commit_log:commit_data" \
+  "mls_group_info:group_info" \
+  "mls_welcome:welcome_data"; do
+  t="${spec%%:*}"
+  col="${spec##*:}"
+  has_table "$t" || continue
+  printf '%-32s %s\n' "$t.$col" "$(query "SELECT COALESCE(SUM(length($col)), 0) FROM $t")"
+done
+
+# Sealed-sender envelopes carry no real sender, so they are reported as one
+# bucket rather than attributed to a user.
+if has_table message_envelope; then
+  echo
+  echo "== Top $TOP_N senders by envelope bytes (user, envelopes, bytes) =="
+  query "SELECT CASE WHEN sealed = 1 THEN '(sealed)' ELSE sender_id END AS who,
+                COUNT(*), COALESCE(SUM(length(ciphertext)), 0) AS bytes
+         FROM message_envelope
+         GROUP BY who
+         ORDER BY bytes DESC
+         LIMIT $TOP_N"
+fi
+
+if has_table mls_key_package; then
+  echo
+  echo "== Top $TOP_N users by key packages (user, unclaimed, total, bytes) =="
+  query "SELECT user_id, SUM(claimed = 0), COUNT(*), COALESCE(SUM(length(key_package)), 0) AS bytes
+         FROM mls_key_package
+         GROUP BY user_id
+         ORDER BY bytes DESC
+         LIMIT $TOP_N"
+fi
+
+if has_table mls_welcome; then
+  echo
+  echo "== Top $TOP_N recipients by undelivered welcomes (user, rows, bytes) =="
+  query "SELECT recipient_id, COUNT(*), COALESCE(SUM(length(welcome_data)), 0) AS bytes
+         FROM mls_welcome
+         WHERE delivered = 0
+         GROUP BY recipient_id
+         ORDER BY bytes DESC
+         LIMIT $TOP_N"
+fi
+
+# Senders muted by the DS flood detector (pollis-delivery/src/flood.rs), newest
+# first. Rows are pruned by the DS after 30 days.
+if has_table flood_incident; then
+  echo
+  echo "== Last $TOP_N flood incidents (created, user, conversation, reason, muted until) =="
+  query "SELECT created_at, user_id, conversation_id, reason, muted_until
+         FROM flood_incident
+         ORDER BY created_at DESC
+         LIMIT $TOP_N"
+fi
+
+# Per-user usage accounting (pollis-delivery/src/usage.rs), when the DS runs
+# with USAGE_ACCOUNTING on. Last 30 days, heaviest first.
+if has_table usage_daily; then
+  echo
+  echo "== Top $TOP_N users by usage, last 30 days (user, envelopes, envelope bytes, attachments, attachment bytes) =="
+  query "SELECT user_id, SUM(envelopes), SUM(envelope_bytes), SUM(attachments_presigned),
+                SUM(attachment_bytes) AS att_bytes
+         FROM usage_daily
+         WHERE day > date('now', '-30 days')
+         GROUP BY user_id
+         ORDER BY SUM(envelope_bytes) + att_bytes DESC
+         LIMIT $TOP_N"
+fi
@@ -0,0 +1,184 @@
+//! Launch-at-login — per-OS, no plugin.
+//!
+//!   - Linux: an XDG autostart entry at `$XDG_CONFIG_HOME/autostart/pollis.desktop`
+//!     (falls back to `~/.config`). AppImage builds register `$APPIMAGE`, not
+//!     the ephemeral squashfs mount the binary runs from.
+//!   - macOS: a per-user LaunchAgent at `~/Library/LaunchAgents/<id>.plist`
+//!     with `RunAtLoad`.
+//!   - Windows: a `HKCU\…\CurrentVersion\Run` value, written with `reg.exe`
+//!     so no registry crate is needed.
+//!
+//! The OS entry *is* the setting — there's nothing in the prefs mirror, so the
+//! toggle is naturally per-device. Every entry launches with [`HIDDEN_ARG`],
+//! which `lib.rs` uses to start straight into the tray (when one exists)
+//! instead of popping the window at login.
+
+use std::path::PathBuf;
+
+/// Passed by every autostart entry; the app starts hidden in the tray.
+pub const HIDDEN_ARG: &str = "--hidden";
+
+const ENTRY_NAME: &str = "Pollis";
+
+/// Executable the login entry should launch.
+fn launch_target() -> Result<PathBuf, String> {
+    #[cfg(target_os = "linux")]
+    if let Some(appimage) = std::env::var_os("APPIMAGE") {
+        return Ok(PathBuf::from(appimage));
+    }
+    std::env::current_exe().map_err(|e| format!("cannot resolve executable: {e}"))
+}
+
+#[cfg(any(target_os = "linux", target_os = "macos"))]
+fn home_dir() -> Result<PathBuf, String> {
+    std::env::var_os("HOME")
+        .map(PathBuf::from)
+        .ok_or_else(|| "HOME is not set".to_string())
+}
+
+#[cfg(target_os = "linux")]
+fn entry_path() -> Result<PathBuf, String> {
+    let config = match std::env::var_os("XDG_CONFIG_HOME") {
+        Some(dir) if !dir.is_empty() => PathBuf::from(dir),
+        _ => home_dir()?.join(".config"),
+    };
+    Ok(config.join("autostart").join("pollis.desktop"))
+}
+
+#[cfg(target_os = "linux")]
+fn entry_contents(exe: &std::path::Path) -> String {
+    format!(
+        "[Desktop Entry]\nType=Application\nName={ENTRY_NAME}\nExec=\"{}\" {HIDDEN_ARG}\nX-GNOME-Autostart-enabled=true\nNoDisplay=true\n",
+        exe.display()
+    )
+}
+
+#[cfg(target_os = "macos")]
+fn entry_path(identifier: &str) -> Result<PathBuf, String> {
+    Ok(home_dir()?
+        .join("Library")
+        .join("LaunchAgents")
+        .join(format!("{identifier}.plist")))
+}
+
+#[cfg(target_os = "macos")]
+fn entry_contents(identifier: &str, exe: &std::path::Path) -> String {
+    format!(
+        r#"<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>{identifier}</string>
+  <key>ProgramArguments</key>
+  <array>
+    <string>{}</string>
+    <string>{HIDDEN_ARG}</string>
+  </array>
+  <key>RunAtLoad</key>
+  <true/>
+</dict>
+</plist>
+"#,
+        exe.display()
+    )
+}
+
+#[cfg(target_os = "windows")]
+const RUN_KEY: &str = r"HKCU\Software\Microsoft\Windows\CurrentVersion\Run";
+
+#[cfg(target_os = "windows")]
+fn reg(args: &[&str]) -> std::io::Result<std::process::Output> {
+    use std::os::windows::process::CommandExt;
+    // CREATE_NO_WINDOW — keep reg.exe from flashing a console.
+    std::process::Command::new("reg")
+        .args(args)
+        .creation_flags(0x0800_0000)
+        .output()
+}
+
+#[cfg(target_os = "linux")]
+fn is_enabled(_app: &tauri::AppHandle) -> Result<bool, String> {
+    Ok(entry_path()?.exists())
+}
+
+#[cfg(target_os = "linux")]
+fn set_enabled(_app: &tauri::AppHandle, enabled: bool) -> Result<(), String> {
+    let path = entry_path()?;
+    if !enabled {
+        return match std::fs::remove_file(&path) {
+            Err(e) if e.kind() != std::io::ErrorKind::NotFound => Err(e.to_string()),
+            _ => Ok(()),
+        };
+    }
+    if let Some(dir) = path.parent() {
+        std::fs::create_dir_all(dir).map_err(|e| e.to_string())?;
+    }
+    std::fs::write(&path, entry_contents(&launch_target()?)).map_err(|e| e.to_string())
+}
+
+#[cfg(target_os = "macos")]
+fn is_enabled(app: &tauri::AppHandle) -> Result<bool, String> {
+    Ok(entry_path(&app.config().identifier)?.exists())
+}
+
+#[cfg(target_os = "macos")]
+fn set_enabled(app: &tauri::AppHandle, enabled: bool) -> Result<(), String> {
+    let identifier = app.config().identifier.clone();
+    let path = entry_path(&identifier)?;
+    if !enabled {
+        return match std::fs::remove_file(&path) {
+            Err(e) if e.kind() != std::io::ErrorKind::NotFound => Err(e.to_string()),
+            _ => Ok(()),
+        };
+    }
+    if let Some(dir) = path.parent() {
+        std::fs::create_dir_all(dir).map_err(|e| e.to_string())?;
+    }
+    std::fs::write(&path, entry_contents(&identifier, &launch_target()?))
+        .map_err(|e| e.to_string())
+}
+
+#[cfg(target_os = "windows")]
+fn is_enabled(_app: &tauri::AppHandle) -> Result<bool, String> {
+    let out = reg(&["query", RUN_KEY, "/v", ENTRY_NAME]).map_err(|e| e.to_string())?;
+    Ok(out.status.success())
+}
+
+#[cfg(target_os = "windows")]
+fn set_enabled(app: &tauri::AppHandle, enabled: bool) -> Result<(), String> {
+    let out = if enabled {
+        let value = format!("\"{}\" {HIDDEN_ARG}", launch_target()?.display());
+        reg(&["add", RUN_KEY, "/v", ENTRY_NAME, "/t", "REG_SZ", "/d", &value, "/f"])
+    } else {
+        if !is_enabled(app)? {
+            return Ok(());
+        }
+        reg(&["delete", RUN_KEY, "/v", ENTRY_NAME, "/f"])
+    }
+    .map_err(|e| e.to_string())?;
+    if out.status.success() {
+        Ok(())
+    } else {
+        Err(String::from_utf8_lossy(&out.stderr).trim().to_string())
+    }
+}
+
+/// True when this process was started by a launch-at-login entry.
+pub fn launched_hidden() -> bool {
+    std::env::args().any(|a| a == HIDDEN_ARG)
+}
+
+// ── Commands (invoked from the renderer via the bridge) ──────────────────────
+
+/// Whether a launch-at-login entry for this user exists.
+#[tauri::command]
+pub fn autostart_is_enabled(app: tauri::AppHandle) -> Result<bool, String> {
+    is_enabled(&app)
+}
+
+/// Create or remove the launch-at-login entry. Idempotent both ways.
+#[tauri::command]
+pub fn autostart_set_enabled(app: tauri::AppHandle, enabled: bool) -> Result<(), String> {
+    set_enabled(&app, enabled)
+}
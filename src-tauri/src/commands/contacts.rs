@@ -0,0 +1,29 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::contacts::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use std::sync::Arc;
+use tauri::State;
+
+use crate::error::Result;
+use crate::state::AppState;
+pub use pollis_core::commands::contacts::*;
+
+#[tauri::command]
+pub async fn set_contact_nickname(user_id: String, nickname: Option<String>, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::contacts::set_contact_nickname(user_id, nickname, &state).await
+}
+
+#[tauri::command]
+pub async fn set_contact_notes(user_id: String, notes: Option<String>, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::contacts::set_contact_notes(user_id, notes, &state).await
+}
+
+#[tauri::command]
+pub async fn get_contact_note(user_id: String, state: State<'_, Arc<AppState>>) -> Result<Option<ContactNote>> {
+    pollis_core::commands::contacts::get_contact_note(user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn list_contact_notes(state: State<'_, Arc<AppState>>) -> Result<Vec<ContactNote>> {
+    pollis_core::commands::contacts::list_contact_notes(&state).await
+}
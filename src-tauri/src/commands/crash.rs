@@ -0,0 +1,20 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::crash::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use crate::error::Result;
+pub use pollis_core::commands::crash::*;
+
+#[tauri::command]
+pub fn list_crash_reports() -> Result<Vec<CrashReportSummary>> {
+    pollis_core::commands::crash::list_crash_reports()
+}
+
+#[tauri::command]
+pub fn acknowledge_crash_reports() -> Result<()> {
+    pollis_core::commands::crash::acknowledge_crash_reports()
+}
+
+#[tauri::command]
+pub fn export_crash_reports() -> Result<String> {
+    pollis_core::commands::crash::export_crash_reports()
+}
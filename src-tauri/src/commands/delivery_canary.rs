@@ -0,0 +1,21 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::delivery_canary::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use crate::error::Result;
+pub use pollis_core::commands::delivery_canary::*;
+
+#[tauri::command]
+pub async fn get_delivery_canary_report() -> Result<DeliveryCanaryReport> {
+    pollis_core::commands::delivery_canary::get_delivery_canary_report().await
+}
+
+#[tauri::command]
+pub async fn acknowledge_delivery_canary_alert() -> Result<DeliveryCanaryReport> {
+    pollis_core::commands::delivery_canary::acknowledge_delivery_canary_alert().await
+}
+
+#[tauri::command]
+pub async fn subscribe_delivery_canary_alerts(on_event: tauri::ipc::Channel<pollis_core::commands::delivery_canary::DeliveryCanaryReport>) -> Result<()> {
+    pollis_core::commands::delivery_canary::subscribe_delivery_canary_alerts(std::sync::Arc::new(crate::sink::ChannelSink(on_event)));
+    Ok(())
+}
@@ -0,0 +1,10 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::delivery_latency::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use crate::error::Result;
+pub use pollis_core::commands::delivery_latency::*;
+
+#[tauri::command]
+pub async fn get_delivery_latency_stats() -> Result<DeliveryLatencyStats> {
+    pollis_core::commands::delivery_latency::get_delivery_latency_stats().await
+}
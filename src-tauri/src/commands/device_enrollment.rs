@@ -43,6 +43,11 @@ pub async fn reset_identity_and_recover(state: State<'_, Arc<AppState>>, user_id
     pollis_core::commands::device_enrollment::reset_identity_and_recover(&state, user_id, confirm_email).await
 }
 
+#[tauri::command]
+pub async fn rotate_account_identity(state: State<'_, Arc<AppState>>, user_id: String, pin: String) -> Result<String> {
+    pollis_core::commands::device_enrollment::rotate_account_identity(&state, user_id, pin).await
+}
+
 #[tauri::command]
 pub async fn finalize_device_enrollment(state: State<'_, Arc<AppState>>, user_id: String) -> Result<()> {
     pollis_core::commands::device_enrollment::finalize_device_enrollment(&state, user_id).await
@@ -24,8 +24,8 @@ pub async fn list_group_channels(group_id: String, state: State<'_, Arc<AppState
 }
 
 #[tauri::command]
-pub async fn create_group(name: String, description: Option<String>, owner_id: String, create_default_text_channel: Option<bool>, create_default_voice_channel: Option<bool>, state: State<'_, Arc<AppState>>) -> Result<Group> {
-    pollis_core::commands::groups::create_group(name, description, owner_id, create_default_text_channel, create_default_voice_channel, &state).await
+pub async fn create_group(name: String, description: Option<String>, owner_id: String, create_default_text_channel: Option<bool>, create_default_voice_channel: Option<bool>, slug: Option<String>, state: State<'_, Arc<AppState>>) -> Result<Group> {
+    pollis_core::commands::groups::create_group(name, description, owner_id, create_default_text_channel, create_default_voice_channel, slug, &state).await
 }
 
 #[tauri::command]
@@ -38,6 +38,11 @@ pub async fn send_group_invite(group_id: String, inviter_id: String, invitee_ide
     pollis_core::commands::groups::send_group_invite(group_id, inviter_id, invitee_identifier, &state).await
 }
 
+#[tauri::command]
+pub async fn redeem_email_invite(token: String, user_id: String, state: State<'_, Arc<AppState>>) -> Result<String> {
+    pollis_core::commands::groups::redeem_email_invite(token, user_id, &state).await
+}
+
 #[tauri::command]
 pub async fn get_pending_invites(user_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<PendingInvite>> {
     pollis_core::commands::groups::get_pending_invites(user_id, &state).await
@@ -88,19 +93,64 @@ pub async fn delete_group(group_id: String, requester_id: String, state: State<'
     pollis_core::commands::groups::delete_group(group_id, requester_id, &state).await
 }
 
+#[tauri::command]
+pub async fn cancel_group_deletion(group_id: String, requester_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::groups::cancel_group_deletion(group_id, requester_id, &state).await
+}
+
+#[tauri::command]
+pub async fn forget_group_history(group_id: String, state: State<'_, Arc<AppState>>) -> Result<usize> {
+    pollis_core::commands::groups::forget_group_history(group_id, &state).await
+}
+
+#[tauri::command]
+pub async fn set_group_export_policy(group_id: String, requester_id: String, allow_export: bool, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::groups::set_group_export_policy(group_id, requester_id, allow_export, &state).await
+}
+
+#[tauri::command]
+pub async fn list_storage_targets(state: State<'_, Arc<AppState>>) -> Result<Vec<StorageTarget>> {
+    pollis_core::commands::groups::list_storage_targets(&state).await
+}
+
+#[tauri::command]
+pub async fn set_group_storage_target(group_id: String, requester_id: String, storage_target: Option<String>, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::groups::set_group_storage_target(group_id, requester_id, storage_target, &state).await
+}
+
+#[tauri::command]
+pub async fn list_group_webhooks(group_id: String, requester_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<GroupWebhook>> {
+    pollis_core::commands::groups::list_group_webhooks(group_id, requester_id, &state).await
+}
+
+#[tauri::command]
+pub async fn create_group_webhook(group_id: String, requester_id: String, url: String, events: Vec<String>, state: State<'_, Arc<AppState>>) -> Result<CreatedWebhook> {
+    pollis_core::commands::groups::create_group_webhook(group_id, requester_id, url, events, &state).await
+}
+
+#[tauri::command]
+pub async fn delete_group_webhook(webhook_id: String, requester_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::groups::delete_group_webhook(webhook_id, requester_id, &state).await
+}
+
 #[tauri::command]
 pub async fn get_group_members(group_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<GroupMember>> {
     pollis_core::commands::groups::get_group_members(group_id, &state).await
 }
 
+#[tauri::command]
+pub async fn get_group_events(group_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<GroupEvent>> {
+    pollis_core::commands::groups::get_group_events(group_id, &state).await
+}
+
 #[tauri::command]
 pub async fn remove_member_from_group(group_id: String, user_id: String, requester_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
     pollis_core::commands::groups::remove_member_from_group(group_id, user_id, requester_id, &state).await
 }
 
 #[tauri::command]
-pub async fn leave_group(group_id: String, user_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
-    pollis_core::commands::groups::leave_group(group_id, user_id, &state).await
+pub async fn leave_group(group_id: String, user_id: String, delete_history: Option<bool>, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::groups::leave_group(group_id, user_id, delete_history, &state).await
 }
 
 #[tauri::command]
@@ -108,6 +158,11 @@ pub async fn update_channel(channel_id: String, requester_id: String, name: Opti
     pollis_core::commands::groups::update_channel(channel_id, requester_id, name, description, &state).await
 }
 
+#[tauri::command]
+pub async fn set_channel_retention(channel_id: String, requester_id: String, days: i64, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::groups::set_channel_retention(channel_id, requester_id, days, &state).await
+}
+
 #[tauri::command]
 pub async fn delete_channel(channel_id: String, requester_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
     pollis_core::commands::groups::delete_channel(channel_id, requester_id, &state).await
@@ -118,7 +173,52 @@ pub async fn set_member_role(group_id: String, user_id: String, role: String, re
     pollis_core::commands::groups::set_member_role(group_id, user_id, role, requester_id, &state).await
 }
 
+#[tauri::command]
+pub async fn transfer_group_ownership(group_id: String, requester_id: String, new_owner_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::groups::transfer_group_ownership(group_id, requester_id, new_owner_id, &state).await
+}
+
+#[tauri::command]
+pub async fn get_pending_ownership_transfers(user_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<OwnershipTransfer>> {
+    pollis_core::commands::groups::get_pending_ownership_transfers(user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn accept_group_ownership(group_id: String, user_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::groups::accept_group_ownership(group_id, user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn decline_group_ownership(group_id: String, user_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::groups::decline_group_ownership(group_id, user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn export_group_structure(group_id: String, requester_id: String, state: State<'_, Arc<AppState>>) -> Result<GroupStructure> {
+    pollis_core::commands::groups::export_group_structure(group_id, requester_id, &state).await
+}
+
 #[tauri::command]
 pub async fn search_group_by_slug(slug: String, state: State<'_, Arc<AppState>>) -> Result<Group> {
     pollis_core::commands::groups::search_group_by_slug(slug, &state).await
 }
+
+#[tauri::command]
+pub async fn normalize_slug(input: String, state: State<'_, Arc<AppState>>) -> Result<NormalizedSlug> {
+    pollis_core::commands::groups::normalize_slug(input, &state).await
+}
+
+#[tauri::command]
+pub async fn reserve_group_slug(slug: String, user_id: String, state: State<'_, Arc<AppState>>) -> Result<String> {
+    pollis_core::commands::groups::reserve_group_slug(slug, user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn get_group_join_code(group_id: String, user_id: String, state: State<'_, Arc<AppState>>) -> Result<GroupJoinCode> {
+    pollis_core::commands::groups::get_group_join_code(group_id, user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn resolve_group_join_code(payload: String, state: State<'_, Arc<AppState>>) -> Result<Group> {
+    pollis_core::commands::groups::resolve_group_join_code(payload, &state).await
+}
@@ -0,0 +1,19 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::links::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use std::sync::Arc;
+use tauri::State;
+
+use crate::error::Result;
+use crate::state::AppState;
+pub use pollis_core::commands::links::*;
+
+#[tauri::command]
+pub async fn check_link(url: String, state: State<'_, Arc<AppState>>) -> Result<LinkCheck> {
+    pollis_core::commands::links::check_link(url, &state).await
+}
+
+#[tauri::command]
+pub async fn check_links(urls: Vec<String>, state: State<'_, Arc<AppState>>) -> Result<Vec<LinkCheck>> {
+    pollis_core::commands::links::check_links(urls, &state).await
+}
@@ -0,0 +1,34 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::local_backup::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use std::sync::Arc;
+use tauri::State;
+
+use crate::error::Result;
+use crate::state::AppState;
+pub use pollis_core::commands::local_backup::*;
+
+#[tauri::command]
+pub async fn list_local_backups(user_id: String) -> Result<Vec<LocalBackup>> {
+    pollis_core::commands::local_backup::list_local_backups(user_id).await
+}
+
+#[tauri::command]
+pub async fn create_local_backup(user_id: String, state: State<'_, Arc<AppState>>) -> Result<Option<LocalBackup>> {
+    pollis_core::commands::local_backup::create_local_backup(user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn restore_local_backup(user_id: String, timestamp: String, state: State<'_, Arc<AppState>>) -> Result<usize> {
+    pollis_core::commands::local_backup::restore_local_backup(user_id, timestamp, &state).await
+}
+
+#[tauri::command]
+pub async fn get_local_backup_count(state: State<'_, Arc<AppState>>) -> Result<i64> {
+    pollis_core::commands::local_backup::get_local_backup_count(&state).await
+}
+
+#[tauri::command]
+pub async fn set_local_backup_count(user_id: String, count: i64, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::local_backup::set_local_backup_count(user_id, count, &state).await
+}
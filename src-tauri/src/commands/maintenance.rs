@@ -0,0 +1,20 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::maintenance::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use std::sync::Arc;
+use tauri::State;
+
+use crate::error::Result;
+use crate::state::AppState;
+pub use pollis_core::commands::maintenance::*;
+
+#[tauri::command]
+pub async fn get_maintenance_status(state: State<'_, Arc<AppState>>) -> Result<MaintenanceStatus> {
+    pollis_core::commands::maintenance::get_maintenance_status(&state).await
+}
+
+#[tauri::command]
+pub async fn subscribe_maintenance_events(on_event: tauri::ipc::Channel<pollis_core::commands::maintenance::MaintenanceStatus>) -> Result<()> {
+    pollis_core::commands::maintenance::subscribe_maintenance_events(std::sync::Arc::new(crate::sink::ChannelSink(on_event)));
+    Ok(())
+}
@@ -39,12 +39,27 @@ pub async fn read_dm_messages(dm_channel_id: String, limit: Option<i64>, cursor:
 }
 
 #[tauri::command]
-pub async fn ingest_channel_envelopes(user_id: String, channel_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
+pub async fn read_filtered_messages(conversation_id: String, filter: MessageFilter, limit: Option<i64>, cursor: Option<MessageCursor>, state: State<'_, Arc<AppState>>) -> Result<MessagePage> {
+    pollis_core::commands::messages::read_filtered_messages(conversation_id, filter, limit, cursor, &state).await
+}
+
+#[tauri::command]
+pub async fn get_channel_activity(channel_id: String, range: ActivityRange, state: State<'_, Arc<AppState>>) -> Result<ChannelActivity> {
+    pollis_core::commands::messages::get_channel_activity(channel_id, range, &state).await
+}
+
+#[tauri::command]
+pub async fn get_most_active_channels(range: ActivityRange, limit: Option<i64>, state: State<'_, Arc<AppState>>) -> Result<Vec<ActiveChannel>> {
+    pollis_core::commands::messages::get_most_active_channels(range, limit, &state).await
+}
+
+#[tauri::command]
+pub async fn ingest_channel_envelopes(user_id: String, channel_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<IngestedMessages>> {
     pollis_core::commands::messages::ingest_channel_envelopes(user_id, channel_id, &state).await
 }
 
 #[tauri::command]
-pub async fn ingest_dm_envelopes(user_id: String, dm_channel_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
+pub async fn ingest_dm_envelopes(user_id: String, dm_channel_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<IngestedMessages>> {
     pollis_core::commands::messages::ingest_dm_envelopes(user_id, dm_channel_id, &state).await
 }
 
@@ -58,6 +73,57 @@ pub async fn list_channel_previews(user_id: String, state: State<'_, Arc<AppStat
     pollis_core::commands::messages::list_channel_previews(user_id, &state).await
 }
 
+#[tauri::command]
+pub async fn broadcast_announcement(sender_id: String, channel_ids: Vec<String>, content: String, sender_username: Option<String>, state: State<'_, Arc<AppState>>) -> Result<BroadcastReport> {
+    pollis_core::commands::messages::broadcast_announcement(sender_id, channel_ids, content, sender_username, &state).await
+}
+
+#[tauri::command]
+pub async fn export_channel_messages(user_id: String, channel_id: String, format: String, from: Option<String>, to: Option<String>, state: State<'_, Arc<AppState>>) -> Result<String> {
+    pollis_core::commands::messages::export_channel_messages(user_id, channel_id, format, from, to, &state).await
+}
+
+#[tauri::command]
+pub async fn export_group_attachments(user_id: String, group_id: String, dest_path: String, state: State<'_, Arc<AppState>>) -> Result<AttachmentExportSummary> {
+    pollis_core::commands::messages::export_group_attachments(user_id, group_id, dest_path, &state).await
+}
+
+#[tauri::command]
+pub async fn pause_attachment_export() -> Result<()> {
+    pollis_core::commands::messages::pause_attachment_export();
+    Ok(())
+}
+
+#[tauri::command]
+pub async fn translate_message(message_id: String, target_lang: Option<String>, state: State<'_, Arc<AppState>>) -> Result<String> {
+    pollis_core::commands::messages::translate_message(message_id, target_lang, &state).await
+}
+
+#[tauri::command]
+pub async fn get_translation_backend(state: State<'_, Arc<AppState>>) -> Result<Option<String>> {
+    pollis_core::commands::messages::get_translation_backend(&state).await
+}
+
+#[tauri::command]
+pub async fn set_translation_backend(path: Option<String>, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::messages::set_translation_backend(path, &state).await
+}
+
+#[tauri::command]
+pub async fn get_conversation_translation_language(conversation_id: String, state: State<'_, Arc<AppState>>) -> Result<Option<String>> {
+    pollis_core::commands::messages::get_conversation_translation_language(conversation_id, &state).await
+}
+
+#[tauri::command]
+pub async fn set_conversation_translation_language(conversation_id: String, target_lang: Option<String>, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::messages::set_conversation_translation_language(conversation_id, target_lang, &state).await
+}
+
+#[tauri::command]
+pub async fn list_conversation_previews(state: State<'_, Arc<AppState>>) -> Result<Vec<ConversationPreview>> {
+    pollis_core::commands::messages::list_conversation_previews(&state).await
+}
+
 #[tauri::command]
 pub async fn search_messages(query: String, limit: Option<i64>, state: State<'_, Arc<AppState>>) -> Result<Vec<SearchResult>> {
     pollis_core::commands::messages::search_messages(query, limit, &state).await
@@ -78,6 +144,21 @@ pub async fn get_reactions(message_id: String, state: State<'_, Arc<AppState>>)
     pollis_core::commands::messages::get_reactions(message_id, &state).await
 }
 
+#[tauri::command]
+pub async fn pin_message(conversation_id: String, message_id: String, user_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::messages::pin_message(conversation_id, message_id, user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn unpin_message(conversation_id: String, message_id: String, user_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::messages::unpin_message(conversation_id, message_id, user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn get_pinned_messages(conversation_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<PinnedMessage>> {
+    pollis_core::commands::messages::get_pinned_messages(conversation_id, &state).await
+}
+
 #[tauri::command]
 pub async fn delete_message(message_id: String, user_id: String, state: State<'_, Arc<AppState>>) -> Result<()> {
     pollis_core::commands::messages::delete_message(message_id, user_id, &state).await
@@ -22,3 +22,19 @@ pub async fn process_pending_commits(state: State<'_, Arc<AppState>>, conversati
 pub async fn catch_up_all_mls_groups(state: State<'_, Arc<AppState>>, user_id: String) -> crate::error::Result<()> {
     pollis_core::commands::mls::catch_up_all_mls_groups(&state, &user_id).await
 }
+
+#[tauri::command]
+pub async fn get_crypto_obligations() -> Result<CryptoObligations> {
+    Ok(pollis_core::commands::mls::get_crypto_obligations())
+}
+
+#[tauri::command]
+pub async fn retry_crypto_obligations(state: State<'_, Arc<AppState>>, user_id: String) -> Result<CryptoObligations> {
+    pollis_core::commands::mls::retry_crypto_obligations(&state, user_id).await
+}
+
+#[tauri::command]
+pub async fn subscribe_crypto_obligation_events(on_event: tauri::ipc::Channel<pollis_core::commands::mls::CryptoObligations>) -> Result<()> {
+    pollis_core::commands::mls::subscribe_crypto_obligation_events(std::sync::Arc::new(crate::sink::ChannelSink(on_event)));
+    Ok(())
+}
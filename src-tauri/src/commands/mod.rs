@@ -2,10 +2,17 @@
 // install_kind stays in src-tauri because it inspects Tauri's bundle metadata.
 pub mod auth;
 pub mod blocks;
+pub mod contacts;
+pub mod crash;
+pub mod delivery_canary;
+pub mod delivery_latency;
 pub mod device_enrollment;
 pub mod dm;
 pub mod groups;
 pub mod install_kind;
+pub mod links;
+pub mod local_backup;
+pub mod maintenance;
 // OS-level media permissions (camera/mic/screen). Like tray.rs it is built
 // from shell-runtime concerns (TCC, the ConsentStore registry, ms-settings
 // deep-links), so it's native-shell-only and never touches pollis-core.
@@ -16,7 +23,10 @@ pub mod mls;
 pub mod overlay;
 pub mod pin;
 pub mod r2;
+pub mod reminders;
 pub mod safety;
+pub mod sidebar;
+pub mod startup;
 pub mod terminal;
 pub mod transparency;
 pub mod update;
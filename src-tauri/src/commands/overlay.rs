@@ -17,3 +17,8 @@ pub async fn get_overlay_mode(state: State<'_, Arc<AppState>>) -> Result<String>
 pub async fn set_overlay_mode(mode: String, state: State<'_, Arc<AppState>>) -> Result<()> {
     pollis_core::commands::overlay::set_overlay_mode(&state, mode).await
 }
+
+#[tauri::command]
+pub async fn get_relay_latency() -> Result<Vec<pollis_core::net::relay_latency::RelayLatencyReport>> {
+    pollis_core::commands::overlay::get_relay_latency().await
+}
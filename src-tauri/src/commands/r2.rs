@@ -14,8 +14,8 @@ pub async fn upload_file(key: String, data: Vec<u8>, content_type: String, state
 }
 
 #[tauri::command]
-pub async fn upload_media(path: String, filename: String, content_type: String, state: State<'_, Arc<AppState>>) -> Result<MediaUploadResult> {
-    pollis_core::commands::r2::upload_media(path, filename, content_type, &state).await
+pub async fn upload_media(path: String, filename: String, content_type: String, group_id: Option<String>, dm_channel_id: Option<String>, state: State<'_, Arc<AppState>>) -> Result<MediaUploadResult> {
+    pollis_core::commands::r2::upload_media(path, filename, content_type, group_id, dm_channel_id, &state).await
 }
 
 #[tauri::command]
@@ -32,3 +32,9 @@ pub async fn download_media(r2_key: String, content_hash: String, state: State<'
 pub async fn get_media_url(r2_key: String, content_hash: String, content_type: String, state: State<'_, Arc<AppState>>) -> Result<String> {
     pollis_core::commands::r2::get_media_url(r2_key, content_hash, content_type, &state).await
 }
+
+#[tauri::command]
+pub async fn subscribe_media_download_events(on_event: tauri::ipc::Channel<pollis_core::commands::r2::MediaDownloadProgress>) -> Result<()> {
+    pollis_core::commands::r2::subscribe_media_download_events(std::sync::Arc::new(crate::sink::ChannelSink(on_event)));
+    Ok(())
+}
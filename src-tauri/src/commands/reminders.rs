@@ -0,0 +1,24 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::reminders::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use std::sync::Arc;
+use tauri::State;
+
+use crate::error::Result;
+use crate::state::AppState;
+pub use pollis_core::commands::reminders::*;
+
+#[tauri::command]
+pub async fn list_reminders(user_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<Reminder>> {
+    pollis_core::commands::reminders::list_reminders(user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn create_reminder(user_id: String, message_id: String, at: i64, state: State<'_, Arc<AppState>>) -> Result<Reminder> {
+    pollis_core::commands::reminders::create_reminder(user_id, message_id, at, &state).await
+}
+
+#[tauri::command]
+pub async fn delete_reminder(user_id: String, reminder_id: String, state: State<'_, Arc<AppState>>) -> Result<Vec<Reminder>> {
+    pollis_core::commands::reminders::delete_reminder(user_id, reminder_id, &state).await
+}
@@ -13,6 +13,11 @@ pub async fn get_safety_number(my_user_id: String, peer_user_id: String, state:
     pollis_core::commands::safety::get_safety_number(my_user_id, peer_user_id, &state).await
 }
 
+#[tauri::command]
+pub async fn get_identity_fingerprint(user_id: String, state: State<'_, Arc<AppState>>) -> Result<IdentityFingerprint> {
+    pollis_core::commands::safety::get_identity_fingerprint(user_id, &state).await
+}
+
 #[tauri::command]
 pub async fn set_contact_verified(peer_user_id: String, verified: bool, state: State<'_, Arc<AppState>>) -> Result<()> {
     pollis_core::commands::safety::set_contact_verified(peer_user_id, verified, &state).await
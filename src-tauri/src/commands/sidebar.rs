@@ -0,0 +1,24 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::sidebar::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use std::sync::Arc;
+use tauri::State;
+
+use crate::error::Result;
+use crate::state::AppState;
+pub use pollis_core::commands::sidebar::*;
+
+#[tauri::command]
+pub async fn get_sidebar_order(state: State<'_, Arc<AppState>>) -> Result<SidebarOrder> {
+    pollis_core::commands::sidebar::get_sidebar_order(&state).await
+}
+
+#[tauri::command]
+pub async fn pin_conversation(user_id: String, target_id: String, pinned: bool, state: State<'_, Arc<AppState>>) -> Result<SidebarOrder> {
+    pollis_core::commands::sidebar::pin_conversation(user_id, target_id, pinned, &state).await
+}
+
+#[tauri::command]
+pub async fn set_sidebar_order(user_id: String, ids: Vec<String>, state: State<'_, Arc<AppState>>) -> Result<SidebarOrder> {
+    pollis_core::commands::sidebar::set_sidebar_order(user_id, ids, &state).await
+}
@@ -0,0 +1,10 @@
+// Generated shim file. Each #[tauri::command] forwards to pollis_core::commands::startup::*. Edit pollis-core, not here.
+
+#![allow(unused_imports)]
+use crate::error::Result;
+pub use pollis_core::commands::startup::*;
+
+#[tauri::command]
+pub fn get_startup_timings() -> Result<Vec<StartupStage>> {
+    Ok(pollis_core::commands::startup::get_startup_timings())
+}
@@ -32,3 +32,18 @@ pub async fn get_preferences(user_id: String, state: State<'_, Arc<AppState>>) -
 pub async fn save_preferences(user_id: String, preferences_json: String, state: State<'_, Arc<AppState>>) -> Result<()> {
     pollis_core::commands::user::save_preferences(user_id, preferences_json, &state).await
 }
+
+#[tauri::command]
+pub async fn get_inactivity_policy(user_id: String, state: State<'_, Arc<AppState>>) -> Result<Option<InactivityPolicy>> {
+    pollis_core::commands::user::get_inactivity_policy(user_id, &state).await
+}
+
+#[tauri::command]
+pub async fn set_inactivity_policy(user_id: String, inactive_days: Option<u32>, delete_recovery: bool, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::user::set_inactivity_policy(user_id, inactive_days, delete_recovery, &state).await
+}
+
+#[tauri::command]
+pub async fn get_my_usage(user_id: String, days: Option<u32>, state: State<'_, Arc<AppState>>) -> Result<UsageReport> {
+    pollis_core::commands::user::get_my_usage(user_id, days, &state).await
+}
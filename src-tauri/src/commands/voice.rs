@@ -38,6 +38,11 @@ pub async fn toggle_voice_mute(state: State<'_, Arc<AppState>>) -> Result<bool>
     pollis_core::commands::voice::toggle_voice_mute(&state).await
 }
 
+#[tauri::command]
+pub async fn set_voice_push_to_talk(pressed: bool, state: State<'_, Arc<AppState>>) -> Result<()> {
+    pollis_core::commands::voice::set_voice_push_to_talk(pressed, &state).await
+}
+
 #[tauri::command]
 pub async fn set_remote_user_volume(user_id: String, volume: f32, state: State<'_, Arc<AppState>>) -> Result<()> {
     pollis_core::commands::voice::set_remote_user_volume(user_id, volume, &state).await
@@ -13,6 +13,9 @@ pub mod commands;
 // only compiled with the native shell.
 #[cfg(feature = "native-shell")]
 pub mod tray;
+// Launch-at-login entries (XDG autostart / LaunchAgent / Run key).
+#[cfg(feature = "native-shell")]
+pub mod autostart;
 
 #[cfg(feature = "test-harness")]
 pub mod test_harness;
@@ -310,6 +313,11 @@ pub fn run() {
         std::env::set_var("GST_AUDIO_SINK", "pulsesink");
     }
 
+    // Write scrubbed crash reports (pollis_core::commands::crash). Installed
+    // before anything else runs; reports only land once setup has plumbed in
+    // the crash directory below.
+    pollis_core::commands::crash::install_panic_hook();
+
     tauri::Builder::default()
         .plugin(tauri_plugin_shell::init())
         .plugin(tauri_plugin_dialog::init())
@@ -324,6 +332,7 @@ pub fn run() {
             #[cfg(debug_assertions)]
             let _ = dotenvy::from_filename(".env.development");
 
+            let setup_started = std::time::Instant::now();
             let config = Config::from_env().map_err(|e| e.to_string())?;
 
             // Owned app handle captured before `app` is moved into the async
@@ -359,36 +368,52 @@ pub fn run() {
                 let cache_dir = data_dir.join("media-cache");
                 let _ = std::fs::create_dir_all(&cache_dir);
                 pollis_core::commands::r2::set_media_cache_dir(cache_dir);
+                pollis_core::commands::crash::set_crash_dir(data_dir.join("crash-reports"));
             }
 
             tauri::async_runtime::block_on(async move {
+                use pollis_core::commands::startup::record_startup_stage;
+
+                let started = std::time::Instant::now();
                 let state = AppState::new(config).await.map_err(|e| e.to_string())?;
                 let state = Arc::new(state);
+                record_startup_stage("remote_db", started);
 
                 // Honor POLLIS_OVERLAY at boot through the runtime apply path
                 // (design §14: construct DBs direct, then apply the mode). A
                 // failure here must not block startup — it leaves the overlay off
                 // (direct), the safe default.
-                let boot_mode = state.config.overlay_mode;
-                if let Err(e) =
-                    pollis_core::commands::overlay::apply_overlay_mode(&state, boot_mode).await
-                {
-                    eprintln!("[overlay] boot apply ({boot_mode:?}) failed, staying direct: {e}");
-                }
+                let overlay = async {
+                    let started = std::time::Instant::now();
+                    let boot_mode = state.config.overlay_mode;
+                    if let Err(e) =
+                        pollis_core::commands::overlay::apply_overlay_mode(&state, boot_mode).await
+                    {
+                        eprintln!("[overlay] boot apply ({boot_mode:?}) failed, staying direct: {e}");
+                    }
+                    record_startup_stage("overlay", started);
+                };
 
                 // Loopback HTTP server for cached media. The webview
                 // embeds `http://127.0.0.1:<port>/<token>/<hash>` URLs
                 // for every `<img>/<audio>/<video>` element. Spawned
                 // before `manage` so the port is on `AppState` by the
-                // time any frontend code runs.
-                match pollis_core::media_server::spawn(state.clone()).await {
-                    Ok(port) => {
-                        *state.media_server_port.lock().await = Some(port);
-                    }
-                    Err(e) => {
-                        eprintln!("[setup] failed to spawn media server: {e}");
+                // time any frontend code runs. Independent of the
+                // overlay, so the two run concurrently.
+                let media_server = async {
+                    let started = std::time::Instant::now();
+                    match pollis_core::media_server::spawn(state.clone()).await {
+                        Ok(port) => {
+                            *state.media_server_port.lock().await = Some(port);
+                        }
+                        Err(e) => {
+                            eprintln!("[setup] failed to spawn media server: {e}");
+                        }
                     }
-                }
+                    record_startup_stage("media_server", started);
+                };
+
+                tokio::join!(overlay, media_server);
 
                 app.manage(state);
                 Ok::<(), String>(())
@@ -399,10 +424,21 @@ pub fn run() {
             tray_handle.manage(tray::TrayState::default());
             tray::setup(&tray_handle);
 
+            // Started by a launch-at-login entry: go straight to the tray
+            // instead of popping the window at login. Only when a tray
+            // actually exists to bring it back from (never macOS by default).
+            if autostart::launched_hidden() && tray::should_hide_on_close(&tray_handle) {
+                if let Some(window) = tray_handle.get_webview_window("main") {
+                    let _ = window.hide();
+                }
+            }
+
             // Holds the "revoke media permissions on quit" preference so the
             // ExitRequested hook can read it synchronously at shutdown.
             tray_handle.manage(commands::media_permissions::MediaPermissionsState::default());
 
+            pollis_core::commands::startup::record_startup_stage("setup_total", setup_started);
+
             // WebRTC is disabled by default in WebKitGTK and must be explicitly enabled.
             // Without this, RTCPeerConnection is undefined in the JS context on Linux.
             #[cfg(target_os = "linux")]
@@ -426,6 +462,8 @@ pub fn run() {
             tray::tray_set_close_to_tray,
             tray::tray_set_enabled,
             tray::tray_set_voice_state,
+            autostart::autostart_is_enabled,
+            autostart::autostart_set_enabled,
             commands::media_permissions::get_media_permission_status,
             commands::media_permissions::open_privacy_settings,
             commands::media_permissions::revoke_media_permissions,
@@ -455,9 +493,11 @@ pub fn run() {
             commands::device_enrollment::reject_device_enrollment,
             commands::device_enrollment::recover_with_secret_key,
             commands::device_enrollment::reset_identity_and_recover,
+            commands::device_enrollment::rotate_account_identity,
             commands::device_enrollment::finalize_device_enrollment,
             commands::device_enrollment::list_security_events,
             commands::safety::get_safety_number,
+            commands::safety::get_identity_fingerprint,
             commands::safety::set_contact_verified,
             commands::safety::list_peer_verifications,
             commands::transparency::self_audit_account_key,
@@ -465,17 +505,22 @@ pub fn run() {
             commands::transparency::verify_own_build,
             commands::overlay::get_overlay_mode,
             commands::overlay::set_overlay_mode,
+            commands::overlay::get_relay_latency,
             commands::user::get_user_profile,
             commands::user::update_user_profile,
             commands::user::search_user_by_username,
             commands::user::get_preferences,
             commands::user::save_preferences,
+            commands::user::get_inactivity_policy,
+            commands::user::set_inactivity_policy,
+            commands::user::get_my_usage,
             commands::groups::list_user_groups,
             commands::groups::list_user_groups_with_channels,
             commands::groups::list_group_channels,
             commands::groups::create_group,
             commands::groups::create_channel,
             commands::groups::send_group_invite,
+            commands::groups::redeem_email_invite,
             commands::groups::get_pending_invites,
             commands::groups::accept_group_invite,
             commands::groups::decline_group_invite,
@@ -486,13 +531,32 @@ pub fn run() {
             commands::groups::reject_join_request,
             commands::groups::update_group,
             commands::groups::delete_group,
+            commands::groups::cancel_group_deletion,
+            commands::groups::forget_group_history,
+            commands::groups::set_group_export_policy,
+            commands::groups::list_storage_targets,
+            commands::groups::set_group_storage_target,
+            commands::groups::list_group_webhooks,
+            commands::groups::create_group_webhook,
+            commands::groups::delete_group_webhook,
             commands::groups::get_group_members,
+            commands::groups::get_group_events,
             commands::groups::remove_member_from_group,
             commands::groups::leave_group,
             commands::groups::update_channel,
+            commands::groups::set_channel_retention,
             commands::groups::delete_channel,
             commands::groups::set_member_role,
+            commands::groups::transfer_group_ownership,
+            commands::groups::get_pending_ownership_transfers,
+            commands::groups::accept_group_ownership,
+            commands::groups::decline_group_ownership,
+            commands::groups::export_group_structure,
             commands::groups::search_group_by_slug,
+            commands::groups::normalize_slug,
+            commands::groups::reserve_group_slug,
+            commands::groups::get_group_join_code,
+            commands::groups::resolve_group_join_code,
             commands::dm::create_dm_channel,
             commands::dm::list_dm_channels,
             commands::dm::list_dm_requests,
@@ -504,28 +568,74 @@ pub fn run() {
             commands::blocks::block_user,
             commands::blocks::unblock_user,
             commands::blocks::list_blocked_users,
+            commands::contacts::set_contact_nickname,
+            commands::contacts::set_contact_notes,
+            commands::contacts::get_contact_note,
+            commands::contacts::list_contact_notes,
+            commands::sidebar::get_sidebar_order,
+            commands::sidebar::pin_conversation,
+            commands::sidebar::set_sidebar_order,
+            commands::reminders::list_reminders,
+            commands::reminders::create_reminder,
+            commands::reminders::delete_reminder,
+            commands::links::check_link,
+            commands::links::check_links,
+            commands::maintenance::get_maintenance_status,
+            commands::maintenance::subscribe_maintenance_events,
+            commands::crash::list_crash_reports,
+            commands::crash::acknowledge_crash_reports,
+            commands::crash::export_crash_reports,
+            commands::delivery_latency::get_delivery_latency_stats,
+            commands::delivery_canary::get_delivery_canary_report,
+            commands::delivery_canary::acknowledge_delivery_canary_alert,
+            commands::delivery_canary::subscribe_delivery_canary_alerts,
+            commands::startup::get_startup_timings,
             commands::messages::list_messages,
             commands::messages::send_message,
             commands::messages::get_channel_messages,
             commands::messages::get_dm_messages,
             commands::messages::read_channel_messages,
             commands::messages::read_dm_messages,
+            commands::messages::read_filtered_messages,
+            commands::messages::get_channel_activity,
+            commands::messages::get_most_active_channels,
             commands::messages::ingest_channel_envelopes,
             commands::messages::ingest_dm_envelopes,
             commands::messages::list_messages_by_sender,
             commands::messages::list_channel_previews,
+            commands::messages::list_conversation_previews,
+            commands::messages::broadcast_announcement,
+            commands::messages::export_channel_messages,
+            commands::messages::export_group_attachments,
+            commands::messages::pause_attachment_export,
+            commands::messages::translate_message,
+            commands::messages::get_translation_backend,
+            commands::messages::set_translation_backend,
+            commands::messages::get_conversation_translation_language,
+            commands::messages::set_conversation_translation_language,
             commands::messages::search_messages,
             commands::messages::add_reaction,
             commands::messages::remove_reaction,
             commands::messages::get_reactions,
+            commands::messages::pin_message,
+            commands::messages::unpin_message,
+            commands::messages::get_pinned_messages,
             commands::messages::delete_message,
             commands::messages::edit_message,
             commands::messages::get_message_retention,
             commands::messages::set_message_retention,
+            commands::local_backup::list_local_backups,
+            commands::local_backup::create_local_backup,
+            commands::local_backup::restore_local_backup,
+            commands::local_backup::get_local_backup_count,
+            commands::local_backup::set_local_backup_count,
             commands::messages::run_message_eviction,
             commands::mls::poll_mls_welcomes,
             commands::mls::process_pending_commits,
             commands::mls::catch_up_all_mls_groups,
+            commands::mls::get_crypto_obligations,
+            commands::mls::retry_crypto_obligations,
+            commands::mls::subscribe_crypto_obligation_events,
 commands::livekit::get_livekit_token,
             commands::livekit::get_livekit_view_token,
             commands::livekit::get_livekit_url,
@@ -543,6 +653,7 @@ commands::livekit::get_livekit_token,
             commands::r2::download_file,
             commands::r2::download_media,
             commands::r2::get_media_url,
+            commands::r2::subscribe_media_download_events,
             commands::update::mark_update_required,
             commands::update::is_update_required,
             commands::install_kind::detect_managed_install,
@@ -552,6 +663,7 @@ commands::livekit::get_livekit_token,
             commands::voice::join_voice_channel,
             commands::voice::leave_voice_channel,
             commands::voice::toggle_voice_mute,
+            commands::voice::set_voice_push_to_talk,
             commands::voice::set_remote_user_volume,
             commands::voice::set_voice_input_device,
             commands::voice::set_voice_output_device,
@@ -54,9 +54,11 @@ pub fn build_client_app(state: Arc<AppState>) -> Result<(App<MockRuntime>, Webvi
             crate::commands::device_enrollment::reject_device_enrollment,
             crate::commands::device_enrollment::recover_with_secret_key,
             crate::commands::device_enrollment::reset_identity_and_recover,
+            crate::commands::device_enrollment::rotate_account_identity,
             crate::commands::device_enrollment::finalize_device_enrollment,
             crate::commands::device_enrollment::list_security_events,
             crate::commands::safety::get_safety_number,
+            crate::commands::safety::get_identity_fingerprint,
             crate::commands::safety::set_contact_verified,
             crate::commands::safety::list_peer_verifications,
             crate::commands::user::get_user_profile,
@@ -64,12 +66,16 @@ pub fn build_client_app(state: Arc<AppState>) -> Result<(App<MockRuntime>, Webvi
             crate::commands::user::search_user_by_username,
             crate::commands::user::get_preferences,
             crate::commands::user::save_preferences,
+            crate::commands::user::get_inactivity_policy,
+            crate::commands::user::set_inactivity_policy,
+            crate::commands::user::get_my_usage,
             crate::commands::groups::list_user_groups,
             crate::commands::groups::list_user_groups_with_channels,
             crate::commands::groups::list_group_channels,
             crate::commands::groups::create_group,
             crate::commands::groups::create_channel,
             crate::commands::groups::send_group_invite,
+            crate::commands::groups::redeem_email_invite,
             crate::commands::groups::get_pending_invites,
             crate::commands::groups::accept_group_invite,
             crate::commands::groups::decline_group_invite,
@@ -80,13 +86,27 @@ pub fn build_client_app(state: Arc<AppState>) -> Result<(App<MockRuntime>, Webvi
             crate::commands::groups::reject_join_request,
             crate::commands::groups::update_group,
             crate::commands::groups::delete_group,
+            crate::commands::groups::cancel_group_deletion,
+            crate::commands::groups::forget_group_history,
+            crate::commands::groups::set_group_export_policy,
+            crate::commands::groups::list_storage_targets,
+            crate::commands::groups::set_group_storage_target,
+            crate::commands::groups::list_group_webhooks,
+            crate::commands::groups::create_group_webhook,
+            crate::commands::groups::delete_group_webhook,
             crate::commands::groups::get_group_members,
+            crate::commands::groups::get_group_events,
             crate::commands::groups::remove_member_from_group,
             crate::commands::groups::leave_group,
             crate::commands::groups::update_channel,
+            crate::commands::groups::set_channel_retention,
             crate::commands::groups::delete_channel,
             crate::commands::groups::set_member_role,
             crate::commands::groups::search_group_by_slug,
+            crate::commands::groups::normalize_slug,
+            crate::commands::groups::reserve_group_slug,
+            crate::commands::groups::get_group_join_code,
+            crate::commands::groups::resolve_group_join_code,
             crate::commands::dm::create_dm_channel,
             crate::commands::dm::list_dm_channels,
             crate::commands::dm::list_dm_requests,
@@ -98,25 +118,67 @@ pub fn build_client_app(state: Arc<AppState>) -> Result<(App<MockRuntime>, Webvi
             crate::commands::blocks::block_user,
             crate::commands::blocks::unblock_user,
             crate::commands::blocks::list_blocked_users,
+            crate::commands::contacts::set_contact_nickname,
+            crate::commands::contacts::set_contact_notes,
+            crate::commands::contacts::get_contact_note,
+            crate::commands::contacts::list_contact_notes,
+            crate::commands::sidebar::get_sidebar_order,
+            crate::commands::sidebar::pin_conversation,
+            crate::commands::sidebar::set_sidebar_order,
+            crate::commands::reminders::list_reminders,
+            crate::commands::reminders::create_reminder,
+            crate::commands::reminders::delete_reminder,
+            crate::commands::links::check_link,
+            crate::commands::links::check_links,
+            crate::commands::maintenance::get_maintenance_status,
+            crate::commands::maintenance::subscribe_maintenance_events,
+            crate::commands::crash::list_crash_reports,
+            crate::commands::crash::acknowledge_crash_reports,
+            crate::commands::crash::export_crash_reports,
+            crate::commands::delivery_latency::get_delivery_latency_stats,
+            crate::commands::delivery_canary::get_delivery_canary_report,
+            crate::commands::delivery_canary::acknowledge_delivery_canary_alert,
+            crate::commands::delivery_canary::subscribe_delivery_canary_alerts,
+            crate::commands::startup::get_startup_timings,
             crate::commands::messages::list_messages,
             crate::commands::messages::send_message,
             crate::commands::messages::get_channel_messages,
             crate::commands::messages::get_dm_messages,
             crate::commands::messages::read_channel_messages,
             crate::commands::messages::read_dm_messages,
+            crate::commands::messages::read_filtered_messages,
+            crate::commands::messages::get_channel_activity,
+            crate::commands::messages::get_most_active_channels,
             crate::commands::messages::list_messages_by_sender,
             crate::commands::messages::list_channel_previews,
+            crate::commands::messages::list_conversation_previews,
+            crate::commands::messages::broadcast_announcement,
+            crate::commands::messages::export_channel_messages,
+            crate::commands::messages::export_group_attachments,
+            crate::commands::messages::pause_attachment_export,
+            crate::commands::messages::translate_message,
+            crate::commands::messages::get_translation_backend,
+            crate::commands::messages::set_translation_backend,
+            crate::commands::messages::get_conversation_translation_language,
+            crate::commands::messages::set_conversation_translation_language,
             crate::commands::messages::search_messages,
             crate::commands::messages::add_reaction,
             crate::commands::messages::remove_reaction,
             crate::commands::messages::get_reactions,
+            crate::commands::messages::pin_message,
+            crate::commands::messages::unpin_message,
+            crate::commands::messages::get_pinned_messages,
             crate::commands::messages::delete_message,
             crate::commands::messages::edit_message,
             crate::commands::mls::poll_mls_welcomes,
             crate::commands::mls::process_pending_commits,
             crate::commands::mls::catch_up_all_mls_groups,
+            crate::commands::mls::get_crypto_obligations,
+            crate::commands::mls::retry_crypto_obligations,
+            crate::commands::mls::subscribe_crypto_obligation_events,
             crate::commands::overlay::get_overlay_mode,
             crate::commands::overlay::set_overlay_mode,
+            crate::commands::overlay::get_relay_latency,
         ])
         .manage(state)
         .build(mock_context(noop_assets()))
@@ -383,13 +445,15 @@ pub async fn wipe_remote(
     log.reconnect().await?;
 
     // MAIN DB: tables that reference others first, then roots. The list covers
-    // the base schema + every table added by migrations 000001–000015. The
+    // the base schema + every table added by migrations 000001–000019. The
     // three MLS control-plane tables (`mls_commit_log`, `mls_welcome`,
     // `mls_group_info`) are deliberately ABSENT — they live only on the log DB
     // now (dropped from main by `drop_log_tables_from_main`), so a DELETE here
     // would itself be a "no such table" failure. They're wiped on `log` below.
     let main_tables = [
         "message_reaction",
+        "group_ownership_transfer",
+        "group_webhook",
         "group_invite",
         "group_join_request",
         "user_preferences",
@@ -401,6 +465,13 @@ pub async fn wipe_remote(
         "mls_key_package",
         "device_enrollment_request",
         "security_event",
+        "flood_incident",
+        "inactivity_policy",
+        "pinned_message",
+        "usage_daily",
+        "group_slug",
+        "email_invite",
+        "email_invite_opt_out",
         "account_recovery",
         "user_device",
         "channels",
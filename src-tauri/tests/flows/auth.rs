@@ -422,6 +422,59 @@ async fn reset_identity_resigns_device_cert() {
     drop(alice);
 }
 
+/// A planned rotation re-seals the synced preferences under the new account
+/// key. Without it the remote row stays sealed under the old key, every read
+/// falls back to the local cache, and the next save overwrites what other
+/// devices synced.
+#[tokio::test(flavor = "multi_thread")]
+#[serial]
+async fn rotate_identity_keeps_synced_preferences() {
+    wipe().await;
+
+    let mut alice = TestClient::new().await;
+    let profile = alice.sign_up("alice@test.local").await;
+    let user_id = profile.id.clone();
+
+    let prefs = json!({ "muted_members": { "g1": ["bob"] }, "sidebar_order": ["g1"] });
+    invoke::<()>(
+        &alice.webview,
+        "save_preferences",
+        json!({ "userId": user_id, "preferencesJson": prefs.to_string() }),
+    )
+    .await
+    .expect("save_preferences");
+
+    pollis_lib::commands::account_identity::rotate_identity(&alice.state, &user_id, TEST_PIN)
+        .await
+        .expect("rotate_identity");
+
+    // Drop the local cache so the read below can only come from the remote row.
+    {
+        let guard = alice.state.local_db.lock().await;
+        let db = guard.as_ref().expect("local db open");
+        db.conn().execute("DELETE FROM preferences", []).expect("clear cache");
+    }
+    let read: String = invoke(&alice.webview, "get_preferences", json!({ "userId": user_id }))
+        .await
+        .expect("get_preferences");
+    let read: serde_json::Value = serde_json::from_str(&read).expect("preferences json");
+    assert_eq!(read, prefs, "preferences must open under the rotated key");
+
+    let w = world().await;
+    let conn = w.remote.conn().await.expect("remote conn");
+    let mut rows = conn
+        .query(
+            "SELECT preferences FROM user_preferences WHERE user_id = ?1",
+            libsql::params![user_id.clone()],
+        )
+        .await
+        .expect("user_preferences select");
+    let stored: String = rows.next().await.expect("rows").expect("row").get(0).expect("column");
+    assert!(stored.starts_with("pollis-sealed-v1:"), "remote row must stay sealed");
+
+    drop(alice);
+}
+
 /// Boot-time self-heal: a sibling device's `user_device` row whose
 /// `cert_identity_version` is behind `users.identity_version` (e.g.
 /// because another device rotated the account identity while this one
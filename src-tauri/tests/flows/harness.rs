@@ -921,10 +921,66 @@ delivery_b!(
     "groups/delete"
 );
 delivery_b!(
-    delivery_groups_leave,
-    pollis_delivery::groups::LeaveGroupBody,
-    pollis_delivery::groups::apply_leave_group,
-    "groups/leave"
+    delivery_groups_cancel_deletion,
+    pollis_delivery::groups::DeleteGroupBody,
+    pollis_delivery::groups::apply_cancel_group_deletion,
+    "groups/delete/cancel"
+);
+/// `POST /v1/groups/leave` — the main-DB `apply_leave_group` plus the log-DB
+/// Welcome purge, like `delivery_members_remove`.
+async fn delivery_groups_leave(
+    axum::extract::State(state): axum::extract::State<DsState>,
+    method: axum::http::Method,
+    uri: axum::http::Uri,
+    headers: axum::http::HeaderMap,
+    body: axum::body::Bytes,
+) -> axum::response::Response {
+    let authed = match ds_auth(&state.main, &method, &uri, &headers, &body).await {
+        Ok(u) => u,
+        Err(resp) => return resp,
+    };
+    let parsed: pollis_delivery::groups::LeaveGroupBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return ds_bad_request(),
+    };
+    let conn = match state.main.conn().await {
+        Ok(c) => c,
+        Err(e) => return ds_internal_error(format!("conn: {e}")),
+    };
+    let outcome = match pollis_delivery::groups::apply_leave_group(&conn, Some(&authed), &parsed).await {
+        Ok(o) => o,
+        Err(e) => return ds_internal_error(format!("groups/leave: {e}")),
+    };
+    if matches!(outcome, pollis_delivery::writes::WriteOutcome::Ok) {
+        let log_conn = match state.log.conn().await {
+            Ok(c) => c,
+            Err(e) => return ds_internal_error(format!("conn: {e}")),
+        };
+        if let Err(e) =
+            pollis_delivery::groups::purge_member_welcomes(&log_conn, &parsed.group_id, &authed).await
+        {
+            return ds_internal_error(format!("groups/leave welcome purge: {e}"));
+        }
+    }
+    ds_outcome(outcome)
+}
+delivery_b!(
+    delivery_groups_transfer_ownership,
+    pollis_delivery::groups::TransferOwnershipBody,
+    pollis_delivery::groups::apply_transfer_ownership,
+    "groups/transfer-ownership"
+);
+delivery_b!(
+    delivery_groups_accept_ownership,
+    pollis_delivery::groups::OwnershipResponseBody,
+    pollis_delivery::groups::apply_accept_ownership,
+    "groups/accept-ownership"
+);
+delivery_b!(
+    delivery_groups_decline_ownership,
+    pollis_delivery::groups::OwnershipResponseBody,
+    pollis_delivery::groups::apply_decline_ownership,
+    "groups/decline-ownership"
 );
 delivery_b!(
     delivery_channels_create,
@@ -944,12 +1000,48 @@ delivery_b!(
     pollis_delivery::groups::apply_delete_channel,
     "channels/delete"
 );
-delivery_b!(
-    delivery_members_remove,
-    pollis_delivery::groups::RemoveMemberBody,
-    pollis_delivery::groups::apply_remove_member,
-    "members/remove"
-);
+/// `POST /v1/members/remove` — the main-DB `apply_remove_member` plus the
+/// log-DB Welcome purge, the same two steps the production handler runs.
+async fn delivery_members_remove(
+    axum::extract::State(state): axum::extract::State<DsState>,
+    method: axum::http::Method,
+    uri: axum::http::Uri,
+    headers: axum::http::HeaderMap,
+    body: axum::body::Bytes,
+) -> axum::response::Response {
+    let authed = match ds_auth(&state.main, &method, &uri, &headers, &body).await {
+        Ok(u) => u,
+        Err(resp) => return resp,
+    };
+    let parsed: pollis_delivery::groups::RemoveMemberBody = match serde_json::from_slice(&body) {
+        Ok(b) => b,
+        Err(_) => return ds_bad_request(),
+    };
+    let conn = match state.main.conn().await {
+        Ok(c) => c,
+        Err(e) => return ds_internal_error(format!("conn: {e}")),
+    };
+    let outcome = match pollis_delivery::groups::apply_remove_member(&conn, Some(&authed), &parsed).await {
+        Ok(o) => o,
+        Err(e) => return ds_internal_error(format!("members/remove: {e}")),
+    };
+    if matches!(outcome, pollis_delivery::writes::WriteOutcome::Ok) {
+        let log_conn = match state.log.conn().await {
+            Ok(c) => c,
+            Err(e) => return ds_internal_error(format!("conn: {e}")),
+        };
+        if let Err(e) = pollis_delivery::groups::purge_member_welcomes(
+            &log_conn,
+            &parsed.group_id,
+            &parsed.user_id,
+        )
+        .await
+        {
+            return ds_internal_error(format!("members/remove welcome purge: {e}"));
+        }
+    }
+    ds_outcome(outcome)
+}
 delivery_b!(
     delivery_members_role,
     pollis_delivery::groups::SetMemberRoleBody,
@@ -1709,7 +1801,9 @@ async fn delivery_establish_identity(
         &headers,
         &state.sessions,
         now_u64(),
-    ) {
+    )
+    .await
+    {
         Ok(c) => c,
         Err(rej) => return rej.into_response(),
     };
@@ -1763,7 +1857,9 @@ async fn delivery_register_device(
         &headers,
         &state.sessions,
         now_u64(),
-    ) {
+    )
+    .await
+    {
         Ok(c) => c,
         Err(rej) => return rej.into_response(),
     };
@@ -1829,9 +1925,10 @@ async fn delivery_publish_device_cert(
     let session_token = pollis_delivery::session::session_token(&headers)
         .filter(|t| !t.is_empty())
         .map(|t| t.to_string());
-    let session_claims = session_token
-        .as_ref()
-        .and_then(|t| state.sessions.resolve(t, now));
+    let session_claims = match &session_token {
+        Some(t) => state.sessions.resolve(t, now).await,
+        None => None,
+    };
     let (user_id, device_id, invalidate_token) = match session_claims {
         Some(claims) => {
             if parsed.device_id != claims.device_id {
@@ -1865,7 +1962,7 @@ async fn delivery_publish_device_cert(
     {
         Ok(pollis_delivery::bootstrap::PublishCertOutcome::Applied) => {
             if let Some(token) = invalidate_token {
-                state.sessions.invalidate(&token);
+                state.sessions.invalidate(&token).await;
             }
             ds_ok()
         }
@@ -1890,7 +1987,12 @@ async fn delivery_enrollment_request(
     body: axum::body::Bytes,
 ) -> axum::response::Response {
     use axum::response::IntoResponse;
-    let claims = match pollis_delivery::session::verify_session(&headers, &state.sessions, now_u64())
+    let claims = match pollis_delivery::session::verify_session(
+        &headers,
+        &state.sessions,
+        now_u64(),
+    )
+    .await
     {
         Ok(c) => c,
         Err(rej) => return rej.into_response(),
@@ -2079,7 +2181,11 @@ async fn spawn_in_process_delivery(main: Arc<RemoteDb>, log: Arc<RemoteDb>) -> S
                     .route("/v1/groups/create", axum::routing::post(delivery_groups_create))
                     .route("/v1/groups/update", axum::routing::post(delivery_groups_update))
                     .route("/v1/groups/delete", axum::routing::post(delivery_groups_delete))
+                    .route("/v1/groups/delete/cancel", axum::routing::post(delivery_groups_cancel_deletion))
                     .route("/v1/groups/leave", axum::routing::post(delivery_groups_leave))
+                    .route("/v1/groups/transfer-ownership", axum::routing::post(delivery_groups_transfer_ownership))
+                    .route("/v1/groups/accept-ownership", axum::routing::post(delivery_groups_accept_ownership))
+                    .route("/v1/groups/decline-ownership", axum::routing::post(delivery_groups_decline_ownership))
                     .route(
                         "/v1/channels/create",
                         axum::routing::post(delivery_channels_create),